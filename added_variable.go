@@ -0,0 +1,125 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// AddedVariable returns the data of the added-variable, or partial
+// regression, plot of column j of the model's design, as car::avPlots draws
+// it: the residuals xres of the column regressed on the other columns, the
+// residuals yres of the response regressed on them, and the slope of the line
+// through the origin that fits yres on xres. Both regressions include the
+// intercept whenever the model has one and j isn't it. By the Frisch-Waugh-
+// Lovell theorem the slope is the coefficient \beta_j of the model and the
+// residuals of that line are the model's, so the plot shows the evidence for
+// column j once the others are accounted for.
+//
+// j indexes the coefficients, as CoefficientNames does, so that the intercept
+// is column 0 unless the model was fit through the origin. A column that is a
+// linear combination of the others has no residuals to regress on, and is a
+// SingularDesignError.
+func AddedVariable(m Summary, j int) (xres, yres []float64, slope float64, err error) {
+	x := m.Data().X
+	n, p := x.Dims()
+	if j < 0 || j >= p {
+		return nil, nil, 0, fmt.Errorf("column %d is out of range for %d coefficients", j, p)
+	}
+	if n < p-1 {
+		return nil, nil, 0, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p-1)
+	}
+	// regress the column and the response on the others at once
+	rhs := mat.NewDense(n, 2, nil)
+	xj := mat.Col(nil, j, x)
+	rhs.SetCol(0, xj)
+	rhs.SetCol(1, m.Response())
+	resid := mat.DenseCopyOf(rhs)
+	if p > 1 {
+		others := mat.NewDense(n, p-1, nil)
+		for k, c := 0, 0; k < p; k++ {
+			if k != j {
+				others.SetCol(c, mat.Col(nil, k, x))
+				c++
+			}
+		}
+		b := &mat.Dense{}
+		if err := factorize(others).SolveTo(b, false, rhs); err != nil {
+			return nil, nil, 0, fmt.Errorf("%w: %v", SingularDesignError, err)
+		}
+		fitted := &mat.Dense{}
+		fitted.Mul(others, b)
+		resid.Sub(rhs, fitted)
+	}
+	xres, yres = mat.Col(nil, 0, resid), mat.Col(nil, 1, resid)
+
+	ss := sum(prod(xres, xres))
+	if !(math.Sqrt(ss) > singularTolerance*math.Sqrt(sum(prod(xj, xj)))) {
+		return nil, nil, 0, fmt.Errorf("%w: column %d is a linear combination of the others", SingularDesignError, j)
+	}
+	return xres, yres, sum(prod(xres, yres)) / ss, nil
+}
+
+// AddedVariableByName is AddedVariable for the coefficient with the given
+// name in CoefficientNames, "(Intercept)" for the intercept.
+func AddedVariableByName(m Summary, name string) (xres, yres []float64, slope float64, err error) {
+	for j, label := range CoefficientNames(m) {
+		if label == name {
+			return AddedVariable(m, j)
+		}
+	}
+	return nil, nil, 0, fmt.Errorf("no coefficient %q", name)
+}
+
+// PartialResiduals returns the data of the component-plus-residual plot of
+// column j of the model's design, as car::crPlots draws it: the values x of
+// the column and the partial residuals
+//
+// e_i + \beta_j (x_ij - \bar{x}_j)
+//
+// the residuals with the column's own contribution added back, centered as
+// R's residuals(fit, type = "partial") centers it when the model has an
+// intercept; through the origin there is no centering. The centering only
+// shifts the points, so a curve in them is a nonlinearity in x_j that the
+// straight line \beta_j x_j misses, which a smooth such as Lowess through the
+// points shows.
+//
+// j indexes the coefficients, as it does for AddedVariable, and can't be the
+// intercept. A model fit to a transformed problem, such as weighted least
+// squares, only has its transformed design and is an error.
+func PartialResiduals(m Summary, j int) (x, pres []float64, err error) {
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return nil, nil, fmt.Errorf("a model fit to a transformed problem doesn't have the columns of its design; compute its partial residuals from the original data")
+	}
+	p := m.Data().Cols()
+	intercept := interceptOf(m)
+	switch {
+	case j < 0 || j >= p:
+		return nil, nil, fmt.Errorf("column %d is out of range for %d coefficients", j, p)
+	case j == intercept:
+		return nil, nil, fmt.Errorf("column %d is the intercept, which has no partial residuals", j)
+	}
+	x = mat.Col(nil, j, m.Data().X)
+	center := 0.0
+	if intercept >= 0 {
+		center = mean(x)
+	}
+	beta := m.Coefficients()[j]
+	pres = make([]float64, len(x))
+	for i, e := range m.Residuals() {
+		pres[i] = e + beta*(x[i]-center)
+	}
+	return x, pres, nil
+}
+
+// PartialResidualsByName is PartialResiduals for the coefficient with the
+// given name in CoefficientNames.
+func PartialResidualsByName(m Summary, name string) (x, pres []float64, err error) {
+	for j, label := range CoefficientNames(m) {
+		if label == name {
+			return PartialResiduals(m, j)
+		}
+	}
+	return nil, nil, fmt.Errorf("no coefficient %q", name)
+}
@@ -0,0 +1,136 @@
+package glasso
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestAddedVariable(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	betas := s.Coefficients()
+	for j, name := range CoefficientNames(s) {
+		xres, yres, slope, err := AddedVariable(s, j)
+		assert.Equal(t, nil, err, name)
+		// the slope is the coefficient, and the residuals of the line through
+		// the origin are those of the model
+		assertClose(t, slope, betas[j], 1e-10)
+		for i, e := range s.Residuals() {
+			assertClose(t, yres[i]-slope*xres[i], e, 1e-10)
+		}
+		if j > 0 {
+			// residuals on a model with an intercept have mean zero
+			assertClose(t, mean(xres), 0, 1e-10)
+			assertClose(t, mean(yres), 0, 1e-10)
+		}
+
+		xn, yn, slopeByName, err := AddedVariableByName(s, name)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, xres, xn)
+		assert.Equal(t, yres, yn)
+		assert.Equal(t, slope, slopeByName)
+	}
+}
+
+func TestAddedVariableStackloss(t *testing.T) {
+	// the points of the Air.Flow panel of car's avPlots(lm(stack.loss ~ .,
+	// stackloss)): the residuals of Air.Flow and of stack.loss on the other
+	// columns, by their definition in exact rational arithmetic rather than
+	// from car, which hasn't been run against them
+	wantX := []float64{6.651217119072567, 7.044004684976479, 5.273519057087252, -4.540573493241711, -0.525483989323113, -2.5330287412824117, -6.897298888665189, -6.897298888665189, -6.533028741282412, 6.254207979841473, 2.719119886706257, 5.119452204569469, 5.468632848033647, -0.8595751288686938, -5.280880113293743, -4.102517415582004, -0.6110362448865225, -3.360549206213913, -5.760881524077125, -0.5464566558849506, 9.918455250979832}
+	wantY := []float64{7.994515579604187, 3.123487632867138, 8.32947523266904, 2.4483572456217955, -2.0877110480504, -4.819676901214303, -7.32547507395591, -6.32547507395591, -7.819676901214303, 5.742956737445865, 4.582208258079307, 6.443146164506159, 2.485012630919963, -0.6656458081363993, -1.4177917419206933, -2.0308755821318405, -1.9572326896144299, -2.8600370624550866, -4.720974968881939, 1.0210809245921586, -0.13966755477439957}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data, []string{"Air.Flow", "Water.Temp", "Acid.Conc."}), y)
+	assert.Equal(t, nil, err)
+	xres, yres, _, err := AddedVariableByName(s, "Air.Flow")
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, xres, wantX, 1e-12)
+	assertCloseSlices(t, yres, wantY, 1e-12)
+}
+
+func TestAddedVariableWeighted(t *testing.T) {
+	// for weighted least squares the regressions are of the transformed problem
+	_, s, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, beta := range s.Coefficients() {
+		_, _, slope, err := AddedVariable(s, j)
+		assert.Equal(t, nil, err)
+		assertClose(t, slope, beta, 1e-10)
+	}
+}
+
+func TestAddedVariableThroughOrigin(t *testing.T) {
+	_, s, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, beta := range s.Coefficients() {
+		_, _, slope, err := AddedVariable(s, j)
+		assert.Equal(t, nil, err)
+		assertClose(t, slope, beta, 1e-10)
+	}
+	_, _, _, err = AddedVariableByName(s, "(Intercept)")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestAddedVariableErrors(t *testing.T) {
+	for _, j := range []int{-1, 4} {
+		_, _, _, err := AddedVariable(summary, j)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, _, err := AddedVariableByName(summary, "Air.Flow")
+	assert.NotEqual(t, nil, err)
+
+	// the third column is the sum of the first two, which leaves it nothing
+	rows := [][]float64{{1, 2, 3}, {2, 1, 3}, {3, 5, 8}, {4, 2, 6}, {5, 7, 12}, {6, 1, 7}}
+	response := []float64{1, 3, 2, 5, 4, 6}
+	x := NewDataFrame(rows)
+	x.PushCol(rep(1, 6))
+	s := OlsSummary{data: x, response: response, n: 6, p: 4}
+	_, _, _, err = AddedVariable(s, 2)
+	assert.T(t, errors.Is(err, SingularDesignError))
+}
+
+func TestPartialResiduals(t *testing.T) {
+	// e_i + b_j (x_ij - mean(x_j)), the definition of R's residuals(lm(
+	// stack.loss ~ ., stackloss), type = "partial")[, "Air.Flow"] and of the
+	// points of car's crPlots, in exact rational arithmetic; neither R nor
+	// car has been run against these
+	want := []float64{17.240738293680494, 12.08861577453184, 14.983433061606286, 6.822351628548902, -0.5870761226739558, -1.8823622470625267, -1.264913256559187, -0.264913256559187, -4.8823622470625265, -0.4707892591602344, 0.8983134131776317, 1.0414770184175508, -3.1665442208629306, -1.788482634616332, -5.101686586822368, -6.558054144268324, -8.98305553673802, -7.918197902697457, -8.061361507937375, -1.7571164696400727, -0.3880137973022067}
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	x, pres, err := PartialResidualsByName(s, "Air.Flow")
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, pres, want, 1e-10)
+	for i, row := range data {
+		assert.Equal(t, row[0], x[i])
+	}
+	// the least squares line through the points has the coefficient as its slope
+	xc := subtractMean(x)
+	assertClose(t, sum(prod(xc, pres))/sum(prod(xc, xc)), s.Coefficients()[1], 1e-10)
+
+	_, _, err = PartialResiduals(s, 0)
+	assert.NotEqual(t, nil, err)
+	for _, j := range []int{-1, 4} {
+		_, _, err = PartialResiduals(s, j)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, err = PartialResidualsByName(s, "(Intercept)")
+	assert.NotEqual(t, nil, err)
+	_, _, err = PartialResidualsByName(s, "Sulfur")
+	assert.NotEqual(t, nil, err)
+	_, ws, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, _, err = PartialResiduals(ws, 1)
+	assert.NotEqual(t, nil, err)
+
+	// through the origin the contribution isn't centered
+	_, s, err = NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, pres, err = PartialResiduals(s, 0)
+	assert.Equal(t, nil, err)
+	for i, e := range s.Residuals() {
+		assertClose(t, pres[i], e+s.Coefficients()[0]*data[i][0], 1e-12)
+	}
+}
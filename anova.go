@@ -0,0 +1,111 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// AnovaRow is one line of an analysis of variance table.
+type AnovaRow struct {
+	Term   string
+	DF     float64
+	SumSq  float64
+	MeanSq float64
+	F      float64 // NaN for the residual row
+	PValue float64 // NaN for the residual row
+}
+
+// AnovaTable is an analysis of variance table whose last row is the residuals.
+type AnovaTable struct {
+	Rows []AnovaRow
+}
+
+// Anova returns the sequential (type I) analysis of variance table of the model,
+// as R's anova.lm does, with one row per predictor in the order of the design
+// columns. The sum of squares of each predictor is the reduction in RSS from
+// adding it to the model containing the intercept and the predictors before it.
+//
+// These come from a single QR factorization: with X = QR, the entries of the
+// effects Q'y = R\beta give the sequential sums of squares as their squares.
+func Anova(m Summary) (*AnovaTable, error) {
+	df := m.Data()
+	n, p := df.Rows(), df.Cols()
+	if n <= p {
+		return nil, fmt.Errorf("%w: anova needs more than %d observations", TooFewObservationsError, p)
+	}
+
+	qr, err := qrOf(m)
+	if err != nil {
+		return nil, err
+	}
+	r := &mat.Dense{}
+	qr.RTo(r)
+	effects := mat.NewVecDense(p, nil)
+	effects.MulVec(r.Slice(0, p, 0, p), mat.NewVecDense(p, m.Coefficients()))
+
+	rss := m.SumOfSquares()
+	residualDF := float64(n - p)
+	s2 := rss / residualDF
+
+	table := &AnovaTable{}
+	intercept := interceptOf(m)
+	for j := 0; j < p; j++ {
+		if j == intercept {
+			continue
+		}
+		ss := effects.At(j, 0) * effects.At(j, 0)
+		f := ss / s2
+		table.Rows = append(table.Rows, AnovaRow{
+			Term:   columnLabel(df, j, intercept),
+			DF:     1,
+			SumSq:  ss,
+			MeanSq: ss,
+			F:      f,
+			PValue: fSurvival(f, 1, residualDF),
+		})
+	}
+	table.Rows = append(table.Rows, AnovaRow{
+		Term:   "Residuals",
+		DF:     residualDF,
+		SumSq:  rss,
+		MeanSq: s2,
+		F:      math.NaN(),
+		PValue: math.NaN(),
+	})
+	return table, nil
+}
+
+// columnLabel names column j of a design matrix. Labels of the DataFrame given
+// before the intercept was pushed on are shifted past it; otherwise the
+// predictors are named x0, x1, ..., after their columns in the data without
+// the intercept.
+func columnLabel(df *DataFrame, j, intercept int) string {
+	labels := df.Labels()
+	k := j
+	if intercept >= 0 && j > intercept {
+		k--
+	}
+	switch len(labels) {
+	case df.Cols():
+		return labels[j]
+	case df.Cols() - 1:
+		if intercept >= 0 {
+			return labels[k]
+		}
+	}
+	return fmt.Sprintf("x%d", k)
+}
+
+func (a *AnovaTable) String() string {
+	s := fmt.Sprintf("%-12s %4s %12s %12s %10s %12s\n", "", "Df", "Sum Sq", "Mean Sq", "F value", "Pr(>F)")
+	for _, row := range a.Rows {
+		if math.IsNaN(row.F) {
+			s += fmt.Sprintf("%-12s %4v %12.4f %12.4f\n", row.Term, row.DF, row.SumSq, row.MeanSq)
+			continue
+		}
+		s += fmt.Sprintf("%-12s %4v %12.4f %12.4f %10.4f %12.4g\n", row.Term, row.DF, row.SumSq, row.MeanSq, row.F, row.PValue)
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestAnova(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	table, err := Anova(s)
+	assert.Equal(t, nil, err)
+
+	// anova(lm(stack.loss ~ ., stackloss))
+	expected := []AnovaRow{
+		{"Air.Flow", 1, 1750.12, 1750.12, 166.3707, 3.309e-10},
+		{"Water.Temp", 1, 130.32, 130.32, 12.3886, 0.002629},
+		{"Acid.Conc.", 1, 9.97, 9.97, 0.9473, 0.344046},
+		{"Residuals", 17, 178.83, 10.52, math.NaN(), math.NaN()},
+	}
+	assert.Equal(t, len(table.Rows), len(expected))
+	for i, row := range table.Rows {
+		e := expected[i]
+		assert.Equal(t, row.Term, e.Term)
+		assert.Equal(t, row.DF, e.DF)
+		assertClose(t, row.SumSq, e.SumSq, 0.005)
+		assertClose(t, row.MeanSq, e.MeanSq, 0.005)
+		if i < 3 {
+			assertClose(t, row.F, e.F, 1e-4)
+			assertClose(t, row.PValue/e.PValue, 1, 1e-3)
+		} else {
+			assert.T(t, math.IsNaN(row.F) && math.IsNaN(row.PValue))
+		}
+	}
+
+	// the last term's F is the square of its t statistic
+	coefs, err := CoefficientTable(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, table.Rows[2].F, coefs[3].T*coefs[3].T, 1e-9)
+	assertClose(t, table.Rows[2].PValue, coefs[3].PValue, 1e-12)
+}
+
+func TestAnovaSequentialRefits(t *testing.T) {
+	// each sum of squares is the drop in RSS from adding that column to the ones before it
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	table, err := Anova(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(table.Rows), 7)
+
+	previous := totalSumOfSquares(longleyY)
+	for j := 1; j <= 6; j++ {
+		rows := make([][]float64, len(longley))
+		for i := range rows {
+			rows[i] = append([]float64{}, longley[i][:j]...)
+		}
+		_, nested, err := NewOlsTrainer().Train(NewDataFrame(rows), longleyY)
+		assert.Equal(t, nil, err)
+		rss := nested.SumOfSquares()
+		assertClose(t, table.Rows[j-1].SumSq/(previous-rss), 1, 1e-6)
+		assert.Equal(t, table.Rows[j-1].Term, columnLabel(s.Data(), j, 0))
+		previous = rss
+	}
+	assert.Equal(t, table.Rows[0].Term, "x0")
+
+	// the sums of squares add up to the total
+	total := 0.0
+	for _, row := range table.Rows {
+		total += row.SumSq
+	}
+	assertClose(t, total/totalSumOfSquares(longleyY), 1, 1e-10)
+}
@@ -0,0 +1,360 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// exactDurbinWatsonRows is the sample size below which DurbinWatson computes
+// the exact p-value rather than the normal approximation (as lmtest::dwtest does).
+const exactDurbinWatsonRows = 100
+
+// DurbinWatson tests the residuals of the model for autocorrelation at the given
+// lag (one by default) with the generalized Durbin-Watson statistic
+//
+// d = \frac{\sum_{t=k+1}^n (e_t - e_{t-k})^2}{\sum_{t=1}^n e_t^2}
+//
+// The p-value is for the one sided alternative of positive autocorrelation,
+// P(D <= d), assuming normal errors. Since d = e'Ae / e'e with e = My, it is the
+// probability that the quadratic form z'M(A - dI)Mz is negative. For fewer than
+// 100 observations it is computed exactly from the eigenvalues of MAM with
+// Imhof's method; otherwise D is approximated by a normal distribution with its
+// exact mean and variance, which is accurate to about two decimal places in the
+// tails at that size. The observations must be in time order.
+func DurbinWatson(m Summary, lag ...int) (stat float64, pValue float64, err error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	k := 1
+	switch len(lag) {
+	case 0:
+	case 1:
+		k = lag[0]
+	default:
+		return 0, 0, fmt.Errorf("expected a single lag, got %d", len(lag))
+	}
+	if k < 1 || k >= n {
+		return 0, 0, fmt.Errorf("lag %d is out of range for %d observations", k, n)
+	}
+	if n <= p {
+		return 0, 0, fmt.Errorf("%w: durbin watson test needs more than %d observations", TooFewObservationsError, p)
+	}
+
+	residuals := m.Residuals()
+	rss := sum(prod(residuals, residuals))
+	if rss == 0 {
+		return 0, 0, fmt.Errorf("residuals are identically zero")
+	}
+	d := 0.0
+	for t := k; t < n; t++ {
+		d += math.Pow(residuals[t]-residuals[t-k], 2)
+	}
+	stat = d / rss
+
+	if n < exactDurbinWatsonRows {
+		pValue, err = dwExact(m, stat, k)
+	} else {
+		pValue, err = dwApprox(m, stat, k)
+	}
+	return stat, pValue, err
+}
+
+// lagDifference returns Av for the n x n matrix A = D'D, where D is the
+// (n - k) x n kth difference operator (Dv)_t = v_{t+k} - v_t.
+func lagDifference(v []float64, k int) []float64 {
+	n := len(v)
+	av := make([]float64, n)
+	for t := k; t < n; t++ {
+		delta := v[t] - v[t-k]
+		av[t] += delta
+		av[t-k] -= delta
+	}
+	return av
+}
+
+// dwExact finds P(D <= d) from the eigenvalues of Q_2'AQ_2, where the columns of
+// Q_2 are an orthonormal basis for the residual space, so that
+// e'(A - dI)e = \sum_i (\lambda_i - d) z_i^2.
+func dwExact(m Summary, d float64, k int) (float64, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	qr, err := qrOf(m)
+	if err != nil {
+		return 0, err
+	}
+	q := &mat.Dense{}
+	qr.QTo(q)
+	q2 := q.Slice(0, n, p, n)
+
+	aq2 := mat.NewDense(n, n-p, nil)
+	for j := 0; j < n-p; j++ {
+		aq2.SetCol(j, lagDifference(mat.Col(nil, j, q2), k))
+	}
+	s := &mat.Dense{}
+	s.Mul(q2.T(), aq2)
+
+	sym := mat.NewSymDense(n-p, nil)
+	for i := 0; i < n-p; i++ {
+		for j := i; j < n-p; j++ {
+			sym.SetSym(i, j, (s.At(i, j)+s.At(j, i))/2)
+		}
+	}
+	eigen := &mat.EigenSym{}
+	if ok := eigen.Factorize(sym, false); !ok {
+		return 0, fmt.Errorf("eigendecomposition of the %d x %d residual quadratic form failed", n-p, n-p)
+	}
+	lambdas := eigen.Values(nil)
+	for i := range lambdas {
+		lambdas[i] -= d
+	}
+	return imhof(lambdas), nil
+}
+
+// dwApprox approximates P(D <= d) with a normal distribution whose mean and
+// variance are the exact moments of D under the null:
+//
+// E(D) = tr(MA) / (n - p)
+// Var(D) = \frac{2}{(n - p)(n - p + 2)} (tr(MAMA) - tr(MA)^2 / (n - p))
+//
+// tr(MA) and tr(MAMA) are expanded in terms of the p x p matrices X'AX and X'A^2X
+// so that nothing n x n is formed.
+func dwApprox(m Summary, d float64, k int) (float64, error) {
+	x := m.Data().X
+	n, p := x.Dims()
+	xtx, err := xtxInverse(m)
+	if err != nil {
+		return 0, err
+	}
+
+	ax := mat.NewDense(n, p, nil)
+	for j := 0; j < p; j++ {
+		ax.SetCol(j, lagDifference(mat.Col(nil, j, x), k))
+	}
+	xax, xaax := &mat.Dense{}, &mat.Dense{}
+	xax.Mul(x.T(), ax)
+	xaax.Mul(ax.T(), ax)
+
+	b := &mat.Dense{}
+	b.Mul(xtx, xax)
+	bb := &mat.Dense{}
+	bb.Mul(b, b)
+	baa := &mat.Dense{}
+	baa.Mul(xtx, xaax)
+
+	// the diagonal of A counts the differences each observation appears in,
+	// and each difference adds a pair of off diagonal -1s
+	traceA, traceAA := 0.0, 2*float64(n-k)
+	for t := 0; t < n; t++ {
+		a := 0.0
+		if t >= k {
+			a++
+		}
+		if t < n-k {
+			a++
+		}
+		traceA += a
+		traceAA += a * a
+	}
+
+	df := float64(n - p)
+	trMA := traceA - mat.Trace(b)
+	trMAMA := traceAA - 2*mat.Trace(baa) + mat.Trace(bb)
+	mu := trMA / df
+	variance := 2 / (df * (df + 2)) * (trMAMA - trMA*trMA/df)
+	return normalCDF((d - mu) / math.Sqrt(variance)), nil
+}
+
+// BreuschGodfrey tests the residuals of the model for serial correlation of
+// any order up to maxLag, as lmtest's bgtest does. Unlike DurbinWatson it
+// remains valid when the regressors include lags of the response. The
+// residuals e are regressed on the design and on e_{t-1}, ..., e_{t-maxLag},
+// with zeros for the lags before the first observation, and
+//
+// LM = n \sum_t \hat{e}_t^2 / \sum_t e_t^2
+//
+// for the fitted values \hat{e} of that regression is chi-squared on maxLag
+// degrees of freedom, while the F form
+//
+// F = \frac{(RSS - RSS_a) / maxLag}{RSS_a / (n - p - maxLag)}
+//
+// compares the residual sums of squares of the model and the auxiliary
+// regression and is referred to F on maxLag and n - p - maxLag degrees of
+// freedom, which is the better approximation in small samples. The
+// observations must be in time order.
+func BreuschGodfrey(m Summary, maxLag int) (lm, f TestResult, err error) {
+	x := m.Data().X
+	n, p := x.Dims()
+	if maxLag < 1 || maxLag >= n {
+		return TestResult{}, TestResult{}, fmt.Errorf("lag %d is out of range for %d observations", maxLag, n)
+	}
+	if n <= p+maxLag {
+		return TestResult{}, TestResult{}, fmt.Errorf("%w: %d observations for %d coefficients of the auxiliary regression", TooFewObservationsError, n, p+maxLag)
+	}
+	residuals := m.Residuals()
+	rss := sum(prod(residuals, residuals))
+	if rss == 0 {
+		return TestResult{}, TestResult{}, fmt.Errorf("residuals are identically zero")
+	}
+
+	z := mat.NewDense(n, p+maxLag, nil)
+	z.Copy(x)
+	for lag := 1; lag <= maxLag; lag++ {
+		for t := lag; t < n; t++ {
+			z.Set(t, p+lag-1, residuals[t-lag])
+		}
+	}
+	fit, err := leastSquares(z, mat.NewDense(n, 1, append([]float64(nil), residuals...)))
+	if err != nil {
+		return TestResult{}, TestResult{}, err
+	}
+
+	df := float64(maxLag)
+	stat := float64(n) * sum(prod(fit.fitted, fit.fitted)) / rss
+	lm = TestResult{
+		Statistic: stat,
+		DF:        df,
+		PValue:    chiSquareSurvival(stat, df),
+	}
+	auxiliary := sum(prod(fit.residuals, fit.residuals))
+	df2 := float64(n - p - maxLag)
+	stat = ((rss - auxiliary) / df) / (auxiliary / df2)
+	f = TestResult{
+		Statistic: stat,
+		DF:        df,
+		DF2:       df2,
+		PValue:    fSurvival(stat, df, df2),
+	}
+	return lm, f, nil
+}
+
+// autocorrelations returns the sample autocorrelations r_1, ..., r_maxLag of
+// x, those of ACF after r_0, for lags that checkLags allows.
+func autocorrelations(x []float64, maxLag int) []float64 {
+	d := subtractMean(x)
+	c0 := sum(prod(d, d))
+	r := make([]float64, maxLag)
+	for k := 1; k <= maxLag; k++ {
+		r[k-1] = sum(prod(d[:len(d)-k], d[k:])) / c0
+	}
+	return r
+}
+
+// LjungBox tests a series, such as the residuals of a model in time order,
+// for autocorrelation at any lag up to maxLag, as R's Box.test with type =
+// "Ljung-Box":
+//
+// Q = n(n + 2) \sum_{k=1}^{maxLag} r_k^2 / (n - k)
+//
+// for the sample autocorrelations r_k, referred to the chi-squared
+// distribution on maxLag - fitdf degrees of freedom. For the residuals of an
+// ARMA(p, q) model fitdf is p + q, the number of its coefficients; otherwise
+// it is 0.
+func LjungBox(residuals []float64, maxLag int, fitdf int) (TestResult, error) {
+	n := len(residuals)
+	if err := checkLags(residuals, maxLag); err != nil {
+		return TestResult{}, err
+	}
+	if fitdf < 0 || fitdf >= maxLag {
+		return TestResult{}, fmt.Errorf("fitdf %d leaves no degrees of freedom at lag %d", fitdf, maxLag)
+	}
+	q := 0.0
+	for k, r := range autocorrelations(residuals, maxLag) {
+		q += r * r / float64(n-k-1)
+	}
+	q *= float64(n * (n + 2))
+	df := float64(maxLag - fitdf)
+	return TestResult{
+		Statistic: q,
+		DF:        df,
+		PValue:    chiSquareSurvival(q, df),
+	}, nil
+}
+
+// checkLags checks that a series of n observations has autocorrelations at
+// lags up to maxLag, which must be from 1 to n - 1, and that it isn't
+// constant.
+func checkLags(x []float64, maxLag int) error {
+	if maxLag < 1 || maxLag >= len(x) {
+		return fmt.Errorf("lag %d is out of range for %d observations", maxLag, len(x))
+	}
+	if centralMoment(x, 2) == 0 {
+		return fmt.Errorf("the series is constant")
+	}
+	return nil
+}
+
+// ACF returns the sample autocorrelation function of x at lags 0 to maxLag,
+// as R's acf: the kth element is
+//
+// r_k = \frac{\sum_{t=1}^{n-k} (x_t - \bar{x})(x_{t+k} - \bar{x})}{\sum_{t=1}^n (x_t - \bar{x})^2}
+//
+// so that the first is r_0 = 1. Dividing every lag by the same sum, rather
+// than by the n - k terms of its own, keeps the function positive definite.
+// The lags run from 1 to n - 1.
+func ACF(x []float64, maxLag int) ([]float64, error) {
+	if err := checkLags(x, maxLag); err != nil {
+		return nil, err
+	}
+	return append([]float64{1}, autocorrelations(x, maxLag)...), nil
+}
+
+// PACF returns the sample partial autocorrelation function of x at lags 0 to
+// maxLag: the kth element is the last coefficient \phi_{kk} of the AR(k)
+// model fit to the autocorrelations of ACF by the Durbin-Levinson recursion
+//
+// \phi_{kk} = \frac{r_k - \sum_{j=1}^{k-1} \phi_{k-1,j} r_{k-j}}{1 - \sum_{j=1}^{k-1} \phi_{k-1,j} r_j}
+//
+// as R's pacf. The partial autocorrelation at lag 0 isn't defined; it is 1 by
+// convention, so that PACF is indexed by lag as ACF is, where R's pacf starts
+// at lag 1.
+func PACF(x []float64, maxLag int) ([]float64, error) {
+	if err := checkLags(x, maxLag); err != nil {
+		return nil, err
+	}
+	r := append([]float64{1}, autocorrelations(x, maxLag)...)
+	pacf := make([]float64, maxLag+1)
+	pacf[0] = 1
+	var phi []float64 // \phi_{k-1,1}, ..., \phi_{k-1,k-1}
+	for k := 1; k <= maxLag; k++ {
+		num, den := r[k], 1.0
+		for j, p := range phi {
+			num -= p * r[k-1-j]
+			den -= p * r[j+1]
+		}
+		kk := num / den
+		next := make([]float64, k)
+		for j, p := range phi {
+			next[j] = p - kk*phi[k-2-j]
+		}
+		next[k-1] = kk
+		phi, pacf[k] = next, kk
+	}
+	return pacf, nil
+}
+
+// Correlogram holds the autocorrelation and partial autocorrelation functions
+// of a series, indexed by lag from 0, as ACF and PACF, with the bound
+// 1.96 / \sqrt{n} beyond which an autocorrelation is significant at the 5%
+// level for white noise, the dashed lines of R's plot.acf.
+type Correlogram struct {
+	ACF, PACF []float64
+	Bound     float64
+}
+
+// ResidualCorrelogram returns the correlogram of the residuals of the model
+// to lag maxLag. The observations must be in time order.
+func ResidualCorrelogram(m Summary, maxLag int) (*Correlogram, error) {
+	residuals := m.Residuals()
+	acf, err := ACF(residuals, maxLag)
+	if err != nil {
+		return nil, err
+	}
+	pacf, err := PACF(residuals, maxLag)
+	if err != nil {
+		return nil, err
+	}
+	return &Correlogram{
+		ACF:   acf,
+		PACF:  pacf,
+		Bound: NormalQuantile(0.975) / math.Sqrt(float64(len(residuals))),
+	}, nil
+}
@@ -0,0 +1,274 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// simulatedDurbinWatson returns the fraction of fits of pure noise on the
+// stackloss design whose statistic at the given lag is at most d.
+func simulatedDurbinWatson(t *testing.T, d float64, lag, reps int) float64 {
+	rng := rand.New(rand.NewSource(1))
+	below := 0
+	for rep := 0; rep < reps; rep++ {
+		response := make([]float64, len(y))
+		for i := range response {
+			response[i] = rng.NormFloat64()
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(data), response)
+		assert.Equal(t, nil, err)
+		e := s.Residuals()
+		num := 0.0
+		for i := lag; i < len(e); i++ {
+			num += (e[i] - e[i-lag]) * (e[i] - e[i-lag])
+		}
+		if num/sum(prod(e, e)) <= d {
+			below++
+		}
+	}
+	return float64(below) / float64(reps)
+}
+
+func TestDurbinWatsonStatistic(t *testing.T) {
+	stat, _, err := DurbinWatson(summary)
+	assert.Equal(t, nil, err)
+	assertClose(t, stat, DW(summary), 1e-12)
+
+	e := summary.Residuals()
+	num, den := 0.0, 0.0
+	for i := range e {
+		den += e[i] * e[i]
+		if i >= 2 {
+			num += (e[i] - e[i-2]) * (e[i] - e[i-2])
+		}
+	}
+	stat, _, err = DurbinWatson(summary, 2)
+	assert.Equal(t, nil, err)
+	assertClose(t, stat, num/den, 1e-12)
+}
+
+func TestDurbinWatsonExact(t *testing.T) {
+	// lmtest::dwtest(lm(stack.loss ~ ., stackloss)): DW = 1.4851, p-value = 0.04346
+	stat, pval, err := DurbinWatson(summary)
+	assert.Equal(t, nil, err)
+	assertClose(t, stat, 1.4851, 1e-4)
+	assertClose(t, pval, 0.04346, 1e-4)
+
+	for _, lag := range []int{1, 4} {
+		stat, pval, err = DurbinWatson(summary, lag)
+		assert.Equal(t, nil, err)
+		assert.T(t, pval > 0 && pval < 1)
+		assertClose(t, pval, simulatedDurbinWatson(t, stat, lag, 10000), 0.02)
+	}
+}
+
+func TestDurbinWatsonApproximation(t *testing.T) {
+	// just above the cutoff the normal approximation should be close to exact
+	s := simulatedSummary(exactDurbinWatsonRows, 3, 7)
+	for _, d := range []float64{1.5, 1.7, 2, 2.3} {
+		exact, err := dwExact(s, d, 1)
+		assert.Equal(t, nil, err)
+		approx, err := dwApprox(s, d, 1)
+		assert.Equal(t, nil, err)
+		assertClose(t, approx, exact, 0.01)
+	}
+}
+
+func TestDurbinWatsonAutocorrelated(t *testing.T) {
+	// AR(1) errors with rho = .8 should be detected, white noise should not
+	rng := rand.New(rand.NewSource(3))
+	n := 60
+	rows := make([][]float64, n)
+	ar, white := make([]float64, n), make([]float64, n)
+	e := 0.0
+	for i := range rows {
+		rows[i] = []float64{float64(i), rng.NormFloat64()}
+		e = 0.8*e + rng.NormFloat64()
+		ar[i] = 1 + 0.5*rows[i][1] + e
+		white[i] = 1 + 0.5*rows[i][1] + rng.NormFloat64()
+	}
+
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), ar)
+	assert.Equal(t, nil, err)
+	_, pval, err := DurbinWatson(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, pval < 0.001)
+
+	_, s, err = NewOlsTrainer().Train(NewDataFrame(rows), white)
+	assert.Equal(t, nil, err)
+	_, pval, err = DurbinWatson(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, pval > 0.01)
+}
+
+func TestDurbinWatsonLagRange(t *testing.T) {
+	_, _, err := DurbinWatson(summary, 0)
+	assert.NotEqual(t, nil, err)
+	_, _, err = DurbinWatson(summary, 21)
+	assert.NotEqual(t, nil, err)
+	_, _, err = DurbinWatson(summary, 1, 2)
+	assert.NotEqual(t, nil, err)
+}
+
+// ar2Regression returns a regression of 40 observations whose errors follow
+// e_t = 0.5 e_{t-1} + 0.3 e_{t-2} + u_t, for a deterministic u_t in [-1, 1].
+func ar2Regression() (*DataFrame, []float64) {
+	n := 40
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	e1, e2 := 0.0, 0.0
+	for t := 0; t < n; t++ {
+		u := float64(t*7919%101)/50 - 1
+		e := 0.5*e1 + 0.3*e2 + u
+		e1, e2 = e, e1
+		x := float64(t*37%23) / 4
+		rows[t] = []float64{x}
+		response[t] = 2 + 0.5*x + e
+	}
+	return NewDataFrame(rows), response
+}
+
+func TestBreuschGodfrey(t *testing.T) {
+	// the LM and F statistics of lmtest's bgtest(fit, order = k) and
+	// bgtest(fit, order = k, type = "F") for k from 1 to 4, hand-derived from
+	// the auxiliary regressions on the lagged residuals, with the missing lags
+	// zero, in exact rational arithmetic; lmtest hasn't been run against these
+	wantLM := []float64{2.92795561149063, 10.020317141809839, 12.000271336952592, 12.657346760609013}
+	wantF := []float64{2.9222655349088864, 6.01626472854108, 5.000161511655974, 3.9347844747627327}
+	x, response := ar2Regression()
+	_, s, err := NewOlsTrainer().Train(x, response)
+	assert.Equal(t, nil, err)
+	for k := 1; k <= 4; k++ {
+		lm, f, err := BreuschGodfrey(s, k)
+		assert.Equal(t, nil, err)
+		assertClose(t, lm.Statistic, wantLM[k-1], 1e-9)
+		assert.Equal(t, float64(k), lm.DF)
+		assertClose(t, lm.PValue, chiSquareSurvival(wantLM[k-1], float64(k)), 1e-12)
+		assertClose(t, f.Statistic, wantF[k-1], 1e-9)
+		assert.Equal(t, float64(k), f.DF)
+		assert.Equal(t, float64(38-k), f.DF2)
+		assertClose(t, f.PValue, fSurvival(wantF[k-1], float64(k), float64(38-k)), 1e-12)
+	}
+	// on two degrees of freedom the chi-squared survival is exp(-x / 2)
+	lm, _, _ := BreuschGodfrey(s, 2)
+	assertClose(t, lm.PValue, math.Exp(-wantLM[1]/2), 1e-12)
+	assert.T(t, lm.PValue < 0.01)
+
+	for _, k := range []int{0, 40} {
+		_, _, err := BreuschGodfrey(s, k)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, err = BreuschGodfrey(s, 38)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+}
+
+func TestBreuschGodfreyIndependent(t *testing.T) {
+	// independent errors are rejected at about the nominal rate
+	rng := rand.New(rand.NewSource(8))
+	rejected := 0
+	for trial := 0; trial < 200; trial++ {
+		rows := make([][]float64, 50)
+		response := make([]float64, 50)
+		for i := range rows {
+			rows[i] = []float64{rng.NormFloat64()}
+			response[i] = 1 + rows[i][0] + rng.NormFloat64()
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		_, f, err := BreuschGodfrey(s, 3)
+		assert.Equal(t, nil, err)
+		if f.PValue < 0.05 {
+			rejected++
+		}
+	}
+	assert.T(t, rejected >= 3 && rejected <= 20)
+}
+
+func TestLjungBox(t *testing.T) {
+	// n (n + 2) \sum_k r_k^2 / (n - k), the statistic of R's Box.test(
+	// stack.loss, lag = 4, type = "Ljung-Box") and with lag = 10, fitdf = 2,
+	// hand-derived in exact rational arithmetic with the p-values from a
+	// 50-digit incomplete gamma function; R hasn't been run against these
+	res, err := LjungBox(y, 4, 0)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 27.71251330782953, 1e-10)
+	assert.Equal(t, 4.0, res.DF)
+	assertClose(t, res.PValue, 1.4263088894724829e-05, 1e-12)
+	assertCloseSlices(t, autocorrelations(y, 4), []float64{0.7824190660293205, 0.5775872460642563, 0.3494894436810026, 0.20294915038782116}, 1e-12)
+
+	res, err = LjungBox(y, 10, 2)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 31.174422295016903, 1e-10)
+	assert.Equal(t, 8.0, res.DF)
+	assertClose(t, res.PValue, 0.000130805830499711, 1e-12)
+
+	// the residuals of the stackloss fit show no autocorrelation at the 5% level
+	res, err = LjungBox(summary.Residuals(), 4, 0)
+	assert.Equal(t, nil, err)
+	assert.T(t, res.PValue > 0.05)
+
+	for _, c := range [][2]int{{0, 0}, {21, 0}, {4, 4}, {4, -1}} {
+		_, err := LjungBox(y, c[0], c[1])
+		assert.NotEqual(t, nil, err)
+	}
+	_, err = LjungBox(rep(2, 10), 3, 0)
+	assert.NotEqual(t, nil, err)
+}
+
+// ar1Series returns 30 observations of x_t = 0.6 x_{t-1} + u_t, for u_t the
+// uniform draws of the minimal standard generator from a seed of 1, less 1/2.
+func ar1Series() []float64 {
+	x := make([]float64, 30)
+	prev, s := 0.0, int64(1)
+	for t := range x {
+		s = s * 16807 % 2147483647
+		prev = 0.6*prev + float64(s)/2147483647 - 0.5
+		x[t] = prev
+	}
+	return x
+}
+
+func TestACF(t *testing.T) {
+	// the autocorrelations of R's acf(x, lag.max = 6) and, by the
+	// Durbin-Levinson recursion, the partial autocorrelations of pacf(x,
+	// lag.max = 6), hand-derived in exact rational arithmetic; R hasn't been
+	// run against these
+	x := ar1Series()
+	acf, err := ACF(x, 6)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, acf, []float64{1, 0.5773469014287402, 0.18638195750267933, -0.02167642871214289, -0.09893805910881893, -0.14178407163627418, -0.20038288869668544}, 1e-10)
+	pacf, err := PACF(x, 6)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, pacf, []float64{1, 0.5773469014287402, -0.22041994489493622, -0.04058684660095601, -0.03896457403887374, -0.08291454263985897, -0.12502102246401023}, 1e-10)
+
+	// the partial autocorrelation at lag 2 is that of the AR(2) fit
+	r1, r2 := acf[1], acf[2]
+	assertClose(t, pacf[2], (r2-r1*r1)/(1-r1*r1), 1e-12)
+
+	for _, lag := range []int{0, 30} {
+		_, err := ACF(x, lag)
+		assert.NotEqual(t, nil, err)
+		_, err = PACF(x, lag)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err = ACF(x, 29)
+	assert.Equal(t, nil, err)
+	_, err = PACF(rep(1, 5), 2)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestResidualCorrelogram(t *testing.T) {
+	c, err := ResidualCorrelogram(summary, 5)
+	assert.Equal(t, nil, err)
+	acf, _ := ACF(summary.Residuals(), 5)
+	pacf, _ := PACF(summary.Residuals(), 5)
+	assert.Equal(t, acf, c.ACF)
+	assert.Equal(t, pacf, c.PACF)
+	assertClose(t, c.Bound, 1.959963984540054/math.Sqrt(21), 1e-12)
+	_, err = ResidualCorrelogram(summary, 21)
+	assert.NotEqual(t, nil, err)
+}
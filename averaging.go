@@ -0,0 +1,111 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Candidate is one of the models that AverageModels averages: the model
+// and the summary of its fit, as a Trainer returns them, and a name.
+type Candidate struct {
+	Name    string
+	Model   *OLS
+	Summary Summary
+}
+
+// ModelAverage is the average of the predictions of a set of candidate
+// models, weighted by the evidence for each. See AverageModels.
+type ModelAverage struct {
+	// Names are the names of the candidates and Weights their weights, in
+	// the order given, which sum to one.
+	Names   []string
+	Weights []float64
+
+	// Predictions are the weighted averages of the predictions of the
+	// candidates for each row of the new data, and SE their unconditional
+	// standard errors.
+	Predictions, SE []float64
+}
+
+// AverageModels averages the predictions of the candidates for each row of x
+// by their Akaike weights, rather than predicting with the best of them alone,
+// so that the uncertainty of the choice between them is accounted for. The
+// weight of candidate k is
+//
+// w_k = \frac{\exp(-\Delta_k / 2)}{\sum_j \exp(-\Delta_j / 2)}
+//
+// with \Delta_k its criterion less the smallest of the candidates', the
+// AkaikeWeight of CompareFits by AIC if criterion is nil, or by BIC or
+// another Criterion otherwise. The standard error of an averaged prediction
+// \bar\mu = \sum_k w_k \hat\mu_k is the unconditional one of Buckland et al.
+// (1997),
+//
+// \hat{se}(\bar\mu) = \sum_k w_k \sqrt{\hat{se}(\hat\mu_k)^2 + (\hat\mu_k - \bar\mu)^2}
+//
+// with \hat{se}(\hat\mu_k) the SEFit of the candidate's fitted mean, so that
+// it counts the variance between the candidates as well as within each.
+//
+// The columns of x are matched to those of each candidate by name, as by
+// PredictFrame, so that x must have labels and every column any candidate
+// uses; nested candidates use some of them each. The candidates must have
+// been fit to the same response, as for CompareFits, or it is a
+// DimensionError.
+func AverageModels(candidates []Candidate, x *DataFrame, criterion Criterion) (*ModelAverage, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no models to average")
+	}
+	if criterion == nil {
+		criterion = AIC
+	}
+	avg := &ModelAverage{
+		Names:       make([]string, len(candidates)),
+		Weights:     make([]float64, len(candidates)),
+		Predictions: make([]float64, x.Rows()),
+		SE:          make([]float64, x.Rows()),
+	}
+	scores := make([]float64, len(candidates))
+	best := math.Inf(1)
+	for k, c := range candidates {
+		if err := sameResponse(c.Summary, candidates[0].Summary); err != nil {
+			return nil, fmt.Errorf("models %q and %q: %w", c.Name, candidates[0].Name, err)
+		}
+		avg.Names[k] = c.Name
+		scores[k] = criterion(c.Summary)
+		best = math.Min(best, scores[k])
+	}
+	total := 0.0
+	for k, score := range scores {
+		avg.Weights[k] = math.Exp(-(score - best) / 2)
+		total += avg.Weights[k]
+	}
+
+	predictions := make([][]float64, len(candidates))
+	se := make([][]float64, len(candidates))
+	for k, c := range candidates {
+		avg.Weights[k] /= total
+		columns, err := c.Model.frameColumns()
+		if err != nil {
+			return nil, fmt.Errorf("model %q: %w", c.Name, err)
+		}
+		aligned, err := x.Select(columns...)
+		if err != nil {
+			return nil, fmt.Errorf("model %q: %w", c.Name, err)
+		}
+		if predictions[k], err = c.Model.PredictAll(aligned); err != nil {
+			return nil, fmt.Errorf("model %q: %w", c.Name, err)
+		}
+		if se[k], err = c.Model.SEFitAll(aligned); err != nil {
+			return nil, fmt.Errorf("model %q: %w", c.Name, err)
+		}
+		for i, mu := range predictions[k] {
+			avg.Predictions[i] += avg.Weights[k] * mu
+		}
+	}
+	for k, w := range avg.Weights {
+		for i, mu := range predictions[k] {
+			d := mu - avg.Predictions[i]
+			avg.SE[i] += w * math.Sqrt(se[k][i]*se[k][i]+d*d)
+		}
+	}
+	return avg, nil
+}
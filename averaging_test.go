@@ -0,0 +1,133 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// nestedData returns the predictors u, v and w, of which the response depends
+// strongly on u, weakly on v and not at all on w.
+func nestedData(rng *rand.Rand, n int) ([][]float64, []float64) {
+	rows := make([][]float64, n)
+	z := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		z[i] = 1 + 2*rows[i][0] + 0.25*rows[i][1] + rng.NormFloat64()
+	}
+	return rows, z
+}
+
+// nestedCandidates fits the nested models of u, u + v and u + v + w, and the
+// model of w alone.
+func nestedCandidates(t *testing.T, rows [][]float64, z []float64) []Candidate {
+	labels := []string{"u", "v", "w"}
+	var candidates []Candidate
+	for _, columns := range [][]string{{"u"}, {"u", "v"}, {"u", "v", "w"}, {"w"}} {
+		x, err := NewDataFrame(rows, labels).Select(columns...)
+		assert.Equal(t, nil, err)
+		m, s, err := NewOlsTrainer().Train(x, z)
+		assert.Equal(t, nil, err)
+		candidates = append(candidates, Candidate{Name: strings.Join(columns, " + "), Model: m.(*OLS), Summary: s})
+	}
+	return candidates
+}
+
+func TestAverageModels(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	rows, z := nestedData(rng, 40)
+	candidates := nestedCandidates(t, rows, z)
+	heldOut, truth := nestedData(rng, 500)
+	x := NewDataFrame(heldOut, []string{"u", "v", "w"})
+
+	for _, criterion := range []Criterion{nil, BIC} {
+		avg, err := AverageModels(candidates, x, criterion)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"u", "u + v", "u + v + w", "w"}, avg.Names)
+
+		// the weights are those of the differences of the criterion by hand
+		score := criterion
+		if score == nil {
+			score = AIC
+		}
+		best := math.Inf(1)
+		for _, c := range candidates {
+			best = math.Min(best, score(c.Summary))
+		}
+		want := make([]float64, len(candidates))
+		total := 0.0
+		for k, c := range candidates {
+			want[k] = math.Exp(-(score(c.Summary) - best) / 2)
+			total += want[k]
+		}
+		assertCloseSlices(t, avg.Weights, multSlice(want, 1/total), 1e-12)
+		assertClose(t, sum(avg.Weights), 1, 1e-12)
+		assert.T(t, avg.Weights[3] < 1e-10)
+
+		// Buckland's standard error of the first prediction
+		mu := make([]float64, len(candidates))
+		fitSE := make([]float64, len(candidates))
+		mean := 0.0
+		for k, c := range candidates {
+			columns, err := x.Select(c.Model.Names()[1:]...)
+			assert.Equal(t, nil, err)
+			row := columns.GetRow(0)
+			mu[k] = c.Model.Predict(row)
+			fitSE[k], err = c.Model.SEFit(row)
+			assert.Equal(t, nil, err)
+			mean += avg.Weights[k] * mu[k]
+		}
+		se := 0.0
+		for k := range candidates {
+			se += avg.Weights[k] * math.Sqrt(fitSE[k]*fitSE[k]+(mu[k]-mean)*(mu[k]-mean))
+		}
+		assertClose(t, avg.Predictions[0], mean, 1e-12)
+		assertClose(t, avg.SE[0], se, 1e-12)
+		assert.T(t, avg.SE[0] > fitSE[0]*avg.Weights[0])
+
+		// and averaging predicts the held out data better than the worst model
+		worst, err := PredictFrame(candidates[3].Model, x)
+		assert.Equal(t, nil, err)
+		assert.T(t, rmse(avg.Predictions, truth) < rmse(worst, truth))
+	}
+}
+
+func rmse(predictions, truth []float64) float64 {
+	total := 0.0
+	for i, p := range predictions {
+		total += (p - truth[i]) * (p - truth[i])
+	}
+	return math.Sqrt(total / float64(len(truth)))
+}
+
+func TestAverageModelsErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	rows, z := nestedData(rng, 30)
+	candidates := nestedCandidates(t, rows, z)
+	x := NewDataFrame(rows, []string{"u", "v", "w"})
+
+	// a single candidate is its own prediction
+	avg, err := AverageModels(candidates[:1], x, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []float64{1}, avg.Weights)
+	want, err := PredictFrame(candidates[0].Model, x)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, avg.Predictions, want, 1e-12)
+
+	_, err = AverageModels(nil, x, nil)
+	assert.NotEqual(t, nil, err)
+	other := append([]float64(nil), z...)
+	other[0]++
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(rows, []string{"u", "v", "w"}), other)
+	assert.Equal(t, nil, err)
+	_, err = AverageModels(append(candidates, Candidate{Name: "other", Model: m.(*OLS), Summary: s}), x, nil)
+	assert.T(t, errors.Is(err, DimensionError), err)
+	onlyU, err := x.Select("u")
+	assert.Equal(t, nil, err)
+	_, err = AverageModels(candidates, onlyU, nil)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,203 @@
+package glasso
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// backendReport writes the diagnostics and fits of a few models to every
+// digit, or their errors, to pin them against changes to the matrix backend.
+type backendReport struct {
+	b strings.Builder
+}
+
+func (r *backendReport) add(name string, v []float64, err error) {
+	if err != nil {
+		r.b.WriteString(name + ": error " + err.Error() + "\n")
+		return
+	}
+	r.b.WriteString(name + ":")
+	for _, x := range v {
+		r.b.WriteString(" " + strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	r.b.WriteString("\n")
+}
+
+func (r *backendReport) frame(name string, f *DataFrame, err error) {
+	if err != nil {
+		r.add(name, nil, err)
+		return
+	}
+	var v []float64
+	for i := 0; i < f.Rows(); i++ {
+		v = append(v, f.GetRow(i)...)
+	}
+	r.add(name, v, nil)
+}
+
+func (r *backendReport) test(name string, result TestResult, err error) {
+	r.add(name, []float64{result.Statistic, result.PValue}, err)
+}
+
+// TestBackendGolden compares the outputs of the current backend to
+// testdata/backend.golden, which holds the outputs of this test in a checkout
+// of 7a23942, the last commit on gonum/matrix/mat64, with its dependencies
+// pinned at github.com/gonum/matrix v0.0.0-20181209220409-c518dec07be9. The
+// test compiles unchanged against that tree; to regenerate the file, run it
+// there with got written to the file. -update doesn't rewrite it here, since
+// the output of the port would pin it to itself.
+func TestBackendGolden(t *testing.T) {
+	r := &backendReport{}
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, wls, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, ill, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	for _, c := range []struct {
+		name string
+		m    Summary
+	}{{"stackloss", ols}, {"wls", wls}, {"longley", ill}} {
+		m := c.m
+		name := func(s string) string { return c.name + " " + s }
+		r.add(name("coefficients"), m.Coefficients(), nil)
+		r.add(name("residuals"), m.Residuals(), nil)
+		r.add(name("fitted"), m.Yhat(), nil)
+		r.add(name("loglik"), []float64{LogLikelihood(m), AIC(m), BIC(m)}, nil)
+		v, err := LeveragePoints(m)
+		r.add(name("leverage"), v, err)
+		v, err = CooksDistance(m)
+		r.add(name("cooks"), v, err)
+		v, err = InternallyStudentizedResiduals(m)
+		r.add(name("studentized"), v, err)
+		v, err = ExternallyStudentizedResiduals(m)
+		r.add(name("rstudent"), v, err)
+		v, err = DFFITS(m)
+		r.add(name("dffits"), v, err)
+		f, err := DFBETAS(m)
+		r.frame(name("dfbetas"), f, err)
+		v, err = COVRATIO(m)
+		r.add(name("covratio"), v, err)
+		v, err = PressResiduals(m)
+		r.add(name("press"), v, err)
+		f, err = VarCov(m)
+		r.frame(name("vcov"), f, err)
+		for _, kind := range []HCKind{HC0, HC1, HC2, HC3} {
+			f, err = RobustVCov(m, kind)
+			r.frame(name(kind.String()), f, err)
+		}
+		f, err = NeweyWestVCov(m, 2)
+		r.frame(name("newey-west"), f, err)
+		v, err = VIF(m)
+		r.add(name("vif"), v, err)
+		col, err := CollinearityDiagnostics(m, true)
+		assert.Equal(t, nil, err)
+		r.add(name("condition"), append(col.Values, col.Indices...), nil)
+		r.frame(name("proportions"), col.Proportions, nil)
+		ci, err := ConfInt(m, 0.95)
+		assert.Equal(t, nil, err)
+		for j, b := range ci {
+			r.add(name(fmt.Sprintf("confint %d", j)), b[:], nil)
+		}
+		dw, p, err := DurbinWatson(m)
+		r.add(name("durbin-watson"), []float64{dw, p}, err)
+		test, err := BreuschPagan(m, true)
+		r.test(name("breusch-pagan"), test, err)
+		test, err = WhiteTest(m)
+		r.test(name("white"), test, err)
+		test, err = ResetTest(m, []int{2, 3})
+		r.test(name("reset"), test, err)
+		lm, fTest, err := BreuschGodfrey(m, 2)
+		r.add(name("breusch-godfrey"), []float64{lm.Statistic, fTest.Statistic}, err)
+	}
+
+	// fits that factorize matrices of their own
+	x := NewDataFrame(longley)
+	pcr, err := FitPCR(x, longleyY, 3)
+	assert.Equal(t, nil, err)
+	r.add("pcr", pcr.Coefficients(), nil)
+	pls, err := FitPLS(x, longleyY, 3)
+	assert.Equal(t, nil, err)
+	r.add("pls", pls.Coefficients(), nil)
+	_, ridge, err := NewRidgeTrainer(&RidgeConfig{Lambda: 0.5, Standardize: true}).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	r.add("ridge", ridge.Coefficients(), nil)
+	_, huber, err := NewHuberTrainer(1.345).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	r.add("huber", huber.Coefficients(), nil)
+	tls, err := FitTLSMulti(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	r.add("tls", tls.Coefficients, nil)
+	d, err := Mahalanobis(NewDataFrame(data).X)
+	r.add("mahalanobis", d, err)
+	cov, err := VarCov(ols)
+	assert.Equal(t, nil, err)
+	glasso, err := GraphicalLasso(cov, 0.01)
+	assert.Equal(t, nil, err)
+	r.frame("graphical lasso", glasso.Precision, nil)
+
+	got := r.b.String()
+	if *update {
+		t.Log("testdata/backend.golden pins the mat64 backend; regenerate it from the tree before the port")
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "backend.golden"))
+	assert.Equal(t, nil, err)
+	compareBackend(t, got, string(want))
+}
+
+// svdLines are those of backendReport from a singular value decomposition,
+// whose LAPACK routines changed with the move from gonum/matrix/mat64, which
+// wrote testdata/backend.golden, to gonum.org/v1/gonum/mat. They agree to
+// within 1e-12 of the largest value of the line; every other line, from QR
+// and Cholesky factorizations, is identical to the last digit.
+var svdLines = map[string]bool{
+	"longley condition":   true,
+	"longley proportions": true,
+	"pcr":                 true,
+	"pls":                 true,
+	"ridge":               true,
+}
+
+func compareBackend(t *testing.T, got, want string) {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	if len(gotLines) != len(wantLines) {
+		t.Fatalf("got %d lines, want %d", len(gotLines), len(wantLines))
+	}
+	for k, line := range gotLines {
+		name := strings.SplitN(line, ":", 2)[0]
+		if line == wantLines[k] {
+			continue
+		}
+		if !svdLines[name] {
+			t.Errorf("got %q, want %q", line, wantLines[k])
+			continue
+		}
+		g, w := strings.Fields(line)[len(strings.Fields(name)):], strings.Fields(wantLines[k])[len(strings.Fields(name)):]
+		if len(g) != len(w) {
+			t.Errorf("got %q, want %q", line, wantLines[k])
+			continue
+		}
+		values := make([][2]float64, len(g))
+		largest := 0.0
+		for i := range g {
+			for c, field := range []string{g[i], w[i]} {
+				v, err := strconv.ParseFloat(field, 64)
+				assert.Equal(t, nil, err)
+				values[i][c] = v
+				largest = math.Max(largest, math.Abs(v))
+			}
+		}
+		for i, v := range values {
+			if math.Abs(v[0]-v[1]) > 1e-12*largest {
+				t.Errorf("%s: value %d is %v, want %v", name, i, v[0], v[1])
+			}
+		}
+	}
+}
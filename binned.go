@@ -0,0 +1,123 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ByVariable chooses what BinnedResiduals bins the observations by, and how.
+// The zero value bins by the fitted values into bins of equal counts.
+type ByVariable struct {
+	// Predictor is the name of the column of the design to bin by, one of
+	// PredictorNames, or "" for the fitted values.
+	Predictor string
+
+	// EqualWidth cuts the range of the variable into bins of equal width
+	// rather than at its quantiles.
+	EqualWidth bool
+}
+
+// Bin summarizes the residuals of the observations whose variable lies in
+// [From, To), or [From, To] for the last bin.
+type Bin struct {
+	From, To float64
+	Count    int
+
+	// Mean is the mean of the variable binned by, and MeanFitted that of the
+	// fitted values, which are the same when binning by the fitted values.
+	Mean, MeanFitted float64
+
+	MeanResidual, ResidualSD float64
+
+	// Interval is the 95% t interval for the mean residual,
+	//
+	// \bar e \pm t_{0.975, k - 1} s / \sqrt{k}
+	//
+	// for the k residuals of the bin, NaN with fewer than two.
+	Interval [2]float64
+}
+
+// BinnedResiduals buckets the observations of the model into bins of the
+// fitted values, or of a predictor, and summarizes the residuals of each, a
+// binned residual plot (Gelman & Hill, 2007) that shows a systematic misfit,
+// such as the U of a missing quadratic term or a funnel of
+// heteroskedasticity, when there are too many observations to plot each.
+//
+// The bins are cut at the quantiles k / bins of the variable, type 7 of R's
+// quantile, so that they hold about equal numbers of observations, or into
+// bins of equal width with by.EqualWidth. A bin without observations, between
+// tied quantiles or in a gap of the data, has a Count of zero and NaN
+// summaries.
+func BinnedResiduals(m Summary, bins int, by ByVariable) ([]Bin, error) {
+	if bins < 1 {
+		return nil, fmt.Errorf("%d bins: must be positive", bins)
+	}
+	fitted, residuals := m.Yhat(), m.Residuals()
+	n := len(residuals)
+	if n == 0 {
+		return nil, fmt.Errorf("no observations to bin")
+	}
+	v := fitted
+	if by.Predictor != "" {
+		j := -1
+		for k, name := range CoefficientNames(m) {
+			if name == by.Predictor && k != interceptOf(m) {
+				j = k
+			}
+		}
+		if j < 0 {
+			return nil, fmt.Errorf("%q is not one of the predictors %q", by.Predictor, PredictorNames(m))
+		}
+		v = m.Data().GetCol(j)
+	}
+	for i, x := range v {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("the variable binned by is %v for observation %d", x, i)
+		}
+	}
+
+	sorted := append([]float64(nil), v...)
+	sort.Float64s(sorted)
+	edges := make([]float64, bins+1)
+	for k := range edges {
+		if by.EqualWidth {
+			edges[k] = sorted[0] + (sorted[n-1]-sorted[0])*float64(k)/float64(bins)
+		} else {
+			edges[k] = quantile(sorted, float64(k)/float64(bins))
+		}
+	}
+	edges[0], edges[bins] = sorted[0], sorted[n-1]
+
+	members := make([][]int, bins)
+	for i, x := range v {
+		// the last bin whose lower edge is at most x, so that observations
+		// on tied edges fall in the last of the tied bins
+		k := sort.Search(bins, func(k int) bool { return edges[k+1] > x })
+		if k == bins {
+			k = bins - 1
+		}
+		members[k] = append(members[k], i)
+	}
+
+	result := make([]Bin, bins)
+	for k, rows := range members {
+		b := Bin{From: edges[k], To: edges[k+1], Count: len(rows)}
+		b.Mean, b.MeanFitted, b.MeanResidual, b.ResidualSD = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+		b.Interval = [2]float64{math.NaN(), math.NaN()}
+		x, f, e := make([]float64, len(rows)), make([]float64, len(rows)), make([]float64, len(rows))
+		for c, i := range rows {
+			x[c], f[c], e[c] = v[i], fitted[i], residuals[i]
+		}
+		if len(rows) > 0 {
+			b.Mean, b.MeanFitted, b.MeanResidual = mean(x), mean(f), mean(e)
+		}
+		if len(rows) > 1 {
+			b.ResidualSD = sd(e)
+			half := studentTQuantile((1+DefaultConfidenceLevel)/2, float64(len(rows)-1)) * b.ResidualSD / math.Sqrt(float64(len(rows)))
+			b.Interval = [2]float64{b.MeanResidual - half, b.MeanResidual + half}
+		}
+		result[k] = b
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestBinnedResiduals(t *testing.T) {
+	// a straight line fit to a parabola leaves residuals that are positive
+	// at both ends of the fitted values and negative in between
+	rng := rand.New(rand.NewSource(1))
+	n := 20000
+	rows := make([][]float64, n)
+	z := make([]float64, n)
+	for i := range rows {
+		x := 4*rng.Float64() - 2
+		rows[i] = []float64{x}
+		z[i] = 1 + 2*x + x*x + 0.5*rng.NormFloat64()
+	}
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(rows, []string{"x"}), z)
+	assert.Equal(t, nil, err)
+	bins, err := BinnedResiduals(m, 10, ByVariable{})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 10, len(bins))
+	total := 0
+	for k, b := range bins {
+		total += b.Count
+		if b.Count < n/10-1 || b.Count > n/10+1 {
+			t.Errorf("bin %d has %d observations, want about %d", k, b.Count, n/10)
+		}
+		assert.Equal(t, b.Mean, b.MeanFitted)
+		if k > 0 {
+			assert.Equal(t, bins[k-1].To, b.From)
+		}
+	}
+	assert.Equal(t, n, total)
+	for _, k := range []int{0, 9} {
+		if !(bins[k].Interval[0] > 0) {
+			t.Errorf("bin %d: interval %v for the mean residual, want it above zero", k, bins[k].Interval)
+		}
+	}
+	for _, k := range []int{4, 5} {
+		if !(bins[k].Interval[1] < 0) {
+			t.Errorf("bin %d: interval %v for the mean residual, want it below zero", k, bins[k].Interval)
+		}
+	}
+
+	// the summaries of a bin are those of its members
+	var e, f []float64
+	for i, v := range m.Yhat() {
+		if v >= bins[3].From && v < bins[3].To {
+			e = append(e, m.Residuals()[i])
+			f = append(f, v)
+		}
+	}
+	assert.Equal(t, len(e), bins[3].Count)
+	assertClose(t, bins[3].MeanFitted, mean(f), 1e-12)
+	assertClose(t, bins[3].MeanResidual, mean(e), 1e-12)
+	assertClose(t, bins[3].ResidualSD, sd(e), 1e-12)
+	half := studentTQuantile(0.975, float64(len(e)-1)) * sd(e) / math.Sqrt(float64(len(e)))
+	assertClose(t, bins[3].Interval[0], mean(e)-half, 1e-12)
+	assertClose(t, bins[3].Interval[1], mean(e)+half, 1e-12)
+
+	// the fitted values increase with x, so binning by it is the same
+	byX, err := BinnedResiduals(m, 10, ByVariable{Predictor: "x"})
+	assert.Equal(t, nil, err)
+	for k := range bins {
+		assert.Equal(t, bins[k].Count, byX[k].Count)
+		assertClose(t, byX[k].MeanResidual, bins[k].MeanResidual, 1e-12)
+		assertClose(t, byX[k].MeanFitted, m.Coefficients()[0]+m.Coefficients()[1]*byX[k].Mean, 1e-9)
+	}
+}
+
+func TestBinnedResidualsEmpty(t *testing.T) {
+	// a gap in x leaves the middle bins of equal width empty
+	x := [][]float64{{0}, {0.2}, {0.5}, {0.9}, {0.95}, {3}, {3.1}, {3.6}, {3.8}, {4}}
+	z := []float64{0.1, 0.3, 0.4, 1.1, 0.9, 3.2, 2.9, 3.7, 3.9, 4.1}
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(x), z)
+	assert.Equal(t, nil, err)
+	bins, err := BinnedResiduals(m, 4, ByVariable{Predictor: "x0", EqualWidth: true})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{5, 0, 0, 5}, []int{bins[0].Count, bins[1].Count, bins[2].Count, bins[3].Count})
+	assert.Equal(t, [4]float64{0, 1, 2, 3}, [4]float64{bins[0].From, bins[1].From, bins[2].From, bins[3].From})
+	assert.Equal(t, 4.0, bins[3].To)
+	assert.T(t, math.IsNaN(bins[1].MeanResidual) && math.IsNaN(bins[1].Interval[0]))
+
+	// tied quantiles leave bins empty, with every tie in one bin
+	x = [][]float64{{0}, {0}, {0}, {0}, {0}, {0}, {1}, {2}, {3}, {4}}
+	_, m, err = NewOlsTrainer().Train(NewDataFrame(x), z)
+	assert.Equal(t, nil, err)
+	bins, err = BinnedResiduals(m, 4, ByVariable{Predictor: "x0"})
+	assert.Equal(t, nil, err)
+	counts := make([]int, len(bins))
+	for k, b := range bins {
+		counts[k] = b.Count
+	}
+	assert.Equal(t, []int{0, 0, 7, 3}, counts)
+
+	// a single observation has no spread or interval
+	bins, err = BinnedResiduals(m, 10, ByVariable{Predictor: "x0", EqualWidth: true})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, bins[9].Count)
+	assert.T(t, !math.IsNaN(bins[9].MeanResidual) && math.IsNaN(bins[9].ResidualSD))
+
+	_, err = BinnedResiduals(m, 0, ByVariable{})
+	assert.NotEqual(t, nil, err)
+	_, err = BinnedResiduals(m, 4, ByVariable{Predictor: "(Intercept)"})
+	assert.NotEqual(t, nil, err)
+	_, err = BinnedResiduals(m, 4, ByVariable{Predictor: "x1"})
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,225 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// A Resampling is a way of drawing bootstrap samples from a regression.
+type Resampling int
+
+const (
+	// ResampleCases draws observations, rows of the design with their
+	// responses, with replacement.
+	ResampleCases Resampling = iota
+
+	// ResampleResiduals keeps the design fixed and adds residuals drawn with
+	// replacement to the fitted values.
+	ResampleResiduals
+
+	// ResampleWildRademacher keeps the design fixed and multiplies each
+	// residual by a random sign, so that each observation keeps its own
+	// variance, as the wild bootstrap of Wu (1986) and Liu (1988) does.
+	ResampleWildRademacher
+
+	// ResampleWildMammen is the wild bootstrap with the weights of Mammen
+	// (1993), -(\sqrt{5} - 1) / 2 with probability (\sqrt{5} + 1) / (2\sqrt{5})
+	// and (\sqrt{5} + 1) / 2 otherwise, which have a mean of zero and a
+	// variance and third moment of one.
+	ResampleWildMammen
+)
+
+// BootstrapResult holds the coefficients of the bootstrap resamples of a fit.
+type BootstrapResult struct {
+	Estimates    []float64  // the coefficients of the fit
+	Coefficients *DataFrame // the coefficients of each resample, a row per resample
+	Skipped      int        // resamples skipped since their design was rank deficient
+
+	deleted *mat.Dense // the coefficients of the fit without each observation
+}
+
+// Bootstrap draws resamples of a least squares fit and refits the model to
+// each of them, for the distribution of the coefficients. By default the
+// observations are resampled (ResampleCases); WithResampling chooses another
+// scheme, and WithSeed seeds the random numbers. The resamples are shared out
+// among the workers of WithParallelism, each drawing from a stream of its
+// own, so a seed gives the same resamples however many workers there are.
+// Case resamples with a rank deficient design are skipped and counted, and it
+// is an error if they all are.
+//
+// The wild bootstraps, which are valid when the errors are heteroskedastic,
+// weight the residuals adjusted for their leverage, e_i / (1 - h_ii) as in
+// HC3, unless WithLeverageAdjustment turns that off.
+func Bootstrap(m Summary, resamples int, opts ...Option) (*BootstrapResult, error) {
+	o := newOptions(opts)
+	if resamples < 2 {
+		return nil, fmt.Errorf("%d resamples are too few for a bootstrap", resamples)
+	}
+	wild := o.resampling == ResampleWildRademacher || o.resampling == ResampleWildMammen
+	if o.resampling != ResampleCases && o.resampling != ResampleResiduals && !wild {
+		return nil, fmt.Errorf("unknown resampling %d", o.resampling)
+	}
+	x, y := m.Data().X, m.Response()
+	n, p := x.Dims()
+	fitted, residuals := m.Yhat(), m.Residuals()
+	if wild && o.adjust {
+		h, err := LeveragePoints(m)
+		if err != nil {
+			return nil, err
+		}
+		adjusted := make([]float64, n)
+		for i, e := range residuals {
+			// the residual of an observation with a leverage of one is zero
+			if !isUnitLeverage(h[i]) {
+				adjusted[i] = e / (1 - h[i])
+			}
+		}
+		residuals = adjusted
+	}
+	deleted, err := deletedCoefficients(m)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BootstrapResult{
+		Estimates: append([]float64(nil), m.Coefficients()...),
+		deleted:   deleted,
+	}
+	coefficients := make([][]float64, resamples)
+	err = newResampler(o).run(resamples, func(b int, rng *rand.Rand) error {
+		design, response := x, mat.NewDense(n, 1, nil)
+		if o.resampling == ResampleCases {
+			design = mat.NewDense(n, p, nil)
+		}
+		for i := 0; i < n; i++ {
+			switch o.resampling {
+			case ResampleCases:
+				k := rng.Intn(n)
+				design.SetRow(i, x.RawRowView(k))
+				response.Set(i, 0, y[k])
+			case ResampleResiduals:
+				response.Set(i, 0, fitted[i]+residuals[rng.Intn(n)])
+			case ResampleWildRademacher:
+				w := 1.0
+				if rng.Intn(2) == 0 {
+					w = -1
+				}
+				response.Set(i, 0, fitted[i]+w*residuals[i])
+			case ResampleWildMammen:
+				w := (math.Sqrt(5) + 1) / 2
+				if rng.Float64() < (math.Sqrt(5)+1)/(2*math.Sqrt(5)) {
+					w = -(math.Sqrt(5) - 1) / 2
+				}
+				response.Set(i, 0, fitted[i]+w*residuals[i])
+			}
+		}
+		if o.resampling == ResampleCases && rankDeficient(design) {
+			return nil
+		}
+		if fit, err := leastSquares(design, response); err == nil {
+			coefficients[b] = fit.betas
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]float64
+	for _, betas := range coefficients {
+		if betas == nil {
+			result.Skipped++
+			continue
+		}
+		rows = append(rows, betas)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("every one of the %d resamples was rank deficient", resamples)
+	}
+	result.Coefficients = NewDataFrame(rows)
+	return result, nil
+}
+
+// StandardErrors returns the standard deviation of each coefficient over the resamples.
+func (b *BootstrapResult) StandardErrors() []float64 {
+	se := make([]float64, b.Coefficients.Cols())
+	for j := range se {
+		se[j] = sd(b.Coefficients.GetCol(j))
+	}
+	return se
+}
+
+// PercentileInterval returns the percentile confidence interval for each
+// coefficient at the given level: the (1 - level) / 2 and (1 + level) / 2
+// quantiles of its bootstrap distribution.
+func (b *BootstrapResult) PercentileInterval(level float64) ([][2]float64, error) {
+	if !(level > 0 && level < 1) {
+		return nil, fmt.Errorf("confidence level %v is not between 0 and 1", level)
+	}
+	intervals := make([][2]float64, b.Coefficients.Cols())
+	for j := range intervals {
+		sorted := b.sortedColumn(j)
+		intervals[j] = [2]float64{quantile(sorted, (1-level)/2), quantile(sorted, (1+level)/2)}
+	}
+	return intervals, nil
+}
+
+// BCaInterval returns the bias-corrected and accelerated confidence interval
+// of Efron (1987) for each coefficient at the given level. The quantiles of
+// the percentile interval are moved to
+//
+// \Phi(z_0 + \frac{z_0 + z_\alpha}{1 - a (z_0 + z_\alpha)})
+//
+// where the bias correction z_0 = \Phi^{-1}(#\{\beta^* < \hat\beta\} / B) and the
+// acceleration a comes from the jackknife coefficients \beta_{(i)},
+//
+// a = \frac{\sum (\bar\beta_{(.)} - \beta_{(i)})^3}{6 (\sum (\bar\beta_{(.)} - \beta_{(i)})^2)^{3/2}}
+//
+// An error is returned if an estimate is outside the range of its bootstrap
+// distribution, or, as a LeverageError, if an observation has a leverage of one.
+func (b *BootstrapResult) BCaInterval(level float64) ([][2]float64, error) {
+	if !(level > 0 && level < 1) {
+		return nil, fmt.Errorf("confidence level %v is not between 0 and 1", level)
+	}
+	intervals := make([][2]float64, b.Coefficients.Cols())
+	for j := range intervals {
+		sorted := b.sortedColumn(j)
+		below := sort.SearchFloat64s(sorted, b.Estimates[j])
+		if below == 0 || below == len(sorted) {
+			return nil, fmt.Errorf("estimate of coefficient %d is outside its bootstrap distribution", j)
+		}
+		z0 := NormalQuantile(float64(below) / float64(len(sorted)))
+
+		deleted := mat.Col(nil, j, b.deleted)
+		for _, v := range deleted {
+			if math.IsNaN(v) {
+				return nil, LeverageError
+			}
+		}
+		centered := subtractMean(deleted)
+		squares, cubes := 0.0, 0.0
+		for _, d := range centered {
+			squares += d * d
+			cubes -= d * d * d
+		}
+		a := 0.0
+		if squares > 0 {
+			a = cubes / (6 * math.Pow(squares, 1.5))
+		}
+
+		for k, alpha := range []float64{(1 - level) / 2, (1 + level) / 2} {
+			z := z0 + NormalQuantile(alpha)
+			intervals[j][k] = quantile(sorted, normalCDF(z0+z/(1-a*z)))
+		}
+	}
+	return intervals, nil
+}
+
+func (b *BootstrapResult) sortedColumn(j int) []float64 {
+	column := b.Coefficients.GetCol(j)
+	sort.Float64s(column)
+	return column
+}
@@ -0,0 +1,69 @@
+//go:build long
+// +build long
+
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestBootstrapCoverage checks by simulation that the bootstrap intervals for
+// the slope of a regression with skewed, centered exponential errors cover the
+// true slope about as often as they should. Run it with go test -tags long.
+func TestBootstrapCoverage(t *testing.T) {
+	const (
+		simulations = 400
+		resamples   = 999
+		n           = 40
+		slope       = 2.0
+	)
+	rng := rand.New(rand.NewSource(11))
+	covered := map[string]int{}
+	for s := 0; s < simulations; s++ {
+		rows := make([][]float64, n)
+		response := make([]float64, n)
+		for i := range rows {
+			rows[i] = []float64{rng.Float64() * 4}
+			response[i] = 1 + slope*rows[i][0] + rng.ExpFloat64() - 1
+		}
+		_, summary, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, resampling := range []Resampling{ResampleCases, ResampleResiduals} {
+			result, err := Bootstrap(summary, resamples, WithSeed(int64(s)), WithResampling(resampling))
+			if err != nil {
+				t.Fatal(err)
+			}
+			percentile, err := result.PercentileInterval(0.95)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bca, err := result.BCaInterval(0.95)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := "cases"
+			if resampling == ResampleResiduals {
+				name = "residuals"
+			}
+			if percentile[1][0] <= slope && slope <= percentile[1][1] {
+				covered[name+" percentile"]++
+			}
+			if bca[1][0] <= slope && slope <= bca[1][1] {
+				covered[name+" BCa"]++
+			}
+		}
+	}
+	// a binomial proportion of 400 has a standard error of about 0.011 at 0.95,
+	// and the bootstrap intervals undercover a little at this size
+	for _, name := range []string{"cases percentile", "cases BCa", "residuals percentile", "residuals BCa"} {
+		coverage := float64(covered[name]) / simulations
+		t.Logf("%s: coverage %.3f", name, coverage)
+		if math.IsNaN(coverage) || coverage < 0.88 || coverage > 0.99 {
+			t.Errorf("%s intervals covered the slope %.3f of the time, want about 0.95", name, coverage)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package glasso
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBootstrap(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Bootstrap(s, 500, WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, c := result.Coefficients.Rows(), result.Coefficients.Cols(); r != 500 || c != 4 || result.Skipped != 0 {
+		t.Fatalf("got %d x %d coefficients with %d skipped, want 500 x 4 with none skipped", r, c, result.Skipped)
+	}
+	again, err := Bootstrap(s, 500, WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mat.Equal(result.Coefficients.X, again.Coefficients.X) {
+		t.Errorf("bootstraps with the same seed differ")
+	}
+	other, err := Bootstrap(s, 500, WithSeed(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mat.Equal(result.Coefficients.X, other.Coefficients.X) {
+		t.Errorf("bootstraps with different seeds are the same")
+	}
+
+	vcov, err := VarCov(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analytic := StandardErrors(vcov)
+	for j, se := range result.StandardErrors() {
+		if ratio := se / analytic[j]; ratio < 0.5 || ratio > 2 {
+			t.Errorf("coefficient %d: bootstrap standard error %v is far from %v", j, se, analytic[j])
+		}
+	}
+
+	percentile, err := result.PercentileInterval(0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bca, err := result.BCaInterval(0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	betas := s.Coefficients()
+	for j := range betas {
+		for _, interval := range [][2]float64{percentile[j], bca[j]} {
+			if !(interval[0] < betas[j] && betas[j] < interval[1]) {
+				t.Errorf("coefficient %d: interval %v doesn't cover the estimate %v", j, interval, betas[j])
+			}
+		}
+	}
+	wider, err := result.PercentileInterval(0.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range betas {
+		if wider[j][0] > percentile[j][0] || wider[j][1] < percentile[j][1] {
+			t.Errorf("coefficient %d: 99%% interval %v is inside the 90%% interval %v", j, wider[j], percentile[j])
+		}
+	}
+}
+
+func TestBootstrapResiduals(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Bootstrap(s, 4000, WithResampling(ResampleResiduals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcov, err := VarCov(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the residuals are resampled with variance RSS / n rather than RSS / (n - p)
+	n, p := float64(len(y)), 4.0
+	analytic := StandardErrors(vcov)
+	betas := s.Coefficients()
+	for j, se := range result.StandardErrors() {
+		want := analytic[j] * math.Sqrt((n-p)/n)
+		if math.Abs(se-want) > 0.1*want {
+			t.Errorf("coefficient %d: bootstrap standard error %v, want about %v", j, se, want)
+		}
+		m := mean(result.Coefficients.GetCol(j))
+		if math.Abs(m-betas[j]) > 0.1*want {
+			t.Errorf("coefficient %d: bootstrap mean %v, want about %v", j, m, betas[j])
+		}
+	}
+}
+
+func TestBootstrapRankDeficient(t *testing.T) {
+	// only the first observation has the indicator, so about a third of the
+	// case resamples leave it out and can't estimate its coefficient
+	rng := rand.New(rand.NewSource(2))
+	rows := make([][]float64, 20)
+	response := make([]float64, 20)
+	for i := range rows {
+		indicator := 0.0
+		if i == 0 {
+			indicator = 1
+		}
+		rows[i] = []float64{rng.Float64(), indicator}
+		response[i] = 1 + rows[i][0] + 3*indicator + rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Bootstrap(s, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept := result.Coefficients.Rows(); result.Skipped < 40 || result.Skipped > 110 || kept+result.Skipped != 200 {
+		t.Errorf("kept %d and skipped %d of 200 resamples, want about a third skipped", kept, result.Skipped)
+	}
+	if _, err := result.PercentileInterval(0.95); err != nil {
+		t.Error(err)
+	}
+	if _, err := result.BCaInterval(0.95); err != LeverageError {
+		t.Errorf("BCa interval with a leverage of one: got error %v, want LeverageError", err)
+	}
+
+	// the residual bootstrap keeps the design, so it never skips
+	fixed, err := Bootstrap(s, 200, WithResampling(ResampleResiduals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.Skipped != 0 {
+		t.Errorf("residual bootstrap skipped %d resamples", fixed.Skipped)
+	}
+}
+
+func TestBootstrapInvalid(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Bootstrap(s, 1); err == nil {
+		t.Errorf("expected an error for a single resample")
+	}
+	if _, err := Bootstrap(s, 100, WithResampling(Resampling(9))); err == nil {
+		t.Errorf("expected an error for an unknown resampling")
+	}
+	result, err := Bootstrap(s, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range []float64{0, 1, -0.5, math.NaN()} {
+		if _, err := result.PercentileInterval(level); err == nil {
+			t.Errorf("expected an error for a percentile interval at level %v", level)
+		}
+		if _, err := result.BCaInterval(level); err == nil {
+			t.Errorf("expected an error for a BCa interval at level %v", level)
+		}
+	}
+}
+
+func TestBootstrapWild(t *testing.T) {
+	// the error variance grows away from the middle of x, where the
+	// observations pull hardest on the slope, so the residual bootstrap, which pools
+	// the residuals, understates the variance of the slope
+	rng := rand.New(rand.NewSource(5))
+	const n = 500
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	variances := make([]float64, n)
+	for i := range rows {
+		x := rng.Float64()
+		rows[i] = []float64{x}
+		variances[i] = (0.1 + 4*math.Abs(x-0.5)) * (0.1 + 4*math.Abs(x-0.5))
+		response[i] = 1 + 2*x + math.Sqrt(variances[i])*rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the true covariance (X'X)^-1 X' diag(\sigma_i^2) X (X'X)^-1 for the design
+	x := s.Data().X
+	xtx := &mat.Dense{}
+	xtx.Mul(x.T(), x)
+	bread := &mat.Dense{}
+	if err := bread.Inverse(xtx); err != nil {
+		t.Fatal(err)
+	}
+	omega := mat.NewDense(n, n, nil)
+	for i, v := range variances {
+		omega.Set(i, i, v)
+	}
+	meat, left, truth := &mat.Dense{}, &mat.Dense{}, &mat.Dense{}
+	left.Mul(x.T(), omega)
+	meat.Mul(left, x)
+	left.Reset()
+	left.Mul(bread, meat)
+	truth.Mul(left, bread)
+	want := StandardErrors(MatToDF(truth))
+
+	for _, resampling := range []Resampling{ResampleWildRademacher, ResampleWildMammen} {
+		result, err := Bootstrap(s, 2000, WithResampling(resampling), WithSeed(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, se := range result.StandardErrors() {
+			if math.Abs(se-want[j]) > 0.15*want[j] {
+				t.Errorf("resampling %d, coefficient %d: standard error %v, want about %v", resampling, j, se, want[j])
+			}
+		}
+		again, err := Bootstrap(s, 2000, WithResampling(resampling), WithSeed(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mat.Equal(result.Coefficients.X, again.Coefficients.X) {
+			t.Errorf("resampling %d: bootstraps with the same seed differ", resampling)
+		}
+		if _, err := result.BCaInterval(0.95); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// without the leverage adjustment the residuals are a little too small
+	adjusted, err := Bootstrap(s, 2000, WithResampling(ResampleWildRademacher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := Bootstrap(s, 2000, WithResampling(ResampleWildRademacher), WithLeverageAdjustment(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, r := adjusted.StandardErrors()[1], raw.StandardErrors()[1]; !(r < a) {
+		t.Errorf("slope standard error %v with the raw residuals is not below %v with the adjusted ones", r, a)
+	}
+
+	pooled, err := Bootstrap(s, 2000, WithResampling(ResampleResiduals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if se := pooled.StandardErrors()[1]; se > 0.8*want[1] {
+		t.Errorf("residual bootstrap slope standard error %v is not well below the true %v", se, want[1])
+	}
+}
+
+func TestBootstrapCancel(t *testing.T) {
+	// far more resamples than can be drawn before the deadline
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := Bootstrap(summary, 10000000, WithContext(ctx))
+	if err != context.DeadlineExceeded || result != nil {
+		t.Fatalf("got %v, %v, want the deadline's error", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v to stop", elapsed)
+	}
+	settleGoroutines(t, before)
+}
@@ -0,0 +1,139 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// BoxCox returns the Box-Cox transformation of the positive responses y,
+//
+// y^{(\lambda)} = \frac{y^\lambda - 1}{\lambda}
+//
+// which is log(y) at \lambda = 0. Responses that aren't positive are an
+// error; BoxCoxShifted transforms them after shifting them, and YeoJohnson
+// takes them as they are.
+func BoxCox(y []float64, lambda float64) ([]float64, error) {
+	if err := checkBoxCoxResponse(y); err != nil {
+		return nil, err
+	}
+	z := make([]float64, len(y))
+	for i, v := range y {
+		z[i] = boxCox(v, lambda)
+	}
+	return z, nil
+}
+
+// BoxCoxShifted returns the Box-Cox transformation of y + shift, for
+// responses that are zero or negative. The shift must make them all positive.
+func BoxCoxShifted(y []float64, lambda, shift float64) ([]float64, error) {
+	shifted := make([]float64, len(y))
+	for i, v := range y {
+		shifted[i] = v + shift
+	}
+	return BoxCox(shifted, lambda)
+}
+
+// InverseBoxCox returns the responses whose Box-Cox transformations are z, to
+// back-transform predictions, by
+//
+// y = (\lambda z + 1)^{1 / \lambda}
+//
+// or exp(z) at \lambda = 0. Values outside the range of the transformation,
+// where \lambda z + 1 is not positive, are NaN. Subtract the shift from the
+// result to invert BoxCoxShifted.
+func InverseBoxCox(z []float64, lambda float64) []float64 {
+	y := make([]float64, len(z))
+	for i, v := range z {
+		switch {
+		case lambda == 0:
+			y[i] = math.Exp(v)
+		case lambda*v+1 > 0:
+			y[i] = math.Exp(math.Log1p(lambda*v) / lambda)
+		default:
+			y[i] = math.NaN()
+		}
+	}
+	return y
+}
+
+// DefaultBoxCoxGrid returns the values of lambda from -2 to 2 in steps of
+// 0.1 that BoxCoxLambda profiles by default, as MASS::boxcox does.
+func DefaultBoxCoxGrid() []float64 {
+	grid := make([]float64, 41)
+	for k := range grid {
+		grid[k] = float64(k-20) / 10
+	}
+	return grid
+}
+
+// BoxCoxLambda profiles the log-likelihood of the least squares fit of the
+// Box-Cox transformed response on the design of m over a grid of lambda
+// (DefaultBoxCoxGrid if grid is nil), and returns the lambda of the grid that
+// maximizes it along with the profile. As in MASS::boxcox, the profile
+// log-likelihood is, up to a constant,
+//
+// l(\lambda) = -\frac{n}{2} \log RSS(y^{(\lambda)} / \dot{y}^{\lambda - 1})
+//
+// where \dot{y} is the geometric mean of the responses. An approximate 95%
+// confidence region for lambda is where the profile is within 1.92 (half the
+// 0.95 quantile of a chi-squared with one degree of freedom) of its maximum.
+func BoxCoxLambda(m Summary, grid []float64) (float64, []float64, error) {
+	if grid == nil {
+		grid = DefaultBoxCoxGrid()
+	}
+	if len(grid) == 0 {
+		return 0, nil, fmt.Errorf("empty grid of lambda")
+	}
+	y := m.Response()
+	if err := checkBoxCoxResponse(y); err != nil {
+		return 0, nil, err
+	}
+	x := m.Data().X
+	n := len(y)
+	logGeometric := 0.0
+	for _, v := range y {
+		logGeometric += math.Log(v)
+	}
+	logGeometric /= float64(n)
+
+	best, bestLoglik := 0.0, math.Inf(-1)
+	profile := make([]float64, len(grid))
+	for k, lambda := range grid {
+		if math.IsNaN(lambda) || math.IsInf(lambda, 0) {
+			return 0, nil, fmt.Errorf("lambda %v is not finite", lambda)
+		}
+		scale := math.Exp((lambda - 1) * logGeometric)
+		z := mat.NewDense(n, 1, nil)
+		for i, v := range y {
+			z.Set(i, 0, boxCox(v, lambda)/scale)
+		}
+		fit, err := leastSquares(x, z)
+		if err != nil {
+			return 0, nil, err
+		}
+		profile[k] = -float64(n) / 2 * math.Log(sum(prod(fit.residuals, fit.residuals)))
+		if profile[k] > bestLoglik {
+			best, bestLoglik = lambda, profile[k]
+		}
+	}
+	return best, profile, nil
+}
+
+// boxCox transforms a positive y, accurately for lambda near zero.
+func boxCox(y, lambda float64) float64 {
+	if lambda == 0 {
+		return math.Log(y)
+	}
+	return math.Expm1(lambda*math.Log(y)) / lambda
+}
+
+func checkBoxCoxResponse(y []float64) error {
+	for i, v := range y {
+		if !(v > 0) || math.IsInf(v, 0) {
+			return fmt.Errorf("response %d is %v, but the Box-Cox transformation needs positive responses; shift them with BoxCoxShifted", i, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package glasso
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// trees is R's trees data: girth, height and volume of 31 black cherry trees.
+var (
+	treesGirth  = []float64{8.3, 8.6, 8.8, 10.5, 10.7, 10.8, 11.0, 11.0, 11.1, 11.2, 11.3, 11.4, 11.4, 11.7, 12.0, 12.9, 12.9, 13.3, 13.7, 13.8, 14.0, 14.2, 14.5, 16.0, 16.3, 17.3, 17.5, 17.9, 18.0, 18.0, 20.6}
+	treesHeight = []float64{70, 65, 63, 72, 81, 83, 66, 75, 80, 75, 79, 76, 76, 69, 75, 74, 85, 86, 71, 64, 78, 80, 74, 72, 77, 81, 82, 80, 80, 80, 87}
+	treesVolume = []float64{10.3, 10.3, 10.2, 16.4, 18.8, 19.7, 15.6, 18.2, 22.6, 19.9, 24.2, 21.0, 21.4, 21.3, 19.1, 22.2, 33.8, 27.4, 25.7, 24.9, 34.5, 31.7, 36.3, 38.3, 42.6, 55.4, 55.7, 58.3, 51.5, 51.0, 77.0}
+)
+
+func treesSummary(t *testing.T) Summary {
+	rows := make([][]float64, len(treesGirth))
+	for i := range rows {
+		rows[i] = []float64{treesGirth[i], treesHeight[i]}
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), treesVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBoxCoxLambda(t *testing.T) {
+	s := treesSummary(t)
+	// lm(Volume ~ Girth + Height, trees)
+	for j, want := range []float64{-57.9877, 4.7082, 0.3393} {
+		if math.Abs(s.Coefficients()[j]-want) > 1e-4 {
+			t.Fatalf("coefficient %d of the trees fit is %v, want %v", j, s.Coefficients()[j], want)
+		}
+	}
+	best, profile, err := BoxCoxLambda(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile) != 41 {
+		t.Fatalf("got a profile of %d, want 41", len(profile))
+	}
+	// MASS::boxcox(Volume ~ Girth + Height, data = trees)
+	if math.Abs(best-0.3) > 1e-12 {
+		t.Errorf("lambda %v, want 0.3", best)
+	}
+	if profile[23] < profile[22] || profile[23] < profile[24] {
+		t.Errorf("profile %v is not highest at 0.3", profile[22:25])
+	}
+	// the cube root of volume, as its dimension suggests, is in the 95% region
+	_, third, err := BoxCoxLambda(s, []float64{best, 1.0 / 3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third[0]-third[1] > 1.92 || third[0]-third[2] < 1.92 {
+		t.Errorf("profile %v doesn't put 1/3 inside the 95%% region and 1 outside", third)
+	}
+}
+
+func TestBoxCox(t *testing.T) {
+	y := []float64{0.5, 1, 2, 10}
+	for _, lambda := range []float64{-1, 0, 0.5, 1, 2} {
+		z, err := BoxCox(y, lambda)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range y {
+			want := math.Log(v)
+			if lambda != 0 {
+				want = (math.Pow(v, lambda) - 1) / lambda
+			}
+			if math.Abs(z[i]-want) > 1e-12 {
+				t.Errorf("lambda %v: transformed %v to %v, want %v", lambda, v, z[i], want)
+			}
+		}
+		for i, v := range InverseBoxCox(z, lambda) {
+			if math.Abs(v-y[i]) > 1e-12 {
+				t.Errorf("lambda %v: back-transformed %v to %v, want %v", lambda, z[i], v, y[i])
+			}
+		}
+	}
+
+	// the transformation is continuous at zero
+	z, err := BoxCox(y, 1e-9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range y {
+		if math.Abs(z[i]-math.Log(v)) > 1e-8 {
+			t.Errorf("lambda 1e-9: transformed %v to %v, want about %v", v, z[i], math.Log(v))
+		}
+	}
+	if v := InverseBoxCox([]float64{-2}, 0.5)[0]; !math.IsNaN(v) {
+		t.Errorf("back-transformed -2 at lambda 0.5 to %v, want NaN", v)
+	}
+}
+
+func TestBoxCoxNonPositive(t *testing.T) {
+	y := []float64{0, 1, 2, 3}
+	if _, err := BoxCox(y, 0.5); err == nil || !strings.Contains(err.Error(), "BoxCoxShifted") {
+		t.Errorf("got error %v for a zero response, want one pointing to BoxCoxShifted", err)
+	}
+	z, err := BoxCoxShifted(y, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(z[0]) > 1e-12 || math.Abs(z[3]-math.Log(4)) > 1e-12 {
+		t.Errorf("shifted transformation is %v", z)
+	}
+	if _, err := BoxCoxShifted(y, 0, -0.5); err == nil {
+		t.Errorf("expected an error for a shift that leaves a negative response")
+	}
+
+	rows := [][]float64{{1}, {2}, {3}, {4}}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), []float64{1, -2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := BoxCoxLambda(s, nil); err == nil {
+		t.Errorf("expected an error for a negative response")
+	}
+	if _, _, err := BoxCoxLambda(treesSummary(t), []float64{}); err == nil {
+		t.Errorf("expected an error for an empty grid")
+	}
+}
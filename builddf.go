@@ -2,10 +2,11 @@ package glasso
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"sync"
 
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 var (
@@ -14,20 +15,31 @@ var (
 )
 
 type DataFrame struct {
-	X      *mat64.Dense
-	n, c   int      // memoize # rows, columns
-	labels []string // optional column names
+	X      *mat.Dense // the numeric columns, empty if there are no rows or columns
+	n, c   int        // memoize # rows, columns
+	labels []string   // optional column names
+
+	// categorical columns by name, such as ReadCSV reads, which aren't in X
+	categorical map[string][]string
+	categories  []string // their names, in order
 }
 
-func Mat64ToDF(mat *mat64.Dense) *DataFrame {
-	rows, cols := mat.Dims()
+// MatToDF returns a DataFrame of the matrix x, which it shares.
+func MatToDF(x *mat.Dense) *DataFrame {
+	rows, cols := x.Dims()
 	return &DataFrame{
-		X: mat,
+		X: x,
 		n: rows,
 		c: cols,
 	}
 }
 
+// Mat64ToDF is MatToDF, under its name from when the package used
+// github.com/gonum/matrix/mat64.
+//
+// Deprecated: use MatToDF.
+func Mat64ToDF(x *mat.Dense) *DataFrame { return MatToDF(x) }
+
 func NewDataFrame(data [][]float64, labels ...[]string) *DataFrame {
 	rows := len(data)
 	cols := len(data[0])
@@ -38,10 +50,15 @@ func NewDataFrame(data [][]float64, labels ...[]string) *DataFrame {
 	}
 
 	df := &DataFrame{
-		X: mat64.NewDense(rows, cols, x),
+		X: &mat.Dense{},
 		c: cols,
 		n: rows,
 	}
+	// gonum has no matrices without rows or columns, so such a frame has an
+	// empty X
+	if rows > 0 && cols > 0 {
+		df.X = mat.NewDense(rows, cols, x)
+	}
 
 	if len(labels) > 0 {
 		df.labels = labels[0]
@@ -54,20 +71,254 @@ func (d *DataFrame) GetRow(i int) []float64 {
 	if i > d.n {
 		return nil
 	}
-	return mat64.Row(nil, i, d.X)
+	if d.c == 0 {
+		return []float64{}
+	}
+	return mat.Row(nil, i, d.X)
 }
 
 func (d *DataFrame) GetCol(j int) []float64 {
 	if j > d.c {
 		return nil
 	}
-	return mat64.Col(nil, j, d.X)
+	return mat.Col(nil, j, d.X)
+}
+
+// Labels returns the column names of the DataFrame, or nil if it has none.
+func (d *DataFrame) Labels() []string { return d.labels }
+
+// Categorical returns the values of the named categorical column, and whether
+// there is one.
+func (d *DataFrame) Categorical(name string) ([]string, bool) {
+	values, ok := d.categorical[name]
+	return values, ok
+}
+
+// CategoricalNames returns the names of the categorical columns, in order.
+func (d *DataFrame) CategoricalNames() []string { return d.categories }
+
+// SplitResponse returns the named column as a response, and the DataFrame of
+// the rest of the columns, with their labels and the categorical columns, as
+// the predictors.
+func (d *DataFrame) SplitResponse(name string) (*DataFrame, []float64, error) {
+	if len(d.labels) != d.c {
+		return nil, nil, LabelError
+	}
+	k := -1
+	for j, label := range d.labels {
+		if label == name {
+			k = j
+		}
+	}
+	if k < 0 {
+		return nil, nil, fmt.Errorf("no numeric column %q", name)
+	}
+	if d.c == 1 {
+		return nil, nil, fmt.Errorf("%q is the only numeric column, which leaves no predictors", name)
+	}
+	x := mat.NewDense(d.n, d.c-1, nil)
+	var labels []string
+	for j := 0; j < d.c; j++ {
+		if j == k {
+			continue
+		}
+		x.SetCol(len(labels), d.GetCol(j))
+		labels = append(labels, d.labels[j])
+	}
+	return &DataFrame{
+		X:           x,
+		n:           d.n,
+		c:           d.c - 1,
+		labels:      labels,
+		categorical: d.categorical,
+		categories:  d.categories,
+	}, d.GetCol(k), nil
+}
+
+// Select returns a DataFrame of the named columns, numeric or categorical, in
+// the order given. An unknown name is an error.
+func (d *DataFrame) Select(names ...string) (*DataFrame, error) {
+	keep := make(map[string]bool, len(names))
+	var numeric []int
+	var categories []string
+	for _, name := range names {
+		if keep[name] {
+			return nil, fmt.Errorf("column %q is selected twice", name)
+		}
+		keep[name] = true
+		j, err := d.column(name)
+		if err != nil {
+			return nil, err
+		}
+		if j < 0 {
+			categories = append(categories, name)
+		} else {
+			numeric = append(numeric, j)
+		}
+	}
+	return d.columns(numeric, categories)
+}
+
+// Drop returns a DataFrame without the named columns, numeric or
+// categorical. An unknown name is an error.
+func (d *DataFrame) Drop(names ...string) (*DataFrame, error) {
+	drop := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, err := d.column(name); err != nil {
+			return nil, err
+		}
+		drop[name] = true
+	}
+	var numeric []int
+	for j, label := range d.labels {
+		if !drop[label] {
+			numeric = append(numeric, j)
+		}
+	}
+	var categories []string
+	for _, name := range d.categories {
+		if !drop[name] {
+			categories = append(categories, name)
+		}
+	}
+	return d.columns(numeric, categories)
+}
+
+// Filter returns a DataFrame of the rows for which keep, given the numeric
+// values of a row, is true. It is an error if there are none.
+func (d *DataFrame) Filter(keep func(row []float64) bool) (*DataFrame, error) {
+	var rows []int
+	for i := 0; i < d.n; i++ {
+		if keep(d.GetRow(i)) {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows pass the filter")
+	}
+	return d.SelectRows(rows)
+}
+
+// SelectRows returns a DataFrame of the given rows, in the order given,
+// which may repeat rows.
+func (d *DataFrame) SelectRows(rows []int) (*DataFrame, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows selected")
+	}
+	x := mat.NewDense(len(rows), d.c, nil)
+	for k, i := range rows {
+		if i < 0 || i >= d.n {
+			return nil, fmt.Errorf("row %d is not one of the %d rows", i, d.n)
+		}
+		x.SetRow(k, d.GetRow(i))
+	}
+	categorical := make(map[string][]string, len(d.categories))
+	for _, name := range d.categories {
+		values := make([]string, len(rows))
+		for k, i := range rows {
+			values[k] = d.categorical[name][i]
+		}
+		categorical[name] = values
+	}
+	return &DataFrame{
+		X:           x,
+		n:           len(rows),
+		c:           d.c,
+		labels:      d.labels,
+		categorical: categorical,
+		categories:  d.categories,
+	}, nil
+}
+
+// Append returns a DataFrame with the numeric column col appended, named
+// name. The columns of a DataFrame without labels are named "x0", "x1", ...
+func (d *DataFrame) Append(col []float64, name string) (*DataFrame, error) {
+	if len(col) != d.n {
+		return nil, DimensionError
+	}
+	names := d.names()
+	for _, label := range append(names, d.categories...) {
+		if label == name {
+			return nil, fmt.Errorf("there already is a column %q", name)
+		}
+	}
+	x := mat.NewDense(d.n, d.c+1, nil)
+	for j := 0; j < d.c; j++ {
+		x.SetCol(j, d.GetCol(j))
+	}
+	x.SetCol(d.c, col)
+	return &DataFrame{
+		X:           x,
+		n:           d.n,
+		c:           d.c + 1,
+		labels:      append(names, name),
+		categorical: d.categorical,
+		categories:  d.categories,
+	}, nil
+}
+
+// column returns the index of the named numeric column, or -1 for a
+// categorical column.
+func (d *DataFrame) column(name string) (int, error) {
+	if len(d.labels) != d.c {
+		return 0, LabelError
+	}
+	for j, label := range d.labels {
+		if label == name {
+			return j, nil
+		}
+	}
+	if _, ok := d.categorical[name]; ok {
+		return -1, nil
+	}
+	return 0, fmt.Errorf("no column %q", name)
+}
+
+// columns returns a DataFrame of the given numeric and categorical columns.
+func (d *DataFrame) columns(numeric []int, categories []string) (*DataFrame, error) {
+	if len(numeric) == 0 {
+		return nil, fmt.Errorf("no numeric columns are left")
+	}
+	x := mat.NewDense(d.n, len(numeric), nil)
+	labels := make([]string, len(numeric))
+	for k, j := range numeric {
+		x.SetCol(k, d.GetCol(j))
+		labels[k] = d.labels[j]
+	}
+	categorical := make(map[string][]string, len(categories))
+	for _, name := range categories {
+		categorical[name] = d.categorical[name]
+	}
+	return &DataFrame{
+		X:           x,
+		n:           d.n,
+		c:           len(numeric),
+		labels:      labels,
+		categorical: categorical,
+		categories:  categories,
+	}, nil
+}
+
+// names returns the labels of the columns, or "x0", "x1", ... if there
+// aren't any, in a new slice.
+func (d *DataFrame) names() []string {
+	if len(d.labels) == d.c {
+		return append([]string(nil), d.labels...)
+	}
+	names := make([]string, d.c)
+	for j := range names {
+		names[j] = fmt.Sprintf("x%d", j)
+	}
+	return names
 }
 
 func (d *DataFrame) Rows() int { return d.n }
 func (d *DataFrame) Cols() int { return d.c }
-func (d *DataFrame) Data() *mat64.Dense {
-	return mat64.DenseCopyOf(d.X)
+func (d *DataFrame) Data() *mat.Dense {
+	if d.X.IsEmpty() {
+		return &mat.Dense{}
+	}
+	return mat.DenseCopyOf(d.X)
 }
 
 func (d *DataFrame) Copy() *DataFrame {
@@ -96,7 +347,7 @@ func (d *DataFrame) AppendCol(col []float64) error {
 		return DimensionError
 	}
 
-	d.X = mat64.DenseCopyOf(d.X.Grow(0, 1))
+	d.X = mat.DenseCopyOf(d.X.Grow(0, 1))
 	d.n, d.c = d.X.Dims()
 	d.X.SetCol(d.c-1, col)
 
@@ -109,7 +360,7 @@ func (d *DataFrame) AppendRow(row []float64) error {
 		return DimensionError
 	}
 
-	d.X = mat64.DenseCopyOf(d.X.Grow(1, 0))
+	d.X = mat.DenseCopyOf(d.X.Grow(1, 0))
 	d.n, d.c = d.X.Dims()
 	d.X.SetRow(d.n-1, row)
 
@@ -123,7 +374,7 @@ func (d *DataFrame) PushCol(col []float64) error {
 	}
 
 	d.c++
-	x := mat64.NewDense(d.n, d.c, nil)
+	x := mat.NewDense(d.n, d.c, nil)
 	x.SetCol(0, col)
 
 	for c := 1; c < d.c; c++ {
@@ -141,7 +392,7 @@ func (d *DataFrame) PushRow(row []float64) error {
 	}
 
 	d.n++
-	x := mat64.NewDense(d.n, d.c, nil)
+	x := mat.NewDense(d.n, d.c, nil)
 	x.SetRow(0, row)
 
 	for r := 1; r < d.n; r++ {
@@ -158,7 +409,7 @@ func (d *DataFrame) RemoveCol(col int) error {
 		return DimensionError
 	}
 
-	tmp := mat64.NewDense(d.n, d.c-1, nil)
+	tmp := mat.NewDense(d.n, d.c-1, nil)
 	j := 0
 	for i := 0; i < d.c; i++ {
 		if i != col {
@@ -177,7 +428,7 @@ func (d *DataFrame) RemoveRow(row int) error {
 		return DimensionError
 	}
 
-	tmp := mat64.NewDense(d.n-1, d.c, nil)
+	tmp := mat.NewDense(d.n-1, d.c, nil)
 	j := 0
 	for i := 0; i < d.n; i++ {
 		if i != row {
@@ -3,6 +3,7 @@ package glasso
 import (
 	"fmt"
 	"math"
+	"sort"
 )
 
 func round(val float64, places int) float64 {
@@ -67,6 +68,49 @@ func sd(x []float64) float64 {
 	return math.Sqrt(variance(x))
 }
 
+// quantile returns the pth sample quantile of sorted by linear interpolation
+// between order statistics (type 7 in R's quantile).
+func quantile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	lo := math.Floor(h)
+	i := int(lo)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (h-lo)*(sorted[i+1]-sorted[i])
+}
+
+// median returns the sample median of x, which is not modified.
+func median(x []float64) float64 {
+	sorted := make([]float64, len(x))
+	copy(sorted, x)
+	sort.Float64s(sorted)
+	return quantile(sorted, 0.5)
+}
+
+// centralMoment returns the kth sample central moment, \frac{1}{n} \sum (x_i - \bar{x})^k
+func centralMoment(x []float64, k float64) float64 {
+	m := mean(x)
+	total := 0.0
+	for _, v := range x {
+		total += math.Pow(v-m, k)
+	}
+	return total / float64(len(x))
+}
+
+// Skewness returns the sample skewness m_3 / m_2^{3/2}, using the biased
+// central moments m_k = \frac{1}{n} \sum (x_i - \bar{x})^k.
+func Skewness(x []float64) float64 {
+	return centralMoment(x, 3) / math.Pow(centralMoment(x, 2), 1.5)
+}
+
+// Kurtosis returns the sample excess kurtosis m_4 / m_2^2 - 3, which is zero
+// in expectation for large normal samples.
+func Kurtosis(x []float64) float64 {
+	m2 := centralMoment(x, 2)
+	return centralMoment(x, 4)/(m2*m2) - 3
+}
+
 func prod(x, y []float64) []float64 {
 	p := make([]float64, len(x))
 
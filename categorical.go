@@ -0,0 +1,430 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// An UnseenLevel says how a CategoricalEncoder encodes a level it wasn't
+// built with.
+type UnseenLevel int
+
+const (
+	UnseenError UnseenLevel = iota // an unseen level is an error
+	UnseenZero                     // an unseen level has zeros in every column, with a warning
+)
+
+// A Contrast is a coding of the k levels of a categorical predictor as k - 1
+// columns, the contrasts of R's lm. Each codes the same column space with
+// the intercept, so the fitted values are the same whichever is used; they
+// differ in what the coefficients compare.
+type Contrast int
+
+const (
+	// TreatmentContrast is dummy coding, R's contr.treatment: a column for
+	// each level but the reference, whose coefficient is the difference of
+	// its mean from the reference level's. The columns are named
+	// "dose[high]".
+	TreatmentContrast Contrast = iota
+
+	// SumContrast is deviation coding, R's contr.sum: the column of each
+	// level but the last is one for the level and minus one for the last, so
+	// that the intercept is the mean of the means of the levels and each
+	// coefficient the difference of the level's mean from it. The columns
+	// are named "dose[S.low]".
+	SumContrast
+
+	// HelmertContrast is R's contr.helmert: column j compares level j + 1
+	// with the mean of the levels before it, and is named after the level,
+	// "dose[H.medium]". The columns are orthogonal for balanced data.
+	HelmertContrast
+
+	// PolyContrast is R's contr.poly for ordered levels: the orthonormal
+	// polynomials of degree 1 to k - 1 in their equally spaced scores, in the
+	// order of Levels. The columns are named as in R, "dose.L", "dose.Q",
+	// "dose.C" and then "dose^4", "dose^5", ...
+	PolyContrast
+)
+
+func (c Contrast) String() string {
+	switch c {
+	case TreatmentContrast:
+		return "treatment"
+	case SumContrast:
+		return "sum"
+	case HelmertContrast:
+		return "helmert"
+	case PolyContrast:
+		return "poly"
+	}
+	return fmt.Sprintf("Contrast(%d)", int(c))
+}
+
+// CategoricalConfig configures a CategoricalEncoder. The zero value is dummy
+// coding with the first level as the reference and unseen levels an error.
+type CategoricalConfig struct {
+	// Contrast is the coding of the levels, TreatmentContrast by default.
+	Contrast Contrast
+
+	// Reference is the level without a column of treatment coding, whose
+	// effect the intercept holds, the first level if empty.
+	Reference string
+
+	// OneHot gives every level a column, for a model without an intercept.
+	OneHot bool
+
+	Unseen UnseenLevel
+}
+
+// CategoricalEncoder encodes a categorical predictor as the columns of a
+// contrast, with the levels it was built with.
+type CategoricalEncoder struct {
+	name      string
+	levels    []string
+	contrast  Contrast
+	reference string
+	unseen    UnseenLevel
+	index     map[string]int // the row of each level in coding
+	coding    [][]float64    // the columns of each level, a row per level
+	names     []string       // the names of the columns
+}
+
+// NewCategoricalEncoder builds an encoder for the categorical predictor with
+// the given name and values. Its levels are the distinct values, sorted as
+// numbers if they all are integers and as strings otherwise, as R orders the
+// levels of a factor. With dummy coding (the default) a predictor with k levels
+// has k - 1 columns, one for each level but the reference, which needs two
+// levels at least; one-hot coding has k columns. The other contrasts of
+// config.Contrast have k - 1 columns too, and no reference level.
+func NewCategoricalEncoder(name string, values []string, config *CategoricalConfig) (*CategoricalEncoder, error) {
+	if config == nil {
+		config = &CategoricalConfig{}
+	}
+	if config.Unseen != UnseenError && config.Unseen != UnseenZero {
+		return nil, fmt.Errorf("unknown handling %d of unseen levels", config.Unseen)
+	}
+	if config.Contrast < TreatmentContrast || config.Contrast > PolyContrast {
+		return nil, fmt.Errorf("unknown contrast %v", config.Contrast)
+	}
+	if config.Contrast != TreatmentContrast && (config.OneHot || config.Reference != "") {
+		return nil, fmt.Errorf("%v coding of %s has neither one-hot columns nor a reference level", config.Contrast, name)
+	}
+	seen := make(map[string]bool)
+	var levels []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			levels = append(levels, v)
+		}
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%s has no values", name)
+	}
+	sortLevels(levels)
+
+	e := &CategoricalEncoder{
+		name:     name,
+		levels:   levels,
+		contrast: config.Contrast,
+		unseen:   config.Unseen,
+		index:    make(map[string]int, len(levels)),
+	}
+	for i, level := range levels {
+		e.index[level] = i
+	}
+	if config.OneHot {
+		if config.Reference != "" {
+			return nil, fmt.Errorf("one-hot coding of %s has no reference level, but %q was given", name, config.Reference)
+		}
+		e.coding = indicators(len(levels), -1)
+		for _, level := range levels {
+			e.names = append(e.names, fmt.Sprintf("%s[%s]", name, level))
+		}
+		return e, nil
+	}
+	if config.Contrast != TreatmentContrast {
+		if len(levels) < 2 {
+			return nil, fmt.Errorf("%s has the single level %q, so its %v coding has no columns", name, levels[0], config.Contrast)
+		}
+		switch config.Contrast {
+		case SumContrast:
+			e.coding, e.names = sumContrast(name, levels)
+		case HelmertContrast:
+			e.coding, e.names = helmertContrast(name, levels)
+		case PolyContrast:
+			e.coding, e.names = polyContrast(name, len(levels))
+		}
+		return e, nil
+	}
+
+	e.reference = config.Reference
+	if e.reference == "" {
+		e.reference = levels[0]
+	}
+	if !seen[e.reference] {
+		return nil, fmt.Errorf("reference %q is not a level of %s", e.reference, name)
+	}
+	if len(levels) < 2 {
+		return nil, fmt.Errorf("%s has the single level %q, so its dummy coding has no columns", name, levels[0])
+	}
+	e.coding = indicators(len(levels), e.index[e.reference])
+	for _, level := range levels {
+		if level != e.reference {
+			e.names = append(e.names, fmt.Sprintf("%s[%s]", name, level))
+		}
+	}
+	return e, nil
+}
+
+// indicators returns the coding of k levels by an indicator column for each
+// but the level reference, or for each if reference is -1.
+func indicators(k, reference int) [][]float64 {
+	width := k
+	if reference >= 0 {
+		width--
+	}
+	coding := make([][]float64, k)
+	j := 0
+	for i := range coding {
+		coding[i] = make([]float64, width)
+		if i != reference {
+			coding[i][j] = 1
+			j++
+		}
+	}
+	return coding
+}
+
+// sumContrast returns the coding of contr.sum, the identity for the levels but
+// the last and minus ones for the last.
+func sumContrast(name string, levels []string) ([][]float64, []string) {
+	k := len(levels)
+	coding := indicators(k, k-1)
+	coding[k-1] = rep(-1, k-1)
+	names := make([]string, k-1)
+	for j := range names {
+		names[j] = fmt.Sprintf("%s[S.%s]", name, levels[j])
+	}
+	return coding, names
+}
+
+// helmertContrast returns the coding of contr.helmert, whose column j is -1
+// for the levels up to j, j + 1 for level j + 1 and 0 beyond.
+func helmertContrast(name string, levels []string) ([][]float64, []string) {
+	k := len(levels)
+	coding := make([][]float64, k)
+	for i := range coding {
+		coding[i] = make([]float64, k-1)
+		for j := range coding[i] {
+			switch {
+			case i <= j:
+				coding[i][j] = -1
+			case i == j+1:
+				coding[i][j] = float64(j + 1)
+			}
+		}
+	}
+	names := make([]string, k-1)
+	for j := range names {
+		names[j] = fmt.Sprintf("%s[H.%s]", name, levels[j+1])
+	}
+	return coding, names
+}
+
+// polyContrast returns the coding of contr.poly for k levels, the powers 1 to
+// k - 1 of the centered scores 1, ..., k orthonormalized by Gram-Schmidt
+// against the lower powers, which leaves each with a positive leading
+// coefficient as R's QR does.
+func polyContrast(name string, k int) ([][]float64, []string) {
+	center := float64(k+1) / 2
+	basis := [][]float64{rep(1/math.Sqrt(float64(k)), k)}
+	for d := 1; d < k; d++ {
+		v := make([]float64, k)
+		for i := range v {
+			v[i] = math.Pow(float64(i+1)-center, float64(d))
+		}
+		for _, q := range basis {
+			r := sum(prod(q, v))
+			for i := range v {
+				v[i] -= r * q[i]
+			}
+		}
+		basis = append(basis, multSlice(v, 1/math.Sqrt(sum(prod(v, v)))))
+	}
+	coding := make([][]float64, k)
+	for i := range coding {
+		coding[i] = make([]float64, k-1)
+		for d := 1; d < k; d++ {
+			coding[i][d-1] = basis[d][i]
+		}
+	}
+	names := make([]string, k-1)
+	for d := range names {
+		if d < 3 {
+			names[d] = name + "." + []string{"L", "Q", "C"}[d]
+		} else {
+			names[d] = fmt.Sprintf("%s^%d", name, d+1)
+		}
+	}
+	return coding, names
+}
+
+// NewFactorEncoder builds a CategoricalEncoder for an integer coded factor,
+// whose levels are the codes.
+func NewFactorEncoder(name string, codes []int, config *CategoricalConfig) (*CategoricalEncoder, error) {
+	return NewCategoricalEncoder(name, FactorStrings(codes), config)
+}
+
+// FactorStrings returns the values of an integer coded factor as the strings
+// a CategoricalEncoder encodes.
+func FactorStrings(codes []int) []string {
+	values := make([]string, len(codes))
+	for i, c := range codes {
+		values[i] = strconv.Itoa(c)
+	}
+	return values
+}
+
+// sortLevels sorts levels as numbers if they all are integers, and as strings
+// otherwise.
+func sortLevels(levels []string) {
+	numbers := make(map[string]int, len(levels))
+	for _, level := range levels {
+		n, err := strconv.Atoi(level)
+		if err != nil {
+			sort.Strings(levels)
+			return
+		}
+		numbers[level] = n
+	}
+	sort.Slice(levels, func(a, b int) bool { return numbers[levels[a]] < numbers[levels[b]] })
+}
+
+// Levels returns the levels of the predictor, in order.
+func (e *CategoricalEncoder) Levels() []string { return e.levels }
+
+// Reference returns the reference level, or "" for one-hot coding and
+// contrasts other than TreatmentContrast.
+func (e *CategoricalEncoder) Reference() string { return e.reference }
+
+// Contrast returns the coding of the levels.
+func (e *CategoricalEncoder) Contrast() Contrast { return e.contrast }
+
+// Names returns the names of the columns of the encoding, such as
+// "color[red]" for the level red of the predictor color, in order.
+func (e *CategoricalEncoder) Names() []string {
+	return append([]string(nil), e.names...)
+}
+
+// ContrastMatrix returns the contrast matrix of the encoding, R's contrasts,
+// with a row for each of Levels, in order, and a column for each of Names:
+// the values that Encode encodes each level as.
+func (e *CategoricalEncoder) ContrastMatrix() *DataFrame {
+	return NewDataFrame(e.coding, e.Names())
+}
+
+// Encode returns the columns of the encoding of values, a row per value and
+// labeled by Names. Unseen levels are an error or, if the encoder was built
+// with UnseenZero, rows of zeros, with a warning for each.
+func (e *CategoricalEncoder) Encode(values []string) (*DataFrame, []string, error) {
+	if len(values) == 0 {
+		return nil, nil, fmt.Errorf("no values of %s to encode", e.name)
+	}
+	var warnings []string
+	rows := make([][]float64, len(values))
+	for i, v := range values {
+		row, err := e.encode(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		if _, ok := e.index[v]; !ok {
+			warnings = append(warnings, fmt.Sprintf("row %d has the unseen level %q of %s, encoded as zeros", i, v, e.name))
+		}
+		rows[i] = row
+	}
+	return NewDataFrame(rows, e.Names()), warnings, nil
+}
+
+// Transform returns x with the encoding of values appended to it, as Encode
+// encodes them. The columns of x keep their labels, or are named "x0",
+// "x1", ... if x has no labels.
+func (e *CategoricalEncoder) Transform(x *DataFrame, values []string) (*DataFrame, []string, error) {
+	if len(values) != x.Rows() {
+		return nil, nil, DimensionError
+	}
+	encoded, warnings, err := e.Encode(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := x.names()
+	rows := make([][]float64, x.Rows())
+	for i := range rows {
+		rows[i] = append(x.GetRow(i), encoded.GetRow(i)...)
+	}
+	return NewDataFrame(rows, append(names, encoded.Labels()...)), warnings, nil
+}
+
+// TransformRow appends the encoding of value to one row of predictors, for
+// predicting from a model trained on transformed data. An unseen level is
+// encoded as Encode encodes it, without a warning.
+func (e *CategoricalEncoder) TransformRow(row []float64, value string) ([]float64, error) {
+	encoded, err := e.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]float64(nil), row...), encoded...), nil
+}
+
+func (e *CategoricalEncoder) encode(value string) ([]float64, error) {
+	i, ok := e.index[value]
+	switch {
+	case ok:
+		return append([]float64(nil), e.coding[i]...), nil
+	case e.unseen == UnseenError:
+		return nil, fmt.Errorf("%q is not a level of %s", value, e.name)
+	}
+	return make([]float64, len(e.names)), nil
+}
+
+// Model returns a model that encodes the categorical column of new data
+// named after the predictor, as Transform does, before m predicts from it,
+// for a model m trained on the transformed data that knows the names of its
+// columns. It only predicts with PredictFrame, from a DataFrame with the
+// categorical column; Predict, whose rows of numbers have no level, predicts
+// NaN. An unseen level is an error, or encoded as zeros with UnseenZero.
+func (e *CategoricalEncoder) Model(m Model) Model {
+	return &encodedModel{encoder: e, model: m}
+}
+
+type encodedModel struct {
+	encoder *CategoricalEncoder
+	model   Model
+}
+
+func (m *encodedModel) Predict(x []float64) float64 { return math.NaN() }
+
+func (m *encodedModel) frameColumns() ([]string, error) {
+	if _, ok := m.model.(frameModel); !ok {
+		return nil, fmt.Errorf("the model the encoding of %s is for doesn't know the names of its columns", m.encoder.name)
+	}
+	return wrappedColumns(m.encoder, m.model)
+}
+
+func (m *encodedModel) predictFrame(x *DataFrame) ([]float64, error) {
+	return predictWrapped(m.encoder, m.model, x)
+}
+
+func (e *CategoricalEncoder) inputs() ([]string, error) { return []string{e.name}, nil }
+
+func (e *CategoricalEncoder) outputs() []string { return e.Names() }
+
+func (e *CategoricalEncoder) transformFrame(x *DataFrame) (*DataFrame, error) {
+	values, ok := x.Categorical(e.name)
+	if !ok {
+		return nil, fmt.Errorf("no categorical column %q", e.name)
+	}
+	encoded, _, err := e.Encode(values)
+	return encoded, err
+}
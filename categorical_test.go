@@ -0,0 +1,281 @@
+package glasso
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCategoricalEncoder(t *testing.T) {
+	codes := make([]int, len(mtcarsCyl))
+	for i, c := range mtcarsCyl {
+		codes[i] = int(c)
+	}
+	// lm(mpg ~ factor(cyl), mtcars), with each level as the reference
+	for _, c := range []struct {
+		reference string
+		names     []string
+		want      []float64
+	}{
+		{"", []string{"cyl[6]", "cyl[8]"}, []float64{26.6636364, -6.9207792, -11.5636364}},
+		{"8", []string{"cyl[4]", "cyl[6]"}, []float64{15.1, 11.5636364, 4.6428571}},
+	} {
+		e, err := NewFactorEncoder("cyl", codes, &CategoricalConfig{Reference: c.reference})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(e.Levels(), []string{"4", "6", "8"}) {
+			t.Errorf("got levels %v", e.Levels())
+		}
+		x, warnings, err := e.Encode(FactorStrings(codes))
+		if err != nil || warnings != nil {
+			t.Fatalf("got warnings %v and error %v", warnings, err)
+		}
+		if !reflect.DeepEqual(x.Labels(), c.names) {
+			t.Errorf("reference %q: got labels %v, want %v", c.reference, x.Labels(), c.names)
+		}
+		model, s, err := NewOlsTrainer().Train(x, mtcarsMpg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, want := range c.want {
+			if b := s.Coefficients()[j]; math.Abs(b-want) > 1e-6 {
+				t.Errorf("reference %q: coefficient %d is %v, want %v", c.reference, j, b, want)
+			}
+		}
+
+		// predictions through the encoding are the means of the levels
+		for level, want := range map[string]float64{"4": 26.6636364, "6": 19.7428571, "8": 15.1} {
+			row, err := e.TransformRow(nil, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := model.Predict(row); math.Abs(got-want) > 1e-6 {
+				t.Errorf("reference %q: prediction for %s cylinders is %v, want %v", c.reference, level, got, want)
+			}
+		}
+	}
+}
+
+func TestCategoricalEncoderOneHot(t *testing.T) {
+	values := []string{"red", "green", "red", "blue", "green", "red"}
+	e, err := NewCategoricalEncoder("color", values, &CategoricalConfig{OneHot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Reference() != "" || !reflect.DeepEqual(e.Names(), []string{"color[blue]", "color[green]", "color[red]"}) {
+		t.Errorf("got reference %q and names %v", e.Reference(), e.Names())
+	}
+	x, _, err := e.Encode(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without an intercept the coefficients are the means of the levels
+	response := mat.NewDense(6, 1, []float64{1, 2, 3, 4, 6, 5})
+	fit, err := leastSquares(x.X, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, want := range []float64{4, 4, 3} {
+		if math.Abs(fit.betas[j]-want) > 1e-12 {
+			t.Errorf("coefficient %d is %v, want %v", j, fit.betas[j], want)
+		}
+	}
+
+	if _, err := NewCategoricalEncoder("color", values, &CategoricalConfig{OneHot: true, Reference: "red"}); err == nil {
+		t.Errorf("expected an error for a reference level with one-hot coding")
+	}
+	if _, err := NewCategoricalEncoder("color", values, &CategoricalConfig{Reference: "purple"}); err == nil {
+		t.Errorf("expected an error for a reference that isn't a level")
+	}
+	if _, err := NewCategoricalEncoder("color", []string{"red", "red"}, nil); err == nil {
+		t.Errorf("expected an error for dummy coding of a single level")
+	}
+}
+
+func TestCategoricalEncoderUnseen(t *testing.T) {
+	values := []string{"b", "a", "c", "a"}
+	e, err := NewCategoricalEncoder("g", values, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := e.Encode([]string{"a", "d"}); err == nil || !strings.Contains(err.Error(), `"d"`) {
+		t.Errorf("got error %v for an unseen level", err)
+	}
+	if _, err := e.TransformRow([]float64{1}, "d"); err == nil {
+		t.Errorf("expected an error for an unseen level")
+	}
+
+	e, err = NewCategoricalEncoder("g", values, &CategoricalConfig{Reference: "b", Unseen: UnseenZero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := NewDataFrame([][]float64{{1.5}, {2.5}, {3.5}})
+	transformed, warnings, err := e.Transform(x, []string{"a", "d", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "row 1") {
+		t.Errorf("got warnings %v, want one for row 1", warnings)
+	}
+	if names := []string{"x0", "g[a]", "g[c]"}; !reflect.DeepEqual(transformed.Labels(), names) {
+		t.Errorf("got labels %v, want %v", transformed.Labels(), names)
+	}
+	want := [][]float64{{1.5, 1, 0}, {2.5, 0, 0}, {3.5, 0, 1}}
+	for i := range want {
+		if row := transformed.GetRow(i); !reflect.DeepEqual(row, want[i]) {
+			t.Errorf("row %d is %v, want %v", i, row, want[i])
+		}
+	}
+	if _, _, err := e.Transform(x, []string{"a"}); err != DimensionError {
+		t.Errorf("got error %v for too few values, want DimensionError", err)
+	}
+
+	// integer levels sort as numbers
+	e, err = NewCategoricalEncoder("n", []string{"10", "9", "2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e.Levels(), []string{"2", "9", "10"}) {
+		t.Errorf("got levels %v", e.Levels())
+	}
+}
+
+func TestCategoricalContrasts(t *testing.T) {
+	codes := make([]int, len(mtcarsCyl))
+	for i, c := range mtcarsCyl {
+		codes[i] = int(c)
+	}
+	// lm(mpg ~ factor(cyl), mtcars, contrasts = list(`factor(cyl)` = ...)),
+	// from the means of the levels computed exactly in Python since R isn't
+	// available here
+	treatment, err := NewFactorEncoder("cyl", codes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _, err := treatment.Encode(FactorStrings(codes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, dummy, err := NewOlsTrainer().Train(x, mtcarsMpg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		contrast Contrast
+		names    []string
+		want     []float64
+	}{
+		{SumContrast, []string{"cyl[S.4]", "cyl[S.6]"}, []float64{20.5021645021645, 6.1614718614718615, -0.7593073593073593}},
+		{HelmertContrast, []string{"cyl[H.6]", "cyl[H.8]"}, []float64{20.5021645021645, -3.4603896103896106, -2.701082251082251}},
+		{PolyContrast, []string{"cyl.L", "cyl.Q"}, []float64{20.5021645021645, -8.176725687902623, 0.929957794121579}},
+	} {
+		e, err := NewFactorEncoder("cyl", codes, &CategoricalConfig{Contrast: c.contrast})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Contrast() != c.contrast || e.Reference() != "" {
+			t.Errorf("%v: got contrast %v and reference %q", c.contrast, e.Contrast(), e.Reference())
+		}
+		x, _, err := e.Encode(FactorStrings(codes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(x.Labels(), c.names) {
+			t.Errorf("%v: got labels %v, want %v", c.contrast, x.Labels(), c.names)
+		}
+		model, s, err := NewOlsTrainer().Train(x, mtcarsMpg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, want := range c.want {
+			if b := s.Coefficients()[j]; math.Abs(b-want) > 1e-10 {
+				t.Errorf("%v: coefficient %d is %v, want %v", c.contrast, j, b, want)
+			}
+		}
+		// the same column space, so the same fit
+		for i, fit := range s.Yhat() {
+			if math.Abs(fit-dummy.Yhat()[i]) > 1e-10 {
+				t.Errorf("%v: fitted value %d is %v, want %v", c.contrast, i, fit, dummy.Yhat()[i])
+			}
+		}
+		row, err := e.TransformRow(nil, "6")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := model.Predict(row); math.Abs(got-19.742857142857142) > 1e-10 {
+			t.Errorf("%v: prediction for 6 cylinders is %v", c.contrast, got)
+		}
+	}
+}
+
+func TestContrastMatrix(t *testing.T) {
+	levels := []string{"a", "b", "c", "d"}
+	// contr.sum(4), contr.helmert(4) and contr.poly(4)
+	for _, c := range []struct {
+		contrast Contrast
+		want     [][]float64
+	}{
+		{TreatmentContrast, [][]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+		{SumContrast, [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-1, -1, -1}}},
+		{HelmertContrast, [][]float64{{-1, -1, -1}, {1, -1, -1}, {0, 2, -1}, {0, 0, 3}}},
+		{PolyContrast, [][]float64{
+			{-0.670820393249937, 0.5, -0.223606797749979},
+			{-0.223606797749979, -0.5, 0.670820393249937},
+			{0.223606797749979, -0.5, -0.670820393249937},
+			{0.670820393249937, 0.5, 0.223606797749979},
+		}},
+	} {
+		e, err := NewCategoricalEncoder("g", levels, &CategoricalConfig{Contrast: c.contrast})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := e.ContrastMatrix()
+		if !reflect.DeepEqual(m.Labels(), e.Names()) {
+			t.Errorf("%v: got labels %v", c.contrast, m.Labels())
+		}
+		for i, row := range c.want {
+			for j, want := range row {
+				if got := m.X.At(i, j); math.Abs(got-want) > 1e-12 {
+					t.Errorf("%v: entry (%d, %d) is %v, want %v", c.contrast, i, j, got, want)
+				}
+			}
+		}
+	}
+
+	e, err := NewCategoricalEncoder("dose", []string{"1", "2", "3", "4", "5", "6"}, &CategoricalConfig{Contrast: PolyContrast})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{"dose.L", "dose.Q", "dose.C", "dose^4", "dose^5"}; !reflect.DeepEqual(e.Names(), names) {
+		t.Errorf("got names %v, want %v", e.Names(), names)
+	}
+	if _, err := NewCategoricalEncoder("g", levels, &CategoricalConfig{Contrast: SumContrast, Reference: "b"}); err == nil {
+		t.Errorf("expected an error for a reference level with sum coding")
+	}
+	if _, err := NewCategoricalEncoder("g", levels, &CategoricalConfig{Contrast: PolyContrast, OneHot: true}); err == nil {
+		t.Errorf("expected an error for one-hot polynomial coding")
+	}
+	if _, err := NewCategoricalEncoder("g", []string{"a"}, &CategoricalConfig{Contrast: HelmertContrast}); err == nil {
+		t.Errorf("expected an error for Helmert coding of a single level")
+	}
+	if _, err := NewCategoricalEncoder("g", levels, &CategoricalConfig{Contrast: Contrast(9)}); err == nil {
+		t.Errorf("expected an error for an unknown contrast")
+	}
+
+	// unseen levels are zeros in any coding
+	e, err = NewCategoricalEncoder("g", levels, &CategoricalConfig{Contrast: HelmertContrast, Unseen: UnseenZero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, warnings, err := e.Encode([]string{"d", "z"})
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("got warnings %v and error %v", warnings, err)
+	}
+	if !reflect.DeepEqual(x.GetRow(1), []float64{0, 0, 0}) || !reflect.DeepEqual(x.GetRow(0), []float64{0, 0, 3}) {
+		t.Errorf("got rows %v and %v", x.GetRow(0), x.GetRow(1))
+	}
+}
@@ -0,0 +1,149 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// ChowTest tests the regression of y on the columns of x for a structural
+// break before row breakIdx, known in advance, against the null hypothesis
+// that the coefficients are the same either side of it (Chow, 1960). The
+// pooled model and the models of rows [0, breakIdx) and [breakIdx, n) are fit
+// by least squares, with an intercept unless WithIntercept(false) is given,
+// and
+//
+// F = \frac{(RSS_p - RSS_1 - RSS_2) / k}{(RSS_1 + RSS_2) / (n - 2k)}
+//
+// on k and n - 2k degrees of freedom, for k coefficients. Each segment must
+// have at least k + 1 observations. The test assumes the error variance is
+// the same in both segments.
+func ChowTest(x *DataFrame, y []float64, breakIdx int, opts ...Option) (TestResult, error) {
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n {
+		return TestResult{}, DimensionError
+	}
+	k := x.Cols()
+	if o.intercept {
+		k++
+	}
+	if err := checkBreak(n, k, breakIdx); err != nil {
+		return TestResult{}, err
+	}
+	pooled, err := segmentRSS(x, y, 0, n, o)
+	if err != nil {
+		return TestResult{}, err
+	}
+	return chowF(x, y, breakIdx, k, pooled, o)
+}
+
+// checkBreak checks that a break before row b leaves both segments of n rows
+// the k + 1 observations that a fit of k coefficients needs.
+func checkBreak(n, k, b int) error {
+	if b < k+1 || n-b < k+1 {
+		return fmt.Errorf("%w: a break before row %d leaves segments of %d and %d observations for %d coefficients",
+			TooFewObservationsError, b, b, n-b, k)
+	}
+	return nil
+}
+
+// segmentRSS returns the residual sum of squares of the fit to rows [from, to).
+// A segment whose design doesn't have full rank is a SingularDesignError.
+func segmentRSS(x *DataFrame, y []float64, from, to int, o options) (float64, error) {
+	rows := make([]int, to-from)
+	for i := range rows {
+		rows[i] = from + i
+	}
+	_, s, err := NewOlsTrainer(WithIntercept(o.intercept)).Train(subsetRows(x, rows), subsetSlice(y, rows))
+	if err != nil {
+		return 0, fmt.Errorf("rows %d to %d: %w", from, to-1, err)
+	}
+	// a segment that can't estimate every coefficient changes the degrees of
+	// freedom of the test
+	if aliased := s.(OlsSummary).Aliased(); aliased != nil {
+		return 0, fmt.Errorf("%w: rows %d to %d don't determine the coefficients of %v", SingularDesignError, from, to-1, aliased)
+	}
+	return s.SumOfSquares(), nil
+}
+
+// chowF returns the Chow test of a break before row breakIdx given the
+// residual sum of squares of the pooled fit.
+func chowF(x *DataFrame, y []float64, breakIdx, k int, pooled float64, o options) (TestResult, error) {
+	before, err := segmentRSS(x, y, 0, breakIdx, o)
+	if err != nil {
+		return TestResult{}, err
+	}
+	after, err := segmentRSS(x, y, breakIdx, len(y), o)
+	if err != nil {
+		return TestResult{}, err
+	}
+	df1, df2 := float64(k), float64(len(y)-2*k)
+	f := ((pooled - before - after) / df1) / ((before + after) / df2)
+	return TestResult{
+		Statistic: f,
+		DF:        df1,
+		DF2:       df2,
+		PValue:    fSurvival(f, df1, df2),
+	}, nil
+}
+
+// BreakScan is the Chow F statistic at each of a set of candidate breaks.
+type BreakScan struct {
+	Breaks []int
+	F      []float64
+
+	// SupF is the largest of the statistics, at the break Break.
+	SupF  float64
+	Break int
+}
+
+// ChowScan computes the Chow test statistic of ChowTest for a break before
+// each row in breaks, to locate a break whose position isn't known, as
+// strucchange's Fstats does. Each candidate must leave both segments k + 1
+// observations. The largest statistic, sup-F, comes of a search over the
+// candidates, so it doesn't have the F distribution of any one of them: its
+// critical values are those of Andrews (1993), which depend on the share of
+// the rows the candidates span and are much larger than the F's. The p-values
+// of the individual tests overstate the evidence for the break at Break.
+func ChowScan(x *DataFrame, y []float64, breaks []int, opts ...Option) (*BreakScan, error) {
+	if len(breaks) == 0 {
+		return nil, fmt.Errorf("no candidate breaks")
+	}
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	k := x.Cols()
+	if o.intercept {
+		k++
+	}
+	for _, b := range breaks {
+		if err := checkBreak(n, k, b); err != nil {
+			return nil, err
+		}
+	}
+	pooled, err := segmentRSS(x, y, 0, n, o)
+	if err != nil {
+		return nil, err
+	}
+	scan := &BreakScan{
+		Breaks: append([]int(nil), breaks...),
+		F:      make([]float64, len(breaks)),
+		SupF:   math.Inf(-1),
+	}
+	for i, b := range breaks {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, err := chowF(x, y, b, k, pooled, o)
+		if err != nil {
+			return nil, err
+		}
+		scan.F[i] = res.Statistic
+		if res.Statistic > scan.SupF {
+			scan.SupF, scan.Break = res.Statistic, b
+		}
+	}
+	return scan, nil
+}
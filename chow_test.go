@@ -0,0 +1,65 @@
+package glasso
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestChowTest(t *testing.T) {
+	// the F statistic that strucchange's sctest(stack.loss ~ ., data =
+	// stackloss, type = "Chow", point = 10) defines, hand-derived from the
+	// residual sums of squares of the three fits in exact rational
+	// arithmetic, and its p-value from a 50-digit incomplete beta function;
+	// strucchange hasn't been run against these
+	res, err := ChowTest(NewDataFrame(data), y, 10)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 1.993832618501208, 1e-10)
+	assert.Equal(t, 4.0, res.DF)
+	assert.Equal(t, 13.0, res.DF2)
+	assertClose(t, res.PValue, 0.15489465289112297, 1e-10)
+}
+
+func TestChowScan(t *testing.T) {
+	scan, err := ChowScan(NewDataFrame(data), y, []int{8, 10, 12})
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, scan.F, []float64{2.11106303533915, 1.993832618501208, 2.520047674243351}, 1e-10)
+	assert.Equal(t, 12, scan.Break)
+	assertClose(t, scan.SupF, 2.520047674243351, 1e-10)
+	for i, b := range scan.Breaks {
+		res, err := ChowTest(NewDataFrame(data), y, b)
+		assert.Equal(t, nil, err)
+		assertClose(t, scan.F[i], res.Statistic, 1e-12)
+	}
+}
+
+func TestChowTestThroughOrigin(t *testing.T) {
+	// a break in the slope of a line through the origin
+	rows := [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}}
+	response := []float64{1.1, 1.9, 3.2, 3.9, 10.1, 12.2, 13.8, 16.1}
+	res, err := ChowTest(NewDataFrame(rows), response, 4, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1.0, res.DF)
+	assert.Equal(t, 6.0, res.DF2)
+	assert.T(t, res.PValue < 0.001)
+}
+
+func TestChowTestErrors(t *testing.T) {
+	x := NewDataFrame(data)
+	for _, b := range []int{0, 4, 17, 21} {
+		_, err := ChowTest(x, y, b)
+		assert.T(t, errors.Is(err, TooFewObservationsError))
+	}
+	_, err := ChowTest(x, y[1:], 10)
+	assert.Equal(t, DimensionError, err)
+	_, err = ChowScan(x, y, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = ChowScan(x, y, []int{10, 3})
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+
+	// in the first segment the third column is the sum of the first two
+	rows := [][]float64{{1, 2, 3}, {2, 1, 3}, {3, 5, 8}, {4, 2, 6}, {5, 7, 12}, {6, 1, 7}, {1, 1, 1}, {2, 7, 3}, {5, 1, 2}, {3, 3, 9}, {4, 8, 1}, {6, 2, 5}}
+	_, err = ChowTest(NewDataFrame(rows), []float64{1, 3, 2, 5, 4, 6, 2, 4, 1, 3, 5, 2}, 6)
+	assert.T(t, errors.Is(err, SingularDesignError))
+}
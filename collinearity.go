@@ -0,0 +1,142 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Collinearity holds the collinearity diagnostics of Belsley, Kuh & Welsch (1980).
+//
+// The design matrix is decomposed as X = UDV', and the variance of each
+// coefficient is split across the singular values:
+//
+// var(\beta_j) \propto \sum_k v_{jk}^2 / d_k^2
+//
+// A near dependency shows up as a large condition index d_max / d_k whose
+// component accounts for a large share of the variance of two or more coefficients.
+type Collinearity struct {
+	Values  []float64 // singular values of the (scaled) design, largest first
+	Indices []float64 // condition indices d_max / d_k
+
+	// Proportions is a p x p matrix whose (k, j) entry is the proportion of the
+	// variance of the jth coefficient associated with the kth singular value.
+	// Each column sums to one, and is labeled by the name of its coefficient.
+	Proportions *DataFrame
+}
+
+// CollinearityDiagnostics computes the condition indices and variance-decomposition
+// proportions of the design matrix of the model, including the intercept column.
+// If scale is true, as recommended by Belsley, the columns are first scaled to unit length.
+func CollinearityDiagnostics(m Summary, scale bool) (*Collinearity, error) {
+	x := m.Data().Data()
+	n, p := x.Dims()
+	names := CoefficientNames(m)
+	if scale {
+		for j := 0; j < p; j++ {
+			col := mat.Col(nil, j, x)
+			norm := math.Sqrt(sum(prod(col, col)))
+			if norm == 0 {
+				return nil, fmt.Errorf("column %q is identically zero", names[j])
+			}
+			x.SetCol(j, multSlice(col, 1/norm))
+		}
+	}
+
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(x, mat.SVDThin); !ok {
+		return nil, fmt.Errorf("singular value decomposition of the %d x %d design failed", n, p)
+	}
+	d := svd.Values(nil)
+	v := &mat.Dense{}
+	svd.VTo(v)
+
+	indices := make([]float64, p)
+	for k := range d {
+		indices[k] = d[0] / d[k]
+	}
+
+	// phi_kj = v_jk^2 / d_k^2, normalized over k
+	props := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		total := 0.0
+		for k := 0; k < p; k++ {
+			phi := v.At(j, k) * v.At(j, k) / (d[k] * d[k])
+			props.Set(k, j, phi)
+			total += phi
+		}
+		for k := 0; k < p; k++ {
+			props.Set(k, j, props.At(k, j)/total)
+		}
+	}
+
+	proportions := MatToDF(props)
+	proportions.labels = names
+	return &Collinearity{
+		Values:      d,
+		Indices:     indices,
+		Proportions: proportions,
+	}, nil
+}
+
+// Flagged returns the components with a condition index above 30 that account
+// for more than half of the variance of at least two coefficients, which
+// Belsley suggests indicate a damaging near dependency.
+func (c *Collinearity) Flagged() []int {
+	var flagged []int
+	for k, index := range c.Indices {
+		if index <= 30 {
+			continue
+		}
+
+		large := 0
+		for _, prop := range c.Proportions.GetRow(k) {
+			if prop > 0.5 {
+				large++
+			}
+		}
+		if large >= 2 {
+			flagged = append(flagged, k)
+		}
+	}
+	return flagged
+}
+
+// collinearColumns returns a SingularDesignError naming the first column of x
+// that is a linear combination of the columns before it, and the columns it is
+// a combination of, or nil if x has full rank.
+func collinearColumns(x *mat.Dense, names []string) error {
+	n, p := x.Dims()
+	for j := 0; j < p; j++ {
+		prefix := mat.NewDense(n, j+1, nil)
+		for k := 0; k <= j; k++ {
+			prefix.SetCol(k, mat.Col(nil, k, x))
+		}
+		if !rankDeficient(prefix) {
+			continue
+		}
+		xj := mat.Col(nil, j, x)
+		if j == 0 || sum(prod(xj, xj)) == 0 {
+			return fmt.Errorf("%w: column %q is identically zero", SingularDesignError, names[j])
+		}
+		before := mat.NewDense(n, j, nil)
+		for k := 0; k < j; k++ {
+			before.SetCol(k, mat.Col(nil, k, x))
+		}
+		fit, err := leastSquares(before, mat.NewDense(n, 1, xj))
+		if err != nil {
+			return nil
+		}
+		var with []string
+		for k, b := range fit.betas {
+			xk := mat.Col(nil, k, x)
+			if math.Abs(b)*math.Sqrt(sum(prod(xk, xk))) > 1e-8*math.Sqrt(sum(prod(xj, xj))) {
+				with = append(with, fmt.Sprintf("%q", names[k]))
+			}
+		}
+		return fmt.Errorf("%w: column %q is collinear with %s", SingularDesignError, names[j], strings.Join(with, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestCollinearityLongley(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+
+	// perturb::colldiag(lm(Employed ~ ., longley))
+	expected := []float64{1, 9.142, 12.256, 25.337, 230.424, 1048.080, 43275.044}
+	c, err := CollinearityDiagnostics(s, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, roundAll(c.Indices), expected)
+
+	for j := 0; j < c.Proportions.Cols(); j++ {
+		assertClose(t, sum(c.Proportions.GetCol(j)), 1, 1e-12)
+	}
+
+	// the smallest singular value carries both the intercept and Year
+	flagged := c.Flagged()
+	assert.T(t, len(flagged) > 0)
+	assert.Equal(t, flagged[len(flagged)-1], 6)
+	last := c.Proportions.GetRow(6)
+	assert.T(t, last[0] > 0.5 && last[6] > 0.5)
+}
+
+func TestCollinearityOrthogonal(t *testing.T) {
+	// orthogonal contrasts: every scaled singular value is one
+	rows := [][]float64{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), []float64{1, 2, 4, 3})
+	assert.Equal(t, nil, err)
+
+	c, err := CollinearityDiagnostics(s, true)
+	assert.Equal(t, nil, err)
+	for _, index := range c.Indices {
+		assertClose(t, index, 1, 1e-10)
+	}
+	assert.Equal(t, len(c.Flagged()), 0)
+}
@@ -0,0 +1,209 @@
+package glasso
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// FitComparison is the row of a ComparisonTable for one model.
+type FitComparison struct {
+	Name         string
+	Coefficients int     // the number of estimable coefficients, the intercept included
+	DF           float64 // the effective degrees of freedom, Coefficients but for a Smoother
+
+	AIC, AICc, BIC   float64
+	AdjustedRSquared float64 // NaN for a summary that has none
+	Cp               float64 // Mallows' Cp, with the residual variance of the largest model
+	ResidualStdError float64 // sqrt(RSS / (n - DF)), NaN for a model that fits exactly
+
+	// DeltaAIC is the AIC less the smallest of the table's, and AkaikeWeight
+	// is exp(-DeltaAIC / 2) as a proportion of its sum over the models, the
+	// weight of evidence for the model among them.
+	DeltaAIC, AkaikeWeight float64
+}
+
+// ComparisonTable lines up the criteria of a set of candidate models fit to
+// the same response, best first.
+type ComparisonTable struct {
+	Fits []FitComparison
+}
+
+// MallowsCp returns Mallows' Cp of the model sub against the model full that
+// it is nested in,
+//
+// C_p = RSS_{sub} / \hat\sigma^2_{full} - n + 2p_{sub}
+//
+// with \hat\sigma^2_{full} = RSS_{full} / (n - p_full) and p counting the
+// coefficients, the intercept included. A submodel without bias has C_p
+// near p, and the full model's is its own p. The models must have been fit to
+// the same response, or it is a DimensionError, and every column of sub's
+// design must be one of full's.
+func MallowsCp(sub, full Summary) (float64, error) {
+	if err := sameResponse(sub, full); err != nil {
+		return 0, err
+	}
+	x, z := sub.Data(), full.Data()
+	for j := 0; j < x.Cols(); j++ {
+		col := x.GetCol(j)
+		nested := false
+		for k := 0; k < z.Cols() && !nested; k++ {
+			nested = equalSlices(col, z.GetCol(k))
+		}
+		if !nested {
+			return 0, fmt.Errorf("column %d of the submodel isn't a column of the full model", j)
+		}
+	}
+	sigma2, err := MseAdjusted(full)
+	if err != nil {
+		return 0, fmt.Errorf("the full model: %w", err)
+	}
+	return mallowsCp(sub.SumOfSquares(), sigma2, x.Rows(), float64(rankOf(sub))), nil
+}
+
+// mallowsCp returns RSS / sigma2 - n + 2p.
+func mallowsCp(rss, sigma2 float64, n int, p float64) float64 {
+	return rss/sigma2 - float64(n) + 2*p
+}
+
+// residualVariance returns RSS / (n - df) for the effectiveDF of the model, or
+// an error if it fits exactly.
+func residualVariance(m Summary) (float64, error) {
+	n, df := float64(m.Data().Rows()), effectiveDF(m)
+	if n <= df {
+		return 0, TooFewObservationsError
+	}
+	return m.SumOfSquares() / (n - df), nil
+}
+
+// sameResponse checks that the models were fit to the same response.
+func sameResponse(m, other Summary) error {
+	y, z := m.Response(), other.Response()
+	if len(y) != len(z) {
+		return fmt.Errorf("%w: the models were fit to %d and %d observations", DimensionError, len(y), len(z))
+	}
+	if !equalSlices(y, z) {
+		return fmt.Errorf("%w: the models were fit to different responses", DimensionError)
+	}
+	return nil
+}
+
+func equalSlices(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareFits compares the models, keyed by name, by their information
+// criteria, adjusted R^2, Mallows' Cp and residual standard error, sorted by
+// criterion from the lowest to the highest, or by AIC if criterion is nil.
+// Ties are broken by name. Cp is
+//
+// C_p = RSS / \hat\sigma^2 - n + 2p
+//
+// with \hat\sigma^2 the residual variance of the model with the most
+// coefficients, as regsubsets takes that of the full model, which must not fit
+// exactly. Information criteria only compare fits of the same data, so the
+// models must have been fit to the same response, or it is a DimensionError.
+//
+// The coefficients p of a Smoother, such as a RidgeSummary, are its
+// EffectiveDF in every criterion and in the residual variance RSS / (n - p),
+// so that penalized fits are compared with least squares fits of the same
+// predictors on an equal footing.
+func CompareFits(models map[string]Summary, criterion Criterion) (*ComparisonTable, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to compare")
+	}
+	if criterion == nil {
+		criterion = AIC
+	}
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	largest := names[0]
+	for _, name := range names {
+		m := models[name]
+		if err := sameResponse(m, models[names[0]]); err != nil {
+			return nil, fmt.Errorf("models %q and %q: %w", name, names[0], err)
+		}
+		if effectiveDF(m) > effectiveDF(models[largest]) {
+			largest = name
+		}
+	}
+	sigma2, err := residualVariance(models[largest])
+	if err != nil {
+		return nil, fmt.Errorf("the largest model %q: %w", largest, err)
+	}
+
+	n := models[largest].Data().Rows()
+	table := &ComparisonTable{Fits: make([]FitComparison, len(names))}
+	scores := make(map[string]float64, len(names))
+	minAIC := math.Inf(1)
+	for k, name := range names {
+		m := models[name]
+		p := effectiveDF(m)
+		fit := FitComparison{
+			Name:             name,
+			Coefficients:     rankOf(m),
+			DF:               p,
+			AIC:              AIC(m),
+			AICc:             AICc(m),
+			BIC:              BIC(m),
+			AdjustedRSquared: math.NaN(),
+			Cp:               mallowsCp(m.SumOfSquares(), sigma2, n, p),
+			ResidualStdError: math.NaN(),
+		}
+		if r, ok := m.(interface{ AdjustedRSquared() float64 }); ok {
+			fit.AdjustedRSquared = r.AdjustedRSquared()
+		}
+		if mse, err := residualVariance(m); err == nil {
+			fit.ResidualStdError = math.Sqrt(mse)
+		}
+		minAIC = math.Min(minAIC, fit.AIC)
+		scores[name] = criterion(m)
+		table.Fits[k] = fit
+	}
+	total := 0.0
+	for k := range table.Fits {
+		fit := &table.Fits[k]
+		fit.DeltaAIC = fit.AIC - minAIC
+		fit.AkaikeWeight = math.Exp(-fit.DeltaAIC / 2)
+		total += fit.AkaikeWeight
+	}
+	for k := range table.Fits {
+		table.Fits[k].AkaikeWeight /= total
+	}
+	sort.SliceStable(table.Fits, func(a, b int) bool {
+		return scores[table.Fits[a].Name] < scores[table.Fits[b].Name]
+	})
+	return table, nil
+}
+
+func (t *ComparisonTable) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	width := 0
+	for _, f := range t.Fits {
+		if len(f.Name) > width {
+			width = len(f.Name)
+		}
+	}
+	fmt.Fprintf(w, "%*s\tp\tdf\tAIC\tAICc\tBIC\tAdj R-squared\tCp\tSigma\tdelta AIC\tweight\t\n", width, "")
+	for _, f := range t.Fits {
+		fmt.Fprintf(w, "%-*s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.4f\t%.2f\t%.4g\t%.2f\t%.3f\t\n",
+			width, f.Name, f.Coefficients, f.DF, f.AIC, f.AICc, f.BIC, f.AdjustedRSquared, f.Cp, f.ResidualStdError, f.DeltaAIC, f.AkaikeWeight)
+	}
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,132 @@
+package glasso
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// stacklossCandidates fits stack.loss on its first k columns for k = 1, 2, 3.
+func stacklossCandidates(t *testing.T) map[string]Summary {
+	models := make(map[string]Summary)
+	for k, name := range []string{"air", "air+water", "full"} {
+		rows := make([][]float64, len(data))
+		for i, row := range data {
+			rows[i] = row[:k+1]
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+		assert.Equal(t, nil, err)
+		models[name] = s
+	}
+	return models
+}
+
+func TestCompareFits(t *testing.T) {
+	models := stacklossCandidates(t)
+	table, err := CompareFits(models, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(table.Fits))
+
+	full, err := MseAdjusted(models["full"])
+	assert.Equal(t, nil, err)
+	weights := 0.0
+	for k, f := range table.Fits {
+		m := models[f.Name]
+		assert.Equal(t, m.Data().Cols(), f.Coefficients)
+		assert.Equal(t, float64(f.Coefficients), f.DF)
+		assertClose(t, f.AIC, AIC(m), 1e-12)
+		assertClose(t, f.AICc, AICc(m), 1e-12)
+		assertClose(t, f.BIC, BIC(m), 1e-12)
+		assertClose(t, f.AdjustedRSquared, m.(OlsSummary).AdjustedRSquared(), 1e-12)
+		assertClose(t, f.ResidualStdError, m.(OlsSummary).ResidualStandardError(), 1e-12)
+		assertClose(t, f.Cp, m.SumOfSquares()/full-21+2*float64(f.Coefficients), 1e-10)
+		if k > 0 {
+			assert.T(t, f.AIC >= table.Fits[k-1].AIC)
+		}
+		assertClose(t, f.DeltaAIC, f.AIC-table.Fits[0].AIC, 1e-12)
+		assertClose(t, f.AkaikeWeight/table.Fits[0].AkaikeWeight, math.Exp(-f.DeltaAIC/2), 1e-12)
+		weights += f.AkaikeWeight
+	}
+	assertClose(t, weights, 1, 1e-12)
+	assert.Equal(t, 0.0, table.Fits[0].DeltaAIC)
+	// the full model's Cp is its number of coefficients
+	for _, f := range table.Fits {
+		if f.Name == "full" {
+			assertClose(t, f.Cp, 4, 1e-10)
+		}
+	}
+
+	// by another criterion, and by one that prefers the largest adjusted R^2
+	byBIC, err := CompareFits(models, BIC)
+	assert.Equal(t, nil, err)
+	for k := 1; k < len(byBIC.Fits); k++ {
+		assert.T(t, byBIC.Fits[k].BIC >= byBIC.Fits[k-1].BIC)
+	}
+	byR2, err := CompareFits(models, func(m Summary) float64 { return -m.(OlsSummary).AdjustedRSquared() })
+	assert.Equal(t, nil, err)
+	for k := 1; k < len(byR2.Fits); k++ {
+		assert.T(t, byR2.Fits[k].AdjustedRSquared <= byR2.Fits[k-1].AdjustedRSquared)
+	}
+
+	out := table.String()
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.T(t, strings.Contains(lines[0], "AIC") && strings.Contains(lines[0], "weight"))
+	assert.T(t, strings.HasPrefix(strings.TrimSpace(lines[1]), table.Fits[0].Name))
+}
+
+func TestCompareFitsRidge(t *testing.T) {
+	models := stacklossCandidates(t)
+	for _, lambda := range []float64{1, 100} {
+		_, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: lambda, Standardize: true}).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		models[fmt.Sprintf("ridge %v", lambda)] = s
+	}
+	table, err := CompareFits(models, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 5, len(table.Fits))
+
+	// the full least squares fit has the most degrees of freedom, and so
+	// gives the residual variance of Cp
+	full, err := MseAdjusted(models["full"])
+	assert.Equal(t, nil, err)
+	for _, f := range table.Fits {
+		m := models[f.Name]
+		assertClose(t, f.AIC, AIC(m), 1e-12)
+		r, ok := m.(*RidgeSummary)
+		if !ok {
+			continue
+		}
+		edf := r.EffectiveDF()
+		assert.Equal(t, edf, f.DF)
+		assert.T(t, edf > 1 && edf < 4)
+		assertClose(t, f.AIC, -2*LogLikelihood(r)+2*(edf+1), 1e-12)
+		assertClose(t, f.Cp, r.SumOfSquares()/full-21+2*edf, 1e-10)
+		assertClose(t, f.ResidualStdError, math.Sqrt(r.SumOfSquares()/(21-edf)), 1e-12)
+	}
+}
+
+func TestCompareFitsResponses(t *testing.T) {
+	models := stacklossCandidates(t)
+	shifted := append([]float64(nil), y...)
+	shifted[3]++
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), shifted)
+	assert.Equal(t, nil, err)
+	models["shifted"] = s
+	_, err = CompareFits(models, nil)
+	assert.T(t, errors.Is(err, DimensionError), err)
+
+	delete(models, "shifted")
+	_, s, err = NewOlsTrainer().Train(NewDataFrame(data[:20]), y[:20])
+	assert.Equal(t, nil, err)
+	models["shorter"] = s
+	_, err = CompareFits(models, nil)
+	assert.T(t, errors.Is(err, DimensionError), err)
+
+	_, err = CompareFits(nil, nil)
+	assert.NotEqual(t, nil, err)
+}
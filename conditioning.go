@@ -0,0 +1,168 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultMaxCondition is the condition number of the scaled design above which
+// a least squares fit warns that it is ill-conditioned. A design this close to
+// singular loses about six of the sixteen digits of a float64 in its
+// coefficients, well before aliasing drops a column as a linear combination
+// of the others.
+const DefaultMaxCondition = 1e6
+
+// A WarningKind is the kind of condition a Warning describes.
+type WarningKind int
+
+const (
+	// IllConditioned warns of a design whose condition number exceeds the
+	// limit of WithMaxCondition, so that its coefficients are sensitive to
+	// small changes in the data and to rounding.
+	IllConditioned WarningKind = iota
+
+	// ClusteredResiduals warns of residuals correlated within groups, whose
+	// design effect exceeds MaxDesignEffect, so that the standard errors that
+	// assume independent errors are too small; see ResidualICC.
+	ClusteredResiduals
+)
+
+func (k WarningKind) String() string {
+	switch k {
+	case IllConditioned:
+		return "ill-conditioned"
+	case ClusteredResiduals:
+		return "clustered residuals"
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(k))
+}
+
+// A Warning describes a condition of a fit that doesn't fail it but may make
+// its results unreliable.
+type Warning struct {
+	Kind    WarningKind
+	Message string
+
+	// Columns are the names of the columns of the design implicated, with
+	// "(Intercept)" for the intercept.
+	Columns []string
+
+	// Value is the quantity that exceeded its limit, such as the condition
+	// number, and Limit that limit.
+	Value, Limit float64
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// designCondition returns the condition number d_max / d_min of the design
+// X = QR with its columns scaled to unit length, from the singular values of
+// the p x p factor R, whose columns have the lengths of those of X, and the
+// names of the columns involved in its nearest dependency: those with more than
+// half of the variance of their coefficient associated with d_min, as
+// Collinearity.Flagged counts them, or the one with the most if none has.
+// Scaling the columns makes the condition number independent of their units,
+// which don't change the accuracy of the fit.
+func designCondition(r mat.Matrix, names []string) (float64, []string) {
+	_, p := r.Dims()
+	scaled := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		col := mat.Col(nil, j, r)
+		norm := math.Sqrt(sum(prod(col, col)))
+		if norm == 0 {
+			return math.Inf(1), []string{names[j]}
+		}
+		scaled.SetCol(j, multSlice(col, 1/norm))
+	}
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(scaled, mat.SVDThin); !ok {
+		return math.NaN(), nil
+	}
+	d := svd.Values(nil)
+	v := &mat.Dense{}
+	svd.VTo(v)
+
+	last := d[p-1]
+	if !(last > 0) {
+		most := 0
+		for j := 1; j < p; j++ {
+			if math.Abs(v.At(j, p-1)) > math.Abs(v.At(most, p-1)) {
+				most = j
+			}
+		}
+		return math.Inf(1), []string{names[most]}
+	}
+	var implicated []string
+	most, largest := 0, 0.0
+	for j := 0; j < p; j++ {
+		total := 0.0
+		for k := 0; k < p; k++ {
+			total += v.At(j, k) * v.At(j, k) / (d[k] * d[k])
+		}
+		share := v.At(j, p-1) * v.At(j, p-1) / (last * last) / total
+		if share > 0.5 {
+			implicated = append(implicated, names[j])
+		}
+		if share > largest {
+			most, largest = j, share
+		}
+	}
+	if implicated == nil {
+		implicated = []string{names[most]}
+	}
+	return d[0] / last, implicated
+}
+
+// fitCondition returns the designCondition of the design of a least squares
+// fit from its R factor.
+func fitCondition(fit *lsFit, names []string) (float64, []string) {
+	if fit.qr != nil {
+		r := &mat.Dense{}
+		fit.qr.RTo(r)
+		_, p := r.Dims()
+		return designCondition(r.Slice(0, p, 0, p), names)
+	}
+	return designCondition(fit.r, names)
+}
+
+// Condition returns the condition number of the design of the fit, or NaN if
+// it has none. For a least squares fit it is that of the design with its
+// columns scaled to unit length, and of the standardized predictors for a fit
+// by WithStandardize(true), so that it doesn't depend on their units. For a fit
+// by FitSVD it is s_max/s_min of the design as given, over every singular
+// value including those discarded, which is infinite for a singular design.
+func (o *OLS) Condition() float64 {
+	if o.condition == 0 {
+		return math.NaN()
+	}
+	return o.condition
+}
+
+// Warnings returns the conditions found at fit time that may make the fit
+// unreliable, such as an ill-conditioned design, or nil if there are none. A
+// least squares fit whose design has a Condition above the limit of
+// WithMaxCondition warns that it is IllConditioned, naming the columns of the
+// near dependency; standardizing the predictors, dropping one of those columns
+// or a fit by FitSVD or NewRidgeTrainer may be more accurate.
+func (o *OLS) Warnings() []Warning {
+	return o.warnings
+}
+
+// checkCondition adds a warning that the fit is ill-conditioned if its
+// condition number exceeds limit.
+func (o *OLS) checkCondition(limit float64) {
+	if !(o.condition > limit) {
+		return
+	}
+	o.warnings = append(o.warnings, Warning{
+		Kind: IllConditioned,
+		Message: fmt.Sprintf("the design has a condition number of %.3g, above %.3g, so its coefficients may be inaccurate; the columns %q are nearly collinear",
+			o.condition, limit, o.implicated),
+		Columns: o.implicated,
+		Value:   o.condition,
+		Limit:   limit,
+	})
+}
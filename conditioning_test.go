@@ -0,0 +1,103 @@
+package glasso
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// nearlyCollinear returns predictors u, v and w = u + v + noise of the given
+// size, and an unrelated predictor z, with a response on u, v and z.
+func nearlyCollinear(noise float64) (*DataFrame, []float64) {
+	rng := rand.New(rand.NewSource(1))
+	rows := make([][]float64, 50)
+	y := make([]float64, len(rows))
+	for i := range rows {
+		u, v, z := rng.NormFloat64(), 10+rng.NormFloat64(), rng.NormFloat64()
+		rows[i] = []float64{u, z, v, u + v + noise*rng.NormFloat64()}
+		y[i] = 1 + u - v + 2*z + rng.NormFloat64()
+	}
+	return NewDataFrame(rows, []string{"u", "z", "v", "w"}), y
+}
+
+func TestConditionNumber(t *testing.T) {
+	// the largest scaled condition index of Belsley's diagnostics
+	model, s, err := NewOlsTrainer().Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	c, err := CollinearityDiagnostics(s, true)
+	assert.Equal(t, nil, err)
+	m := model.(*OLS)
+	assertClose(t, m.Condition(), c.Indices[len(c.Indices)-1], 1e-9*m.Condition())
+	assert.Equal(t, 0, len(m.Warnings()))
+
+	// by the normal equations too
+	model, _, err = NewOlsTrainer(WithSolver(CholeskySolver)).Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	assertClose(t, model.(*OLS).Condition(), m.Condition(), 1e-6*m.Condition())
+
+	// Longley's design is famously ill-conditioned, but not enough to warn
+	model, _, err = NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	assert.T(t, model.(*OLS).Condition() > 1000)
+	assert.Equal(t, 0, len(model.(*OLS).Warnings()))
+
+	// a fit by FitSVD has that of its design as given, whose columns are on
+	// very different scales
+	svd, _, err := FitSVD(NewDataFrame(data), y, 0)
+	assert.Equal(t, nil, err)
+	assert.T(t, svd.rawCondition)
+	assert.T(t, !m.rawCondition)
+	assert.T(t, svd.Condition() > 10*m.Condition())
+}
+
+func TestIllConditionedWarning(t *testing.T) {
+	x, z := nearlyCollinear(1)
+	model, _, err := NewOlsTrainer().Train(x, z)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(model.(*OLS).Warnings()))
+
+	for _, opts := range [][]Option{nil, {WithStandardize(true)}} {
+		x, z = nearlyCollinear(1e-6)
+		model, _, err = NewOlsTrainer(opts...).Train(x, z)
+		assert.Equal(t, nil, err)
+		m := model.(*OLS)
+		assert.T(t, m.Condition() > DefaultMaxCondition)
+		warnings := m.Warnings()
+		assert.Equal(t, 1, len(warnings))
+		w := warnings[0]
+		assert.Equal(t, IllConditioned, w.Kind)
+		assert.Equal(t, []string{"u", "v", "w"}, w.Columns)
+		assert.Equal(t, m.Condition(), w.Value)
+		assert.Equal(t, DefaultMaxCondition, w.Limit)
+	}
+
+	// weighted fits warn too, and the limit is configurable
+	x, z = nearlyCollinear(1e-6)
+	weights := make([]float64, x.Rows())
+	for i := range weights {
+		weights[i] = 1 + float64(i%3)
+	}
+	model, _, err = NewWlsTrainer(weights).Train(x, z)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(model.(*OLS).Warnings()))
+	x, z = nearlyCollinear(1e-6)
+	model, _, err = NewWlsTrainer(weights, WithMaxCondition(math.Inf(1))).Train(x, z)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(model.(*OLS).Warnings()))
+	model, _, err = NewOlsTrainer(WithMaxCondition(10)).Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(model.(*OLS).Warnings()))
+
+	// the warnings are kept by the encodings
+	x, z = nearlyCollinear(1e-6)
+	model, _, err = NewOlsTrainer().Train(x, z)
+	assert.Equal(t, nil, err)
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assert.Equal(t, model.(*OLS).Warnings(), decoded.Warnings())
+}
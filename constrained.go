@@ -0,0 +1,228 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type constrainedTrainer struct {
+	a *DataFrame
+	b []float64
+}
+
+// NewConstrainedTrainer returns a Trainer for least squares subject to the
+// linear equality constraints A\beta = b, such as coefficients that sum to one
+// or two coefficients that are equal. The columns of a are the coefficients in
+// the order of the fit, the intercept first, and each row is a constraint.
+func NewConstrainedTrainer(a *DataFrame, b []float64) Trainer {
+	return &constrainedTrainer{
+		a: a,
+		b: b,
+	}
+}
+
+// Train fits the model by the null space method. With A' = QR and Q = [Q_1 Q_2],
+// every solution of the constraints is \beta = \beta_0 + Q_2\gamma for the
+// particular solution \beta_0 = Q_1 R^{-T} b, and \gamma is the least squares
+// fit of y - X\beta_0 on XQ_2. The covariance of the coefficients is
+//
+// \sigma^2 Q_2 (Q_2'X'XQ_2)^-1 Q_2'
+//
+// with \sigma^2 = RSS / (n - p + q) for q constraints, which is the
+// unconstrained covariance less the part the constraints determine.
+//
+// Constraints that are linear combinations of the others are an error, with a
+// message that says whether they contradict the others or are redundant, as
+// are constraints that leave the coefficients unidentifiable.
+//
+// The DataFrame is not modified, and the summary is a *ConstrainedSummary.
+func (c *constrainedTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	if c.a == nil {
+		return nil, nil, fmt.Errorf("constraints not set")
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	design := x.Copy()
+	design.labels = x.Labels()
+	design.PushCol(rep(1, n))
+	p, q := design.Cols(), c.a.Rows()
+	if c.a.Cols() != p {
+		return nil, nil, fmt.Errorf("constraint matrix has %d columns for %d coefficients (including the intercept)", c.a.Cols(), p)
+	}
+	if len(c.b) != q {
+		return nil, nil, fmt.Errorf("%d constraints have %d right hand sides", q, len(c.b))
+	}
+	if err := checkConstraints(c.a.X, c.b); err != nil {
+		return nil, nil, err
+	}
+	if n <= p-q {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d free coefficients", TooFewObservationsError, n, p-q)
+	}
+
+	// A' = QR, so A\beta = b is R'Q'\beta = b
+	at := mat.DenseCopyOf(c.a.X.T())
+	qr := factorize(at)
+	qq, r := &mat.Dense{}, &mat.Dense{}
+	qr.QTo(qq)
+	qr.RTo(r)
+	u := make([]float64, q)
+	for j := 0; j < q; j++ {
+		s := c.b[j]
+		for k := 0; k < j; k++ {
+			s -= r.At(k, j) * u[k]
+		}
+		u[j] = s / r.At(j, j)
+	}
+	betas := make([]float64, p)
+	for j := range betas {
+		betas[j] = sum(prod(qq.RawRowView(j)[:q], u))
+	}
+
+	vcov := mat.NewDense(p, p, nil)
+	if q < p {
+		free := mat.DenseCopyOf(qq.Slice(0, p, q, p))
+		xn := &mat.Dense{}
+		xn.Mul(design.X, free)
+		if rankDeficient(xn) {
+			return nil, nil, fmt.Errorf("%w: constrained coefficients are not identifiable on the solutions of the constraints", SingularDesignError)
+		}
+		z := mat.NewDense(n, 1, nil)
+		for i := range y {
+			z.Set(i, 0, y[i]-sum(prod(design.X.RawRowView(i), betas)))
+		}
+		fit, err := leastSquares(xn, z)
+		if err != nil {
+			return nil, nil, err
+		}
+		for j := range betas {
+			betas[j] += sum(prod(free.RawRowView(j), fit.betas))
+		}
+		inverse, err := glmVCov(xn, rep(1, n), 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		left := &mat.Dense{}
+		left.Mul(free, inverse.X)
+		vcov.Mul(left, free.T())
+	}
+
+	fitted := make([]float64, n)
+	residuals := make([]float64, n)
+	for i := range y {
+		fitted[i] = sum(prod(design.X.RawRowView(i), betas))
+		residuals[i] = y[i] - fitted[i]
+	}
+	sigma2 := sum(prod(residuals, residuals)) / float64(n-p+q)
+	vcov.Scale(sigma2, vcov)
+	symmetrize(vcov)
+	for j := 0; j < p; j++ {
+		// rounding leaves the variance of a fixed coefficient about zero
+		vcov.Set(j, j, math.Max(vcov.At(j, j), 0))
+	}
+
+	// X'e = A'\lambda, which holds exactly at the solution
+	xte := mat.NewDense(p, 1, nil)
+	xte.Mul(design.X.T(), mat.NewDense(n, 1, residuals))
+	multipliers, err := leastSquares(at, xte)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &OLS{
+		betas:  betas,
+		n:      n,
+		p:      p,
+		names:  x.Labels(),
+		sigma2: sigma2,
+		vcov:   vcov,
+	}, &ConstrainedSummary{
+		data:        design,
+		betas:       betas,
+		fitted:      fitted,
+		residuals:   residuals,
+		response:    append([]float64(nil), y...),
+		multipliers: multipliers.betas,
+		sigma2:      sigma2,
+		vcov:        MatToDF(vcov),
+	}, nil
+}
+
+// checkConstraints checks that no row of a is a linear combination of the
+// rows before it.
+func checkConstraints(a *mat.Dense, b []float64) error {
+	q, p := a.Dims()
+	for j := 0; j < q; j++ {
+		row := a.RawRowView(j)
+		if math.IsNaN(b[j]) || math.IsInf(b[j], 0) {
+			return fmt.Errorf("constraint %d has a right hand side of %v", j, b[j])
+		}
+		size := math.Sqrt(sum(prod(row, row)))
+		if math.IsNaN(size) || math.IsInf(size, 0) {
+			return fmt.Errorf("constraint %d is not finite", j)
+		}
+
+		// the rows before it are independent, so there are at most p of them
+		implied, distance := 0.0, size
+		if j > 0 {
+			previous := mat.DenseCopyOf(a.Slice(0, j, 0, p).T())
+			fit, err := leastSquares(previous, mat.NewDense(p, 1, append([]float64(nil), row...)))
+			if err != nil {
+				return err
+			}
+			implied = sum(prod(fit.betas, b[:j]))
+			distance = math.Sqrt(sum(prod(fit.residuals, fit.residuals)))
+		}
+		if distance > 1e-10*size {
+			continue
+		}
+		if math.Abs(b[j]-implied) > 1e-8*(1+math.Abs(b[j])) {
+			return fmt.Errorf("constraint %d contradicts the constraints before it", j)
+		}
+		return fmt.Errorf("constraint %d is redundant, since it follows from the constraints before it", j)
+	}
+	return nil
+}
+
+// ConstrainedSummary summarizes an equality constrained least squares fit.
+// The diagnostics that assume an unconstrained fit, such as VarCov and
+// LeveragePoints, don't apply to it.
+type ConstrainedSummary struct {
+	data        *DataFrame
+	betas       []float64
+	fitted      []float64
+	residuals   []float64
+	response    []float64
+	multipliers []float64
+	sigma2      float64
+	vcov        *DataFrame
+}
+
+func (c *ConstrainedSummary) Data() *DataFrame        { return c.data }
+func (c *ConstrainedSummary) Coefficients() []float64 { return c.betas }
+func (c *ConstrainedSummary) Residuals() []float64    { return c.residuals }
+func (c *ConstrainedSummary) Yhat() []float64         { return c.fitted }
+func (c *ConstrainedSummary) Response() []float64     { return c.response }
+
+func (c *ConstrainedSummary) SumOfSquares() float64 {
+	return sum(prod(c.residuals, c.residuals))
+}
+
+// Multipliers returns the Lagrange multipliers \lambda of the constraints,
+// one per constraint, for which X'(y - X\beta) = A'\lambda. A large multiplier
+// marks a constraint that the data pull against.
+func (c *ConstrainedSummary) Multipliers() []float64 { return c.multipliers }
+
+// Sigma2 returns the residual variance RSS / (n - p + q).
+func (c *ConstrainedSummary) Sigma2() float64 { return c.sigma2 }
+
+// VarCov returns the variance-covariance matrix of the constrained
+// coefficients, which is singular along the constraints.
+func (c *ConstrainedSummary) VarCov() *DataFrame { return c.vcov }
+
+// StandardErrors returns the standard errors of the coefficients, which are
+// zero for coefficients that the constraints fix.
+func (c *ConstrainedSummary) StandardErrors() []float64 { return StandardErrors(c.vcov) }
@@ -0,0 +1,165 @@
+package glasso
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConstrainedTrainer(t *testing.T) {
+	// an orthogonal design, with X'X = 8I, so that the constrained fit is
+	//
+	// \beta = c - A'(AA')^-1 (Ac - b), V = \sigma^2 / 8 (I - A'(AA')^-1 A)
+	//
+	// for the least squares coefficients c = X'y / 8
+	rows := [][]float64{{1, 1, 1}, {-1, 1, -1}, {1, -1, -1}, {-1, -1, 1}}
+	x := NewDataFrame(append(append([][]float64(nil), rows...), rows...))
+	response := []float64{3, 1, 4, 1, 5, 9, 2, 6}
+	// b1 + b2 + b3 = 1 and b1 = b2
+	a := NewDataFrame([][]float64{{0, 1, 1, 1}, {0, 1, -1, 0}})
+	model, s, err := NewConstrainedTrainer(a, []float64{1, 0}).Train(x, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Cols() != 3 {
+		t.Errorf("Train changed the number of columns of the DataFrame to %d", x.Cols())
+	}
+	summary := s.(*ConstrainedSummary)
+
+	c := make([]float64, 4)
+	for i, v := range response {
+		c[0] += v / 8
+		for j := 0; j < 3; j++ {
+			c[j+1] += x.X.At(i, j) * v / 8
+		}
+	}
+	d1, d2 := (c[1]+c[2]+c[3]-1)/3, (c[1]-c[2])/2
+	want := []float64{c[0], c[1] - d1 - d2, c[2] - d1 + d2, c[3] - d1}
+	b := summary.Coefficients()
+	for j := range want {
+		if math.Abs(b[j]-want[j]) > 1e-12 {
+			t.Errorf("coefficient %d is %v, want %v", j, b[j], want[j])
+		}
+	}
+	if math.Abs(b[1]+b[2]+b[3]-1) > 1e-12 || math.Abs(b[1]-b[2]) > 1e-12 {
+		t.Errorf("coefficients %v don't satisfy the constraints", b)
+	}
+	if m := summary.Multipliers(); math.Abs(m[0]-8*d1) > 1e-12 || math.Abs(m[1]-8*d2) > 1e-12 {
+		t.Errorf("got multipliers %v, want [%v %v]", m, 8*d1, 8*d2)
+	}
+
+	sigma2 := summary.SumOfSquares() / 6
+	if math.Abs(summary.Sigma2()-sigma2) > 1e-12 {
+		t.Errorf("residual variance is %v, want %v", summary.Sigma2(), sigma2)
+	}
+	projection := [][]float64{
+		{1, 0, 0, 0},
+		{0, 1. / 6, 1. / 6, -1. / 3},
+		{0, 1. / 6, 1. / 6, -1. / 3},
+		{0, -1. / 3, -1. / 3, 2. / 3},
+	}
+	vcov := summary.VarCov()
+	for j := range projection {
+		for k := range projection[j] {
+			if want := sigma2 / 8 * projection[j][k]; math.Abs(vcov.X.At(j, k)-want) > 1e-12 {
+				t.Errorf("covariance (%d, %d) is %v, want %v", j, k, vcov.X.At(j, k), want)
+			}
+		}
+	}
+	if got, want := model.Predict([]float64{1, 2, 3}), b[0]+b[1]+2*b[2]+3*b[3]; math.Abs(got-want) > 1e-12 {
+		t.Errorf("prediction is %v, want %v", got, want)
+	}
+}
+
+func TestConstrainedEqual(t *testing.T) {
+	// forcing Air.Flow and Water.Temp to have the same coefficient is least
+	// squares on their sum
+	a := NewDataFrame([][]float64{{0, 1, -1, 0}})
+	_, s, err := NewConstrainedTrainer(a, []float64{0}).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := s.(*ConstrainedSummary)
+	merged := make([][]float64, len(data))
+	for i, row := range data {
+		merged[i] = []float64{row[0] + row[1], row[2]}
+	}
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(merged), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcov, err := VarCov(ols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the coefficients of the sum and Acid.Conc. in the constrained fit
+	index := []int{0, 1, 3}
+	b, se := summary.Coefficients(), summary.StandardErrors()
+	wantSE := StandardErrors(vcov)
+	for k, j := range index {
+		if math.Abs(b[j]-ols.Coefficients()[k]) > 1e-10 {
+			t.Errorf("coefficient %d is %v, want %v", j, b[j], ols.Coefficients()[k])
+		}
+		if math.Abs(se[j]-wantSE[k]) > 1e-10 {
+			t.Errorf("standard error %d is %v, want %v", j, se[j], wantSE[k])
+		}
+	}
+	if math.Abs(b[1]-b[2]) > 1e-12 {
+		t.Errorf("coefficients %v and %v aren't equal", b[1], b[2])
+	}
+	if mse, _ := MseAdjusted(ols); math.Abs(summary.Sigma2()-mse) > 1e-10 {
+		t.Errorf("residual variance is %v, want %v", summary.Sigma2(), mse)
+	}
+
+	// constraints that fix every coefficient
+	fixed := NewDataFrame([][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 1, 1, 0}, {0, 0, 0, 2}})
+	_, s, err = NewConstrainedTrainer(fixed, []float64{-40, 1, 1.5, -0.2}).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{-40, 1, 0.5, -0.1}
+	for j, v := range s.Coefficients() {
+		if math.Abs(v-want[j]) > 1e-12 {
+			t.Errorf("fixed coefficient %d is %v, want %v", j, v, want[j])
+		}
+		if se := s.(*ConstrainedSummary).StandardErrors()[j]; se > 1e-12 {
+			t.Errorf("fixed coefficient %d has standard error %v", j, se)
+		}
+	}
+}
+
+func TestConstrainedInvalid(t *testing.T) {
+	for name, test := range map[string]struct {
+		a       [][]float64
+		b       []float64
+		message string
+	}{
+		"columns":      {[][]float64{{1, 1, 1}}, []float64{1}, "columns"},
+		"sides":        {[][]float64{{0, 1, 1, 1}}, []float64{1, 2}, "right hand sides"},
+		"redundant":    {[][]float64{{0, 1, 1, 0}, {0, 2, 2, 0}}, []float64{1, 2}, "constraint 1 is redundant"},
+		"inconsistent": {[][]float64{{0, 1, 1, 0}, {0, 0, 0, 1}, {0, 1, 1, 1}}, []float64{1, 0, 3}, "constraint 2 contradicts"},
+		"zero":         {[][]float64{{0, 0, 0, 0}}, []float64{1}, "constraint 0 contradicts"},
+		"many":         {[][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}, {1, 1, 1, 1}}, []float64{1, 1, 1, 1, 4}, "constraint 4 is redundant"},
+		"infinite":     {[][]float64{{0, 1, 1, 0}}, []float64{math.Inf(1)}, "right hand side"},
+	} {
+		_, _, err := NewConstrainedTrainer(NewDataFrame(test.a), test.b).Train(NewDataFrame(data), y)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: got error %v, want one about %q", name, err, test.message)
+		}
+	}
+	if _, _, err := NewConstrainedTrainer(nil, nil).Train(NewDataFrame(data), y); err == nil {
+		t.Error("expected an error for no constraints")
+	}
+
+	// a duplicated predictor is identified only if the constraint separates the copies
+	twice := make([][]float64, len(data))
+	for i, row := range data {
+		twice[i] = []float64{row[0], row[0]}
+	}
+	if _, _, err := NewConstrainedTrainer(NewDataFrame([][]float64{{1, 0, 0}}), []float64{0}).Train(NewDataFrame(twice), y); err == nil {
+		t.Error("expected an error for unidentifiable coefficients")
+	}
+	if _, _, err := NewConstrainedTrainer(NewDataFrame([][]float64{{0, 1, -1}}), []float64{0}).Train(NewDataFrame(twice), y); err != nil {
+		t.Errorf("got error %v for identifiable coefficients", err)
+	}
+}
@@ -0,0 +1,193 @@
+package glasso
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ConstantColumnError is returned by PartialCorrelations for data with a column
+// whose values are all the same, which has no correlation with the others.
+var ConstantColumnError = errors.New("column is constant")
+
+// Correlation is a kind of correlation coefficient.
+type Correlation int
+
+const (
+	// PearsonCorrelation is the product-moment correlation of the values, the
+	// default.
+	PearsonCorrelation Correlation = iota
+
+	// SpearmanCorrelation is the Pearson correlation of the ranks of the
+	// values, with tied values given the mean of their ranks as R's cor(method
+	// = "spearman") does.
+	SpearmanCorrelation
+)
+
+func (c Correlation) String() string {
+	switch c {
+	case PearsonCorrelation:
+		return "Pearson"
+	case SpearmanCorrelation:
+		return "Spearman"
+	}
+	return fmt.Sprintf("Correlation(%d)", int(c))
+}
+
+// CorrelationMatrix returns the p x p matrix of the correlations between the
+// columns of the n x p matrix x, as R's cor(x) does, of the kind set by
+// WithCorrelation. A constant column has no correlation with anything, and its
+// row and column, diagonal included, are NaN; ConstantColumns lists them. So
+// are those of a column with a NaN value or, for PearsonCorrelation, an
+// infinite one.
+func CorrelationMatrix(x *mat.Dense, opts ...Option) *mat.Dense {
+	o := newOptions(opts)
+	n, p := x.Dims()
+	// the columns centered and scaled to unit length, or nil for those without a correlation
+	cols := make([][]float64, p)
+	for j := range cols {
+		col := mat.Col(nil, j, x)
+		switch o.correlation {
+		case PearsonCorrelation:
+		case SpearmanCorrelation:
+			col = ranks(col)
+		default:
+			panic(fmt.Sprintf("unknown correlation %v", o.correlation))
+		}
+		if s := sum(col); isConstant(col) || math.IsNaN(s) || math.IsInf(s, 0) {
+			continue
+		}
+		col = subtractMean(col)
+		cols[j] = multSlice(col, 1/math.Sqrt(sum(prod(col, col))))
+	}
+
+	r := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		for k := 0; k <= j; k++ {
+			v := math.NaN()
+			switch {
+			case cols[j] == nil || cols[k] == nil:
+			case j == k:
+				v = 1
+			default:
+				v = math.Max(-1, math.Min(1, sum(prod(cols[j], cols[k]))))
+			}
+			r.Set(j, k, v)
+			r.Set(k, j, v)
+		}
+	}
+	if n < 2 {
+		r.Apply(func(_, _ int, _ float64) float64 { return math.NaN() }, r)
+	}
+	return r
+}
+
+// PartialCorrelations returns the p x p matrix of the partial correlations
+// between the columns of x, each pair's correlation with the other columns
+// held fixed, as ppcor's pcor(x) does. With P the inverse of the correlation
+// matrix of x,
+//
+// r_{jk \cdot rest} = -P_{jk} / \sqrt{P_{jj} P_{kk}}
+//
+// and the diagonal is one. WithCorrelation(SpearmanCorrelation) gives the
+// partial correlations of the ranks. A constant column is a
+// ConstantColumnError, and a column that is a linear combination of the others,
+// whose correlation matrix has no inverse, is a SingularDesignError.
+func PartialCorrelations(x *mat.Dense, opts ...Option) (*mat.Dense, error) {
+	n, p := x.Dims()
+	if n < 2 {
+		return nil, fmt.Errorf("%w: %d observations", TooFewObservationsError, n)
+	}
+	if constant := ConstantColumns(x); constant != nil {
+		return nil, fmt.Errorf("%w: %v", ConstantColumnError, constant)
+	}
+	r := CorrelationMatrix(x, opts...)
+	for j := 0; j < p; j++ {
+		if math.IsNaN(r.At(j, j)) {
+			return nil, fmt.Errorf("column %d has a value that isn't finite", j)
+		}
+	}
+
+	chol := &mat.Cholesky{}
+	if !chol.Factorize(mat.NewSymDense(p, r.RawMatrix().Data)) {
+		return nil, fmt.Errorf("%w: the correlation matrix isn't positive definite", SingularDesignError)
+	}
+	// the pivots of a correlation matrix, whose diagonal is one, are the
+	// variances of the columns unexplained by those before them
+	tri := &mat.TriDense{}
+	chol.UTo(tri)
+	for j := 0; j < p; j++ {
+		if d := tri.At(j, j); !(d*d > pivotTolerance) {
+			return nil, fmt.Errorf("%w: column %d is a linear combination of the columns before it", SingularDesignError, j)
+		}
+	}
+	inv := &mat.SymDense{}
+	if err := chol.InverseTo(inv); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+
+	partial := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		partial.Set(j, j, 1)
+		for k := 0; k < j; k++ {
+			v := -inv.At(j, k) / math.Sqrt(inv.At(j, j)*inv.At(k, k))
+			v = math.Max(-1, math.Min(1, v))
+			partial.Set(j, k, v)
+			partial.Set(k, j, v)
+		}
+	}
+	return partial, nil
+}
+
+// ConstantColumns returns the indices of the columns of x whose values are all
+// the same, or nil if there are none.
+func ConstantColumns(x *mat.Dense) []int {
+	_, p := x.Dims()
+	var constant []int
+	for j := 0; j < p; j++ {
+		if isConstant(mat.Col(nil, j, x)) {
+			constant = append(constant, j)
+		}
+	}
+	return constant
+}
+
+// isConstant reports whether every value of x is the same.
+func isConstant(x []float64) bool {
+	for _, v := range x {
+		if v != x[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// ranks returns the ranks of x from 1, with ties given the mean of the ranks
+// they span. A NaN value has no rank, and makes every rank NaN.
+func ranks(x []float64) []float64 {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+		if math.IsNaN(x[i]) {
+			return rep(math.NaN(), len(x))
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return x[order[a]] < x[order[b]] })
+	r := make([]float64, len(x))
+	for first := 0; first < len(order); {
+		last := first
+		for last+1 < len(order) && x[order[last+1]] == x[order[first]] {
+			last++
+		}
+		// ranks first+1, ..., last+1 average to this
+		mid := float64(first+last)/2 + 1
+		for _, i := range order[first : last+1] {
+			r[i] = mid
+		}
+		first = last + 1
+	}
+	return r
+}
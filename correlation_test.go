@@ -0,0 +1,142 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// The mpg, cyl, disp and drat of the first 13 rows of R's mtcars, which have
+// ties in every column.
+var mtcars = mat.NewDense(13, 4, []float64{
+	21.0, 6, 160, 3.9,
+	21.0, 6, 160, 3.9,
+	22.8, 4, 108, 3.85,
+	21.4, 6, 258, 3.08,
+	18.7, 8, 360, 3.15,
+	18.1, 6, 225, 2.76,
+	14.3, 8, 360, 3.21,
+	24.4, 4, 146.7, 3.69,
+	22.8, 4, 140.8, 3.92,
+	19.2, 6, 167.6, 3.92,
+	17.8, 6, 167.6, 3.92,
+	16.4, 8, 275.8, 3.07,
+	17.3, 8, 275.8, 3.07,
+})
+
+func assertCloseMatrix(t *testing.T, got *mat.Dense, want [][]float64, tol float64) {
+	for j, row := range want {
+		assertCloseSlices(t, got.RawRowView(j), row, tol)
+	}
+}
+
+func TestCorrelationMatrix(t *testing.T) {
+	// the Pearson correlations and those of the midranks, as R's cor(x) and
+	// cor(x, method = "spearman") define them, evaluated in 50-digit decimal
+	// arithmetic; they are not R's output, which hasn't been run against them
+	assertCloseMatrix(t, CorrelationMatrix(mtcars), [][]float64{
+		{1, -0.868263394102139, -0.733726980888749, 0.53413946492123},
+		{-0.868263394102139, 1, 0.868222243895738, -0.614269973686877},
+		{-0.733726980888749, 0.868222243895738, 1, -0.764967732477677},
+		{0.53413946492123, -0.614269973686877, -0.764967732477677, 1},
+	}, 1e-13)
+	assertCloseMatrix(t, CorrelationMatrix(mtcars, WithCorrelation(SpearmanCorrelation)), [][]float64{
+		{1, -0.873599654932973, -0.806097275568872, 0.394450531690977},
+		{-0.873599654932973, 1, 0.935414346693485, -0.518146367757019},
+		{-0.806097275568872, 0.935414346693485, 1, -0.604459169545325},
+		{0.394450531690977, -0.518146367757019, -0.604459169545325, 1},
+	}, 1e-13)
+
+	// the pairwise correlations agree with cor
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			assertClose(t, CorrelationMatrix(mtcars).At(j, k), cor(mat.Col(nil, j, mtcars), mat.Col(nil, k, mtcars)), 1e-12)
+		}
+	}
+}
+
+func TestPartialCorrelations(t *testing.T) {
+	// -P_jk / sqrt(P_jj P_kk) for P the inverse of the correlation matrices
+	// above, the definition of ppcor's pcor(x)$estimate and pcor(x, method =
+	// "spearman")$estimate, evaluated in 50-digit decimal arithmetic rather
+	// than taken from ppcor
+	partial, err := PartialCorrelations(mtcars)
+	assert.Equal(t, nil, err)
+	assertCloseMatrix(t, partial, [][]float64{
+		{1, -0.685909776766499, 0.102862276640703, 0.0626967539278845},
+		{-0.685909776766499, 1, 0.637851629278973, 0.156388587129333},
+		{0.102862276640703, 0.637851629278973, 1, -0.593793091522504},
+		{0.0626967539278845, 0.156388587129333, -0.593793091522504, 1},
+	}, 1e-12)
+	partial, err = PartialCorrelations(mtcars, WithCorrelation(SpearmanCorrelation))
+	assert.Equal(t, nil, err)
+	assertCloseMatrix(t, partial, [][]float64{
+		{1, -0.557067483402327, 0.00991216188189964, -0.124767256568845},
+		{-0.557067483402327, 1, 0.763868734976178, 0.0687947447019025},
+		{0.00991216188189964, 0.763868734976178, 1, -0.391750182818053},
+		{-0.124767256568845, 0.0687947447019025, -0.391750182818053, 1},
+	}, 1e-12)
+
+	// the partial correlation of mpg and cyl is that of their residuals on
+	// disp and drat
+	var residuals [][]float64
+	for j := 0; j < 2; j++ {
+		rest := MatToDF(mat.DenseCopyOf(mtcars.Slice(0, 13, 2, 4)))
+		_, s, err := NewOlsTrainer().Train(rest, mat.Col(nil, j, mtcars))
+		assert.Equal(t, nil, err)
+		residuals = append(residuals, s.Residuals())
+	}
+	partial, err = PartialCorrelations(mtcars)
+	assert.Equal(t, nil, err)
+	assertClose(t, partial.At(0, 1), cor(residuals[0], residuals[1]), 1e-12)
+}
+
+func TestCorrelationConstantColumn(t *testing.T) {
+	x := mat.NewDense(4, 3, []float64{
+		1, 5, 2,
+		2, 5, 1,
+		3, 5, 4,
+		4, 5, 3,
+	})
+	assert.Equal(t, []int{1}, ConstantColumns(x))
+	for _, c := range []Correlation{PearsonCorrelation, SpearmanCorrelation} {
+		r := CorrelationMatrix(x, WithCorrelation(c))
+		for j := 0; j < 3; j++ {
+			assert.T(t, math.IsNaN(r.At(1, j)) && math.IsNaN(r.At(j, 1)))
+		}
+		assert.Equal(t, 1.0, r.At(0, 0))
+		assertClose(t, r.At(0, 2), 0.6, 1e-15)
+	}
+	_, err := PartialCorrelations(x)
+	assert.T(t, errors.Is(err, ConstantColumnError))
+
+	x.Set(1, 1, math.NaN())
+	assert.Equal(t, []int(nil), ConstantColumns(x))
+	assert.T(t, math.IsNaN(CorrelationMatrix(x).At(1, 1)))
+	_, err = PartialCorrelations(x)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestPartialCorrelationsSingular(t *testing.T) {
+	// the third column is the sum of the first two
+	x := mat.NewDense(5, 3, []float64{
+		1, 2, 3,
+		2, 1, 3,
+		3, 5, 8,
+		4, 3, 7,
+		5, 7, 12,
+	})
+	_, err := PartialCorrelations(x)
+	assert.T(t, errors.Is(err, SingularDesignError))
+
+	_, err = PartialCorrelations(mat.NewDense(1, 2, []float64{1, 2}))
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+}
+
+func TestRanks(t *testing.T) {
+	assert.Equal(t, []float64{2.5, 1, 2.5, 5, 4}, ranks([]float64{3, 1, 3, 9, 4}))
+	assert.T(t, math.IsNaN(ranks([]float64{1, math.NaN()})[0]))
+}
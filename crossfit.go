@@ -0,0 +1,117 @@
+package glasso
+
+import "fmt"
+
+// PartialOutFit is the cross-fitted estimate of the effect of a focal
+// predictor on the response once controls are partialled out of both. See
+// PartialOut.
+type PartialOutFit struct {
+	// Model is the regression through the origin of YResiduals on
+	// FocalResiduals, and Summary its summary, whose coefficient is the
+	// effect of the focal predictor.
+	Model   *OLS
+	Summary OlsSummary
+
+	// Estimate is the coefficient of the focal predictor and StdError its
+	// standard error, from the CoefficientTable of Summary.
+	Estimate, StdError float64
+
+	// YResiduals and FocalResiduals are the residuals of the response and of
+	// the focal predictor, each predicted by the models of the fold that
+	// held its observation out.
+	YResiduals, FocalResiduals []float64
+
+	// Folds are the observations held out of each fold, in order.
+	Folds [][]int
+}
+
+// PartialOut estimates the effect of the focal predictor on y adjusted for
+// the columns of controls by cross-fitted partialling out, the
+// double/debiased machine learning estimator of Chernozhukov et al. (2018)
+// for the partially linear model y = \theta d + g(controls) + e. The
+// observations are split into k folds as CrossValidate splits them, and the
+// response and the focal predictor of each fold are predicted from the
+// controls by models the trainer fits to the other folds, so that no
+// observation is predicted by a model trained on it. The effect is the
+// coefficient of the regression through the origin of the residuals of y on
+// those of the focal predictor,
+//
+// \hat\theta = \frac{\sum_i \tilde d_i \tilde y_i}{\sum_i \tilde d_i^2}
+//
+// with its least squares standard error. Were the models fit to every
+// observation by least squares, this would be the coefficient of d in the
+// regression on d and the controls, by Frisch-Waugh-Lovell; fitting them out
+// of fold keeps a flexible fit of many controls, by NewRidgeTrainer or
+// NewLassoTrainer, from biasing the estimate by overfitting. The trainer is
+// NewOlsTrainer if it is nil.
+//
+// The folds are contiguous blocks of the observations unless they're shuffled
+// (WithShuffle, with the random numbers seeded by WithSeed), and WithStrata
+// deals them out by the rank of the response, as for CrossValidate. controls
+// isn't modified.
+func PartialOut(focal []float64, controls *DataFrame, y []float64, k int, trainer Trainer, opts ...Option) (*PartialOutFit, error) {
+	o := newOptions(opts)
+	if trainer == nil {
+		trainer = NewOlsTrainer()
+	}
+	n := controls.Rows()
+	if len(y) != n || len(focal) != n {
+		return nil, DimensionError
+	}
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("%d folds is not between 2 and the %d observations", k, n)
+	}
+	if o.strata < 0 || o.strata > n {
+		return nil, fmt.Errorf("%d strata is not between 0 and the %d observations", o.strata, n)
+	}
+
+	fit := &PartialOutFit{
+		YResiduals:     make([]float64, n),
+		FocalResiduals: make([]float64, n),
+		Folds:          assignFolds(y, k, o),
+	}
+	for f, rows := range fit.Folds {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		held := make(map[int]bool, len(rows))
+		for _, i := range rows {
+			held[i] = true
+		}
+		var training []int
+		for i := 0; i < n; i++ {
+			if !held[i] {
+				training = append(training, i)
+			}
+		}
+		for _, target := range []struct {
+			values, residuals []float64
+			name              string
+		}{{y, fit.YResiduals, "response"}, {focal, fit.FocalResiduals, "focal predictor"}} {
+			// each fit is given its own copy of the rows, which a trainer may modify
+			model, _, err := trainer.Train(subsetRows(controls, training), subsetSlice(target.values, training))
+			if err != nil {
+				return nil, fmt.Errorf("fold %d, the %s: %v", f, target.name, err)
+			}
+			for _, i := range rows {
+				target.residuals[i] = target.values[i] - model.Predict(controls.GetRow(i))
+			}
+		}
+	}
+
+	residuals := make([][]float64, n)
+	for i, d := range fit.FocalResiduals {
+		residuals[i] = []float64{d}
+	}
+	model, summary, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(residuals, []string{"focal"}), fit.YResiduals)
+	if err != nil {
+		return nil, fmt.Errorf("the regression of the residuals: %v", err)
+	}
+	fit.Model, fit.Summary = model.(*OLS), summary.(OlsSummary)
+	table, err := CoefficientTable(fit.Summary)
+	if err != nil {
+		return nil, err
+	}
+	fit.Estimate, fit.StdError = table[0].Estimate, table[0].StdError
+	return fit, nil
+}
@@ -0,0 +1,121 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// partiallyLinear simulates n observations of y = theta d + g(controls) + e
+// with p controls, of which the first five confound d and y and the rest are
+// noise.
+func partiallyLinear(rng *rand.Rand, n, p int, theta float64) ([]float64, *DataFrame, []float64) {
+	d := make([]float64, n)
+	y := make([]float64, n)
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+		}
+		g := 0.0
+		for j := 0; j < 5; j++ {
+			g += rows[i][j] / float64(j+1)
+		}
+		d[i] = g + rng.NormFloat64()
+		y[i] = 1 + theta*d[i] + 2*g + rng.NormFloat64()
+	}
+	return d, NewDataFrame(rows), y
+}
+
+// memorizer is a Trainer whose model predicts the response of each row it
+// was trained on, and zero for any other.
+type memorizer struct{}
+
+type memorized map[string]float64
+
+func (memorizer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	m := memorized{}
+	for i := range y {
+		m[fmt.Sprint(x.GetRow(i))] = y[i]
+	}
+	return m, nil, nil
+}
+
+func (m memorized) Predict(x []float64) float64 { return m[fmt.Sprint(x)] }
+
+func TestPartialOut(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d, controls, y := partiallyLinear(rng, 400, 30, 0.5)
+	fit, err := PartialOut(d, controls, y, 5, nil)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 30, controls.Cols())
+	assertClose(t, fit.Estimate, 0.5, 4*fit.StdError)
+	assertClose(t, fit.Estimate, sum(prod(fit.FocalResiduals, fit.YResiduals))/sum(prod(fit.FocalResiduals, fit.FocalResiduals)), 1e-12)
+	assert.Equal(t, []string{"focal"}, fit.Model.Names())
+
+	// every observation is held out of one fold
+	seen := make([]int, len(y))
+	for _, rows := range fit.Folds {
+		for _, i := range rows {
+			seen[i]++
+		}
+	}
+	assert.Equal(t, rep(1, len(y)), asFloats(seen))
+
+	// and is never predicted by a model trained on it, so that a model that
+	// only remembers its training data predicts none of them
+	memorized, err := PartialOut(d, controls, y, 4, memorizer{}, WithShuffle(true))
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, memorized.YResiduals, y, 0)
+	assertCloseSlices(t, memorized.FocalResiduals, d, 0)
+	assert.T(t, memorized.Folds[0][1]-memorized.Folds[0][0] != 1 || memorized.Folds[0][2]-memorized.Folds[0][1] != 1)
+
+	// ridge for the nuisance fits recovers the effect too
+	ridge, err := PartialOut(d, controls, y, 5, NewRidgeTrainer(&RidgeConfig{Lambda: 5}))
+	assert.Equal(t, nil, err)
+	assertClose(t, ridge.Estimate, 0.5, 4*ridge.StdError)
+}
+
+func asFloats(v []int) []float64 {
+	f := make([]float64, len(v))
+	for i, x := range v {
+		f[i] = float64(x)
+	}
+	return f
+}
+
+func TestPartialOutCoverage(t *testing.T) {
+	// the 95% intervals of the estimate cover the effect about as often as
+	// they should, with many noisy controls
+	rng := rand.New(rand.NewSource(2))
+	const replications = 200
+	covered := 0
+	for r := 0; r < replications; r++ {
+		d, controls, y := partiallyLinear(rng, 400, 20, 1)
+		fit, err := PartialOut(d, controls, y, 5, nil)
+		assert.Equal(t, nil, err)
+		if math.Abs(fit.Estimate-1) < 1.96*fit.StdError {
+			covered++
+		}
+	}
+	coverage := float64(covered) / replications
+	assert.T(t, coverage > 0.9 && coverage < 0.99, coverage)
+}
+
+func TestPartialOutErrors(t *testing.T) {
+	d, controls, y := partiallyLinear(rand.New(rand.NewSource(3)), 50, 8, 1)
+	_, err := PartialOut(d[:10], controls, y, 5, nil)
+	assert.Equal(t, DimensionError, err)
+	_, err = PartialOut(d, controls, y[:10], 5, nil)
+	assert.Equal(t, DimensionError, err)
+	_, err = PartialOut(d, controls, y, 1, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = PartialOut(d, controls, y, 51, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = PartialOut(d, controls, y, 5, NewRidgeTrainer(nil))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,369 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// CVFold is one fold of a cross-validation: a model trained on the other
+// folds and evaluated on this one.
+type CVFold struct {
+	Rows         []int     // the observations held out, in order
+	Predicted    []float64 // the predictions for them
+	Coefficients []float64 // of the model trained without them
+
+	MSE, MAE, RSquared float64
+}
+
+// CVResult is the result of a cross-validation.
+type CVResult struct {
+	Folds []CVFold
+
+	// the scores of the predictions of every observation from the fold that
+	// held it out, pooled over the folds
+	MSE, MAE, RSquared float64
+}
+
+// Coefficients returns the coefficients of the model of each fold, a row per
+// fold, to show how stable they are.
+func (c *CVResult) Coefficients() *DataFrame {
+	rows := make([][]float64, len(c.Folds))
+	for f, fold := range c.Folds {
+		rows[f] = fold.Coefficients
+	}
+	return NewDataFrame(rows)
+}
+
+// CrossValidate estimates the prediction error of the models the trainer
+// fits by k-fold cross-validation. The observations are split into k folds
+// whose sizes differ by at most one, and each fold is predicted by the model
+// trained on the others.
+//
+// The folds are contiguous blocks of the observations unless they're shuffled
+// (WithShuffle, with the random numbers seeded by WithSeed). With WithStrata
+// the observations are bucketed by the rank of the response and each bucket is
+// dealt out over the folds in turn.
+//
+// The R^2 of a fold is 1 - SSE / SST for the held out responses around their
+// mean, and the pooled R^2 uses the mean of every response.
+func CrossValidate(x *DataFrame, y []float64, k int, trainer Trainer, opts ...Option) (*CVResult, error) {
+	o := newOptions(opts)
+	if trainer == nil {
+		return nil, fmt.Errorf("trainer not set")
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("%d folds is not between 2 and the %d observations", k, n)
+	}
+	if o.strata < 0 || o.strata > n {
+		return nil, fmt.Errorf("%d strata is not between 0 and the %d observations", o.strata, n)
+	}
+
+	result := &CVResult{}
+	predicted := make([]float64, n)
+	for f, rows := range assignFolds(y, k, o) {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		held := make(map[int]bool, len(rows))
+		for _, i := range rows {
+			held[i] = true
+		}
+		var training []int
+		for i := 0; i < n; i++ {
+			if !held[i] {
+				training = append(training, i)
+			}
+		}
+		model, summary, err := trainer.Train(subsetRows(x, training), subsetSlice(y, training))
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %v", f, err)
+		}
+
+		fold := CVFold{
+			Rows:         rows,
+			Predicted:    make([]float64, len(rows)),
+			Coefficients: append([]float64(nil), summary.Coefficients()...),
+		}
+		for a, i := range rows {
+			fold.Predicted[a] = model.Predict(x.GetRow(i))
+			predicted[i] = fold.Predicted[a]
+		}
+		actual := subsetSlice(y, rows)
+		fold.MSE = MSE(actual, fold.Predicted)
+		fold.MAE = MAE(actual, fold.Predicted)
+		fold.RSquared = RSquaredScore(actual, fold.Predicted)
+		result.Folds = append(result.Folds, fold)
+	}
+	result.MSE = MSE(y, predicted)
+	result.MAE = MAE(y, predicted)
+	result.RSquared = RSquaredScore(y, predicted)
+	return result, nil
+}
+
+// LOOCV returns the leave-one-out cross-validation estimate of the prediction
+// error of a least squares fit, the mean squared PressResiduals
+//
+// CV = \frac{1}{n} \sum_i (\frac{e_i}{1 - h_{ii}})^2
+//
+// which is exact without refitting the model n times, along with the
+// residuals themselves. If an observation has a leverage of one the model
+// can't predict it without it: its residual is NaN, and a LeverageError is
+// returned with the residuals so that it can be found.
+func LOOCV(m Summary) (float64, []float64, error) {
+	residuals, err := PressResiduals(m)
+	if err != nil {
+		return 0, nil, err
+	}
+	press := 0.0
+	for _, e := range residuals {
+		if math.IsNaN(e) {
+			return 0, residuals, LeverageError
+		}
+		press += e * e
+	}
+	return press / float64(len(residuals)), residuals, nil
+}
+
+// GCV returns the generalized cross-validation score of Craven & Wahba (1979)
+// for a least squares fit with p coefficients, including the intercept,
+//
+// GCV = \frac{RSS}{n (1 - p / n)^2}
+//
+// which replaces each leverage in LOOCV by their average p / n.
+func GCV(m Summary) float64 {
+	n, p := float64(m.Data().Rows()), float64(m.Data().Cols())
+	return m.SumOfSquares() / (n * (1 - p/n) * (1 - p/n))
+}
+
+// assignFolds returns the observations in each of k folds.
+func assignFolds(y []float64, k int, o options) [][]int {
+	n := len(y)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	var rng *rand.Rand
+	if o.shuffle {
+		rng = o.rng()
+	}
+
+	folds := make([][]int, k)
+	if o.strata > 0 {
+		sort.SliceStable(order, func(a, b int) bool { return y[order[a]] < y[order[b]] })
+		next := 0
+		for b := 0; b < o.strata; b++ {
+			bucket := order[b*n/o.strata : (b+1)*n/o.strata]
+			if rng != nil {
+				rng.Shuffle(len(bucket), func(i, j int) { bucket[i], bucket[j] = bucket[j], bucket[i] })
+			}
+			for _, i := range bucket {
+				folds[next] = append(folds[next], i)
+				next = (next + 1) % k
+			}
+		}
+	} else {
+		if rng != nil {
+			rng.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+		// the first n % k folds have an extra observation
+		start := 0
+		for f := range folds {
+			size := n / k
+			if f < n%k {
+				size++
+			}
+			folds[f] = order[start : start+size]
+			start += size
+		}
+	}
+	for _, rows := range folds {
+		sort.Ints(rows)
+	}
+	return folds
+}
+
+// Split splits the observations into a training set and a test set holding
+// the fraction testFraction of them, rounded up, with the rows of x and y kept
+// together and in their order. The test set is the last of the observations
+// unless they're shuffled (WithShuffle, with the random numbers seeded by
+// WithSeed). Both sets must have at least one observation.
+func Split(x *DataFrame, y []float64, testFraction float64, opts ...Option) (xTrain, xTest *DataFrame, yTrain, yTest []float64, err error) {
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, nil, nil, DimensionError
+	}
+	if !(testFraction > 0 && testFraction < 1) {
+		return nil, nil, nil, nil, fmt.Errorf("test fraction %v is not in (0, 1)", testFraction)
+	}
+	size := int(math.Ceil(testFraction * float64(n)))
+	if size >= n {
+		return nil, nil, nil, nil, fmt.Errorf("%d observations are too few to hold out %v of them and train on the rest", n, testFraction)
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if o.shuffle {
+		rng := o.rng()
+		rng.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+	training, test := order[:n-size], order[n-size:]
+	sort.Ints(training)
+	sort.Ints(test)
+	return subsetRows(x, training), subsetRows(x, test), subsetSlice(y, training), subsetSlice(y, test), nil
+}
+
+// subsetRows returns the given rows of x, with its labels.
+func subsetRows(x *DataFrame, rows []int) *DataFrame {
+	data := make([][]float64, len(rows))
+	for a, i := range rows {
+		data[a] = x.GetRow(i)
+	}
+	return NewDataFrame(data, x.Labels())
+}
+
+// subsetSlice returns the given elements of v.
+func subsetSlice(v []float64, rows []int) []float64 {
+	s := make([]float64, len(rows))
+	for a, i := range rows {
+		s[a] = v[i]
+	}
+	return s
+}
+
+// A PenalizedFitter returns the Trainer of a model with the penalty lambda,
+// such as
+//
+//	func(lambda float64) Trainer { return NewLassoTrainer(lambda) }
+type PenalizedFitter func(lambda float64) Trainer
+
+// CVCurve is the cross-validated prediction error of a penalized model over a
+// grid of penalties, from CVLambda.
+type CVCurve struct {
+	Lambdas []float64
+	MSE     []float64 // the mean validation MSE of each penalty over the folds
+	SE      []float64 // the standard error of each MSE
+
+	// LambdaMin is the penalty with the least MSE, and Lambda1SE the largest
+	// penalty whose MSE is within one standard error of that least MSE
+	LambdaMin, Lambda1SE float64
+
+	// Model and Summary are of the fit on every observation with Lambda1SE
+	Model   Model
+	Summary Summary
+}
+
+// CVLambda estimates the prediction error of the model fit with each penalty
+// in lambdas by k-fold cross-validation, as R's cv.glmnet does. The folds are
+// assigned once, as CrossValidate assigns them, and every penalty is
+// evaluated on the same folds so that the points of the curve are
+// comparable; they are shuffled by default, with the random numbers seeded by
+// WithSeed, and WithShuffle(false) and WithStrata assign them as they do for
+// CrossValidate.
+//
+// As cv.glmnet weighs them, the MSE of a penalty is the mean of the MSEs of
+// the folds weighted by their sizes, which is the pooled MSE of CrossValidate,
+// and its standard error is
+//
+// SE = \sqrt{\frac{\sum_f n_f (MSE_f - MSE)^2}{n (k - 1)}}
+//
+// Of several penalties with the least MSE, LambdaMin is the largest. The
+// penalty of Lambda1SE, the most regularized model that is about as good as
+// the best, is the one that the model is refit on every observation with.
+func CVLambda(x *DataFrame, y []float64, lambdas []float64, k int, fit PenalizedFitter, opts ...Option) (*CVCurve, error) {
+	o := newOptions(append([]Option{WithShuffle(true)}, opts...))
+	if fit == nil {
+		return nil, fmt.Errorf("fitter not set")
+	}
+	if len(lambdas) == 0 {
+		return nil, fmt.Errorf("no penalties to cross-validate")
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("%d folds is not between 2 and the %d observations", k, n)
+	}
+	if o.strata < 0 || o.strata > n {
+		return nil, fmt.Errorf("%d strata is not between 0 and the %d observations", o.strata, n)
+	}
+
+	folds := assignFolds(y, k, o)
+	// the MSE of each penalty on each fold
+	mse := make([][]float64, len(lambdas))
+	for l := range mse {
+		mse[l] = make([]float64, k)
+	}
+	for f, rows := range folds {
+		held := make(map[int]bool, len(rows))
+		for _, i := range rows {
+			held[i] = true
+		}
+		var training []int
+		for i := 0; i < n; i++ {
+			if !held[i] {
+				training = append(training, i)
+			}
+		}
+		xTrain, yTrain := subsetRows(x, training), subsetSlice(y, training)
+		actual := subsetSlice(y, rows)
+		for l, lambda := range lambdas {
+			if err := o.ctx.Err(); err != nil {
+				return nil, err
+			}
+			model, _, err := fit(lambda).Train(xTrain, yTrain)
+			if err != nil {
+				return nil, fmt.Errorf("fold %d, penalty %v: %v", f, lambda, err)
+			}
+			predicted := make([]float64, len(rows))
+			for a, i := range rows {
+				predicted[a] = model.Predict(x.GetRow(i))
+			}
+			mse[l][f] = MSE(actual, predicted)
+		}
+	}
+
+	curve := &CVCurve{
+		Lambdas: append([]float64(nil), lambdas...),
+		MSE:     make([]float64, len(lambdas)),
+		SE:      make([]float64, len(lambdas)),
+	}
+	best := 0
+	for l := range lambdas {
+		for f, rows := range folds {
+			curve.MSE[l] += float64(len(rows)) * mse[l][f] / float64(n)
+		}
+		ss := 0.0
+		for f, rows := range folds {
+			d := mse[l][f] - curve.MSE[l]
+			ss += float64(len(rows)) * d * d
+		}
+		curve.SE[l] = math.Sqrt(ss / float64(n) / float64(k-1))
+		if curve.MSE[l] < curve.MSE[best] || curve.MSE[l] == curve.MSE[best] && lambdas[l] > lambdas[best] {
+			best = l
+		}
+	}
+	curve.LambdaMin = lambdas[best]
+	curve.Lambda1SE = curve.LambdaMin
+	for l, lambda := range lambdas {
+		if curve.MSE[l] <= curve.MSE[best]+curve.SE[best] && lambda > curve.Lambda1SE {
+			curve.Lambda1SE = lambda
+		}
+	}
+
+	model, summary, err := fit(curve.Lambda1SE).Train(x, y)
+	if err != nil {
+		return nil, err
+	}
+	curve.Model, curve.Summary = model, summary
+	return curve, nil
+}
@@ -0,0 +1,478 @@
+package glasso
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// checkPartition checks that the folds hold every one of n observations once.
+func checkPartition(t *testing.T, folds []CVFold, n int) {
+	seen := make([]int, n)
+	for _, fold := range folds {
+		for _, i := range fold.Rows {
+			seen[i]++
+		}
+	}
+	for i, count := range seen {
+		if count != 1 {
+			t.Errorf("observation %d is in %d folds", i, count)
+		}
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	x := NewDataFrame(data)
+	result, err := CrossValidate(x, y, 4, NewOlsTrainer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Cols() != 3 {
+		t.Errorf("CrossValidate changed the number of columns of the DataFrame to %d", x.Cols())
+	}
+	// 21 observations in contiguous folds of 6, 5, 5 and 5
+	starts := []int{0, 6, 11, 16, 21}
+	if len(result.Folds) != 4 {
+		t.Fatalf("got %d folds, want 4", len(result.Folds))
+	}
+	sse, sae := 0.0, 0.0
+	for f, fold := range result.Folds {
+		if len(fold.Rows) != starts[f+1]-starts[f] {
+			t.Fatalf("fold %d has rows %v", f, fold.Rows)
+		}
+		for a, i := range fold.Rows {
+			if i != starts[f]+a {
+				t.Errorf("fold %d has rows %v, want %d to %d", f, fold.Rows, starts[f], starts[f+1]-1)
+				break
+			}
+		}
+
+		// the model of the fold is the OLS fit on the other rows
+		var rows [][]float64
+		var response []float64
+		for i := range data {
+			if i < starts[f] || i >= starts[f+1] {
+				rows = append(rows, data[i])
+				response = append(response, y[i])
+			}
+		}
+		model, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, b := range s.Coefficients() {
+			if math.Abs(fold.Coefficients[j]-b) > 1e-10 {
+				t.Errorf("fold %d: coefficient %d is %v, want %v", f, j, fold.Coefficients[j], b)
+			}
+		}
+		foldSSE := 0.0
+		for a, i := range fold.Rows {
+			want := model.Predict(data[i])
+			if math.Abs(fold.Predicted[a]-want) > 1e-10 {
+				t.Errorf("fold %d: prediction %d is %v, want %v", f, a, fold.Predicted[a], want)
+			}
+			foldSSE += (y[i] - want) * (y[i] - want)
+			sae += math.Abs(y[i] - want)
+		}
+		sse += foldSSE
+		if math.Abs(fold.MSE-foldSSE/float64(len(fold.Rows))) > 1e-10 {
+			t.Errorf("fold %d: MSE is %v, want %v", f, fold.MSE, foldSSE/float64(len(fold.Rows)))
+		}
+		held := subsetSlice(y, fold.Rows)
+		centered := subtractMean(held)
+		if want := 1 - foldSSE/sum(prod(centered, centered)); math.Abs(fold.RSquared-want) > 1e-10 {
+			t.Errorf("fold %d: R^2 is %v, want %v", f, fold.RSquared, want)
+		}
+	}
+	n := float64(len(y))
+	centered := subtractMean(y)
+	if math.Abs(result.MSE-sse/n) > 1e-10 || math.Abs(result.MAE-sae/n) > 1e-10 {
+		t.Errorf("got MSE %v and MAE %v, want %v and %v", result.MSE, result.MAE, sse/n, sae/n)
+	}
+	if want := 1 - sse/sum(prod(centered, centered)); math.Abs(result.RSquared-want) > 1e-10 {
+		t.Errorf("R^2 is %v, want %v", result.RSquared, want)
+	}
+	if c := result.Coefficients(); c.Rows() != 4 || c.Cols() != 4 || c.X.At(2, 1) != result.Folds[2].Coefficients[1] {
+		t.Errorf("coefficient matrix is %d x %d", c.Rows(), c.Cols())
+	}
+}
+
+func TestCrossValidateShuffle(t *testing.T) {
+	x := NewDataFrame(data)
+	folds := func(opts ...Option) []CVFold {
+		result, err := CrossValidate(x, y, 5, NewOlsTrainer(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPartition(t, result.Folds, len(y))
+		return result.Folds
+	}
+	a, b := folds(WithShuffle(true), WithSeed(3)), folds(WithShuffle(true), WithSeed(3))
+	c := folds(WithShuffle(true), WithSeed(4))
+	same, contiguous := true, true
+	for f := range a {
+		for r := range a[f].Rows {
+			if a[f].Rows[r] != b[f].Rows[r] {
+				t.Errorf("fold %d differs for the same seed: %v and %v", f, a[f].Rows, b[f].Rows)
+			}
+			if r > 0 && a[f].Rows[r] != a[f].Rows[r-1]+1 {
+				contiguous = false
+			}
+		}
+		same = same && len(a[f].Rows) == len(c[f].Rows) && a[f].Rows[0] == c[f].Rows[0] && a[f].Rows[1] == c[f].Rows[1]
+	}
+	if contiguous || same {
+		t.Errorf("shuffled folds are contiguous (%v) or the same for different seeds (%v)", contiguous, same)
+	}
+
+	// any trainer works
+	result, err := CrossValidate(x, y, 3, NewQuantileTrainer(0.5), WithShuffle(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, result.Folds, len(y))
+}
+
+func TestCrossValidateStrata(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	n := 50
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.Float64()}
+		response[i] = math.Exp(2*rows[i][0] + rng.NormFloat64())
+	}
+	result, err := CrossValidate(NewDataFrame(rows), response, 5, NewOlsTrainer(), WithStrata(10), WithShuffle(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, result.Folds, n)
+
+	// each fold holds one of the five observations in every tenth of the responses
+	rank := make([]int, n)
+	for i := range response {
+		for j := range response {
+			if response[j] < response[i] {
+				rank[i]++
+			}
+		}
+	}
+	for f, fold := range result.Folds {
+		buckets := make([]int, 10)
+		for _, i := range fold.Rows {
+			buckets[rank[i]/5]++
+		}
+		for b, count := range buckets {
+			if count != 1 {
+				t.Errorf("fold %d has %d observations from bucket %d", f, count, b)
+			}
+		}
+	}
+}
+
+func TestCrossValidateInvalid(t *testing.T) {
+	x := NewDataFrame(data)
+	for _, k := range []int{-1, 0, 1, 22} {
+		if _, err := CrossValidate(x, y, k, NewOlsTrainer()); err == nil {
+			t.Errorf("expected an error for %d folds", k)
+		}
+	}
+	if _, err := CrossValidate(x, y, 3, nil); err == nil {
+		t.Error("expected an error for no trainer")
+	}
+	if _, err := CrossValidate(x, y[1:], 3, NewOlsTrainer()); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+	if _, err := CrossValidate(x, y, 3, NewOlsTrainer(), WithStrata(-2)); err == nil {
+		t.Error("expected an error for negative strata")
+	}
+	// each fold trains on 4 observations, too few for a robust fit of 4 coefficients
+	if _, err := CrossValidate(NewDataFrame(data[:5]), y[:5], 5, NewRobustTrainer(Huber(DefaultHuberK))); err == nil {
+		t.Error("expected the error of the trainer")
+	}
+}
+
+func TestLOOCV(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	n := 30
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64(), rng.Float64() * 10, rng.ExpFloat64()}
+		response[i] = 1 + rows[i][0] - 0.5*rows[i][1] + 2*rows[i][2] + rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mse, residuals, err := LOOCV(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// refit without each observation
+	want := 0.0
+	for i := range rows {
+		var others [][]float64
+		var y []float64
+		for j := range rows {
+			if j != i {
+				others = append(others, rows[j])
+				y = append(y, response[j])
+			}
+		}
+		model, _, err := NewOlsTrainer().Train(NewDataFrame(others), y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := response[i] - model.Predict(rows[i])
+		if math.Abs(residuals[i]-e) > 1e-10 {
+			t.Errorf("residual %d is %v, want %v", i, residuals[i], e)
+		}
+		want += e * e / float64(n)
+	}
+	if math.Abs(mse-want) > 1e-10 {
+		t.Errorf("LOOCV is %v, want %v", mse, want)
+	}
+	// n-fold cross-validation is leave-one-out
+	result, err := CrossValidate(NewDataFrame(rows), response, n, NewOlsTrainer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.MSE-mse) > 1e-10 {
+		t.Errorf("%d-fold cross-validation MSE is %v, want %v", n, result.MSE, mse)
+	}
+
+	gcv := s.SumOfSquares() / float64(n) / ((1 - 4./30) * (1 - 4./30))
+	if math.Abs(GCV(s)-gcv) > 1e-10 {
+		t.Errorf("GCV is %v, want %v", GCV(s), gcv)
+	}
+	if GCV(s) > mse*1.5 || GCV(s) < mse/1.5 {
+		t.Errorf("GCV %v is far from LOOCV %v", GCV(s), mse)
+	}
+
+	// an indicator of one observation gives it a leverage of one
+	for i := range rows {
+		rows[i] = append(rows[i], 0)
+	}
+	rows[4][3] = 1
+	_, s, err = NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, residuals, err = LOOCV(s)
+	if err != LeverageError || !math.IsNaN(residuals[4]) || math.IsNaN(residuals[3]) {
+		t.Errorf("got error %v and residuals %v and %v, want LeverageError and NaN only for 4", err, residuals[4], residuals[3])
+	}
+}
+
+func TestSplit(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	x := NewDataFrame(data, labels)
+	xTrain, xTest, yTrain, yTest, err := Split(x, y, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ceil(0.25 * 21) = 6 observations are held out, the last ones
+	if xTrain.Rows() != 15 || xTest.Rows() != 6 || len(yTrain) != 15 || len(yTest) != 6 {
+		t.Fatalf("got %d and %d training and %d and %d test observations", xTrain.Rows(), len(yTrain), xTest.Rows(), len(yTest))
+	}
+	if xTest.Labels()[1] != labels[1] {
+		t.Errorf("got labels %v, want %v", xTest.Labels(), labels)
+	}
+	for i := range data {
+		row, response := xTrain.GetRow, yTrain
+		a := i
+		if i >= 15 {
+			row, response, a = xTest.GetRow, yTest, i-15
+		}
+		for j, v := range row(a) {
+			if v != data[i][j] {
+				t.Errorf("row %d isn't recovered: %v", i, row(a))
+				break
+			}
+		}
+		if response[a] != y[i] {
+			t.Errorf("response %d is %v, want %v", i, response[a], y[i])
+		}
+	}
+
+	shuffled := func(seed int64) (*DataFrame, []float64) {
+		xTrain, xTest, yTrain, yTest, err := Split(x, y, 0.3, WithShuffle(true), WithSeed(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// every row is in one of the sets, with its response
+		found := make([]int, len(data))
+		for _, set := range []struct {
+			x *DataFrame
+			y []float64
+		}{{xTrain, yTrain}, {xTest, yTest}} {
+			for a := 0; a < set.x.Rows(); a++ {
+				for i := range data {
+					if row := set.x.GetRow(a); row[0] == data[i][0] && row[1] == data[i][1] && row[2] == data[i][2] && set.y[a] == y[i] {
+						found[i]++
+					}
+				}
+			}
+		}
+		for i, count := range found {
+			if count != 1 {
+				t.Errorf("row %d is found %d times", i, count)
+			}
+		}
+		return xTest, yTest
+	}
+	a, ya := shuffled(5)
+	b, yb := shuffled(5)
+	c, _ := shuffled(6)
+	sameSeed, otherSeed := true, true
+	for i := 0; i < a.Rows(); i++ {
+		sameSeed = sameSeed && a.X.At(i, 0) == b.X.At(i, 0) && ya[i] == yb[i]
+		otherSeed = otherSeed && a.X.At(i, 0) == c.X.At(i, 0) && a.X.At(i, 1) == c.X.At(i, 1)
+	}
+	if !sameSeed || otherSeed {
+		t.Errorf("the same seed gives the same split %v, and another seed the same one %v", sameSeed, otherSeed)
+	}
+
+	for _, fraction := range []float64{0, 1, -0.1, 1.5, math.NaN()} {
+		if _, _, _, _, err := Split(x, y, fraction); err == nil {
+			t.Errorf("expected an error for fraction %v", fraction)
+		}
+	}
+	if _, _, _, _, err := Split(NewDataFrame(data[:1]), y[:1], 0.5); err == nil {
+		t.Error("expected an error for one observation")
+	}
+	if _, _, _, _, err := Split(NewDataFrame(data[:3]), y[:3], 0.9); err == nil {
+		t.Error("expected an error when every observation would be held out")
+	}
+	if _, _, _, _, err := Split(x, y[1:], 0.5); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+}
+
+func TestCrossValidateCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CrossValidate(NewDataFrame(data), y, 5, NewOlsTrainer(), WithContext(ctx)); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if _, err := CrossValidate(NewDataFrame(data), y, 5, NewOlsTrainer(), WithContext(context.Background())); err != nil {
+		t.Error(err)
+	}
+}
+
+// sparseRegression returns n observations of p Gaussian predictors with a
+// response on the first three of them.
+func sparseRegression(rng *rand.Rand, n, p int) (*DataFrame, []float64) {
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+		}
+		response[i] = 3*rows[i][0] - 2*rows[i][1] + rows[i][2] + 2*rng.NormFloat64()
+	}
+	return NewDataFrame(rows), response
+}
+
+func TestCVLambda(t *testing.T) {
+	x, response := sparseRegression(rand.New(rand.NewSource(11)), 60, 10)
+	lambdas := []float64{5, 2, 1, 0.5, 0.2, 0.1, 0.05, 0.01, 0}
+	lasso := func(lambda float64) Trainer { return NewLassoTrainer(lambda) }
+	curve, err := CVLambda(x, response, lambdas, 5, lasso, WithSeed(2))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, lambdas, curve.Lambdas)
+
+	best := 0
+	for l, lambda := range lambdas {
+		// every penalty is evaluated on the folds CrossValidate would assign
+		cv, err := CrossValidate(x, response, 5, lasso(lambda), WithShuffle(true), WithSeed(2))
+		assert.Equal(t, nil, err)
+		assertClose(t, curve.MSE[l], cv.MSE, 1e-10)
+		ss := 0.0
+		for _, fold := range cv.Folds {
+			ss += float64(len(fold.Rows)) * (fold.MSE - cv.MSE) * (fold.MSE - cv.MSE)
+		}
+		assertClose(t, curve.SE[l], math.Sqrt(ss/60/4), 1e-10)
+		if curve.MSE[l] < curve.MSE[best] {
+			best = l
+		}
+	}
+	assert.Equal(t, lambdas[best], curve.LambdaMin)
+
+	// the curve falls from the null model to a minimum between the ends of the
+	// grid, where the 1-SE rule picks a sparser model than the least MSE
+	assert.T(t, best > 0 && best < len(lambdas)-1, best)
+	assert.T(t, curve.MSE[0] > 2*curve.MSE[best])
+	assert.T(t, curve.Lambda1SE >= curve.LambdaMin)
+	for l, lambda := range lambdas {
+		if lambda > curve.Lambda1SE {
+			assert.T(t, curve.MSE[l] > curve.MSE[best]+curve.SE[best])
+		}
+	}
+
+	// refit on every observation with lambda.1se
+	_, s, err := lasso(curve.Lambda1SE).Train(x, response)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.Coefficients(), curve.Summary.Coefficients())
+	assertClose(t, curve.Model.Predict(x.GetRow(0)), s.Yhat()[0], 1e-12)
+
+	// the seed makes it reproducible
+	again, err := CVLambda(x, response, lambdas, 5, lasso, WithSeed(2))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, curve.MSE, again.MSE)
+	other, err := CVLambda(x, response, lambdas, 5, lasso, WithSeed(3))
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, curve.MSE, other.MSE)
+}
+
+func TestCVLambdaRidge(t *testing.T) {
+	ridge := func(lambda float64) Trainer { return NewRidgeTrainer(&RidgeConfig{Lambda: lambda}) }
+	lambdas := []float64{0, 1, 10, 100, 1000}
+	curve, err := CVLambda(NewDataFrame(data), y, lambdas, 7, ridge, WithShuffle(false))
+	assert.Equal(t, nil, err)
+	for l, lambda := range lambdas {
+		cv, err := CrossValidate(NewDataFrame(data), y, 7, ridge(lambda))
+		assert.Equal(t, nil, err)
+		assertClose(t, curve.MSE[l], cv.MSE, 1e-10)
+	}
+	assert.Equal(t, curve.Lambda1SE, curve.Summary.(*RidgeSummary).Lambda())
+}
+
+func TestCVLambdaStackloss(t *testing.T) {
+	// unshuffled folds are the contiguous blocks of cv.glmnet(x, y, lambda =
+	// c(8, 5, 2, 1, 0.5, 0.1, 0.01), foldid = rep(1:3, each = 7)), whose
+	// cvm, cvsd, lambda.min and lambda.1se these are on its definitions, with
+	// each fold's lasso standardized on the fold's own training rows. They
+	// are not cv.glmnet's output, which hasn't been run against them: each
+	// fit is the exact minimizer of TestLassoPathStackloss, in 50-digit
+	// decimal arithmetic.
+	lambdas := []float64{8, 5, 2, 1, 0.5, 0.1, 0.01}
+	lasso := func(lambda float64) Trainer { return NewLassoTrainer(lambda, WithTolerance(1e-24)) }
+	curve, err := CVLambda(NewDataFrame(data), y, lambdas, 3, lasso, WithShuffle(false))
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, curve.MSE, []float64{153.60800579594078, 133.3686851306328, 101.77453043625061, 65.12722335643355, 50.8235786736213, 41.70476738145038, 39.68741119685066}, 1e-8)
+	assertCloseSlices(t, curve.SE, []float64{105.07889573343074, 112.93353319286035, 87.2973578621842, 48.154377267362214, 31.740403834732327, 21.901696423386856, 20.22508183894738}, 1e-8)
+	assert.Equal(t, 0.01, curve.LambdaMin)
+	assert.Equal(t, 0.5, curve.Lambda1SE)
+}
+
+func TestCVLambdaInvalid(t *testing.T) {
+	x := NewDataFrame(data)
+	ridge := func(lambda float64) Trainer { return NewRidgeTrainer(&RidgeConfig{Lambda: lambda}) }
+	_, err := CVLambda(x, y, []float64{1}, 5, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = CVLambda(x, y, nil, 5, ridge)
+	assert.NotEqual(t, nil, err)
+	_, err = CVLambda(x, y[1:], []float64{1}, 5, ridge)
+	assert.Equal(t, DimensionError, err)
+	_, err = CVLambda(x, y, []float64{1}, 1, ridge)
+	assert.NotEqual(t, nil, err)
+	_, err = CVLambda(x, y, []float64{1, -1}, 5, ridge)
+	assert.NotEqual(t, nil, err)
+}
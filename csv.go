@@ -0,0 +1,179 @@
+package glasso
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// A ColumnType says how ReadCSV parses a column.
+type ColumnType int
+
+const (
+	ColumnAuto        ColumnType = iota // numeric if every value that isn't missing is a number, and categorical otherwise
+	ColumnNumeric                       // numbers, with a value that isn't one an error
+	ColumnCategorical                   // strings, to encode with a CategoricalEncoder
+)
+
+// A CSVOption configures ReadCSV.
+type CSVOption func(*csvOptions)
+
+type csvOptions struct {
+	delimiter rune
+	header    bool
+	missing   []string
+	types     map[string]ColumnType
+}
+
+// DefaultMissingTokens are the values ReadCSV reads as missing by default.
+var DefaultMissingTokens = []string{"NA", "", "null"}
+
+// WithDelimiter sets the field delimiter (a comma by default).
+func WithDelimiter(delimiter rune) CSVOption {
+	return func(o *csvOptions) { o.delimiter = delimiter }
+}
+
+// WithNoHeader reads the first record as data rather than the column names,
+// and names the columns "x0", "x1", ...
+func WithNoHeader() CSVOption {
+	return func(o *csvOptions) { o.header = false }
+}
+
+// WithMissingTokens sets the values that are read as missing, replacing
+// DefaultMissingTokens. Values are compared after trimming spaces.
+func WithMissingTokens(tokens ...string) CSVOption {
+	return func(o *csvOptions) { o.missing = tokens }
+}
+
+// WithColumnType overrides the detected type of the named column.
+func WithColumnType(name string, t ColumnType) CSVOption {
+	return func(o *csvOptions) { o.types[name] = t }
+}
+
+// ReadCSV reads a data set with a column per variable. The header names the
+// columns, and numeric columns become the columns of the DataFrame, labeled by
+// their names, with missing values NaN for a fit's NAPolicy to handle. The
+// other columns are categorical, read as strings with missing values "", and
+// are available by name from Categorical for encoding. A parse error names the
+// line and column of the value.
+func ReadCSV(r io.Reader, opts ...CSVOption) (*DataFrame, error) {
+	o := csvOptions{delimiter: ',', header: true, missing: DefaultMissingTokens, types: make(map[string]ColumnType)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = o.delimiter
+	// the line each record starts on, for errors, since quoted fields may span lines
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("reading CSV: no records")
+	}
+
+	var names []string
+	if o.header {
+		names, records, lines = records[0], records[1:], lines[1:]
+	} else {
+		names = make([]string, len(records[0]))
+		for j := range names {
+			names[j] = fmt.Sprintf("x%d", j)
+		}
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("reading CSV: no rows of data")
+	}
+	seen := make(map[string]bool, len(names))
+	for j, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("x%d", j)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("reading CSV: column %d has the name %q of an earlier column", j, name)
+		}
+		seen[name] = true
+		names[j] = name
+	}
+	for name := range o.types {
+		if !seen[name] {
+			return nil, fmt.Errorf("reading CSV: no column %q to set the type of", name)
+		}
+	}
+	missing := make(map[string]bool, len(o.missing))
+	for _, token := range o.missing {
+		missing[strings.TrimSpace(token)] = true
+	}
+
+	var columns [][]float64
+	var labels, order []string
+	categorical := make(map[string][]string)
+	for j, name := range names {
+		t := o.types[name]
+		var values []float64
+		if t != ColumnCategorical {
+			values = make([]float64, len(records))
+		}
+		for i := 0; i < len(records) && values != nil; i++ {
+			cell := strings.TrimSpace(records[i][j])
+			if missing[cell] {
+				values[i] = math.NaN()
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			switch {
+			case err != nil && t == ColumnNumeric:
+				return nil, fmt.Errorf("reading CSV: line %d, column %d (%s): %q is not a number", lines[i], j, name, cell)
+			case err != nil:
+				values = nil
+			default:
+				values[i] = v
+			}
+		}
+		if values != nil {
+			columns = append(columns, values)
+			labels = append(labels, name)
+			continue
+		}
+		strs := make([]string, len(records))
+		for i, record := range records {
+			if cell := strings.TrimSpace(record[j]); !missing[cell] {
+				strs[i] = cell
+			}
+		}
+		categorical[name] = strs
+		order = append(order, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("reading CSV: no numeric columns")
+	}
+
+	x := mat.NewDense(len(records), len(columns), nil)
+	for j, column := range columns {
+		x.SetCol(j, column)
+	}
+	return &DataFrame{
+		X:           x,
+		n:           len(records),
+		c:           len(columns),
+		labels:      labels,
+		categorical: categorical,
+		categories:  order,
+	}, nil
+}
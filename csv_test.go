@@ -0,0 +1,97 @@
+package glasso
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func readMessy(t *testing.T, opts ...CSVOption) (*DataFrame, error) {
+	f, err := os.Open("testdata/messy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return ReadCSV(f, opts...)
+}
+
+func TestReadCSV(t *testing.T) {
+	df, err := readMessy(t)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, df.Labels(), []string{"mpg", "wt", "cyl"})
+	assert.Equal(t, df.Rows(), 6)
+	assert.Equal(t, df.GetRow(0), []float64{21, 2.62, 6})
+	assert.Equal(t, df.GetRow(5), []float64{18.1, 3.52, 8})
+	assert.Equal(t, df.X.At(2, 0), 21.4)
+	for _, missing := range [][2]int{{1, 1}, {3, 2}, {4, 0}} {
+		assert.T(t, math.IsNaN(df.X.At(missing[0], missing[1])), missing)
+	}
+
+	assert.Equal(t, df.CategoricalNames(), []string{"name", "notes"})
+	name, ok := df.Categorical("name")
+	assert.T(t, ok)
+	assert.Equal(t, name[4], `Valiant "slant six"`)
+	notes, _ := df.Categorical("notes")
+	assert.Equal(t, notes, []string{"fast, red", "", "two\nlines", "", "", "quiet"})
+	_, ok = df.Categorical("mpg")
+	assert.T(t, !ok)
+
+	// the response by name and the rest as predictors, with the incomplete rows dropped
+	x, response, err := df.SplitResponse("mpg")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, x.Labels(), []string{"wt", "cyl"})
+	assert.Equal(t, x.CategoricalNames(), []string{"name", "notes"})
+	assert.Equal(t, response[0], 21.0)
+	_, s, err := NewOlsTrainer(WithNAPolicy(OmitRows)).Train(x, response)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.(OlsSummary).RetainedRows(), []int{0, 2, 5})
+	_, _, err = df.SplitResponse("name")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestReadCSVOptions(t *testing.T) {
+	// an integer coded column read as categorical, for a CategoricalEncoder
+	df, err := readMessy(t, WithColumnType("cyl", ColumnCategorical))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, df.Labels(), []string{"mpg", "wt"})
+	cyl, _ := df.Categorical("cyl")
+	assert.Equal(t, cyl, []string{"6", "4", "6", "", "8", "8"})
+
+	// NA is a value, not missing, so wt isn't numeric
+	df, err = readMessy(t, WithMissingTokens("", "null"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, df.Labels(), []string{"mpg", "cyl"})
+	wt, _ := df.Categorical("wt")
+	assert.Equal(t, wt[1], "NA")
+
+	df, err = ReadCSV(strings.NewReader("1;2\n3;4\n5;7\n"), WithDelimiter(';'), WithNoHeader())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, df.Labels(), []string{"x0", "x1"})
+	assert.Equal(t, df.GetCol(1), []float64{2, 4, 7})
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	// the error names the line, after the quoted field spanning two lines, and the column
+	_, err := readMessy(t, WithColumnType("notes", ColumnNumeric))
+	assert.NotEqual(t, nil, err)
+	assert.T(t, strings.Contains(err.Error(), "line 2, column 4 (notes)"), err)
+	_, err = readMessy(t, WithColumnType("name", ColumnNumeric), WithMissingTokens("Mazda RX4", "Datsun 710", "Hornet 4 Drive"))
+	assert.NotEqual(t, nil, err)
+	assert.T(t, strings.Contains(err.Error(), "line 6, column 3 (name)"), err)
+
+	_, err = readMessy(t, WithColumnType("hp", ColumnNumeric))
+	assert.NotEqual(t, nil, err)
+	for _, input := range []string{
+		"",
+		"a,b\n",
+		"a,a\n1,2\n",
+		"a,b\n1,2\n3\n",
+		"a,b\nx,y\n",
+	} {
+		_, err := ReadCSV(strings.NewReader(input))
+		assert.NotEqual(t, nil, err, input)
+	}
+}
@@ -1,9 +1,11 @@
 package glasso
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
 )
 
 func makeDF() *DataFrame {
@@ -132,3 +134,111 @@ func TestRemove(t *testing.T) {
 		assert.Equal(t, col-1, df.Cols())
 	}
 }
+
+func TestSelectDrop(t *testing.T) {
+	t.Parallel()
+
+	df := mtcarsNamed()
+	x, err := df.Select("hp", "wt")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, x.Labels(), []string{"hp", "wt"})
+	assert.Equal(t, x.Cols(), 2)
+	dropped, err := df.Drop("mpg", "disp")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, dropped.Labels(), []string{"wt", "hp"})
+	assert.Equal(t, df.Labels(), []string{"mpg", "wt", "hp", "disp"})
+	assert.Equal(t, df.Cols(), 4)
+
+	// the fits on the selected columns are those on the matrices built by hand
+	_, s, err := NewOlsTrainer().Train(x, mtcarsMpg)
+	assert.Equal(t, nil, err)
+	_, manual, err := NewOlsTrainer().Train(mtcarsFrame(mtcarsHp, mtcarsWt), mtcarsMpg)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.Coefficients(), manual.Coefficients())
+	_, s, err = NewOlsTrainer().Train(dropped, mtcarsMpg)
+	assert.Equal(t, nil, err)
+	_, manual, err = NewOlsTrainer().Train(mtcarsFrame(mtcarsWt, mtcarsHp), mtcarsMpg)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.Coefficients(), manual.Coefficients())
+
+	for _, names := range [][]string{{"wt", "cyl"}, {"wt", "wt"}, {}} {
+		_, err = df.Select(names...)
+		assert.NotEqual(t, nil, err, names)
+	}
+	_, err = df.Drop("mpg", "cyl")
+	assert.NotEqual(t, nil, err)
+	_, err = df.Drop("mpg", "wt", "hp", "disp")
+	assert.NotEqual(t, nil, err)
+	_, err = makeDF().Copy().Select("a")
+	assert.Equal(t, LabelError, err)
+}
+
+func TestFilterRows(t *testing.T) {
+	t.Parallel()
+
+	// the heavy cars
+	df := mtcarsNamed()
+	heavy, err := df.Filter(func(row []float64) bool { return row[1] > 3 })
+	assert.Equal(t, nil, err)
+	var rows [][]float64
+	var mpg []float64
+	var indices []int
+	for i, wt := range mtcarsWt {
+		if wt > 3 {
+			rows = append(rows, []float64{mtcarsWt[i], mtcarsHp[i]})
+			mpg = append(mpg, mtcarsMpg[i])
+			indices = append(indices, i)
+		}
+	}
+	assert.Equal(t, heavy.Rows(), len(rows))
+	assert.Equal(t, df.Rows(), len(mtcarsMpg))
+	selected, err := df.SelectRows(indices)
+	assert.Equal(t, nil, err)
+	assert.T(t, mat.Equal(selected.X, heavy.X))
+
+	x, response, err := heavy.SplitResponse("mpg")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, response, mpg)
+	x, err = x.Drop("disp")
+	assert.Equal(t, nil, err)
+	_, s, err := NewOlsTrainer().Train(x, response)
+	assert.Equal(t, nil, err)
+	_, manual, err := NewOlsTrainer().Train(NewDataFrame(rows), mpg)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.Coefficients(), manual.Coefficients())
+
+	_, err = df.Filter(func(row []float64) bool { return false })
+	assert.NotEqual(t, nil, err)
+	_, err = df.SelectRows([]int{0, 32})
+	assert.NotEqual(t, nil, err)
+
+	// categorical columns follow the rows
+	csv, err := ReadCSV(strings.NewReader("y,g\n1,a\n2,b\n3,c\n"))
+	assert.Equal(t, nil, err)
+	csv, err = csv.SelectRows([]int{2, 0})
+	assert.Equal(t, nil, err)
+	g, _ := csv.Categorical("g")
+	assert.Equal(t, g, []string{"c", "a"})
+}
+
+func TestAppendNamed(t *testing.T) {
+	t.Parallel()
+
+	df := makeDF()
+	appended, err := df.Append([]float64{1, 2, 3}, "d")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, appended.Labels(), []string{"a", "b", "c", "d"})
+	assert.Equal(t, appended.GetCol(3), []float64{1, 2, 3})
+	assert.Equal(t, df.Cols(), 3)
+	assert.Equal(t, df.Labels(), []string{"a", "b", "c"})
+
+	_, err = df.Append([]float64{1, 2, 3}, "a")
+	assert.NotEqual(t, nil, err)
+	_, err = df.Append([]float64{1, 2}, "d")
+	assert.Equal(t, DimensionError, err)
+
+	// the columns of an unlabeled frame are named by their indices
+	unlabeled, err := df.Copy().Append([]float64{1, 2, 3}, "d")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, unlabeled.Labels(), []string{"x0", "x1", "x2", "d"})
+}
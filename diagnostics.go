@@ -1,52 +1,132 @@
 package glasso
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
-	"sync"
+	"strings"
+	"sync/atomic"
 
 	"github.com/drewlanenga/govector"
-	"github.com/ematvey/gostat"
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	// LeverageError is returned when a diagnostic is undefined because an
+	// observation has a leverage of one.
+	LeverageError = errors.New("observation has a leverage of one")
+
+	// SingularDesignError is returned when the design matrix doesn't have full
+	// column rank, so that (X'X)^-1 and the diagnostics built on it don't exist.
+	SingularDesignError = errors.New("design matrix is singular")
+
+	// TooFewObservationsError is returned when a model has too few observations
+	// for its coefficients: fewer than them for the QR factorization, or no
+	// more than them for an estimate of the error variance.
+	TooFewObservationsError = errors.New("too few observations for the coefficients")
 )
 
 // CooksDistance concurrently calculates the cooks distances for the model
 //
 // D_{i} = \frac{r_{i}^2}{p * MSE} * \frac{h_{ii}}{(1 - h_{ii})^2}
-func CooksDistance(m Summary) []float64 {
-	h := LeveragePoints(m)
-	residuals := m.Residuals()
-	distances := make([]float64, m.Data().Rows())
-	p := float64(m.Data().Cols())
-	mse := MseAdjusted(m)
-	wg := sync.WaitGroup{}
-	mu := sync.Mutex{}
-	cooks := func(i int) {
-		left := math.Pow(residuals[i], 2.0) / (p * mse)
-		right := h[i] / math.Pow(1-h[i], 2.0)
+//
+// Bounds optionally restrict the calculation to a range of observations:
+// CooksDistance(m, 10, 50) returns the distances for rows 10 through 50 (inclusive),
+// and CooksDistance(m, 10) returns the distances from row 10 to the last row.
+// The distance of an observation with a leverage of one is undefined, and a
+// LeverageError is returned if there is one in the range.
+func CooksDistance(m Summary, bounds ...int) ([]float64, error) {
+	return CooksDistanceCtx(context.Background(), m, bounds...)
+}
 
-		mu.Lock()
-		distances[i] = left * right
-		mu.Unlock()
+// cooksChunk is the number of observations a worker of CooksDistanceCtx takes
+// at a time between checks of its context.
+const cooksChunk = 1024
+
+// CooksDistanceCtx is CooksDistance, stopping early with ctx.Err() if ctx is
+// done. The observations are shared out in chunks among Parallelism()
+// workers, which check ctx between chunks and have all exited by the time it
+// returns.
+// Each leverage is found from its row of the design, h_ii = ||x_i R^-1||^2,
+// so that the thin Q isn't formed.
+func CooksDistanceCtx(ctx context.Context, m Summary, bounds ...int) ([]float64, error) {
+	n := m.Data().Rows()
+	start, end := 0, n-1
+	switch len(bounds) {
+	case 0:
+	case 1:
+		start = bounds[0]
+	default:
+		start, end = bounds[0], bounds[1]
 	}
-
-	for i := 0; i < m.Data().Rows(); i++ {
-		wg.Add(1)
-		go func(j int) {
-			cooks(j)
-			wg.Done()
-		}(i)
+	if start < 0 || end >= n || start > end {
+		return nil, fmt.Errorf("bounds [%d, %d] are out of range for %d rows", start, end, n)
 	}
-	wg.Wait()
 
-	return distances
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mse, err := MseAdjusted(m)
+	if err != nil {
+		return nil, err
+	}
+	tri, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	// the rows of the transpose are the columns of R^-1
+	rinvT := mat.DenseCopyOf(tri.T())
+	x := m.Data().X
+	residuals := m.Residuals()
+	distances := make([]float64, end-start+1)
+	c := m.Data().Cols()
+	p := float64(c)
+
+	var unit int32
+	err = parallelChunks(ctx, Parallelism(), start, end, cooksChunk, func(first, last int) bool {
+		for i := first; i <= last; i++ {
+			row := x.RawRowView(i)
+			h := 0.0
+			for j := 0; j < c; j++ {
+				v := 0.0
+				for k, r := range rinvT.RawRowView(j)[:j+1] {
+					v += row[k] * r
+				}
+				h += v * v
+			}
+			if isUnitLeverage(h) {
+				atomic.StoreInt32(&unit, 1)
+				return false
+			}
+			distances[i-start] = residuals[i] * residuals[i] / (p * mse) * h / ((1 - h) * (1 - h))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unit != 0 {
+		return nil, LeverageError
+	}
+	return distances, nil
 }
 
+// Mse returns RSS / n, as OlsSummary.MeanSquaredError does.
 func Mse(m Summary) float64 {
 	return m.SumOfSquares() / float64(m.Data().Rows())
 }
 
-func MseAdjusted(m Summary) float64 {
-	return m.SumOfSquares() / float64(m.Data().Rows()-m.Data().Cols())
+// MseAdjusted returns the unbiased estimate of the error variance RSS / (n - p),
+// with p the rank of a fit by FitSVD.
+// A model with no more observations than coefficients fits them exactly and
+// has no estimate, so a TooFewObservationsError is returned.
+func MseAdjusted(m Summary) (float64, error) {
+	n, p := m.Data().Rows(), rankOf(m)
+	if n <= p {
+		return 0, TooFewObservationsError
+	}
+	return m.SumOfSquares() / float64(n-p), nil
 }
 
 // LeveragePoints returns the diagonal of the hat matrix
@@ -56,62 +136,286 @@ func MseAdjusted(m Summary) float64 {
 //	 = QRR'-1 R-1 R'Q'
 //	 = QQ' (the first p cols of Q, where X = n x p)
 //
+// so h_ii is the squared norm of the ith row of the thin n x p Q. The n x n
+// hat matrix is never formed, allowing leverage to scale to large n.
+//
 // Leverage points are considered large if they exceed 2p/ n
+//
+// The leverages of an OlsSummary are found once and shared by the copies of
+// the summary, which like its other diagnostics are safe to compute from
+// several goroutines at once.
+func LeveragePoints(m Summary) ([]float64, error) {
+	if s, ok := m.(OlsSummary); ok && s.qr != nil {
+		return s.qr.leverages(m.Data().X, func() ([]float64, error) { return leveragePoints(m) })
+	}
+	return leveragePoints(m)
+}
 
-func LeveragePoints(m Summary) []float64 {
-	q := &mat64.Dense{}
-	h := &mat64.Dense{}
-	qr := &mat64.QR{}
-	qr.Factorize(m.Data().X)
-	q.QFromQR(qr)
-
-	// get the first p columns of Q
-	n, p := m.Data().X.Dims()
-	q = q.View(0, 0, n, p).(*mat64.Dense)
-	h.Mul(q, q.T())
+// leverageHook is called whenever leverages are computed.
+var leverageHook = func() {}
 
-	n = m.Data().Rows()
+func leveragePoints(m Summary) ([]float64, error) {
+	leverageHook()
+	q, err := thinQ(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := q.Dims()
 	diagonals := make([]float64, n)
 	for i := 0; i < n; i++ {
-		for j := -0; j < n; j++ {
-			if i == j {
-				diagonals[i] = h.At(i, j)
-			}
+		row := q.RawRowView(i)
+		for j := 0; j < p; j++ {
+			diagonals[i] += row[j] * row[j]
 		}
 	}
 
-	return diagonals
+	return diagonals, nil
 }
 
-// StudentizedResiduals returns the studentized residuals,
-// found by dividing residual by estimate of std deviation
+// DefaultMaxHatMatrixRows is the largest number of observations whose hat
+// matrix HatMatrix forms by default, with 800MB of entries.
+const DefaultMaxHatMatrixRows = 10000
+
+// maxHatMatrixRows is the limit of HatMatrix, or 0 for the default.
+var maxHatMatrixRows int64
+
+// SetMaxHatMatrixRows sets the largest number of observations whose hat
+// matrix HatMatrix will form. n <= 0 restores DefaultMaxHatMatrixRows.
+func SetMaxHatMatrixRows(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&maxHatMatrixRows, int64(n))
+}
+
+// MaxHatMatrixRows returns the largest number of observations whose hat
+// matrix HatMatrix will form.
+func MaxHatMatrixRows() int {
+	if n := atomic.LoadInt64(&maxHatMatrixRows); n > 0 {
+		return int(n)
+	}
+	return DefaultMaxHatMatrixRows
+}
+
+// HatMatrix returns the full n x n hat matrix H = QQ'. Since it requires n^2
+// memory, it should only be used for small n when the off-diagonal entries
+// are needed; use LeveragePoints for the diagonal and HatTrace for its trace.
+// A model of more than MaxHatMatrixRows observations is an error rather than
+// an allocation that could exhaust memory; SetMaxHatMatrixRows raises the
+// limit. The matrix of an OlsSummary is computed once and shared by the
+// copies of the summary, so it must not be modified.
+func HatMatrix(m Summary) (*mat.Dense, error) {
+	if n := m.Data().Rows(); n > MaxHatMatrixRows() {
+		return nil, fmt.Errorf("the hat matrix of %d observations has %d entries, more than SetMaxHatMatrixRows allows", n, n*n)
+	}
+	s, ok := m.(OlsSummary)
+	if ok && s.qr != nil {
+		if h := s.qr.hatMatrix(m.Data().X); h != nil {
+			return h, nil
+		}
+	}
+	q, err := thinQ(m)
+	if err != nil {
+		return nil, err
+	}
+	h := &mat.Dense{}
+	h.Mul(q, q.T())
+	if ok && s.qr != nil {
+		s.qr.setHatMatrix(m.Data().X, h)
+	}
+	return h, nil
+}
+
+// HatTrace returns the trace of the hat matrix, the sum of the leverages,
+// without forming the matrix. It is the number of coefficients of a design of
+// full rank, or the rank k of a fit by FitSVD, and is the model's degrees of
+// freedom.
+func HatTrace(m Summary) (float64, error) {
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return 0, err
+	}
+	return sum(h), nil
+}
+
+// thinQ returns the first p columns of Q from X = QR.
+// Since X = Q_1 R with R upper triangular, Q_1 = X R^-1.
+// For a fit by FitSVD it is U_k, which spans the same columns.
+func thinQ(m Summary) (*mat.Dense, error) {
+	if f := svdOf(m); f != nil {
+		return mat.DenseCopyOf(f.u), nil
+	}
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	q := &mat.Dense{}
+	q.Mul(m.Data().X, rinv)
+	return q, nil
+}
+
+// singularTolerance bounds |R_jj| / ||x_j||, the part of column j of the
+// design that isn't in the span of the columns before it, for rInverse.
+const singularTolerance = 1e-10
+
+// rInverse returns the inverse of the upper triangular R from X = QR, or from
+// X'X = R'R for a fit by the normal equations, which is the same but for the
+// signs of its rows. An R with a diagonal entry that is zero relative to its
+// column of the design, from a design without full column rank, is a
+// SingularDesignError.
+func rInverse(m Summary) (*mat.TriDense, error) {
+	if f := svdOf(m); f != nil && len(f.s) < m.Data().Cols() {
+		return nil, fmt.Errorf("%w: the fit kept %d of %d singular values", SingularDesignError, len(f.s), m.Data().Cols())
+	}
+	rtri, err := rFactor(m)
+	if err != nil {
+		return nil, err
+	}
+	x := m.Data().X
+	_, p := rtri.Dims()
+	for j := 0; j < p; j++ {
+		col := mat.Col(nil, j, x)
+		if !(math.Abs(rtri.At(j, j)) > singularTolerance*math.Sqrt(sum(prod(col, col)))) {
+			return nil, SingularDesignError
+		}
+	}
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(rtri); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	return rinv, nil
+}
+
+// rFactor returns the R of the summary's design, the Cholesky factor its fit
+// found if it solved the normal equations, and otherwise from its QR
+// factorization.
+func rFactor(m Summary) (*mat.TriDense, error) {
+	if s, ok := m.(OlsSummary); ok && s.qr != nil {
+		if r := s.qr.cholesky(m.Data().X); r != nil {
+			return r, nil
+		}
+	}
+	qr, err := qrOf(m)
+	if err != nil {
+		return nil, err
+	}
+	r := &mat.Dense{}
+	qr.RTo(r)
+	_, p := r.Dims()
+	rtri := mat.NewTriDense(p, mat.Upper, nil)
+	rtri.Copy(r)
+	return rtri, nil
+}
+
+// xtxInverse returns the unscaled covariance matrix (X'X)^-1 = R^-1 R'^-1, and
+// for a fit by FitSVD the pseudoinverse V_k S_k^-2 V_k'.
+func xtxInverse(m Summary) (*mat.Dense, error) {
+	if f := svdOf(m); f != nil {
+		return f.pseudoInverse(), nil
+	}
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	xtx := &mat.Dense{}
+	xtx.Mul(rinv, rinv.T())
+	return xtx, nil
+}
+
+// StandardizedResiduals returns the standardized residuals (R's rstandard),
+// found by dividing each residual by an estimate of its std deviation
+//
+// r_{i} = \frac{e_i}{s * \sqrt{1 - h_{ii}}}
 //
-// t_{i} = \frac{\hat{\epsilon}}{\sigma * \sqrt{1 - h_{ii}}}
-// \hat{\epsilon} =
-func StudentizedResiduals(m Summary) []float64 {
-	n, c := m.Data().Rows(), m.Data().Cols()
-	sigma := math.Sqrt(m.SumOfSquares() / float64(n-c))
-	h := LeveragePoints(m)
+// where s is the residual standard error sqrt(RSS / (n - p)), with p the number
+// of coefficients including the intercept. Observations with a leverage of one
+// have no residual variance and are NaN.
+func StandardizedResiduals(m Summary) ([]float64, error) {
+	mse, err := MseAdjusted(m)
+	if err != nil {
+		return nil, err
+	}
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	n := m.Data().Rows()
+	sigma := math.Sqrt(mse)
 	t := make([]float64, n)
 	residuals := m.Residuals()
-	for i := 0; i < m.Data().Rows(); i++ {
+	for i := 0; i < n; i++ {
+		if isUnitLeverage(h[i]) {
+			t[i] = math.NaN()
+			continue
+		}
 		t[i] = residuals[i] / (sigma * math.Sqrt(1-h[i]))
 	}
 
-	return t
+	return t, nil
 }
 
-// Press returns the Predicted Error Sum of Squares (Press) of the model.
-// This is used as estimate the model's ability to predict new observations
-// R^2_prediction = 1 - (PRESS / TSS)
-func Press(m Summary) []float64 {
+// InternallyStudentizedResiduals returns the internally studentized residuals,
+// which are the StandardizedResiduals: sigma is estimated from all of the residuals.
+// Since an outlier inflates the estimate of sigma, this understates how unusual
+// it is; see ExternallyStudentizedResiduals.
+func InternallyStudentizedResiduals(m Summary) ([]float64, error) {
+	return StandardizedResiduals(m)
+}
+
+// PressResiduals returns the prediction residuals of the model: the residual for
+// each observation when it is predicted by the model fit without it,
+// e_{(i)} = e_i / (1 - h_{ii}). Observations with a leverage of one are NaN.
+func PressResiduals(m Summary) ([]float64, error) {
+	hdiag, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
 	press := make([]float64, m.Data().Rows())
-	hdiag := LeveragePoints(m)
 	residuals := m.Residuals()
 	for i := 0; i < m.Data().Rows(); i++ {
+		if isUnitLeverage(hdiag[i]) {
+			press[i] = math.NaN()
+			continue
+		}
 		press[i] = residuals[i] / (1.0 - hdiag[i])
 	}
-	return press
+	return press, nil
+}
+
+// Press returns the Predicted Error Sum of Squares (Press) of the model.
+// This is used as estimate the model's ability to predict new observations
+// without refitting the model. If any observation has a leverage of one its
+// prediction residual is undefined, and a LeverageError is returned.
+func Press(m Summary) (float64, error) {
+	residuals, err := PressResiduals(m)
+	if err != nil {
+		return 0, err
+	}
+	press := 0.0
+	for _, e := range residuals {
+		if math.IsNaN(e) {
+			return 0, LeverageError
+		}
+		press += e * e
+	}
+	return press, nil
+}
+
+// PredictedRSquared is a leave-one-out estimate of the model's r-squared on new observations
+// R^2_prediction = 1 - (PRESS / TSS)
+//
+// with the TSS taken as for RSquared.
+func PredictedRSquared(m Summary) (float64, error) {
+	press, err := Press(m)
+	if err != nil {
+		return 0, err
+	}
+	return 1 - press/modelTotalSumOfSquares(m), nil
+}
+
+func totalSumOfSquares(y []float64) float64 {
+	dev := subtractMean(y)
+	return sum(prod(dev, dev))
 }
 
 // VarCov calculates the variance-covariance matrix of the regression coefficients
@@ -119,137 +423,179 @@ func Press(m Summary) []float64 {
 // Using QR decomposition: X = QR
 // ((QR)tQR)-1 ---> (RtQtQR)-1 ---> (RtR)-1 ---> R-1Rt-1 --> sigma*R-1Rt-1
 func VarCov(m Summary) (*DataFrame, error) {
-	r := &mat64.Dense{}
-	qr := &mat64.QR{}
-	qr.Factorize(m.Data().X)
-	r.RFromQR(qr)
-
-	var rinv mat64.Dense
-	var rtinv mat64.Dense
-	_, columns := r.Dims()
-	rCopy := mat64.NewDense(columns, columns, nil)
-	rCopy.Copy(r)
-
-	rt := mat64.NewDense(columns, columns, nil)
-	rt.Copy(rCopy.T())
-	if err := rtinv.Inverse(rt); err != nil {
+	mse, err := MseAdjusted(m)
+	if err != nil {
 		return nil, err
 	}
+	return scaledVarCov(m, mse)
+}
 
-	if err := rinv.Inverse(rCopy); err != nil {
+// scaledVarCov returns sigma2 (X'X)^-1, labeled by the coefficient names.
+func scaledVarCov(m Summary, sigma2 float64) (*DataFrame, error) {
+	varCov, err := xtxInverse(m)
+	if err != nil {
 		return nil, err
 	}
-
-	cols := m.Data().Cols()
-	varCov := mat64.NewDense(cols, cols, nil)
-	varCov.Mul(&rinv, &rtinv)
-	mse := MseAdjusted(m)
-	varCov.Apply(func(_, _ int, v float64) float64 { return v * mse }, varCov)
-	return Mat64ToDF(varCov), nil
+	varCov.Apply(func(_, _ int, v float64) float64 { return v * sigma2 }, varCov)
+	df := MatToDF(varCov)
+	df.labels = CoefficientNames(m)
+	return df, nil
 }
 
-// VarianceInflationFactors calculates the VIFs for the model.
+// VIF calculates the variance inflation factors for the predictors of the model.
 // VIF calculations are straightforward and easily comprehensible; the higher the value, the higher the collinearity
 // A VIF for a single explanatory variable is obtained using the r-squared value of the regression of that
-// variable against all other explanatory variables:
+// variable against all other explanatory variables (and an intercept):
 //
 // VIF_{j} = \frac{1}{1 - R_{j}^2}
 //
-func VarianceInflationFactors(m Summary, trainer Trainer) ([]float64, error) {
-	vifs := make([]float64, m.Data().Cols())
-	for i := 0; i < m.Data().Cols(); i++ {
-		data := m.Data().Copy()
-		if err := data.RemoveCol(i); err != nil {
-			return nil, err
+// The intercept column is skipped, so there is one VIF per predictor, in the
+// order of PredictorNames. For a weighted fit the auxiliary regressions are
+// weighted too. An error, naming the predictors involved, is returned if a
+// predictor is constant or perfectly collinear with the others.
+func VIF(m Summary) ([]float64, error) {
+	x := m.Data().X
+	n, c := x.Dims()
+	intercept := interceptOf(m)
+	ones := interceptValues(m)
+	names := CoefficientNames(m)
+
+	var vifs []float64
+	for j := 0; j < c; j++ {
+		if j == intercept {
+			continue
 		}
 
-		_, summary, err := trainer.Train(data, m.Response())
+		// regress x_j on an intercept and the remaining predictors
+		cols := []int{}
+		for k := 0; k < c; k++ {
+			if k != j && k != intercept {
+				cols = append(cols, k)
+			}
+		}
+		others := mat.NewDense(n, len(cols)+1, nil)
+		others.SetCol(0, ones)
+		for i, k := range cols {
+			others.SetCol(i+1, mat.Col(nil, k, x))
+		}
+
+		xj := mat.Col(nil, j, x)
+		fit, err := leastSquares(others, mat.NewDense(n, 1, xj))
 		if err != nil {
 			return nil, err
 		}
-		vifs[i] = 1.0 / (1.0 - summary.SumOfSquares())
+
+		tss := sumOfSquaresAround(xj, ones)
+		r2 := 1 - sum(prod(fit.residuals, fit.residuals))/tss
+		if tss == 0 {
+			return nil, fmt.Errorf("column %q is constant", names[j])
+		}
+		if r2 > 1-1e-10 {
+			// the predictors that x_j is a combination of
+			var with []string
+			for i, k := range cols {
+				xk := mat.Col(nil, k, x)
+				if math.Abs(fit.betas[i+1])*math.Sqrt(sumOfSquaresAround(xk, ones)) > 1e-8*math.Sqrt(tss) {
+					with = append(with, fmt.Sprintf("%q", names[k]))
+				}
+			}
+			return nil, fmt.Errorf("column %q is collinear with %s", names[j], strings.Join(with, ", "))
+		}
+		vifs = append(vifs, 1/(1-r2))
 	}
 	return vifs, nil
 }
 
-// VarBeta returns the variance of the coefficients for the model.
-// var(\beta) = \sigma * (Xt X_)-1
-// 			  = \sigma * ((QR)t QR) -1
-// 			  = \sigma * (RtQt QR) -1
-//			  = \sigma * (Rt R) -1
-//
-func VarBeta(m Summary) []float64 {
-	// use the unbiased estimator for sigma^2
-	sig := m.SumOfSquares() / float64(m.Data().Rows()-m.Data().Cols()-1)
-	vc, err := VarCov(m)
+// Tolerance returns the tolerance 1 / VIF_j of each predictor of the model.
+func Tolerance(m Summary) ([]float64, error) {
+	vifs, err := VIF(m)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-
-	vcdiag := make([]float64, vc.Rows())
-	for i := 0; i < vc.Rows(); i++ {
-		vcdiag[i] = vc.X.At(i, i)
+	tol := make([]float64, len(vifs))
+	for i, v := range vifs {
+		tol[i] = 1 / v
 	}
+	return tol, nil
+}
 
-	varbetas := make([]float64, len(vcdiag))
-	for i := range vcdiag {
-		varbetas[i] = math.Sqrt(sig * vcdiag[i])
+// VarBeta returns the standard error of each coefficient of the model, the
+// square root of the diagonal of VarCov:
+// var(\beta) = \sigma^2 * (Xt X)-1
+// 			  = \sigma^2 * ((QR)t QR) -1
+// 			  = \sigma^2 * (RtQt QR) -1
+//			  = \sigma^2 * (Rt R) -1
+//
+// with \sigma^2 estimated by MseAdjusted, RSS / (n - p).
+func VarBeta(m Summary) ([]float64, error) {
+	vc, err := VarCov(m)
+	if err != nil {
+		return nil, err
 	}
-
-	return varbetas
+	return StandardErrors(vc), nil
 }
 
 // Z Scores returns the Z score for each coefficient in the model.
 // To test a hypothesis that a coefficient B_j = 0, we form
 // the standardized coefficient or Z-score
 // Z_j = \frac{B_j}{\sigma * sqrt{v_{j}}}
-// where v_j is the jth diagonal element from the variance covariance matrix: (XtX)-1
-func ZScores(m Summary) []float64 {
-	z := make([]float64, m.Data().Cols())
-	v := make([]float64, m.Data().Cols())
-	sigma := math.Sqrt(m.SumOfSquares() / float64(m.Data().Rows()-m.Data().Cols()-1))
-
-	vc, err := VarCov(m)
+// where v_j is the jth diagonal element of (XtX)-1 and \sigma^2 is estimated
+// by MseAdjusted, so that \sigma * sqrt{v_j} is the standard error from
+// VarBeta. These are the t values of CoefficientTable.
+func ZScores(m Summary) ([]float64, error) {
+	se, err := VarBeta(m)
 	if err != nil {
-		return nil
-	}
-	for i := 0; i < len(v); i++ {
-		v[i] = vc.X.At(i, i)
+		return nil, err
 	}
-
+	z := make([]float64, len(se))
 	for i, beta := range m.Coefficients() {
-		z[i] = beta / (sigma * math.Sqrt(v[i]))
+		z[i] = beta / se[i]
 	}
-
-	return z
+	return z, nil
 }
 
 // The F statistic measures the change in residual sum-of-squares per
 // additional parameter in the bigger model, and it is normalized by an estimate of sigma2
+//
+// FTest refits the model with trainer after removing the design columns in
+// toRemove (indices into m.Data(), where the intercept is column 0 and can't be
+// removed), and compares the two fits with CompareModels. The model's own data is not modified.
 func FTest(m Summary, trainer Trainer, toRemove []int) (fval, pval float64, err error) {
-	tmp := m.Data()
-	n, c := m.Data().Rows(), m.Data().Cols()
-	oldSS := m.SumOfSquares()
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return 0, 0, fmt.Errorf("a model fit to a transformed problem can't be refit from its transformed data; fit the reduced model the same way and use CompareModels")
+	}
+	x := m.Data()
+	intercept := interceptColumn(x.X)
+	var keep []int
+	for j := 0; j < x.Cols(); j++ {
+		if j == intercept && containsInt(j, toRemove) {
+			return 0, 0, fmt.Errorf("can't remove the intercept")
+		}
+		if j != intercept && !containsInt(j, toRemove) {
+			keep = append(keep, j)
+		}
+	}
+	for _, j := range toRemove {
+		if j < 0 || j >= x.Cols() {
+			return 0, 0, DimensionError
+		}
+	}
+	if len(keep) == 0 {
+		return 0, 0, fmt.Errorf("can't remove every predictor")
+	}
 
-	for _, col := range toRemove {
-		err = tmp.RemoveCol(col)
-		if err != nil {
-			return
+	rows := make([][]float64, x.Rows())
+	for i := range rows {
+		rows[i] = make([]float64, len(keep))
+		for k, j := range keep {
+			rows[i][k] = x.X.At(i, j)
 		}
 	}
-	_, summary, err := trainer.Train(tmp, m.Response())
+	_, reduced, err := trainer.Train(NewDataFrame(rows), m.Response())
 	if err != nil {
-		return
+		return 0, 0, err
 	}
-
-	tmpN, tmpC := tmp.Data().Dims()
-	d1 := float64(c - tmpC)
-	d2 := float64(n - tmpN)
-	fval = (summary.SumOfSquares() / oldSS) / d1
-	fval /= oldSS / d2
-	Fdist := stat.F_CDF(d1, d2)
-	pval = 1 - Fdist(fval)
-	return
+	return CompareModels(m, reduced)
 }
 
 // Durbin Watson Test for Autocorrelatoin of the Residuals
@@ -268,16 +614,104 @@ func DW(m Summary) float64 {
 	return d
 }
 
-// n log(SSE(M) + 2(p(M)+1)
-// AIC = n log(SSE/n) + 2(p + 1).
+// LogLikelihood returns the maximized Gaussian log-likelihood of the model,
+// with the variance estimated by RSS / n:
+//
+// \ell = -\frac{n}{2} (\log(2 \pi) + \log(RSS / n) + 1)
+//
+// A weighted fit, whose errors have variances \sigma^2 / w_i, adds
+// \frac{1}{2} \sum_i \log w_i over the observations of positive weight, as
+// R's logLik.lm does.
+func LogLikelihood(m Summary) float64 {
+	n := float64(m.Data().Rows())
+	ll := -n / 2 * (math.Log(2*math.Pi) + math.Log(m.SumOfSquares()/n) + 1)
+	if o, ok := m.(OlsSummary); ok {
+		for _, w := range o.weights {
+			ll += math.Log(w) / 2
+		}
+	}
+	return ll
+}
+
+// A Smoother is a fit whose fitted values are a linear smoother of the
+// response, \hat{y} = H y, that knows the trace of H, its effective degrees
+// of freedom. For a least squares fit H is the hat matrix, whose trace is the
+// number of coefficients, but for a penalized fit such as ridge regression it
+// is less, and needn't be an integer. The information criteria and
+// CompareFits count the coefficients of a Smoother by EffectiveDF, so that
+// least squares and penalized fits can be compared with each other.
+type Smoother interface {
+	Summary
+
+	// EffectiveDF returns tr(H).
+	EffectiveDF() float64
+}
+
+// EffectiveDF returns the rank of the fit, the trace of its hat matrix, as
+// HatTrace does without computing the leverages.
+func (o OlsSummary) EffectiveDF() float64 {
+	return float64(rankOf(o))
+}
+
+// LogLikContributions returns the contribution of each observation to the
+// LogLikelihood, which they sum to,
+//
+// \ell_i = -\frac{1}{2} (\log(2 \pi) + \log(RSS / n) + e_i^2 / (RSS / n))
+//
+// with \frac{1}{2} \log w_i added for a weighted fit, whose residuals e_i are
+// scaled by \sqrt{w_i}. They are the pointwise log-likelihoods that model
+// stacking and the information criteria of single observations weigh.
+func LogLikContributions(m Summary) []float64 {
+	residuals := m.Residuals()
+	sigma2 := m.SumOfSquares() / float64(len(residuals))
+	ll := make([]float64, len(residuals))
+	for i, e := range residuals {
+		ll[i] = -(math.Log(2*math.Pi) + math.Log(sigma2) + e*e/sigma2) / 2
+	}
+	if o, ok := m.(OlsSummary); ok {
+		for i, w := range o.weights {
+			ll[i] += math.Log(w) / 2
+		}
+	}
+	return ll
+}
+
+// effectiveDF returns the EffectiveDF of a Smoother, and otherwise the rank
+// of the fit.
+func effectiveDF(m Summary) float64 {
+	if s, ok := m.(Smoother); ok {
+		return s.EffectiveDF()
+	}
+	return float64(rankOf(m))
+}
+
+// parameters counts the coefficients, by effectiveDF, and the error variance,
+// as R's logLik.lm does.
+func parameters(m Summary) float64 {
+	return effectiveDF(m) + 1
+}
+
+// AIC = -2 \ell + 2k, where k counts the coefficients and the error variance.
+// It matches R's AIC for lm fits. The coefficients of a Smoother, such as a
+// RidgeSummary, are counted by its EffectiveDF.
 func AIC(m Summary) float64 {
-	n, p := float64(m.Data().Rows()), float64(m.Data().Cols())
-	return n*math.Log(m.SumOfSquares()/n) + (2.0*p + 1)
+	return -2*LogLikelihood(m) + 2*parameters(m)
+}
+
+// AICc is the AIC with the small-sample correction of Hurvich & Tsai (1989)
+// AICc = AIC + \frac{2k(k + 1)}{n - k - 1}
+// It is infinite when n <= k + 1.
+func AICc(m Summary) float64 {
+	n, k := float64(m.Data().Rows()), parameters(m)
+	if n <= k+1 {
+		return math.Inf(1)
+	}
+	return AIC(m) + 2*k*(k+1)/(n-k-1)
 }
 
-// n log(SSE(M) + (p(M)+1)log(n)
-// BIC = n log(SSE/n) + log(n)(p + 1).
+// BIC = -2 \ell + k log(n), where k counts the coefficients and the error variance.
+// It matches R's BIC for lm fits.
 func BIC(m Summary) float64 {
-	n, p := float64(m.Data().Rows()), float64(m.Data().Cols())
-	return n*math.Log(m.SumOfSquares()/n) + (math.Log(n)*p + 1)
+	n := float64(m.Data().Rows())
+	return -2*LogLikelihood(m) + math.Log(n)*parameters(m)
 }
@@ -1,10 +1,18 @@
 package glasso
 
 import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/bmizerany/assert"
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 var (
@@ -54,33 +62,639 @@ func init() {
 
 func TestLeverage(t *testing.T) {
 	// compare leverage values with output from R to make sure it's correct
-	leverage := roundAll(LeveragePoints(summary))
+	leverage, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	leverage = roundAll(leverage)
 	assert.Equal(t, leverage[0], .302)
 	assert.Equal(t, leverage[1], .318)
 	assert.Equal(t, leverage[20], 0.285)
 }
 
+func TestLeverageMatchesHatMatrix(t *testing.T) {
+	h, err := HatMatrix(summary)
+	assert.Equal(t, nil, err)
+	leverage, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	n, c := h.Dims()
+	assert.Equal(t, n, 21)
+	assert.Equal(t, c, 21)
+	for i := range leverage {
+		assert.Equal(t, round(leverage[i], 10), round(h.At(i, i), 10))
+	}
+}
+
+func TestLeverageBruteForce(t *testing.T) {
+	// the diagonal of X(X'X)^{-1}X', formed directly from the design with its
+	// intercept column
+	x := summary.Data().X
+	xtx := &mat.Dense{}
+	xtx.Mul(x.T(), x)
+	inv := &mat.Dense{}
+	assert.Equal(t, nil, inv.Inverse(xtx))
+	xinv := &mat.Dense{}
+	xinv.Mul(x, inv)
+	h := &mat.Dense{}
+	h.Mul(xinv, x.T())
+
+	leverage, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	n, p := x.Dims()
+	assert.Equal(t, n, len(leverage))
+	for i, l := range leverage {
+		assert.T(t, l > 0 && l <= 1)
+		assertClose(t, l, h.At(i, i), 1e-10)
+	}
+	assertClose(t, sum(leverage), float64(p), 1e-10)
+}
+
+// assertProjection checks that h is the orthogonal projection onto a space
+// of dimension rank: symmetric, idempotent and with trace rank.
+func assertProjection(t *testing.T, h *mat.Dense, rank int) {
+	t.Helper()
+	n, c := h.Dims()
+	assert.Equal(t, n, c)
+	hh := &mat.Dense{}
+	hh.Mul(h, h)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			assertClose(t, h.At(i, j), h.At(j, i), 1e-12)
+			assertClose(t, hh.At(i, j), h.At(i, j), 1e-10)
+		}
+	}
+	assertClose(t, mat.Trace(h), float64(rank), 1e-10)
+}
+
+func TestHatMatrixInvariants(t *testing.T) {
+	for seed, size := range [][2]int{{10, 1}, {30, 4}, {60, 12}, {15, 14}} {
+		s := simulatedSummary(size[0], size[1], int64(seed))
+		h, err := HatMatrix(s)
+		assert.Equal(t, nil, err)
+		assertProjection(t, h, size[1]+1)
+		trace, err := HatTrace(s)
+		assert.Equal(t, nil, err)
+		assertClose(t, trace, float64(size[1]+1), 1e-10)
+	}
+
+	// through the origin, weighted, standardized and truncated
+	_, origin, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, weighted, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, standardized, err := NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	model, truncated, err := FitSVD(NewDataFrame(longley), longleyY, 1e-3)
+	assert.Equal(t, nil, err)
+	k := model.Rank()
+	assert.T(t, k < 7)
+	for _, c := range []struct {
+		s    Summary
+		rank int
+	}{{origin, 3}, {weighted, 4}, {standardized, 4}, {truncated, k}} {
+		h, err := HatMatrix(c.s)
+		assert.Equal(t, nil, err)
+		assertProjection(t, h, c.rank)
+		trace, err := HatTrace(c.s)
+		assert.Equal(t, nil, err)
+		assertClose(t, trace, float64(c.rank), 1e-10)
+	}
+}
+
+func TestHatMatrixCache(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	h, err := HatMatrix(s)
+	assert.Equal(t, nil, err)
+	again, err := HatMatrix(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, h == again)
+
+	// a summary with its design replaced doesn't use the old matrix
+	other := s.(OlsSummary)
+	other.data = NewDataFrame(longley)
+	other.data.PushCol(rep(1, len(longleyY)))
+	replaced, err := HatMatrix(other)
+	assert.Equal(t, nil, err)
+	r, _ := replaced.Dims()
+	assert.Equal(t, len(longleyY), r)
+}
+
+func TestHatMatrixLimit(t *testing.T) {
+	defer SetMaxHatMatrixRows(0)
+	assert.Equal(t, DefaultMaxHatMatrixRows, MaxHatMatrixRows())
+	SetMaxHatMatrixRows(20)
+	assert.Equal(t, 20, MaxHatMatrixRows())
+	_, err := HatMatrix(summary)
+	assert.NotEqual(t, nil, err)
+	// the diagonal and trace need no n x n matrix
+	_, err = LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	_, err = HatTrace(summary)
+	assert.Equal(t, nil, err)
+	SetMaxHatMatrixRows(21)
+	_, err = HatMatrix(summary)
+	assert.Equal(t, nil, err)
+	SetMaxHatMatrixRows(-1)
+	assert.Equal(t, DefaultMaxHatMatrixRows, MaxHatMatrixRows())
+}
+
+// simulatedSummary fits an OLS model on n rows of random data with p predictors.
+func simulatedSummary(n, p int, seed int64) Summary {
+	rng := rand.New(rand.NewSource(seed))
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		response[i] = rng.NormFloat64()
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+			response[i] += float64(j+1) * rows[i][j]
+		}
+	}
+
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func BenchmarkLeveragePoints(b *testing.B) {
+	s := simulatedSummary(50000, 5, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LeveragePoints(s)
+	}
+}
+
+// The hat matrix at n=50k would need 20GB, so compare at a size where it still fits.
+func BenchmarkLeverageFromHatMatrix(b *testing.B) {
+	s := simulatedSummary(2000, 5, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h, _ := HatMatrix(s)
+		for j := 0; j < 2000; j++ {
+			_ = h.At(j, j)
+		}
+	}
+}
+
+func BenchmarkLeveragePointsSmall(b *testing.B) {
+	s := simulatedSummary(2000, 5, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LeveragePoints(s)
+	}
+}
+
 func TestCooksDistance(t *testing.T) {
 	// compare cooks distances with output from R to make sure it's correct
-	cooks := roundAll(CooksDistance(summary))
+	cooks, err := CooksDistance(summary)
+	assert.Equal(t, nil, err)
+	cooks = roundAll(cooks)
 	assert.Equal(t, cooks[0], 0.154)
 	assert.Equal(t, cooks[1], 0.06)
 	assert.Equal(t, cooks[20], 0.692)
 }
 
+func TestCooksDistanceBounds(t *testing.T) {
+	all, err := CooksDistance(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(all), 21)
+
+	// explicit start and end are inclusive
+	subset, err := CooksDistance(summary, 10, 15)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(subset), 6)
+	assert.Equal(t, subset, all[10:16])
+
+	// a single bound runs through the last observation
+	tail, err := CooksDistance(summary, 18)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, tail, all[18:])
+
+	// out of range bounds are an error
+	for _, bounds := range [][]int{{5, 21}, {15, 10}, {-1}} {
+		_, err = CooksDistance(summary, bounds...)
+		assert.NotEqual(t, nil, err, bounds)
+	}
+}
+
+// sequentialCooks is a straightforward single goroutine implementation
+// of cooks distance used to check the concurrent version.
+func sequentialCooks(m Summary) []float64 {
+	h, _ := LeveragePoints(m)
+	p := float64(m.Data().Cols())
+	mse, _ := MseAdjusted(m)
+	d := make([]float64, len(h))
+	for i, r := range m.Residuals() {
+		d[i] = (r * r / (p * mse)) * (h[i] / ((1 - h[i]) * (1 - h[i])))
+	}
+	return d
+}
+
+func TestCooksDistanceOrdering(t *testing.T) {
+	expected := sequentialCooks(summary)
+
+	// repeat to give the scheduler a chance to interleave differently
+	for run := 0; run < 50; run++ {
+		cooks, err := CooksDistance(summary)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, len(cooks), len(expected))
+		for i := range cooks {
+			assert.Equal(t, round(cooks[i], 10), round(expected[i], 10))
+		}
+	}
+
+	// bounded results line up with the same observations
+	sub, err := CooksDistance(summary, 3, 9)
+	assert.Equal(t, nil, err)
+	for i := range sub {
+		assert.Equal(t, round(sub[i], 10), round(expected[i+3], 10))
+	}
+}
+
+func TestCooksDistanceLarge(t *testing.T) {
+	n := 5000
+	cooks, err := CooksDistance(simulatedSummary(n, 2, 1))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(cooks), n)
+	for _, d := range cooks {
+		assert.T(t, d >= 0)
+	}
+}
+
+func TestFactorizationIsCached(t *testing.T) {
+	var count int32
+	factorizeHook = func() { atomic.AddInt32(&count, 1) }
+	defer func() { factorizeHook = func() {} }()
+
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	leverage, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	cooks, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	students, err := InternallyStudentizedResiduals(s)
+	assert.Equal(t, nil, err)
+	press, err := PressResiduals(s)
+	assert.Equal(t, nil, err)
+	_, err = VarCov(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// results match a summary that has no cached factorization
+	uncached := s.(OlsSummary)
+	uncached.qr = nil
+	for _, diagnostic := range []struct {
+		f    func(Summary) ([]float64, error)
+		want []float64
+	}{
+		{LeveragePoints, leverage},
+		{func(m Summary) ([]float64, error) { return CooksDistance(m) }, cooks},
+		{InternallyStudentizedResiduals, students},
+		{PressResiduals, press},
+	} {
+		got, err := diagnostic.f(uncached)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, got, diagnostic.want)
+	}
+
+	// replacing the design matrix invalidates the cache
+	atomic.StoreInt32(&count, 0)
+	s.Data().X = s.Data().Data()
+	again, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, leverage, again)
+	LeveragePoints(s)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	// concurrent diagnostics share a single factorization
+	atomic.StoreInt32(&count, 0)
+	s.Data().X = s.Data().Data()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			LeveragePoints(s)
+			VarCov(s)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestConcurrentDiagnostics(t *testing.T) {
+	var factorizations, leverages int32
+	factorizeHook = func() { atomic.AddInt32(&factorizations, 1) }
+	leverageHook = func() { atomic.AddInt32(&leverages, 1) }
+	defer func() { factorizeHook, leverageHook = func() {}, func() {} }()
+
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	// the fit factorized the design; the diagnostics reuse it
+	atomic.StoreInt32(&factorizations, 0)
+	want, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	atomic.StoreInt32(&leverages, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h, err := LeveragePoints(s)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, want, h)
+			// a caller's changes to its copy don't reach the others
+			h[0] = 2
+			_, err = HatTrace(s)
+			assert.Equal(t, nil, err)
+			_, err = CooksDistance(s)
+			assert.Equal(t, nil, err)
+			_, err = StandardizedResiduals(s)
+			assert.Equal(t, nil, err)
+			_, err = VarCov(s)
+			assert.Equal(t, nil, err)
+			_, err = HatMatrix(s)
+			assert.Equal(t, nil, err)
+			m.Predict(data[0])
+			m.(*OLS).ResidualStandardError()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&factorizations))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&leverages))
+}
+
 func TestStudentized(t *testing.T) {
-	// compare studentized residuals with output from R
-	students := roundAll(StudentizedResiduals(summary))
+	// compare standardized residuals with output from R's rstandard
+	students, err := StandardizedResiduals(summary)
+	assert.Equal(t, nil, err)
+	students = roundAll(students)
 	assert.Equal(t, len(students), 21)
 	assert.Equal(t, students[0], 1.193)
 	assert.Equal(t, students[1], -0.716)
 }
 
+func TestStandardizedResidualsSmallSample(t *testing.T) {
+	// n = 4 observations and p = 2 coefficients leaves 2 residual degrees of freedom.
+	// By hand: yhat = 1.1 + 1.1x, e = (-0.1, 0.8, -1.3, 0.6), RSS = 2.7, s^2 = 2.7 / 2
+	// and h = 1/4 + (x - 1.5)^2 / 5 = (0.7, 0.3, 0.3, 0.7)
+	df := NewDataFrame([][]float64{{0}, {1}, {2}, {3}})
+	_, s, err := NewOlsTrainer().Train(df, []float64{1, 3, 2, 5})
+	assert.Equal(t, nil, err)
+
+	e := []float64{-0.1, 0.8, -1.3, 0.6}
+	h := []float64{0.7, 0.3, 0.3, 0.7}
+	standardized, err := StandardizedResiduals(s)
+	assert.Equal(t, nil, err)
+	for i := range e {
+		expected := e[i] / math.Sqrt(2.7/2*(1-h[i]))
+		assertClose(t, standardized[i], expected, 1e-10)
+	}
+	internal, err := InternallyStudentizedResiduals(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, standardized, internal)
+}
+
 func TestVarianceCovariance(t *testing.T) {
 	var_cov, err := VarCov(summary)
 	assert.Equal(t, nil, err)
-	assert.Equal(t, roundAll(mat64.Col(nil, 0, var_cov.Data())), []float64{141.515, 0.288, -0.652, -1.677})
-	assert.Equal(t, roundAll(mat64.Col(nil, 1, var_cov.Data())), []float64{0.288, 0.018, -0.037, -0.008})
-	assert.Equal(t, roundAll(mat64.Col(nil, 2, var_cov.Data())), []float64{-0.652, -0.037, 0.135, 0})
-	assert.Equal(t, roundAll(mat64.Col(nil, 3, var_cov.Data())), []float64{-1.677, -0.008, 0, 0.024})
+	assert.Equal(t, roundAll(mat.Col(nil, 0, var_cov.Data())), []float64{141.515, 0.288, -0.652, -1.677})
+	assert.Equal(t, roundAll(mat.Col(nil, 1, var_cov.Data())), []float64{0.288, 0.018, -0.037, -0.008})
+	assert.Equal(t, roundAll(mat.Col(nil, 2, var_cov.Data())), []float64{-0.652, -0.037, 0.135, 0})
+	assert.Equal(t, roundAll(mat.Col(nil, 3, var_cov.Data())), []float64{-1.677, -0.008, 0, 0.024})
+}
+
+func TestVIF(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+
+	// car::vif(lm(Employed ~ ., longley))
+	expected := []float64{135.53244, 1788.51348, 33.61889, 3.58893, 399.15102, 758.98060}
+	vifs, err := VIF(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(vifs), len(expected))
+	for i := range vifs {
+		assertClose(t, vifs[i]/expected[i], 1, 1e-6)
+	}
+
+	tol, err := Tolerance(s)
+	assert.Equal(t, nil, err)
+	for i := range tol {
+		assertClose(t, tol[i]*vifs[i], 1, 1e-12)
+	}
+}
+
+func TestVIFCollinear(t *testing.T) {
+	// the last column is the sum of the two before it; the fit itself would
+	// reject this design, so hand the design matrix to VIF directly
+	rows := [][]float64{{1, 1, 2, 3}, {1, 2, 1, 3}, {1, 3, 5, 8}, {1, 4, 3, 7}, {1, 5, 8, 13}, {1, 6, 2, 8}}
+	_, err := VIF(OlsSummary{data: NewDataFrame(rows)})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestInformationCriteria(t *testing.T) {
+	// logLik(lm(stack.loss ~ ., stackloss)) = -52.28779 (df = 5)
+	assertClose(t, LogLikelihood(summary), -52.28779, 1e-5)
+	assertClose(t, AIC(summary), 2*52.28779+2*5, 1e-4)
+	assertClose(t, BIC(summary), 2*52.28779+math.Log(21)*5, 1e-4)
+	assertClose(t, AICc(summary), AIC(summary)+2*5*6/15.0, 1e-12)
+
+	// a least squares fit is a Smoother with tr(H) = p, so that its
+	// coefficients count as before
+	s, ok := summary.(Smoother)
+	assert.T(t, ok)
+	assert.Equal(t, 4.0, s.EffectiveDF())
+	assert.Equal(t, -2*LogLikelihood(summary)+2*float64(summary.Data().Cols()+1), AIC(summary))
+	assert.Equal(t, -2*LogLikelihood(summary)+math.Log(21)*float64(summary.Data().Cols()+1), BIC(summary))
+
+	// longley, from the NIST certified residual standard deviation on 9 df
+	_, l, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	rss := 0.304854073561965 * 0.304854073561965 * 9
+	ll := -8 * (math.Log(2*math.Pi) + math.Log(rss/16) + 1)
+	assertClose(t, LogLikelihood(l), ll, 1e-8)
+	assertClose(t, AIC(l), -2*ll+16, 1e-8)
+	assertClose(t, BIC(l), -2*ll+8*math.Log(16), 1e-8)
+
+	// through the origin there is one fewer coefficient
+	origin := noInterceptSummary(t)
+	ll = -10.5 * (math.Log(2*math.Pi) + math.Log(origin.SumOfSquares()/21) + 1)
+	assertClose(t, LogLikelihood(origin), ll, 1e-12)
+	assertClose(t, AIC(origin), -2*ll+8, 1e-12)
+	assertClose(t, BIC(origin), -2*ll+4*math.Log(21), 1e-12)
+	assert.T(t, AIC(origin) > AIC(summary))
+
+	// the contributions of the observations sum to the log-likelihood
+	_, wls, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for _, m := range []Summary{summary, l, origin, wls} {
+		ll := LogLikContributions(m)
+		assert.Equal(t, m.Data().Rows(), len(ll))
+		assertClose(t, sum(ll), LogLikelihood(m), 1e-10)
+	}
+
+	// AICc needs n > k + 1
+	tiny := OlsSummary{residuals: []float64{1, -1, 0.5}, data: NewDataFrame([][]float64{{1, 0}, {1, 1}, {1, 2}})}
+	assert.T(t, math.IsInf(AICc(tiny), 1))
+}
+
+func TestDiagnosticErrors(t *testing.T) {
+	// a design whose third column duplicates its second has no (X'X)^-1
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{1, row[0], row[0]}
+	}
+	singular := OlsSummary{data: NewDataFrame(rows), residuals: y, betas: []float64{0, 0, 0}}
+	_, err := VarCov(singular)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	_, err = LeveragePoints(singular)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	_, err = CooksDistance(singular)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	_, err = DFBETA(singular)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+
+	// fewer observations than coefficients can't be factorized
+	wide := OlsSummary{data: NewDataFrame([][]float64{{1, 2, 3}, {1, 5, 4}}), residuals: []float64{0, 0}}
+	_, err = LeveragePoints(wide)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+	_, err = HatMatrix(wide)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+	_, err = Anova(wide)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+	_, _, err = trainLeastSquares(NewDataFrame([][]float64{{1, 2}, {3, 5}}), []float64{1, 2}, nil)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+
+	// an exact fit of as many observations as coefficients has no error variance
+	_, exact, err := NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {2}}), []float64{3, 5})
+	assert.Equal(t, nil, err)
+	_, err = MseAdjusted(exact)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+	_, err = VarCov(exact)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+	_, err = StandardizedResiduals(exact)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+
+	// the Cook's distance of an observation with a leverage of one is undefined
+	_, err = CooksDistance(unitLeverageSummary(t))
+	assert.Equal(t, LeverageError, err)
+	_, err = CooksDistance(unitLeverageSummary(t), 0, 5)
+	assert.Equal(t, nil, err)
+}
+
+func TestVarBetaStackloss(t *testing.T) {
+	// the Std. Error and t value columns of summary(lm(stack.loss ~ .))
+	se, err := VarBeta(summary)
+	assert.Equal(t, nil, err)
+	z, err := ZScores(summary)
+	assert.Equal(t, nil, err)
+	for j, want := range []float64{11.896, 0.1349, 0.3680, 0.1563} {
+		assertClose(t, se[j], want, 5e-4)
+	}
+	for j, want := range []float64{-3.356, 5.307, 3.520, -0.973} {
+		assertClose(t, z[j], want, 5e-4)
+	}
+
+	table, err := CoefficientTable(summary)
+	assert.Equal(t, nil, err)
+	for j, c := range table {
+		assert.Equal(t, c.StdError, se[j])
+		assert.Equal(t, c.T, z[j])
+	}
+}
+
+func TestResidualScaleByHand(t *testing.T) {
+	// y = 1.1x exactly at the least squares fit of (1, 1), (2, 3), (3, 2),
+	// (4, 5): the residuals are -0.1, 0.8, -1.3 and 0.6, RSS = 2.7, and with
+	// n - p = 2 the error variance is 1.35. The leverages are
+	// 1/4 + (x - 2.5)^2 / 5 and Sxx = 5.
+	m, s, err := NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {2}, {3}, {4}}), []float64{1, 3, 2, 5})
+	assert.Equal(t, nil, err)
+	o := s.(OlsSummary)
+	assertClose(t, o.ResidualSumofSquares(), 2.7, 1e-12)
+	assertClose(t, o.MeanSquaredError(), 2.7/4, 1e-12)
+	assertClose(t, Mse(s), 2.7/4, 1e-12)
+	mse, err := MseAdjusted(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, mse, 1.35, 1e-12)
+	assertClose(t, o.ResidualStandardError(), math.Sqrt(1.35), 1e-12)
+	assertClose(t, m.(*OLS).ResidualStandardError(), math.Sqrt(1.35), 1e-12)
+
+	// Var(intercept) = sigma^2 (1/n + xbar^2 / Sxx), Var(slope) = sigma^2 / Sxx
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, vcov.X.At(0, 0), 1.35*(0.25+6.25/5), 1e-12)
+	assertClose(t, vcov.X.At(1, 1), 1.35/5, 1e-12)
+	se, err := VarBeta(s)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, se, []float64{math.Sqrt(1.35 * 1.5), math.Sqrt(1.35 / 5)}, 1e-12)
+	z, err := ZScores(s)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, z, []float64{0, 1.1 / math.Sqrt(1.35/5)}, 1e-12)
+
+	h := []float64{0.7, 0.3, 0.3, 0.7}
+	e := []float64{-0.1, 0.8, -1.3, 0.6}
+	standardized, err := StandardizedResiduals(s)
+	assert.Equal(t, nil, err)
+	cooks, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	for i := range e {
+		r := e[i] / math.Sqrt(1.35*(1-h[i]))
+		assertClose(t, standardized[i], r, 1e-12)
+		assertClose(t, cooks[i], r*r/2*h[i]/(1-h[i]), 1e-12)
+	}
+}
+
+// settleGoroutines waits for the number of goroutines to fall back to n,
+// failing the test if workers are still running after a second.
+func settleGoroutines(t *testing.T, n int) {
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines are running, want %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCooksDistanceCancel(t *testing.T) {
+	// a design whose distances take much longer than the deadline
+	n, p := 200000, 20
+	rng := rand.New(rand.NewSource(1))
+	x := mat.NewDense(n, p, nil)
+	residuals := make([]float64, n)
+	for i := 0; i < n; i++ {
+		residuals[i] = rng.NormFloat64()
+		for j := 0; j < p; j++ {
+			x.Set(i, j, rng.NormFloat64())
+		}
+	}
+	s := OlsSummary{data: MatToDF(x), residuals: residuals, qr: &qrCache{}}
+	// factorize up front, so that the deadline falls in the distances themselves
+	_, err := rInverse(s)
+	assert.Equal(t, nil, err)
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	distances, err := CooksDistanceCtx(ctx, s)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, len(distances), 0)
+	settleGoroutines(t, before)
+
+	// a context that is already done stops it before any work
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = CooksDistanceCtx(ctx, summary)
+	assert.Equal(t, context.Canceled, err)
+
+	// and one that isn't gives the distances of CooksDistance
+	withCtx, err := CooksDistanceCtx(context.Background(), summary, 3, 9)
+	assert.Equal(t, nil, err)
+	want, err := CooksDistance(summary, 3, 9)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, withCtx, want)
 }
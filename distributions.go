@@ -0,0 +1,398 @@
+package glasso
+
+import "math"
+
+// normalCDF returns the standard normal cumulative distribution function at x.
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// NormalQuantile returns the inverse of the standard normal cumulative
+// distribution function. It uses Acklam's rational approximation, which has a
+// relative error below 1.15e-9, followed by a Halley step against the exact CDF
+// that brings it to full double precision. Values of p outside of (0, 1) return
+// -Inf, +Inf or NaN accordingly.
+func NormalQuantile(p float64) float64 {
+	switch {
+	case math.IsNaN(p) || p < 0 || p > 1:
+		return math.NaN()
+	case p == 0:
+		return math.Inf(-1)
+	case p == 1:
+		return math.Inf(1)
+	}
+
+	a := []float64{-3.969683028665376e+01, 2.209460984245205e+02, -2.759285104469687e+02,
+		1.383577518672690e+02, -3.066479806614716e+01, 2.506628277459239e+00}
+	b := []float64{-5.447609879822406e+01, 1.615858368580409e+02, -1.556989798598866e+02,
+		6.680131188771972e+01, -1.328068155288572e+01}
+	c := []float64{-7.784894002430293e-03, -3.223964580411365e-01, -2.400758277161838e+00,
+		-2.549732539343734e+00, 4.374664141464968e+00, 2.938163982698783e+00}
+	d := []float64{7.784695709041462e-03, 3.224671290700398e-01, 2.445134137142996e+00,
+		3.754408661907416e+00}
+	const low = 0.02425
+
+	// rational approximations in the central region and in each tail
+	var x float64
+	switch {
+	case p < low:
+		q := math.Sqrt(-2 * math.Log(p))
+		x = (((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	case p <= 1-low:
+		q := p - 0.5
+		r := q * q
+		x = (((((a[0]*r+a[1])*r+a[2])*r+a[3])*r+a[4])*r + a[5]) * q /
+			(((((b[0]*r+b[1])*r+b[2])*r+b[3])*r+b[4])*r + 1)
+	default:
+		q := math.Sqrt(-2 * math.Log1p(-p))
+		x = -(((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	}
+
+	// refine against the CDF, working in the tail nearest to p to avoid cancellation
+	var e float64
+	if p < 0.5 {
+		e = normalCDF(x) - p
+	} else {
+		e = (1 - p) - normalCDF(-x)
+	}
+	u := e * math.Sqrt(2*math.Pi) * math.Exp(x*x/2)
+	return x - u/(1+x*u/2)
+}
+
+// chiSquareSurvival returns P(X > x) for a chi-squared random variable with df degrees of freedom.
+func chiSquareSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return upperGamma(df/2, x/2)
+}
+
+// chiSquareQuantile returns the inverse of the lower tail of the chi-squared
+// distribution with df degrees of freedom, by bisection on chiSquareSurvival.
+func chiSquareQuantile(p, df float64) float64 {
+	switch {
+	case math.IsNaN(p) || p < 0 || p > 1:
+		return math.NaN()
+	case p == 0:
+		return 0
+	case p == 1:
+		return math.Inf(1)
+	}
+	lo, hi := 0.0, math.Max(df, 1)
+	for 1-chiSquareSurvival(hi, df) < p {
+		lo = hi
+		hi *= 2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-15*hi; i++ {
+		mid := (lo + hi) / 2
+		if 1-chiSquareSurvival(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// upperGamma returns the regularized upper incomplete gamma function Q(a, x) = 1 - P(a, x).
+func upperGamma(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	if x < a+1 {
+		return 1 - gammaSeries(a, x)
+	}
+	return gammaFraction(a, x)
+}
+
+// gammaSeries evaluates P(a, x) by its series expansion, which converges quickly for x < a + 1.
+func gammaSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	term := 1 / a
+	total := term
+	for n := 1; n < 1000; n++ {
+		term *= x / (a + float64(n))
+		total += term
+		if math.Abs(term) < math.Abs(total)*1e-15 {
+			break
+		}
+	}
+	return total * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+// gammaFraction evaluates Q(a, x) by its continued fraction (modified Lentz),
+// which converges quickly for x >= a + 1.
+func gammaFraction(a, x float64) float64 {
+	const tiny = 1e-300
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// studentTCDF returns P(T <= t) for a Student's t random variable with df degrees of freedom.
+func studentTCDF(t, df float64) float64 {
+	tail := regularizedBeta(df/(df+t*t), df/2, 0.5) / 2
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for a Student's t random variable with
+// df degrees of freedom, without the cancellation of 1 - studentTCDF for large t.
+func studentTTwoSided(t, df float64) float64 {
+	return regularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// studentTPDF returns the density of Student's t distribution with df degrees of freedom.
+func studentTPDF(t, df float64) float64 {
+	a, _ := math.Lgamma((df + 1) / 2)
+	b, _ := math.Lgamma(df / 2)
+	return math.Exp(a - b - math.Log(df*math.Pi)/2 - (df+1)/2*math.Log1p(t*t/df))
+}
+
+// studentTQuantile returns the inverse of studentTCDF. It solves for the lower
+// tail quantile with safeguarded Newton steps inside an expanding bracket.
+func studentTQuantile(p, df float64) float64 {
+	switch {
+	case math.IsNaN(p) || p < 0 || p > 1:
+		return math.NaN()
+	case p == 0:
+		return math.Inf(-1)
+	case p == 1:
+		return math.Inf(1)
+	case p == 0.5:
+		return 0
+	case p > 0.5:
+		return -studentTQuantile(1-p, df)
+	}
+
+	// the quantile is negative; find lo with F(lo) < p
+	hi := 0.0
+	lo := math.Min(NormalQuantile(p), -1)
+	for studentTCDF(lo, df) > p {
+		hi = lo
+		lo *= 2
+	}
+
+	x := (lo + hi) / 2
+	for i := 0; i < 200; i++ {
+		f := studentTCDF(x, df) - p
+		if f > 0 {
+			hi = x
+		} else {
+			lo = x
+		}
+		next := x - f/studentTPDF(x, df)
+		if next <= lo || next >= hi || math.IsNaN(next) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-x) <= 1e-15*math.Abs(x) {
+			return next
+		}
+		x = next
+	}
+	return x
+}
+
+// fSurvival returns P(X > f) for an F random variable with d1 and d2 degrees of freedom.
+func fSurvival(f, d1, d2 float64) float64 {
+	if f <= 0 {
+		return 1
+	}
+	return regularizedBeta(d2/(d2+d1*f), d2/2, d1/2)
+}
+
+// fQuantile returns the inverse of the lower tail of the F distribution with
+// d1 and d2 degrees of freedom, by bisection on fSurvival.
+func fQuantile(p, d1, d2 float64) float64 {
+	switch {
+	case math.IsNaN(p) || p < 0 || p > 1:
+		return math.NaN()
+	case p == 0:
+		return 0
+	case p == 1:
+		return math.Inf(1)
+	}
+	lo, hi := 0.0, 1.0
+	for 1-fSurvival(hi, d1, d2) < p {
+		lo = hi
+		hi *= 2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-15*hi; i++ {
+		mid := (lo + hi) / 2
+		if 1-fSurvival(mid, d1, d2) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regularizedBeta returns the regularized incomplete beta function I_x(a, b).
+func regularizedBeta(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log1p(-x))
+
+	// the continued fraction converges quickly for x < (a + 1) / (a + b + 2),
+	// and I_x(a, b) = 1 - I_{1-x}(b, a) covers the rest
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// betaFraction evaluates the continued fraction for the incomplete beta function (modified Lentz).
+func betaFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+	qab, qap, qam := a+b, a+1, a-1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m < 10000; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		// even step
+		an := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + an*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// odd step
+		an = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + an*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
+
+// imhof returns P(Q < 0) for the quadratic form Q = \sum_i \lambda_i z_i^2 of
+// independent standard normals, by numerically inverting its characteristic
+// function (Imhof, 1961):
+//
+// P(Q > 0) = 1/2 + 1/\pi \int_0^\infty \frac{\sin \theta(u)}{u \rho(u)} du
+// \theta(u) = 1/2 \sum_i \arctan(\lambda_i u)
+// \rho(u) = \prod_i (1 + \lambda_i^2 u^2)^{1/4}
+func imhof(lambdas []float64) float64 {
+	const tol = 1e-10
+
+	// drop the terms that don't contribute to Q
+	var l []float64
+	for _, v := range lambdas {
+		if math.Abs(v) > 1e-12 {
+			l = append(l, v)
+		}
+	}
+	if len(l) == 0 {
+		return 0.5
+	}
+
+	integrand := func(u float64) float64 {
+		if u == 0 {
+			return 0.5 * sum(l)
+		}
+		theta, logRho := 0.0, 0.0
+		for _, v := range l {
+			theta += math.Atan(v * u)
+			logRho += math.Log1p(v*v*u*u) / 4
+		}
+		return math.Sin(theta/2) / (u * math.Exp(logRho))
+	}
+
+	// truncate the integral where Imhof's bound on the remainder drops below tol:
+	// 1 / (\pi k/2 U^{k/2} \prod |\lambda_i|^{1/2})
+	k := float64(len(l))
+	logProd := 0.0
+	for _, v := range l {
+		logProd += math.Log(math.Abs(v)) / 2
+	}
+	upper := math.Exp((-math.Log(math.Pi*k/2*tol) - logProd) / (k / 2))
+
+	integral := adaptiveSimpson(integrand, 0, upper, tol)
+	p := 0.5 - integral/math.Pi
+	return math.Min(math.Max(p, 0), 1)
+}
+
+// adaptiveSimpson integrates f over [a, b] to within roughly tol.
+func adaptiveSimpson(f func(float64) float64, a, b, tol float64) float64 {
+	// start from a fixed partition so slowly oscillating integrands aren't undersampled
+	const pieces = 64
+	total := 0.0
+	width := (b - a) / pieces
+	for i := 0; i < pieces; i++ {
+		lo, hi := a+float64(i)*width, a+float64(i+1)*width
+		flo, fhi, fmid := f(lo), f(hi), f((lo+hi)/2)
+		whole := (hi - lo) / 6 * (flo + 4*fmid + fhi)
+		total += simpsonStep(f, lo, hi, flo, fmid, fhi, whole, tol/pieces, 40)
+	}
+	return total
+}
+
+func simpsonStep(f func(float64) float64, a, b, fa, fm, fb, whole, tol float64, depth int) float64 {
+	m := (a + b) / 2
+	lm, rm := (a+m)/2, (m+b)/2
+	flm, frm := f(lm), f(rm)
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	if depth <= 0 || math.Abs(left+right-whole) <= 15*tol {
+		return left + right + (left+right-whole)/15
+	}
+	return simpsonStep(f, a, m, fa, flm, fm, left, tol/2, depth-1) +
+		simpsonStep(f, m, b, fm, frm, fb, right, tol/2, depth-1)
+}
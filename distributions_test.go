@@ -0,0 +1,148 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestChiSquareSurvival(t *testing.T) {
+	// upper 5% points of the chi-squared distribution
+	assertClose(t, chiSquareSurvival(3.841459, 1), 0.05, 1e-7)
+	assertClose(t, chiSquareSurvival(7.814728, 3), 0.05, 1e-7)
+	assertClose(t, chiSquareSurvival(18.307038, 10), 0.05, 1e-7)
+	assertClose(t, chiSquareSurvival(124.342113, 100), 0.05, 1e-7)
+	// with two degrees of freedom the survival function is exp(-x / 2)
+	assertClose(t, chiSquareSurvival(2, 2), math.Exp(-1), 1e-14)
+	assertClose(t, chiSquareSurvival(0.01, 2), math.Exp(-0.005), 1e-14)
+	assertClose(t, chiSquareSurvival(0, 4), 1, 0)
+}
+
+func TestChiSquareQuantile(t *testing.T) {
+	// qchisq in R
+	assertClose(t, chiSquareQuantile(0.95, 3), 7.814727903251178, 1e-9)
+	assertClose(t, chiSquareQuantile(0.5, 1), 0.454936423119572, 1e-9)
+	assertClose(t, chiSquareQuantile(0.5, 2), 2*math.Ln2, 1e-12)
+	assert.Equal(t, chiSquareQuantile(0, 4), 0.0)
+	assert.T(t, math.IsInf(chiSquareQuantile(1, 4), 1))
+}
+
+func TestFQuantile(t *testing.T) {
+	// F(1, d) is the square of t(d), and F(2, d) and F(d, 2) have quantiles in
+	// closed form
+	for _, p := range []float64{0.5, 0.9, 0.95, 0.99} {
+		for _, d := range []float64{2, 5, 17, 100} {
+			tq := studentTQuantile((1+p)/2, d)
+			assertClose(t, fQuantile(p, 1, d), tq*tq, 1e-9*tq*tq)
+			want := d / 2 * (math.Pow(1-p, -2/d) - 1)
+			assertClose(t, fQuantile(p, 2, d), want, 1e-10*want)
+			u := math.Pow(p, 2/d)
+			want = 2 * u / (d * (1 - u))
+			assertClose(t, fQuantile(p, d, 2), want, 1e-10*want)
+			assertClose(t, 1-fSurvival(fQuantile(p, 3, d), 3, d), p, 1e-12)
+		}
+	}
+	assert.Equal(t, fQuantile(0, 3, 4), 0.0)
+	assert.T(t, math.IsInf(fQuantile(1, 3, 4), 1))
+	assert.T(t, math.IsNaN(fQuantile(1.5, 3, 4)))
+}
+
+func TestImhof(t *testing.T) {
+	// z_1^2 - z_2^2 is symmetric about zero
+	assertClose(t, imhof([]float64{1, -1}), 0.5, 1e-8)
+	// chi^2_2 - 3 chi^2_2 is the difference of exponentials, so P(E_1 < 3 E_2) = 3/4
+	assertClose(t, imhof([]float64{1, 1, -3, -3}), 0.75, 1e-8)
+}
+
+func TestNormalQuantile(t *testing.T) {
+	// qnorm in R (and AS241)
+	quantiles := map[float64]float64{
+		1e-300: -37.0470962993612,
+		1e-10:  -6.361340902404056,
+		0.001:  -3.090232306167813,
+		0.02:   -2.053748910631823,
+		0.025:  -1.959963984540054,
+		0.25:   -0.674489750196082,
+		0.5:    0,
+		0.6:    0.253347103135800,
+		0.9:    1.281551565544601,
+		0.975:  1.959963984540054,
+		0.995:  2.575829303548901,
+		0.9999: 3.719016485455709,
+	}
+	for p, q := range quantiles {
+		assertClose(t, NormalQuantile(p), q, 1e-9)
+		if p > 1e-10 {
+			assertClose(t, normalCDF(NormalQuantile(p)), p, 1e-15)
+		}
+	}
+	assert.T(t, math.IsInf(NormalQuantile(0), -1))
+	assert.T(t, math.IsInf(NormalQuantile(1), 1))
+	assert.T(t, math.IsNaN(NormalQuantile(1.5)))
+}
+
+func TestRegularizedBeta(t *testing.T) {
+	for _, x := range []float64{0.01, 0.3, 0.5, 0.77, 0.999} {
+		assertClose(t, regularizedBeta(x, 2.5, 1), math.Pow(x, 2.5), 1e-14)
+		assertClose(t, regularizedBeta(x, 1, 3.5), 1-math.Pow(1-x, 3.5), 1e-14)
+		// symmetry
+		assertClose(t, regularizedBeta(x, 4, 7)+regularizedBeta(1-x, 7, 4), 1, 1e-14)
+	}
+	assertClose(t, regularizedBeta(0.5, 20, 20), 0.5, 1e-14)
+	assert.Equal(t, regularizedBeta(0, 2, 2), 0.0)
+	assert.Equal(t, regularizedBeta(1, 2, 2), 1.0)
+}
+
+func TestStudentTCDF(t *testing.T) {
+	for _, x := range []float64{-40, -3.2, -1, -0.1, 0, 0.4, 1.7, 6, 250} {
+		// closed forms for 1, 2 and 3 degrees of freedom
+		assertClose(t, studentTCDF(x, 1), 0.5+math.Atan(x)/math.Pi, 1e-14)
+		assertClose(t, studentTCDF(x, 2), 0.5+x/(2*math.Sqrt(2+x*x)), 1e-14)
+		r := x / math.Sqrt(3)
+		assertClose(t, studentTCDF(x, 3), 0.5+(r/(1+r*r)+math.Atan(r))/math.Pi, 1e-14)
+	}
+	// the t distribution approaches the normal
+	assertClose(t, studentTCDF(1.959963984540054, 1e7), 0.975, 1e-7)
+	// qt(0.975, 17) = 2.109816
+	assertClose(t, studentTTwoSided(2.109816, 17), 0.05, 1e-6)
+	assertClose(t, studentTTwoSided(60, 17), 2*(1-studentTCDF(60, 17)), 1e-14)
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	// qt in R
+	quantiles := []struct{ p, df, q float64 }{
+		{0.975, 17, 2.109815577833317},
+		{0.995, 17, 2.898230519677},
+		{0.95, 1, 6.313751514675},
+		{0.975, 2, 4.302652729696},
+		{0.025, 5, -2.570581835636},
+	}
+	for _, c := range quantiles {
+		assertClose(t, studentTQuantile(c.p, c.df), c.q, 1e-9)
+	}
+	for _, df := range []float64{0.5, 1, 3, 17, 1000} {
+		for _, p := range []float64{1e-12, 1e-4, 0.1, 0.3, 0.7, 0.999} {
+			q := studentTQuantile(p, df)
+			if p < 0.5 {
+				assertClose(t, studentTCDF(q, df)/p, 1, 1e-10)
+			} else {
+				assertClose(t, studentTCDF(q, df), p, 1e-12)
+			}
+		}
+	}
+	// closed form for one degree of freedom
+	assertClose(t, studentTQuantile(0.9, 1), math.Tan(math.Pi*0.4), 1e-10)
+	assert.Equal(t, studentTQuantile(0.5, 4), 0.0)
+	assert.T(t, math.IsInf(studentTQuantile(1, 4), 1))
+}
+
+func TestFSurvival(t *testing.T) {
+	for _, f := range []float64{0.01, 0.5, 1, 3.2, 40} {
+		// F(1, d) is the square of t(d)
+		assertClose(t, fSurvival(f, 1, 17), studentTTwoSided(math.Sqrt(f), 17), 1e-14)
+		// closed form with two numerator degrees of freedom
+		assertClose(t, fSurvival(f, 2, 9), math.Pow(1+2*f/9, -4.5), 1e-14)
+	}
+	assert.Equal(t, fSurvival(0, 3, 4), 1.0)
+}
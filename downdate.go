@@ -0,0 +1,345 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// deletion is the least squares fit that Without downdates: the summary of the
+// fit the model was trained with, the observations of it that are left, and
+// the factor and estimable coefficients of the fit to those observations.
+type deletion struct {
+	summary OlsSummary
+	kept    []int         // the rows of summary's design still in the fit, or nil for all of them
+	r       *mat.TriDense // R'R = X'X over the kept rows, or nil for the R of summary's QR
+	betas   []float64     // the coefficients of summary's columns
+	rss     float64
+}
+
+func newDeletion(s OlsSummary) *deletion {
+	return &deletion{summary: s, betas: s.betas, rss: s.SumOfSquares()}
+}
+
+// factor returns the triangular factor of the fit, summary's R for a fit that
+// nothing has been deleted from.
+func (d *deletion) factor() (*mat.TriDense, error) {
+	if d.r != nil {
+		return d.r, nil
+	}
+	return rFactor(d.summary)
+}
+
+// Without returns the model fit without observation i, counting the
+// observations as the model's summary does (so that for a model that dropped
+// rows, observation i is row RetainedRows()[i] of the training data), and for
+// weighted least squares as if the observation's weight were zero.
+//
+// The model isn't refit. With R'R = X'X and a = R'^-1 x_i, the leverage is
+// h_ii = ||a||^2, the coefficients are
+//
+// \beta_{(i)} = \beta - R^-1 a e_i / (1 - h_ii)
+//
+// the residual sum of squares loses e_i^2 / (1 - h_ii), and R is downdated to
+// the factor of X'X - x_i x_i' with the rotations of LINPACK's dchdd, so the
+// arithmetic of a deletion is O(p^2) for p coefficients however many
+// observations there are. The model returned can have further observations deleted in turn.
+//
+// An observation with a leverage of one is the only one determining some
+// combination of the coefficients, and deleting it leaves a rank-deficient
+// design. The remaining observations are then refit, aliasing the columns
+// that have become linear combinations of the others as Train does.
+//
+// Only a model trained by NewOlsTrainer or NewWlsTrainer keeps the fit it
+// needs; the model of other trainers, or one decoded from its serialized
+// form, returns an error.
+func (o *OLS) Without(i int) (*OLS, error) {
+	d := o.fit
+	if d == nil {
+		return nil, fmt.Errorf("the model doesn't keep the fit to delete observations from")
+	}
+	if i < 0 || i >= o.n {
+		return nil, fmt.Errorf("observation %d is out of range for %d observations", i, o.n)
+	}
+	p := len(d.betas)
+	if o.n-1 < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, o.n-1, p)
+	}
+	row := i
+	if d.kept != nil {
+		row = d.kept[i]
+	}
+	x := d.summary.data.X.RawRowView(row)
+	e := d.summary.response[row] - sum(prod(x, d.betas))
+
+	r, err := d.factor()
+	if err != nil {
+		return nil, err
+	}
+	a := transposeSolve(r, x)
+	h := sum(prod(a, a))
+	if isUnitLeverage(h) {
+		return o.refitWithout(i)
+	}
+
+	// u = (X'X)^-1 x_i = R^-1 a
+	u := make([]float64, p)
+	for j := p - 1; j >= 0; j-- {
+		v := a[j]
+		for k := j + 1; k < p; k++ {
+			v -= r.At(j, k) * u[k]
+		}
+		u[j] = v / r.At(j, j)
+	}
+	betas := make([]float64, p)
+	for j := range betas {
+		betas[j] = d.betas[j] - u[j]*e/(1-h)
+	}
+	rss := math.Max(d.rss-e*e/(1-h), 0)
+
+	downdated := choleskyDowndate(r, a, h)
+	next := &deletion{summary: d.summary, kept: withoutEntry(d.kept, i, o.n), r: downdated, betas: betas, rss: rss}
+	return o.deleted(i, next, o.aliased)
+}
+
+// Refit returns the model fit without the observations exclude, indices of
+// its observations as for Without, deleting them one at a time with Without
+// from the last to the first so that the indices of those left still hold.
+// Indices repeated are deleted once.
+func (o *OLS) Refit(exclude []int) (*OLS, error) {
+	rows := append([]int(nil), exclude...)
+	sort.Sort(sort.Reverse(sort.IntSlice(rows)))
+	fit := o
+	for k, i := range rows {
+		if k > 0 && i == rows[k-1] {
+			continue
+		}
+		next, err := fit.Without(i)
+		if err != nil {
+			return nil, fmt.Errorf("deleting observation %d: %w", i, err)
+		}
+		fit = next
+	}
+	return fit, nil
+}
+
+// transposeSolve returns a = R'^-1 x for the upper triangular R, solving
+// R'a = x by forward substitution.
+func transposeSolve(r *mat.TriDense, x []float64) []float64 {
+	a := make([]float64, len(x))
+	for j := range a {
+		v := x[j]
+		for k := 0; k < j; k++ {
+			v -= r.At(k, j) * a[k]
+		}
+		a[j] = v / r.At(j, j)
+	}
+	return a
+}
+
+// choleskyDowndate returns the upper triangular factor of R'R - x x', given
+// a = R'^-1 x and h = ||a||^2 < 1, as LINPACK's dchdd computes it: the
+// rotations that zero a against sqrt(1 - h), from its last element to its
+// first, are applied to the rows of R.
+func choleskyDowndate(r *mat.TriDense, a []float64, h float64) *mat.TriDense {
+	p := len(a)
+	c, s := make([]float64, p), make([]float64, p)
+	alpha := math.Sqrt(1 - h)
+	for k := p - 1; k >= 0; k-- {
+		scale := alpha + math.Abs(a[k])
+		u, v := alpha/scale, a[k]/scale
+		norm := math.Hypot(u, v)
+		c[k], s[k] = u/norm, v/norm
+		alpha = scale * norm
+	}
+	downdated := mat.NewTriDense(p, mat.Upper, nil)
+	for j := 0; j < p; j++ {
+		xx := 0.0
+		for k := j; k >= 0; k-- {
+			rkj := r.At(k, j)
+			t := c[k]*xx + s[k]*rkj
+			downdated.SetTri(k, j, c[k]*rkj-s[k]*xx)
+			xx = t
+		}
+	}
+	return downdated
+}
+
+// Summary returns the summary of the model's fit to the observations it has
+// left, the same as training it on them alone would return, so that the
+// diagnostics of a model from Without or Refit, its LeveragePoints,
+// CooksDistance and the rest, are exact rather than approximated from those
+// of the full fit. The observations left are refit by least squares for it;
+// a model nothing was deleted from returns the summary it was trained with.
+// RetainedRows and RowIDs of the summary are those of the model, so that the
+// observations of the diagnostics are still known by the rows of the
+// training data. A model that doesn't keep its fit, as Without describes,
+// returns an error; one decoded from its serialized form keeps its RowIDs,
+// but not the fit to summarize.
+func (o *OLS) Summary() (OlsSummary, error) {
+	d := o.fit
+	if d == nil {
+		return OlsSummary{}, fmt.Errorf("the model doesn't keep the fit to summarize")
+	}
+	summary := d.summary
+	if d.kept != nil {
+		var err error
+		if _, summary, err = d.refit(d.kept); err != nil {
+			return OlsSummary{}, err
+		}
+	}
+	summary.rows, summary.ids = o.rows, o.ids
+	return summary, nil
+}
+
+// refit fits the rows kept of the summary's design by least squares,
+// aliasing the columns that are linearly dependent among them. The summary
+// returned describes the problem the summary does, so that of a weighted fit
+// keeps its weights, but not which rows of the training data its
+// observations are.
+func (d *deletion) refit(kept []int) (*OLS, OlsSummary, error) {
+	s := d.summary
+	design, err := s.data.SelectRows(kept)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	// the names of the coefficients, for those the refit aliases
+	design.labels = CoefficientNames(s)
+	response := make([]float64, len(kept))
+	for k, row := range kept {
+		response[k] = s.response[row]
+	}
+	// the design already has its intercept column, as do the summary's of every fit
+	refit, summary, err := fitEstimable(design, design, false, func(x *DataFrame) (*OLS, OlsSummary, error) {
+		return fitLeastSquares(x, response, nil, false, QRSolver)
+	})
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	summary.origin = s.origin
+	summary.aliased = append(append([]string(nil), s.aliased...), summary.aliased...)
+	if s.transformed() {
+		summary.ones = make([]float64, len(kept))
+		summary.weights = make([]float64, len(kept))
+		summary.original = make([]float64, len(kept))
+		for k, row := range kept {
+			summary.ones[k] = s.ones[row]
+			summary.weights[k] = s.weights[row]
+			// the residual of the rescaled problem is \sqrt{w_i} e_i
+			summary.original[k] = summary.residuals[k] / math.Sqrt(s.weights[row])
+		}
+	}
+	return refit, summary, nil
+}
+
+// refitWithout refits the model's observations but i by least squares,
+// aliasing the columns that the deletion leaves linearly dependent.
+func (o *OLS) refitWithout(i int) (*OLS, error) {
+	refit, summary, err := o.fit.refit(withoutEntry(o.fit.kept, i, o.n))
+	if err != nil {
+		return nil, err
+	}
+
+	// the columns of the refit are the estimable coefficients of the model
+	estimable := o.estimable()
+	aliased := append([]int(nil), o.aliased...)
+	for _, k := range refit.aliased {
+		aliased = append(aliased, estimable[k])
+	}
+	sort.Ints(aliased)
+	next := newDeletion(summary)
+	return o.deleted(i, next, aliased)
+}
+
+// deleted builds the model without observation i from the fit next, whose
+// columns are the coefficients of the model that aren't aliased.
+func (o *OLS) deleted(i int, next *deletion, aliased []int) (*OLS, error) {
+	n, p := o.n-1, len(next.betas)
+	betas := make([]float64, o.p)
+	if aliased == nil {
+		copy(betas, next.betas)
+	} else {
+		isAliased := make([]bool, o.p)
+		for _, j := range aliased {
+			isAliased[j] = true
+		}
+		k := 0
+		for j := range betas {
+			if !isAliased[j] {
+				betas[j] = next.betas[k]
+				k++
+			}
+		}
+	}
+
+	sigma2 := math.NaN()
+	if n > p {
+		sigma2 = next.rss / float64(n-p)
+	}
+	r, err := next.factor()
+	if err != nil {
+		return nil, err
+	}
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(r); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	vcov := &mat.Dense{}
+	vcov.Mul(rinv, rinv.T())
+	vcov.Scale(sigma2, vcov)
+
+	model := &OLS{
+		betas:       betas,
+		n:           n,
+		p:           o.p,
+		names:       o.names,
+		sigma2:      sigma2,
+		vcov:        vcov,
+		rows:        withoutEntry(o.rows, i, o.n),
+		ids:         withoutID(o.ids, i),
+		noIntercept: o.noIntercept,
+		aliased:     aliased,
+		fit:         next,
+	}
+	if o.response != nil {
+		model.response = &responseTransform{ResponseTransform: o.response.ResponseTransform, residuals: model.Residuals()}
+	}
+	if o.weights != nil {
+		// the observations are the rows of positive weight, in order
+		model.weights = append([]float64(nil), o.weights...)
+		for k, w := range model.weights {
+			if w > 0 {
+				if i == 0 {
+					model.weights[k] = 0
+					break
+				}
+				i--
+			}
+		}
+	}
+	return model, nil
+}
+
+// estimable returns the indices of the coefficients that aren't aliased.
+func (o *OLS) estimable() []int {
+	var estimable []int
+	k := 0
+	for j := 0; j < o.p; j++ {
+		if k < len(o.aliased) && o.aliased[k] == j {
+			k++
+			continue
+		}
+		estimable = append(estimable, j)
+	}
+	return estimable
+}
+
+// withoutEntry returns rows, or 0, ..., n-1 if it is nil, without its i-th entry.
+func withoutEntry(rows []int, i, n int) []int {
+	if rows == nil {
+		rows = identityRows(n)
+	}
+	return append(append(make([]int, 0, len(rows)-1), rows[:i]...), rows[i+1:]...)
+}
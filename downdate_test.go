@@ -0,0 +1,287 @@
+package glasso
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// deletionData returns n observations of p predictors and their response.
+func deletionData(rng *rand.Rand, n, p int) ([][]float64, []float64) {
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		response[i] = 1
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64() + float64(j)
+			response[i] += float64(j+1) * rows[i][j]
+		}
+		response[i] += rng.NormFloat64()
+	}
+	return rows, response
+}
+
+// except returns rows and response without the observations in deleted.
+func except(rows [][]float64, response []float64, deleted ...int) ([][]float64, []float64) {
+	isDeleted := make(map[int]bool)
+	for _, i := range deleted {
+		isDeleted[i] = true
+	}
+	var r [][]float64
+	var y []float64
+	for i := range rows {
+		if !isDeleted[i] {
+			r = append(r, rows[i])
+			y = append(y, response[i])
+		}
+	}
+	return r, y
+}
+
+// assertSameModel checks a downdated model against a refit.
+func assertSameModel(t *testing.T, got, want *OLS) {
+	assert.Equal(t, got.n, want.n)
+	assert.Equal(t, got.aliased, want.aliased)
+	for j, b := range want.betas {
+		assertClose(t, got.betas[j], b, 1e-8*math.Max(1, math.Abs(b)))
+	}
+	assertClose(t, got.sigma2, want.sigma2, 1e-8*want.sigma2)
+	r, c := want.vcov.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			assertClose(t, got.vcov.At(i, j), want.vcov.At(i, j), 1e-8*math.Sqrt(want.vcov.At(i, i)*want.vcov.At(j, j)))
+		}
+	}
+}
+
+func TestWithout(t *testing.T) {
+	rows, response := deletionData(rand.New(rand.NewSource(1)), 50, 5)
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	model := m.(*OLS)
+	for i := range rows {
+		without, err := model.Without(i)
+		assert.Equal(t, nil, err)
+		r, y := except(rows, response, i)
+		refit, _, err := NewOlsTrainer().Train(NewDataFrame(r), y)
+		assert.Equal(t, nil, err)
+		assertSameModel(t, without, refit.(*OLS))
+		assert.Equal(t, without.RetainedRows(), withoutEntry(nil, i, len(rows)))
+	}
+
+	// in turn, and for a standardized fit
+	m, _, err = NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	model = m.(*OLS)
+	for _, i := range []int{10, 0, 47} {
+		model, err = model.Without(i)
+		assert.Equal(t, nil, err)
+	}
+	r, y := except(rows, response, 0, 10, 49)
+	refit, _, err := NewOlsTrainer().Train(NewDataFrame(r), y)
+	assert.Equal(t, nil, err)
+	assertSameModel(t, model, refit.(*OLS))
+	assert.Equal(t, model.RetainedRows()[:3], []int{1, 2, 3})
+	assertClose(t, model.Predict(rows[0]), refit.Predict(rows[0]), 1e-8)
+}
+
+func TestWithoutWeighted(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	rows, response := deletionData(rng, 50, 3)
+	weights := make([]float64, len(rows))
+	for i := range weights {
+		weights[i] = rng.Float64() + 0.5
+	}
+	weights[3] = 0
+	m, _, err := NewWlsTrainer(weights).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	// observation 5 is row 6, as row 3 isn't fit
+	without, err := m.(*OLS).Without(5)
+	assert.Equal(t, nil, err)
+	deleted := append([]float64(nil), weights...)
+	deleted[6] = 0
+	refit, _, err := NewWlsTrainer(deleted).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	assertSameModel(t, without, refit.(*OLS))
+	assert.Equal(t, without.weights, deleted)
+	assert.Equal(t, without.RetainedRows(), refit.(*OLS).RetainedRows())
+
+	// which is still a valid model
+	b, err := json.Marshal(without)
+	assert.Equal(t, nil, err)
+	var decoded OLS
+	assert.Equal(t, nil, json.Unmarshal(b, &decoded))
+	_, err = decoded.Without(0)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestWithoutRankDeficient(t *testing.T) {
+	// the last predictor is an indicator of row 7, which has a leverage of one
+	rows, response := deletionData(rand.New(rand.NewSource(3)), 30, 3)
+	for i := range rows {
+		rows[i] = append(rows[i], 0)
+	}
+	rows[7][3] = 1
+	df := NewDataFrame(rows)
+	df.labels = []string{"a", "b", "c", "d"}
+	m, _, err := NewOlsTrainer().Train(df, response)
+	assert.Equal(t, nil, err)
+	model := m.(*OLS)
+
+	without, err := model.Without(7)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, without.Aliased(), []string{"d"})
+	assert.T(t, math.IsNaN(without.Coefficients()[4]))
+	r, y := except(rows, response, 7)
+	refit, _, err := NewOlsTrainer().Train(NewDataFrame(r), y)
+	assert.Equal(t, nil, err)
+	assertSameModel(t, without, refit.(*OLS))
+
+	// and the rank-deficient model can be downdated further
+	again, err := without.Without(0)
+	assert.Equal(t, nil, err)
+	r, y = except(rows, response, 0, 7)
+	refit, _, err = NewOlsTrainer().Train(NewDataFrame(r), y)
+	assert.Equal(t, nil, err)
+	assertSameModel(t, again, refit.(*OLS))
+	assert.T(t, reflect.DeepEqual(again.Names(), model.Names()))
+}
+
+func TestWithoutErrors(t *testing.T) {
+	rows, response := deletionData(rand.New(rand.NewSource(4)), 4, 2)
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	model := m.(*OLS)
+	for _, i := range []int{-1, 4} {
+		_, err = model.Without(i)
+		assert.NotEqual(t, nil, err)
+	}
+	model, err = model.Without(0)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.IsNaN(model.sigma2))
+	_, err = model.Without(0)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+}
+
+func benchmarkDeletion(b *testing.B, refit bool) {
+	rows, response := deletionData(rand.New(rand.NewSource(5)), 10000, 5)
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		b.Fatal(err)
+	}
+	model := m.(*OLS)
+	b.ResetTimer()
+	for k := 0; k < b.N; k++ {
+		i := k % len(rows)
+		if refit {
+			r, y := except(rows, response, i)
+			_, _, err = NewOlsTrainer().Train(NewDataFrame(r), y)
+		} else {
+			_, err = model.Without(i)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWithout(b *testing.B)      { benchmarkDeletion(b, false) }
+func BenchmarkWithoutRefit(b *testing.B) { benchmarkDeletion(b, true) }
+
+// assertSameSummary checks the summary of a downdated model against that of a
+// refit.
+func assertSameSummary(t *testing.T, got, want OlsSummary) {
+	assert.Equal(t, got.Aliased(), want.Aliased())
+	assert.Equal(t, got.RetainedRows(), want.RetainedRows())
+	assertCloseSlices(t, got.Coefficients(), want.Coefficients(), 1e-8)
+	assertCloseSlices(t, got.Residuals(), want.Residuals(), 1e-8)
+	assertCloseSlices(t, got.OriginalResiduals(), want.OriginalResiduals(), 1e-8)
+	assertClose(t, got.RSquared(), want.RSquared(), 1e-10)
+	cooks := func(m Summary) ([]float64, error) { return CooksDistance(m) }
+	for _, measure := range []func(Summary) ([]float64, error){LeveragePoints, cooks, ExternallyStudentizedResiduals} {
+		g, err := measure(got)
+		assert.Equal(t, nil, err)
+		w, err := measure(want)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, g, w, 1e-8)
+	}
+}
+
+func TestSummaryOfRefit(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	rows, response := deletionData(rng, 40, 3)
+	weights := make([]float64, len(rows))
+	for i := range weights {
+		weights[i] = rng.Float64() + 0.5
+	}
+	weights[3] = 0
+	for _, weighted := range []bool{false, true} {
+		trainer := func(w []float64) Trainer {
+			if weighted {
+				return NewWlsTrainer(w)
+			}
+			return NewOlsTrainer()
+		}
+		m, s, err := trainer(weights).Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		model := m.(*OLS)
+
+		// the summary of a model nothing was deleted from is its own
+		summary, err := model.Summary()
+		assert.Equal(t, nil, err)
+		assertSameSummary(t, summary, s.(OlsSummary))
+
+		// that of a refit is the summary of a fit to the observations left,
+		// which for the weighted fit are rows 10, 20 and 30
+		refit, err := model.Refit([]int{9, 19, 29})
+		assert.Equal(t, nil, err)
+		summary, err = refit.Summary()
+		assert.Equal(t, nil, err)
+		deleted := append([]float64(nil), weights...)
+		var want Summary
+		if weighted {
+			deleted[10], deleted[20], deleted[30] = 0, 0, 0
+			_, want, err = trainer(deleted).Train(NewDataFrame(rows), response)
+		} else {
+			r, y := except(rows, response, 9, 19, 29)
+			_, want, err = trainer(nil).Train(NewDataFrame(r), y)
+			want = withRows(want.(OlsSummary), refit.RetainedRows())
+		}
+		assert.Equal(t, nil, err)
+		assertSameSummary(t, summary, want.(OlsSummary))
+	}
+
+	// a refit that aliases a column
+	for i := range rows {
+		rows[i] = append(rows[i], 0)
+	}
+	rows[7][3] = 1
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	without, err := m.(*OLS).Refit([]int{7, 0})
+	assert.Equal(t, nil, err)
+	summary, err := without.Summary()
+	assert.Equal(t, nil, err)
+	r, y := except(rows, response, 0, 7)
+	_, want, err := NewOlsTrainer().Train(NewDataFrame(r), y)
+	assert.Equal(t, nil, err)
+	assertSameSummary(t, summary, withRows(want.(OlsSummary), without.RetainedRows()))
+
+	var decoded OLS
+	_, err = decoded.Summary()
+	assert.NotEqual(t, nil, err)
+}
+
+// withRows returns the summary of a fit as if its observations were the rows
+// of a larger training data.
+func withRows(s OlsSummary, rows []int) OlsSummary {
+	s.rows = rows
+	return s
+}
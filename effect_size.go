@@ -0,0 +1,94 @@
+package glasso
+
+import "math"
+
+// The effect sizes below have one value per coefficient, in the order of
+// CoefficientNames, with NaN for the intercept, which has no effect of its
+// own to measure.
+
+// PartialRSquared returns the squared partial correlation of the response with
+// each predictor given the others, the proportion of the variation left after
+// the others that the predictor explains,
+//
+// R^2_{y j \cdot rest} = \frac{t_j^2}{t_j^2 + (n - p)}
+//
+// from its t statistic of CoefficientTable, as the squares of the partial
+// correlations of R's ppcor::pcor are.
+func PartialRSquared(m Summary) ([]float64, error) {
+	table, err := CoefficientTable(m)
+	if err != nil {
+		return nil, err
+	}
+	df := float64(m.Data().Rows() - rankOf(m))
+	intercept := interceptOf(m)
+	r2 := make([]float64, len(table))
+	for j, c := range table {
+		r2[j] = math.NaN()
+		if j != intercept {
+			r2[j] = c.T * c.T / (c.T*c.T + df)
+		}
+	}
+	return r2, nil
+}
+
+// SemiPartialRSquared returns the increase in R^2 from adding each predictor
+// to the model of the others, the squared semi-partial correlation of R's
+// ppcor::spcor,
+//
+// sr^2_j = \frac{SS_j}{TSS} = \frac{t_j^2 s^2}{TSS}
+//
+// where SS_j is the sum of squares of the predictor entered last, the
+// sequential sum of squares of Anova with its column at the end, which is
+// t_j^2 s^2. The TSS is that of the R^2 of OlsSummary, around the mean or
+// around zero for a model without an intercept. The increments sum to R^2
+// only for uncorrelated predictors; the rest is shared between them.
+func SemiPartialRSquared(m Summary) ([]float64, error) {
+	table, err := CoefficientTable(m)
+	if err != nil {
+		return nil, err
+	}
+	s2, err := MseAdjusted(m)
+	if err != nil {
+		return nil, err
+	}
+	tss := modelTotalSumOfSquares(m)
+	intercept := interceptOf(m)
+	sr2 := make([]float64, len(table))
+	for j, c := range table {
+		sr2[j] = math.NaN()
+		if j != intercept {
+			sr2[j] = c.T * c.T * s2 / tss
+		}
+	}
+	return sr2, nil
+}
+
+// StandardizedCoefficients returns the beta weights of the predictors, their
+// coefficients on the scale of standard deviations,
+//
+// \beta^*_j = \beta_j \frac{sd(x_j)}{sd(y)}
+//
+// the change in the response in its standard deviations for a change of one
+// standard deviation in the predictor, as R's lm.beta. The standard
+// deviations are around the mean even for a model without an intercept, and
+// weighted by the weights of a weighted fit.
+func StandardizedCoefficients(m Summary) ([]float64, error) {
+	x := m.Data()
+	intercept := interceptOf(m)
+	// the square roots of the sums of squares around the mean, whose ratios
+	// are those of the standard deviations
+	ones := interceptValues(m)
+	sd := func(v []float64) float64 {
+		return math.Sqrt(sumOfSquaresAround(v, ones))
+	}
+	sy := sd(m.Response())
+	betas := m.Coefficients()
+	standardized := make([]float64, len(betas))
+	for j, b := range betas {
+		standardized[j] = math.NaN()
+		if j != intercept {
+			standardized[j] = b * sd(x.GetCol(j)) / sy
+		}
+	}
+	return standardized, nil
+}
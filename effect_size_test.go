@@ -0,0 +1,93 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestEffectSizes(t *testing.T) {
+	// ppcor's pcor and spcor squared and lm.beta for lm(stack.loss ~ .), from
+	// their definitions computed exactly in Python since R isn't available here
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	nan := math.NaN()
+	for _, c := range []struct {
+		effect func(Summary) ([]float64, error)
+		want   []float64
+	}{
+		{PartialRSquared, []float64{nan, 0.6235618535268612, 0.4215198648737059, 0.052783996617726474}},
+		{SemiPartialRSquared, []float64{nan, 0.1431580357807113, 0.06297372950541776, 0.004815962110386564}},
+		{StandardizedCoefficients, []float64{nan, 0.6450476626821834, 0.4025024914643829, -0.08014054334947358}},
+	} {
+		got, err := c.effect(m)
+		assert.Equal(t, nil, err)
+		assert.T(t, math.IsNaN(got[0]))
+		assertCloseSlices(t, got[1:], c.want[1:], 1e-12)
+	}
+
+	// the semi-partial R² is the drop in R² without the predictor
+	full := m.(OlsSummary).RSquared()
+	sr2, err := SemiPartialRSquared(m)
+	assert.Equal(t, nil, err)
+	for j := range stacklossLabels {
+		rows := make([][]float64, len(data))
+		for i, row := range data {
+			rows[i] = append(append([]float64(nil), row[:j]...), row[j+1:]...)
+		}
+		_, without, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+		assert.Equal(t, nil, err)
+		assertClose(t, sr2[j+1], full-without.(OlsSummary).RSquared(), 1e-12)
+	}
+
+	// and the beta weights are the coefficients of the standardized variables
+	_, standardized, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	betas, err := StandardizedCoefficients(standardized)
+	assert.Equal(t, nil, err)
+	zy := subtractMean(y)
+	zy = multSlice(zy, 1/math.Sqrt(totalSumOfSquares(y)))
+	rows := make([][]float64, len(data))
+	for i := range rows {
+		rows[i] = make([]float64, 3)
+	}
+	for j := 0; j < 3; j++ {
+		col := make([]float64, len(data))
+		for i, row := range data {
+			col[i] = row[j]
+		}
+		for i, v := range multSlice(subtractMean(col), 1/math.Sqrt(totalSumOfSquares(col))) {
+			rows[i][j] = v
+		}
+	}
+	_, scaled, err := NewOlsTrainer().Train(NewDataFrame(rows), zy)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, betas[1:], scaled.Coefficients()[1:], 1e-12)
+}
+
+func TestEffectSizesThroughOrigin(t *testing.T) {
+	// the partial and semi-partial R² of lm(stack.loss ~ . - 1), the latter
+	// of the R² around zero
+	_, m, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	pr2, err := PartialRSquared(m)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, pr2, []float64{0.5606639434945366, 0.2481375765579422, 0.7514840758087843}, 1e-12)
+	sr2, err := SemiPartialRSquared(m)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, sr2, []float64{0.044539483343045104, 0.011518436997480367, 0.10553705107063795}, 1e-12)
+
+	// a weighted fit with equal weights is the unweighted one
+	_, w, err := NewWlsTrainer(rep(4, len(y))).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, u, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for _, effect := range []func(Summary) ([]float64, error){PartialRSquared, SemiPartialRSquared, StandardizedCoefficients} {
+		got, err := effect(w)
+		assert.Equal(t, nil, err)
+		want, err := effect(u)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, got[1:], want[1:], 1e-10)
+	}
+}
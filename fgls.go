@@ -0,0 +1,138 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// FGLSFit is a feasible generalized least squares fit, the weighted least
+// squares fit with weights from an estimated variance function.
+type FGLSFit struct {
+	Model   *OLS
+	Summary OlsSummary // of the final weighted fit, as NewWlsTrainer describes it
+
+	// Variance are the coefficients \gamma of the variance function
+	// \log \sigma^2_i = \gamma_0 + z_i'\gamma of the last iteration, the
+	// intercept first and then those of the columns varCols. The intercept
+	// absorbs E \log \chi^2_1, so only the others are on the scale of the
+	// variance.
+	Variance []float64
+	Weights  []float64 // the weights of the final fit, exp(-z_i'\gamma)
+
+	Iterations int
+	Converged  bool // whether the coefficients changed by less than the tolerance
+
+	varCols []int
+}
+
+// ErrorVariance returns the variance of the errors at the predictors x0,
+// without the intercept, that the fit estimates,
+//
+// \hat\sigma^2(x_0) = s^2 \exp(\hat\gamma_0 + z_0'\hat\gamma)
+//
+// with s^2 the residual variance of the final weighted fit, which puts the
+// variance function on the scale of the errors, and z_0 the values of x0 in
+// the columns varCols. It is the variance function of WithErrorVariance, for
+// the prediction intervals of PredictInterval.
+func (f *FGLSFit) ErrorVariance(x0 []float64) float64 {
+	eta := f.Variance[0]
+	for k, j := range f.varCols {
+		eta += f.Variance[k+1] * x0[j]
+	}
+	return f.Model.sigma2 * math.Exp(eta)
+}
+
+// FitFGLS fits y on the columns of x by feasible generalized least squares,
+// for errors whose variance is a function of the columns varCols of x,
+// \sigma^2_i = \exp(\gamma_0 + z_i'\gamma). From the least squares fit, each
+// iteration regresses \log e_i^2 on the columns z of varCols and refits by
+// weighted least squares with the weights 1 / \exp(\hat\gamma_0 + z_i'\hat\gamma),
+// for at most maxIter iterations. One iteration is the two-step estimator of
+// Harvey (1976); more iterate it until the coefficients \beta of the fit
+// change by a relative
+//
+// \sqrt{\sum (\beta_old - \beta)^2 / \sum \beta_old^2}
+//
+// of less than the tolerance (WithTolerance). The fit is through the origin
+// with WithIntercept(false), but the variance function always has an
+// intercept.
+func FitFGLS(x *DataFrame, y []float64, varCols []int, maxIter int, opts ...Option) (*FGLSFit, error) {
+	o := newOptions(opts)
+	if maxIter <= 0 {
+		return nil, fmt.Errorf("%d iterations: must be positive", maxIter)
+	}
+	if !(o.tolerance > 0) {
+		return nil, fmt.Errorf("tolerance %v is not positive", o.tolerance)
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if len(varCols) == 0 {
+		return nil, fmt.Errorf("no variance covariates given")
+	}
+	seen := make(map[int]bool, len(varCols))
+	for _, j := range varCols {
+		if j < 0 || j >= x.Cols() {
+			return nil, fmt.Errorf("variance covariate %d is not one of the %d columns", j, x.Cols())
+		}
+		if seen[j] {
+			return nil, fmt.Errorf("variance covariate %d is given twice", j)
+		}
+		seen[j] = true
+	}
+	z := make([][]float64, n)
+	for i := range z {
+		z[i] = make([]float64, len(varCols))
+		for k, j := range varCols {
+			z[i][k] = x.X.At(i, j)
+		}
+	}
+	covariates := NewDataFrame(z)
+
+	model, summary, err := NewOlsTrainer(WithIntercept(o.intercept)).Train(x.Copy(), y)
+	if err != nil {
+		return nil, err
+	}
+	fit := &FGLSFit{Model: model.(*OLS), Summary: summary.(OlsSummary), varCols: append([]int(nil), varCols...)}
+	logs := make([]float64, n)
+	for fit.Iterations < maxIter {
+		fit.Iterations++
+		for i, e := range fit.Summary.OriginalResiduals() {
+			if e == 0 {
+				return nil, fmt.Errorf("residual %d is zero, which has no log", i)
+			}
+			logs[i] = math.Log(e * e)
+		}
+		_, variance, err := NewOlsTrainer().Train(covariates.Copy(), logs)
+		if err != nil {
+			return nil, fmt.Errorf("the variance function: %w", err)
+		}
+		fit.Variance = variance.Coefficients()
+		fit.Weights = make([]float64, n)
+		for i, v := range variance.Yhat() {
+			fit.Weights[i] = math.Exp(-v)
+		}
+
+		previous := fit.Model.Coefficients()
+		model, summary, err := NewWlsTrainer(fit.Weights, WithIntercept(o.intercept)).Train(x.Copy(), y)
+		if err != nil {
+			return nil, err
+		}
+		fit.Model, fit.Summary = model.(*OLS), summary.(OlsSummary)
+
+		change, size := 0.0, 0.0
+		for j, b := range fit.Model.Coefficients() {
+			if math.IsNaN(b) {
+				continue // aliased
+			}
+			change += (previous[j] - b) * (previous[j] - b)
+			size += previous[j] * previous[j]
+		}
+		if math.Sqrt(change/math.Max(size, 1e-20)) < o.tolerance {
+			fit.Converged = true
+			break
+		}
+	}
+	return fit, nil
+}
@@ -0,0 +1,105 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// heteroskedasticData draws y = 1 + 2x + e for x uniform on [0, 4] and
+// normal errors e with the variance exp(-1 + 1.2x).
+func heteroskedasticData(n int, seed int64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		x := 4 * rng.Float64()
+		rows[i] = []float64{x}
+		response[i] = 1 + 2*x + math.Exp((-1+1.2*x)/2)*rng.NormFloat64()
+	}
+	return rows, response
+}
+
+func TestFitFGLS(t *testing.T) {
+	rows, response := heteroskedasticData(2000, 2)
+	x := NewDataFrame(rows)
+	fit, err := FitFGLS(x, response, []int{0}, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, fit.Iterations)
+	assert.Equal(t, 2, len(fit.Variance))
+	// E log chi^2_1 = -1.2704 shifts the intercept
+	assert.T(t, math.Abs(fit.Variance[0]-(-1-1.2704)) < 0.2, fit.Variance)
+	assert.T(t, math.Abs(fit.Variance[1]-1.2) < 0.1, fit.Variance)
+
+	// the final fit is the weighted least squares fit with the weights
+	for i, w := range fit.Weights {
+		assertClose(t, w, math.Exp(-fit.Variance[0]-fit.Variance[1]*rows[i][0]), 1e-9)
+	}
+	_, wls, err := NewWlsTrainer(fit.Weights).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, fit.Summary.Coefficients(), wls.Coefficients(), 1e-12)
+	assertCloseSlices(t, fit.Model.Coefficients(), wls.Coefficients(), 1e-12)
+
+	// iterating converges
+	iterated, err := FitFGLS(x, response, []int{0}, 50)
+	assert.Equal(t, nil, err)
+	assert.T(t, iterated.Converged)
+	assert.T(t, iterated.Iterations > 1 && iterated.Iterations < 50, iterated.Iterations)
+	assertCloseSlices(t, iterated.Model.Coefficients(), fit.Model.Coefficients(), 0.05)
+}
+
+func TestFGLSStandardErrors(t *testing.T) {
+	// the standard error of the slope against its spread over many samples:
+	// least squares is less efficient, and its usual standard error
+	// understates the spread, where the FGLS one is about right
+	const samples = 400
+	var fgls, ols, fglsSE, olsSE []float64
+	for k := 0; k < samples; k++ {
+		rows, response := heteroskedasticData(100, int64(10+k))
+		x := NewDataFrame(rows)
+		fit, err := FitFGLS(x, response, []int{0}, 1)
+		assert.Equal(t, nil, err)
+		table, err := CoefficientTable(fit.Summary)
+		assert.Equal(t, nil, err)
+		fgls = append(fgls, table[1].Estimate)
+		fglsSE = append(fglsSE, table[1].StdError)
+
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		table, err = CoefficientTable(s)
+		assert.Equal(t, nil, err)
+		ols = append(ols, table[1].Estimate)
+		olsSE = append(olsSE, table[1].StdError)
+	}
+	spread := func(v []float64) float64 {
+		m := mean(v)
+		ss := 0.0
+		for _, b := range v {
+			ss += (b - m) * (b - m)
+		}
+		return math.Sqrt(ss / float64(len(v)-1))
+	}
+	fglsSpread, olsSpread := spread(fgls), spread(ols)
+	assert.T(t, math.Abs(mean(fgls)-2) < 0.02, mean(fgls))
+	assert.T(t, fglsSpread < olsSpread, fglsSpread, olsSpread)
+	fglsErr := math.Abs(mean(fglsSE)/fglsSpread - 1)
+	olsErr := math.Abs(mean(olsSE)/olsSpread - 1)
+	assert.T(t, fglsErr < 0.15, fglsErr)
+	assert.T(t, fglsErr < olsErr, fglsErr, olsErr)
+}
+
+func TestFitFGLSErrors(t *testing.T) {
+	x := NewDataFrame(data)
+	for _, cols := range [][]int{nil, {3}, {-1}, {0, 0}} {
+		_, err := FitFGLS(x, y, cols, 1)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := FitFGLS(x, y, []int{0}, 0)
+	assert.NotEqual(t, nil, err)
+	_, err = FitFGLS(x, y[1:], []int{0}, 1)
+	assert.Equal(t, DimensionError, err)
+	_, err = FitFGLS(x, y, []int{0}, 1, WithTolerance(0))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,553 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// FormulaError is a syntax error in a formula, at the byte offset Pos of the
+// token that caused it.
+type FormulaError struct {
+	Formula string
+	Pos     int
+	Msg     string
+}
+
+func (e *FormulaError) Error() string {
+	return fmt.Sprintf("formula %q: %s at offset %d", e.Formula, e.Msg, e.Pos)
+}
+
+// A FormulaVariable is a column of the data in a formula, possibly transformed.
+type FormulaVariable struct {
+	Name      string // the label of the column
+	Transform string // "", or the "log" or "poly" it is wrapped in
+	Degree    int    // the degree of a poly
+}
+
+func (v FormulaVariable) String() string {
+	switch v.Transform {
+	case "log":
+		return fmt.Sprintf("log(%s)", v.Name)
+	case "poly":
+		return fmt.Sprintf("poly(%s,%d)", v.Name, v.Degree)
+	}
+	return v.Name
+}
+
+// A Term of a formula is the interaction of its variables, or a single
+// variable.
+type Term []FormulaVariable
+
+// String returns the name of the term, its variables joined by ":".
+func (t Term) String() string {
+	names := make([]string, len(t))
+	for k, v := range t {
+		names[k] = v.String()
+	}
+	return strings.Join(names, ":")
+}
+
+// key identifies the term regardless of the order of its variables, since
+// a:b and b:a are the same term.
+func (t Term) key() string {
+	names := make([]string, len(t))
+	for k, v := range t {
+		names[k] = v.String()
+	}
+	sort.Strings(names)
+	return strings.Join(names, ":")
+}
+
+// union returns the interaction of the variables of t and u, each variable once.
+func (t Term) union(u Term) Term {
+	result := append(Term(nil), t...)
+	for _, v := range u {
+		found := false
+		for _, w := range result {
+			found = found || w == v
+		}
+		if !found {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Formula is a parsed model formula.
+type Formula struct {
+	Response  string
+	Intercept bool
+	Terms     []Term // in the order of their coefficients: main effects, then interactions of two variables, and so on
+}
+
+// ParseFormula parses a model formula in the style of R, such as
+//
+// y ~ x1 + x2 + x1:x2 + poly(x3,2)
+//
+// The response is the name of a column, and the right hand side is a sum of
+// terms: column names, log(x) and poly(x,d) (the orthogonal polynomials of
+// degree 1 up to d in x) and their interactions a:b. a*b is shorthand for
+// a + b + a:b, and (a + b):c for a:c + b:c. A term after "-" is dropped from
+// the terms before it. The intercept is kept unless the formula has -1 or +0.
+// Terms are ordered as R orders them, by the number of variables they
+// interact and then as they first appear.
+func ParseFormula(formula string) (*Formula, error) {
+	p := &formulaParser{formula: formula}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	response := p.next()
+	if response.kind != tokenName {
+		return nil, p.errorAt(response, "expected the name of the response")
+	}
+	if t := p.next(); t.text != "~" {
+		return nil, p.errorAt(t, `expected "~" after the response`)
+	}
+	f := &Formula{Response: response.text, Intercept: true}
+	var terms []Term
+	seen := map[string]bool{}
+	sign := "+"
+	if t := p.peek(); t.text == "+" || t.text == "-" {
+		sign = p.next().text
+	}
+	for {
+		if t := p.peek(); t.kind == tokenNumber {
+			p.next()
+			switch t.text {
+			case "0":
+				f.Intercept = sign == "-"
+			case "1":
+				f.Intercept = sign == "+"
+			default:
+				return nil, p.errorAt(t, fmt.Sprintf("unexpected number %s, only 0 and 1 can add or drop the intercept", t.text))
+			}
+		} else {
+			expanded, err := p.parseStar()
+			if err != nil {
+				return nil, err
+			}
+			for _, term := range expanded {
+				key := term.key()
+				switch {
+				case sign == "+" && !seen[key]:
+					seen[key] = true
+					terms = append(terms, term)
+				case sign == "-" && seen[key]:
+					delete(seen, key)
+					for k := range terms {
+						if terms[k].key() == key {
+							terms = append(terms[:k], terms[k+1:]...)
+							break
+						}
+					}
+				}
+			}
+		}
+		t := p.next()
+		if t.kind == tokenEnd {
+			break
+		}
+		if t.text != "+" && t.text != "-" {
+			return nil, p.errorAt(t, fmt.Sprintf("unexpected %q, expected \"+\" or \"-\"", t.text))
+		}
+		sign = t.text
+	}
+	sort.SliceStable(terms, func(a, b int) bool { return len(terms[a]) < len(terms[b]) })
+	f.Terms = terms
+	return f, nil
+}
+
+const (
+	tokenName = iota
+	tokenNumber
+	tokenSymbol
+	tokenEnd
+)
+
+type formulaToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+type formulaParser struct {
+	formula string
+	tokens  []formulaToken
+	at      int
+}
+
+func (p *formulaParser) errorAt(t formulaToken, msg string) error {
+	return &FormulaError{Formula: p.formula, Pos: t.pos, Msg: msg}
+}
+
+func (p *formulaParser) tokenize() error {
+	s := p.formula
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("~+-:*(),", c):
+			p.tokens = append(p.tokens, formulaToken{tokenSymbol, string(c), i})
+			i++
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && unicode.IsDigit(rune(s[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, formulaToken{tokenNumber, s[i:j], i})
+			i = j
+		case unicode.IsLetter(c) || c == '_' || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, formulaToken{tokenName, s[i:j], i})
+			i = j
+		default:
+			return &FormulaError{Formula: s, Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	p.tokens = append(p.tokens, formulaToken{tokenEnd, "end of formula", len(s)})
+	return nil
+}
+
+func (p *formulaParser) peek() formulaToken { return p.tokens[p.at] }
+
+func (p *formulaParser) next() formulaToken {
+	t := p.tokens[p.at]
+	if t.kind != tokenEnd {
+		p.at++
+	}
+	return t
+}
+
+// parseStar parses a * b * ..., whose terms are every interaction of the
+// terms of a, b, ...
+func (p *formulaParser) parseStar() ([]Term, error) {
+	terms, err := p.parseColon()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "*" {
+		p.next()
+		right, err := p.parseColon()
+		if err != nil {
+			return nil, err
+		}
+		expanded := append([]Term(nil), terms...)
+		expanded = append(expanded, right...)
+		for _, a := range terms {
+			for _, b := range right {
+				expanded = append(expanded, a.union(b))
+			}
+		}
+		terms = expanded
+	}
+	return terms, nil
+}
+
+// parseColon parses a:b:..., whose terms are the interactions of a term of
+// each of a, b, ...
+func (p *formulaParser) parseColon() ([]Term, error) {
+	terms, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == ":" {
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		var product []Term
+		for _, a := range terms {
+			for _, b := range right {
+				product = append(product, a.union(b))
+			}
+		}
+		terms = product
+	}
+	return terms, nil
+}
+
+// parsePrimary parses a variable or a parenthesized sum of terms.
+func (p *formulaParser) parsePrimary() ([]Term, error) {
+	t := p.next()
+	switch {
+	case t.text == "(":
+		var terms []Term
+		for {
+			inner, err := p.parseStar()
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, inner...)
+			closing := p.next()
+			if closing.text == ")" {
+				return terms, nil
+			}
+			if closing.text != "+" {
+				return nil, p.errorAt(closing, fmt.Sprintf("unexpected %q, expected \"+\" or \")\"", closing.text))
+			}
+		}
+	case t.kind != tokenName:
+		return nil, p.errorAt(t, fmt.Sprintf("unexpected %q, expected a variable", t.text))
+	case p.peek().text != "(":
+		return []Term{{{Name: t.text}}}, nil
+	}
+
+	// a transformed variable
+	p.next()
+	v := FormulaVariable{Transform: t.text}
+	if v.Transform != "log" && v.Transform != "poly" {
+		return nil, p.errorAt(t, fmt.Sprintf("unknown transformation %q, expected log or poly", t.text))
+	}
+	name := p.next()
+	if name.kind != tokenName {
+		return nil, p.errorAt(name, fmt.Sprintf("unexpected %q, expected the variable of %s", name.text, v.Transform))
+	}
+	v.Name = name.text
+	if v.Transform == "poly" {
+		if comma := p.next(); comma.text != "," {
+			return nil, p.errorAt(comma, `expected "," and the degree of poly`)
+		}
+		degree := p.next()
+		d, err := strconv.Atoi(degree.text)
+		if degree.kind != tokenNumber || err != nil || d < 1 {
+			return nil, p.errorAt(degree, fmt.Sprintf("degree %q of poly is not a positive integer", degree.text))
+		}
+		v.Degree = d
+	}
+	if closing := p.next(); closing.text != ")" {
+		return nil, p.errorAt(closing, fmt.Sprintf("unexpected %q, expected \")\"", closing.text))
+	}
+	return []Term{{v}}, nil
+}
+
+// FormulaModel is a least squares fit of a formula, which predicts from rows
+// of data with the columns of the data it was fit on.
+type FormulaModel struct {
+	formula *Formula
+	columns map[string]int              // the column of each label of the data
+	bases   map[string]*PolynomialBasis // the basis of each poly, from the data it was fit on
+	names   []string                    // the name of each coefficient
+	betas   []float64
+}
+
+// Fit fits the formula to the data by least squares, with the columns of the
+// data named by its labels. The polynomials of poly are orthogonal over the
+// data, and the model predicts with the same basis. The coefficients are
+// named after their terms, "(Intercept)" first if the formula has one, and
+// the design of the summary is labeled by the names of its other columns.
+// The log of a value that isn't positive is an error.
+func Fit(formula string, data *DataFrame) (*FormulaModel, OlsSummary, error) {
+	f, err := ParseFormula(formula)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	labels := data.Labels()
+	if len(labels) != data.Cols() {
+		return nil, OlsSummary{}, LabelError
+	}
+	m := &FormulaModel{
+		formula: f,
+		columns: make(map[string]int, len(labels)),
+		bases:   map[string]*PolynomialBasis{},
+	}
+	for j, label := range labels {
+		if _, ok := m.columns[label]; ok {
+			return nil, OlsSummary{}, fmt.Errorf("column name %q is repeated", label)
+		}
+		m.columns[label] = j
+	}
+	response, ok := m.columns[f.Response]
+	if !ok {
+		return nil, OlsSummary{}, fmt.Errorf("response %s is not a column of the data", f.Response)
+	}
+	for _, term := range f.Terms {
+		for _, v := range term {
+			j, ok := m.columns[v.Name]
+			if !ok {
+				return nil, OlsSummary{}, fmt.Errorf("variable %s is not a column of the data", v.Name)
+			}
+			if v.Transform == "poly" && m.bases[v.String()] == nil {
+				b, err := NewPolynomialBasis(data.GetCol(j), v.Degree, true)
+				if err != nil {
+					return nil, OlsSummary{}, fmt.Errorf("%s: %v", v, err)
+				}
+				m.bases[v.String()] = b
+			}
+		}
+	}
+
+	n := data.Rows()
+	rows := make([][]float64, n)
+	for i := range rows {
+		row, err := m.designRow(data.GetRow(i))
+		if err != nil {
+			return nil, OlsSummary{}, fmt.Errorf("row %d: %v", i, err)
+		}
+		rows[i] = row
+	}
+	m.names = m.coefficientNames()
+	if len(m.names) == 0 {
+		return nil, OlsSummary{}, fmt.Errorf("formula has no terms and no intercept")
+	}
+	if n <= len(m.names) {
+		return nil, OlsSummary{}, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, len(m.names))
+	}
+	predictors := m.names
+	if f.Intercept {
+		predictors = m.names[1:]
+	}
+	design := NewDataFrame(rows, predictors)
+	y := data.GetCol(response)
+	fit, err := leastSquares(design.X, mat.NewDense(n, 1, append([]float64(nil), y...)))
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	m.betas = fit.betas
+	return m, OlsSummary{
+		betas:     fit.betas,
+		residuals: fit.residuals,
+		fitted:    fit.fitted,
+		response:  y,
+		n:         n,
+		p:         len(fit.betas),
+		data:      design,
+		qr:        &qrCache{x: design.X, qr: fit.qr},
+		origin:    !f.Intercept,
+	}, nil
+}
+
+// coefficientNames returns the names of the columns of the design.
+func (m *FormulaModel) coefficientNames() []string {
+	var names []string
+	if m.formula.Intercept {
+		names = append(names, "(Intercept)")
+	}
+	for _, term := range m.formula.Terms {
+		columns := []string{""}
+		for _, v := range term {
+			vnames := []string{v.String()}
+			if b := m.bases[v.String()]; b != nil {
+				vnames = b.Names(v.Name)
+			}
+			var product []string
+			for _, c := range columns {
+				for _, name := range vnames {
+					if c != "" {
+						name = c + ":" + name
+					}
+					product = append(product, name)
+				}
+			}
+			columns = product
+		}
+		names = append(names, columns...)
+	}
+	return names
+}
+
+// designRow returns the row of the design for a row of the data.
+func (m *FormulaModel) designRow(row []float64) ([]float64, error) {
+	var design []float64
+	if m.formula.Intercept {
+		design = append(design, 1)
+	}
+	for _, term := range m.formula.Terms {
+		columns := []float64{1}
+		for _, v := range term {
+			x := row[m.columns[v.Name]]
+			var values []float64
+			switch v.Transform {
+			case "log":
+				if !(x > 0) {
+					return nil, fmt.Errorf("%s is the log of %v", v, x)
+				}
+				values = []float64{math.Log(x)}
+			case "poly":
+				for _, column := range m.bases[v.String()].Expand([]float64{x}) {
+					values = append(values, column[0])
+				}
+			default:
+				values = []float64{x}
+			}
+			var product []float64
+			for _, c := range columns {
+				for _, value := range values {
+					product = append(product, c*value)
+				}
+			}
+			columns = product
+		}
+		design = append(design, columns...)
+	}
+	return design, nil
+}
+
+// Predict predicts the response for a row of data with the columns of the data
+// the model was fit on; the value in the column of the response is ignored.
+// Rows of the wrong length, or for which a log isn't defined, predict NaN.
+func (m *FormulaModel) Predict(x []float64) float64 {
+	if len(x) != len(m.columns) {
+		return math.NaN()
+	}
+	row, err := m.designRow(x)
+	if err != nil {
+		return math.NaN()
+	}
+	return sum(prod(row, m.betas))
+}
+
+// frameColumns returns the variables of the formula, in the order they appear.
+func (m *FormulaModel) frameColumns() ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, term := range m.formula.Terms {
+		for _, v := range term {
+			if !seen[v.Name] {
+				seen[v.Name] = true
+				names = append(names, v.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (m *FormulaModel) predictFrame(x *DataFrame) ([]float64, error) {
+	names, _ := m.frameColumns()
+	predictions := make([]float64, x.Rows())
+	row := make([]float64, len(m.columns))
+	for i := range predictions {
+		for j := range row {
+			row[j] = math.NaN()
+		}
+		for k, name := range names {
+			row[m.columns[name]] = x.X.At(i, k)
+		}
+		design, err := m.designRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		predictions[i] = sum(prod(design, m.betas))
+	}
+	return predictions, nil
+}
+
+// Formula returns the parsed formula of the model.
+func (m *FormulaModel) Formula() *Formula { return m.formula }
+
+// Names returns the names of the coefficients, such as "(Intercept)", "x1"
+// and "x1:x2", in order.
+func (m *FormulaModel) Names() []string { return m.names }
+
+// Coefficients returns the coefficients, in the order of Names.
+func (m *FormulaModel) Coefficients() []float64 { return m.betas }
@@ -0,0 +1,176 @@
+package glasso
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormula(t *testing.T) {
+	for _, test := range []struct {
+		formula   string
+		terms     []string
+		intercept bool
+	}{
+		{"y ~ x1", []string{"x1"}, true},
+		{"y ~ x1 + x2 + x1:x2 + poly(x3,2)", []string{"x1", "x2", "poly(x3,2)", "x1:x2"}, true},
+		{"y~x1*x2", []string{"x1", "x2", "x1:x2"}, true},
+		{"y ~ x1*x2*x3", []string{"x1", "x2", "x3", "x1:x2", "x1:x3", "x2:x3", "x1:x2:x3"}, true},
+		{"y ~ x1*x2 - x1:x2", []string{"x1", "x2"}, true},
+		{"y ~ x1 + x2 - 1", []string{"x1", "x2"}, false},
+		{"y ~ 0 + x1", []string{"x1"}, false},
+		{"y ~ -1 + x1", []string{"x1"}, false},
+		{"y ~ x1 - 1 + 1", []string{"x1"}, true},
+		{"y ~ log(x1) + x2:x1", []string{"log(x1)", "x2:x1"}, true},
+		{"y ~ (x1 + x2):x3", []string{"x1:x3", "x2:x3"}, true},
+		{"y ~ x1 + x1 + x2:x1 + x1:x2", []string{"x1", "x2:x1"}, true},
+		{"y ~ x1:x1 + poly(x1, 3):log(x2)", []string{"x1", "poly(x1,3):log(x2)"}, true},
+		{"log.y ~ x_1 + x.2", []string{"x_1", "x.2"}, true},
+	} {
+		f, err := ParseFormula(test.formula)
+		if err != nil {
+			t.Errorf("%s: %v", test.formula, err)
+			continue
+		}
+		var terms []string
+		for _, term := range f.Terms {
+			terms = append(terms, term.String())
+		}
+		if !reflect.DeepEqual(terms, test.terms) || f.Intercept != test.intercept {
+			t.Errorf("%s: got terms %v and intercept %v, want %v and %v", test.formula, terms, f.Intercept, test.terms, test.intercept)
+		}
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	for _, test := range []struct {
+		formula string
+		pos     int
+	}{
+		{"y ~ x1 + + x2", 9},
+		{"y x1", 2},
+		{"~ x1", 0},
+		{"y ~", 3},
+		{"y ~ sqrt(x1)", 4},
+		{"y ~ poly(x1)", 11},
+		{"y ~ poly(x1, 0)", 13},
+		{"y ~ x1 + 2", 9},
+		{"y ~ x1 $ x2", 7},
+		{"y ~ (x1 + x2", 12},
+		{"y ~ x1 x2", 7},
+		{"y ~ log(1)", 8},
+	} {
+		_, err := ParseFormula(test.formula)
+		fe, ok := err.(*FormulaError)
+		if !ok {
+			t.Errorf("%s: got error %v, want a FormulaError", test.formula, err)
+			continue
+		}
+		if fe.Pos != test.pos {
+			t.Errorf("%s: error %v is at %d, want %d", test.formula, err, fe.Pos, test.pos)
+		}
+	}
+}
+
+func mtcarsNamed() *DataFrame {
+	x := mtcarsFrame(mtcarsMpg, mtcarsWt, mtcarsHp, mtcarsDisp)
+	x.labels = []string{"mpg", "wt", "hp", "disp"}
+	return x
+}
+
+func TestFit(t *testing.T) {
+	// lm(mpg ~ wt * hp, mtcars)
+	model, s, err := Fit("mpg ~ wt*hp", mtcarsNamed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{"(Intercept)", "wt", "hp", "wt:hp"}; !reflect.DeepEqual(model.Names(), names) {
+		t.Errorf("got names %v, want %v", model.Names(), names)
+	}
+	if labels := []string{"wt", "hp", "wt:hp"}; !reflect.DeepEqual(s.Data().Labels(), labels) {
+		t.Errorf("got design labels %v, want %v", s.Data().Labels(), labels)
+	}
+	for j, want := range []float64{49.80842, -8.21662, -0.12010, 0.02785} {
+		if b := model.Coefficients()[j]; math.Abs(b-want) > 1e-5 {
+			t.Errorf("coefficient %d is %v, want %v", j, b, want)
+		}
+	}
+	row := []float64{0, 3, 150, 200}
+	if got, want := model.Predict(row), 49.80842-8.21662*3-0.12010*150+0.02785*450; math.Abs(got-want) > 1e-2 {
+		t.Errorf("prediction %v, want %v", got, want)
+	}
+	if r := s.RSquared(); math.Abs(r-0.8848) > 1e-4 {
+		t.Errorf("R^2 is %v, want 0.8848", r)
+	}
+
+	// without an intercept the slope is x'y / x'x
+	model, s, err = Fit("mpg ~ wt - 1", mtcarsNamed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sum(prod(mtcarsWt, mtcarsMpg)) / sum(prod(mtcarsWt, mtcarsWt))
+	if len(model.Coefficients()) != 1 || math.Abs(model.Coefficients()[0]-want) > 1e-10 {
+		t.Errorf("got coefficients %v, want [%v]", model.Coefficients(), want)
+	}
+	if s.hasIntercept() {
+		t.Errorf("summary of a fit without an intercept has one")
+	}
+}
+
+func TestFitTransforms(t *testing.T) {
+	data := mtcarsNamed()
+	model, _, err := Fit("mpg ~ poly(hp,2) + log(disp)", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{"(Intercept)", "poly(hp,1)", "poly(hp,2)", "log(disp)"}; !reflect.DeepEqual(model.Names(), names) {
+		t.Errorf("got names %v, want %v", model.Names(), names)
+	}
+
+	// the same fit from the polynomial features of hp
+	rows := make([][]float64, data.Rows())
+	for i := range rows {
+		rows[i] = []float64{mtcarsHp[i], math.Log(mtcarsDisp[i])}
+	}
+	x := NewDataFrame(rows)
+	features, err := NewPolynomialFeatures(x, 2, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := features.Transform(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, s, err := NewOlsTrainer().Train(expanded, mtcarsMpg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, want := range s.Coefficients() {
+		if b := model.Coefficients()[j]; math.Abs(b-want) > 1e-9 {
+			t.Errorf("coefficient %d is %v, want %v", j, b, want)
+		}
+	}
+	if v := model.Predict([]float64{0, 3, 150, -1}); !math.IsNaN(v) {
+		t.Errorf("prediction with the log of -1 is %v, want NaN", v)
+	}
+	if v := model.Predict([]float64{0, 3}); !math.IsNaN(v) {
+		t.Errorf("prediction from a short row is %v, want NaN", v)
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	data := mtcarsNamed()
+	for _, formula := range []string{"mpg ~ weight", "y ~ wt", "mpg ~ 0", "mpg ~ log(mpg - 1)", "mpg ~ poly(wt,40)"} {
+		if _, _, err := Fit(formula, data); err == nil {
+			t.Errorf("%s: expected an error", formula)
+		}
+	}
+	if _, _, err := Fit("mpg ~ wt", mtcarsFrame(mtcarsMpg, mtcarsWt)); err != LabelError {
+		t.Errorf("got error %v for data without labels, want LabelError", err)
+	}
+	negative := mtcarsNamed()
+	negative.X.Set(3, 3, -1)
+	if _, _, err := Fit("mpg ~ log(disp)", negative); err == nil {
+		t.Errorf("expected an error for the log of a negative value")
+	}
+}
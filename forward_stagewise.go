@@ -3,7 +3,7 @@ package glasso
 import (
 	"sort"
 
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 type fsTrainer struct {
@@ -26,11 +26,11 @@ func (r *fsModel) Predict(x []float64) float64 {
 	return r.betas[0] + sum(prod(x, r.betas[1:]))
 }
 
-func calculateCorrelation(x *mat64.Dense, y []float64) []float64 {
+func calculateCorrelation(x *mat.Dense, y []float64) []float64 {
 	_, p := x.Dims()
 	cors := make([]float64, 0, p)
 	for i := 0; i < p; i++ {
-		cors[i] = cor(mat64.Col(nil, i, x), y)
+		cors[i] = cor(mat.Col(nil, i, x), y)
 	}
 	return cors
 }
@@ -53,7 +53,7 @@ func (f *fsTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
 
 	// center y
 	r := subtractMean(y) // make sure y_bar = 0
-	x := mat64.NewDense(n, p, rep(0.0, n*p))
+	x := mat.NewDense(n, p, rep(0.0, n*p))
 	data := df.Data()
 	firstRun := true
 
@@ -84,16 +84,16 @@ func (f *fsTrainer) Train(df *DataFrame, y []float64) (Model, Summary, error) {
 		// update beta_j
 		// beta_j = beta_j + delta_j
 		// where delta_j = epsilon * sign(y, x_j)
-		x.SetCol(maxIdx, mat64.Col(nil, maxIdx, data))
+		x.SetCol(maxIdx, mat.Col(nil, maxIdx, data))
 		//ols := NewOLS(&DataFrame{x, n, p, nil})
 		//ols.Train(r)
 
 		// update beta
-		delta := f.epsilon * sign(sum(prod(mat64.Col(nil, maxIdx, x), r)))
+		delta := f.epsilon * sign(sum(prod(mat.Col(nil, maxIdx, x), r)))
 		betas[maxIdx] += delta
 
 		// set r = r - delta_j * x_j
-		r = diff(r, multSlice(mat64.Col(nil, maxIdx, x), delta))
+		r = diff(r, multSlice(mat.Col(nil, maxIdx, x), delta))
 	}
 
 	return &fsModel{
@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"math"
 
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 const DefaultTolerance = .000001
 
-// The GamConfig specifies the desired family, and other model configurations
+// The GLMConfig specifies the desired family, and other model configurations
 type GLMConfig struct {
 	F         Family  // Distribution family for the GLM
 	MaxIt     int64   // upper bound on number of model iterations
@@ -34,190 +34,525 @@ func NewGlmTrainer(config *GLMConfig) Trainer {
 	}
 }
 
-// Iterative Re-weighting Least Squares Estimation for Generalized Linear Models
+// maxHalvings bounds the number of times an IRLS step is halved to keep the
+// fitted means valid for the family.
+const maxHalvings = 30
+
+// Iterative Re-weighting Least Squares Estimation for Generalized Linear Models.
+// As in R's glm.fit, each iteration regresses the working response
+//
+// z = \eta + (y - \mu) / \mu'(\eta)
+//
+// on the design with weights w = \mu'(\eta)^2 / V(\mu), and the fit has converged
+// when the relative change in deviance |D - D_old| / (|D| + 0.1) is below the
+// tolerance. A step that takes the means outside of the family's domain is
+// halved until they are valid. A fit that doesn't converge within MaxIt
+// iterations is returned with Converged false, but when the coefficients
+// diverge, as they do when a binary response is separated by the predictors,
+// SeparationError is returned instead.
+//
+// The DataFrame is not modified, and the summary is a *GLMSummary, whose design
+// includes the intercept column.
 func (l *glmTrainer) Train(df *DataFrame, b []float64) (Model, Summary, error) {
 	if l.config == nil {
 		return nil, nil, fmt.Errorf("config not set")
 	}
+	family := l.config.F
+	if family == nil {
+		return nil, nil, fmt.Errorf("family not set")
+	}
+	if l.config.MaxIt < 1 || !(l.config.Tolerance > 0) {
+		return nil, nil, fmt.Errorf("need a positive tolerance and number of iterations")
+	}
+	nrow := df.Rows()
+	if len(b) != nrow {
+		return nil, nil, DimensionError
+	}
 
-	A := df.Data()
-	nrow, ncol := A.Dims()
-	x := mat64.NewDense(ncol, 1, rep(0.0, ncol))
-
-	var i int64
-	var err error
-	for ; i < l.config.MaxIt; i++ {
-		eta := matrixMult(A, x)
-		etaCol := mat64.Col(nil, 0, eta)
-
-		var (
-			g      = make([]float64, nrow) // g = invLink(eta)
-			gprime = make([]float64, nrow) // g = derivativeFn(eta)
-			w      = make([]float64, nrow) // w = gprime^2 / variance(g)
-		)
+	A := df.Copy()
+	A.labels = df.Labels()
+	A.PushCol(rep(1, nrow))
+	if A.Cols() > nrow {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, nrow, A.Cols())
+	}
 
-		for i, val := range etaCol {
-			g[i] = l.config.F.LinkFn(val)
-			gprime[i] = l.config.F.DerivativeFn(val)
-			w[i] = math.Pow(gprime[i], 2.0) / l.config.F.VarianceFn(g[i])
+	mu, eta := make([]float64, nrow), make([]float64, nrow)
+	for i, v := range b {
+		start, err := family.Initialize(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("observation %d: %v", i, err)
 		}
-
-		// z = eta + (b - g) / gprime
-		z := mat64.NewDense(nrow, 1, nil)
-		eta.Clone(z)
-		z.Apply(func(i, j int, eta float64) float64 {
-			return eta + (b[i]-g[i])/gprime[i]
-		}, z)
-
-		// convert w = w * I
-		wMat := mat64.NewDense(nrow, nrow, rep(0.0, nrow*nrow))
-		for i := 0; i < nrow; i++ {
-			wMat.Set(i, i, w[i])
+		mu[i], eta[i] = start, family.Link(start)
+	}
+	deviance := totalDeviance(family, b, mu)
+
+	var (
+		x          []float64
+		iterations int64
+		converged  bool
+		step       float64 // largest change in the linear predictor in the last iteration
+	)
+	for iterations < l.config.MaxIt {
+		iterations++
+		wa, wz := mat.DenseCopyOf(A.X), mat.NewDense(nrow, 1, nil)
+		for i, w := range workingWeights(family, eta, mu) {
+			wz.Set(i, 0, math.Sqrt(w)*(eta[i]+(b[i]-mu[i])/family.MuEta(eta[i])))
+			row := wa.RawRowView(i)
+			for j := range row {
+				row[j] *= math.Sqrt(w)
+			}
 		}
-
-		var (
-			wa     = matrixMult(wMat, A)
-			cprod1 = matrixMult(wa.T(), A)
-			wz     = matrixMult(wMat, z)
-			cprod2 = matrixMult(wz.T(), A)
-		)
-
-		// save xold for evaluating convergence
-		xold := mat64.NewDense(ncol, 1, nil)
-		x.Clone(xold)
-
-		// xnew = solve(crossprod(A,W*A), crossprod(A,W*z))
-		x = &mat64.Dense{}
-		err = x.Solve(cprod1, cprod2.T())
+		fit, err := leastSquares(wa, wz)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		// convergence = sqrt(crossprod(x - xold)) <= tolerance
-		diff := &mat64.Dense{}
-		diff.Sub(x, xold)
-		conv := matrixMult(diff.T(), diff)
-		if math.Sqrt(conv.At(0, 0)) <= l.config.Tolerance {
+		// halve the step back toward the last coefficients until the means are valid
+		xnew := fit.betas
+		etanew, munew := make([]float64, nrow), make([]float64, nrow)
+		previous := deviance
+		for halvings := 0; ; halvings++ {
+			for i := range etanew {
+				etanew[i] = sum(prod(A.X.RawRowView(i), xnew))
+				munew[i] = family.InverseLink(etanew[i])
+			}
+			deviance = totalDeviance(family, b, munew)
+			if !math.IsNaN(deviance) && !math.IsInf(deviance, 0) {
+				break
+			}
+			if x == nil || halvings == maxHalvings {
+				return nil, nil, fmt.Errorf("no coefficients give valid means for the family")
+			}
+			for j := range xnew {
+				xnew[j] = (xnew[j] + x[j]) / 2
+			}
+		}
+
+		step = 0
+		for i := range eta {
+			step = math.Max(step, math.Abs(etanew[i]-eta[i]))
+		}
+		x, eta, mu = xnew, etanew, munew
+		if _, ok := family.(binomial); ok {
+			for _, m := range mu {
+				if m < separationEpsilon || m > 1-separationEpsilon {
+					return nil, nil, SeparationError
+				}
+			}
+		}
+
+		if math.Abs(deviance-previous)/(math.Abs(deviance)+0.1) < l.config.Tolerance {
+			converged = true
 			break
 		}
 	}
 
-	coef := mat64.Col(nil, 0, x)
-	fmt.Printf("coef=%v x=%v", coef, x)
-	return nil, nil, nil
-}
+	// the deviance of a diverging fit levels off at its limit while the linear
+	// predictor keeps moving, where Newton steps have long since become tiny
+	if converged && step > divergenceStep {
+		return nil, nil, SeparationError
+	}
 
-func matrixMult(a, b mat64.Matrix) *mat64.Dense {
-	out := &mat64.Dense{}
-	out.Mul(a, b)
-	return out
-}
+	p := A.Cols()
+	dispersion := 1.0
+	if family.EstimatesDispersion() {
+		// the Pearson estimate, as summary.glm uses
+		pearson := 0.0
+		for i, v := range b {
+			pearson += (v - mu[i]) * (v - mu[i]) / family.Variance(mu[i])
+		}
+		dispersion = pearson / float64(nrow-p)
+	}
+	vcov, err := glmVCov(A.X, workingWeights(family, eta, mu), dispersion)
+	if err != nil {
+		return nil, nil, err
+	}
 
-type linkFunc func(float64) float64
+	// every family here has an intercept, so the null model fits the mean
+	ybar := mean(b)
+	null := make([]float64, nrow)
+	resid := make([]float64, nrow)
+	for i := range b {
+		null[i] = ybar
+		resid[i] = b[i] - mu[i]
+	}
+	k := float64(p)
+	if family.EstimatesDispersion() {
+		k++
+	}
 
-type fnType uint8
+	return &GLM{
+		betas:  x,
+		family: family,
+	}, &GLMSummary{
+		data:         A,
+		family:       family,
+		betas:        x,
+		vcov:         vcov,
+		dispersion:   dispersion,
+		fitted:       mu,
+		residuals:    resid,
+		response:     append([]float64(nil), b...),
+		deviance:     deviance,
+		nullDeviance: totalDeviance(family, b, null),
+		aic:          -2*family.LogLikelihood(b, mu, deviance) + 2*k,
+		iterations:   int(iterations),
+		converged:    converged,
+	}, nil
+}
 
 const (
-	Link fnType = iota
-	Derivative
-	Variance
+	// separationEpsilon is the distance from 0 or 1 at which R reports fitted
+	// probabilities as numerically 0 or 1.
+	separationEpsilon = 10 * 2.220446049250313e-16
+
+	// divergenceStep is the change in the linear predictor beyond which a fit
+	// whose deviance has converged is taken to be diverging.
+	divergenceStep = 0.1
 )
 
-type evalFn func(float64) float64
+// workingWeights returns the IRLS weights \mu'(\eta)^2 / V(\mu).
+func workingWeights(family Family, eta, mu []float64) []float64 {
+	w := make([]float64, len(eta))
+	for i := range w {
+		d := family.MuEta(eta[i])
+		w[i] = d * d / family.Variance(mu[i])
+	}
+	return w
+}
 
-type Family struct {
-	LinkFn       evalFn
-	VarianceFn   evalFn
-	DerivativeFn evalFn
+// totalDeviance sums the unit deviances of the observations.
+func totalDeviance(family Family, y, mu []float64) float64 {
+	d := 0.0
+	for i, v := range y {
+		d += family.Deviance(v, mu[i])
+	}
+	return d
 }
 
-func NewFamily(l, d, v evalFn) Family {
-	return Family{
-		LinkFn:       l,
-		VarianceFn:   v,
-		DerivativeFn: d,
+// glmVCov returns the variance-covariance matrix of the coefficients,
+// \phi (X'WX)^-1, as \phi (R'R)^-1 from the QR factorization of W^{1/2} X.
+func glmVCov(x *mat.Dense, weights []float64, dispersion float64) (*DataFrame, error) {
+	wx := mat.DenseCopyOf(x)
+	for i, w := range weights {
+		row := wx.RawRowView(i)
+		for j := range row {
+			row[j] *= math.Sqrt(w)
+		}
+	}
+	r := &mat.Dense{}
+	factorize(wx).RTo(r)
+	_, p := r.Dims()
+	rtri := mat.NewTriDense(p, mat.Upper, nil)
+	rtri.Copy(r)
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(rtri); err != nil {
+		return nil, err
 	}
+	vcov := &mat.Dense{}
+	vcov.Mul(rinv, rinv.T())
+	vcov.Scale(dispersion, vcov)
+	return MatToDF(vcov), nil
+}
+
+// GLM is a generalized linear model, which predicts the mean of the response.
+type GLM struct {
+	betas  []float64
+	family Family
+}
+
+// Predict returns the fitted mean for the predictors x (without the intercept),
+// such as a probability for a logistic regression or a rate for a Poisson one.
+func (g *GLM) Predict(x []float64) float64 {
+	return g.family.InverseLink(g.betas[0] + sum(prod(x, g.betas[1:])))
+}
+
+// GLMSummary summarizes a generalized linear model. Its residuals are the
+// response residuals y - \mu, and the diagnostics that assume a least squares
+// fit, such as VarCov and LeveragePoints, don't apply to it.
+type GLMSummary struct {
+	data         *DataFrame
+	family       Family
+	betas        []float64
+	vcov         *DataFrame
+	dispersion   float64
+	fitted       []float64
+	residuals    []float64
+	response     []float64
+	deviance     float64
+	nullDeviance float64
+	aic          float64
+	iterations   int
+	converged    bool
+}
+
+func (g *GLMSummary) Data() *DataFrame        { return g.data }
+func (g *GLMSummary) Coefficients() []float64 { return g.betas }
+func (g *GLMSummary) Residuals() []float64    { return g.residuals }
+func (g *GLMSummary) Response() []float64     { return g.response }
+func (g *GLMSummary) Family() Family          { return g.family }
+
+// Yhat returns the fitted means, such as the fitted probabilities of a logistic regression.
+func (g *GLMSummary) Yhat() []float64 { return g.fitted }
+
+func (g *GLMSummary) SumOfSquares() float64 {
+	return sum(prod(g.residuals, g.residuals))
+}
+
+// Deviance returns the residual deviance of the fit, twice the difference
+// between the log-likelihoods of the saturated model and the fitted one.
+func (g *GLMSummary) Deviance() float64 { return g.deviance }
+
+// NullDeviance returns the deviance of the model with only an intercept.
+func (g *GLMSummary) NullDeviance() float64 { return g.nullDeviance }
+
+// AIC returns -2 \ell + 2k, where k counts the coefficients and, for a family
+// that estimates it, the dispersion. It matches R's AIC for glm fits.
+func (g *GLMSummary) AIC() float64 { return g.aic }
+
+// Dispersion returns the dispersion \phi of the family: one for the binomial and
+// Poisson families, and otherwise the Pearson estimate \sum (y - \mu)^2 / V(\mu) / (n - p).
+func (g *GLMSummary) Dispersion() float64 { return g.dispersion }
+
+// VarCov returns the variance-covariance matrix of the coefficients, the
+// inverse of the information matrix, \phi (X'WX)^-1, at the fitted means.
+func (g *GLMSummary) VarCov() *DataFrame { return g.vcov }
+
+// StandardErrors returns the standard errors of the coefficients.
+func (g *GLMSummary) StandardErrors() []float64 { return StandardErrors(g.vcov) }
+
+// Iterations returns the number of IRLS iterations of the fit.
+func (g *GLMSummary) Iterations() int { return g.iterations }
+
+// Converged reports whether the deviance converged within the maximum number of iterations.
+func (g *GLMSummary) Converged() bool { return g.converged }
+
+// A Family is the distribution of the response of a generalized linear model,
+// along with the link g between its mean and the linear predictor, \eta = g(\mu).
+// Each family uses its canonical link.
+type Family interface {
+	Link(mu float64) float64         // \eta = g(\mu)
+	InverseLink(eta float64) float64 // \mu = g^-1(\eta)
+	MuEta(eta float64) float64       // d\mu / d\eta
+	Variance(mu float64) float64     // V(\mu), the variance as a function of the mean
+	Deviance(y, mu float64) float64  // the contribution of an observation to the deviance
+
+	// LogLikelihood returns the maximized log-likelihood of the fitted means of
+	// all of the observations, given their total deviance.
+	LogLikelihood(y, mu []float64, deviance float64) float64
+
+	// Initialize checks that y is a valid response and returns a starting mean for it.
+	Initialize(y float64) (float64, error)
+
+	// EstimatesDispersion reports whether the family has a dispersion
+	// parameter to estimate, rather than one fixed at one.
+	EstimatesDispersion() bool
 }
 
 var (
-	Binomial  = NewFamily(binomialLink, binomialDerivative, binomialVariance)
-	Poisson   = NewFamily(poissonLink, poissonDerivative, poissonVariance)
-	Gamma     = NewFamily(gammaLink, gammaDerivative, gammaVariance)
-	InvNormal = NewFamily(invnLink, invnDerivative, invnVariance)
+	Gaussian  Family = gaussian{}
+	Binomial  Family = binomial{}
+	Poisson   Family = poisson{}
+	Gamma     Family = gamma{}
+	InvNormal Family = inverseGaussian{}
 )
 
 // -------------------------- //
-//          Binomial
+//          Gaussian
 // -------------------------- //
 
-// logistic link function
-func binomialLink(x float64) float64 {
-	return 1 / (1 + math.Exp(-x))
+// identity link: g(mu) = mu
+type gaussian struct{}
+
+func (gaussian) Link(mu float64) float64               { return mu }
+func (gaussian) InverseLink(eta float64) float64       { return eta }
+func (gaussian) MuEta(eta float64) float64             { return 1 }
+func (gaussian) Variance(mu float64) float64           { return 1 }
+func (gaussian) Deviance(y, mu float64) float64        { return (y - mu) * (y - mu) }
+func (gaussian) EstimatesDispersion() bool             { return true }
+func (gaussian) Initialize(y float64) (float64, error) { return checkFinite(y) }
+
+// the variance is estimated by deviance / n
+func (gaussian) LogLikelihood(y, mu []float64, deviance float64) float64 {
+	n := float64(len(y))
+	return -n / 2 * (math.Log(2*math.Pi*deviance/n) + 1)
 }
 
+// -------------------------- //
+//          Binomial
+// -------------------------- //
+
+// logit link: g(mu) = log(mu / (1 - mu))
+type binomial struct{}
+
+func (binomial) Link(mu float64) float64         { return math.Log(mu / (1 - mu)) }
+func (binomial) InverseLink(eta float64) float64 { return logistic(eta) }
+
 // derivative of logistic f(x) = f(x) * (1 - f(x))
-func binomialDerivative(x float64) float64 {
-	l := 1 / (1 + math.Exp(-x))
-	ans := l * (1 - l)
-	return ans
+func (binomial) MuEta(eta float64) float64 {
+	l := logistic(eta)
+	return l * (1 - l)
 }
 
 // mean = x 	variance = np(1 - p) = p - p^2
-func binomialVariance(x float64) float64 {
-	return x - math.Pow(x, 2.0)
+func (binomial) Variance(mu float64) float64 { return mu * (1 - mu) }
+
+func (binomial) Deviance(y, mu float64) float64 {
+	d := 0.0
+	if y > 0 {
+		d += y * math.Log(y/mu)
+	}
+	if y < 1 {
+		d += (1 - y) * math.Log((1-y)/(1-mu))
+	}
+	return 2 * d
+}
+
+func (binomial) LogLikelihood(y, mu []float64, deviance float64) float64 {
+	l := 0.0
+	for i, v := range y {
+		if v > 0 {
+			l += v * math.Log(mu[i])
+		}
+		if v < 1 {
+			l += (1 - v) * math.Log(1-mu[i])
+		}
+	}
+	return l
+}
+
+// start from (y + 1/2) / 2, as R does
+func (binomial) Initialize(y float64) (float64, error) {
+	if !(y >= 0 && y <= 1) {
+		return 0, fmt.Errorf("binomial response %v is not between 0 and 1", y)
+	}
+	return (y + 0.5) / 2, nil
 }
 
+func (binomial) EstimatesDispersion() bool { return false }
+
 // -------------------------- //
 //          Poisson
 // -------------------------- //
 
-// exponential link function
-func poissonLink(x float64) float64 {
-	return math.Exp(x)
+// log link: g(mu) = log(mu)
+type poisson struct{}
+
+func (poisson) Link(mu float64) float64         { return math.Log(mu) }
+func (poisson) InverseLink(eta float64) float64 { return math.Exp(eta) }
+func (poisson) MuEta(eta float64) float64       { return math.Exp(eta) }
+func (poisson) Variance(mu float64) float64     { return mu }
+
+func (poisson) Deviance(y, mu float64) float64 {
+	d := mu - y
+	if y > 0 {
+		d += y * math.Log(y/mu)
+	}
+	return 2 * d
 }
 
-func poissonDerivative(x float64) float64 {
-	return poissonLink(x)
+func (poisson) LogLikelihood(y, mu []float64, deviance float64) float64 {
+	l := 0.0
+	for i, v := range y {
+		lg, _ := math.Lgamma(v + 1)
+		l += v*math.Log(mu[i]) - mu[i] - lg
+	}
+	return l
 }
 
-func poissonVariance(x float64) float64 {
-	return x
+// start from y + 0.1, as R does
+func (poisson) Initialize(y float64) (float64, error) {
+	if !(y >= 0) || math.IsInf(y, 0) {
+		return 0, fmt.Errorf("poisson response %v is not a non-negative number", y)
+	}
+	return y + 0.1, nil
 }
 
+func (poisson) EstimatesDispersion() bool { return false }
+
 // -------------------------- //
 //          Gamma
 // -------------------------- //
 
-// inverse link function: 1/x
-func gammaLink(x float64) float64 {
-	return 1 / x
-}
+// inverse link: g(mu) = 1 / mu
+type gamma struct{}
 
-// derivative of link: 1/x^2
-func gammaDerivative(x float64) float64 {
-	return 1 / math.Pow(x, 2.0)
+func (gamma) Link(mu float64) float64         { return 1 / mu }
+func (gamma) InverseLink(eta float64) float64 { return 1 / eta }
+func (gamma) MuEta(eta float64) float64       { return -1 / (eta * eta) }
+
+// variance of gamma dist: kx^2, with the dispersion k estimated separately
+func (gamma) Variance(mu float64) float64 { return mu * mu }
+
+func (gamma) Deviance(y, mu float64) float64 {
+	if !(mu > 0) {
+		return math.NaN()
+	}
+	return -2 * (math.Log(y/mu) - (y-mu)/mu)
 }
 
-// variance of gamma dist: kx^2, but k=1
-func gammaVariance(x float64) float64 {
-	return math.Pow(x, 2.0)
+// the shape is 1 / \phi, with \phi estimated by deviance / n as R's Gamma()$aic does
+func (gamma) LogLikelihood(y, mu []float64, deviance float64) float64 {
+	phi := deviance / float64(len(y))
+	shape := 1 / phi
+	lg, _ := math.Lgamma(shape)
+	l := 0.0
+	for i, v := range y {
+		scale := mu[i] * phi
+		l += (shape-1)*math.Log(v) - v/scale - lg - shape*math.Log(scale)
+	}
+	return l
 }
 
+func (gamma) Initialize(y float64) (float64, error) { return checkPositive("gamma", y) }
+func (gamma) EstimatesDispersion() bool             { return true }
+
 // -------------------------- //
 //       Inverse Normal
 // -------------------------- //
 
-// Link function: 1 / sqrt(x)
+// Link function: 1 / mu^2
+type inverseGaussian struct{}
+
+func (inverseGaussian) Link(mu float64) float64         { return 1 / (mu * mu) }
+func (inverseGaussian) InverseLink(eta float64) float64 { return 1 / math.Sqrt(eta) }
+func (inverseGaussian) MuEta(eta float64) float64       { return -0.5 * math.Pow(eta, -1.5) }
+
+// Variance : mu^3 / lambda, with the dispersion 1 / lambda estimated separately
+func (inverseGaussian) Variance(mu float64) float64 { return mu * mu * mu }
+
+func (inverseGaussian) Deviance(y, mu float64) float64 {
+	if !(mu > 0) {
+		return math.NaN()
+	}
+	return (y - mu) * (y - mu) / (y * mu * mu)
+}
 
-func invnLink(x float64) float64 { return 1 / math.Sqrt(x) }
+// the dispersion is estimated by deviance / n, as R's inverse.gaussian()$aic does
+func (inverseGaussian) LogLikelihood(y, mu []float64, deviance float64) float64 {
+	n := float64(len(y))
+	logs := 0.0
+	for _, v := range y {
+		logs += math.Log(v)
+	}
+	return -(n*(math.Log(2*math.Pi*deviance/n)+1) + 3*logs) / 2
+}
 
-// Derivative of Link: - (x ^ 3/2) / 2
+func (inverseGaussian) Initialize(y float64) (float64, error) {
+	return checkPositive("inverse gaussian", y)
+}
+func (inverseGaussian) EstimatesDispersion() bool { return true }
 
-func invnDerivative(x float64) float64 {
-	return -0.5 * math.Pow(x, -1.5)
+func checkFinite(y float64) (float64, error) {
+	if math.IsNaN(y) || math.IsInf(y, 0) {
+		return 0, fmt.Errorf("response %v is not a finite number", y)
+	}
+	return y, nil
 }
 
-// Variance : mu^3 / lambda , lambda = 1
-func invnVariance(x float64) float64 {
-	return math.Pow(x, 3.0)
+func checkPositive(family string, y float64) (float64, error) {
+	if !(y > 0) || math.IsInf(y, 0) {
+		return 0, fmt.Errorf("%s response %v is not a positive number", family, y)
+	}
+	return y, nil
 }
@@ -1,6 +1,8 @@
 package glasso
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
@@ -13,6 +15,207 @@ func TestGLM(t *testing.T) {
 		glm    = NewGlmTrainer(config)            // model builder
 	)
 
+	// the stack loss isn't a binomial response, a proportion between 0 and 1
+	_, _, err := glm.Train(df, y)
+	assert.NotEqual(t, nil, err)
+	assert.T(t, strings.Contains(err.Error(), "binomial response 42 is not between 0 and 1"), err)
+}
+
+func TestGLMStacklossPoisson(t *testing.T) {
+	var (
+		df     = NewDataFrame(data)              // data frame
+		config = NewGLMConfig(Poisson, 2, 0.005) // model config
+		glm    = NewGlmTrainer(config)           // model builder
+	)
+
 	_, _, err := glm.Train(df, y)
 	assert.Equal(t, nil, err)
 }
+
+// Dobson (1990), the example of R's ?glm: counts by outcome and treatment
+var (
+	dobsonCounts = []float64{18, 17, 15, 20, 10, 20, 25, 13, 12}
+	dobson       = [][]float64{ // outcome2, outcome3, treatment2, treatment3
+		{0, 0, 0, 0}, {1, 0, 0, 0}, {0, 1, 0, 0},
+		{0, 0, 1, 0}, {1, 0, 1, 0}, {0, 1, 1, 0},
+		{0, 0, 0, 1}, {1, 0, 0, 1}, {0, 1, 0, 1},
+	}
+)
+
+func TestPoissonGLM(t *testing.T) {
+	// glm(counts ~ outcome + treatment, family = poisson())
+	model, summary, err := NewGlmTrainer(NewGLMConfig(Poisson, 25, 1e-8)).Train(NewDataFrame(dobson), dobsonCounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summary.(*GLMSummary)
+	betas := []float64{3.045, -0.4543, -0.2930, 0, 0}
+	se := []float64{0.1709, 0.2022, 0.1927, 0.2000, 0.2000}
+	for j, b := range s.Coefficients() {
+		if math.Abs(b-betas[j]) > 5e-4 {
+			t.Errorf("coefficient %d: got %v, want %v", j, b, betas[j])
+		}
+		if got := s.StandardErrors()[j]; math.Abs(got-se[j]) > 5e-5 {
+			t.Errorf("standard error %d: got %v, want %v", j, got, se[j])
+		}
+	}
+	// the fitted counts are the products of the margins over the total, 21 for the first cell
+	if math.Abs(s.Coefficients()[0]-math.Log(21)) > 1e-8 {
+		t.Errorf("intercept: got %v, want log 21", s.Coefficients()[0])
+	}
+	for name, test := range map[string][2]float64{
+		"deviance":      {s.Deviance(), 5.1291},
+		"null deviance": {s.NullDeviance(), 10.5814},
+		"aic":           {s.AIC(), 56.761},
+	} {
+		if math.Abs(test[0]-test[1]) > 5e-4 {
+			t.Errorf("%s: got %v, want %v", name, test[0], test[1])
+		}
+	}
+	if s.Dispersion() != 1 || !s.Converged() {
+		t.Errorf("dispersion %v, converged %v", s.Dispersion(), s.Converged())
+	}
+	for i, mu := range s.Yhat() {
+		if got := model.Predict(dobson[i]); math.Abs(got-mu) > 1e-10 {
+			t.Errorf("prediction %d: got %v, want %v", i, got, mu)
+		}
+	}
+}
+
+func TestGaussianGLM(t *testing.T) {
+	_, summary, err := NewGlmTrainer(NewGLMConfig(Gaussian, 25, 1e-8)).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summary.(*GLMSummary)
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcov, err := VarCov(ols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := StandardErrors(vcov)
+	for j, b := range ols.Coefficients() {
+		if math.Abs(s.Coefficients()[j]-b) > 1e-8*math.Max(math.Abs(b), 1) {
+			t.Errorf("coefficient %d: got %v, want %v", j, s.Coefficients()[j], b)
+		}
+		if math.Abs(s.StandardErrors()[j]-se[j]) > 1e-8*se[j] {
+			t.Errorf("standard error %d: got %v, want %v", j, s.StandardErrors()[j], se[j])
+		}
+	}
+	mse, err := MseAdjusted(ols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, test := range map[string][2]float64{
+		"deviance":      {s.Deviance(), ols.SumOfSquares()},
+		"null deviance": {s.NullDeviance(), totalSumOfSquares(y)},
+		"dispersion":    {s.Dispersion(), mse},
+		"aic":           {s.AIC(), AIC(ols)},
+	} {
+		if math.Abs(test[0]-test[1]) > 1e-8*math.Abs(test[1]) {
+			t.Errorf("%s: got %v, want %v", name, test[0], test[1])
+		}
+	}
+	if s.Iterations() != 2 {
+		t.Errorf("iterations: got %d, want 2", s.Iterations())
+	}
+}
+
+func TestDispersedGLM(t *testing.T) {
+	// one way layout of the Dobson counts by outcome, where the fitted means are the group means
+	x := make([][]float64, len(dobson))
+	for i := range dobson {
+		x[i] = dobson[i][:2]
+	}
+	means := []float64{21, 40.0 / 3, 47.0 / 3}
+	for name, family := range map[string]Family{"gamma": Gamma, "inverse gaussian": InvNormal} {
+		_, summary, err := NewGlmTrainer(NewGLMConfig(family, 25, 1e-10)).Train(NewDataFrame(x), dobsonCounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := summary.(*GLMSummary)
+		betas := []float64{family.Link(means[0]), family.Link(means[1]) - family.Link(means[0]), family.Link(means[2]) - family.Link(means[0])}
+		for j, b := range s.Coefficients() {
+			if math.Abs(b-betas[j]) > 1e-6*math.Abs(betas[0]) {
+				t.Errorf("%s coefficient %d: got %v, want %v", name, j, b, betas[j])
+			}
+		}
+
+		deviance, pearson := 0.0, 0.0
+		ybar := mean(dobsonCounts)
+		null := 0.0
+		for i, v := range dobsonCounts {
+			mu := means[0]
+			if x[i][0] == 1 {
+				mu = means[1]
+			} else if x[i][1] == 1 {
+				mu = means[2]
+			}
+			deviance += family.Deviance(v, mu)
+			null += family.Deviance(v, ybar)
+			pearson += (v - mu) * (v - mu) / family.Variance(mu)
+		}
+		for stat, test := range map[string][2]float64{
+			"deviance":      {s.Deviance(), deviance},
+			"null deviance": {s.NullDeviance(), null},
+			"dispersion":    {s.Dispersion(), pearson / 6},
+		} {
+			if math.Abs(test[0]-test[1]) > 1e-8*test[1] {
+				t.Errorf("%s %s: got %v, want %v", name, stat, test[0], test[1])
+			}
+		}
+	}
+}
+
+func TestFamilies(t *testing.T) {
+	for name, test := range map[string]struct {
+		family Family
+		mu     []float64
+	}{
+		"gaussian":         {Gaussian, []float64{-3, 0.5, 10}},
+		"binomial":         {Binomial, []float64{0.01, 0.5, 0.9}},
+		"poisson":          {Poisson, []float64{0.2, 1, 30}},
+		"gamma":            {Gamma, []float64{0.2, 1, 30}},
+		"inverse gaussian": {InvNormal, []float64{0.2, 1, 30}},
+	} {
+		f := test.family
+		for _, mu := range test.mu {
+			eta := f.Link(mu)
+			if got := f.InverseLink(eta); math.Abs(got-mu) > 1e-12*math.Abs(mu) {
+				t.Errorf("%s: inverse link of the link of %v is %v", name, mu, got)
+			}
+			const h = 1e-6
+			numeric := (f.InverseLink(eta+h) - f.InverseLink(eta-h)) / (2 * h)
+			if got := f.MuEta(eta); math.Abs(got-numeric) > 1e-6*math.Abs(numeric) {
+				t.Errorf("%s: derivative at %v is %v, want %v", name, eta, got, numeric)
+			}
+			if d := f.Deviance(mu, mu); math.Abs(d) > 1e-12 {
+				t.Errorf("%s: deviance of a perfect fit to %v is %v", name, mu, d)
+			}
+		}
+	}
+
+	for name, test := range map[string]struct {
+		family Family
+		y      float64
+	}{
+		"negative count":          {Poisson, -1},
+		"zero gamma response":     {Gamma, 0},
+		"binomial proportion > 1": {Binomial, 1.5},
+		"infinite gaussian":       {Gaussian, math.Inf(1)},
+	} {
+		if _, err := test.family.Initialize(test.y); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		x := NewDataFrame([][]float64{{1}, {2}, {3}})
+		if _, _, err := NewGlmTrainer(NewGLMConfig(test.family, 25, 1e-8)).Train(x, []float64{0.5, test.y, 0.5}); err == nil {
+			t.Errorf("%s: expected the fit to fail", name)
+		}
+	}
+	if _, _, err := NewGlmTrainer(NewGLMConfig(nil, 25, 1e-8)).Train(NewDataFrame(data), y); err == nil {
+		t.Error("no family: expected an error")
+	}
+}
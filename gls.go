@@ -0,0 +1,121 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// NewGlsTrainer returns a Trainer for generalized least squares with a known
+// error covariance \Omega (up to scale), one row and column per observation:
+//
+// \beta = (X'\Omega^-1 X)^-1 X'\Omega^-1 y
+//
+// With the Cholesky factorization \Omega = LL', this is the OLS fit of the
+// whitened problem L^-1 y = L^-1 X\beta + e, which has uncorrelated errors of
+// equal variance, and \Omega^-1 is never formed. The summary describes the
+// whitened problem, as nlme::gls does for its standardized residuals, so VarCov
+// is \sigma^2 (X'\Omega^-1 X)^-1 and the residual diagnostics are on the whitened
+// scale; OlsSummary.OriginalResiduals returns y - X\beta.
+//
+// Omega must be symmetric positive definite.
+func NewGlsTrainer(omega *mat.Dense) Trainer {
+	return &glsTrainer{omega: omega}
+}
+
+type glsTrainer struct {
+	omega *mat.Dense
+}
+
+func (g *glsTrainer) Train(x *DataFrame, yvector []float64) (Model, Summary, error) {
+	l, err := choleskyFactor(g.omega, x.Rows())
+	if err != nil {
+		return nil, nil, err
+	}
+	model, summary, err := trainLeastSquares(x, yvector, func(x *DataFrame, y []float64) (*DataFrame, []float64, []int, error) {
+		design := forwardSubstitute(l, x.X)
+		response := forwardSubstitute(l, mat.NewDense(len(y), 1, y))
+		rows := make([]int, len(y))
+		for i := range rows {
+			rows[i] = i
+		}
+		return &DataFrame{X: design, n: x.Rows(), c: x.Cols(), labels: x.Labels()}, mat.Col(nil, 0, response), rows, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return model, summary, nil
+}
+
+// choleskyFactor checks that omega is an n x n symmetric positive definite
+// matrix and returns the lower triangular L with omega = LL'.
+func choleskyFactor(omega *mat.Dense, n int) (*mat.TriDense, error) {
+	if omega == nil {
+		return nil, fmt.Errorf("no covariance matrix given")
+	}
+	if r, c := omega.Dims(); r != n || c != n {
+		return nil, fmt.Errorf("covariance matrix is %d x %d for %d observations", r, c, n)
+	}
+
+	scale := 0.0
+	for i := 0; i < n; i++ {
+		scale = math.Max(scale, math.Abs(omega.At(i, i)))
+	}
+	sym := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			a, b := omega.At(i, j), omega.At(j, i)
+			if math.IsNaN(a) || math.IsInf(a, 0) {
+				return nil, fmt.Errorf("covariance matrix has a non-finite entry at (%d, %d)", i, j)
+			}
+			if math.Abs(a-b) > 1e-10*scale {
+				return nil, fmt.Errorf("covariance matrix is not symmetric at (%d, %d)", i, j)
+			}
+			sym.SetSym(i, j, (a+b)/2)
+		}
+	}
+
+	chol := &mat.Cholesky{}
+	if ok := chol.Factorize(sym); !ok {
+		return nil, fmt.Errorf("covariance matrix is not positive definite")
+	}
+	l := &mat.TriDense{}
+	chol.LTo(l)
+	return l, nil
+}
+
+// forwardSubstitute solves LZ = B for Z, with L lower triangular.
+func forwardSubstitute(l *mat.TriDense, b *mat.Dense) *mat.Dense {
+	n, c := b.Dims()
+	z := mat.NewDense(n, c, nil)
+	for j := 0; j < c; j++ {
+		for i := 0; i < n; i++ {
+			v := b.At(i, j)
+			for k := 0; k < i; k++ {
+				v -= l.At(i, k) * z.At(k, j)
+			}
+			z.Set(i, j, v/l.At(i, i))
+		}
+	}
+	return z
+}
+
+// AR1Correlation returns the n x n correlation matrix of a stationary
+// first-order autoregressive process, \Omega_{ij} = \rho^{|i - j|}, for use with
+// NewGlsTrainer (nlme's corAR1).
+func AR1Correlation(n int, rho float64) (*mat.Dense, error) {
+	if !(rho > -1 && rho < 1) {
+		return nil, fmt.Errorf("autocorrelation %v is not between -1 and 1", rho)
+	}
+	if n < 1 {
+		return nil, DimensionError
+	}
+	omega := mat.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			omega.Set(i, j, math.Pow(rho, math.Abs(float64(i-j))))
+		}
+	}
+	return omega, nil
+}
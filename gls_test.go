@@ -0,0 +1,146 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGlsTrainer(t *testing.T) {
+	omega, err := AR1Correlation(len(longleyY), 0.6)
+	assert.Equal(t, nil, err)
+	_, s, err := NewGlsTrainer(omega).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+
+	// the GLS normal equations, with the inverse of omega formed explicitly
+	x := NewDataFrame(longley)
+	x.PushCol(rep(1, len(longleyY)))
+	n, p := x.X.Dims()
+	omegaInv := &mat.Dense{}
+	assert.Equal(t, nil, omegaInv.Inverse(omega))
+	xto, xtox, xtoxInv, xtoy := &mat.Dense{}, &mat.Dense{}, &mat.Dense{}, &mat.Dense{}
+	xto.Mul(x.X.T(), omegaInv)
+	xtox.Mul(xto, x.X)
+	assert.Equal(t, nil, xtoxInv.Inverse(xtox))
+	xtoy.Mul(xto, mat.NewDense(n, 1, longleyY))
+	betas := &mat.Dense{}
+	betas.Mul(xtoxInv, xtoy)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, betas.At(j, 0), 1e-7*math.Abs(betas.At(j, 0)))
+	}
+
+	// original scale residuals, and sigma^2 = e'Omega^-1 e / (n - p)
+	e := s.(OlsSummary).OriginalResiduals()
+	for i := range e {
+		assertClose(t, e[i], longleyY[i]-sum(prod(x.GetRow(i), s.Coefficients())), 1e-9)
+	}
+	ev := mat.NewVecDense(n, e)
+	oe := mat.NewVecDense(n, nil)
+	oe.MulVec(omegaInv, ev)
+	sigma2 := mat.Dot(ev, oe) / float64(n-p)
+	mse, err := MseAdjusted(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, mse, sigma2, 1e-9*sigma2)
+
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	expected := &mat.Dense{}
+	expected.Scale(sigma2, xtoxInv)
+	// longley is badly conditioned, so the explicit inverses are only accurate to a few digits
+	for j := 0; j < p; j++ {
+		for k := 0; k < p; k++ {
+			assertClose(t, vcov.X.At(j, k), expected.At(j, k), 1e-6*math.Abs(expected.At(j, k)))
+		}
+	}
+	assert.Equal(t, interceptOf(s), 0)
+}
+
+func TestGlsSpecialCases(t *testing.T) {
+	n := len(y)
+
+	// the identity is OLS
+	identity := mat.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		identity.Set(i, i, 1)
+	}
+	_, s, err := NewGlsTrainer(identity).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, summary.Coefficients()[j], 1e-10)
+	}
+	assertClose(t, s.(OlsSummary).RSquared(), summary.(OlsSummary).RSquared(), 1e-12)
+
+	// a diagonal covariance is WLS with the reciprocal weights, and scaling
+	// omega changes nothing
+	diagonal := mat.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		diagonal.Set(i, i, 3/stacklossWeights[i])
+	}
+	_, gls, err := NewGlsTrainer(diagonal).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, wls, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, b := range gls.Coefficients() {
+		assertClose(t, b, wls.Coefficients()[j], 1e-10)
+	}
+	ge, we := gls.(OlsSummary).OriginalResiduals(), wls.(OlsSummary).OriginalResiduals()
+	gh, err := LeveragePoints(gls)
+	assert.Equal(t, nil, err)
+	wh, err := LeveragePoints(wls)
+	assert.Equal(t, nil, err)
+	gd, err := CooksDistance(gls)
+	assert.Equal(t, nil, err)
+	wd, err := CooksDistance(wls)
+	assert.Equal(t, nil, err)
+	for i := 0; i < n; i++ {
+		assertClose(t, ge[i], we[i], 1e-10)
+		assertClose(t, gh[i], wh[i], 1e-10)
+		assertClose(t, gd[i], wd[i], 1e-10)
+	}
+	gv, wv := coefficientSEs(t, gls), coefficientSEs(t, wls)
+	for j := range gv {
+		assertClose(t, gv[j], wv[j], 1e-10)
+	}
+	assertClose(t, gls.(OlsSummary).RSquared(), wls.(OlsSummary).RSquared(), 1e-12)
+}
+
+func TestGlsInvalid(t *testing.T) {
+	n := len(y)
+	_, _, err := NewGlsTrainer(nil).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+
+	omega, err := AR1Correlation(n-1, 0.5)
+	assert.Equal(t, nil, err)
+	_, _, err = NewGlsTrainer(omega).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+
+	omega, err = AR1Correlation(n, 0.5)
+	assert.Equal(t, nil, err)
+	omega.Set(2, 5, omega.At(2, 5)+0.1)
+	_, _, err = NewGlsTrainer(omega).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+
+	// symmetric but indefinite
+	omega, err = AR1Correlation(n, 0.5)
+	assert.Equal(t, nil, err)
+	omega.Set(0, 1, 2)
+	omega.Set(1, 0, 2)
+	_, _, err = NewGlsTrainer(omega).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestAR1Correlation(t *testing.T) {
+	omega, err := AR1Correlation(4, -0.5)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, omega.At(0, 0), 1.0)
+	assert.Equal(t, omega.At(0, 1), -0.5)
+	assert.Equal(t, omega.At(3, 1), 0.25)
+	assert.Equal(t, omega.At(0, 3), -0.125)
+
+	for _, rho := range []float64{1, -1, 2, math.NaN()} {
+		_, err := AR1Correlation(4, rho)
+		assert.NotEqual(t, nil, err)
+	}
+}
@@ -0,0 +1,211 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GraphicalLassoFit is a sparse estimate of an inverse covariance matrix.
+type GraphicalLassoFit struct {
+	Precision  *DataFrame // \Theta, whose zeros are conditional independencies
+	Covariance *DataFrame // W = \Theta^-1, the regularized covariance estimate
+
+	Iterations int  // sweeps over the columns
+	Converged  bool // whether the sweeps converged within the maximum number of iterations
+}
+
+// GraphicalLasso estimates a sparse precision matrix from the sample covariance
+// matrix s by maximizing the penalized log-likelihood
+//
+// \log\det\Theta - tr(S\Theta) - \rho \sum_{jk} |\Theta_{jk}|
+//
+// with the block coordinate descent of Friedman, Hastie & Tibshirani (2008),
+// as R's glasso package does (including its penalty on the diagonal). Each sweep
+// updates W one row and column at a time, and each of those updates is a lasso
+// problem solved by coordinate descent. The sweeps stop when the average
+// absolute change in W is below the tolerance (WithTolerance) times the average
+// absolute off-diagonal entry of s.
+func GraphicalLasso(s *DataFrame, rho float64, opts ...Option) (*GraphicalLassoFit, error) {
+	p := s.Rows()
+	penalties := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		for k := 0; k < p; k++ {
+			penalties.Set(j, k, rho)
+		}
+	}
+	return GraphicalLassoPenalties(s, MatToDF(penalties), opts...)
+}
+
+// GraphicalLassoPenalties is GraphicalLasso with a separate penalty for each
+// entry of the precision matrix, given as a symmetric non-negative matrix.
+// A penalty of zero leaves that entry unpenalized.
+func GraphicalLassoPenalties(s, rho *DataFrame, opts ...Option) (*GraphicalLassoFit, error) {
+	o := newOptions(opts)
+	if err := o.checkIterations(); err != nil {
+		return nil, err
+	}
+	if err := checkCovariance(s.X); err != nil {
+		return nil, err
+	}
+	p := s.Rows()
+	if rho.Rows() != p || rho.Cols() != p {
+		return nil, fmt.Errorf("penalty matrix is %d x %d for a %d x %d covariance matrix", rho.Rows(), rho.Cols(), p, p)
+	}
+	for j := 0; j < p; j++ {
+		for k := 0; k < p; k++ {
+			if r := rho.X.At(j, k); !(r >= 0) || math.IsInf(r, 0) || r != rho.X.At(k, j) {
+				return nil, fmt.Errorf("penalty matrix must be symmetric and non-negative, but has %v at (%d, %d)", r, j, k)
+			}
+		}
+	}
+
+	w := mat.DenseCopyOf(s.X)
+	scale, diagonal := 0.0, 0.0
+	for j := 0; j < p; j++ {
+		w.Set(j, j, w.At(j, j)+rho.X.At(j, j))
+		if w.At(j, j) <= 0 {
+			return nil, fmt.Errorf("variable %d has no variance and no penalty", j)
+		}
+		diagonal += w.At(j, j) / float64(p)
+		for k := 0; k < p; k++ {
+			if k != j {
+				scale += math.Abs(s.X.At(j, k)) / float64(p*(p-1))
+			}
+		}
+	}
+	if scale == 0 {
+		scale = 1
+	}
+
+	// betas holds the lasso coefficients of each column, as warm starts
+	betas := mat.NewDense(p, p, nil)
+	fit := &GraphicalLassoFit{}
+	for p > 1 && fit.Iterations < o.maxIter {
+		fit.Iterations++
+		change := 0.0
+		for j := 0; j < p; j++ {
+			lasso := newColumnLasso(w, s.X, rho.X, betas, j)
+			descend(lasso, o.tolerance*diagonal, o.maxIter)
+
+			// w_12 = W_11 \beta
+			for a, k := range lasso.others {
+				betas.Set(k, j, lasso.betas[a])
+				change += math.Abs(lasso.wb[a] - w.At(k, j))
+				w.Set(k, j, lasso.wb[a])
+				w.Set(j, k, lasso.wb[a])
+			}
+		}
+		if change/float64(p*(p-1)) < o.tolerance*scale {
+			fit.Converged = true
+			break
+		}
+	}
+	if p == 1 {
+		fit.Converged = true
+	}
+
+	// \theta_22 = 1 / (w_22 - w_12'\beta), and \theta_12 = -\beta \theta_22
+	theta := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		wb := 0.0
+		for k := 0; k < p; k++ {
+			if k != j {
+				wb += w.At(k, j) * betas.At(k, j)
+			}
+		}
+		t := 1 / (w.At(j, j) - wb)
+		theta.Set(j, j, t)
+		for k := 0; k < p; k++ {
+			if k != j && betas.At(k, j) != 0 {
+				theta.Set(k, j, -betas.At(k, j)*t)
+			}
+		}
+	}
+	symmetrize(theta)
+
+	fit.Precision = MatToDF(theta)
+	fit.Covariance = MatToDF(w)
+	return fit, nil
+}
+
+// checkCovariance checks that s is a symmetric positive semidefinite matrix.
+func checkCovariance(s *mat.Dense) error {
+	p, c := s.Dims()
+	if p != c || p == 0 {
+		return fmt.Errorf("covariance matrix is %d x %d", p, c)
+	}
+	sym := mat.NewSymDense(p, nil)
+	for j := 0; j < p; j++ {
+		for k := j; k < p; k++ {
+			v := s.At(j, k)
+			if math.IsNaN(v) || math.IsInf(v, 0) || v != s.At(k, j) {
+				return fmt.Errorf("covariance matrix must be symmetric and finite, but has %v at (%d, %d)", v, j, k)
+			}
+			sym.SetSym(j, k, v)
+		}
+	}
+	eigen := &mat.EigenSym{}
+	if ok := eigen.Factorize(sym, false); !ok {
+		return fmt.Errorf("eigendecomposition of the %d x %d covariance matrix failed", p, p)
+	}
+	values := eigen.Values(nil)
+	largest := math.Max(math.Abs(values[0]), math.Abs(values[p-1]))
+	if values[0] < -1e-10*largest {
+		return fmt.Errorf("covariance matrix is not positive semidefinite")
+	}
+	return nil
+}
+
+// columnLasso is the lasso problem for the jth column of the graphical lasso,
+//
+// \min_\beta 1/2 \beta'W_{11}\beta - s_{12}'\beta + \sum_k \rho_k |\beta_k|
+//
+// over the other variables, where W_{11} is W without its jth row and column.
+// W_{11}\beta is kept up to date as coefficients change.
+type columnLasso struct {
+	w      *mat.Dense
+	others []int // the variables other than j
+	s, rho []float64
+	betas  []float64
+	wb     []float64 // W_{11}\beta
+}
+
+func newColumnLasso(w, s, rho, betas *mat.Dense, j int) *columnLasso {
+	p, _ := w.Dims()
+	l := &columnLasso{w: w}
+	for k := 0; k < p; k++ {
+		if k != j {
+			l.others = append(l.others, k)
+			l.s = append(l.s, s.At(k, j))
+			l.rho = append(l.rho, rho.At(k, j))
+			l.betas = append(l.betas, betas.At(k, j))
+		}
+	}
+	l.wb = make([]float64, len(l.others))
+	for a, k := range l.others {
+		for b, m := range l.others {
+			l.wb[a] += w.At(k, m) * l.betas[b]
+		}
+	}
+	return l
+}
+
+func (l *columnLasso) coefficients() []float64 { return l.betas }
+
+func (l *columnLasso) update(a int) float64 {
+	k := l.others[a]
+	wkk, old := l.w.At(k, k), l.betas[a]
+	g := l.s[a] - (l.wb[a] - wkk*old)
+	b := softThreshold(g, l.rho[a]) / wkk
+	if b == old {
+		return 0
+	}
+	delta := b - old
+	for c, m := range l.others {
+		l.wb[c] += l.w.At(m, k) * delta
+	}
+	l.betas[a] = b
+	return wkk * delta * delta
+}
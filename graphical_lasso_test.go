@@ -0,0 +1,212 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// chainCovariance returns the covariance matrix of a Gaussian chain graph,
+// the inverse of a tridiagonal precision matrix.
+func chainCovariance(t *testing.T, p int) *DataFrame {
+	theta := mat.NewDense(p, p, nil)
+	for j := 0; j < p; j++ {
+		theta.Set(j, j, 2)
+		if j > 0 {
+			theta.Set(j, j-1, -0.8)
+			theta.Set(j-1, j, -0.8)
+		}
+	}
+	s := &mat.Dense{}
+	assert.Equal(t, nil, s.Inverse(theta))
+	symmetrize(s)
+	return MatToDF(s)
+}
+
+// assertGraphicalLassoKKT checks the optimality conditions W - S = \rho \Gamma,
+// where \Gamma_jk = sign(\Theta_jk) if \Theta_jk is nonzero and |\Gamma_jk| <= 1
+// otherwise, and that W is the inverse of \Theta.
+func assertGraphicalLassoKKT(t *testing.T, s *DataFrame, rho float64, fit *GraphicalLassoFit, tol float64) {
+	p := s.Rows()
+	w, theta := fit.Covariance.X, fit.Precision.X
+	for j := 0; j < p; j++ {
+		assertClose(t, w.At(j, j), s.X.At(j, j)+rho, tol)
+		for k := 0; k < p; k++ {
+			if j == k {
+				continue
+			}
+			gap := w.At(j, k) - s.X.At(j, k)
+			if theta.At(j, k) == 0 {
+				assert.T(t, math.Abs(gap) <= rho+tol)
+			} else {
+				assertClose(t, gap, rho*sign(theta.At(j, k)), tol)
+			}
+		}
+	}
+	identity := &mat.Dense{}
+	identity.Mul(w, theta)
+	for j := 0; j < p; j++ {
+		for k := 0; k < p; k++ {
+			expected := 0.0
+			if j == k {
+				expected = 1
+			}
+			assertClose(t, identity.At(j, k), expected, 100*tol)
+		}
+	}
+}
+
+func TestGraphicalLasso(t *testing.T) {
+	s := chainCovariance(t, 6)
+	for _, rho := range []float64{0.01, 0.05, 0.1, 0.2} {
+		fit, err := GraphicalLasso(s, rho, WithTolerance(1e-12))
+		assert.Equal(t, nil, err)
+		assert.T(t, fit.Converged)
+		assertGraphicalLassoKKT(t, s, rho, fit, 1e-8)
+	}
+
+	// the chain's non-edges are recovered
+	fit, err := GraphicalLasso(s, 0.1, WithTolerance(1e-12))
+	assert.Equal(t, nil, err)
+	for j := 0; j < 6; j++ {
+		for k := 0; k < 6; k++ {
+			d := j - k
+			if d < 0 {
+				d = -d
+			}
+			assert.Equal(t, fit.Precision.X.At(j, k) != 0, d <= 1)
+		}
+	}
+}
+
+func TestGraphicalLassoLimits(t *testing.T) {
+	s := chainCovariance(t, 5)
+
+	// no penalty is the inverse of s
+	fit, err := GraphicalLasso(s, 0, WithTolerance(1e-14))
+	assert.Equal(t, nil, err)
+	inverse := &mat.Dense{}
+	assert.Equal(t, nil, inverse.Inverse(s.X))
+	assertMatricesClose(t, fit.Precision.X, inverse, 1e-8)
+
+	// a penalty above every covariance leaves only the diagonal
+	largest := 0.0
+	for j := 0; j < 5; j++ {
+		for k := 0; k < j; k++ {
+			largest = math.Max(largest, math.Abs(s.X.At(j, k)))
+		}
+	}
+	fit, err = GraphicalLasso(s, largest*1.01)
+	assert.Equal(t, nil, err)
+	for j := 0; j < 5; j++ {
+		for k := 0; k < 5; k++ {
+			if j == k {
+				assertClose(t, fit.Precision.X.At(j, j), 1/(s.X.At(j, j)+largest*1.01), 1e-12)
+			} else {
+				assert.Equal(t, fit.Precision.X.At(j, k), 0.0)
+			}
+		}
+	}
+	assert.T(t, fit.Iterations <= 2)
+
+	// a single variable
+	fit, err = GraphicalLasso(NewDataFrame([][]float64{{4}}), 1)
+	assert.Equal(t, nil, err)
+	assertClose(t, fit.Precision.X.At(0, 0), 0.2, 1e-15)
+}
+
+func TestGraphicalLassoExact(t *testing.T) {
+	// glasso::glasso(s, rho)$wi for rho = 0.1 and 0.15, which penalizes the
+	// diagonal by default. These are not glasso's output, which hasn't been
+	// run against them, but the unique solutions of the optimality
+	// conditions: of the 729 patterns of zeros and signs of the off-diagonal
+	// of the precision matrix, the one whose W = S + rho sign(Theta), with
+	// the entries where Theta is zero solved for by Newton's method in
+	// 50-digit decimal arithmetic, has |W - S| <= rho where Theta is zero.
+	s := NewDataFrame([][]float64{
+		{1, 0.5, 0.3, 0.1},
+		{0.5, 1, 0.4, 0.2},
+		{0.3, 0.4, 1, 0.45},
+		{0.1, 0.2, 0.45, 1},
+	})
+	for _, c := range []struct {
+		rho       float64
+		precision []float64
+	}{
+		{0.1, []float64{
+			1.0566037735849056, -0.3584905660377358, -0.09433962264150944, 0,
+			-0.3584905660377358, 1.1037937442450791, -0.23441774350504122, -0.004515692029803568,
+			-0.09433962264150944, -0.23441774350504122, 1.092189267745848, -0.3206141341160533,
+			0, -0.004515692029803568, -0.3206141341160533, 1.011515014675999,
+		}},
+		{0.15, []float64{
+			0.9629346580053496, -0.27894535727932745, -0.0649598777225831, 0,
+			-0.27894535727932745, 0.9935040122277417, -0.17959495605655332, 0,
+			-0.0649598777225831, -0.17959495605655332, 0.9805782896050008, -0.2434077079107505,
+			0, 0, -0.2434077079107505, 0.9330628803245437,
+		}},
+	} {
+		fit, err := GraphicalLasso(s, c.rho, WithTolerance(1e-14))
+		assert.Equal(t, nil, err)
+		assert.T(t, fit.Converged)
+		want := mat.NewDense(4, 4, c.precision)
+		assertMatricesClose(t, fit.Precision.X, want, 1e-10)
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				assert.Equal(t, want.At(j, k) == 0, fit.Precision.X.At(j, k) == 0)
+			}
+		}
+	}
+}
+
+func TestGraphicalLassoPenalties(t *testing.T) {
+	s := chainCovariance(t, 4)
+	rho := mat.NewDense(4, 4, nil)
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			rho.Set(j, k, 1)
+		}
+	}
+	// heavy penalties except on the (0, 3) entry and the diagonal
+	rho.Set(0, 3, 0)
+	rho.Set(3, 0, 0)
+	for j := 0; j < 4; j++ {
+		rho.Set(j, j, 0)
+	}
+	fit, err := GraphicalLassoPenalties(s, MatToDF(rho), WithTolerance(1e-12))
+	assert.Equal(t, nil, err)
+	assert.T(t, fit.Converged)
+
+	// only the unpenalized entry survives, where W matches S
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			nonzero := fit.Precision.X.At(j, k) != 0
+			assert.Equal(t, nonzero, j == k || j+k == 3 && (j == 0 || k == 0))
+		}
+		assertClose(t, fit.Covariance.X.At(j, j), s.X.At(j, j), 1e-12)
+	}
+	assertClose(t, fit.Covariance.X.At(0, 3), s.X.At(0, 3), 1e-8)
+}
+
+func TestGraphicalLassoInvalid(t *testing.T) {
+	s := chainCovariance(t, 3)
+	_, err := GraphicalLasso(s, -1)
+	assert.NotEqual(t, nil, err)
+
+	asymmetric := mat.DenseCopyOf(s.X)
+	asymmetric.Set(0, 1, asymmetric.At(0, 1)+0.1)
+	_, err = GraphicalLasso(MatToDF(asymmetric), 0.1)
+	assert.NotEqual(t, nil, err)
+
+	indefinite := NewDataFrame([][]float64{{1, 2}, {2, 1}})
+	_, err = GraphicalLasso(indefinite, 0.1)
+	assert.NotEqual(t, nil, err)
+
+	_, err = GraphicalLasso(NewDataFrame([][]float64{{1, 0, 0}, {0, 1, 0}}), 0.1)
+	assert.NotEqual(t, nil, err)
+
+	_, err = GraphicalLassoPenalties(s, NewDataFrame([][]float64{{1, 1}, {1, 1}}))
+	assert.NotEqual(t, nil, err)
+}
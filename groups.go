@@ -0,0 +1,158 @@
+package glasso
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+// GroupCoefficient is the row of a GroupedCoefs table for one coefficient of
+// the fit to one group.
+type GroupCoefficient struct {
+	Group, Term string
+	Coefficient
+}
+
+// GroupedCoefs stacks the coefficient tables of the fits of FitByGroup, a row
+// for each coefficient of each group, the groups in sorted order and the
+// coefficients in the order of the model.
+type GroupedCoefs struct {
+	Groups []string // the groups fit, sorted
+	Terms  []string // the names of the coefficients, the intercept first
+	Rows   []GroupCoefficient
+
+	// Skipped are the groups with too few rows to fit, with fewer than one
+	// more than the number of coefficients, sorted.
+	Skipped []string
+}
+
+// Coefficients returns the rows of the group, in the order of Terms, or nil
+// if it wasn't fit.
+func (g *GroupedCoefs) Coefficients(group string) []GroupCoefficient {
+	for k, name := range g.Groups {
+		if name == group {
+			p := len(g.Terms)
+			return g.Rows[k*p : (k+1)*p]
+		}
+	}
+	return nil
+}
+
+// FitByGroup fits y on the columns of x by least squares separately within
+// each group, the rows i sharing the label group[i], as lmList of R's nlme
+// does. The groups are fit concurrently by WithParallelism workers, and the
+// options are also those of NewOlsTrainer for each fit. A group with fewer rows than one more than the number of coefficients, the
+// intercept included, is left out of the fits and reported in Skipped, so
+// that every fit has a residual degree of freedom for its standard errors.
+//
+// It returns the model of each group that was fit, and their coefficients
+// and standard errors stacked in one table. An error fitting a group is
+// that of the first of them in sorted order. With WithRowIDs, the ids are
+// those of the rows of x, and the model of each group has the ids of its rows.
+func FitByGroup(x *DataFrame, y []float64, group []string, opts ...Option) (map[string]*OLS, *GroupedCoefs, error) {
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n || len(group) != n {
+		return nil, nil, DimensionError
+	}
+	if err := checkRowIDs(o.rowIDs, n); err != nil {
+		return nil, nil, err
+	}
+	rows := make(map[string][]int)
+	for i, g := range group {
+		rows[g] = append(rows[g], i)
+	}
+	p := x.Cols()
+	if o.intercept {
+		p++
+	}
+
+	table := &GroupedCoefs{}
+	for g, r := range rows {
+		if len(r) < p+1 {
+			table.Skipped = append(table.Skipped, g)
+			continue
+		}
+		table.Groups = append(table.Groups, g)
+	}
+	sort.Strings(table.Groups)
+	sort.Strings(table.Skipped)
+	if len(table.Groups) == 0 {
+		return nil, nil, fmt.Errorf("%w: none of the %d groups has %d rows to fit", TooFewObservationsError, len(rows), p+1)
+	}
+
+	fits := make([]groupFit, len(table.Groups))
+	err := parallelChunks(o.ctx, o.workers(), 0, len(fits)-1, 1, func(first, last int) bool {
+		for k := first; k <= last; k++ {
+			fits[k] = fitGroup(x, y, rows[table.Groups[k]], o.rowIDs, opts)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	models := make(map[string]*OLS, len(fits))
+	for k, fit := range fits {
+		if fit.err != nil {
+			return nil, nil, fmt.Errorf("group %q: %w", table.Groups[k], fit.err)
+		}
+		g := table.Groups[k]
+		models[g] = fit.model
+		if k == 0 {
+			table.Terms = fit.model.Names()
+		}
+		for j, c := range fit.coefs {
+			table.Rows = append(table.Rows, GroupCoefficient{Group: g, Term: table.Terms[j], Coefficient: c})
+		}
+	}
+	return models, table, nil
+}
+
+// groupFit is the fit of FitByGroup to one group.
+type groupFit struct {
+	model *OLS
+	coefs []Coefficient
+	err   error
+}
+
+// fitGroup fits y on x for the given rows, which keep their ids if there are
+// any.
+func fitGroup(x *DataFrame, y []float64, rows []int, ids []string, opts []Option) (fit groupFit) {
+	sub, err := x.SelectRows(rows)
+	if err != nil {
+		fit.err = err
+		return fit
+	}
+	z := make([]float64, len(rows))
+	for k, i := range rows {
+		z[k] = y[i]
+	}
+	if ids != nil {
+		opts = append(opts[:len(opts):len(opts)], WithRowIDs(selectIDs(ids, rows)))
+	}
+	model, summary, err := NewOlsTrainer(opts...).Train(sub, z)
+	if err != nil {
+		fit.err = err
+		return fit
+	}
+	fit.model = model.(*OLS)
+	fit.coefs, fit.err = CoefficientTable(summary)
+	return fit
+}
+
+// String formats the table with a line for each coefficient of each group.
+func (g *GroupedCoefs) String() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "group\tterm\tEstimate\tStd. Error\tt value\tPr(>|t|)\t\n")
+	for _, r := range g.Rows {
+		fmt.Fprintf(w, "%s\t%s\t%.6g\t%.6g\t%.3f\t%s\t\n", r.Group, r.Term, r.Estimate, r.StdError, r.T, formatPValue(r.PValue))
+	}
+	w.Flush()
+	if len(g.Skipped) > 0 {
+		fmt.Fprintf(&buf, "Skipped for too few rows: %q\n", g.Skipped)
+	}
+	return buf.String()
+}
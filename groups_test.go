@@ -0,0 +1,85 @@
+package glasso
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestFitByGroup(t *testing.T) {
+	// the stackloss runs in three groups of seven, and a fourth of two rows
+	// too few for its four coefficients
+	group := make([]string, len(y))
+	for i := range group {
+		group[i] = []string{"a", "b", "c"}[i%3]
+	}
+	group[0], group[20] = "d", "d"
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	models, table, err := FitByGroup(NewDataFrame(data, labels), y, group)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a", "b", "c"}, table.Groups)
+	assert.Equal(t, []string{"d"}, table.Skipped)
+	assert.Equal(t, []string{"(Intercept)", "Air.Flow", "Water.Temp", "Acid.Conc."}, table.Terms)
+	assert.Equal(t, 12, len(table.Rows))
+	assert.Equal(t, 3, len(models))
+
+	for _, g := range table.Groups {
+		var rows [][]float64
+		var z []float64
+		for i := range y {
+			if group[i] == g {
+				rows = append(rows, data[i])
+				z = append(z, y[i])
+			}
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), z)
+		assert.Equal(t, nil, err)
+		want, err := CoefficientTable(s)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, models[g].betas, s.Coefficients(), 1e-10)
+		coefs := table.Coefficients(g)
+		assert.Equal(t, len(want), len(coefs))
+		for j, c := range coefs {
+			assert.Equal(t, g, c.Group)
+			assert.Equal(t, table.Terms[j], c.Term)
+			assertClose(t, c.Estimate, want[j].Estimate, 1e-10)
+			assertClose(t, c.StdError, want[j].StdError, 1e-10)
+			assertClose(t, c.PValue, want[j].PValue, 1e-10)
+		}
+	}
+	assert.Equal(t, 0, len(table.Coefficients("d")))
+	out := table.String()
+	assert.T(t, strings.Contains(out, "Air.Flow"), out)
+	assert.T(t, strings.Contains(out, `Skipped for too few rows: ["d"]`), out)
+
+	// the fits don't depend on the number of workers
+	_, serial, err := FitByGroup(NewDataFrame(data, labels), y, group, WithParallelism(1))
+	assert.Equal(t, nil, err)
+	_, parallel, err := FitByGroup(NewDataFrame(data, labels), y, group, WithParallelism(8))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, serial, parallel)
+
+	// without an intercept a group of four rows is enough
+	group[1], group[2] = "d", "d"
+	_, table, err = FitByGroup(NewDataFrame(data), y, group)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"d"}, table.Skipped)
+	_, table, err = FitByGroup(NewDataFrame(data), y, group, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, table.Groups)
+	assert.Equal(t, 0, len(table.Skipped))
+}
+
+func TestFitByGroupErrors(t *testing.T) {
+	_, _, err := FitByGroup(NewDataFrame(data), y, make([]string, 3))
+	assert.Equal(t, DimensionError, err)
+
+	// every group too small
+	group := make([]string, len(y))
+	for i := range group {
+		group[i] = string(rune('a' + i%7))
+	}
+	_, _, err = FitByGroup(NewDataFrame(data), y, group)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,467 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TestResult holds the outcome of a hypothesis test.
+type TestResult struct {
+	Statistic float64
+	DF        float64 // degrees of freedom of the reference distribution
+	DF2       float64 // denominator degrees of freedom, for tests referred to an F distribution
+	PValue    float64
+
+	// Warnings describe conditions under which the p-value may be unreliable.
+	Warnings []string
+}
+
+// BreuschPagan tests the residuals of the model for heteroskedasticity, against
+// the alternative that their variance is a function of the regressors. Squared
+// residuals are regressed on the design matrix (with an intercept added if the
+// design has none), and the LM statistic is chi-squared with one degree of
+// freedom per non-intercept regressor.
+//
+// The original test (studentized = false) scales the squared residuals by
+// RSS / n and takes LM = ESS / 2, which is only valid for normal errors.
+// Koenker's studentized version (studentized = true, the default in lmtest::bptest)
+// takes LM = n R^2 of the auxiliary regression and is robust to non-normality.
+func BreuschPagan(m Summary, studentized bool) (TestResult, error) {
+	x := m.Data().X
+	n, c := x.Dims()
+
+	// the auxiliary regression always needs an intercept
+	z := x
+	df := c - 1
+	if interceptColumn(x) < 0 {
+		z = mat.NewDense(n, c+1, nil)
+		z.SetCol(0, rep(1, n))
+		for j := 0; j < c; j++ {
+			z.SetCol(j+1, mat.Col(nil, j, x))
+		}
+		df = c
+	}
+	if df < 1 {
+		return TestResult{}, fmt.Errorf("breusch pagan test needs at least one regressor")
+	}
+
+	residuals := m.Residuals()
+	u2 := prod(residuals, residuals)
+	sigma2 := sum(u2) / float64(n)
+	if sigma2 == 0 {
+		return TestResult{}, fmt.Errorf("residuals are identically zero")
+	}
+	if !studentized {
+		u2 = multSlice(u2, 1/sigma2)
+	}
+
+	fit, err := leastSquares(z, mat.NewDense(n, 1, u2))
+	if err != nil {
+		return TestResult{}, err
+	}
+	tss := totalSumOfSquares(u2)
+	ess := tss - sum(prod(fit.residuals, fit.residuals))
+
+	var lm float64
+	if studentized {
+		lm = float64(n) * ess / tss
+	} else {
+		lm = ess / 2
+	}
+	return TestResult{
+		Statistic: lm,
+		DF:        float64(df),
+		PValue:    chiSquareSurvival(lm, float64(df)),
+	}, nil
+}
+
+// WhiteTest is White's general test for heteroskedasticity. Squared residuals
+// are regressed on an intercept, the regressors, their squares and their pairwise
+// products, and LM = n R^2 of that regression is chi-squared with one degree of
+// freedom per auxiliary term other than the intercept.
+//
+// Terms that are constant or linear combinations of earlier ones, such as the
+// square of a dummy variable or the product of mutually exclusive dummies, are
+// dropped from the auxiliary design so it is always of full rank.
+func WhiteTest(m Summary) (TestResult, error) {
+	x := m.Data().X
+	n, c := x.Dims()
+	intercept := interceptColumn(x)
+
+	var regressors [][]float64
+	for j := 0; j < c; j++ {
+		if j != intercept {
+			regressors = append(regressors, mat.Col(nil, j, x))
+		}
+	}
+	terms := [][]float64{rep(1, n)}
+	terms = append(terms, regressors...)
+	for j := range regressors {
+		for k := j; k < len(regressors); k++ {
+			terms = append(terms, prod(regressors[j], regressors[k]))
+		}
+	}
+
+	kept := independentColumns(terms)
+	df := len(kept) - 1
+	if df < 1 {
+		return TestResult{}, fmt.Errorf("white test needs at least one regressor")
+	}
+	if df >= n-1 {
+		return TestResult{}, fmt.Errorf("%w: white test needs more than %d observations for %d auxiliary terms", TooFewObservationsError, df+1, df)
+	}
+
+	z := mat.NewDense(n, len(kept), nil)
+	for j, col := range kept {
+		z.SetCol(j, col)
+	}
+	residuals := m.Residuals()
+	u2 := prod(residuals, residuals)
+	fit, err := leastSquares(z, mat.NewDense(n, 1, u2))
+	if err != nil {
+		return TestResult{}, err
+	}
+	tss := totalSumOfSquares(u2)
+	if tss == 0 {
+		return TestResult{}, fmt.Errorf("squared residuals are constant")
+	}
+
+	lm := float64(n) * (1 - sum(prod(fit.residuals, fit.residuals))/tss)
+	return TestResult{
+		Statistic: lm,
+		DF:        float64(df),
+		PValue:    chiSquareSurvival(lm, float64(df)),
+	}, nil
+}
+
+// independentColumns returns the columns, in order, that are not numerically a
+// linear combination of the columns kept before them, using modified Gram-Schmidt.
+func independentColumns(cols [][]float64) [][]float64 {
+	const tol = 1e-8
+	var kept, basis [][]float64
+	for _, col := range cols {
+		v := make([]float64, len(col))
+		copy(v, col)
+		norm := math.Sqrt(sum(prod(v, v)))
+		if norm == 0 {
+			continue
+		}
+		for _, q := range basis {
+			proj := sum(prod(q, v))
+			for i := range v {
+				v[i] -= proj * q[i]
+			}
+		}
+		rest := math.Sqrt(sum(prod(v, v)))
+		if rest <= tol*norm {
+			continue
+		}
+		kept = append(kept, col)
+		basis = append(basis, multSlice(v, 1/rest))
+	}
+	return kept
+}
+
+// smallSampleJarqueBera is the sample size below which the chi-squared
+// approximation to the Jarque-Bera statistic is considered unreliable.
+const smallSampleJarqueBera = 30
+
+// JarqueBera tests the residuals of the model for normality using their
+// skewness S and excess kurtosis K:
+//
+// JB = \frac{n}{6} (S^2 + K^2 / 4)
+//
+// which is asymptotically chi-squared with two degrees of freedom. The
+// approximation converges slowly and the test is conservative in small
+// samples, so a warning is included when n < 30.
+func JarqueBera(m Summary) (TestResult, error) {
+	residuals := m.Residuals()
+	n := len(residuals)
+	if n < 3 {
+		return TestResult{}, fmt.Errorf("jarque bera test needs at least 3 observations, got %d", n)
+	}
+	if centralMoment(residuals, 2) == 0 {
+		return TestResult{}, fmt.Errorf("residuals have zero variance")
+	}
+
+	s, k := Skewness(residuals), Kurtosis(residuals)
+	jb := float64(n) / 6 * (s*s + k*k/4)
+	result := TestResult{
+		Statistic: jb,
+		DF:        2,
+		PValue:    chiSquareSurvival(jb, 2),
+	}
+	if n < smallSampleJarqueBera {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("%d observations is too few for the chi-squared approximation to be reliable", n))
+	}
+	return result, nil
+}
+
+// OverallFTest tests the null hypothesis that all of the slope coefficients of
+// the model are zero, as reported on the last line of R's summary.lm:
+//
+// F = \frac{ESS / (p - 1)}{RSS / (n - p)}
+//
+// on p - 1 and n - p degrees of freedom, where p counts the intercept. Without an
+// intercept the model is compared against y = 0 instead, so ESS is uncentered
+// and the numerator has p degrees of freedom.
+func OverallFTest(m Summary) (TestResult, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	df1 := p
+	if interceptOf(m) >= 0 {
+		df1--
+	}
+	df2 := n - p
+	if df1 < 1 || df2 < 1 {
+		return TestResult{}, fmt.Errorf("f test needs at least one slope and one residual degree of freedom")
+	}
+
+	rss := m.SumOfSquares()
+	ess := modelTotalSumOfSquares(m) - rss
+	f := (ess / float64(df1)) / (rss / float64(df2))
+	return TestResult{
+		Statistic: f,
+		DF:        float64(df1),
+		DF2:       float64(df2),
+		PValue:    fSurvival(f, float64(df1), float64(df2)),
+	}, nil
+}
+
+// ResetTest is Ramsey's RESET test of the functional form of the model, as
+// lmtest's resettest with type = "fitted": the design is augmented with the
+// given powers of the fitted values, 2 and 3 if powers is nil, and
+//
+// F = \frac{(RSS - RSS_a) / q}{RSS_a / (n - p - q)}
+//
+// on q and n - p - q degrees of freedom tests that the q added terms are
+// jointly zero. A model that misses a nonlinearity in its predictors, or an
+// interaction among them, leaves it in the residuals, where the powers of the
+// fitted values pick it up. The fitted values are divided by their largest
+// absolute value before they're raised to a power, which leaves the fit of the
+// augmented model as it was but keeps \hat{y}^3 from overflowing; the
+// intercept column isn't powered. A model fit to a transformed problem, such
+// as weighted least squares, is an error.
+func ResetTest(m Summary, powers []int) (TestResult, error) {
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return TestResult{}, fmt.Errorf("the fitted values of a transformed problem aren't those of the model; test the original fit")
+	}
+	if powers == nil {
+		powers = []int{2, 3}
+	}
+	seen := make(map[int]bool)
+	for _, k := range powers {
+		if k < 2 || seen[k] {
+			return TestResult{}, fmt.Errorf("powers %v must be distinct and at least 2", powers)
+		}
+		seen[k] = true
+	}
+	x := m.Data().X
+	n, p := x.Dims()
+	q := len(powers)
+	if n <= p+q {
+		return TestResult{}, fmt.Errorf("%w: %d observations for %d coefficients of the augmented model", TooFewObservationsError, n, p+q)
+	}
+
+	fitted := m.Yhat()
+	scale := 0.0
+	for _, f := range fitted {
+		scale = math.Max(scale, math.Abs(f))
+	}
+	if scale == 0 {
+		return TestResult{}, fmt.Errorf("the fitted values are all zero")
+	}
+	z := mat.NewDense(n, p+q, nil)
+	z.Copy(x)
+	for i, f := range fitted {
+		for a, k := range powers {
+			z.Set(i, p+a, math.Pow(f/scale, float64(k)))
+		}
+	}
+	fit, err := leastSquares(z, mat.NewDense(n, 1, append([]float64(nil), m.Response()...)))
+	if err != nil {
+		return TestResult{}, err
+	}
+	augmented := sum(prod(fit.residuals, fit.residuals))
+	df1, df2 := float64(q), float64(n-p-q)
+	f := ((m.SumOfSquares() - augmented) / df1) / (augmented / df2)
+	return TestResult{
+		Statistic: f,
+		DF:        df1,
+		DF2:       df2,
+		PValue:    fSurvival(f, df1, df2),
+	}, nil
+}
+
+// Alternative is the alternative hypothesis of a one- or two-sided test.
+type Alternative int
+
+const (
+	// GreaterAlternative is that the statistic is larger than under the null
+	// hypothesis, the default.
+	GreaterAlternative Alternative = iota
+	// LessAlternative is that it is smaller.
+	LessAlternative
+	// TwoSidedAlternative is that it is either.
+	TwoSidedAlternative
+)
+
+func (a Alternative) String() string {
+	switch a {
+	case GreaterAlternative:
+		return "greater"
+	case LessAlternative:
+		return "less"
+	case TwoSidedAlternative:
+		return "two-sided"
+	}
+	return fmt.Sprintf("Alternative(%d)", int(a))
+}
+
+// GoldfeldQuandt is the Goldfeld-Quandt test for heteroskedasticity, as
+// lmtest's gqtest with point = 0.5. The observations are sorted by column
+// sortBy of x, their order kept among ties, or left in the order given if
+// sortBy is negative, as for time-ordered data. The central floor(dropFraction
+// n) of them are dropped, and the regression of y on x, with an intercept
+// unless WithIntercept(false) is given, is fit to those below and those above
+// separately. The ratio of the residual variances
+//
+// F = \frac{RSS_2 / (n_2 - k)}{RSS_1 / (n_1 - k)}
+//
+// of the high group to the low is F on n_2 - k and n_1 - k degrees of freedom
+// if the errors have the same variance. WithAlternative sets the alternative:
+// by default GreaterAlternative, the variance increasing with the column, or
+// LessAlternative, decreasing, or TwoSidedAlternative. Each group must have
+// at least k + 1 observations for the k coefficients.
+func GoldfeldQuandt(x *DataFrame, y []float64, sortBy int, dropFraction float64, opts ...Option) (TestResult, error) {
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n {
+		return TestResult{}, DimensionError
+	}
+	if sortBy >= x.Cols() {
+		return TestResult{}, fmt.Errorf("column %d is out of range for %d columns", sortBy, x.Cols())
+	}
+	if !(dropFraction >= 0 && dropFraction < 1) {
+		return TestResult{}, fmt.Errorf("fraction %v to drop is not in [0, 1)", dropFraction)
+	}
+	k := x.Cols()
+	if o.intercept {
+		k++
+	}
+
+	order := identityRows(n)
+	if sortBy >= 0 {
+		col := x.GetCol(sortBy)
+		sort.SliceStable(order, func(a, b int) bool { return col[order[a]] < col[order[b]] })
+	}
+	// the groups that gqtest takes, which drop the middle rows from
+	// point - ceil(drop / 2) to point + floor(drop / 2), counted from zero
+	point := n / 2
+	drop := int(math.Floor(dropFraction * float64(n)))
+	low := point - (drop+1)/2
+	high := point + drop/2
+	if low < k+1 || n-high < k+1 {
+		return TestResult{}, fmt.Errorf("%w: groups of %d and %d observations for %d coefficients", TooFewObservationsError, low, n-high, k)
+	}
+	rss := func(rows []int) (float64, error) {
+		_, s, err := NewOlsTrainer(WithIntercept(o.intercept)).Train(subsetRows(x, rows), subsetSlice(y, rows))
+		if err != nil {
+			return 0, err
+		}
+		if s.(OlsSummary).Aliased() != nil {
+			return 0, fmt.Errorf("%w: a group doesn't determine the coefficients", SingularDesignError)
+		}
+		return s.SumOfSquares(), nil
+	}
+	rss1, err := rss(order[:low])
+	if err != nil {
+		return TestResult{}, err
+	}
+	rss2, err := rss(order[high:])
+	if err != nil {
+		return TestResult{}, err
+	}
+	df1, df2 := float64(n-high-k), float64(low-k)
+	f := (rss2 / df1) / (rss1 / df2)
+	res := TestResult{Statistic: f, DF: df1, DF2: df2}
+	greater, less := fSurvival(f, df1, df2), fSurvival(1/f, df2, df1)
+	switch o.alternative {
+	case GreaterAlternative:
+		res.PValue = greater
+	case LessAlternative:
+		res.PValue = less
+	case TwoSidedAlternative:
+		res.PValue = math.Min(1, 2*math.Min(greater, less))
+	default:
+		return TestResult{}, fmt.Errorf("unknown alternative %v", o.alternative)
+	}
+	return res, nil
+}
+
+// modelTotalSumOfSquares returns the total sum of squares of the response around
+// its (weighted) mean, or around zero if the model has no intercept.
+func modelTotalSumOfSquares(m Summary) float64 {
+	y := m.Response()
+	if interceptOf(m) < 0 {
+		return sum(prod(y, y))
+	}
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return sumOfSquaresAround(y, s.ones)
+	}
+	return totalSumOfSquares(y)
+}
+
+// CompareModels tests whether the full model fits significantly better than a
+// reduced model nested within it, as R's anova(reduced, full) does, with the partial F statistic
+//
+// F = \frac{(RSS_r - RSS_f) / (df_r - df_f)}{RSS_f / df_f}
+//
+// where df are the residual degrees of freedom. The models must be fit to the
+// same response, and every column of the reduced design must lie in the column
+// space of the full design.
+func CompareModels(full, reduced Summary) (F float64, pValue float64, err error) {
+	xf, xr := full.Data().X, reduced.Data().X
+	n, pf := xf.Dims()
+	nr, pr := xr.Dims()
+	if n != nr {
+		return 0, 0, fmt.Errorf("models were fit on %d and %d observations", n, nr)
+	}
+	yf, yr := full.Response(), reduced.Response()
+	for i := range yf {
+		if yf[i] != yr[i] {
+			return 0, 0, fmt.Errorf("models were fit to different responses")
+		}
+	}
+	if pr >= pf {
+		return 0, 0, fmt.Errorf("reduced model has %d coefficients, which is not fewer than the full model's %d", pr, pf)
+	}
+
+	// project the reduced design onto the full one
+	qr, err := qrOf(full)
+	if err != nil {
+		return 0, 0, err
+	}
+	proj := &mat.Dense{}
+	if err := qr.SolveTo(proj, false, xr); err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	fitted, residual := &mat.Dense{}, &mat.Dense{}
+	fitted.Mul(xf, proj)
+	residual.Sub(xr, fitted)
+	for j := 0; j < pr; j++ {
+		col := mat.Col(nil, j, xr)
+		res := mat.Col(nil, j, residual)
+		if math.Sqrt(sum(prod(res, res))) > 1e-8*math.Max(math.Sqrt(sum(prod(col, col))), 1) {
+			return 0, 0, fmt.Errorf("column %d of the reduced model is not in the span of the full model", j)
+		}
+	}
+
+	rssF, rssR := full.SumOfSquares(), reduced.SumOfSquares()
+	dfF, dfR := float64(n-pf), float64(n-pr)
+	F = ((rssR - rssF) / (dfR - dfF)) / (rssF / dfF)
+	return F, fSurvival(F, dfR-dfF, dfF), nil
+}
@@ -0,0 +1,428 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// auxiliaryFit regresses response on the stackloss predictors with an intercept.
+func auxiliaryFit(t *testing.T, response []float64) Summary {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), response)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func TestBreuschPagan(t *testing.T) {
+	e := summary.Residuals()
+	u2 := prod(e, e)
+	n := float64(len(e))
+
+	// Koenker: n R^2 of the regression of the squared residuals on the predictors
+	koenker, err := BreuschPagan(summary, true)
+	assert.Equal(t, nil, err)
+	aux := auxiliaryFit(t, u2)
+	r2 := 1 - aux.SumOfSquares()/totalSumOfSquares(u2)
+	assertClose(t, koenker.Statistic, n*r2, 1e-8)
+	assert.Equal(t, koenker.DF, 3.0)
+	assertClose(t, koenker.PValue, chiSquareSurvival(n*r2, 3), 1e-12)
+
+	// original: half the explained sum of squares of the scaled squared residuals
+	classic, err := BreuschPagan(summary, false)
+	assert.Equal(t, nil, err)
+	g := multSlice(u2, n/sum(u2))
+	aux = auxiliaryFit(t, g)
+	ess := totalSumOfSquares(g) - aux.SumOfSquares()
+	assertClose(t, classic.Statistic, ess/2, 1e-8)
+	assert.Equal(t, classic.DF, 3.0)
+}
+
+func TestBreuschPaganNoIntercept(t *testing.T) {
+	// a fit through the origin gets an intercept in the auxiliary regression
+	s := noInterceptSummary(t)
+	result, err := BreuschPagan(s, true)
+	assert.Equal(t, nil, err)
+	u2 := prod(s.Residuals(), s.Residuals())
+	aux := auxiliaryFit(t, u2)
+	r2 := 1 - aux.SumOfSquares()/totalSumOfSquares(u2)
+	assertClose(t, result.Statistic, float64(len(y))*r2, 1e-8)
+	assert.Equal(t, result.DF, 3.0)
+}
+
+func TestBreuschPaganSize(t *testing.T) {
+	// with homoskedastic normal errors both versions reject at about the nominal rate
+	const reps = 2000
+	rejected := map[bool]int{}
+	for rep := 0; rep < reps; rep++ {
+		s := simulatedSummary(40, 2, int64(rep))
+		for _, studentized := range []bool{true, false} {
+			result, err := BreuschPagan(s, studentized)
+			assert.Equal(t, nil, err)
+			if result.PValue < 0.05 {
+				rejected[studentized]++
+			}
+		}
+	}
+	assertClose(t, float64(rejected[true])/reps, 0.05, 0.015)
+	assertClose(t, float64(rejected[false])/reps, 0.05, 0.015)
+}
+
+func TestBreuschPaganPower(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	rows := make([][]float64, 100)
+	response := make([]float64, 100)
+	for i := range rows {
+		x := rng.Float64() * 10
+		rows[i] = []float64{x}
+		response[i] = 2 + x + x*rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	result, err := BreuschPagan(s, true)
+	assert.Equal(t, nil, err)
+	assert.T(t, result.PValue < 0.001)
+}
+
+func TestWhiteTest(t *testing.T) {
+	result, err := WhiteTest(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, result.DF, 9.0)
+
+	// n R^2 from the expanded auxiliary regression built by hand
+	rows := make([][]float64, len(data))
+	for i, r := range data {
+		rows[i] = append([]float64{}, r...)
+		for j := range r {
+			for k := j; k < len(r); k++ {
+				rows[i] = append(rows[i], r[j]*r[k])
+			}
+		}
+	}
+	e := summary.Residuals()
+	u2 := prod(e, e)
+	_, aux, err := NewOlsTrainer().Train(NewDataFrame(rows), u2)
+	assert.Equal(t, nil, err)
+	lm := float64(len(e)) * (1 - aux.SumOfSquares()/totalSumOfSquares(u2))
+	assertClose(t, result.Statistic, lm, 1e-8)
+	assertClose(t, result.PValue, chiSquareSurvival(lm, 9), 1e-12)
+}
+
+func TestWhiteTestDummies(t *testing.T) {
+	// d1 and d2 are mutually exclusive dummies, so d1^2 = d1, d2^2 = d2 and
+	// d1 d2 = 0 all drop out, leaving x, d1, d2, x^2, x d1 and x d2
+	rng := rand.New(rand.NewSource(11))
+	rows := make([][]float64, 60)
+	response := make([]float64, 60)
+	for i := range rows {
+		x := rng.NormFloat64()
+		d1, d2 := 0.0, 0.0
+		switch i % 3 {
+		case 1:
+			d1 = 1
+		case 2:
+			d2 = 1
+		}
+		rows[i] = []float64{x, d1, d2}
+		response[i] = 1 + x + d1 - d2 + rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	result, err := WhiteTest(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, result.DF, 6.0)
+	assert.T(t, result.PValue > 0 && result.PValue <= 1)
+}
+
+func TestWhiteTestPower(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	rows := make([][]float64, 200)
+	response := make([]float64, 200)
+	for i := range rows {
+		x := rng.NormFloat64()
+		rows[i] = []float64{x, rng.NormFloat64()}
+		// the variance depends on x^2, which Breusch-Pagan can't see
+		response[i] = 1 + x + rows[i][1] + (0.2+x*x)*rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	result, err := WhiteTest(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, result.PValue < 0.001)
+}
+
+func TestSkewnessKurtosis(t *testing.T) {
+	x := []float64{0, 0, 0, 1}
+	assertClose(t, Skewness(x), 2/math.Sqrt(3), 1e-12)
+	assertClose(t, Kurtosis(x), -2.0/3, 1e-12)
+
+	seq := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assertClose(t, Skewness(seq), 0, 1e-12)
+	assertClose(t, Kurtosis(seq), 120.8625/(8.25*8.25)-3, 1e-12)
+}
+
+func TestJarqueBera(t *testing.T) {
+	// tseries::jarque.bera.test(1:10): X-squared = 0.6245, p-value = 0.7318
+	s := OlsSummary{residuals: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	result, err := JarqueBera(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, result.Statistic, 0.6245, 1e-4)
+	assertClose(t, result.PValue, 0.7318, 1e-4)
+	assert.Equal(t, result.DF, 2.0)
+	assert.Equal(t, len(result.Warnings), 1)
+
+	e := summary.Residuals()
+	sk, k := Skewness(e), Kurtosis(e)
+	result, err = JarqueBera(summary)
+	assert.Equal(t, nil, err)
+	assertClose(t, result.Statistic, 21.0/6*(sk*sk+k*k/4), 1e-12)
+	assert.Equal(t, len(result.Warnings), 1)
+
+	result, err = JarqueBera(simulatedSummary(200, 2, 1))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(result.Warnings), 0)
+
+	_, err = JarqueBera(OlsSummary{residuals: []float64{1, 1, 1}})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestOverallFTest(t *testing.T) {
+	// summary(lm(stack.loss ~ ., stackloss)): F-statistic: 59.9 on 3 and 17 DF, p-value: 3.016e-09
+	result, err := OverallFTest(summary)
+	assert.Equal(t, nil, err)
+	assertClose(t, result.Statistic, 59.9, 0.05)
+	assert.Equal(t, result.DF, 3.0)
+	assert.Equal(t, result.DF2, 17.0)
+	assertClose(t, result.PValue, 3.016e-09, 1e-12)
+
+	f, p := summary.(OlsSummary).F_Statistic()
+	assert.Equal(t, f, result.Statistic)
+	assert.Equal(t, p, result.PValue)
+
+	// summary(lm(Employed ~ ., longley)): F-statistic: 330.3 on 6 and 9 DF, p-value: 4.984e-10
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	result, err = OverallFTest(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, result.Statistic, 330.3, 0.05)
+	assert.Equal(t, result.DF, 6.0)
+	assertClose(t, result.PValue, 4.984e-10, 1e-13)
+
+	// through the origin all three coefficients are tested, against y = 0
+	origin := noInterceptSummary(t)
+	result, err = OverallFTest(origin)
+	assert.Equal(t, nil, err)
+	rss := origin.SumOfSquares()
+	expected := ((sum(prod(y, y)) - rss) / 3) / (rss / 18)
+	assertClose(t, result.Statistic, expected, 1e-9)
+	assert.Equal(t, result.DF, 3.0)
+	assert.Equal(t, result.DF2, 18.0)
+}
+
+// stacklossSubset fits stack.loss on the given stackloss columns.
+func stacklossSubset(t *testing.T, cols ...int) Summary {
+	rows := make([][]float64, len(data))
+	for i := range rows {
+		for _, j := range cols {
+			rows[i] = append(rows[i], data[i][j])
+		}
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func TestCompareModels(t *testing.T) {
+	// anova(lm(stack.loss ~ Air.Flow), lm(stack.loss ~ ., stackloss)) pools the
+	// last two rows of the sequential table
+	reduced := stacklossSubset(t, 0)
+	f, p, err := CompareModels(summary, reduced)
+	assert.Equal(t, nil, err)
+	table, err := Anova(summary)
+	assert.Equal(t, nil, err)
+	expected := (table.Rows[1].SumSq + table.Rows[2].SumSq) / 2 / table.Rows[3].MeanSq
+	assertClose(t, f, expected, 1e-9)
+	assertClose(t, f, 6.668, 1e-3)
+	assertClose(t, p, fSurvival(expected, 2, 17), 1e-12)
+
+	// dropping a single column gives the square of its t statistic
+	coefs, err := CoefficientTable(summary)
+	assert.Equal(t, nil, err)
+	f, p, err = CompareModels(summary, stacklossSubset(t, 0, 1))
+	assert.Equal(t, nil, err)
+	assertClose(t, f, coefs[3].T*coefs[3].T, 1e-9)
+	assertClose(t, p, coefs[3].PValue, 1e-12)
+
+	// a transformed column is still nested
+	rows := make([][]float64, len(data))
+	for i := range rows {
+		rows[i] = []float64{2*data[i][0] - data[i][1] + 3}
+	}
+	_, combined, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	_, _, err = CompareModels(summary, combined)
+	assert.Equal(t, nil, err)
+
+	// not nested
+	for i := range rows {
+		rows[i] = []float64{data[i][0] * data[i][1]}
+	}
+	_, product, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	_, _, err = CompareModels(summary, product)
+	assert.NotEqual(t, nil, err)
+
+	// wrong way round, and different observations
+	_, _, err = CompareModels(reduced, summary)
+	assert.NotEqual(t, nil, err)
+	_, _, err = CompareModels(summary, simulatedSummary(30, 1, 1))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestFTest(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	before := s.Data().Data()
+
+	// remove Water.Temp and Acid.Conc., which are design columns 2 and 3
+	f, p, err := FTest(s, NewOlsTrainer(), []int{2, 3})
+	assert.Equal(t, nil, err)
+	ef, ep, err := CompareModels(s, stacklossSubset(t, 0))
+	assert.Equal(t, nil, err)
+	assertClose(t, f, ef, 1e-12)
+	assertClose(t, p, ep, 1e-12)
+	assert.T(t, mat.Equal(before, s.Data().X))
+
+	_, _, err = FTest(s, NewOlsTrainer(), []int{0})
+	assert.NotEqual(t, nil, err)
+	_, _, err = FTest(s, NewOlsTrainer(), []int{1, 2, 3})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestResetTest(t *testing.T) {
+	// the F test of the squares and cubes of the fitted values added to the
+	// model, as lmtest's resettest(stack.loss ~ ., data = stackloss) defines
+	// it, hand-derived in exact rational arithmetic with the p-value from a
+	// 50-digit incomplete beta function; lmtest hasn't been run against these
+	res, err := ResetTest(summary, nil)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 1.1728648400634147, 1e-9)
+	assert.Equal(t, 2.0, res.DF)
+	assert.Equal(t, 15.0, res.DF2)
+	assertClose(t, res.PValue, 0.3363102765984362, 1e-9)
+
+	res, err = ResetTest(summary, []int{2})
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 2.2597624724695513, 1e-9)
+	assert.Equal(t, 16.0, res.DF2)
+
+	// fitted values of 1e100 would overflow when cubed, but are rescaled first
+	scaled := multSlice(y, 1e100)
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), scaled)
+	assert.Equal(t, nil, err)
+	res, err = ResetTest(s, nil)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 1.1728648400634147, 1e-6)
+}
+
+func TestResetTestPower(t *testing.T) {
+	// a straight line fit to a quadratic is caught; fit to a line it isn't
+	rng := rand.New(rand.NewSource(3))
+	rows := make([][]float64, 60)
+	quadratic, linear := make([]float64, 60), make([]float64, 60)
+	for i := range rows {
+		x := rng.Float64() * 4
+		rows[i] = []float64{x}
+		quadratic[i] = 1 + x + x*x + 0.3*rng.NormFloat64()
+		linear[i] = 1 + x + 0.3*rng.NormFloat64()
+	}
+	res, err := ResetTest(auxiliaryFitOn(t, rows, quadratic), nil)
+	assert.Equal(t, nil, err)
+	assert.T(t, res.PValue < 1e-6)
+	res, err = ResetTest(auxiliaryFitOn(t, rows, linear), nil)
+	assert.Equal(t, nil, err)
+	assert.T(t, res.PValue > 0.01)
+}
+
+func TestResetTestErrors(t *testing.T) {
+	for _, powers := range [][]int{{1, 2}, {2, 2}, {0}} {
+		_, err := ResetTest(summary, powers)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := ResetTest(summary, []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18})
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, ws, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, err = ResetTest(ws, nil)
+	assert.NotEqual(t, nil, err)
+}
+
+func auxiliaryFitOn(t *testing.T, rows [][]float64, response []float64) Summary {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func TestGoldfeldQuandt(t *testing.T) {
+	// the statistics of lmtest's gqtest(stack.loss ~ ., data = stackloss,
+	// order.by = ~ Air.Flow), and with fraction = 0.2, hand-derived from its
+	// split points floor((point - fraction/2) n) and ceiling((point +
+	// fraction/2) n + 0.01) in exact rational arithmetic, with the p-values
+	// from a 50-digit incomplete beta function; lmtest hasn't been run
+	// against these. Air.Flow has ties, which keep the order of the rows
+	x := NewDataFrame(data)
+	res, err := GoldfeldQuandt(x, y, 0, 0)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 17.390793106843386, 1e-9)
+	assert.Equal(t, 7.0, res.DF)
+	assert.Equal(t, 6.0, res.DF2)
+
+	res, err = GoldfeldQuandt(x, y, 0, 0.2)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 12.94478322337274, 1e-9)
+	assert.Equal(t, 5.0, res.DF)
+	assert.Equal(t, 4.0, res.DF2)
+	assertClose(t, res.PValue, 0.013968000873348041, 1e-9)
+
+	res, err = GoldfeldQuandt(x, y, 2, 0.2)
+	assert.Equal(t, nil, err)
+	assertClose(t, res.Statistic, 6.132928860570765, 1e-9)
+	assertClose(t, res.PValue, 0.05167960781204062, 1e-9)
+	less, err := GoldfeldQuandt(x, y, 2, 0.2, WithAlternative(LessAlternative))
+	assert.Equal(t, nil, err)
+	assertClose(t, less.PValue, 1-res.PValue, 1e-9)
+	two, err := GoldfeldQuandt(x, y, 2, 0.2, WithAlternative(TwoSidedAlternative))
+	assert.Equal(t, nil, err)
+	assertClose(t, two.PValue, 2*res.PValue, 1e-9)
+
+	// unsorted, the groups are the first and last rows
+	res, err = GoldfeldQuandt(x, y, -1, 0.2)
+	assert.Equal(t, nil, err)
+	rows := identityRows(len(y))
+	_, low, _ := NewOlsTrainer().Train(subsetRows(x, rows[:8]), y[:8])
+	_, high, _ := NewOlsTrainer().Train(subsetRows(x, rows[12:]), y[12:])
+	assertClose(t, res.Statistic, (high.SumOfSquares()/5)/(low.SumOfSquares()/4), 1e-10)
+	assert.Equal(t, "two-sided", TwoSidedAlternative.String())
+}
+
+func TestGoldfeldQuandtErrors(t *testing.T) {
+	x := NewDataFrame(data)
+	for _, fraction := range []float64{-0.1, 1, math.NaN()} {
+		_, err := GoldfeldQuandt(x, y, 0, fraction)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := GoldfeldQuandt(x, y, 0, 0.6)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = GoldfeldQuandt(x, y, 3, 0)
+	assert.NotEqual(t, nil, err)
+	_, err = GoldfeldQuandt(x, y[1:], 0, 0)
+	assert.Equal(t, DimensionError, err)
+	_, err = GoldfeldQuandt(x, y, 0, 0, WithAlternative(Alternative(5)))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,125 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxDesignEffect is the design effect of the residuals above which ResidualICC
+// warns that they are clustered, the rule of thumb of Muthén & Satorra (1995)
+// beyond which ignoring the clustering misleads inference.
+const MaxDesignEffect = 2
+
+// ICCEstimate is the one-way analysis of variance estimate of the correlation
+// between values in the same group. See ICC.
+type ICCEstimate struct {
+	ICC float64
+
+	// Between and Within are the variance components, \sigma^2_b of the
+	// group effects and \sigma^2_w of the values around them.
+	Between, Within float64
+
+	Groups int
+
+	// GroupSize is the size n_0 of the groups in the estimate of Between,
+	// their mean size for groups of equal sizes.
+	GroupSize float64
+
+	// DesignEffect is the factor 1 + (\bar m - 1) ICC by which clustering
+	// inflates the variance of a mean, with \bar m = n / G the mean size of
+	// the G groups of the n values. Standard errors that ignore the
+	// clustering are too small by about its square root.
+	DesignEffect float64
+
+	// Warnings describe conditions under which the estimate may mislead, as
+	// ResidualICC adds them.
+	Warnings []Warning
+}
+
+// ICC returns the intraclass correlation of the values, grouped by the labels
+// of group, by the one-way analysis of variance estimator
+//
+// \hat\sigma^2_w = MSW, \hat\sigma^2_b = \frac{MSB - MSW}{n_0}, ICC = \frac{\hat\sigma^2_b}{\hat\sigma^2_b + \hat\sigma^2_w}
+//
+// with the mean squares between and within the G groups of n values, and
+//
+// n_0 = \frac{1}{G - 1} (n - \frac{\sum_g n_g^2}{n})
+//
+// the correction for groups of unequal sizes n_g, as R's ICC::ICCest. The
+// estimate isn't truncated: when the groups differ by less than chance would
+// make them, Between and the ICC are negative. It needs two groups and a group
+// of two values at least.
+func ICC(values []float64, group []int) (*ICCEstimate, error) {
+	n := len(values)
+	if len(group) != n {
+		return nil, DimensionError
+	}
+	groups := clusterIndex(group)
+	g := len(groups)
+	if g < 2 {
+		return nil, fmt.Errorf("an intraclass correlation needs at least 2 groups, got %d", g)
+	}
+	if n == g {
+		return nil, fmt.Errorf("every group has a single value, so there is no variance within them")
+	}
+	grand := mean(values)
+	var ssb, ssw, squares float64
+	for _, rows := range groups {
+		m := 0.0
+		for _, i := range rows {
+			m += values[i]
+		}
+		m /= float64(len(rows))
+		for _, i := range rows {
+			ssw += (values[i] - m) * (values[i] - m)
+		}
+		size := float64(len(rows))
+		ssb += size * (m - grand) * (m - grand)
+		squares += size * size
+	}
+	msb, msw := ssb/float64(g-1), ssw/float64(n-g)
+	n0 := (float64(n) - squares/float64(n)) / float64(g-1)
+	between := (msb - msw) / n0
+	icc := between / (between + msw)
+	return &ICCEstimate{
+		ICC:          icc,
+		Between:      between,
+		Within:       msw,
+		Groups:       g,
+		GroupSize:    n0,
+		DesignEffect: 1 + (float64(n)/float64(g)-1)*icc,
+	}, nil
+}
+
+// IntraclassCorrelation returns the intraclass correlation of the residuals,
+// grouped by the labels of group, and its variance components between and
+// within the groups, as ICC finds them.
+func IntraclassCorrelation(residuals []float64, group []int) (icc, betweenVar, withinVar float64, err error) {
+	e, err := ICC(residuals, group)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return e.ICC, e.Between, e.Within, nil
+}
+
+// ResidualICC returns the intraclass correlation of the residuals of the model
+// grouped by the labels of group, as ICC, for whether they are clustered before
+// reaching for random effects. With a DesignEffect above MaxDesignEffect, a
+// warning of ClusteredResiduals suggests standard errors clustered on the
+// groups, of ClusteredVCov.
+func ResidualICC(m Summary, group []int) (*ICCEstimate, error) {
+	icc, err := ICC(m.Residuals(), group)
+	if err != nil {
+		return nil, err
+	}
+	if icc.DesignEffect > MaxDesignEffect {
+		icc.Warnings = append(icc.Warnings, Warning{
+			Kind: ClusteredResiduals,
+			Message: fmt.Sprintf("the residuals have an intraclass correlation of %.3g within the %d groups, for a design effect of %.3g, above %v, so the standard errors may be too small by a factor of %.3g; use standard errors clustered on the groups",
+				icc.ICC, icc.Groups, icc.DesignEffect, float64(MaxDesignEffect), math.Sqrt(icc.DesignEffect)),
+			Value: icc.DesignEffect,
+			Limit: MaxDesignEffect,
+		})
+	}
+	return icc, nil
+}
@@ -0,0 +1,89 @@
+package glasso
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestICC(t *testing.T) {
+	// an unbalanced example worked exactly in Python: groups of 3, 2 and 4,
+	// with MSB = 5.5, MSW = 1.5 and n_0 = 26/9
+	values := []float64{1, 2, 3, 4, 6, 2, 3, 4, 5}
+	group := []int{7, 7, 7, 3, 3, 5, 5, 5, 5}
+	icc, err := ICC(values, group)
+	assert.Equal(t, nil, err)
+	assertClose(t, icc.ICC, 0.48, 1e-12)
+	assertClose(t, icc.Between, 1.3846153846153846, 1e-12)
+	assertClose(t, icc.Within, 1.5, 1e-12)
+	assertClose(t, icc.GroupSize, 2.888888888888889, 1e-12)
+	// with groups of 3 on average, 1 + 2 ICC
+	assertClose(t, icc.DesignEffect, 1.96, 1e-12)
+	assert.Equal(t, 3, icc.Groups)
+
+	rho, between, within, err := IntraclassCorrelation(values, group)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []float64{icc.ICC, icc.Between, icc.Within}, []float64{rho, between, within})
+	_, _, _, err = IntraclassCorrelation(values, group[:3])
+	assert.Equal(t, DimensionError, err)
+
+	// groups that differ by less than chance have a negative ICC
+	icc, err = ICC([]float64{1, 3, 2, 2}, []int{0, 0, 1, 1})
+	assert.Equal(t, nil, err)
+	assert.T(t, icc.ICC < 0 && icc.Between < 0)
+
+	_, err = ICC(values, group[:3])
+	assert.Equal(t, DimensionError, err)
+	_, err = ICC(values, make([]int, len(values)))
+	assert.NotEqual(t, nil, err)
+	_, err = ICC([]float64{1, 2}, []int{0, 1})
+	assert.NotEqual(t, nil, err)
+}
+
+// clusteredData returns a line with errors of a random effect of variance
+// between for each of groups groups of unequal sizes and of variance one
+// within them.
+func clusteredData(rng *rand.Rand, groups int, between float64) (*DataFrame, []float64, []int) {
+	var rows [][]float64
+	var z []float64
+	var labels []int
+	for g := 0; g < groups; g++ {
+		effect := between * rng.NormFloat64()
+		for k := 0; k < 2+g%7; k++ {
+			x := rng.NormFloat64()
+			rows = append(rows, []float64{x})
+			z = append(z, 1+2*x+effect+rng.NormFloat64())
+			labels = append(labels, g)
+		}
+	}
+	return NewDataFrame(rows), z, labels
+}
+
+func TestResidualICC(t *testing.T) {
+	// a random effect of variance 0.5 within groups of 2 to 8, for an ICC of 1/3
+	x, z, group := clusteredData(rand.New(rand.NewSource(1)), 400, 0.7071067811865476)
+	_, s, err := NewOlsTrainer().Train(x, z)
+	assert.Equal(t, nil, err)
+	icc, err := ResidualICC(s, group)
+	assert.Equal(t, nil, err)
+	assertClose(t, icc.ICC, 1.0/3, 0.05)
+	assertClose(t, icc.Between, 0.5, 0.1)
+	assertClose(t, icc.Within, 1, 0.1)
+	assert.T(t, icc.DesignEffect > MaxDesignEffect)
+	assert.Equal(t, 1, len(icc.Warnings))
+	assert.Equal(t, ClusteredResiduals, icc.Warnings[0].Kind)
+	assert.Equal(t, icc.DesignEffect, icc.Warnings[0].Value)
+
+	// without the random effect there is no warning
+	x, z, group = clusteredData(rand.New(rand.NewSource(2)), 400, 0)
+	_, s, err = NewOlsTrainer().Train(x, z)
+	assert.Equal(t, nil, err)
+	icc, err = ResidualICC(s, group)
+	assert.Equal(t, nil, err)
+	assertClose(t, icc.ICC, 0, 0.05)
+	assert.Equal(t, 0, len(icc.Warnings))
+	_, err = ResidualICC(s, group[:10])
+	assert.T(t, errors.Is(err, DimensionError), err)
+}
@@ -0,0 +1,253 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Coefficient holds the inference for a single regression coefficient.
+type Coefficient struct {
+	Estimate float64
+	StdError float64
+	T        float64 // Estimate / StdError
+	PValue   float64 // two sided, from the t distribution with n - p degrees of freedom
+}
+
+// CoefficientTable returns the estimate, standard error, t statistic and
+// two-sided p-value of each coefficient of the model, as in the coefficient
+// table of R's summary.lm:
+//
+// se(\beta_j) = \sqrt{s^2 (X'X)^{-1}_{jj}}, s^2 = RSS / (n - p)
+// t_j = \beta_j / se(\beta_j) \sim t(n - p)
+//
+// The standard errors come from VarCov unless another variance-covariance
+// matrix, such as one from RobustVCov, is given.
+func CoefficientTable(m Summary, vcov ...*DataFrame) ([]Coefficient, error) {
+	v, err := coefficientVCov(m, vcov)
+	if err != nil {
+		return nil, err
+	}
+	df := float64(m.Data().Rows() - rankOf(m))
+	se := StandardErrors(v)
+
+	table := make([]Coefficient, len(se))
+	for j, b := range m.Coefficients() {
+		t := b / se[j]
+		table[j] = Coefficient{
+			Estimate: b,
+			StdError: se[j],
+			T:        t,
+			PValue:   studentTTwoSided(t, df),
+		}
+	}
+	return table, nil
+}
+
+// CoefficientNames returns the names of the coefficients of the model, in the
+// order of its Coefficients: "(Intercept)" for the intercept and the labels of
+// the predictors, or x0, x1, ... after their columns if the data had no labels.
+func CoefficientNames(m Summary) []string {
+	x := m.Data()
+	intercept := interceptOf(m)
+	names := make([]string, x.Cols())
+	for j := range names {
+		names[j] = "(Intercept)"
+		if j != intercept {
+			names[j] = columnLabel(x, j, intercept)
+		}
+	}
+	return names
+}
+
+// PredictorNames returns the names of the coefficients but the intercept, in
+// the order of the values of VIF.
+func PredictorNames(m Summary) []string {
+	intercept := interceptOf(m)
+	var names []string
+	for j, name := range CoefficientNames(m) {
+		if j != intercept {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// NamedCoefficients returns the coefficients of the model by their names.
+func NamedCoefficients(m Summary) map[string]float64 {
+	coefficients := make(map[string]float64)
+	for j, name := range CoefficientNames(m) {
+		coefficients[name] = m.Coefficients()[j]
+	}
+	return coefficients
+}
+
+// coefficientVCov returns the variance-covariance matrix to base inference on:
+// the one given, or the classical one of the model.
+func coefficientVCov(m Summary, vcov []*DataFrame) (*DataFrame, error) {
+	p := m.Data().Cols()
+	switch len(vcov) {
+	case 0:
+		return VarCov(m)
+	case 1:
+		if vcov[0].Rows() != p || vcov[0].Cols() != p {
+			return nil, DimensionError
+		}
+		return vcov[0], nil
+	}
+	return nil, DimensionError
+}
+
+// DefaultConfidenceLevel is the level used by DefaultConfInt.
+const DefaultConfidenceLevel = 0.95
+
+// ConfInt returns the lower and upper bounds of the confidence interval for each
+// coefficient of the model at the given level, as R's confint.lm does:
+//
+// \beta_j \pm t_{(1 + level) / 2, n - p} se(\beta_j)
+//
+// The standard errors come from VarCov unless another variance-covariance
+// matrix, such as one from RobustVCov, is given. The level must lie in (0, 1).
+func ConfInt(m Summary, level float64, vcov ...*DataFrame) ([][2]float64, error) {
+	if !(level > 0 && level < 1) {
+		return nil, fmt.Errorf("confidence level %v is not between 0 and 1", level)
+	}
+	v, err := coefficientVCov(m, vcov)
+	if err != nil {
+		return nil, err
+	}
+	df := float64(m.Data().Rows() - rankOf(m))
+	t := studentTQuantile((1+level)/2, df)
+
+	se := StandardErrors(v)
+	intervals := make([][2]float64, len(se))
+	for j, b := range m.Coefficients() {
+		intervals[j] = [2]float64{b - t*se[j], b + t*se[j]}
+	}
+	return intervals, nil
+}
+
+// DefaultConfInt returns the 95% confidence intervals for the coefficients of the model.
+func DefaultConfInt(m Summary, vcov ...*DataFrame) ([][2]float64, error) {
+	return ConfInt(m, DefaultConfidenceLevel, vcov...)
+}
+
+// LinearHypothesis tests the linear hypothesis L\beta = r about the
+// coefficients of the model, such as that two coefficients are equal or that
+// some sum to one, by the Wald F test of R's car::linearHypothesis:
+//
+// F = (L\hat\beta - r)' (L V L')^{-1} (L\hat\beta - r) / q \sim F(q, n - p)
+//
+// for the q rows of l, each a hypothesis, whose columns are the coefficients
+// in the order of m.Coefficients(). V is VarCov unless another
+// variance-covariance matrix, such as one from RobustVCov, ClusteredVCov or
+// NeweyWestVCov, is given, for a test that is robust to the errors it
+// allows for. Rows of l that are linear combinations of the rows before them
+// are an error naming them, since they leave L V L' singular.
+func LinearHypothesis(m Summary, l *DataFrame, r []float64, vcov ...*DataFrame) (TestResult, error) {
+	w, q, err := waldStatistic(m, l, r, vcov)
+	if err != nil {
+		return TestResult{}, err
+	}
+	f := w / float64(q)
+	df := float64(m.Data().Rows() - rankOf(m))
+	return TestResult{Statistic: f, DF: float64(q), DF2: df, PValue: fSurvival(f, float64(q), df)}, nil
+}
+
+// LinearHypothesisChiSquared tests the linear hypothesis L\beta = r as
+// LinearHypothesis does, but with the Wald statistic q F referred to the
+// chi-squared distribution on q degrees of freedom, the test="Chisq" of
+// car::linearHypothesis, which doesn't rely on the errors being normal.
+func LinearHypothesisChiSquared(m Summary, l *DataFrame, r []float64, vcov ...*DataFrame) (TestResult, error) {
+	w, q, err := waldStatistic(m, l, r, vcov)
+	if err != nil {
+		return TestResult{}, err
+	}
+	return TestResult{Statistic: w, DF: float64(q), PValue: chiSquareSurvival(w, float64(q))}, nil
+}
+
+// waldStatistic returns (L\hat\beta - r)' (L V L')^{-1} (L\hat\beta - r) and
+// the number of hypotheses q.
+func waldStatistic(m Summary, l *DataFrame, r []float64, vcov []*DataFrame) (float64, int, error) {
+	v, err := coefficientVCov(m, vcov)
+	if err != nil {
+		return 0, 0, err
+	}
+	betas := m.Coefficients()
+	q, p := l.Rows(), len(betas)
+	if q == 0 {
+		return 0, 0, fmt.Errorf("no hypotheses given")
+	}
+	if l.Cols() != p {
+		return 0, 0, fmt.Errorf("hypothesis matrix has %d columns for %d coefficients", l.Cols(), p)
+	}
+	if len(r) != q {
+		return 0, 0, fmt.Errorf("%d hypotheses have %d right hand sides", q, len(r))
+	}
+	for j := 0; j < q; j++ {
+		for _, a := range append(l.GetRow(j), r[j]) {
+			if math.IsNaN(a) || math.IsInf(a, 0) {
+				return 0, 0, fmt.Errorf("hypothesis %d is not finite", j)
+			}
+		}
+	}
+	if redundant := dependentRows(l.X); len(redundant) > 0 {
+		if len(redundant) == 1 {
+			return 0, 0, fmt.Errorf("hypothesis %d is redundant, since it is a linear combination of the hypotheses before it", redundant[0])
+		}
+		return 0, 0, fmt.Errorf("hypotheses %v are redundant, since they are linear combinations of the hypotheses before them", redundant)
+	}
+
+	d := make([]float64, q)
+	for j := range d {
+		d[j] = sum(prod(l.X.RawRowView(j), betas)) - r[j]
+	}
+	lv := &mat.Dense{}
+	lv.Mul(l.X, v.X)
+	middle := &mat.Dense{}
+	middle.Mul(lv, l.X.T())
+	symmetrize(middle)
+	var chol mat.Cholesky
+	if !chol.Factorize(mat.NewSymDense(q, middle.RawMatrix().Data)) {
+		return 0, 0, fmt.Errorf("the variance of the hypotheses, L V L', is not positive definite")
+	}
+	z := mat.NewVecDense(q, nil)
+	if err := chol.SolveVecTo(z, mat.NewVecDense(q, d)); err != nil {
+		return 0, 0, err
+	}
+	return sum(prod(d, z.RawVector().Data)), q, nil
+}
+
+// dependentRows returns the rows of a that are linear combinations of the
+// rows before them.
+func dependentRows(a *mat.Dense) []int {
+	q, p := a.Dims()
+	var independent, dependent []int
+	for j := 0; j < q; j++ {
+		row := a.RawRowView(j)
+		size := math.Sqrt(sum(prod(row, row)))
+
+		// its distance from the span of the independent rows before it
+		distance := size
+		switch {
+		case len(independent) == p:
+			distance = 0
+		case len(independent) > 0:
+			previous := mat.NewDense(p, len(independent), nil)
+			for k, i := range independent {
+				previous.SetCol(k, a.RawRowView(i))
+			}
+			fit, err := leastSquares(previous, mat.NewDense(p, 1, append([]float64(nil), row...)))
+			if err == nil {
+				distance = math.Sqrt(sum(prod(fit.residuals, fit.residuals)))
+			}
+		}
+		if distance > 1e-10*size {
+			independent = append(independent, j)
+			continue
+		}
+		dependent = append(dependent, j)
+	}
+	return dependent
+}
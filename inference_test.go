@@ -0,0 +1,218 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestCoefficientTable(t *testing.T) {
+	table, err := CoefficientTable(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(table), 4)
+
+	// summary(lm(stack.loss ~ ., stackloss))
+	expected := []Coefficient{
+		{-39.9197, 11.8960, -3.356, 0.00375},
+		{0.7156, 0.1349, 5.307, 5.8e-05},
+		{1.2953, 0.3680, 3.520, 0.00263},
+		{-0.1521, 0.1563, -0.973, 0.34405},
+	}
+	for j, c := range table {
+		assertClose(t, c.Estimate, expected[j].Estimate, 1e-4)
+		assertClose(t, c.StdError, expected[j].StdError, 1e-4)
+		assertClose(t, c.T, expected[j].T, 1e-3)
+		assertClose(t, c.PValue, expected[j].PValue, 5e-6)
+		assertClose(t, c.T, c.Estimate/c.StdError, 1e-12)
+		assertClose(t, c.PValue, 2*studentTCDF(-math.Abs(c.T), 17), 1e-12)
+	}
+}
+
+func TestCoefficientTableRobust(t *testing.T) {
+	hc, err := RobustVCov(summary, HC3)
+	assert.Equal(t, nil, err)
+	table, err := CoefficientTable(summary, hc)
+	assert.Equal(t, nil, err)
+	for j, se := range StandardErrors(hc) {
+		assert.Equal(t, table[j].StdError, se)
+	}
+
+	_, err = CoefficientTable(summary, NewDataFrame([][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}))
+	assert.Equal(t, DimensionError, err)
+}
+
+func TestConfInt(t *testing.T) {
+	// confint(lm(stack.loss ~ ., stackloss))
+	expected := [][2]float64{
+		{-65.0180, -14.8213},
+		{0.4311, 1.0002},
+		{0.5188, 2.0717},
+		{-0.4818, 0.1776},
+	}
+	intervals, err := DefaultConfInt(summary)
+	assert.Equal(t, nil, err)
+	table, err := CoefficientTable(summary)
+	assert.Equal(t, nil, err)
+	for j, ci := range intervals {
+		assertClose(t, ci[0], expected[j][0], 1e-4)
+		assertClose(t, ci[1], expected[j][1], 1e-4)
+		// the interval excludes zero exactly when the coefficient is significant at 5%
+		assert.Equal(t, ci[0] > 0 || ci[1] < 0, table[j].PValue < 0.05)
+	}
+
+	// the deprecated method is the same intervals at the level 1 - alpha
+	deprecated, err := summary.(OlsSummary).Confidence_interval(0.05)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, intervals, deprecated)
+
+	narrow, err := ConfInt(summary, 0.5)
+	assert.Equal(t, nil, err)
+	for j := range narrow {
+		assert.T(t, narrow[j][0] > intervals[j][0] && narrow[j][1] < intervals[j][1])
+	}
+
+	hc, err := RobustVCov(summary, HC1)
+	assert.Equal(t, nil, err)
+	robust, err := ConfInt(summary, 0.95, hc)
+	assert.Equal(t, nil, err)
+	se := StandardErrors(hc)
+	for j := range robust {
+		assertClose(t, robust[j][1]-robust[j][0], 2*2.109815577833317*se[j], 1e-9)
+	}
+
+	for _, level := range []float64{0, 1, -0.5, 95} {
+		_, err = ConfInt(summary, level)
+		assert.NotEqual(t, nil, err)
+	}
+}
+
+func TestCoefficientNames(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	names := []string{"(Intercept)", "Air.Flow", "Water.Temp", "Acid.Conc."}
+	assert.Equal(t, CoefficientNames(s), names)
+	assert.Equal(t, PredictorNames(s), names[1:])
+	assert.Equal(t, m.(*OLS).Names(), names)
+
+	// the names are in the order of the coefficients
+	named := NamedCoefficients(s)
+	for j, name := range names {
+		assert.Equal(t, named[name], s.Coefficients()[j])
+		assert.Equal(t, m.(*OLS).Coefficients()[j], s.Coefficients()[j])
+	}
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, vcov.Labels(), names)
+	dfbetas, err := DFBETAS(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, dfbetas.Labels(), names)
+	collinearity, err := CollinearityDiagnostics(s, true)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, collinearity.Proportions.Labels(), names)
+
+	// without labels the predictors are named after their columns
+	m, s, err = NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	names = []string{"(Intercept)", "x0", "x1", "x2"}
+	assert.Equal(t, CoefficientNames(s), names)
+	assert.Equal(t, m.(*OLS).Names(), names)
+	assert.Equal(t, PredictorNames(noInterceptSummary(t)), names[1:])
+}
+
+func TestCollinearNames(t *testing.T) {
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{row[0], row[1], 2 * row[0]}
+	}
+	x := NewDataFrame(rows, []string{"income", "age", "salary"})
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows, []string{"income", "age", "salary"}), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, m.(*OLS).Aliased(), []string{"salary"})
+	// fits that don't alias columns name them in their error
+	_, _, err = trainLeastSquares(x, y, nil)
+	assert.NotEqual(t, nil, err)
+	assert.T(t, errors.Is(err, SingularDesignError))
+	assert.Equal(t, err.Error(), `design matrix is singular: column "salary" is collinear with "income"`)
+
+	for i, row := range data {
+		rows[i] = []float64{row[0], row[1], row[0] - row[1] + 3}
+	}
+	_, _, err = trainLeastSquares(NewDataFrame(rows), y, nil)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, err.Error(), `design matrix is singular: column "x2" is collinear with "(Intercept)", "x0", "x1"`)
+
+	// a constant column is aliased with the intercept, and isn't standardized
+	for i, row := range data {
+		rows[i] = []float64{row[0], row[1], 5}
+	}
+	_, s, err := NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(rows, []string{"income", "age", "year"}), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.(OlsSummary).Aliased(), []string{"year"})
+	_, _, err = trainStandardized(NewDataFrame(rows, []string{"income", "age", "year"}), y, true, QRSolver)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, err.Error(), `column "year" is constant and can't be standardized`)
+}
+
+func TestLinearHypothesis(t *testing.T) {
+	// the references are computed in exact rational arithmetic, by the
+	// formulas of car::linearHypothesis
+	equal := NewDataFrame([][]float64{{0, 1, -1, 0}})
+	joint := NewDataFrame([][]float64{{0, 1, -1, 0}, {0, 0, 1, 1}})
+	hc3, err := RobustVCov(summary, HC3)
+	assert.Equal(t, nil, err)
+	for _, c := range []struct {
+		name string
+		l    *DataFrame
+		r    []float64
+		vcov []*DataFrame
+		want float64 // the Wald statistic
+	}{
+		{"air flow = water temp", equal, []float64{0}, nil, 1.4824155614512502},
+		{"air flow = water temp, HC3", equal, []float64{0}, []*DataFrame{hc3}, 0.5493951593855284},
+		{"and water temp + acid conc = 1", joint, []float64{0, 1}, nil, 6.884744936742687},
+		{"and water temp + acid conc = 1, HC3", joint, []float64{0, 1}, []*DataFrame{hc3}, 6.846143484294298},
+	} {
+		q := float64(c.l.Rows())
+		f, err := LinearHypothesis(summary, c.l, c.r, c.vcov...)
+		assert.Equal(t, nil, err)
+		assertClose(t, f.Statistic, c.want/q, 1e-10)
+		assert.Equal(t, q, f.DF)
+		assert.Equal(t, 17.0, f.DF2)
+		assertClose(t, f.PValue, fSurvival(c.want/q, q, 17), 1e-12)
+
+		chi, err := LinearHypothesisChiSquared(summary, c.l, c.r, c.vcov...)
+		assert.Equal(t, nil, err)
+		assertClose(t, chi.Statistic, c.want, 1e-10)
+		assert.Equal(t, q, chi.DF)
+		assertClose(t, chi.PValue, chiSquareSurvival(c.want, q), 1e-12)
+	}
+
+	// a single hypothesis is the square of the t test of its contrast
+	table, err := CoefficientTable(summary)
+	assert.Equal(t, nil, err)
+	f, err := LinearHypothesis(summary, NewDataFrame([][]float64{{0, 0, 1, 0}}), []float64{0})
+	assert.Equal(t, nil, err)
+	assertClose(t, f.Statistic, table[2].T*table[2].T, 1e-10)
+	assertClose(t, f.PValue, table[2].PValue, 1e-10)
+}
+
+func TestLinearHypothesisErrors(t *testing.T) {
+	// the third row is the sum of the first two, the fourth twice the first
+	l := NewDataFrame([][]float64{{0, 1, -1, 0}, {0, 0, 1, 1}, {0, 1, 0, 1}, {0, 2, -2, 0}})
+	_, err := LinearHypothesis(summary, l, []float64{0, 1, 1, 0})
+	assert.Equal(t, "hypotheses [2 3] are redundant, since they are linear combinations of the hypotheses before them", err.Error())
+	_, err = LinearHypothesis(summary, NewDataFrame([][]float64{{0, 1, 0, 0}, {0, 3, 0, 0}}), []float64{0, 0})
+	assert.Equal(t, "hypothesis 1 is redundant, since it is a linear combination of the hypotheses before it", err.Error())
+
+	_, err = LinearHypothesis(summary, NewDataFrame([][]float64{{0, 1, 0}}), []float64{0})
+	assert.NotEqual(t, nil, err)
+	_, err = LinearHypothesis(summary, NewDataFrame([][]float64{{0, 1, 0, 0}}), []float64{0, 1})
+	assert.NotEqual(t, nil, err)
+	_, err = LinearHypothesis(summary, NewDataFrame([][]float64{{0, math.NaN(), 0, 0}}), []float64{0})
+	assert.NotEqual(t, nil, err)
+	_, err = LinearHypothesis(summary, NewDataFrame([][]float64{{0, 1, 0, 0}}), []float64{0}, NewDataFrame([][]float64{{1}}))
+	assert.Equal(t, DimensionError, err)
+}
@@ -0,0 +1,328 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// isUnitLeverage reports whether an observation's leverage is numerically one,
+// in which case the model fits it exactly and deletion diagnostics are undefined.
+func isUnitLeverage(h float64) bool {
+	return 1-h < 1e-10
+}
+
+// deletedDF returns n - p - 1, the residual degrees of freedom of the model
+// fit without an observation, which the deletion diagnostics divide by. It is
+// an error if none are left.
+func deletedDF(m Summary) (int, error) {
+	df := m.Data().Rows() - m.Data().Cols() - 1
+	if df < 1 {
+		return 0, fmt.Errorf("%w: no residual degrees of freedom are left once an observation is deleted", TooFewObservationsError)
+	}
+	return df, nil
+}
+
+// deletedStudentized returns the residuals studentized by the residual
+// standard error of the model fit without observation i:
+//
+// s_{(i)}^2 = \frac{(n - p) s^2 - e_i^2 / (1 - h_{ii})}{n - p - 1}
+// t_{i} = \frac{e_i}{s_{(i)} \sqrt{1 - h_{ii}}}
+//
+// Observations with a leverage of one are NaN.
+func deletedStudentized(m Summary, h []float64) ([]float64, error) {
+	df, err := deletedDF(m)
+	if err != nil {
+		return nil, err
+	}
+	n := m.Data().Rows()
+	rss := m.SumOfSquares()
+	residuals := m.Residuals()
+	t := make([]float64, n)
+	for i, e := range residuals {
+		if isUnitLeverage(h[i]) {
+			t[i] = math.NaN()
+			continue
+		}
+		s2 := (rss - e*e/(1-h[i])) / float64(df)
+		t[i] = e / math.Sqrt(s2*(1-h[i]))
+	}
+	return t, nil
+}
+
+// ExternallyStudentizedResiduals returns the residuals studentized with sigma
+// estimated from the model fit without each observation (R's rstudent)
+//
+// t_{i} = \frac{e_i}{s_{(i)} \sqrt{1 - h_{ii}}}
+//
+// s_(i) comes from a closed form, so the model is not refit. Under normal errors
+// each t_i follows a t distribution with n - p - 1 degrees of freedom, so
+// there must be more than p + 1 observations. Observations with a leverage of
+// one are NaN.
+func ExternallyStudentizedResiduals(m Summary) ([]float64, error) {
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	return deletedStudentized(m, h)
+}
+
+// DFFITS measures how much the fitted value for each observation changes
+// when that observation is deleted, in units of its standard error:
+//
+// DFFITS_{i} = t_{i} * \sqrt{\frac{h_{ii}}{1 - h_{ii}}}
+//
+// where t_i is the externally studentized residual. An observation with a
+// leverage of one is fit exactly whether or not it is included, so the
+// measure is undefined and reported as NaN.
+//
+// Values larger than 2 * sqrt(p / n) in absolute value are considered influential.
+func DFFITS(m Summary) ([]float64, error) {
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	t, err := deletedStudentized(m, h)
+	if err != nil {
+		return nil, err
+	}
+	dffits := make([]float64, len(h))
+	for i := range h {
+		if isUnitLeverage(h[i]) {
+			dffits[i] = math.NaN()
+			continue
+		}
+		dffits[i] = t[i] * math.Sqrt(h[i]/(1-h[i]))
+	}
+	return dffits, nil
+}
+
+// dfbeta returns an n x p matrix whose ith row is \beta - \beta_{(i)}, the
+// change in the coefficients when the ith observation is deleted. It is found
+// without refitting the model, since
+//
+// \beta - \beta_{(i)} = (X'X)^-1 x_i e_i / (1 - h_ii)
+//
+// and (X'X)^-1 x_i = R^-1 q_i where q_i is the ith row of the thin Q. Rows for
+// observations with a leverage of one are NaN.
+func dfbeta(m Summary) (*mat.Dense, error) {
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	q := &mat.Dense{}
+	q.Mul(m.Data().X, rinv)
+
+	// row i of c is ((X'X)^-1 x_i)'
+	c := &mat.Dense{}
+	c.Mul(q, rinv.T())
+
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := c.Dims()
+	residuals := m.Residuals()
+	for i := 0; i < n; i++ {
+		if isUnitLeverage(h[i]) {
+			c.SetRow(i, rep(math.NaN(), p))
+			continue
+		}
+		for j := 0; j < p; j++ {
+			c.Set(i, j, c.At(i, j)*residuals[i]/(1-h[i]))
+		}
+	}
+	return c, nil
+}
+
+// DFBETA returns an n x p matrix whose (i, j) entry is the change in the jth
+// coefficient when the ith observation is deleted, \beta_j - \beta_{j(i)}, as
+// R's dfbeta does. Rows for observations with a leverage of one are NaN. The
+// columns are labeled by CoefficientNames, as are those of DFBETAS.
+func DFBETA(m Summary) (*DataFrame, error) {
+	c, err := dfbeta(m)
+	if err != nil {
+		return nil, err
+	}
+	df := MatToDF(c)
+	df.labels = CoefficientNames(m)
+	return df, nil
+}
+
+// DFBETAS returns an n x p matrix whose (i, j) entry is the standardized change
+// in the jth coefficient when the ith observation is deleted:
+//
+// DFBETAS_{ij} = \frac{\beta_j - \beta_{j(i)}}{s_{(i)} \sqrt{(X'X)^{-1}_{jj}}}
+//
+// The change in the coefficients is that of DFBETA. Rows for observations
+// with a leverage of one are NaN.
+func DFBETAS(m Summary) (*DataFrame, error) {
+	rdf, err := deletedDF(m)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dfbeta(m)
+	if err != nil {
+		return nil, err
+	}
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	xtx := &mat.Dense{}
+	xtx.Mul(rinv, rinv.T())
+
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := c.Dims()
+	rss := m.SumOfSquares()
+	residuals := m.Residuals()
+	for i := 0; i < n; i++ {
+		if isUnitLeverage(h[i]) {
+			continue
+		}
+		e := residuals[i]
+		si := math.Sqrt((rss - e*e/(1-h[i])) / float64(rdf))
+		for j := 0; j < p; j++ {
+			c.Set(i, j, c.At(i, j)/(si*math.Sqrt(xtx.At(j, j))))
+		}
+	}
+
+	df := MatToDF(c)
+	df.labels = CoefficientNames(m)
+	return df, nil
+}
+
+// deletedCoefficients returns an n x p matrix whose ith row is \beta_{(i)},
+// the coefficients of the model fit without the ith observation. Rows for
+// observations with a leverage of one are NaN.
+func deletedCoefficients(m Summary) (*mat.Dense, error) {
+	c, err := dfbeta(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := c.Dims()
+	betas := m.Coefficients()
+	for i := 0; i < n; i++ {
+		for j := 0; j < p; j++ {
+			c.Set(i, j, betas[j]-c.At(i, j))
+		}
+	}
+	return c, nil
+}
+
+// DFBETASCutoff returns the conventional size-adjusted cutoff 2 / sqrt(n),
+// above which (in absolute value) a DFBETAS entry is considered influential.
+func DFBETASCutoff(m Summary) float64 {
+	return 2 / math.Sqrt(float64(m.Data().Rows()))
+}
+
+// COVRATIO returns, for each observation, the ratio of the determinant of the
+// coefficient covariance matrix with the observation deleted to the determinant
+// with it included. It is found without refitting the model using
+//
+// COVRATIO_{i} = \frac{1}{(1 - h_{ii}) ((n - p - 1 + t_i^2) / (n - p))^p}
+//
+// where t_i is the externally studentized residual. Observations with a
+// leverage of one are NaN.
+func COVRATIO(m Summary) ([]float64, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	t, err := deletedStudentized(m, h)
+	if err != nil {
+		return nil, err
+	}
+	ratios := make([]float64, n)
+	for i := range ratios {
+		if isUnitLeverage(h[i]) {
+			ratios[i] = math.NaN()
+			continue
+		}
+		scale := (float64(n-p-1) + t[i]*t[i]) / float64(n-p)
+		ratios[i] = 1 / ((1 - h[i]) * math.Pow(scale, float64(p)))
+	}
+	return ratios, nil
+}
+
+// CovRatioOutliers returns the indices of observations whose COVRATIO lies
+// outside of 1 +/- 3p/n.
+func CovRatioOutliers(m Summary) ([]int, error) {
+	ratios, err := COVRATIO(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := m.Data().Rows(), m.Data().Cols()
+	cutoff := 3 * float64(p) / float64(n)
+	var outliers []int
+	for i, r := range ratios {
+		if math.Abs(r-1) > cutoff {
+			outliers = append(outliers, i)
+		}
+	}
+	return outliers, nil
+}
+
+// OutlierRecord is the test of an observation by OutlierTest.
+type OutlierRecord struct {
+	Observation         int     // the index of the observation, as in RetainedRows
+	StudentizedResidual float64 // its externally studentized residual t_i
+	PValue              float64 // the unadjusted two-sided p-value of t_i
+	BonferroniPValue    float64 // n times PValue, at most 1
+}
+
+// OutlierTest tests each observation for being an outlier, as car's
+// outlierTest does: under normal errors its externally studentized residual
+// t_i follows a t distribution on n - p - 1 degrees of freedom, and the
+// two-sided p-value of the largest |t_i| of n is corrected for the number of
+// observations by Bonferroni's bound. The records are those of the
+// observations whose Bonferroni p-value is below cutoff, 0.05 in car,
+// smallest first, or if there are none that of the largest |t_i| alone.
+// Observations with a leverage of one have no studentized residual, and are
+// left out of the test and of n.
+func OutlierTest(m Summary, cutoff float64) ([]OutlierRecord, error) {
+	if !(cutoff > 0) {
+		return nil, fmt.Errorf("cutoff %v is not positive", cutoff)
+	}
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+	df, err := deletedDF(m)
+	if err != nil {
+		return nil, err
+	}
+	studentized, err := deletedStudentized(m, h)
+	if err != nil {
+		return nil, err
+	}
+	var records []OutlierRecord
+	for i, t := range studentized {
+		if math.IsNaN(t) {
+			continue
+		}
+		records = append(records, OutlierRecord{
+			Observation:         i,
+			StudentizedResidual: t,
+			PValue:              studentTTwoSided(t, float64(df)),
+		})
+	}
+	sort.SliceStable(records, func(a, b int) bool { return records[a].PValue < records[b].PValue })
+	k := 0
+	for i := range records {
+		records[i].BonferroniPValue = math.Min(float64(len(records))*records[i].PValue, 1)
+		if records[i].BonferroniPValue < cutoff {
+			k++
+		}
+	}
+	if k == 0 {
+		k = 1
+	}
+	return records[:k], nil
+}
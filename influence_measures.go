@@ -0,0 +1,392 @@
+package glasso
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Influence collects the influence measures of every observation of a fit,
+// as R's influence.measures does, for an influence plot of the studentized
+// residuals against the leverages with the points sized by Cook's distance.
+// Each slice has an entry for every observation, and observations with a
+// leverage of one have NaN for all but their leverage.
+type Influence struct {
+	Names         []string    // of the coefficients, labeling the columns of DFBETAS
+	Leverage      []float64   // the diagonal of the hat matrix h_ii
+	Standardized  []float64   // the internally studentized residuals, as StandardizedResiduals
+	Studentized   []float64   // the externally studentized residuals, as ExternallyStudentizedResiduals
+	CooksDistance []float64   // as CooksDistance
+	DFFITS        []float64   // as DFFITS
+	COVRATIO      []float64   // as COVRATIO
+	DFBETAS       [][]float64 // a row of the standardized changes in the coefficients for each observation, as DFBETAS
+	Noteworthy    []bool      // the observations influential by any of the cutoffs of R's influence.measures
+	IDs           []string    // the RowIDs of the observations, or nil if the model has none
+}
+
+// InfluenceMeasures returns the influence measures of every observation of
+// the model, the same as LeveragePoints, StandardizedResiduals,
+// ExternallyStudentizedResiduals, CooksDistance, DFFITS, COVRATIO and DFBETAS
+// return, but from a single inverse of R rather than one for each. An
+// observation is noteworthy, as R's influence.measures marks it, if any of
+//
+// |DFBETAS_ij| > 1, |DFFITS_i| > 3 \sqrt{p / (n - p)}, |1 - COVRATIO_i| > 3p / (n - p),
+// F_{p, n-p}(D_i) > 0.5, h_ii > 3p / n
+//
+// holds, for p coefficients and the F distribution function F_{p, n-p}.
+func InfluenceMeasures(m Summary) (*Influence, error) {
+	mse, err := MseAdjusted(m)
+	if err != nil {
+		return nil, err
+	}
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	q := &mat.Dense{}
+	q.Mul(m.Data().X, rinv)
+	n, p := q.Dims()
+	// the diagonal of (X'X)^-1 = R^-1 R'^-1, and the rows ((X'X)^-1 x_i)' = q_i R'^-1 of c
+	xtx := make([]float64, p)
+	for j := range xtx {
+		for l := j; l < p; l++ {
+			xtx[j] += rinv.At(j, l) * rinv.At(j, l)
+		}
+	}
+	c := &mat.Dense{}
+	c.Mul(q, rinv.T())
+
+	inf := &Influence{
+		Names:         CoefficientNames(m),
+		Leverage:      make([]float64, n),
+		Standardized:  make([]float64, n),
+		Studentized:   make([]float64, n),
+		CooksDistance: make([]float64, n),
+		DFFITS:        make([]float64, n),
+		COVRATIO:      make([]float64, n),
+		DFBETAS:       make([][]float64, n),
+		Noteworthy:    make([]bool, n),
+		IDs:           rowIDs(m),
+	}
+	rss := m.SumOfSquares()
+	residuals := m.Residuals()
+	k, df := float64(p), float64(n-p)
+	for i := 0; i < n; i++ {
+		row := q.RawRowView(i)
+		h := sum(prod(row, row))
+		inf.Leverage[i] = h
+		inf.DFBETAS[i] = rep(math.NaN(), p)
+		if isUnitLeverage(h) {
+			for _, v := range [][]float64{inf.Standardized, inf.Studentized, inf.CooksDistance, inf.DFFITS, inf.COVRATIO} {
+				v[i] = math.NaN()
+			}
+			inf.Noteworthy[i] = h > 3*k/float64(n)
+			continue
+		}
+		e := residuals[i]
+		si := math.Sqrt((rss - e*e/(1-h)) / (df - 1))
+		inf.Standardized[i] = e / math.Sqrt(mse*(1-h))
+		t := e / (si * math.Sqrt(1-h))
+		inf.Studentized[i] = t
+		inf.CooksDistance[i] = e * e / (k * mse) * h / ((1 - h) * (1 - h))
+		inf.DFFITS[i] = t * math.Sqrt(h/(1-h))
+		inf.COVRATIO[i] = 1 / ((1 - h) * math.Pow((df-1+t*t)/df, k))
+
+		noteworthy := false
+		for j := 0; j < p; j++ {
+			inf.DFBETAS[i][j] = c.At(i, j) * e / (1 - h) / (si * math.Sqrt(xtx[j]))
+			noteworthy = noteworthy || math.Abs(inf.DFBETAS[i][j]) > 1
+		}
+		inf.Noteworthy[i] = noteworthy ||
+			math.Abs(inf.DFFITS[i]) > 3*math.Sqrt(k/df) ||
+			math.Abs(1-inf.COVRATIO[i]) > 3*k/df ||
+			fSurvival(inf.CooksDistance[i], k, df) < 0.5 ||
+			h > 3*k/float64(n)
+	}
+	return inf, nil
+}
+
+// influenceColumns are the columns of the measures that WriteCSV writes
+// before those of DFBETAS.
+var influenceColumns = []string{"leverage", "standardized", "studentized", "cooks_distance", "dffits", "covratio"}
+
+func (inf *Influence) measures(i int) []float64 {
+	return []float64{inf.Leverage[i], inf.Standardized[i], inf.Studentized[i], inf.CooksDistance[i], inf.DFFITS[i], inf.COVRATIO[i]}
+}
+
+// WriteCSV writes the measures as CSV with a header and a row for each
+// observation: its index, its id if the measures have IDs, the measures, a
+// column dfbetas_<name> for each coefficient and whether it is noteworthy. NaN is written as NA, as R's
+// read.csv reads it.
+func (inf *Influence) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := []string{"observation"}
+	if inf.IDs != nil {
+		header = append(header, "id")
+	}
+	header = append(header, influenceColumns...)
+	for _, name := range inf.Names {
+		header = append(header, "dfbetas_"+name)
+	}
+	header = append(header, "noteworthy")
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for i := range inf.Leverage {
+		record := []string{strconv.Itoa(i)}
+		if inf.IDs != nil {
+			record = append(record, inf.IDs[i])
+		}
+		for _, v := range append(inf.measures(i), inf.DFBETAS[i]...) {
+			record = append(record, formatCSVFloat(v))
+		}
+		record = append(record, strconv.FormatBool(inf.Noteworthy[i]))
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// formatCSVFloat formats v for CSV, with NaN as NA, as R's read.csv reads it.
+func formatCSVFloat(v float64) string {
+	if math.IsNaN(v) {
+		return "NA"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// jsonFloat is a float64 that is encoded as null when it isn't finite, which
+// JSON has no number for.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v)
+}
+
+// influenceRecord is an observation of an Influence as WriteJSON encodes it.
+type influenceRecord struct {
+	Observation   int                  `json:"observation"`
+	ID            string               `json:"id,omitempty"`
+	Leverage      jsonFloat            `json:"leverage"`
+	Standardized  jsonFloat            `json:"standardized"`
+	Studentized   jsonFloat            `json:"studentized"`
+	CooksDistance jsonFloat            `json:"cooks_distance"`
+	DFFITS        jsonFloat            `json:"dffits"`
+	COVRATIO      jsonFloat            `json:"covratio"`
+	DFBETAS       map[string]jsonFloat `json:"dfbetas"`
+	Noteworthy    bool                 `json:"noteworthy"`
+}
+
+// WriteJSON writes the measures as a JSON array with an object for each
+// observation, whose DFBETAS are keyed by the names of the coefficients, and
+// with its id if the measures have IDs. NaN is written as null.
+func (inf *Influence) WriteJSON(w io.Writer) error {
+	records := make([]influenceRecord, len(inf.Leverage))
+	for i := range records {
+		dfbetas := make(map[string]jsonFloat, len(inf.Names))
+		for j, name := range inf.Names {
+			dfbetas[name] = jsonFloat(inf.DFBETAS[i][j])
+		}
+		id := ""
+		if inf.IDs != nil {
+			id = inf.IDs[i]
+		}
+		records[i] = influenceRecord{
+			Observation:   i,
+			ID:            id,
+			Leverage:      jsonFloat(inf.Leverage[i]),
+			Standardized:  jsonFloat(inf.Standardized[i]),
+			Studentized:   jsonFloat(inf.Studentized[i]),
+			CooksDistance: jsonFloat(inf.CooksDistance[i]),
+			DFFITS:        jsonFloat(inf.DFFITS[i]),
+			COVRATIO:      jsonFloat(inf.COVRATIO[i]),
+			DFBETAS:       dfbetas,
+			Noteworthy:    inf.Noteworthy[i],
+		}
+	}
+	return json.NewEncoder(w).Encode(records)
+}
+
+// Format is a format of WriteDiagnostics.
+type Format int
+
+const (
+	// CSVFormat is CSV with a header, NaN written as NA, the default.
+	CSVFormat Format = iota
+	// JSONLinesFormat is a JSON object on a line for each observation, NaN
+	// written as null.
+	JSONLinesFormat
+)
+
+func (f Format) String() string {
+	switch f {
+	case CSVFormat:
+		return "csv"
+	case JSONLinesFormat:
+		return "json-lines"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// diagnosticsColumns are the columns of WriteDiagnostics.
+var diagnosticsColumns = []string{"row", "y", "fitted", "residual", "standardized", "studentized", "leverage", "cooks_distance", "dffits"}
+
+// diagnosticsRecord is an observation as WriteDiagnostics encodes it in JSON.
+type diagnosticsRecord struct {
+	Row           int       `json:"row"`
+	ID            string    `json:"id,omitempty"`
+	Y             jsonFloat `json:"y"`
+	Fitted        jsonFloat `json:"fitted"`
+	Residual      jsonFloat `json:"residual"`
+	Standardized  jsonFloat `json:"standardized"`
+	Studentized   jsonFloat `json:"studentized"`
+	Leverage      jsonFloat `json:"leverage"`
+	CooksDistance jsonFloat `json:"cooks_distance"`
+	DFFITS        jsonFloat `json:"dffits"`
+}
+
+// WriteDiagnostics writes the diagnostics of every observation of the model
+// in the format: the row of the training data it is, as in RetainedRows when
+// rows were dropped, its id if the model has RowIDs, its response, fitted
+// value and residual, its standardized and studentized residuals, leverage,
+// Cook's distance and DFFITS. The measures are those of InfluenceMeasures, found in a single pass
+// over the factorization. The response, fitted values and residuals are the
+// summary's, so those of a weighted fit are on the transformed scale.
+func WriteDiagnostics(m Summary, w io.Writer, format Format) error {
+	if format != CSVFormat && format != JSONLinesFormat {
+		return fmt.Errorf("unknown format %v", format)
+	}
+	inf, err := InfluenceMeasures(m)
+	if err != nil {
+		return err
+	}
+	n := m.Data().Rows()
+	rows := identityRows(n)
+	if r, ok := m.(interface{ RetainedRows() []int }); ok {
+		rows = r.RetainedRows()
+	}
+	ids := inf.IDs
+	response, fitted, residuals := m.Response(), m.Yhat(), m.Residuals()
+
+	if format == JSONLinesFormat {
+		out := bufio.NewWriter(w)
+		enc := json.NewEncoder(out)
+		for i := 0; i < n; i++ {
+			record := diagnosticsRecord{
+				Row:           rows[i],
+				Y:             jsonFloat(response[i]),
+				Fitted:        jsonFloat(fitted[i]),
+				Residual:      jsonFloat(residuals[i]),
+				Standardized:  jsonFloat(inf.Standardized[i]),
+				Studentized:   jsonFloat(inf.Studentized[i]),
+				Leverage:      jsonFloat(inf.Leverage[i]),
+				CooksDistance: jsonFloat(inf.CooksDistance[i]),
+				DFFITS:        jsonFloat(inf.DFFITS[i]),
+			}
+			if ids != nil {
+				record.ID = ids[i]
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return out.Flush()
+	}
+
+	out := csv.NewWriter(w)
+	header := diagnosticsColumns
+	if ids != nil {
+		header = append([]string{"row", "id"}, diagnosticsColumns[1:]...)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		record := []string{strconv.Itoa(rows[i])}
+		if ids != nil {
+			record = append(record, ids[i])
+		}
+		for _, v := range []float64{response[i], fitted[i], residuals[i], inf.Standardized[i], inf.Studentized[i],
+			inf.Leverage[i], inf.CooksDistance[i], inf.DFFITS[i]} {
+			record = append(record, formatCSVFloat(v))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// FlagThresholds are the cutoffs of FlagInfluential. A cutoff of zero is the
+// conventional one given for it.
+type FlagThresholds struct {
+	Leverage    float64 // h_ii above it, 2p / n
+	Cooks       float64 // D_i above it, 4 / n
+	DFFITS      float64 // |DFFITS_i| above it, 2\sqrt{p / n}
+	Studentized float64 // |t_i| of the externally studentized residual above it, 2
+}
+
+// FlagInfluential returns the observations of the model that are influential
+// or outlying by any of the rules of thumb of FlagThresholds, in order, with
+// the reasons each was flagged for, named as the columns of
+// Influence.WriteCSV: "leverage", "cooks_distance", "dffits" and
+// "studentized". WithFlagThresholds overrides the cutoffs. The measures are
+// those of InfluenceMeasures; an observation with a leverage of one is
+// flagged for its leverage alone. OLS.Refit fits the model without the
+// flagged observations, to see how much they move it. The observations are
+// indices, as in RetainedRows; for a model with RowIDs, the kth of them is
+// the id of observation k.
+func FlagInfluential(m Summary, opts ...Option) ([]int, map[int][]string, error) {
+	inf, err := InfluenceMeasures(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	n, p := float64(len(inf.Leverage)), float64(len(inf.Names))
+	cutoffs := newOptions(opts).thresholds
+	cutoff := func(c, conventional float64) float64 {
+		if c == 0 {
+			return conventional
+		}
+		return c
+	}
+	leverage := cutoff(cutoffs.Leverage, 2*p/n)
+	cooks := cutoff(cutoffs.Cooks, 4/n)
+	dffits := cutoff(cutoffs.DFFITS, 2*math.Sqrt(p/n))
+	studentized := cutoff(cutoffs.Studentized, 2)
+
+	var flagged []int
+	reasons := make(map[int][]string)
+	for i, h := range inf.Leverage {
+		var why []string
+		if h > leverage {
+			why = append(why, "leverage")
+		}
+		if inf.CooksDistance[i] > cooks {
+			why = append(why, "cooks_distance")
+		}
+		if math.Abs(inf.DFFITS[i]) > dffits {
+			why = append(why, "dffits")
+		}
+		if math.Abs(inf.Studentized[i]) > studentized {
+			why = append(why, "studentized")
+		}
+		if why != nil {
+			flagged = append(flagged, i)
+			reasons[i] = why
+		}
+	}
+	return flagged, reasons, nil
+}
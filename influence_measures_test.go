@@ -0,0 +1,284 @@
+package glasso
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestInfluenceMeasures(t *testing.T) {
+	// the measures of R's influence.measures(lm(stack.loss ~ ., stackloss)),
+	// rstandard and rstudent by their definitions, evaluated in exact
+	// rational arithmetic up to the square roots; they are not R's output,
+	// which hasn't been run against them
+	want := struct {
+		hat, rstandard, rstudent, cook, dffit, covr []float64
+		dfbetas                                     [][]float64
+	}{
+		hat:       []float64{0.30155546893633345, 0.31784095844273536, 0.1746150054187583, 0.12850524308117023, 0.052220329600913815, 0.07748736213067939, 0.21923675949669244, 0.21923675949669244, 0.1401836345538369, 0.20004426210783144, 0.15503318478358438, 0.21717596532330735, 0.15753145936440766, 0.20582287753623849, 0.19046486329606185, 0.13107370179326994, 0.41212349785785957, 0.1605927246366633, 0.17453662924310212, 0.08018585017451553, 0.2845334627253461},
+		rstandard: []float64{1.1933392878675189, -0.7158030434165223, 1.5460204390998051, 1.8818160220034295, -0.5420838858185226, -0.9652568149982805, -0.8337771468303237, -0.484842061298036, -1.0455296159206433, 0.4368320323456799, 0.8842575731219321, 0.9685738252223629, -0.4798729004417244, -0.017471528863220547, 0.8092065754177452, 0.29935451081385966, -0.6112104041234638, -0.15315037580968746, -0.20302189876676513, 0.45397743671070895, -2.6382199811638216},
+		rstudent:  []float64{1.2094746739174729, -0.7051385660136401, 1.6179041089532953, 2.0517974810996003, -0.5305036358942826, -0.9632037885213, -0.8259467182796171, -0.47365206134466614, -1.0485858536979569, 0.4261880156884119, 0.8782920379233278, 0.9667067200179762, -0.46873057667111895, -0.016950024871889548, 0.8006163924410614, 0.2911850181843836, -0.5995857905163456, -0.14868029158992388, -0.19719938055579286, 0.4431170085598583, -3.3304933193280513},
+		cook:      []float64{0.15371037236820223, 0.059683091608855814, 0.12641408444864238, 0.13054204179874862, 0.004047671168333566, 0.01956520100279014, 0.04880159310209831, 0.01650192476980178, 0.04455580513626184, 0.01192968850723892, 0.03586597159825443, 0.06506584512065877, 0.010764802405339011, 1.9777805515314848e-05, 0.038515716445870714, 0.0033794361512767324, 0.06547307839404698, 0.001121835760453422, 0.002178785903964015, 0.004491652996856842, 0.6919999163395094},
+		dffit:     []float64{0.7947205126436674, -0.481322960247821, 0.744158204437289, 0.7878844455896744, -0.12452440480564263, -0.27915631590777623, -0.43767227866435215, -0.25099001230319856, -0.42339897155928596, 0.21312348240526197, 0.3762109686669388, 0.5091767237415982, -0.20268895642902662, -0.008628960410081205, 0.38834172991362464, 0.11309289931264366, -0.5020210987541314, -0.06503242861085765, -0.09067758294704975, 0.13083298384984862, -2.1002963528996896},
+		covr:      []float64{1.2858945642234085, 1.6529774915314877, 0.8422539502603033, 0.5744822009524154, 1.2540581437506568, 1.1026183888969194, 1.3812245370366254, 1.5438585244958054, 1.1361954652953932, 1.5227899185130667, 1.24933551246739, 1.2972980821072542, 1.432418196768547, 1.6046014829709552, 1.345376067016819, 1.4359896247215511, 1.983486041007082, 1.5098874399589728, 1.5289768289438919, 1.3195525753906354, 0.21668566482728518},
+		dfbetas: [][]float64{
+			{-0.08511854274750792, 0.40023362626208764, 0.10331686343866774, -0.2096731593564801},
+			{0.01312099649371609, -0.2500534868721133, -0.060942272490011475, 0.1648339222567588},
+			{-0.18831441079238204, 0.3904870572873683, -0.004633319454997938, -0.04678867571385855},
+			{-0.12178092697995088, -0.4149487332180541, 0.6187948469548018, 0.027112936580213638},
+			{0.011782329545853632, 0.011906271706968879, -0.029754426148994814, -0.006699639775452823},
+			{0.03862473200227139, 0.10562184952025541, -0.16855044876764427, -0.012350321127562113},
+			{0.2954640233889689, 0.26807297234641975, -0.2632195648692837, -0.2817963326944709},
+			{0.16943846453300623, 0.1537306379849162, -0.15094737557196136, -0.16160051357564356},
+			{0.07727050004908699, 0.3071831151622534, -0.32842309543034703, -0.07767009548441775},
+			{0.1526085380977107, 0.1239145215316091, -0.13198595114194475, -0.12805708615026098},
+			{-0.06760739721978325, 0.10802620164439468, -0.26457858858827676, 0.15761311812618783},
+			{-0.011382265194130232, 0.23257754682011164, -0.42653800314681234, 0.12755974173456305},
+			{-0.1191775705987733, -0.11611994885407563, 0.14144199251170742, 0.08802243321063506},
+			{0.004972593069978061, 0.0006797936840412465, 0.0031079334407767265, -0.006804120373056294},
+			{-0.11602274080772813, -0.1953865052595993, -0.02865778318122525, 0.24710053948461194},
+			{-1.4499085563422836e-05, -0.05328557044762932, -0.01006858539135967, 0.04158590540015782},
+			{-0.46241343376321287, 0.019868125012480965, -0.06343197683927837, 0.4234511764049532},
+			{-0.046605391596593854, 0.022675607398180215, -0.013173338083098281, 0.03313345890607054},
+			{-0.04923732878059737, 0.05199088587846201, -0.04224954396577963, 0.033851769622052864},
+			{0.08532625880798121, -0.010497979680350936, 0.0051497153826078786, -0.06659613040343484},
+			{0.40159543503715356, -1.6238263051708972, 1.6419272744301487, -0.36331697966469256},
+		},
+	}
+	inf, err := InfluenceMeasures(summary)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, inf.Leverage, want.hat, 1e-12)
+	assertCloseSlices(t, inf.Standardized, want.rstandard, 1e-12)
+	assertCloseSlices(t, inf.Studentized, want.rstudent, 1e-12)
+	assertCloseSlices(t, inf.CooksDistance, want.cook, 1e-12)
+	assertCloseSlices(t, inf.DFFITS, want.dffit, 1e-12)
+	assertCloseSlices(t, inf.COVRATIO, want.covr, 1e-12)
+	for i, row := range want.dfbetas {
+		assertCloseSlices(t, inf.DFBETAS[i], row, 1e-11)
+	}
+	// of the rules of influence.measures' is.inf, |1 - covr| > 3p / (n - p)
+	// flags observations 17 and 21, and |dffit| > 3 sqrt(p / (n - p)) and
+	// |dfbetas| > 1 flag 21; no hat exceeds 3p / n, nor Cook's distance the
+	// median of F(p, n - p)
+	var noteworthy []int
+	for i, b := range inf.Noteworthy {
+		if b {
+			noteworthy = append(noteworthy, i)
+		}
+	}
+	assert.Equal(t, []int{16, 20}, noteworthy)
+	assert.Equal(t, CoefficientNames(summary), inf.Names)
+
+	// the same as the measures one at a time
+	for _, c := range []struct {
+		got []float64
+		fn  func(Summary) ([]float64, error)
+	}{
+		{inf.Leverage, LeveragePoints},
+		{inf.Standardized, StandardizedResiduals},
+		{inf.Studentized, ExternallyStudentizedResiduals},
+		{inf.CooksDistance, func(m Summary) ([]float64, error) { return CooksDistance(m) }},
+		{inf.DFFITS, DFFITS},
+		{inf.COVRATIO, COVRATIO},
+	} {
+		want, err := c.fn(summary)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, c.got, want, 1e-12)
+	}
+	dfbetas, err := DFBETAS(summary)
+	assert.Equal(t, nil, err)
+	for i := range inf.DFBETAS {
+		assertCloseSlices(t, inf.DFBETAS[i], dfbetas.GetRow(i), 1e-12)
+	}
+}
+
+func TestInfluenceMeasuresUnitLeverage(t *testing.T) {
+	inf, err := InfluenceMeasures(unitLeverageSummary(t))
+	assert.Equal(t, nil, err)
+	assertClose(t, inf.Leverage[6], 1, 1e-10)
+	for _, v := range []float64{inf.Standardized[6], inf.Studentized[6], inf.CooksDistance[6], inf.DFFITS[6], inf.COVRATIO[6], inf.DFBETAS[6][0]} {
+		assert.T(t, math.IsNaN(v))
+	}
+	// with three coefficients and seven observations 3p / n is more than one,
+	// so no leverage is noteworthy by itself
+	assert.T(t, !inf.Noteworthy[6])
+	assert.T(t, !math.IsNaN(inf.CooksDistance[0]))
+
+	// NaN is NA in CSV and null in JSON
+	var buf bytes.Buffer
+	assert.Equal(t, nil, inf.WriteCSV(&buf))
+	df, err := ReadCSV(strings.NewReader(buf.String()))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 7, df.Rows())
+	assert.T(t, math.IsNaN(df.X.At(6, 2)))
+
+	buf.Reset()
+	assert.Equal(t, nil, inf.WriteJSON(&buf))
+	var records []map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal(buf.Bytes(), &records))
+	assert.Equal(t, nil, records[6]["cooks_distance"])
+}
+
+func TestInfluenceWriters(t *testing.T) {
+	inf, err := InfluenceMeasures(summary)
+	assert.Equal(t, nil, err)
+
+	var buf bytes.Buffer
+	assert.Equal(t, nil, inf.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 22, len(lines))
+	header := strings.Split(lines[0], ",")
+	assert.Equal(t, "observation", header[0])
+	assert.Equal(t, "dfbetas_"+inf.Names[1], header[8])
+	assert.Equal(t, "noteworthy", header[len(header)-1])
+	assert.T(t, strings.HasSuffix(lines[21], ",true"))
+
+	buf.Reset()
+	assert.Equal(t, nil, inf.WriteJSON(&buf))
+	var records []struct {
+		Observation int                `json:"observation"`
+		Leverage    float64            `json:"leverage"`
+		Studentized float64            `json:"studentized"`
+		DFBETAS     map[string]float64 `json:"dfbetas"`
+		Noteworthy  bool               `json:"noteworthy"`
+	}
+	assert.Equal(t, nil, json.Unmarshal(buf.Bytes(), &records))
+	assert.Equal(t, 21, len(records))
+	for i, r := range records {
+		assert.Equal(t, i, r.Observation)
+		assert.Equal(t, inf.Leverage[i], r.Leverage)
+		assert.Equal(t, inf.Studentized[i], r.Studentized)
+		assert.Equal(t, inf.DFBETAS[i][1], r.DFBETAS[inf.Names[1]])
+		assert.Equal(t, inf.Noteworthy[i], r.Noteworthy)
+	}
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	rows, response := stacklossMissing()
+	_, s, err := NewOlsTrainer(WithNAPolicy(OmitRows)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	retained := s.(OlsSummary).RetainedRows()
+	n := len(retained)
+	standardized, err := StandardizedResiduals(s)
+	assert.Equal(t, nil, err)
+	studentized, err := ExternallyStudentizedResiduals(s)
+	assert.Equal(t, nil, err)
+	leverage, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	cooks, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	dffits, err := DFFITS(s)
+	assert.Equal(t, nil, err)
+	// the columns after the row, by the individual functions
+	expected := func(k int) []float64 {
+		return []float64{s.Response()[k], s.Yhat()[k], s.Residuals()[k], standardized[k], studentized[k], leverage[k], cooks[k], dffits[k]}
+	}
+
+	var buf bytes.Buffer
+	assert.Equal(t, nil, WriteDiagnostics(s, &buf, CSVFormat))
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, n+1, len(records))
+	assert.Equal(t, diagnosticsColumns, records[0])
+	for k, record := range records[1:] {
+		assert.Equal(t, strconv.Itoa(retained[k]), record[0])
+		for j, want := range expected(k) {
+			v, err := strconv.ParseFloat(record[j+1], 64)
+			assert.Equal(t, nil, err)
+			assertClose(t, v, want, 1e-10)
+		}
+	}
+	assert.Equal(t, "19", records[len(records)-1][0])
+
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(s, &buf, JSONLinesFormat))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, n, len(lines))
+	for k, line := range lines {
+		var r struct {
+			Row                                 int
+			Y, Fitted, Residual                 float64
+			Standardized, Studentized, Leverage float64
+			CooksDistance                       float64 `json:"cooks_distance"`
+			DFFITS                              float64
+		}
+		assert.Equal(t, nil, json.Unmarshal([]byte(line), &r))
+		assert.Equal(t, retained[k], r.Row)
+		for j, v := range []float64{r.Y, r.Fitted, r.Residual, r.Standardized, r.Studentized, r.Leverage, r.CooksDistance, r.DFFITS} {
+			assertClose(t, v, expected(k)[j], 1e-10)
+		}
+	}
+
+	// an observation with a leverage of one has no residual diagnostics
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(unitLeverageSummary(t), &buf, CSVFormat))
+	records, err = csv.NewReader(&buf).ReadAll()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "NA", records[7][4])
+	assert.Equal(t, "NA", records[7][8])
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(unitLeverageSummary(t), &buf, JSONLinesFormat))
+	assert.T(t, strings.Contains(buf.String(), `"studentized":null`))
+
+	assert.NotEqual(t, nil, WriteDiagnostics(s, &buf, Format(5)))
+}
+
+// plantedInfluence returns y = 1 + 2x on x = 1, ..., 20 with small errors and
+// 15 added to the tenth observation, and with two observations far out in x
+// that lie on the line.
+func plantedInfluence() ([][]float64, []float64) {
+	var rows [][]float64
+	var response []float64
+	for i := 1; i <= 20; i++ {
+		rows = append(rows, []float64{float64(i)})
+		response = append(response, 1+2*float64(i)+math.Sin(float64(7*i)))
+	}
+	response[9] += 15
+	rows = append(rows, []float64{60}, []float64{70})
+	response = append(response, 121, 141)
+	return rows, response
+}
+
+func TestFlagInfluential(t *testing.T) {
+	rows, response := plantedInfluence()
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	flagged, reasons, err := FlagInfluential(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{9, 20, 21}, flagged)
+	assert.Equal(t, []string{"cooks_distance", "dffits", "studentized"}, reasons[9])
+	assert.Equal(t, []string{"leverage"}, reasons[20])
+	assert.Equal(t, []string{"leverage"}, reasons[21])
+
+	// the cutoffs can be overridden one at a time
+	flagged, reasons, err = FlagInfluential(s, WithFlagThresholds(FlagThresholds{Leverage: 0.9, Studentized: 100}))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []int{9}, flagged)
+	assert.Equal(t, []string{"cooks_distance", "dffits"}, reasons[9])
+
+	// the fit without the flagged observations is that of the others
+	refit, err := m.(*OLS).Refit([]int{21, 9, 20, 9})
+	assert.Equal(t, nil, err)
+	var keptRows [][]float64
+	var kept []float64
+	for i := range rows {
+		if i != 9 && i < 20 {
+			keptRows = append(keptRows, rows[i])
+			kept = append(kept, response[i])
+		}
+	}
+	want, _, err := NewOlsTrainer().Train(NewDataFrame(keptRows), kept)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, refit.Coefficients(), want.(*OLS).Coefficients(), 1e-10)
+	assert.Equal(t, 19, refit.NumObs())
+	assertCloseSlices(t, refit.Residuals(), want.(*OLS).Residuals(), 1e-8)
+
+	_, err = m.(*OLS).Refit([]int{22})
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,280 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/ematvey/gostat"
+	"gonum.org/v1/gonum/mat"
+)
+
+// withoutRow returns copies of the stackloss data and response with row i removed.
+func withoutRow(i int) ([][]float64, []float64) {
+	rows := make([][]float64, 0, len(data)-1)
+	response := make([]float64, 0, len(y)-1)
+	for j := range data {
+		if j != i {
+			rows = append(rows, data[j])
+			response = append(response, y[j])
+		}
+	}
+	return rows, response
+}
+
+// looFit refits the stackloss model without row i.
+func looFit(t *testing.T, i int) (Model, Summary) {
+	rows, response := withoutRow(i)
+	model, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	return model, s
+}
+
+func assertClose(t *testing.T, x, y, tol float64) {
+	if math.Abs(x-y) > tol {
+		t.Errorf("%v and %v differ by more than %v", x, y, tol)
+	}
+}
+
+// unitLeverageSummary fits a model where the last row is the only one with a
+// nonzero indicator, so it is fit exactly and has a leverage of one.
+func unitLeverageSummary(t *testing.T) Summary {
+	rows := [][]float64{{0, 1.2}, {0, 2.3}, {0, 2.9}, {0, 4.1}, {0, 5.2}, {0, 5.8}, {1, 7.1}}
+	response := []float64{1.1, 2.0, 3.2, 3.9, 5.1, 6.2, 9.0}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func TestDFFITS(t *testing.T) {
+	dffits, err := DFFITS(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(dffits), 21)
+
+	fitted := summary.Yhat()
+	leverage, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	for i := range dffits {
+		// (yhat_i - yhat_i(i)) / (s_(i) * sqrt(h_ii)) from an actual refit
+		model, s := looFit(t, i)
+		h := leverage[i]
+		si := math.Sqrt(s.SumOfSquares() / float64(s.Data().Rows()-s.Data().Cols()))
+		expected := (fitted[i] - model.Predict(data[i])) / (si * math.Sqrt(h))
+		assertClose(t, dffits[i], expected, 1e-8)
+	}
+}
+
+func TestDFFITSUnitLeverage(t *testing.T) {
+	s := unitLeverageSummary(t)
+	dffits, err := DFFITS(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.IsNaN(dffits[6]))
+	for _, d := range dffits[:6] {
+		assert.T(t, !math.IsNaN(d) && !math.IsInf(d, 0))
+	}
+}
+
+func TestDFBETAS(t *testing.T) {
+	dfbetas, err := DFBETAS(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, dfbetas.Rows(), 21)
+	assert.Equal(t, dfbetas.Cols(), 4)
+
+	xtx, err := xtxInverse(summary)
+	assert.Equal(t, nil, err)
+	betas := summary.Coefficients()
+	for i := 0; i < dfbetas.Rows(); i++ {
+		_, s := looFit(t, i)
+		si := math.Sqrt(s.SumOfSquares() / float64(s.Data().Rows()-s.Data().Cols()))
+		for j, b := range s.Coefficients() {
+			expected := (betas[j] - b) / (si * math.Sqrt(xtx.At(j, j)))
+			assertClose(t, dfbetas.X.At(i, j), expected, 1e-8)
+		}
+	}
+
+	assertClose(t, DFBETASCutoff(summary), 2/math.Sqrt(21), 1e-12)
+}
+
+func TestDFBETA(t *testing.T) {
+	dfbeta, err := DFBETA(summary)
+	assert.Equal(t, nil, err)
+	betas := summary.Coefficients()
+	for i := 0; i < dfbeta.Rows(); i++ {
+		_, s := looFit(t, i)
+		for j, b := range s.Coefficients() {
+			assertClose(t, dfbeta.X.At(i, j), betas[j]-b, 1e-9)
+		}
+	}
+
+	dfbeta, err = DFBETA(unitLeverageSummary(t))
+	assert.Equal(t, nil, err)
+	for j := 0; j < dfbeta.Cols(); j++ {
+		assert.T(t, math.IsNaN(dfbeta.X.At(6, j)))
+		assert.T(t, !math.IsNaN(dfbeta.X.At(0, j)))
+	}
+}
+
+func TestCOVRATIO(t *testing.T) {
+	ratios, err := COVRATIO(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(ratios), 21)
+
+	full, err := VarCov(summary)
+	assert.Equal(t, nil, err)
+	det := mat.Det(full.X)
+	var expected []int
+	for i := range ratios {
+		_, s := looFit(t, i)
+		vc, err := VarCov(s)
+		assert.Equal(t, nil, err)
+		ratio := mat.Det(vc.X) / det
+		assertClose(t, ratios[i], ratio, 1e-8)
+
+		if math.Abs(ratio-1) > 3*4/21.0 {
+			expected = append(expected, i)
+		}
+	}
+
+	assert.T(t, len(expected) > 0)
+	outliers, err := CovRatioOutliers(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, outliers, expected)
+}
+
+func TestPress(t *testing.T) {
+	press, err := Press(summary)
+	assert.Equal(t, nil, err)
+
+	// sum of squared residuals from genuine leave-one-out refits
+	expected := 0.0
+	for i := range data {
+		model, _ := looFit(t, i)
+		e := y[i] - model.Predict(data[i])
+		expected += e * e
+	}
+	assertClose(t, press, expected, 1e-8)
+
+	r2, err := PredictedRSquared(summary)
+	assert.Equal(t, nil, err)
+	assertClose(t, r2, 1-expected/totalSumOfSquares(y), 1e-10)
+	assert.T(t, r2 < summary.(OlsSummary).RSquared())
+}
+
+func TestPressUnitLeverage(t *testing.T) {
+	s := unitLeverageSummary(t)
+	residuals, err := PressResiduals(s)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.IsNaN(residuals[6]))
+	_, err = Press(s)
+	assert.Equal(t, LeverageError, err)
+	_, err = PredictedRSquared(s)
+	assert.Equal(t, LeverageError, err)
+}
+
+func TestExternallyStudentizedResiduals(t *testing.T) {
+	students, err := ExternallyStudentizedResiduals(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(students), 21)
+
+	h, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	residuals := summary.Residuals()
+	for i := range students {
+		_, s := looFit(t, i)
+		si := math.Sqrt(s.SumOfSquares() / float64(s.Data().Rows()-s.Data().Cols()))
+		assertClose(t, students[i], residuals[i]/(si*math.Sqrt(1-h[i])), 1e-8)
+	}
+
+	// the outlying last observation stands out more once it can't inflate sigma
+	internal, err := InternallyStudentizedResiduals(summary)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.Abs(students[20]) > math.Abs(internal[20]))
+}
+
+func TestExternallyStudentizedDistribution(t *testing.T) {
+	// with gaussian errors t_i ~ t(n - p - 1), so |t_i| should exceed the
+	// two sided critical value at the nominal rate
+	const reps = 2000
+	n, p := 15, 3
+	df := float64(n - p - 1)
+	prob := stat.F_CDF(1, df) // t^2 ~ F(1, df)
+
+	rejected05, rejected20 := 0, 0
+	for rep := 0; rep < reps; rep++ {
+		s := simulatedSummary(n, p-1, int64(rep))
+		students, _ := ExternallyStudentizedResiduals(s)
+		ti := students[0]
+		pval := 1 - prob(ti*ti)
+		if pval < 0.05 {
+			rejected05++
+		}
+		if pval < 0.2 {
+			rejected20++
+		}
+	}
+
+	assertClose(t, float64(rejected05)/reps, 0.05, 0.015)
+	assertClose(t, float64(rejected20)/reps, 0.2, 0.03)
+}
+
+func TestOutlierTest(t *testing.T) {
+	// y = 3 + 0.7x plus small errors, with 4 added to the eighth observation;
+	// the statistics of car's outlierTest(lm(y ~ x)) are from their
+	// definitions in exact arithmetic, and the p-values by quadrature
+	rows := make([][]float64, 12)
+	for i := range rows {
+		rows[i] = []float64{float64(i + 1)}
+	}
+	response := []float64{4.0, 4.2, 5.2, 5.4, 6.7, 7.25, 7.8, 12.95, 9.05, 10.15, 10.4, 11.5}
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	records, err := OutlierTest(m, 0.05)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, 7, records[0].Observation)
+	assertClose(t, records[0].StudentizedResidual, 17.504178642880458, 1e-10)
+	assertCloseSlices(t, []float64{records[0].PValue, records[0].BonferroniPValue}, []float64{2.931901828647054e-08, 3.518282194376465e-07}, 1e-7)
+
+	// with no observation below the cutoff, the largest residual is reported
+	// alone; with a cutoff above one, every observation is, in order
+	records, err = OutlierTest(m, 1e-9)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, 7, records[0].Observation)
+	records, err = OutlierTest(m, 1.5)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 12, len(records))
+	assert.Equal(t, 10, records[1].Observation)
+	assertClose(t, records[1].PValue, 0.5288679240974321, 1e-9)
+	assert.Equal(t, 1.0, records[1].BonferroniPValue)
+	for i := 1; i < len(records); i++ {
+		assert.T(t, records[i].PValue >= records[i-1].PValue)
+	}
+
+	_, err = OutlierTest(m, 0)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestDeletionDiagnosticsTooFewObservations(t *testing.T) {
+	// n = p + 1 leaves a residual degree of freedom, but none once an
+	// observation is deleted
+	_, m, err := NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {2}, {4}}), []float64{1, 3, 2})
+	assert.Equal(t, nil, err)
+	_, err = ExternallyStudentizedResiduals(m)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = DFFITS(m)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = DFBETAS(m)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = COVRATIO(m)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = OutlierTest(m, 0.05)
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+
+	// the influence measures that don't need s_(i) are still defined
+	_, err = DFBETA(m)
+	assert.Equal(t, nil, err)
+	_, err = CooksDistance(m)
+	assert.Equal(t, nil, err)
+}
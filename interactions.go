@@ -0,0 +1,165 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// InteractionFeatures appends the products of pairs of columns of a design,
+// the interactions of the predictors, to it.
+type InteractionFeatures struct {
+	pairs   [][2]int
+	skipped [][2]int
+	cols    int      // columns of the data it transforms
+	labels  []string // their labels, if the data had them
+}
+
+// NewInteractionFeatures checks the pairs of columns of x to interact, which
+// Transform appends in the order given. A column is only interacted with
+// itself, its square, if allowSelf is set, and a pair may appear only once in
+// either order. Pairs with a column that is constant in x are skipped, since
+// their product is a multiple of the other column, and Skipped lists them.
+func NewInteractionFeatures(x *DataFrame, pairs [][2]int, allowSelf bool) (*InteractionFeatures, error) {
+	f := &InteractionFeatures{cols: x.Cols()}
+	if labels := x.Labels(); len(labels) == x.Cols() {
+		f.labels = labels
+	}
+	constant := make([]bool, x.Cols())
+	for j := range constant {
+		column := x.GetCol(j)
+		constant[j] = true
+		for _, v := range column {
+			constant[j] = constant[j] && v == column[0]
+		}
+	}
+	seen := make(map[[2]int]bool, len(pairs))
+	for _, pair := range pairs {
+		a, b := pair[0], pair[1]
+		if a < 0 || a >= x.Cols() || b < 0 || b >= x.Cols() {
+			return nil, fmt.Errorf("interaction %d:%d is not between the %d columns", a, b, x.Cols())
+		}
+		if a == b && !allowSelf {
+			return nil, fmt.Errorf("interaction %d:%d is of a column with itself", a, b)
+		}
+		key := [2]int{a, b}
+		if b < a {
+			key = [2]int{b, a}
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("interaction %d:%d is repeated", a, b)
+		}
+		seen[key] = true
+		if constant[a] || constant[b] {
+			f.skipped = append(f.skipped, pair)
+			continue
+		}
+		f.pairs = append(f.pairs, pair)
+	}
+	return f, nil
+}
+
+// AllPairs returns every pair of distinct columns of a design with p columns,
+// (0, 1), (0, 2), ..., (1, 2), ..., (p - 2, p - 1), to interact every pair of
+// predictors.
+func AllPairs(p int) [][2]int {
+	var pairs [][2]int
+	for a := 0; a < p; a++ {
+		for b := a + 1; b < p; b++ {
+			pairs = append(pairs, [2]int{a, b})
+		}
+	}
+	return pairs
+}
+
+// Pairs returns the pairs of columns that Transform interacts, in order.
+func (f *InteractionFeatures) Pairs() [][2]int { return f.pairs }
+
+// Skipped returns the pairs that were skipped since a column was constant.
+func (f *InteractionFeatures) Skipped() [][2]int { return f.skipped }
+
+// Transform returns x with the interactions appended, in the order of Pairs.
+// The columns are labeled, with the interaction of columns a and b named
+// "a:b" after their labels, or "x2:x5" after their indices if x has no labels.
+func (f *InteractionFeatures) Transform(x *DataFrame) (*DataFrame, error) {
+	if x.Cols() != f.cols {
+		return nil, DimensionError
+	}
+	rows := make([][]float64, x.Rows())
+	for i := range rows {
+		row, err := f.TransformRow(x.GetRow(i))
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+	}
+	names := x.names()
+	for _, pair := range f.pairs {
+		names = append(names, names[pair[0]]+":"+names[pair[1]])
+	}
+	return NewDataFrame(rows, names), nil
+}
+
+// TransformRow appends the interactions to one row of predictors.
+func (f *InteractionFeatures) TransformRow(row []float64) ([]float64, error) {
+	if len(row) != f.cols {
+		return nil, DimensionError
+	}
+	expanded := append(make([]float64, 0, f.cols+len(f.pairs)), row...)
+	for _, pair := range f.pairs {
+		expanded = append(expanded, row[pair[0]]*row[pair[1]])
+	}
+	return expanded, nil
+}
+
+// Model returns a model that appends the interactions to each row of
+// predictors before m predicts from it, for a model trained on the
+// transformed data. Rows of the wrong length predict NaN. If the data it was
+// built from was labeled, PredictFrame matches the columns of new data to
+// its labels.
+func (f *InteractionFeatures) Model(m Model) Model {
+	return &interactionModel{features: f, model: m}
+}
+
+type interactionModel struct {
+	features *InteractionFeatures
+	model    Model
+}
+
+func (m *interactionModel) Predict(x []float64) float64 {
+	row, err := m.features.TransformRow(x)
+	if err != nil {
+		return math.NaN()
+	}
+	return m.model.Predict(row)
+}
+
+func (m *interactionModel) frameColumns() ([]string, error) {
+	return wrappedColumns(m.features, m.model)
+}
+
+func (m *interactionModel) predictFrame(x *DataFrame) ([]float64, error) {
+	return predictWrapped(m.features, m.model, x)
+}
+
+func (f *InteractionFeatures) inputs() ([]string, error) {
+	if f.labels == nil {
+		return nil, fmt.Errorf("%w: the interactions were built from data without names for its columns", LabelError)
+	}
+	return f.labels, nil
+}
+
+func (f *InteractionFeatures) outputs() []string {
+	names := append([]string(nil), f.labels...)
+	for _, pair := range f.pairs {
+		names = append(names, f.labels[pair[0]]+":"+f.labels[pair[1]])
+	}
+	return names
+}
+
+func (f *InteractionFeatures) transformFrame(x *DataFrame) (*DataFrame, error) {
+	in, err := x.Select(f.labels...)
+	if err != nil {
+		return nil, err
+	}
+	return f.Transform(in)
+}
@@ -0,0 +1,88 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestInteractionFeatures(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	const n = 100
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		a, b, c := rows[i][0], rows[i][1], rows[i][2]
+		response[i] = 1 + 2*a - b + 0.5*c + 3*a*b + 0.01*rng.NormFloat64()
+	}
+	df := NewDataFrame(rows, []string{"a", "b", "c"})
+	features, err := NewInteractionFeatures(df, AllPairs(3), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := features.Transform(df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{"a", "b", "c", "a:b", "a:c", "b:c"}; !reflect.DeepEqual(expanded.Labels(), names) {
+		t.Errorf("got labels %v, want %v", expanded.Labels(), names)
+	}
+	model, s, err := NewOlsTrainer().Train(expanded, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, want := range []float64{1, 2, -1, 0.5, 3, 0, 0} {
+		if b := s.Coefficients()[j]; math.Abs(b-want) > 0.01 {
+			t.Errorf("coefficient %d is %v, want about %v", j, b, want)
+		}
+	}
+
+	wrapped := features.Model(model)
+	row := []float64{0.5, -1, 2}
+	want := model.Predict([]float64{0.5, -1, 2, -0.5, 1, -2})
+	if got := wrapped.Predict(row); math.Abs(got-want) > 1e-12 {
+		t.Errorf("prediction from the wrapped model is %v, want %v", got, want)
+	}
+	if v := wrapped.Predict(row[:2]); !math.IsNaN(v) {
+		t.Errorf("prediction from a short row is %v, want NaN", v)
+	}
+}
+
+func TestInteractionFeaturesPairs(t *testing.T) {
+	if pairs := AllPairs(4); !reflect.DeepEqual(pairs, [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}) {
+		t.Errorf("got pairs %v", pairs)
+	}
+	// the second column is constant
+	df := NewDataFrame([][]float64{{1, 5, 2}, {2, 5, 3}, {3, 5, 7}})
+	if _, err := NewInteractionFeatures(df, [][2]int{{0, 0}}, false); err == nil {
+		t.Errorf("expected an error for a column interacted with itself")
+	}
+	for _, pairs := range [][][2]int{{{0, 2}, {2, 0}}, {{0, 3}}, {{-1, 0}}} {
+		if _, err := NewInteractionFeatures(df, pairs, true); err == nil {
+			t.Errorf("expected an error for the interactions %v", pairs)
+		}
+	}
+
+	features, err := NewInteractionFeatures(df, [][2]int{{2, 0}, {0, 1}, {0, 0}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(features.Pairs(), [][2]int{{2, 0}, {0, 0}}) || !reflect.DeepEqual(features.Skipped(), [][2]int{{0, 1}}) {
+		t.Errorf("kept %v and skipped %v", features.Pairs(), features.Skipped())
+	}
+	expanded, err := features.Transform(df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{"x0", "x1", "x2", "x2:x0", "x0:x0"}; !reflect.DeepEqual(expanded.Labels(), names) {
+		t.Errorf("got labels %v, want %v", expanded.Labels(), names)
+	}
+	if row := expanded.GetRow(2); !reflect.DeepEqual(row, []float64{3, 5, 7, 21, 9}) {
+		t.Errorf("got row %v", row)
+	}
+	if _, err := features.Transform(NewDataFrame([][]float64{{1, 2}})); err != DimensionError {
+		t.Errorf("got error %v for the wrong number of columns, want DimensionError", err)
+	}
+}
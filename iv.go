@@ -0,0 +1,183 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// IVModel is an instrumental variables fit by two-stage least squares.
+type IVModel struct {
+	// Coefficients are those of the columns of x, the intercept first unless
+	// the fit is through the origin.
+	Coefficients []float64
+	// VCov is \hat\sigma^2 (\hat X'\hat X)^-1, the covariance matrix of the
+	// coefficients, with \hat\sigma^2 from the structural residuals.
+	VCov *mat.Dense
+	// Residuals are the structural residuals y - X\beta, of the regressors
+	// rather than their first-stage fitted values.
+	Residuals []float64
+	Sigma     float64 // the residual standard error sqrt(e'e / (n - p))
+
+	// FirstStage has the F test of the excluded instruments in the
+	// first-stage regression of each endogenous column, in the order of
+	// endogenousCols. A small F, below 10 by the rule of thumb of Staiger &
+	// Stock (1997), warns of weak instruments.
+	FirstStage []TestResult
+
+	origin bool
+}
+
+// Fit2SLS fits y on the columns of x by two-stage least squares, as R's
+// AER::ivreg(y ~ x | exogenous + z) does, where the columns endogenousCols of
+// x are endogenous and the columns of z are the instruments excluded from x.
+// The first stage regresses each endogenous column on the instruments z and
+// the exogenous columns of x, and the second regresses y on x with the
+// endogenous columns replaced by their fitted values \hat X:
+//
+// \beta = (\hat X'\hat X)^-1 \hat X'y
+//
+// The covariance matrix is \hat\sigma^2 (\hat X'\hat X)^-1 with the variance
+// estimated from the residuals y - X\beta, not those of the second stage,
+// which would understate it. An intercept is added to both stages unless
+// WithIntercept(false) is given. The order condition, that there are at least
+// as many instruments as endogenous columns, must hold, and x, z and y must
+// have the same number of rows, or it is a DimensionError.
+func Fit2SLS(y []float64, x, z *mat.Dense, endogenousCols []int, opts ...Option) (*IVModel, error) {
+	o := newOptions(opts)
+	n, k := x.Dims()
+	zn, m := z.Dims()
+	if zn != n || len(y) != n {
+		return nil, DimensionError
+	}
+	if len(endogenousCols) == 0 {
+		return nil, fmt.Errorf("no endogenous columns given")
+	}
+	endogenous := make([]bool, k)
+	for _, j := range endogenousCols {
+		if j < 0 || j >= k {
+			return nil, fmt.Errorf("endogenous column %d is not one of the %d columns", j, k)
+		}
+		if endogenous[j] {
+			return nil, fmt.Errorf("endogenous column %d is given twice", j)
+		}
+		endogenous[j] = true
+	}
+	q := len(endogenousCols)
+	if m < q {
+		return nil, fmt.Errorf("%d instruments for %d endogenous columns: the order condition fails", m, q)
+	}
+
+	// the first stage, on the instruments and exogenous columns
+	var exogenous []int
+	for j := 0; j < k; j++ {
+		if !endogenous[j] {
+			exogenous = append(exogenous, j)
+		}
+	}
+	xe := columnsOf(x, exogenous)
+	xn := columnsOf(x, endogenousCols)
+	w := mat.NewDense(n, len(exogenous)+m, nil)
+	for i := 0; i < n; i++ {
+		row := w.RawRowView(i)
+		for c, j := range exogenous {
+			row[c] = x.At(i, j)
+		}
+		for c := 0; c < m; c++ {
+			row[len(exogenous)+c] = z.At(i, c)
+		}
+	}
+	first, err := FitMulti(w, xn, WithIntercept(o.intercept))
+	if err != nil {
+		return nil, fmt.Errorf("first stage: %w", err)
+	}
+	var reduced *MultiOLS
+	if xe != nil {
+		if reduced, err = FitMulti(xe, xn, WithIntercept(o.intercept)); err != nil {
+			return nil, fmt.Errorf("first stage: %w", err)
+		}
+	}
+
+	model := &IVModel{FirstStage: make([]TestResult, q), origin: !o.intercept}
+	xhat := mat.DenseCopyOf(x)
+	df1, df2 := float64(m), float64(n-first.summaries[0].p)
+	for c, j := range endogenousCols {
+		s := first.Summary(c)
+		xhat.SetCol(j, s.fitted)
+
+		// the partial F test of z, against the fit on the exogenous columns alone
+		var rssReduced float64
+		switch {
+		case reduced != nil:
+			rssReduced = reduced.Summary(c).SumOfSquares()
+		case o.intercept:
+			rssReduced = totalSumOfSquares(s.response)
+		default:
+			rssReduced = sum(prod(s.response, s.response))
+		}
+		rss := s.SumOfSquares()
+		f := (rssReduced - rss) / df1 / (rss / df2)
+		model.FirstStage[c] = TestResult{Statistic: f, DF: df1, DF2: df2, PValue: fSurvival(f, df1, df2)}
+	}
+
+	// the second stage
+	fit, second, err := NewOlsTrainer(WithIntercept(o.intercept)).Train(MatToDF(xhat), y)
+	if err != nil {
+		return nil, fmt.Errorf("second stage: %w", err)
+	}
+	if aliased := fit.(*OLS).Aliased(); aliased != nil {
+		return nil, fmt.Errorf("%w: the instruments don't identify %v", SingularDesignError, aliased)
+	}
+	p := second.Data().Cols()
+	if n <= p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+	model.Coefficients = second.Coefficients()
+	model.Residuals = make([]float64, n)
+	rss := 0.0
+	for i := range y {
+		e := y[i] - model.Predict(x.RawRowView(i))
+		model.Residuals[i] = e
+		rss += e * e
+	}
+	sigma2 := rss / float64(n-p)
+	model.Sigma = math.Sqrt(sigma2)
+	inv, err := xtxInverse(second)
+	if err != nil {
+		return nil, err
+	}
+	model.VCov = &mat.Dense{}
+	model.VCov.Scale(sigma2, inv)
+	return model, nil
+}
+
+// columnsOf returns the columns cols of x, or nil if cols is nil.
+func columnsOf(x *mat.Dense, cols []int) *mat.Dense {
+	if cols == nil {
+		return nil
+	}
+	n, _ := x.Dims()
+	c := mat.NewDense(n, len(cols), nil)
+	for k, j := range cols {
+		c.SetCol(k, mat.Col(nil, j, x))
+	}
+	return c
+}
+
+// StandardErrors returns the standard errors of the coefficients.
+func (m *IVModel) StandardErrors() []float64 {
+	se := make([]float64, len(m.Coefficients))
+	for j := range se {
+		se[j] = math.Sqrt(m.VCov.At(j, j))
+	}
+	return se
+}
+
+// Predict returns the prediction of the model at the regressors x.
+func (m *IVModel) Predict(x []float64) float64 {
+	if m.origin {
+		return sum(prod(x, m.Coefficients))
+	}
+	return m.Coefficients[0] + sum(prod(x, m.Coefficients[1:]))
+}
@@ -0,0 +1,107 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// ivData are y, the endogenous x1, the exogenous x2 and the instruments z1
+// and z2, with x1 correlated with the errors of y.
+var ivData = [][]float64{
+	{-4.3, -1.9, 1.0, -1.2, 0.4}, {-1.2, -1.1, -1.2, 0.5, 1.1}, {4.3, 1.8, -0.1, 1.5, 1.0},
+	{0.8, -0.1, 0.1, -0.8, -1.1}, {-0.7, -0.5, 1.1, 0.3, 0.7}, {-1.2, -1.9, -1.5, -1.4, -0.2},
+	{2.7, 1.8, 0.8, 3.3, 0.0}, {-1.7, -0.7, 0.1, -0.1, 0.4}, {5.1, 2.5, 0.7, 1.9, -0.5},
+	{3.5, 1.7, 1.3, 1.0, -0.1}, {1.0, 0.9, 1.6, 0.8, 0.5}, {-1.3, -0.7, 0.2, 0.3, 0.2},
+	{3.7, 1.2, 1.0, 0.6, 1.5}, {-1.1, -0.3, 1.8, 0.2, 0.8}, {0.3, 0.4, 0.9, 0.2, 1.0},
+}
+
+// ivColumns returns y and the columns from to to of ivData.
+func ivColumns(from, to int) ([]float64, *mat.Dense) {
+	response := make([]float64, len(ivData))
+	x := mat.NewDense(len(ivData), to-from, nil)
+	for i, row := range ivData {
+		response[i] = row[0]
+		x.SetRow(i, row[from:to])
+	}
+	return response, x
+}
+
+func TestFit2SLS(t *testing.T) {
+	response, x := ivColumns(1, 3)
+	_, z := ivColumns(3, 5)
+	fit, err := Fit2SLS(response, x, z, []int{0})
+	assert.Equal(t, nil, err)
+
+	// the coefficients, standard errors and sigma of AER's ivreg(y ~ x1 + x2
+	// | x2 + z1 + z2) by their definitions, hand-derived in exact rational
+	// arithmetic up to the square roots; AER hasn't been run against these
+	assertCloseSlices(t, fit.Coefficients, []float64{0.6041307177935017, 1.7977674680526279, -0.607056399726368}, 1e-12)
+	assertCloseSlices(t, fit.StandardErrors(), []float64{0.2272050479835587, 0.19368430484702684, 0.24674792356012004}, 1e-12)
+	assertClose(t, fit.Sigma, 0.7599343735870445, 1e-12)
+	// the F test of z1 and z2 in the first stage, as the weak instruments
+	// diagnostic of summary(ivreg, diagnostics = TRUE) defines it, from the
+	// same exact arithmetic
+	assert.Equal(t, 1, len(fit.FirstStage))
+	assertClose(t, fit.FirstStage[0].Statistic, 11.900049698582182, 1e-10)
+	assert.Equal(t, 2.0, fit.FirstStage[0].DF)
+	assert.Equal(t, 11.0, fit.FirstStage[0].DF2)
+	assertClose(t, fit.FirstStage[0].PValue, fSurvival(fit.FirstStage[0].Statistic, 2, 11), 1e-15)
+	for i, row := range ivData {
+		assertClose(t, fit.Residuals[i], row[0]-fit.Predict(row[1:3]), 1e-12)
+	}
+
+	// the naive second stage has the same coefficients, but its standard
+	// errors are scaled by the residuals of the fitted regressors
+	xhat := mat.DenseCopyOf(x)
+	_, w := ivColumns(2, 5)
+	stage, err := FitMulti(w, columnsOf(x, []int{0}))
+	assert.Equal(t, nil, err)
+	xhat.SetCol(0, stage.Summary(0).Yhat())
+	_, naive, err := NewOlsTrainer().Train(MatToDF(xhat), response)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, naive.Coefficients(), fit.Coefficients, 1e-10)
+	naiveVCov, err := VarCov(naive)
+	assert.Equal(t, nil, err)
+	scale := fit.Sigma / naive.(OlsSummary).ResidualStandardError()
+	assert.T(t, math.Abs(scale-1) > 0.1, scale)
+	assertCloseSlices(t, fit.StandardErrors(), multSlice(StandardErrors(naiveVCov), scale), 1e-10)
+
+	// instrumenting a column by itself is least squares
+	_, ols, err := NewOlsTrainer().Train(MatToDF(x), response)
+	assert.Equal(t, nil, err)
+	self, err := Fit2SLS(response, x, columnsOf(x, []int{0}), []int{0})
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, self.Coefficients, ols.Coefficients(), 1e-10)
+	vcov, err := VarCov(ols)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, self.StandardErrors(), StandardErrors(vcov), 1e-10)
+	assert.T(t, self.FirstStage[0].PValue < 1e-12)
+
+	// through the origin, with both regressors endogenous
+	origin, err := Fit2SLS(response, x, z, []int{1, 0}, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(origin.Coefficients))
+	assert.Equal(t, 2, len(origin.FirstStage))
+	assert.Equal(t, 13.0, origin.FirstStage[0].DF2)
+}
+
+func TestFit2SLSInvalid(t *testing.T) {
+	response, x := ivColumns(1, 3)
+	_, z := ivColumns(3, 5)
+	// one instrument for two endogenous columns fails the order condition
+	_, err := Fit2SLS(response, x, columnsOf(z, []int{0}), []int{0, 1})
+	assert.NotEqual(t, nil, err)
+	_, err = Fit2SLS(response[1:], x, z, []int{0})
+	assert.T(t, errors.Is(err, DimensionError), err)
+	for _, cols := range [][]int{nil, {2}, {-1}, {0, 0}} {
+		_, err = Fit2SLS(response, x, z, cols)
+		assert.NotEqual(t, nil, err)
+	}
+	// an instrument that is the exogenous column identifies nothing
+	_, err = Fit2SLS(response, x, columnsOf(x, []int{1}), []int{0})
+	assert.NotEqual(t, nil, err)
+}
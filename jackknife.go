@@ -0,0 +1,61 @@
+package glasso
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// JackknifeResult holds the jackknife estimates of the bias and variance of
+// the coefficients of a least squares fit.
+type JackknifeResult struct {
+	Estimates []float64  // the coefficients of the fit
+	Deleted   *DataFrame // the coefficients of the fit without each observation, a row per observation
+	Bias      []float64  // the jackknife estimate of the bias of each coefficient
+	Variance  []float64  // the jackknife estimate of the variance of each coefficient
+}
+
+// Jackknife computes the coefficients of a least squares fit with each
+// observation deleted in turn, \beta_{(i)} = \beta - DFBETA_i, without
+// refitting the model, and from them the jackknife estimates
+//
+// bias_j = (n - 1) (\bar\beta_{j(.)} - \beta_j)
+// var_j = \frac{n - 1}{n} \sum_i (\beta_{j(i)} - \bar\beta_{j(.)})^2
+//
+// The jackknife is undefined if an observation has a leverage of one, and a
+// LeverageError is returned.
+func Jackknife(m Summary) (*JackknifeResult, error) {
+	deleted, err := deletedCoefficients(m)
+	if err != nil {
+		return nil, err
+	}
+	n, p := deleted.Dims()
+	result := &JackknifeResult{
+		Estimates: append([]float64(nil), m.Coefficients()...),
+		Deleted:   MatToDF(deleted),
+		Bias:      make([]float64, p),
+		Variance:  make([]float64, p),
+	}
+	for j := 0; j < p; j++ {
+		column := mat.Col(nil, j, deleted)
+		for _, v := range column {
+			if math.IsNaN(v) {
+				return nil, LeverageError
+			}
+		}
+		centered := subtractMean(column)
+		result.Bias[j] = float64(n-1) * (mean(column) - result.Estimates[j])
+		result.Variance[j] = float64(n-1) / float64(n) * sum(prod(centered, centered))
+	}
+	return result, nil
+}
+
+// StandardErrors returns the jackknife standard errors of the coefficients,
+// the square roots of the variances.
+func (j *JackknifeResult) StandardErrors() []float64 {
+	se := make([]float64, len(j.Variance))
+	for k, v := range j.Variance {
+		se[k] = math.Sqrt(v)
+	}
+	return se
+}
@@ -0,0 +1,81 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestJackknife(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	const n = 25
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64(), rng.Float64() * 10, rng.ExpFloat64()}
+		response[i] = 2 - rows[i][0] + 0.5*rows[i][1] + 3*rows[i][2] + rng.ExpFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Jackknife(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, c := result.Deleted.Rows(), result.Deleted.Cols(); r != n || c != 4 {
+		t.Fatalf("got %d x %d deleted estimates, want %d x 4", r, c, n)
+	}
+
+	// refit without each row
+	refits := make([][]float64, n)
+	for i := range refits {
+		var without [][]float64
+		var y []float64
+		for k := range rows {
+			if k != i {
+				without = append(without, rows[k])
+				y = append(y, response[k])
+			}
+		}
+		_, refit, err := NewOlsTrainer().Train(NewDataFrame(without), y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refits[i] = refit.Coefficients()
+		for j, b := range refits[i] {
+			if math.Abs(result.Deleted.X.At(i, j)-b) > 1e-9 {
+				t.Errorf("row %d, coefficient %d: deleted estimate %v, refit %v", i, j, result.Deleted.X.At(i, j), b)
+			}
+		}
+	}
+
+	betas := s.Coefficients()
+	for j := range betas {
+		column := make([]float64, n)
+		for i := range refits {
+			column[i] = refits[i][j]
+		}
+		m := mean(column)
+		variance := 0.0
+		for _, b := range column {
+			variance += (b - m) * (b - m)
+		}
+		variance *= float64(n-1) / n
+		if bias := (n - 1) * (m - betas[j]); math.Abs(result.Bias[j]-bias) > 1e-9 {
+			t.Errorf("coefficient %d: bias %v, want %v", j, result.Bias[j], bias)
+		}
+		if math.Abs(result.Variance[j]-variance) > 1e-9 {
+			t.Errorf("coefficient %d: variance %v, want %v", j, result.Variance[j], variance)
+		}
+		if se := result.StandardErrors()[j]; math.Abs(se*se-variance) > 1e-9 {
+			t.Errorf("coefficient %d: standard error %v, want %v", j, se, math.Sqrt(variance))
+		}
+	}
+}
+
+func TestJackknifeUnitLeverage(t *testing.T) {
+	if _, err := Jackknife(unitLeverageSummary(t)); err != LeverageError {
+		t.Errorf("got error %v, want LeverageError", err)
+	}
+}
@@ -4,7 +4,7 @@ package glasso
 import (
 	"sort"
 
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 // Analogous to least squares boosting (trees = predictors)
@@ -36,7 +36,7 @@ func (f *LeastAngle) Train(y []float64) error {
 
 	// center y
 	r := subtractMean(y) // make sure y_bar = 0
-	x := mat64.NewDense(n, p, rep(0.0, n*p))
+	x := mat.NewDense(n, p, rep(0.0, n*p))
 	f.firstRun = true
 
 	// find the most correlated variable
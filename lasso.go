@@ -0,0 +1,429 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Beta_lasso = min( 1/2n \sum(y_i - \beta_0 - \sum x_ij * \beta_j)^2 + \lambda \sum |\beta_j| )
+//
+// The L1 penalty sets some coefficients exactly to zero, so the lasso selects
+// variables as well as shrinking them. It is fit by cyclic coordinate descent
+// (Friedman, Hastie & Tibshirani, 2010): with the other coefficients held fixed,
+// each coefficient has the closed form
+//
+// \beta_j = S(\frac{1}{n} x_j'r_{(j)}, \lambda) / (\frac{1}{n} x_j'x_j)
+//
+// where r_{(j)} is the partial residual without x_j and S(z, \lambda) = sign(z)(|z| - \lambda)_+
+// is the soft-thresholding operator.
+//
+// The elastic net (Zou & Hastie, 2005) mixes in a ridge penalty,
+//
+// \lambda \sum_j (\alpha |\beta_j| + (1 - \alpha) \beta_j^2 / 2)
+//
+// which keeps groups of correlated predictors together where the lasso picks
+// one of them. The threshold is then \lambda\alpha, and the ridge term adds
+// \lambda(1 - \alpha) to the denominator of the update. The lasso is the elastic
+// net with \alpha = 1.
+type Lasso struct {
+	betas       []float64
+	noIntercept bool
+}
+
+func (l *Lasso) Predict(x []float64) float64 {
+	if l.noIntercept {
+		return sum(prod(x, l.betas))
+	}
+	return l.betas[0] + sum(prod(x, l.betas[1:]))
+}
+
+type lassoTrainer struct {
+	lambda, alpha float64
+	opts          options
+}
+
+// NewLassoTrainer returns a Trainer for the lasso with penalty lambda, on the
+// scale of glmnet's lambda. The intercept is not penalized. By default the
+// predictors are standardized; see WithStandardize, WithTolerance,
+// WithMaxIterations and WithUpdating. WithIntercept(false) fits through the origin, as glmnet's
+// intercept = FALSE, without centering the predictors or the response.
+func NewLassoTrainer(lambda float64, opts ...Option) Trainer {
+	return NewElasticNetTrainer(lambda, 1, opts...)
+}
+
+// NewElasticNetTrainer returns a Trainer for the elastic net with penalty
+// lambda and mixing parameter alpha in [0, 1]: alpha = 1 is the lasso, and
+// alpha = 0 is ridge regression. The intercept is not penalized, and by
+// default the predictors are standardized. For the lasso lambda is on the
+// scale of glmnet's, but glmnet standardizes the response before the fit,
+// which for alpha < 1 changes the solution: its lambda and alpha give the
+// same fit as these only for a response whose standard deviation, of divisor
+// n, is one.
+func NewElasticNetTrainer(lambda, alpha float64, opts ...Option) Trainer {
+	return &lassoTrainer{
+		lambda: lambda,
+		alpha:  alpha,
+		opts:   newOptions(opts),
+	}
+}
+
+// FitElasticNet fits the elastic net of y on x with penalty lambda and mixing
+// parameter alpha, as the Trainer from NewElasticNetTrainer does.
+func FitElasticNet(x *DataFrame, y []float64, lambda, alpha float64, opts ...Option) (*Lasso, *LassoSummary, error) {
+	m, s, err := NewElasticNetTrainer(lambda, alpha, opts...).Train(x, y)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.(*Lasso), s.(*LassoSummary), nil
+}
+
+// Train fits the lasso or elastic net. The DataFrame is not modified, and the summary is a
+// *LassoSummary, whose design includes the intercept column. A fit that
+// doesn't converge within the maximum number of iterations is returned with
+// Converged false rather than an error.
+func (l *lassoTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	if !(l.lambda >= 0) || math.IsInf(l.lambda, 0) {
+		return nil, nil, fmt.Errorf("penalty %v is not a non-negative number", l.lambda)
+	}
+	if !(l.alpha >= 0 && l.alpha <= 1) {
+		return nil, nil, fmt.Errorf("mixing parameter %v is not between 0 and 1", l.alpha)
+	}
+	if len(y) != x.Rows() {
+		return nil, nil, DimensionError
+	}
+	if err := l.opts.checkIterations(); err != nil {
+		return nil, nil, err
+	}
+
+	n := x.Rows()
+	intercept := l.opts.intercept
+	z, means, scales := lassoDesign(x, l.opts)
+	ybar := 0.0
+	if intercept {
+		ybar = mean(y)
+	}
+
+	cd := newCoordinateDescent(z, subSlice(y, ybar), l.lambda*l.alpha, l.lambda*(1-l.alpha), l.opts.updating)
+	iterations, converged := cd.solve(l.opts)
+
+	betas := lassoCoefficients(cd.betas, means, scales, ybar, intercept)
+	design := x.Copy()
+	design.labels = x.Labels()
+	if intercept {
+		design.PushCol(rep(1, n))
+	}
+	fitted := make([]float64, n)
+	residuals := make([]float64, n)
+	for i := range fitted {
+		fitted[i] = sum(prod(design.GetRow(i), betas))
+		residuals[i] = y[i] - fitted[i]
+	}
+
+	return &Lasso{
+		betas:       betas,
+		noIntercept: !intercept,
+	}, &LassoSummary{
+		data:       design,
+		lambda:     l.lambda,
+		alpha:      l.alpha,
+		iterations: iterations,
+		converged:  converged,
+		fitted:     fitted,
+		residuals:  residuals,
+		response:   append([]float64(nil), y...),
+		betas:      betas,
+		intercept:  intercept,
+	}, nil
+}
+
+// lassoDesign returns the columns of x centered, if the fit has an intercept,
+// and scaled to unit variance (or root mean square, without an intercept) if
+// it standardizes, with their means and scales.
+func lassoDesign(x *DataFrame, o options) (z *mat.Dense, means, scales []float64) {
+	n, c := x.Rows(), x.Cols()
+	means, scales = make([]float64, c), rep(1, c)
+	z = mat.NewDense(n, c, nil)
+	for j := 0; j < c; j++ {
+		col := x.GetCol(j)
+		s := math.Sqrt(sum(prod(col, col)) / float64(n))
+		if o.intercept {
+			means[j] = mean(col)
+			s = math.Sqrt(centralMoment(col, 2))
+		}
+		// a constant column stays zero, and its coefficient with it
+		if o.standardize && s > 0 {
+			scales[j] = s
+		}
+		z.SetCol(j, multSlice(subSlice(col, means[j]), 1/scales[j]))
+	}
+	return z, means, scales
+}
+
+// lassoCoefficients returns the coefficients on the original scale of those
+// on the scale of lassoDesign, with the intercept first if there is one.
+func lassoCoefficients(scaled, means, scales []float64, ybar float64, intercept bool) []float64 {
+	betas := make([]float64, len(scaled)+1)
+	betas[0] = ybar
+	for j, b := range scaled {
+		betas[j+1] = b / scales[j]
+		betas[0] -= means[j] * betas[j+1]
+	}
+	if !intercept {
+		return betas[1:]
+	}
+	return betas
+}
+
+// Updating is how coordinate descent keeps track of the fit as the
+// coefficients of the lasso or elastic net change, glmnet's type.gaussian.
+type Updating int
+
+const (
+	// AutomaticUpdating uses CovarianceUpdating for fewer than
+	// covarianceLimit predictors and NaiveUpdating otherwise, as glmnet
+	// chooses, the default.
+	AutomaticUpdating Updating = iota
+
+	// NaiveUpdating keeps the residuals r = y - Z\beta, so that each update
+	// takes the inner product z_j'r over the n observations. For a wide
+	// design, p >> n, it is the faster.
+	NaiveUpdating
+
+	// CovarianceUpdating keeps the gradient \frac{1}{n} Z'r instead, from
+	// Z'y and the columns of the Gram matrix Z'Z of the coefficients that have
+	// ever been nonzero, each computed once. An update then takes O(p)
+	// operations whatever n is, and a coefficient that stays zero takes none,
+	// so for a tall design, n >> p, it is much the faster; a fit with many
+	// nonzero coefficients among many predictors pays for their p-long Gram
+	// columns.
+	CovarianceUpdating
+)
+
+// covarianceLimit is the number of predictors from which AutomaticUpdating
+// chooses NaiveUpdating, glmnet's.
+const covarianceLimit = 500
+
+func (u Updating) String() string {
+	switch u {
+	case AutomaticUpdating:
+		return "automatic"
+	case NaiveUpdating:
+		return "naive"
+	case CovarianceUpdating:
+		return "covariance"
+	}
+	return fmt.Sprintf("Updating(%d)", int(u))
+}
+
+// coordinateDescent minimizes 1/2n ||r||^2 + l_1 ||\beta||_1 + l_2/2 ||\beta||^2
+// over the columns of x, with r = y - x\beta kept up to date as coefficients
+// change, or with covariance updating the gradient \frac{1}{n} x'r instead.
+type coordinateDescent struct {
+	l1, l2 float64
+	cols   [][]float64
+	v      []float64 // \frac{1}{n} x_j'x_j
+	r      []float64 // the residuals; y itself with covariance updating
+	betas  []float64
+	null   float64 // \frac{1}{n} y'y, which scales the tolerance
+
+	covariance bool
+	xy         []float64   // \frac{1}{n} x'y
+	g          []float64   // \frac{1}{n} x'r
+	gram       [][]float64 // \frac{1}{n} x'x_j, once coefficient j has been nonzero
+}
+
+func newCoordinateDescent(x *mat.Dense, y []float64, l1, l2 float64, u Updating) *coordinateDescent {
+	n, c := x.Dims()
+	cd := &coordinateDescent{
+		l1:    l1,
+		l2:    l2,
+		cols:  make([][]float64, c),
+		v:     make([]float64, c),
+		r:     append([]float64(nil), y...),
+		betas: make([]float64, c),
+		null:  sum(prod(y, y)) / float64(n),
+	}
+	for j := range cd.cols {
+		cd.cols[j] = mat.Col(nil, j, x)
+		cd.v[j] = sum(prod(cd.cols[j], cd.cols[j])) / float64(n)
+	}
+	if u == CovarianceUpdating || (u == AutomaticUpdating && c < covarianceLimit) {
+		cd.covariance = true
+		cd.xy = make([]float64, c)
+		for j, col := range cd.cols {
+			cd.xy[j] = sum(prod(col, y)) / float64(n)
+		}
+		cd.g = append([]float64(nil), cd.xy...)
+		cd.gram = make([][]float64, c)
+	}
+	return cd
+}
+
+// softThreshold returns sign(z)(|z| - gamma)_+.
+func softThreshold(z, gamma float64) float64 {
+	switch {
+	case z > gamma:
+		return z - gamma
+	case z < -gamma:
+		return z + gamma
+	}
+	return 0
+}
+
+// gradient returns \frac{1}{n} x_j'r.
+func (cd *coordinateDescent) gradient(j int) float64 {
+	if cd.covariance {
+		return cd.g[j]
+	}
+	return sum(prod(cd.cols[j], cd.r)) / float64(len(cd.r))
+}
+
+// update minimizes over the jth coefficient and returns the resulting
+// decrease in the fit, \frac{1}{n} x_j'x_j (\Delta\beta_j)^2.
+func (cd *coordinateDescent) update(j int) float64 {
+	if cd.v[j] == 0 {
+		return 0
+	}
+	old := cd.betas[j]
+	b := softThreshold(cd.gradient(j)+cd.v[j]*old, cd.l1) / (cd.v[j] + cd.l2)
+	if b == old {
+		return 0
+	}
+	delta := b - old
+	if cd.covariance {
+		if cd.gram[j] == nil {
+			n := float64(len(cd.r))
+			cd.gram[j] = make([]float64, len(cd.cols))
+			for k, col := range cd.cols {
+				cd.gram[j][k] = sum(prod(col, cd.cols[j])) / n
+			}
+		}
+		for k, gkj := range cd.gram[j] {
+			cd.g[k] -= delta * gkj
+		}
+	} else {
+		col := cd.cols[j]
+		for i := range cd.r {
+			cd.r[i] -= delta * col[i]
+		}
+	}
+	cd.betas[j] = b
+	return cd.v[j] * delta * delta
+}
+
+// rss returns the residual sum of squares ||r||^2, with covariance updating
+// from y'y - \beta'x'y - \beta'x'r without the residuals.
+func (cd *coordinateDescent) rss() float64 {
+	if !cd.covariance {
+		return sum(prod(cd.r, cd.r))
+	}
+	return float64(len(cd.r)) * (cd.null - sum(prod(cd.betas, cd.xy)) - sum(prod(cd.betas, cd.g)))
+}
+
+func (cd *coordinateDescent) coefficients() []float64 { return cd.betas }
+
+// solve runs coordinate descent from the current coefficients until a pass
+// changes none of them by more than the tolerance, relative to the null deviance.
+func (cd *coordinateDescent) solve(opts options) (int, bool) {
+	threshold := opts.tolerance * cd.null
+	if threshold == 0 {
+		threshold = opts.tolerance
+	}
+	return descend(cd, threshold, opts.maxIter)
+}
+
+// A coordinateProblem is a convex problem that coordinate descent minimizes
+// one coefficient at a time.
+type coordinateProblem interface {
+	// update minimizes over the jth coefficient with the others held fixed,
+	// and returns a measure of how much the fit changed.
+	update(j int) float64
+	coefficients() []float64
+}
+
+// descend minimizes the problem by cyclic coordinate descent from its current
+// coefficients, using active set iteration: after each pass over every
+// coefficient, it iterates over the nonzero ones alone until they converge. It
+// stops when a full pass changes no coefficient by more than threshold, and
+// returns the number of passes made and whether it converged within maxIter.
+func descend(p coordinateProblem, threshold float64, maxIter int) (int, bool) {
+	all := make([]int, len(p.coefficients()))
+	for j := range all {
+		all[j] = j
+	}
+	pass := func(coefs []int) float64 {
+		largest := 0.0
+		for _, j := range coefs {
+			largest = math.Max(largest, p.update(j))
+		}
+		return largest
+	}
+
+	iterations := 0
+	for iterations < maxIter {
+		iterations++
+		if pass(all) < threshold {
+			return iterations, true
+		}
+
+		var active []int
+		for j, b := range p.coefficients() {
+			if b != 0 {
+				active = append(active, j)
+			}
+		}
+		for len(active) > 0 && iterations < maxIter {
+			iterations++
+			if pass(active) < threshold {
+				break
+			}
+		}
+	}
+	return iterations, false
+}
+
+// LassoSummary summarizes a lasso or elastic net fit. The diagnostics that
+// assume a least squares fit, such as LeveragePoints, don't apply to it.
+type LassoSummary struct {
+	data       *DataFrame
+	lambda     float64
+	alpha      float64
+	iterations int
+	converged  bool
+	fitted     []float64
+	residuals  []float64
+	response   []float64
+	betas      []float64
+	intercept  bool
+}
+
+func (l *LassoSummary) Data() *DataFrame        { return l.data }
+func (l *LassoSummary) Coefficients() []float64 { return l.betas }
+func (l *LassoSummary) Residuals() []float64    { return l.residuals }
+func (l *LassoSummary) Yhat() []float64         { return l.fitted }
+func (l *LassoSummary) Response() []float64     { return l.response }
+func (l *LassoSummary) Lambda() float64         { return l.lambda }
+func (l *LassoSummary) Alpha() float64          { return l.alpha }
+
+// Iterations returns the number of coordinate descent passes the fit made.
+func (l *LassoSummary) Iterations() int { return l.iterations }
+
+// Converged reports whether the fit converged within the maximum number of iterations.
+func (l *LassoSummary) Converged() bool { return l.converged }
+
+func (l *LassoSummary) SumOfSquares() float64 {
+	return sum(prod(l.residuals, l.residuals))
+}
+
+// NonZero returns the indices of the nonzero coefficients, excluding the intercept.
+func (l *LassoSummary) NonZero() []int {
+	var nonzero []int
+	for j, b := range l.betas {
+		if b != 0 && (j > 0 || !l.intercept) {
+			nonzero = append(nonzero, j)
+		}
+	}
+	return nonzero
+}
@@ -0,0 +1,203 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultPathLength is the number of penalties in the default grid of a lasso
+// path, as glmnet's nlambda.
+const DefaultPathLength = 100
+
+// Path is the lasso or elastic net fit at each penalty of a grid, from the
+// largest penalty to the smallest, as glmnet's fit object holds it.
+type Path struct {
+	Lambdas []float64
+
+	// Coefficients holds a column of the coefficients, in the order of
+	// LassoSummary's, for each penalty.
+	Coefficients *mat.Dense
+
+	DF                []int     // the number of nonzero coefficients but the intercept at each penalty
+	DevianceExplained []float64 // 1 - RSS / RSS_0 at each penalty, glmnet's dev.ratio
+	Converged         bool      // whether every fit converged within the maximum number of iterations
+
+	alpha     float64
+	intercept bool
+}
+
+// LassoPath fits the lasso at each of the penalties in lambdas, as
+// ElasticNetPath does with alpha = 1.
+func LassoPath(x *DataFrame, y []float64, lambdas []float64, opts ...Option) (*Path, error) {
+	return ElasticNetPath(x, y, 1, lambdas, opts...)
+}
+
+// ElasticNetPath fits the elastic net that NewElasticNetTrainer(lambda, alpha,
+// opts...) fits, for every lambda in lambdas, taken from the largest to the
+// smallest. Each fit starts from the coefficients of the one before it, and
+// with the sequential strong rule of Tibshirani et al. (2012) coordinate
+// descent only visits the coefficients that were nonzero or that had
+//
+// |\frac{1}{n} z_j'r| \ge \alpha (2\lambda_k - \lambda_{k-1})
+//
+// at the fit before; the others are checked against the optimality conditions
+// once it converges, and any that fails them is added and the fit resumed. A
+// path is then not much slower than its last, least penalized fit alone.
+//
+// With lambdas nil the grid is that of glmnet: DefaultPathLength penalties
+// evenly spaced on the log scale from \lambda_{max} = max_j |z_j'y| / n\alpha,
+// the least penalty at which every coefficient is zero, down to
+// 10^{-4}\lambda_{max}, or 10^{-2}\lambda_{max} when there are no more
+// observations than predictors. The penalties of the path are a grid for
+// CVLambda.
+func ElasticNetPath(x *DataFrame, y []float64, alpha float64, lambdas []float64, opts ...Option) (*Path, error) {
+	o := newOptions(opts)
+	if !(alpha >= 0 && alpha <= 1) {
+		return nil, fmt.Errorf("mixing parameter %v is not between 0 and 1", alpha)
+	}
+	n, c := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if err := o.checkIterations(); err != nil {
+		return nil, err
+	}
+	for _, lambda := range lambdas {
+		if !(lambda >= 0) || math.IsInf(lambda, 0) {
+			return nil, fmt.Errorf("penalty %v is not a non-negative number", lambda)
+		}
+	}
+
+	z, means, scales := lassoDesign(x, o)
+	ybar := 0.0
+	if o.intercept {
+		ybar = mean(y)
+	}
+	cd := newCoordinateDescent(z, subSlice(y, ybar), 0, 0, o.updating)
+
+	// glmnet takes lambda_max for the ridge penalty from alpha = 0.001
+	lambdaMax := 0.0
+	for j := 0; j < c; j++ {
+		lambdaMax = math.Max(lambdaMax, math.Abs(cd.gradient(j))/math.Max(alpha, 1e-3))
+	}
+	if lambdas == nil {
+		if !(lambdaMax > 0) {
+			return nil, fmt.Errorf("the response is constant or orthogonal to every column, so there is no path")
+		}
+		ratio := 1e-4
+		if n <= c {
+			ratio = 1e-2
+		}
+		lambdas = make([]float64, DefaultPathLength)
+		for k := range lambdas {
+			lambdas[k] = lambdaMax * math.Pow(ratio, float64(k)/float64(DefaultPathLength-1))
+		}
+	} else {
+		lambdas = append([]float64(nil), lambdas...)
+		sort.Sort(sort.Reverse(sort.Float64Slice(lambdas)))
+	}
+	if len(lambdas) == 0 {
+		return nil, fmt.Errorf("no penalties to fit")
+	}
+
+	threshold := o.tolerance * cd.null
+	if threshold == 0 {
+		threshold = o.tolerance
+	}
+	rss0 := cd.rss()
+	coefs := c
+	if o.intercept {
+		coefs++
+	}
+	path := &Path{
+		Lambdas:           lambdas,
+		Coefficients:      mat.NewDense(coefs, len(lambdas), nil),
+		DF:                make([]int, len(lambdas)),
+		DevianceExplained: make([]float64, len(lambdas)),
+		Converged:         true,
+		alpha:             alpha,
+		intercept:         o.intercept,
+	}
+	previous := math.Max(lambdaMax, lambdas[0])
+	for k, lambda := range lambdas {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		cd.l1, cd.l2 = lambda*alpha, lambda*(1-alpha)
+		strong := make([]bool, c)
+		var cols []int
+		for j := 0; j < c; j++ {
+			if cd.betas[j] != 0 || math.Abs(cd.gradient(j)) >= alpha*(2*lambda-previous) {
+				strong[j] = true
+				cols = append(cols, j)
+			}
+		}
+		for iterations := 0; ; {
+			used, converged := descend(&screenedProblem{cd: cd, cols: cols, betas: make([]float64, len(cols))}, threshold, o.maxIter-iterations)
+			iterations += used
+			if !converged {
+				path.Converged = false
+				break
+			}
+			// the strong rule can be wrong; add the coefficients it left out that
+			// aren't optimal at zero
+			var violations []int
+			for j := 0; j < c; j++ {
+				if !strong[j] && cd.v[j] > 0 && math.Abs(cd.gradient(j)) > cd.l1 {
+					violations = append(violations, j)
+				}
+			}
+			if len(violations) == 0 {
+				break
+			}
+			for _, j := range violations {
+				strong[j] = true
+			}
+			cols = append(cols, violations...)
+			sort.Ints(cols)
+		}
+
+		path.Coefficients.SetCol(k, lassoCoefficients(cd.betas, means, scales, ybar, o.intercept))
+		for _, b := range cd.betas {
+			if b != 0 {
+				path.DF[k]++
+			}
+		}
+		if rss0 > 0 {
+			path.DevianceExplained[k] = 1 - cd.rss()/rss0
+		}
+		previous = lambda
+	}
+	return path, nil
+}
+
+// Alpha returns the mixing parameter of the path, 1 for the lasso.
+func (p *Path) Alpha() float64 { return p.alpha }
+
+// Model returns the model of the kth penalty of the path.
+func (p *Path) Model(k int) Model {
+	return &Lasso{
+		betas:       mat.Col(nil, k, p.Coefficients),
+		noIntercept: !p.intercept,
+	}
+}
+
+// screenedProblem is coordinate descent over the columns cols of a problem
+// alone, the others held fixed.
+type screenedProblem struct {
+	cd    *coordinateDescent
+	cols  []int
+	betas []float64
+}
+
+func (s *screenedProblem) update(j int) float64 { return s.cd.update(s.cols[j]) }
+
+func (s *screenedProblem) coefficients() []float64 {
+	for a, j := range s.cols {
+		s.betas[a] = s.cd.betas[j]
+	}
+	return s.betas
+}
@@ -0,0 +1,463 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// assertLassoKKT checks the optimality conditions of the elastic net on the
+// standardized scale: (1/n) z_j'r - lambda (1 - alpha) beta_j = lambda alpha sign(beta_j)
+// for the nonzero coefficients, and |(1/n) z_j'r| <= lambda alpha for the rest.
+func assertLassoKKT(t *testing.T, rows [][]float64, response []float64, s *LassoSummary, tol float64) {
+	x := NewDataFrame(rows)
+	n := float64(x.Rows())
+	r := s.Residuals()
+	for j := 0; j < x.Cols(); j++ {
+		col := x.GetCol(j)
+		scale := math.Sqrt(centralMoment(col, 2))
+		z := multSlice(subSlice(col, mean(col)), 1/scale)
+		b := s.Coefficients()[j+1]
+		g := sum(prod(z, r))/n - s.Lambda()*(1-s.Alpha())*b*scale
+		if b == 0 {
+			assert.T(t, math.Abs(g) <= s.Lambda()*s.Alpha()+tol)
+		} else {
+			assertClose(t, g, s.Lambda()*s.Alpha()*sign(b), tol)
+		}
+	}
+}
+
+func TestLassoKKT(t *testing.T) {
+	for _, lambda := range []float64{0.05, 0.5, 2, 5} {
+		_, s, err := NewLassoTrainer(lambda, WithTolerance(1e-14)).Train(NewDataFrame(longley), longleyY)
+		assert.Equal(t, nil, err)
+		ls := s.(*LassoSummary)
+		assert.T(t, ls.Converged())
+		assertLassoKKT(t, longley, longleyY, ls, 1e-6)
+	}
+
+	// larger penalties select fewer variables
+	_, small, _ := NewLassoTrainer(0.05).Train(NewDataFrame(longley), longleyY)
+	_, large, _ := NewLassoTrainer(2).Train(NewDataFrame(longley), longleyY)
+	assert.T(t, len(large.(*LassoSummary).NonZero()) < len(small.(*LassoSummary).NonZero()))
+	assert.T(t, len(large.(*LassoSummary).NonZero()) > 0)
+}
+
+func TestLassoLambdaMax(t *testing.T) {
+	// every coefficient is zero once lambda exceeds max_j |(1/n) z_j'(y - ybar)|
+	x := NewDataFrame(data)
+	n := float64(len(y))
+	lambdaMax := 0.0
+	for j := 0; j < x.Cols(); j++ {
+		col := x.GetCol(j)
+		z := multSlice(subSlice(col, mean(col)), 1/math.Sqrt(centralMoment(col, 2)))
+		lambdaMax = math.Max(lambdaMax, math.Abs(sum(prod(z, subtractMean(y))))/n)
+	}
+	_, s, err := NewLassoTrainer(lambdaMax*1.0001).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(s.(*LassoSummary).NonZero()), 0)
+	assertClose(t, s.Coefficients()[0], mean(y), 1e-12)
+
+	_, s, err = NewLassoTrainer(lambdaMax*0.99).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, s.(*LassoSummary).NonZero(), []int{1})
+}
+
+func TestLassoOrthogonal(t *testing.T) {
+	// with orthogonal columns scaled to (1/n) x_j'x_j = 1, the lasso is the
+	// soft-thresholded least squares coefficients
+	rows := [][]float64{
+		{1, 1, 1}, {1, 1, -1}, {1, -1, 1}, {1, -1, -1},
+		{-1, 1, 1}, {-1, 1, -1}, {-1, -1, 1}, {-1, -1, -1},
+	}
+	response := []float64{5, 3, 4, 1, 2, 2.5, 0, -1}
+	lambda := 0.7
+	_, s, err := NewLassoTrainer(lambda, WithStandardize(false)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	x := NewDataFrame(rows)
+	for j := 0; j < 3; j++ {
+		ols := sum(prod(x.GetCol(j), response)) / 8
+		assertClose(t, s.Coefficients()[j+1], softThreshold(ols, lambda), 1e-12)
+	}
+	assert.Equal(t, s.Coefficients()[3], 0.0)
+	assert.T(t, s.(*LassoSummary).Iterations() <= 3)
+}
+
+func TestLassoLimits(t *testing.T) {
+	// no penalty is least squares
+	_, s, err := NewLassoTrainer(0, WithTolerance(1e-16)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, summary.Coefficients()[j], 1e-6)
+	}
+
+	// standardizing is the same as fitting standardized predictors and rescaling
+	_, s, err = NewLassoTrainer(0.3, WithTolerance(1e-14)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	scaled := NewDataFrame(data)
+	scales := make([]float64, scaled.Cols())
+	for j := range scales {
+		col := scaled.GetCol(j)
+		scales[j] = math.Sqrt(centralMoment(col, 2))
+		scaled.X.SetCol(j, multSlice(col, 1/scales[j]))
+	}
+	_, direct, err := NewLassoTrainer(0.3, WithTolerance(1e-14), WithStandardize(false)).Train(scaled, y)
+	assert.Equal(t, nil, err)
+	for j, b := range direct.Coefficients()[1:] {
+		assertClose(t, s.Coefficients()[j+1], b/scales[j], 1e-8)
+	}
+	assertClose(t, s.Coefficients()[0], direct.Coefficients()[0], 1e-8)
+}
+
+func TestLassoConvergence(t *testing.T) {
+	_, s, err := NewLassoTrainer(0.01, WithMaxIterations(2)).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	assert.T(t, !s.(*LassoSummary).Converged())
+	assert.Equal(t, s.(*LassoSummary).Iterations(), 2)
+
+	_, _, err = NewLassoTrainer(-1).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewLassoTrainer(1, WithTolerance(0)).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewLassoTrainer(1).Train(NewDataFrame(data), y[2:])
+	assert.Equal(t, DimensionError, err)
+}
+
+func TestElasticNet(t *testing.T) {
+	n := float64(len(longleyY))
+	for _, alpha := range []float64{0, 0.25, 0.5, 1} {
+		for _, lambda := range []float64{0.1, 1} {
+			_, s, err := NewElasticNetTrainer(lambda, alpha, WithTolerance(1e-15)).Train(NewDataFrame(longley), longleyY)
+			assert.Equal(t, nil, err)
+			ls := s.(*LassoSummary)
+			assert.T(t, ls.Converged())
+			assert.Equal(t, ls.Alpha(), alpha)
+			assertLassoKKT(t, longley, longleyY, ls, 1e-6)
+
+			// the fitted values pass through the means
+			assertClose(t, mean(s.Yhat()), mean(longleyY), 1e-9)
+		}
+	}
+
+	// alpha = 0 is ridge regression, whose objective is 2n times as large
+	_, en, err := NewElasticNetTrainer(0.2, 0, WithTolerance(1e-16)).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	_, ridge, err := NewRidgeTrainer(&RidgeConfig{Lambda: 0.2 * n, Standardize: true}).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	for j, b := range ridge.Coefficients() {
+		assertClose(t, en.Coefficients()[j], b, 1e-6*math.Max(math.Abs(b), 1))
+	}
+	assert.Equal(t, len(en.(*LassoSummary).NonZero()), 6)
+}
+
+func TestElasticNetOrthogonal(t *testing.T) {
+	// for an orthogonal design the elastic net is the soft-thresholded least
+	// squares coefficients, shrunk by 1 / (1 + lambda (1 - alpha))
+	rows := [][]float64{
+		{1, 1, 1}, {1, 1, -1}, {1, -1, 1}, {1, -1, -1},
+		{-1, 1, 1}, {-1, 1, -1}, {-1, -1, 1}, {-1, -1, -1},
+	}
+	response := []float64{5, 3, 4, 1, 2, 2.5, 0, -1}
+	x := NewDataFrame(rows)
+	lambda := 0.8
+	for _, alpha := range []float64{0, 0.25, 0.5, 1} {
+		_, s, err := NewElasticNetTrainer(lambda, alpha, WithStandardize(false)).Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		for j := 0; j < 3; j++ {
+			ols := sum(prod(x.GetCol(j), response)) / 8
+			expected := softThreshold(ols, lambda*alpha) / (1 + lambda*(1-alpha))
+			assertClose(t, s.Coefficients()[j+1], expected, 1e-12)
+		}
+	}
+
+	for _, alpha := range []float64{-0.1, 1.1, math.NaN()} {
+		_, _, err := NewElasticNetTrainer(lambda, alpha).Train(NewDataFrame(rows), response)
+		assert.NotEqual(t, nil, err)
+	}
+}
+
+// elasticNetObjective returns the objective that the elastic net minimizes,
+// on the standardized scale, at the coefficients coefs.
+func elasticNetObjective(x *DataFrame, response, coefs []float64, lambda, alpha float64) float64 {
+	n := x.Rows()
+	intercept := len(coefs) > x.Cols()
+	rss := 0.0
+	for i := 0; i < n; i++ {
+		e := response[i] - sum(prod(x.GetRow(i), coefs[len(coefs)-x.Cols():]))
+		if intercept {
+			e -= coefs[0]
+		}
+		rss += e * e
+	}
+	penalty := 0.0
+	for j := 0; j < x.Cols(); j++ {
+		col := x.GetCol(j)
+		scale := math.Sqrt(sum(prod(col, col)) / float64(n))
+		if intercept {
+			scale = math.Sqrt(centralMoment(col, 2))
+		}
+		b := coefs[len(coefs)-x.Cols()+j] * scale
+		penalty += alpha*math.Abs(b) + (1-alpha)/2*b*b
+	}
+	return rss/float64(2*n) + lambda*penalty
+}
+
+// assertPathMatches checks every fit of a path against the one-shot fit at its
+// penalty. Where the predictors are correlated the objective is flat enough
+// that the two stop at coefficients that differ in the sixth digit, so it is
+// the objectives that are compared.
+func assertPathMatches(t *testing.T, x *DataFrame, response []float64, alpha float64, path *Path, opts ...Option) {
+	t.Helper()
+	opts = append([]Option{WithTolerance(1e-14)}, opts...)
+	for k, lambda := range path.Lambdas {
+		_, s, err := NewElasticNetTrainer(lambda, alpha, opts...).Train(x, response)
+		assert.Equal(t, nil, err)
+		coefs := mat.Col(nil, k, path.Coefficients)
+		want := elasticNetObjective(x, response, s.Coefficients(), lambda, alpha)
+		assertClose(t, elasticNetObjective(x, response, coefs, lambda, alpha), want, 1e-8*want)
+		assert.Equal(t, len(s.(*LassoSummary).NonZero()), path.DF[k])
+
+		m := path.Model(k)
+		rss := 0.0
+		for i, yi := range response {
+			e := yi - m.Predict(x.GetRow(i))
+			rss += e * e
+		}
+		centered := response
+		if len(coefs) > x.Cols() {
+			centered = subtractMean(response)
+		}
+		assertClose(t, path.DevianceExplained[k], 1-rss/sum(prod(centered, centered)), 1e-10)
+	}
+}
+
+func TestLassoPath(t *testing.T) {
+	x := NewDataFrame(longley)
+	path, err := LassoPath(x, longleyY, nil, WithTolerance(1e-14))
+	assert.Equal(t, nil, err)
+	assert.T(t, path.Converged)
+	assert.Equal(t, DefaultPathLength, len(path.Lambdas))
+	assert.Equal(t, 1.0, path.Alpha())
+	r, c := path.Coefficients.Dims()
+	assert.Equal(t, 7, r)
+	assert.Equal(t, DefaultPathLength, c)
+
+	// the path starts where every coefficient is zero, and ends at 1e-4 of it
+	assert.Equal(t, 0, path.DF[0])
+	assertClose(t, path.DevianceExplained[0], 0, 1e-14)
+	assert.T(t, path.DF[1] > 0)
+	assertClose(t, path.Lambdas[DefaultPathLength-1]/path.Lambdas[0], 1e-4, 1e-16)
+	for k := 1; k < len(path.Lambdas); k++ {
+		assert.T(t, path.Lambdas[k] < path.Lambdas[k-1])
+		assert.T(t, path.DevianceExplained[k] >= path.DevianceExplained[k-1]-1e-12)
+	}
+	assertPathMatches(t, x, longleyY, 1, path)
+}
+
+func TestLassoPathStackloss(t *testing.T) {
+	// the lasso of stack.loss on the three predictors at a fixed grid, on the
+	// scale of glmnet(x, y, lambda = c(8, 5, 2, 1, 0.5, 0.1)) with its
+	// defaults standardize = TRUE and intercept = TRUE: the objective is
+	// 1/(2n) RSS + lambda ||b||_1, with the predictors standardized by their
+	// standard deviations of divisor n and the coefficients returned on the
+	// original scale. These are not glmnet's output, which hasn't been run
+	// against them, but the exact minimizers, found by solving the optimality
+	// conditions of every active set and sign pattern in 50-digit decimal
+	// arithmetic and keeping the one that satisfies them all.
+	want := []struct {
+		lambda       float64
+		coefficients []float64
+	}{
+		{8, []float64{9.898558074406196, 0.12618619419816382, 0, 0}},
+		{5, []float64{-12.21673819630518, 0.3575330822915997, 0.3856479022446248, 0}},
+		{2, []float64{-35.10199932291646, 0.5457058974553946, 0.9314699817389392, 0}},
+		{1, []float64{-42.73041969845356, 0.6084301691766595, 1.1134106749037107, 0}},
+		{0.5, []float64{-46.544629886222104, 0.6397923050372919, 1.2043810214860964, 0}},
+		{0.1, []float64{-41.781994155819426, 0.6981808337247972, 1.2771084620551927, -0.11386790806567886}},
+	}
+	lambdas := make([]float64, len(want))
+	for k, w := range want {
+		lambdas[k] = w.lambda
+	}
+	// Air.Flow and Water.Temp are correlated enough that coordinate descent
+	// creeps toward the minimum, so it takes a tolerance far below the
+	// default to reach it to eight digits
+	path, err := LassoPath(NewDataFrame(data), y, lambdas, WithTolerance(1e-24))
+	assert.Equal(t, nil, err)
+	assert.T(t, path.Converged)
+	for k, w := range want {
+		assertCloseSlices(t, mat.Col(nil, k, path.Coefficients), w.coefficients, 1e-8)
+		_, s, err := NewLassoTrainer(w.lambda, WithTolerance(1e-24)).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, s.Coefficients(), w.coefficients, 1e-8)
+	}
+}
+
+func TestElasticNetPathStackloss(t *testing.T) {
+	// the elastic net with alpha = 0.5 of TestLassoPathStackloss, whose
+	// objective adds lambda (1 - alpha) / 2 ||b||^2. glmnet standardizes the
+	// response internally, which for alpha < 1 changes the fit, so the
+	// response here is stack.loss over its standard deviation of divisor n,
+	// for which glmnet(x, y, alpha = 0.5, lambda = c(0.5, 0.2, 0.1, 0.05,
+	// 0.01)) minimizes this objective as it is. The coefficients are again
+	// the exact minimizers in 50-digit decimal arithmetic, not glmnet's
+	// output.
+	want := []struct {
+		lambda       float64
+		coefficients []float64
+	}{
+		{0.5, []float64{-2.5628465036169636, 0.040892679369417914, 0.08803498227465613, 0}},
+		{0.2, []float64{-3.9897280909708908, 0.0551259782158498, 0.1149028312550408, 0}},
+		{0.1, []float64{-4.516882362537448, 0.06091584730268309, 0.12330665931758311, 0}},
+		{0.05, []float64{-4.531234369290735, 0.06516383586155859, 0.1272640207149816, -0.0037761680123849293}},
+		{0.01, []float64{-4.128743477848328, 0.07062021427744955, 0.12996753418166204, -0.012923027502338362}},
+	}
+	lambdas := make([]float64, len(want))
+	for k, w := range want {
+		lambdas[k] = w.lambda
+	}
+	response := multSlice(y, 1/math.Sqrt(centralMoment(y, 2)))
+	path, err := ElasticNetPath(NewDataFrame(data), response, 0.5, lambdas, WithTolerance(1e-24))
+	assert.Equal(t, nil, err)
+	assert.T(t, path.Converged)
+	for k, w := range want {
+		assertCloseSlices(t, mat.Col(nil, k, path.Coefficients), w.coefficients, 1e-9)
+		_, s, err := NewElasticNetTrainer(w.lambda, 0.5, WithTolerance(1e-24)).Train(NewDataFrame(data), response)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, s.Coefficients(), w.coefficients, 1e-9)
+		model, fit, err := FitElasticNet(NewDataFrame(data), response, w.lambda, 0.5, WithTolerance(1e-24))
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, fit.Coefficients(), w.coefficients, 1e-9)
+		assert.Equal(t, 0.5, fit.Alpha())
+		assertClose(t, model.Predict(data[0]), fit.Yhat()[0], 1e-12)
+	}
+
+	_, _, err = FitElasticNet(NewDataFrame(data), response, 0.1, 1.5)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestElasticNetPathWide(t *testing.T) {
+	// with more predictors than observations the strong rule discards most of
+	// them, and the grid stops at 1e-2 of lambda_max
+	x, response := sparseRegression(rand.New(rand.NewSource(5)), 30, 60)
+	for _, alpha := range []float64{1, 0.5} {
+		path, err := ElasticNetPath(x, response, alpha, nil, WithTolerance(1e-14))
+		assert.Equal(t, nil, err)
+		assert.T(t, path.Converged)
+		assertClose(t, path.Lambdas[DefaultPathLength-1]/path.Lambdas[0], 1e-2, 1e-14)
+		assertPathMatches(t, x, response, alpha, path)
+	}
+}
+
+func TestLassoPathGrid(t *testing.T) {
+	// a grid is fit from its largest penalty down, whatever its order
+	x := NewDataFrame(data)
+	lambdas := []float64{0.1, 5, 1, 0}
+	path, err := LassoPath(x, y, lambdas, WithTolerance(1e-14))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []float64{5, 1, 0.1, 0}, path.Lambdas)
+	assert.Equal(t, []float64{0.1, 5, 1, 0}, lambdas)
+	assertPathMatches(t, x, y, 1, path)
+
+	// through the origin there is no intercept among the coefficients
+	path, err = LassoPath(x, y, lambdas, WithIntercept(false), WithTolerance(1e-14))
+	assert.Equal(t, nil, err)
+	r, _ := path.Coefficients.Dims()
+	assert.Equal(t, 3, r)
+	assertPathMatches(t, x, y, 1, path, WithIntercept(false))
+}
+
+func TestLassoPathInvalid(t *testing.T) {
+	x := NewDataFrame(data)
+	for _, lambdas := range [][]float64{{}, {1, -1}, {math.NaN()}, {math.Inf(1)}} {
+		_, err := LassoPath(x, y, lambdas)
+		assert.NotEqual(t, nil, err)
+	}
+	for _, alpha := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err := ElasticNetPath(x, y, alpha, nil)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := LassoPath(x, y[1:], nil)
+	assert.Equal(t, DimensionError, err)
+	_, err = LassoPath(x, y, nil, WithMaxIterations(0))
+	assert.NotEqual(t, nil, err)
+	// a constant response has no lambda_max to start the grid from
+	_, err = LassoPath(x, rep(3, len(y)), nil)
+	assert.NotEqual(t, nil, err)
+}
+
+func benchmarkPath(b *testing.B, path bool) {
+	x, response := sparseRegression(rand.New(rand.NewSource(1)), 200, 100)
+	grid, err := LassoPath(x, response, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if path {
+			LassoPath(x, response, grid.Lambdas)
+			continue
+		}
+		for _, lambda := range grid.Lambdas {
+			NewLassoTrainer(lambda).Train(x, response)
+		}
+	}
+}
+
+func BenchmarkLassoPath(b *testing.B)        { benchmarkPath(b, true) }
+func BenchmarkLassoIndependent(b *testing.B) { benchmarkPath(b, false) }
+
+func TestLassoUpdating(t *testing.T) {
+	// naive and covariance updating make the same updates, so they reach the
+	// same solutions
+	tall, tallY := sparseRegression(rand.New(rand.NewSource(3)), 200, 8)
+	wide, wideY := sparseRegression(rand.New(rand.NewSource(4)), 30, 80)
+	for _, c := range []struct {
+		x        *DataFrame
+		response []float64
+	}{{NewDataFrame(data), y}, {tall, tallY}, {wide, wideY}} {
+		for _, lambda := range []float64{1, 0.3, 0.05} {
+			for _, alpha := range []float64{1, 0.5} {
+				_, naive, err := NewElasticNetTrainer(lambda, alpha, WithUpdating(NaiveUpdating), WithTolerance(1e-14)).Train(c.x, c.response)
+				assert.Equal(t, nil, err)
+				_, cov, err := NewElasticNetTrainer(lambda, alpha, WithUpdating(CovarianceUpdating), WithTolerance(1e-14)).Train(c.x, c.response)
+				assert.Equal(t, nil, err)
+				assert.Equal(t, naive.(*LassoSummary).NonZero(), cov.(*LassoSummary).NonZero())
+				assertCloseSlices(t, cov.Coefficients(), naive.Coefficients(), 1e-9)
+			}
+		}
+
+		naive, err := LassoPath(c.x, c.response, nil, WithUpdating(NaiveUpdating))
+		assert.Equal(t, nil, err)
+		cov, err := LassoPath(c.x, c.response, nil, WithUpdating(CovarianceUpdating))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, naive.DF, cov.DF)
+		assertCloseSlices(t, cov.DevianceExplained[1:], naive.DevianceExplained[1:], 1e-9)
+	}
+	assert.Equal(t, "covariance", CovarianceUpdating.String())
+	assert.Equal(t, "Updating(7)", Updating(7).String())
+}
+
+// benchmarkUpdating times the lasso path of n observations of p standard
+// normal predictors.
+func benchmarkUpdating(b *testing.B, n, p int, u Updating) {
+	x, response := sparseRegression(rand.New(rand.NewSource(1)), n, p)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LassoPath(x, response, nil, WithUpdating(u)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// At n = 10^6, p = 50 covariance updating wins; at n = 200, p = 10^4 naive
+// updating does.
+func BenchmarkLassoNaiveTall(b *testing.B) { benchmarkUpdating(b, 1000000, 50, NaiveUpdating) }
+func BenchmarkLassoCovarianceTall(b *testing.B) {
+	benchmarkUpdating(b, 1000000, 50, CovarianceUpdating)
+}
+func BenchmarkLassoNaiveWide(b *testing.B)      { benchmarkUpdating(b, 200, 10000, NaiveUpdating) }
+func BenchmarkLassoCovarianceWide(b *testing.B) { benchmarkUpdating(b, 200, 10000, CovarianceUpdating) }
@@ -0,0 +1,25 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+)
+
+// SeparationError is returned when a linear combination of the predictors
+// separates the outcomes, so the maximum likelihood estimates don't exist and
+// the coefficients diverge.
+var SeparationError = errors.New("outcomes are perfectly separated by the predictors")
+
+// NewLogisticTrainer returns a Trainer for logistic regression of a binary (or
+// proportion) response in [0, 1], a GLM of the Binomial family. The options
+// set the tolerance on the change in deviance and the maximum number of
+// iterations.
+func NewLogisticTrainer(opts ...Option) Trainer {
+	o := newOptions(opts)
+	return NewGlmTrainer(NewGLMConfig(Binomial, int64(o.maxIter), o.tolerance))
+}
+
+// logistic is the inverse of the logit link.
+func logistic(eta float64) float64 {
+	return 1 / (1 + math.Exp(-eta))
+}
@@ -0,0 +1,152 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// mtcars weight (1000 lbs), gross horsepower and transmission (1 = manual)
+var (
+	mtcarsWt = []float64{2.620, 2.875, 2.320, 3.215, 3.440, 3.460, 3.570, 3.190, 3.150, 3.440, 3.440,
+		4.070, 3.730, 3.780, 5.250, 5.424, 5.345, 2.200, 1.615, 1.835, 2.465, 3.520, 3.435, 3.840,
+		3.845, 1.935, 2.140, 1.513, 3.170, 2.770, 3.570, 2.780}
+	mtcarsHp = []float64{110, 110, 93, 110, 175, 105, 245, 62, 95, 123, 123, 180, 180, 180, 205, 215,
+		230, 66, 52, 65, 97, 150, 150, 245, 175, 66, 91, 113, 264, 175, 335, 109}
+	mtcarsAm = []float64{1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 0, 0, 0, 0, 0, 1,
+		1, 1, 1, 1, 1, 1}
+)
+
+func mtcarsFrame(cols ...[]float64) *DataFrame {
+	rows := make([][]float64, len(mtcarsAm))
+	for i := range rows {
+		for _, col := range cols {
+			rows[i] = append(rows[i], col[i])
+		}
+	}
+	return NewDataFrame(rows)
+}
+
+func TestLogisticTrainer(t *testing.T) {
+	// glm(am ~ hp + wt, family = binomial, data = mtcars) and glm(am ~ wt, ...)
+	for _, test := range []struct {
+		x         *DataFrame
+		betas     []float64
+		se        []float64
+		deviance  float64
+		tolerance float64 // R prints 4 significant digits for am ~ wt
+	}{
+		{mtcarsFrame(mtcarsHp, mtcarsWt), []float64{18.86630, 0.03626, -8.08348}, []float64{7.44356, 0.01773, 3.06868}, 10.059, 5e-4},
+		{mtcarsFrame(mtcarsWt), []float64{12.040, -4.024}, []float64{4.510, 1.436}, 19.176, 5e-4},
+	} {
+		model, summary, err := NewLogisticTrainer(WithTolerance(1e-8)).Train(test.x, mtcarsAm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := summary.(*GLMSummary)
+		if !s.Converged() || s.Iterations() > 25 {
+			t.Errorf("converged %v after %d iterations", s.Converged(), s.Iterations())
+		}
+		for j, b := range s.Coefficients() {
+			if math.Abs(b-test.betas[j]) > test.tolerance*math.Abs(test.betas[j]) {
+				t.Errorf("coefficient %d: got %v, want %v", j, b, test.betas[j])
+			}
+			if se := s.StandardErrors()[j]; math.Abs(se-test.se[j]) > test.tolerance*test.se[j] {
+				t.Errorf("standard error %d: got %v, want %v", j, se, test.se[j])
+			}
+		}
+		if math.Abs(s.Deviance()-test.deviance) > 5e-4 {
+			t.Errorf("deviance: got %v, want %v", s.Deviance(), test.deviance)
+		}
+
+		// the score equations X'(y - \mu) = 0 hold at the maximum
+		x := s.Data().X
+		for j := 0; j < x.RawMatrix().Cols; j++ {
+			if score := sum(prod(mat.Col(nil, j, x), s.Residuals())); math.Abs(score) > 1e-6 {
+				t.Errorf("score %d: got %v", j, score)
+			}
+		}
+
+		// VarCov is the inverse of X'WX
+		n, p := x.Dims()
+		wx := mat.NewDense(n, p, nil)
+		for i, mu := range s.Yhat() {
+			for j := 0; j < p; j++ {
+				wx.Set(i, j, x.At(i, j)*mu*(1-mu))
+			}
+		}
+		info, inv := &mat.Dense{}, &mat.Dense{}
+		info.Mul(x.T(), wx)
+		if err := inv.Inverse(info); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				if got, want := s.VarCov().X.At(j, k), inv.At(j, k); math.Abs(got-want) > 1e-8*math.Abs(want) {
+					t.Errorf("vcov (%d, %d): got %v, want %v", j, k, got, want)
+				}
+			}
+		}
+
+		for i, mu := range s.Yhat() {
+			if got := model.Predict(test.x.GetRow(i)); math.Abs(got-mu) > 1e-12 {
+				t.Errorf("prediction %d: got %v, want %v", i, got, mu)
+			}
+		}
+	}
+}
+
+func TestLogisticConvergence(t *testing.T) {
+	x := mtcarsFrame(mtcarsHp, mtcarsWt)
+	_, summary, err := NewLogisticTrainer(WithMaxIterations(2)).Train(x, mtcarsAm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := summary.(*GLMSummary); s.Converged() || s.Iterations() != 2 {
+		t.Errorf("converged %v after %d iterations", s.Converged(), s.Iterations())
+	}
+}
+
+func TestLogisticSeparation(t *testing.T) {
+	column := func(v ...float64) *DataFrame {
+		rows := make([][]float64, len(v))
+		for i := range v {
+			rows[i] = []float64{v[i]}
+		}
+		return NewDataFrame(rows)
+	}
+	for _, test := range []struct {
+		name string
+		x, y []float64
+	}{
+		{"complete", []float64{1, 2, 3, 4, 5, 6, 7, 8}, []float64{0, 0, 0, 0, 1, 1, 1, 1}},
+		{"quasi-complete", []float64{1, 2, 3, 4, 4, 5, 6, 7}, []float64{0, 0, 0, 1, 0, 1, 1, 1}},
+	} {
+		// a loose tolerance lets the deviance converge before the probabilities reach 0 or 1
+		for _, tol := range []float64{1e-4, 1e-8} {
+			if _, _, err := NewLogisticTrainer(WithTolerance(tol)).Train(column(test.x...), test.y); err != SeparationError {
+				t.Errorf("%s separation with tolerance %v: got %v", test.name, tol, err)
+			}
+		}
+	}
+}
+
+func TestLogisticInvalid(t *testing.T) {
+	x := mtcarsFrame(mtcarsHp, mtcarsWt)
+	y := append([]float64(nil), mtcarsAm...)
+	if _, _, err := NewLogisticTrainer().Train(x, y[1:]); err != DimensionError {
+		t.Errorf("short response: got %v", err)
+	}
+	y[3] = 2
+	if _, _, err := NewLogisticTrainer().Train(x, y); err == nil {
+		t.Error("response outside [0, 1]: expected an error")
+	}
+	wide := NewDataFrame([][]float64{{1, 2}, {3, 5}})
+	if _, _, err := NewLogisticTrainer().Train(wide, []float64{0, 1}); err == nil {
+		t.Error("too few observations: expected an error")
+	}
+	if _, _, err := NewLogisticTrainer(WithMaxIterations(0)).Train(x, mtcarsAm); err == nil {
+		t.Error("no iterations: expected an error")
+	}
+}
@@ -0,0 +1,217 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Lowess smooths y against x by locally weighted regression, as R's
+// lowess(x, y, f = span, iter = iters) does, the algorithm of Cleveland
+// (1979). The fit at each x is the weighted least squares line through the
+// nearest span * n points, weighted by the tricube kernel (1 - (d / h)^3)^3 of
+// their distance d over the largest distance h among them. Each of the iters
+// robustness iterations then refits with the points also weighted by the
+// bisquare of their residuals over six times the median absolute residual,
+// so that outliers pull on the curve less. As in R, the fit is only computed
+// at points more than 1% of the range of x past the last one fit, and
+// interpolated between them, and tied x share their fitted value.
+//
+// The fitted values are those at the points x, in their original order. span
+// must be in (0, 1] and iters can't be negative.
+func Lowess(x, y []float64, span float64, iters int) (fitted []float64, err error) {
+	n := len(x)
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no points to smooth")
+	}
+	if !(span > 0 && span <= 1) {
+		return nil, fmt.Errorf("span %v is not in (0, 1]", span)
+	}
+	if iters < 0 {
+		return nil, fmt.Errorf("%d robustness iterations", iters)
+	}
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsInf(x[i], 0) || math.IsNaN(y[i]) || math.IsInf(y[i], 0) {
+			return nil, fmt.Errorf("point %d, (%v, %v), isn't finite", i, x[i], y[i])
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return x[order[a]] < x[order[b]] })
+	xs, ys := make([]float64, n), make([]float64, n)
+	for k, i := range order {
+		xs[k], ys[k] = x[i], y[i]
+	}
+	smooth := clowess(xs, ys, span, iters, 0.01*(xs[n-1]-xs[0]))
+	fitted = make([]float64, n)
+	for k, i := range order {
+		fitted[i] = smooth[k]
+	}
+	return fitted, nil
+}
+
+// clowess is the lowess of R's C code on x sorted increasingly, fitting
+// points within delta of the last one fit by interpolation.
+func clowess(x, y []float64, f float64, nsteps int, delta float64) []float64 {
+	n := len(x)
+	ys := make([]float64, n)
+	if n < 2 {
+		ys[0] = y[0]
+		return ys
+	}
+	ns := int(f*float64(n) + 1e-7)
+	if ns > n {
+		ns = n
+	}
+	if ns < 2 {
+		ns = 2
+	}
+	rw, res, w := make([]float64, n), make([]float64, n), make([]float64, n)
+	for iter := 1; iter <= nsteps+1; iter++ {
+		nleft, nright := 0, ns-1
+		last := -1 // the last point fit
+		i := 0
+		for {
+			if nright < n-1 {
+				// move the window right if that brings it closer
+				if d1, d2 := x[i]-x[nleft], x[nright+1]-x[i]; d1 > d2 {
+					nleft++
+					nright++
+					continue
+				}
+			}
+			var ok bool
+			ys[i], ok = lowest(x, y, x[i], nleft, nright, w, rw, iter > 1)
+			if !ok {
+				ys[i] = y[i]
+			}
+			if last < i-1 {
+				// interpolate the points skipped
+				denom := x[i] - x[last]
+				for j := last + 1; j < i; j++ {
+					alpha := (x[j] - x[last]) / denom
+					ys[j] = alpha*ys[i] + (1-alpha)*ys[last]
+				}
+			}
+			last = i
+			cut := x[last] + delta
+			for i = last + 1; i < n; i++ {
+				if x[i] > cut {
+					break
+				}
+				if x[i] == x[last] {
+					ys[i] = ys[last]
+					last = i
+				}
+			}
+			if i-1 > last+1 {
+				i--
+			} else {
+				i = last + 1
+			}
+			if last >= n-1 {
+				break
+			}
+		}
+		for i := range res {
+			res[i] = y[i] - ys[i]
+		}
+		if iter > nsteps {
+			break
+		}
+
+		// the bisquare robustness weights of the residuals, over six median
+		// absolute residuals
+		sc := 0.0
+		for i, r := range res {
+			rw[i] = math.Abs(r)
+			sc += rw[i]
+		}
+		sc /= float64(n)
+		cmad := 6 * median(rw)
+		if cmad < 1e-7*sc {
+			break
+		}
+		c9, c1 := 0.999*cmad, 0.001*cmad
+		for i, r := range res {
+			r = math.Abs(r)
+			switch {
+			case r <= c1:
+				rw[i] = 1
+			case r <= c9:
+				u := r / cmad
+				rw[i] = (1 - u*u) * (1 - u*u)
+			default:
+				rw[i] = 0
+			}
+		}
+	}
+	return ys
+}
+
+// lowest is the fit at xs of the weighted line through the points nleft to
+// nright, and those to the right tied with nright, weighted by the tricube
+// kernel and, if robust, by rw. It isn't ok if every weight is zero.
+func lowest(x, y []float64, xs float64, nleft, nright int, w, rw []float64, robust bool) (float64, bool) {
+	n := len(x)
+	span := x[n-1] - x[0]
+	h := math.Max(xs-x[nleft], x[nright]-xs)
+	h9, h1 := 0.999*h, 0.001*h
+	a := 0.0
+	j := nleft
+	for ; j < n; j++ {
+		w[j] = 0
+		r := math.Abs(x[j] - xs)
+		if r <= h9 {
+			if r <= h1 {
+				w[j] = 1
+			} else {
+				u := r / h
+				u = 1 - u*u*u
+				w[j] = u * u * u
+			}
+			if robust {
+				w[j] *= rw[j]
+			}
+			a += w[j]
+		} else if x[j] > xs {
+			break
+		}
+	}
+	nrt := j - 1
+	if a <= 0 {
+		return 0, false
+	}
+	for j := nleft; j <= nrt; j++ {
+		w[j] /= a
+	}
+	if h > 0 {
+		// the line through the weighted center of the x
+		a = 0
+		for j := nleft; j <= nrt; j++ {
+			a += w[j] * x[j]
+		}
+		b := xs - a
+		c := 0.0
+		for j := nleft; j <= nrt; j++ {
+			c += w[j] * (x[j] - a) * (x[j] - a)
+		}
+		if math.Sqrt(c) > 0.001*span {
+			b /= c
+			for j := nleft; j <= nrt; j++ {
+				w[j] *= b*(x[j]-a) + 1
+			}
+		}
+	}
+	ys := 0.0
+	for j := nleft; j <= nrt; j++ {
+		ys += w[j] * y[j]
+	}
+	return ys, true
+}
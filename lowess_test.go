@@ -0,0 +1,81 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// R's cars data, speed and stopping distance
+var (
+	carsSpeed = []float64{4, 4, 7, 7, 8, 9, 10, 10, 10, 11, 11, 12, 12, 12, 12, 13, 13, 13, 13, 14, 14, 14, 14, 15, 15,
+		15, 16, 16, 17, 17, 17, 18, 18, 18, 18, 19, 19, 19, 20, 20, 20, 20, 20, 22, 23, 24, 24, 24, 24, 25}
+	carsDist = []float64{2, 10, 4, 22, 16, 10, 18, 26, 34, 17, 28, 14, 20, 24, 28, 26, 34, 34, 46, 26, 36, 60, 80, 20, 26,
+		54, 32, 40, 32, 40, 50, 42, 56, 76, 84, 36, 46, 68, 32, 48, 52, 56, 64, 66, 54, 70, 92, 93, 120, 85}
+)
+
+func TestLowess(t *testing.T) {
+	// lowess(cars)$y
+	want := []float64{4.965459, 4.965459, 13.124495, 13.124495, 15.858633, 18.579691, 21.280313, 21.280313,
+		21.280313, 24.129277, 24.129277, 27.119549, 27.119549, 27.119549, 27.119549, 30.027276, 30.027276,
+		30.027276, 30.027276, 32.962506, 32.962506, 32.962506, 32.962506, 36.757728, 36.757728, 36.757728,
+		40.435075, 40.435075, 43.463492, 43.463492, 43.463492, 46.885479, 46.885479, 46.885479, 46.885479,
+		50.793152, 50.793152, 50.793152, 56.491224, 56.491224, 56.491224, 56.491224, 56.491224, 67.585824,
+		73.079695, 78.643164, 78.643164, 78.643164, 78.643164, 84.328698}
+	fitted, err := Lowess(carsSpeed, carsDist, 2.0/3, 3)
+	assert.Equal(t, nil, err)
+	for i := range want {
+		assertClose(t, fitted[i], want[i], 1e-6)
+	}
+
+	// the fits are at the points in their original order
+	rng := rand.New(rand.NewSource(2))
+	perm := rng.Perm(len(carsSpeed))
+	x, y := make([]float64, len(perm)), make([]float64, len(perm))
+	for k, i := range perm {
+		x[k], y[k] = carsSpeed[i], carsDist[i]
+	}
+	shuffled, err := Lowess(x, y, 2.0/3, 3)
+	assert.Equal(t, nil, err)
+	for k, i := range perm {
+		assertClose(t, shuffled[k], fitted[i], 1e-12)
+	}
+
+	// a line is its own smooth, and without robustness iterations an outlier
+	// pulls the curve toward it
+	line := make([]float64, 20)
+	outlier := make([]float64, 20)
+	idx := make([]float64, 20)
+	for i := range line {
+		idx[i] = float64(i)
+		line[i] = 2 + 0.5*float64(i)
+		outlier[i] = line[i] + 0.1*rng.NormFloat64()
+	}
+	outlier[10] += 20
+	smooth, err := Lowess(idx, line, 0.5, 0)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, smooth, line, 1e-12)
+	plain, err := Lowess(idx, outlier, 0.5, 0)
+	assert.Equal(t, nil, err)
+	robust, err := Lowess(idx, outlier, 0.5, 3)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.Abs(plain[9]-line[9]) > 1, plain[9])
+	assert.T(t, math.Abs(robust[9]-line[9]) < 0.2, robust[9])
+
+	for _, c := range []struct {
+		span  float64
+		iters int
+	}{{0, 3}, {1.5, 3}, {math.NaN(), 3}, {0.5, -1}} {
+		_, err := Lowess(carsSpeed, carsDist, c.span, c.iters)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err = Lowess(carsSpeed, carsDist[1:], 0.5, 3)
+	assert.Equal(t, DimensionError, err)
+	_, err = Lowess([]float64{1, math.NaN()}, []float64{1, 2}, 0.5, 3)
+	assert.NotEqual(t, nil, err)
+	single, err := Lowess([]float64{1}, []float64{3}, 0.5, 3)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []float64{3}, single)
+}
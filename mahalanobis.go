@@ -0,0 +1,299 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// CovarianceEstimator is a way of estimating the covariance matrix of the
+// columns of a design.
+type CovarianceEstimator int
+
+const (
+	// SampleCovariance is the sample covariance with divisor n - 1, as R's cov
+	// computes it, the default.
+	SampleCovariance CovarianceEstimator = iota
+
+	// ShrunkCovariance is the Ledoit-Wolf estimate of ShrinkCovariance, which
+	// is positive definite even when there are more columns than observations.
+	ShrunkCovariance
+
+	// RobustCovariance is a minimum covariance determinant estimate, the mean
+	// and covariance of the h = (n + p + 1) / 2 observations whose covariance
+	// has the least determinant, which a minority of outliers can't mask.
+	RobustCovariance
+)
+
+func (c CovarianceEstimator) String() string {
+	switch c {
+	case SampleCovariance:
+		return "sample"
+	case ShrunkCovariance:
+		return "shrunk"
+	case RobustCovariance:
+		return "robust"
+	}
+	return fmt.Sprintf("CovarianceEstimator(%d)", int(c))
+}
+
+// mcdStarts is the number of random starts of a RobustCovariance estimate
+// unless WithSubsets sets it.
+const mcdStarts = 500
+
+// mcdCandidates is the number of the best starts that are concentrated until
+// they converge.
+const mcdCandidates = 10
+
+// Mahalanobis returns the squared Mahalanobis distance of each row x_i of the
+// n x p matrix x from the column means \bar{x},
+//
+// d_i^2 = (x_i - \bar{x})' \Sigma^{-1} (x_i - \bar{x})
+//
+// as R's mahalanobis(x, colMeans(x), cov(x)) does, with the covariance \Sigma
+// estimated as WithCovariance sets. Where leverage measures outlyingness
+// relative to a fitted model, these measure it in the design alone; for
+// Gaussian rows they are about chi-squared with p degrees of freedom, and
+// MahalanobisPValues turns them into p-values for flagging.
+//
+// The distances are found from the Cholesky factor \Sigma = U'U as the squared
+// norms of U'^{-1}(x_i - \bar{x}). A covariance that is singular, as the sample
+// covariance is for n <= p or with a column that is constant or a linear
+// combination of the others, is a SingularDesignError; the ShrunkCovariance
+// estimate is positive definite whatever n and p are.
+//
+// The RobustCovariance estimate is centered on the mean of its h observations
+// rather than the column means. It is found by the concentration steps of
+// Rousseeuw and Van Driessen's FAST-MCD (1999) from random subsets of p + 1
+// observations, 500 unless WithSubsets sets the number and drawn from
+// WithSeed: each subset is replaced twice by the h observations nearest its
+// mean in the metric of its covariance, and the ten with the least
+// determinant are concentrated until it stops decreasing. The covariance is
+// then scaled so that the median distance is the median of the chi-squared
+// distribution with p degrees of freedom, which makes it consistent for
+// Gaussian data; unlike R's robustbase::covMcd it isn't reweighted.
+func Mahalanobis(x *mat.Dense, opts ...Option) ([]float64, error) {
+	o := newOptions(append([]Option{WithSubsets(mcdStarts)}, opts...))
+	n, p := x.Dims()
+	if n < 2 {
+		return nil, fmt.Errorf("%w: %d observations", TooFewObservationsError, n)
+	}
+	for j := 0; j < p; j++ {
+		if s := sum(mat.Col(nil, j, x)); math.IsNaN(s) || math.IsInf(s, 0) {
+			return nil, fmt.Errorf("column %d has a value that isn't finite", j)
+		}
+	}
+
+	var center []float64
+	var sigma *mat.SymDense
+	switch o.covariance {
+	case SampleCovariance:
+		rows := make([]int, n)
+		for i := range rows {
+			rows[i] = i
+		}
+		center, sigma = meanCovariance(x, rows)
+	case ShrunkCovariance:
+		var err error
+		if sigma, _, err = ShrinkCovariance(x); err != nil {
+			return nil, err
+		}
+		center = make([]float64, p)
+		for j := range center {
+			center[j] = mean(mat.Col(nil, j, x))
+		}
+	case RobustCovariance:
+		var err error
+		if center, sigma, err = minimumCovariance(x, o); err != nil {
+			return nil, err
+		}
+	default:
+		panic(fmt.Sprintf("unknown covariance estimator %v", o.covariance))
+	}
+
+	u, err := covarianceFactor(sigma)
+	if err != nil {
+		return nil, fmt.Errorf("%w; ShrinkCovariance, or WithCovariance(ShrunkCovariance), gives one that isn't", err)
+	}
+	return squaredDistances(x, center, u), nil
+}
+
+// MahalanobisPValues returns the probability that a chi-squared random
+// variable with p degrees of freedom, for the p columns the distances were
+// computed from, exceeds each of the squared distances d. Under a Gaussian
+// design they are uniform, so a small one flags its row as an outlier; those
+// below a level α flag about a fraction α of the rows of data without any.
+func MahalanobisPValues(d []float64, p int) []float64 {
+	pvalues := make([]float64, len(d))
+	for i, di := range d {
+		pvalues[i] = chiSquareSurvival(di, float64(p))
+	}
+	return pvalues
+}
+
+// meanCovariance returns the mean and the sample covariance, with divisor
+// len(rows) - 1, of the given rows of x.
+func meanCovariance(x *mat.Dense, rows []int) ([]float64, *mat.SymDense) {
+	_, p := x.Dims()
+	center := make([]float64, p)
+	for _, i := range rows {
+		for j, v := range x.RawRowView(i) {
+			center[j] += v
+		}
+	}
+	for j := range center {
+		center[j] /= float64(len(rows))
+	}
+	cov := mat.NewSymDense(p, nil)
+	r := make([]float64, p)
+	for _, i := range rows {
+		for j, v := range x.RawRowView(i) {
+			r[j] = v - center[j]
+		}
+		for j := 0; j < p; j++ {
+			for k := j; k < p; k++ {
+				cov.SetSym(j, k, cov.At(j, k)+r[j]*r[k])
+			}
+		}
+	}
+	cov.ScaleSym(1/float64(len(rows)-1), cov)
+	return center, cov
+}
+
+// covarianceFactor returns the upper triangular Cholesky factor U of sigma =
+// U'U, or a SingularDesignError if sigma is singular: a pivot U_jj^2 within
+// pivotTolerance of sigma_jj is a column that is a linear combination of
+// those before it but for rounding.
+func covarianceFactor(sigma *mat.SymDense) (*mat.TriDense, error) {
+	chol := &mat.Cholesky{}
+	if !chol.Factorize(sigma) {
+		return nil, fmt.Errorf("%w: the covariance matrix isn't positive definite", SingularDesignError)
+	}
+	u := &mat.TriDense{}
+	chol.UTo(u)
+	for j := 0; j < sigma.SymmetricDim(); j++ {
+		if d := u.At(j, j); !(d*d > pivotTolerance*sigma.At(j, j)) {
+			return nil, fmt.Errorf("%w: the covariance matrix is singular, column %d being constant or a linear combination of the columns before it", SingularDesignError, j)
+		}
+	}
+	return u, nil
+}
+
+// squaredDistances returns ||U'^{-1}(x_i - center)||^2 for each row x_i of x,
+// solving U'z = x_i - center by forward substitution.
+func squaredDistances(x *mat.Dense, center []float64, u *mat.TriDense) []float64 {
+	n, p := x.Dims()
+	d := make([]float64, n)
+	z := make([]float64, p)
+	for i := range d {
+		row := x.RawRowView(i)
+		for j := 0; j < p; j++ {
+			v := row[j] - center[j]
+			for k := 0; k < j; k++ {
+				v -= u.At(k, j) * z[k]
+			}
+			z[j] = v / u.At(j, j)
+		}
+		d[i] = sum(prod(z, z))
+	}
+	return d
+}
+
+// mcdSubset is a subset of the observations in a minimum covariance
+// determinant search, with its mean, the Cholesky factor of its covariance
+// and the log of its determinant.
+type mcdSubset struct {
+	rows   []int
+	center []float64
+	cov    *mat.SymDense
+	u      *mat.TriDense
+	logdet float64
+}
+
+// newMCDSubset estimates the mean and covariance of the rows of x, or returns
+// false if the covariance is singular.
+func newMCDSubset(x *mat.Dense, rows []int) (*mcdSubset, bool) {
+	center, cov := meanCovariance(x, rows)
+	u, err := covarianceFactor(cov)
+	if err != nil {
+		return nil, false
+	}
+	logdet := 0.0
+	for j := 0; j < len(center); j++ {
+		logdet += 2 * math.Log(u.At(j, j))
+	}
+	return &mcdSubset{rows: rows, center: center, cov: cov, u: u, logdet: logdet}, true
+}
+
+// concentrate returns the subset of the h observations nearest the mean of s
+// in the metric of its covariance, whose determinant is no greater.
+func (s *mcdSubset) concentrate(x *mat.Dense, h int) (*mcdSubset, bool) {
+	d := squaredDistances(x, s.center, s.u)
+	order := make([]int, len(d))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return d[order[a]] < d[order[b]] })
+	return newMCDSubset(x, order[:h])
+}
+
+// minimumCovariance returns the RobustCovariance estimate of the location and
+// covariance of the rows of x.
+func minimumCovariance(x *mat.Dense, o options) ([]float64, *mat.SymDense, error) {
+	n, p := x.Dims()
+	if n < p+1 {
+		return nil, nil, fmt.Errorf("%w: %d observations for the robust covariance of %d columns", TooFewObservationsError, n, p)
+	}
+	if o.subsets < 1 {
+		return nil, nil, fmt.Errorf("%d random starts is not positive", o.subsets)
+	}
+	h := (n + p + 1) / 2
+	rng := o.rng()
+
+	var candidates []*mcdSubset
+	for start := 0; start < o.subsets; start++ {
+		if err := o.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		s, ok := newMCDSubset(x, rng.Perm(n)[:p+1])
+		for step := 0; ok && step < 2; step++ {
+			s, ok = s.concentrate(x, h)
+		}
+		if ok {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("%w: the covariance of every random start was singular", SingularDesignError)
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].logdet < candidates[b].logdet })
+	if len(candidates) > mcdCandidates {
+		candidates = candidates[:mcdCandidates]
+	}
+
+	var best *mcdSubset
+	for _, s := range candidates {
+		for iter := 0; iter < o.maxIter; iter++ {
+			next, ok := s.concentrate(x, h)
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: more than %d of the observations lie on a hyperplane", SingularDesignError, h-1)
+			}
+			if !(next.logdet < s.logdet) {
+				break
+			}
+			s = next
+		}
+		if best == nil || s.logdet < best.logdet {
+			best = s
+		}
+	}
+
+	// the covariance of the most concentrated half is too small; scale it to
+	// match the median distance to the chi-squared median
+	scale := median(squaredDistances(x, best.center, best.u)) / chiSquareQuantile(0.5, float64(p))
+	cov := mat.NewSymDense(p, nil)
+	cov.ScaleSym(scale, best.cov)
+	return best.center, cov, nil
+}
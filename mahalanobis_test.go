@@ -0,0 +1,121 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMahalanobis(t *testing.T) {
+	// (x_i - m)' S^{-1} (x_i - m) with the column means and the covariance of
+	// divisor n - 1, as R's mahalanobis(x, colMeans(x), cov(x)) defines it,
+	// in exact rational arithmetic; R hasn't been run against these
+	want := []float64{2.337013508666659, 2.337013508666659, 2.1263298446398835, 2.9549537717384182, 5.9198120825864775, 7.398459559195666, 7.775962442571661, 3.2820397181174172, 3.013427534179456, 1.6697199502762414, 3.494645169998359, 2.6549601010298405, 3.035662808333262}
+	d, err := Mahalanobis(mtcars)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, d, want, 1e-12)
+	// the distances from the sample covariance sum to (n - 1) p
+	assertClose(t, sum(d), 48, 1e-11)
+
+	pvalues := MahalanobisPValues(d, 4)
+	for i, di := range d {
+		assertClose(t, pvalues[i], chiSquareSurvival(di, 4), 0)
+	}
+}
+
+func TestMahalanobisShrunk(t *testing.T) {
+	// more columns than observations leave the sample covariance singular
+	_, err := Mahalanobis(wide)
+	assert.T(t, errors.Is(err, SingularDesignError))
+
+	d, err := Mahalanobis(wide, WithCovariance(ShrunkCovariance))
+	assert.Equal(t, nil, err)
+	sigma, _, err := ShrinkCovariance(wide)
+	assert.Equal(t, nil, err)
+	inv := &mat.Dense{}
+	assert.Equal(t, nil, inv.Inverse(sigma))
+	for i := 0; i < 6; i++ {
+		r := mat.NewVecDense(8, subSlice(wide.RawRowView(i), 0))
+		for j := 0; j < 8; j++ {
+			r.SetVec(j, r.At(j, 0)-mean(mat.Col(nil, j, wide)))
+		}
+		s := mat.NewVecDense(8, nil)
+		s.MulVec(inv, r)
+		assertClose(t, d[i], mat.Dot(r, s), 1e-10)
+	}
+}
+
+// gaussianRows returns n rows of p correlated Gaussian columns.
+func gaussianRows(rng *rand.Rand, n, p int) *mat.Dense {
+	x := mat.NewDense(n, p, nil)
+	for i := 0; i < n; i++ {
+		z := 0.0
+		for j := 0; j < p; j++ {
+			z = 0.6*z + rng.NormFloat64()
+			x.Set(i, j, 10*float64(j)+z)
+		}
+	}
+	return x
+}
+
+func TestMahalanobisCalibration(t *testing.T) {
+	// on Gaussian data the distances are about chi-squared with p degrees of
+	// freedom, so about 5% of the p-values are below 0.05
+	rng := rand.New(rand.NewSource(7))
+	x := gaussianRows(rng, 4000, 3)
+	for _, c := range []CovarianceEstimator{SampleCovariance, ShrunkCovariance, RobustCovariance} {
+		d, err := Mahalanobis(x, WithCovariance(c), WithSubsets(50))
+		assert.Equal(t, nil, err, c)
+		assertClose(t, mean(d), 3, 0.15)
+		flagged := 0
+		for _, pv := range MahalanobisPValues(d, 3) {
+			if pv < 0.05 {
+				flagged++
+			}
+		}
+		assertClose(t, float64(flagged)/4000, 0.05, 0.01)
+	}
+}
+
+func TestMahalanobisRobust(t *testing.T) {
+	// a cluster of outliers inflates the sample covariance enough to hide
+	// them, but not the robust one
+	rng := rand.New(rand.NewSource(3))
+	x := gaussianRows(rng, 100, 2)
+	for i := 0; i < 15; i++ {
+		x.SetRow(i, []float64{4 + 0.1*rng.NormFloat64(), 6 + 0.1*rng.NormFloat64()})
+	}
+	sample, err := Mahalanobis(x)
+	assert.Equal(t, nil, err)
+	robust, err := Mahalanobis(x, WithCovariance(RobustCovariance))
+	assert.Equal(t, nil, err)
+	sp, rp := MahalanobisPValues(sample, 2), MahalanobisPValues(robust, 2)
+	for i := 0; i < 15; i++ {
+		assert.T(t, sp[i] > 0.001, i, sp[i])
+		assert.T(t, rp[i] < 1e-6, i, rp[i])
+	}
+
+	// the search is reproducible from its seed
+	again, err := Mahalanobis(x, WithCovariance(RobustCovariance))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, robust, again)
+}
+
+func TestMahalanobisErrors(t *testing.T) {
+	constant := mat.NewDense(4, 2, []float64{1, 5, 2, 5, 3, 5, 6, 5})
+	_, err := Mahalanobis(constant)
+	assert.T(t, errors.Is(err, SingularDesignError))
+	_, err = Mahalanobis(constant, WithCovariance(ShrunkCovariance))
+	assert.Equal(t, nil, err)
+
+	_, err = Mahalanobis(mat.NewDense(1, 2, []float64{1, 2}))
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = Mahalanobis(mat.NewDense(3, 3, []float64{1, 2, 3, 2, 0, 1, 4, 4, 4}), WithCovariance(RobustCovariance))
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, err = Mahalanobis(mat.NewDense(2, 1, []float64{1, math.NaN()}))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,58 @@
+package glasso
+
+import "math"
+
+// MSE returns the mean squared error of the predictions of the actual values,
+// \frac{1}{n} \sum (y_i - \hat{y}_i)^2. The metrics are NaN for slices that
+// are empty or of different lengths.
+func MSE(actual, predicted []float64) float64 {
+	if len(actual) == 0 || len(actual) != len(predicted) {
+		return math.NaN()
+	}
+	e := diff(actual, predicted)
+	return sum(prod(e, e)) / float64(len(actual))
+}
+
+// RMSE returns the root mean squared error, \sqrt{MSE}.
+func RMSE(actual, predicted []float64) float64 {
+	return math.Sqrt(MSE(actual, predicted))
+}
+
+// MAE returns the mean absolute error, \frac{1}{n} \sum |y_i - \hat{y}_i|.
+func MAE(actual, predicted []float64) float64 {
+	if len(actual) == 0 || len(actual) != len(predicted) {
+		return math.NaN()
+	}
+	total := 0.0
+	for i := range actual {
+		total += math.Abs(actual[i] - predicted[i])
+	}
+	return total / float64(len(actual))
+}
+
+// MAPE returns the mean absolute percentage error as a fraction,
+// \frac{1}{n} \sum |y_i - \hat{y}_i| / |y_i|. It is infinite if an actual
+// value is zero and its prediction isn't.
+func MAPE(actual, predicted []float64) float64 {
+	if len(actual) == 0 || len(actual) != len(predicted) {
+		return math.NaN()
+	}
+	total := 0.0
+	for i := range actual {
+		if actual[i] != predicted[i] {
+			total += math.Abs((actual[i] - predicted[i]) / actual[i])
+		}
+	}
+	return total / float64(len(actual))
+}
+
+// RSquaredScore returns the coefficient of determination of the predictions,
+// 1 - \sum (y_i - \hat{y}_i)^2 / \sum (y_i - \bar{y})^2, which is negative for
+// predictions worse than the mean of the actual values.
+func RSquaredScore(actual, predicted []float64) float64 {
+	if len(actual) == 0 || len(actual) != len(predicted) {
+		return math.NaN()
+	}
+	e, centered := diff(actual, predicted), subtractMean(actual)
+	return 1 - sum(prod(e, e))/sum(prod(centered, centered))
+}
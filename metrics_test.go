@@ -0,0 +1,47 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	actual := []float64{3, -0.5, 2, 7}
+	predicted := []float64{2.5, 0.0, 2, 8}
+	// the examples of scikit-learn's metrics
+	for _, test := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"MSE", MSE(actual, predicted), 0.375},
+		{"RMSE", RMSE(actual, predicted), math.Sqrt(0.375)},
+		{"MAE", MAE(actual, predicted), 0.5},
+		{"MAPE", MAPE(actual, predicted), (0.5/3 + 1 + 0 + 1./7) / 4},
+		{"R^2", RSquaredScore(actual, predicted), 0.9486081370449679},
+	} {
+		if math.Abs(test.got-test.want) > 1e-12 {
+			t.Errorf("%s is %v, want %v", test.name, test.got, test.want)
+		}
+	}
+
+	// predicting the mean has an R^2 of zero, and worse is negative
+	if r := RSquaredScore(actual, rep(mean(actual), 4)); math.Abs(r) > 1e-12 {
+		t.Errorf("R^2 of the mean is %v", r)
+	}
+	if r := RSquaredScore(actual, []float64{7, 2, -0.5, 3}); r >= 0 {
+		t.Errorf("R^2 of reversed predictions is %v", r)
+	}
+
+	// zero errors, a perfect prediction of zero, and a zero actual value
+	if MSE(actual, actual) != 0 || MAPE([]float64{0, 1}, []float64{0, 1}) != 0 {
+		t.Error("perfect predictions have errors")
+	}
+	if !math.IsInf(MAPE([]float64{0, 1}, []float64{1, 1}), 1) {
+		t.Error("MAPE with a zero actual value is finite")
+	}
+	for name, metric := range map[string]func(a, p []float64) float64{"MSE": MSE, "RMSE": RMSE, "MAE": MAE, "MAPE": MAPE, "R^2": RSquaredScore} {
+		if !math.IsNaN(metric(actual, predicted[1:])) || !math.IsNaN(metric(nil, nil)) {
+			t.Errorf("%s isn't NaN for mismatched or empty slices", name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// An NAPolicy says how a least squares fit handles missing values, which are
+// NaN, in its training data. Infinite values aren't missing, and are an error
+// under every policy.
+type NAPolicy int
+
+const (
+	ErrorOnNA  NAPolicy = iota // a missing value is an error, naming its row and column
+	OmitRows                   // rows with a missing value are dropped (listwise deletion)
+	ImputeMean                 // missing predictors are the mean of the rest of their column, and rows with a missing response are dropped
+)
+
+// handleMissing applies the policy to the training data. It returns the data
+// to fit, and the rows of x they come from, or nil if they are all of the rows
+// unchanged.
+func handleMissing(x *DataFrame, y []float64, policy NAPolicy) (*DataFrame, []float64, []int, error) {
+	if len(y) != x.Rows() {
+		return nil, nil, nil, DimensionError
+	}
+	if policy != ErrorOnNA && policy != OmitRows && policy != ImputeMean {
+		return nil, nil, nil, fmt.Errorf("unknown missing value policy %d", policy)
+	}
+	var rows []int
+	names := x.names()
+	missing := false
+	for i, v := range y {
+		if math.IsInf(v, 0) {
+			return nil, nil, nil, fmt.Errorf("response %d is %v", i, v)
+		}
+		complete := !math.IsNaN(v)
+		if !complete && policy == ErrorOnNA {
+			return nil, nil, nil, fmt.Errorf("response %d is missing", i)
+		}
+		for j, v := range x.GetRow(i) {
+			if math.IsInf(v, 0) {
+				return nil, nil, nil, fmt.Errorf("row %d, column %q is %v", i, names[j], v)
+			}
+			if math.IsNaN(v) {
+				if policy == ErrorOnNA {
+					return nil, nil, nil, fmt.Errorf("row %d, column %q is missing", i, names[j])
+				}
+				missing = true
+				complete = complete && policy == ImputeMean
+			}
+		}
+		if complete {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) == x.Rows() && !missing {
+		return x, y, nil, nil
+	}
+	if len(rows) == 0 {
+		return nil, nil, nil, fmt.Errorf("every row has a missing value")
+	}
+
+	data := make([][]float64, len(rows))
+	response := make([]float64, len(rows))
+	for k, i := range rows {
+		data[k] = x.GetRow(i)
+		response[k] = y[i]
+	}
+	if policy == ImputeMean {
+		for j := 0; j < x.Cols(); j++ {
+			total, count := 0.0, 0
+			for _, row := range data {
+				if !math.IsNaN(row[j]) {
+					total += row[j]
+					count++
+				}
+			}
+			if count == 0 {
+				return nil, nil, nil, fmt.Errorf("column %q has no values to impute from", names[j])
+			}
+			for _, row := range data {
+				if math.IsNaN(row[j]) {
+					row[j] = total / float64(count)
+				}
+			}
+		}
+	}
+	if len(rows) == x.Rows() {
+		rows = nil
+	}
+	return NewDataFrame(data, x.Labels()), response, rows, nil
+}
+
+// compose maps the rows kept by a second step of a fit, indices into the rows
+// kept by the first, back to the original rows. nil means every row.
+func compose(first, second []int) []int {
+	switch {
+	case first == nil:
+		return second
+	case second == nil:
+		return first
+	}
+	rows := make([]int, len(second))
+	for k, i := range second {
+		rows[k] = first[i]
+	}
+	return rows
+}
+
+// identityRows returns the rows 0, 1, ..., n - 1.
+func identityRows(n int) []int {
+	rows := make([]int, n)
+	for i := range rows {
+		rows[i] = i
+	}
+	return rows
+}
@@ -0,0 +1,127 @@
+package glasso
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// stacklossMissing returns the stackloss data with NaNs in a predictor of row
+// 2, another predictor of row 9, both of row 15 and the response of row 20.
+func stacklossMissing() ([][]float64, []float64) {
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = append([]float64(nil), row...)
+	}
+	response := append([]float64(nil), y...)
+	rows[2][0] = math.NaN()
+	rows[9][2] = math.NaN()
+	rows[15][1], rows[15][2] = math.NaN(), math.NaN()
+	response[20] = math.NaN()
+	return rows, response
+}
+
+func TestErrorOnNA(t *testing.T) {
+	rows, response := stacklossMissing()
+	_, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.NotEqual(t, nil, err)
+	assert.T(t, strings.Contains(err.Error(), `row 2, column "x0"`), err)
+
+	_, _, err = NewOlsTrainer().Train(NewDataFrame(data), response)
+	assert.NotEqual(t, nil, err)
+	assert.T(t, strings.Contains(err.Error(), "response 20"), err)
+
+	// infinite values aren't missing
+	rows = [][]float64{{1, 2}, {2, math.Inf(1)}, {3, 5}, {4, 3}, {5, 4}}
+	_, _, err = NewOlsTrainer(WithNAPolicy(OmitRows)).Train(NewDataFrame(rows), []float64{1, 2, 3, 4, 5})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestOmitRows(t *testing.T) {
+	rows, response := stacklossMissing()
+	m, s, err := NewOlsTrainer(WithNAPolicy(OmitRows)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	retained := []int{0, 1, 3, 4, 5, 6, 7, 8, 10, 11, 12, 13, 14, 16, 17, 18, 19}
+	assert.Equal(t, s.(OlsSummary).RetainedRows(), retained)
+	assert.Equal(t, m.(*OLS).RetainedRows(), retained)
+
+	// the fit and its diagnostics are those of the complete rows
+	var complete [][]float64
+	var completeY []float64
+	for _, i := range retained {
+		complete = append(complete, data[i])
+		completeY = append(completeY, y[i])
+	}
+	mc, sc, err := NewOlsTrainer().Train(NewDataFrame(complete), completeY)
+	assert.Equal(t, nil, err)
+	for j, b := range sc.Coefficients() {
+		assertClose(t, s.Coefficients()[j], b, 1e-10)
+	}
+	cooks, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	cooksComplete, err := CooksDistance(sc)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(cooks), len(retained))
+	for k := range retained {
+		assertClose(t, s.Residuals()[k], sc.Residuals()[k], 1e-10)
+		assertClose(t, cooks[k], cooksComplete[k], 1e-10)
+	}
+	assert.Equal(t, mc.(*OLS).RetainedRows(), identityRows(len(retained)))
+
+	// the mapping is saved with the model
+	b, err := json.Marshal(m)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assert.Equal(t, decoded.RetainedRows(), retained)
+
+	// with weights, rows of weight zero are dropped too
+	w := rep(1, len(y))
+	w[5] = 0
+	_, ws, err := NewWlsTrainer(w, WithNAPolicy(OmitRows)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ws.(OlsSummary).RetainedRows(), []int{0, 1, 3, 4, 6, 7, 8, 10, 11, 12, 13, 14, 16, 17, 18, 19})
+	cooks, err = CooksDistance(ws)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(cooks), 16)
+
+	// a row of nothing but NaNs can't be fit
+	_, _, err = NewOlsTrainer(WithNAPolicy(OmitRows)).Train(NewDataFrame([][]float64{{math.NaN()}, {math.NaN()}}), []float64{1, 2})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestImputeMean(t *testing.T) {
+	rows, response := stacklossMissing()
+	_, s, err := NewOlsTrainer(WithNAPolicy(ImputeMean)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	// only the row with a missing response is dropped
+	retained := s.(OlsSummary).RetainedRows()
+	assert.Equal(t, retained, identityRows(20))
+
+	// missing predictors are the means of the rest of the first 20 rows
+	design := s.Data()
+	imputed := func(j int, missing ...int) float64 {
+		total, count := 0.0, 0
+		for i := 0; i < 20; i++ {
+			skip := false
+			for _, k := range missing {
+				skip = skip || i == k
+			}
+			if !skip {
+				total += data[i][j]
+				count++
+			}
+		}
+		return total / float64(count)
+	}
+	// the design's first column is the intercept
+	assertClose(t, design.X.At(2, 1), imputed(0, 2), 1e-12)
+	assertClose(t, design.X.At(9, 3), imputed(2, 9, 15), 1e-12)
+	assertClose(t, design.X.At(15, 2), imputed(1, 15), 1e-12)
+	assertClose(t, design.X.At(15, 3), imputed(2, 9, 15), 1e-12)
+	assert.Equal(t, design.X.At(3, 1), data[3][0])
+}
@@ -0,0 +1,116 @@
+package glasso
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// MultiOLS is the least squares fit of several responses on the same design,
+// a column of Y each, as R's lm fits a matrix response. The fits are those
+// that NewOlsTrainer would make of the columns one at a time.
+type MultiOLS struct {
+	// Coefficients holds a column of coefficients for each response, the
+	// intercept first unless the fit is through the origin.
+	Coefficients *mat.Dense
+
+	summaries []OlsSummary
+}
+
+// FitMulti fits each column of Y on the columns of x by least squares, with an
+// intercept column unless WithIntercept(false) is given. The design is
+// factorized once and solved for every response at the same time, and the
+// summaries of the responses share the factorization, so that diagnostics
+// that depend on the design alone, such as LeveragePoints, are the same for
+// all of them and computed from it without refactorizing. x and Y must have
+// the same number of rows, or it is a DimensionError.
+func FitMulti(x *mat.Dense, Y *mat.Dense, opts ...Option) (*MultiOLS, error) {
+	o := newOptions(opts)
+	n, _ := x.Dims()
+	rows, m := Y.Dims()
+	if rows != n {
+		return nil, DimensionError
+	}
+	design := MatToDF(mat.DenseCopyOf(x))
+	if o.intercept {
+		design.PushCol(rep(1, n))
+	}
+	p := design.Cols()
+	if n < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+
+	qr := factorize(design.X)
+	betas := &mat.Dense{}
+	if err := qr.SolveTo(betas, false, Y); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	fitted := &mat.Dense{}
+	fitted.Mul(design.X, betas)
+	residuals := &mat.Dense{}
+	residuals.Sub(Y, fitted)
+
+	cache := &qrCache{x: design.X, qr: qr}
+	fit := &MultiOLS{Coefficients: betas, summaries: make([]OlsSummary, m)}
+	for k := range fit.summaries {
+		fit.summaries[k] = OlsSummary{
+			betas:     mat.Col(nil, k, betas),
+			residuals: mat.Col(nil, k, residuals),
+			fitted:    mat.Col(nil, k, fitted),
+			response:  mat.Col(nil, k, Y),
+			n:         n,
+			p:         p,
+			data:      design,
+			qr:        cache,
+			origin:    !o.intercept,
+		}
+	}
+	return fit, nil
+}
+
+// Responses returns the number of responses fit.
+func (f *MultiOLS) Responses() int { return len(f.summaries) }
+
+// Summary returns the summary of the fit of the kth response, for the
+// diagnostics of a single fit: CooksDistance(f.Summary(k)), for instance.
+func (f *MultiOLS) Summary(k int) OlsSummary { return f.summaries[k] }
+
+// Residuals returns the residuals of the kth response.
+func (f *MultiOLS) Residuals(k int) []float64 { return f.summaries[k].residuals }
+
+// RSquared returns the R^2 of each response.
+func (f *MultiOLS) RSquared() []float64 {
+	r2 := make([]float64, len(f.summaries))
+	for k, s := range f.summaries {
+		r2[k] = s.RSquared()
+	}
+	return r2
+}
+
+// MeanSquaredError returns RSS / n for each response, as
+// OlsSummary.MeanSquaredError does; see MseAdjusted for RSS / (n - p).
+func (f *MultiOLS) MeanSquaredError() []float64 {
+	mse := make([]float64, len(f.summaries))
+	for k, s := range f.summaries {
+		mse[k] = s.MeanSquaredError()
+	}
+	return mse
+}
+
+// LeveragePoints returns the diagonal of the hat matrix of the design, which
+// every response shares.
+func (f *MultiOLS) LeveragePoints() ([]float64, error) {
+	return LeveragePoints(f.summaries[0])
+}
+
+// Predict returns the prediction of each response at the predictors x.
+func (f *MultiOLS) Predict(x []float64) []float64 {
+	if !f.summaries[0].origin {
+		x = append([]float64{1}, x...)
+	}
+	predictions := make([]float64, len(f.summaries))
+	for k, s := range f.summaries {
+		predictions[k] = sum(prod(x, s.betas))
+	}
+	return predictions
+}
@@ -0,0 +1,101 @@
+package glasso
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// stacklossResponses returns stack.loss and two other responses on the
+// stackloss design.
+func stacklossResponses() *mat.Dense {
+	Y := mat.NewDense(len(y), 3, nil)
+	for i, row := range data {
+		Y.Set(i, 0, y[i])
+		Y.Set(i, 1, row[0]*row[1]/10+float64(i%3))
+		Y.Set(i, 2, float64(i*i%7)-row[2]/20)
+	}
+	return Y
+}
+
+func TestFitMulti(t *testing.T) {
+	x := NewDataFrame(data)
+	Y := stacklossResponses()
+	var count int32
+	factorizeHook = func() { atomic.AddInt32(&count, 1) }
+	defer func() { factorizeHook = func() {} }()
+	fit, err := FitMulti(x.X, Y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, fit.Responses())
+	r, c := fit.Coefficients.Dims()
+	assert.Equal(t, 4, r)
+	assert.Equal(t, 3, c)
+
+	leverage, err := fit.LeveragePoints()
+	assert.Equal(t, nil, err)
+	for k := 0; k < fit.Responses(); k++ {
+		_, err := CooksDistance(fit.Summary(k))
+		assert.Equal(t, nil, err)
+		h, err := LeveragePoints(fit.Summary(k))
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, h, leverage, 1e-12)
+	}
+	// the diagnostics of every response reuse the one factorization
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	for k := 0; k < fit.Responses(); k++ {
+		response := mat.Col(nil, k, Y)
+		_, single, err := NewOlsTrainer().Train(NewDataFrame(data), response)
+		assert.Equal(t, nil, err)
+		s := single.(OlsSummary)
+		assertCloseSlices(t, mat.Col(nil, k, fit.Coefficients), s.Coefficients(), 1e-10)
+		assertCloseSlices(t, fit.Summary(k).Coefficients(), s.Coefficients(), 1e-10)
+		for i, e := range s.Residuals() {
+			assertClose(t, fit.Residuals(k)[i], e, 1e-10)
+		}
+		assertClose(t, fit.RSquared()[k], s.RSquared(), 1e-12)
+		assertClose(t, fit.MeanSquaredError()[k], s.MeanSquaredError(), 1e-10)
+
+		want, err := CooksDistance(s)
+		assert.Equal(t, nil, err)
+		got, err := CooksDistance(fit.Summary(k))
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, got, want, 1e-9)
+		h, err := LeveragePoints(s)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, leverage, h, 1e-10)
+		assertClose(t, fit.Predict(data[4])[k], s.Yhat()[4], 1e-10)
+	}
+}
+
+func TestFitMultiThroughOrigin(t *testing.T) {
+	Y := stacklossResponses()
+	fit, err := FitMulti(NewDataFrame(data).X, Y, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	r, _ := fit.Coefficients.Dims()
+	assert.Equal(t, 3, r)
+	for k := 0; k < fit.Responses(); k++ {
+		_, s, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(data), mat.Col(nil, k, Y))
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, fit.Summary(k).Coefficients(), s.Coefficients(), 1e-10)
+		assertClose(t, fit.RSquared()[k], s.(OlsSummary).RSquared(), 1e-12)
+		assertClose(t, fit.Predict(data[0])[k], s.Yhat()[0], 1e-10)
+	}
+}
+
+func TestFitMultiErrors(t *testing.T) {
+	x := NewDataFrame(data).X
+	_, err := FitMulti(x, mat.NewDense(20, 2, nil))
+	assert.Equal(t, DimensionError, err)
+
+	_, err = FitMulti(x.Slice(0, 3, 0, 3).(*mat.Dense), mat.NewDense(3, 2, nil))
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+
+	// the third column is the sum of the first two
+	rows := [][]float64{{1, 2, 3}, {2, 1, 3}, {3, 5, 8}, {4, 2, 6}, {5, 7, 12}, {6, 1, 7}}
+	_, err = FitMulti(NewDataFrame(rows).X, mat.NewDense(6, 2, rep(1, 12)))
+	assert.T(t, errors.Is(err, SingularDesignError))
+}
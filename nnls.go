@@ -0,0 +1,156 @@
+package glasso
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// NNLSFit is a least squares fit with non-negative coefficients.
+type NNLSFit struct {
+	Coefficients []float64
+	Residuals    []float64
+	Bound        []bool // whether each coefficient is held at zero by its constraint
+
+	Iterations int  // coefficients freed from the bound
+	Converged  bool // whether the KKT conditions held within the maximum number of iterations
+}
+
+// NNLS solves the non-negative least squares problem
+//
+// \min_\beta ||y - X\beta||^2 subject to \beta >= 0
+//
+// by the active set algorithm of Lawson & Hanson (1974), as
+// scipy.optimize.nnls does. Each iteration frees the bound coefficient with
+// the largest gradient X'(y - X\beta), then solves least squares on the free
+// coefficients, backing off toward the previous solution and re-binding
+// coefficients until they're all positive. The solution is optimal when every
+// bound coefficient has a gradient of at most the tolerance (WithTolerance)
+// times the largest of the initial gradients.
+//
+// x has no intercept column added, since the constraint usually concerns
+// weights of components such as mixture proportions.
+func NNLS(x *DataFrame, y []float64, opts ...Option) (*NNLSFit, error) {
+	o := newOptions(opts)
+	if err := o.checkIterations(); err != nil {
+		return nil, err
+	}
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	response := mat.NewDense(n, 1, append([]float64(nil), y...))
+
+	betas := make([]float64, p)
+	free := make([]bool, p)
+	gradient := nnlsGradient(x.X, y, betas)
+	tol := 0.0
+	for _, g := range gradient {
+		tol = math.Max(tol, math.Abs(g))
+	}
+	tol *= o.tolerance
+
+	fit := &NNLSFit{}
+	for {
+		next, largest := -1, tol
+		for j, g := range gradient {
+			if !free[j] && g > largest {
+				next, largest = j, g
+			}
+		}
+		if next < 0 {
+			fit.Converged = true
+			break
+		}
+		if fit.Iterations == o.maxIter {
+			break
+		}
+		fit.Iterations++
+		free[next] = true
+
+		for {
+			s, err := freeLeastSquares(x.X, response, free)
+			if err != nil {
+				return nil, err
+			}
+			// step from betas toward s as far as the constraints allow,
+			// binding the coefficient that reaches zero first
+			step, blocking := 1.0, -1
+			for j := range s {
+				if free[j] && s[j] <= 0 {
+					a := 0.0
+					if betas[j] > s[j] {
+						a = betas[j] / (betas[j] - s[j])
+					}
+					if blocking < 0 || a < step {
+						step, blocking = a, j
+					}
+				}
+			}
+			if blocking < 0 {
+				betas = s
+				break
+			}
+			for j := range betas {
+				if free[j] {
+					betas[j] += step * (s[j] - betas[j])
+					if j == blocking || betas[j] <= 0 {
+						betas[j], free[j] = 0, false
+					}
+				}
+			}
+		}
+		gradient = nnlsGradient(x.X, y, betas)
+	}
+
+	fit.Coefficients = betas
+	fit.Bound = make([]bool, p)
+	for j := range free {
+		fit.Bound[j] = !free[j]
+	}
+	fit.Residuals = make([]float64, n)
+	for i := range y {
+		fit.Residuals[i] = y[i] - sum(prod(x.X.RawRowView(i), betas))
+	}
+	return fit, nil
+}
+
+// nnlsGradient returns X'(y - X\beta), half the negative gradient of the
+// residual sum of squares.
+func nnlsGradient(x *mat.Dense, y, betas []float64) []float64 {
+	n, p := x.Dims()
+	gradient := make([]float64, p)
+	for i := 0; i < n; i++ {
+		row := x.RawRowView(i)
+		e := y[i] - sum(prod(row, betas))
+		for j := range gradient {
+			gradient[j] += row[j] * e
+		}
+	}
+	return gradient
+}
+
+// freeLeastSquares returns the least squares coefficients on the free columns
+// of x, with zeros for the others.
+func freeLeastSquares(x, y *mat.Dense, free []bool) ([]float64, error) {
+	n, p := x.Dims()
+	var cols []int
+	for j := 0; j < p; j++ {
+		if free[j] {
+			cols = append(cols, j)
+		}
+	}
+	sub := mat.NewDense(n, len(cols), nil)
+	for k, j := range cols {
+		sub.SetCol(k, mat.Col(nil, j, x))
+	}
+	fit, err := leastSquares(sub, y)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]float64, p)
+	for k, j := range cols {
+		s[j] = fit.betas[k]
+	}
+	return s, nil
+}
@@ -0,0 +1,157 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNNLS(t *testing.T) {
+	// the examples of scipy.optimize.nnls
+	x := NewDataFrame([][]float64{{1, 0}, {1, 0}, {0, 1}})
+	fit, err := NNLS(x, []float64{2, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := fit.Coefficients; math.Abs(b[0]-1.5) > 1e-12 || math.Abs(b[1]-1) > 1e-12 {
+		t.Errorf("got coefficients %v, want [1.5 1]", b)
+	}
+	if rnorm := math.Sqrt(sum(prod(fit.Residuals, fit.Residuals))); math.Abs(rnorm-math.Sqrt(0.5)) > 1e-12 {
+		t.Errorf("residual norm is %v, want 0.7071", rnorm)
+	}
+	fit, err = NNLS(x, []float64{-1, -1, -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := fit.Coefficients; b[0] != 0 || b[1] != 0 || !fit.Bound[0] || !fit.Bound[1] || !fit.Converged {
+		t.Errorf("got coefficients %v bound at %v, want [0 0] both bound", b, fit.Bound)
+	}
+}
+
+// bestNonNegative returns the non-negative least squares solution by trying
+// every set of free coefficients: the solution is the feasible least squares
+// fit on its free set whose gradient is non-positive on the others.
+func bestNonNegative(x *mat.Dense, y []float64) []float64 {
+	n, p := x.Dims()
+	response := mat.NewDense(n, 1, y)
+	for set := 0; set < 1<<uint(p); set++ {
+		free := make([]bool, p)
+		for j := range free {
+			free[j] = set&(1<<uint(j)) != 0
+		}
+		betas := make([]float64, p)
+		if set != 0 {
+			var err error
+			if betas, err = freeLeastSquares(x, response, free); err != nil {
+				continue
+			}
+		}
+		ok := true
+		for j, g := range nnlsGradient(x, y, betas) {
+			if free[j] && betas[j] <= 0 || !free[j] && g > 1e-9 {
+				ok = false
+			}
+		}
+		if ok {
+			return betas
+		}
+	}
+	return nil
+}
+
+func TestNNLSMixture(t *testing.T) {
+	// unmix correlated spectra, where least squares gives negative weights
+	rng := rand.New(rand.NewSource(4))
+	n, p := 30, 6
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	weights := []float64{0.5, 0, 0.3, 0, 0.2, 0}
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		common := rng.Float64()
+		for j := range rows[i] {
+			rows[i][j] = common + 0.2*rng.Float64()
+		}
+		response[i] = sum(prod(rows[i], weights)) + 0.05*rng.NormFloat64()
+	}
+	x := NewDataFrame(rows)
+	ls, err := leastSquares(x.X, mat.NewDense(n, 1, response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	negative := false
+	for _, b := range ls.betas {
+		negative = negative || b < 0
+	}
+	if !negative {
+		t.Fatalf("least squares coefficients %v are all non-negative", ls.betas)
+	}
+
+	fit, err := NNLS(x, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fit.Converged {
+		t.Error("fit didn't converge")
+	}
+	want := bestNonNegative(x.X, response)
+	gradient := nnlsGradient(x.X, response, fit.Coefficients)
+	bound := 0
+	for j, b := range fit.Coefficients {
+		if math.Abs(b-want[j]) > 1e-10 {
+			t.Errorf("coefficient %d is %v, want %v", j, b, want[j])
+		}
+		if fit.Bound[j] != (b == 0) {
+			t.Errorf("coefficient %d is %v but bound is %v", j, b, fit.Bound[j])
+		}
+		// KKT: the gradient is zero on the free coefficients, at most zero on the bound ones
+		if fit.Bound[j] && gradient[j] > 1e-9 || !fit.Bound[j] && math.Abs(gradient[j]) > 1e-9 {
+			t.Errorf("coefficient %d has gradient %v", j, gradient[j])
+		}
+		if fit.Bound[j] {
+			bound++
+		}
+	}
+	if bound == 0 {
+		t.Error("no coefficient is bound")
+	}
+	for i, e := range fit.Residuals {
+		if want := response[i] - sum(prod(rows[i], fit.Coefficients)); math.Abs(e-want) > 1e-12 {
+			t.Errorf("residual %d is %v, want %v", i, e, want)
+		}
+	}
+
+	// a solution that's already non-negative is the least squares one
+	positive := make([]float64, n)
+	for i := range rows {
+		positive[i] = sum(prod(rows[i], []float64{1, 2, 3, 4, 5, 6}))
+	}
+	fit, err = NNLS(x, positive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, b := range fit.Coefficients {
+		if math.Abs(b-float64(j+1)) > 1e-8 {
+			t.Errorf("coefficient %d is %v, want %d", j, b, j+1)
+		}
+	}
+}
+
+func TestNNLSInvalid(t *testing.T) {
+	x := NewDataFrame([][]float64{{1, 0}, {1, 0}, {0, 1}})
+	if _, err := NNLS(x, []float64{1, 2}); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+	if _, err := NNLS(x, []float64{1, 2, 3}, WithTolerance(-1)); err == nil {
+		t.Error("expected an error for a negative tolerance")
+	}
+	fit, err := NNLS(x, []float64{1, 2, 3}, WithMaxIterations(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fit.Converged || fit.Iterations != 1 {
+		t.Errorf("got converged %v after %d iterations, want false after 1", fit.Converged, fit.Iterations)
+	}
+}
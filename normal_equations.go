@@ -0,0 +1,222 @@
+package glasso
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// A Solver is the method a least squares fit finds its coefficients by.
+type Solver int
+
+const (
+	// QRSolver factorizes the design as X = QR, the default.
+	QRSolver Solver = iota
+
+	// CholeskySolver factorizes X'X = R'R and solves the normal equations
+	// X'X \beta = X'y. It takes O(np^2 / 2) operations and p^2 memory beyond the
+	// design, about half the work of QR and none of its n x p copy, which for
+	// tall designs of millions of rows and a few predictors is much the
+	// cheaper. But X'X has the square of the condition number of X, so the
+	// coefficients lose twice as many digits: for a design with a condition
+	// number much above 1e5, such as one with nearly collinear predictors or on
+	// very different scales, use QR or WithStandardize(true).
+	CholeskySolver
+)
+
+func (s Solver) String() string {
+	switch s {
+	case QRSolver:
+		return "QR"
+	case CholeskySolver:
+		return "Cholesky"
+	}
+	return fmt.Sprintf("Solver(%d)", int(s))
+}
+
+// leastSquaresBy solves min ||y - X beta|| with solver.
+func leastSquaresBy(x, y *mat.Dense, solver Solver) (*lsFit, error) {
+	switch solver {
+	case QRSolver:
+		return leastSquares(x, y)
+	case CholeskySolver:
+	default:
+		return nil, fmt.Errorf("unknown solver %v", solver)
+	}
+	n, p := x.Dims()
+	if n < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+	response := mat.Col(nil, 0, y)
+	normal := newNormalEquations(p)
+	normal.add(x, response)
+	betas, r, err := normal.solve()
+	if err != nil {
+		return nil, err
+	}
+	fit := &lsFit{betas: betas, fitted: make([]float64, n), residuals: make([]float64, n), r: r}
+	for i, v := range response {
+		fit.fitted[i] = sum(prod(x.RawRowView(i), betas))
+		fit.residuals[i] = v - fit.fitted[i]
+	}
+	return fit, nil
+}
+
+// normalEquations accumulates X'X, X'y and y'y over blocks of the rows of a
+// regression.
+type normalEquations struct {
+	xtx *mat.SymDense
+	xty []float64
+	yty float64
+	n   int
+}
+
+func newNormalEquations(p int) *normalEquations {
+	return &normalEquations{xtx: mat.NewSymDense(p, nil), xty: make([]float64, p)}
+}
+
+// add adds the rows of x, with the responses y.
+func (e *normalEquations) add(x *mat.Dense, y []float64) {
+	e.xtx.SymRankK(e.xtx, 1, x.T())
+	for i, v := range y {
+		for j, xij := range x.RawRowView(i) {
+			e.xty[j] += xij * v
+		}
+		e.yty += v * v
+	}
+	e.n += len(y)
+}
+
+// solve returns the coefficients and the Cholesky factor R of X'X = R'R. A
+// pivot R_jj^2 within pivotTolerance of (X'X)_jj, for a column that is a linear
+// combination of those before it but for rounding, is a SingularDesignError.
+func (e *normalEquations) solve() ([]float64, *mat.TriDense, error) {
+	chol := &mat.Cholesky{}
+	if !chol.Factorize(e.xtx) {
+		return nil, nil, fmt.Errorf("%w: X'X isn't positive definite", SingularDesignError)
+	}
+	r := &mat.TriDense{}
+	chol.UTo(r)
+	p := len(e.xty)
+	for j := 0; j < p; j++ {
+		if d := r.At(j, j); !(d*d > pivotTolerance*e.xtx.At(j, j)) {
+			return nil, nil, fmt.Errorf("%w: column %d is a linear combination of the columns before it", SingularDesignError, j)
+		}
+	}
+	betas := &mat.VecDense{}
+	if err := chol.SolveVecTo(betas, mat.NewVecDense(p, append([]float64(nil), e.xty...))); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	return mat.Col(nil, 0, betas), r, nil
+}
+
+// rss returns the residual sum of squares y'y - \beta'X'y of the coefficients
+// that solve the normal equations. It has an absolute error of about machine
+// precision times y'y, which for a fit with an R² near one is large relative
+// to the RSS itself.
+func (e *normalEquations) rss(betas []float64) float64 {
+	return math.Max(e.yty-sum(prod(betas, e.xty)), 0)
+}
+
+// designBlock returns the design of a block of rows, with a column of ones
+// first for an intercept.
+func designBlock(x *DataFrame, intercept bool) *mat.Dense {
+	if !intercept {
+		return x.X
+	}
+	block := mat.NewDense(x.Rows(), x.Cols()+1, nil)
+	for i := 0; i < x.Rows(); i++ {
+		row := block.RawRowView(i)
+		row[0] = 1
+		copy(row[1:], x.X.RawRowView(i))
+	}
+	return block
+}
+
+// A RowIterator supplies the observations of a regression a block of rows at
+// a time, so that they needn't all be held at once. Next returns the
+// predictors and responses of the next block, and io.EOF once there are none
+// left. Every block has the same columns.
+type RowIterator interface {
+	Next() (x *DataFrame, y []float64, err error)
+}
+
+// FitStream fits the observations that rows supplies by least squares, with
+// an intercept unless WithIntercept(false) is given, solving the normal
+// equations as CholeskySolver does. Only X'X and X'y are accumulated, so the
+// memory used is that of a block and p^2, whatever the number of rows; see
+// CholeskySolver for the precision this costs. The model carries the
+// coefficients, with the names of the first block's labels, the residual
+// variance and the covariance matrix of the coefficients, and has no summary,
+// as the observations aren't kept. The residual sum of squares is y'y -
+// \beta'X'y, without a second pass over the rows; SummarizeStream makes one for
+// the exact RSS and R², and StreamLeverage for the leverages. WithContext stops
+// the fit between blocks. ObservationBlocks adapts an iterator over single
+// rows.
+func FitStream(rows RowIterator, opts ...Option) (*OLS, error) {
+	o := newOptions(opts)
+	var normal *normalEquations
+	var labels []string
+	cols := 0
+	for {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		x, y, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(y) != x.Rows() {
+			return nil, DimensionError
+		}
+		if normal == nil {
+			cols, labels = x.Cols(), x.Labels()
+			p := cols
+			if o.intercept {
+				p++
+			}
+			normal = newNormalEquations(p)
+		} else if x.Cols() != cols {
+			return nil, fmt.Errorf("%w: a block of %d columns after blocks of %d", DimensionError, x.Cols(), cols)
+		}
+		normal.add(designBlock(x, o.intercept), y)
+	}
+	if normal == nil {
+		return nil, fmt.Errorf("no observations")
+	}
+	n, p := normal.n, len(normal.xty)
+	if n < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+	betas, r, err := normal.solve()
+	if err != nil {
+		return nil, err
+	}
+
+	sigma2 := math.NaN()
+	if n > p {
+		sigma2 = normal.rss(betas) / float64(n-p)
+	}
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(r); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	vcov := &mat.Dense{}
+	vcov.Mul(rinv, rinv.T())
+	vcov.Scale(sigma2, vcov)
+	return &OLS{
+		betas:       betas,
+		n:           n,
+		p:           p,
+		names:       labels,
+		sigma2:      sigma2,
+		vcov:        vcov,
+		noIntercept: !o.intercept,
+	}, nil
+}
@@ -0,0 +1,185 @@
+package glasso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// wellConditioned returns n observations of p independent predictors and a
+// response linear in them.
+func wellConditioned(rng *rand.Rand, n, p int) ([][]float64, []float64) {
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		response[i] = 3
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+			response[i] += float64(j-1) * rows[i][j]
+		}
+		response[i] += rng.NormFloat64()
+	}
+	return rows, response
+}
+
+func TestCholeskySolver(t *testing.T) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(1)), 500, 5)
+	weights := make([]float64, len(rows))
+	for i := range weights {
+		weights[i] = 1 + float64(i%4)
+	}
+	for _, trainer := range []func(...Option) Trainer{
+		NewOlsTrainer,
+		func(opts ...Option) Trainer { return NewOlsTrainer(append(opts, WithStandardize(true))...) },
+		func(opts ...Option) Trainer { return NewWlsTrainer(weights, opts...) },
+	} {
+		qm, qs, err := trainer().Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		cm, cs, err := trainer(WithSolver(CholeskySolver)).Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, cm.(*OLS).Coefficients(), qm.(*OLS).Coefficients(), 1e-8)
+		assertCloseSlices(t, cs.Residuals(), qs.Residuals(), 1e-8)
+		assertClose(t, cm.(*OLS).sigma2, qm.(*OLS).sigma2, 1e-8)
+		r, c := qm.(*OLS).vcov.Dims()
+		for i := 0; i < r; i++ {
+			assertCloseSlices(t, cm.(*OLS).vcov.RawRowView(i)[:c], qm.(*OLS).vcov.RawRowView(i)[:c], 1e-8)
+		}
+
+		// the diagnostics take R from the Cholesky factorization
+		var count int32
+		factorizeHook = func() { atomic.AddInt32(&count, 1) }
+		want, err := CooksDistance(qs)
+		assert.Equal(t, nil, err)
+		count = 0
+		got, err := CooksDistance(cs)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, got, want, 1e-8)
+		factorizeHook = func() {}
+		if cm.(*OLS).scale == nil {
+			assert.Equal(t, count, int32(0))
+		}
+	}
+
+	// a singular design
+	x := mat.NewDense(4, 2, []float64{1, 2, 1, 2, 1, 2, 1, 2})
+	_, err := leastSquaresBy(x, mat.NewDense(4, 1, []float64{1, 2, 3, 4}), CholeskySolver)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	_, _, err = NewOlsTrainer(WithSolver(Solver(7))).Train(NewDataFrame(rows), response)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, CholeskySolver.String(), "Cholesky")
+}
+
+// blockRows is a RowIterator over rows a block at a time.
+type blockRows struct {
+	rows     [][]float64
+	response []float64
+	labels   []string
+	block    int
+	next     int
+}
+
+func (b *blockRows) Next() (*DataFrame, []float64, error) {
+	if b.next >= len(b.rows) {
+		return nil, nil, io.EOF
+	}
+	end := b.next + b.block
+	if end > len(b.rows) {
+		end = len(b.rows)
+	}
+	x := NewDataFrame(b.rows[b.next:end], b.labels)
+	y := b.response[b.next:end]
+	b.next = end
+	return x, y, nil
+}
+
+func TestFitStream(t *testing.T) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(2)), 10000, 4)
+	labels := []string{"a", "b", "c", "d"}
+	for _, intercept := range []bool{true, false} {
+		model, err := FitStream(&blockRows{rows: rows, response: response, labels: labels, block: 999}, WithIntercept(intercept))
+		assert.Equal(t, nil, err)
+		m, _, err := NewOlsTrainer(WithIntercept(intercept)).Train(NewDataFrame(rows, labels), response)
+		assert.Equal(t, nil, err)
+		batch := m.(*OLS)
+
+		assertCloseSlices(t, model.Coefficients(), batch.Coefficients(), 1e-8)
+		assert.Equal(t, model.Names(), batch.Names())
+		assert.Equal(t, model.n, 10000)
+		assertClose(t, model.sigma2, batch.sigma2, 1e-8)
+		r, c := batch.vcov.Dims()
+		for i := 0; i < r; i++ {
+			assertCloseSlices(t, model.vcov.RawRowView(i)[:c], batch.vcov.RawRowView(i)[:c], 1e-8)
+		}
+		assertClose(t, model.Predict(rows[5]), batch.Predict(rows[5]), 1e-8)
+	}
+}
+
+// failingRows fails after its first block.
+type failingRows struct{ blockRows }
+
+func (f *failingRows) Next() (*DataFrame, []float64, error) {
+	if f.next > 0 {
+		return nil, nil, fmt.Errorf("read failed")
+	}
+	return f.blockRows.Next()
+}
+
+// widening returns a block of a column more each time.
+type widening struct{ calls int }
+
+func (w *widening) Next() (*DataFrame, []float64, error) {
+	w.calls++
+	rows := make([][]float64, 5)
+	for i := range rows {
+		rows[i] = rep(float64(i), w.calls)
+	}
+	return NewDataFrame(rows), make([]float64, 5), nil
+}
+
+func TestFitStreamErrors(t *testing.T) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(3)), 20, 2)
+	_, err := FitStream(&blockRows{block: 10})
+	assert.NotEqual(t, nil, err)
+	_, err = FitStream(&failingRows{blockRows{rows: rows, response: response, block: 10}})
+	assert.Equal(t, err.Error(), "read failed")
+	_, err = FitStream(&widening{})
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, err = FitStream(&blockRows{rows: rows[:2], response: response[:2], block: 10})
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FitStream(&blockRows{rows: rows, response: response, block: 10}, WithContext(ctx))
+	assert.Equal(t, err, context.Canceled)
+
+	// the second column is twice the first
+	for i := range rows {
+		rows[i][1] = 2 * rows[i][0]
+	}
+	_, err = FitStream(&blockRows{rows: rows, response: response, block: 7})
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	assert.T(t, !math.IsNaN(response[0]))
+}
+
+func benchmarkSolver(b *testing.B, solver Solver) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(4)), 200000, 10)
+	x := NewDataFrame(rows)
+	b.ResetTimer()
+	for k := 0; k < b.N; k++ {
+		if _, _, err := NewOlsTrainer(WithSolver(solver)).Train(x, response); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrainQR(b *testing.B)       { benchmarkSolver(b, QRSolver) }
+func BenchmarkTrainCholesky(b *testing.B) { benchmarkSolver(b, CholeskySolver) }
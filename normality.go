@@ -0,0 +1,232 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// polynomial evaluates c[0] + c[1] x + c[2] x^2 + ...
+func polynomial(c []float64, x float64) float64 {
+	total := 0.0
+	for i := len(c) - 1; i >= 0; i-- {
+		total = total*x + c[i]
+	}
+	return total
+}
+
+// ShapiroWilk tests the sample for normality with the Shapiro-Wilk W statistic,
+// using Royston's (1995) algorithm AS R94 for the coefficients and the p-value,
+// as R's shapiro.test does. W is the squared correlation between the ordered
+// sample and the approximate expected normal order statistics; small values
+// indicate departure from normality.
+//
+// The sample must have between 3 and 5000 values, which is the range over
+// which Royston's approximations were fit, and must not be constant.
+func ShapiroWilk(x []float64) (W float64, pValue float64, err error) {
+	n := len(x)
+	if n < 3 || n > 5000 {
+		return 0, 0, fmt.Errorf("shapiro wilk test needs between 3 and 5000 observations, got %d", n)
+	}
+	sorted := make([]float64, n)
+	copy(sorted, x)
+	sort.Float64s(sorted)
+	if sorted[n-1]-sorted[0] < 1e-10 {
+		return 0, 0, fmt.Errorf("all values are identical")
+	}
+
+	a := shapiroWilkCoefficients(n)
+
+	// W is the squared correlation between the sample and the antisymmetric
+	// coefficient vector, whose mean is zero
+	m := mean(sorted)
+	sax, ssa, ssx := 0.0, 0.0, 0.0
+	for i, v := range sorted {
+		var ai float64
+		if j := n - 1 - i; i < j {
+			ai = -a[i]
+		} else if i > j {
+			ai = a[j]
+		}
+		sax += ai * (v - m)
+		ssa += ai * ai
+		ssx += (v - m) * (v - m)
+	}
+	W = sax * sax / (ssa * ssx)
+	return W, shapiroWilkPValue(W, n), nil
+}
+
+// shapiroWilkCoefficients returns the positive halves a_1 >= a_2 >= ... of the
+// coefficients for a sample of size n, approximated as in AS R94.
+func shapiroWilkCoefficients(n int) []float64 {
+	half := n / 2
+	a := make([]float64, half)
+	if n == 3 {
+		a[0] = math.Sqrt(0.5)
+		return a
+	}
+
+	c1 := []float64{0, 0.221157, -0.147981, -2.07119, 4.434685, -2.706056}
+	c2 := []float64{0, 0.042981, -0.293762, -1.752461, 5.682633, -3.582633}
+
+	// the approximate expected normal order statistics, which are negative
+	an := float64(n)
+	m := make([]float64, half)
+	summ2 := 0.0
+	for i := range m {
+		m[i] = NormalQuantile((float64(i+1) - 0.375) / (an + 0.25))
+		summ2 += m[i] * m[i]
+	}
+	summ2 *= 2
+	ssumm2 := math.Sqrt(summ2)
+	rsn := 1 / math.Sqrt(an)
+
+	// the largest one or two coefficients come from polynomials in 1/sqrt(n),
+	// and the rest are normalized so the full vector has unit length
+	a1 := polynomial(c1, rsn) - m[0]/ssumm2
+	first := 1
+	var fac float64
+	if n > 5 {
+		first = 2
+		a2 := -m[1]/ssumm2 + polynomial(c2, rsn)
+		fac = math.Sqrt((summ2 - 2*m[0]*m[0] - 2*m[1]*m[1]) / (1 - 2*a1*a1 - 2*a2*a2))
+		a[1] = a2
+	} else {
+		fac = math.Sqrt((summ2 - 2*m[0]*m[0]) / (1 - 2*a1*a1))
+	}
+	a[0] = a1
+	for i := first; i < half; i++ {
+		a[i] = -m[i] / fac
+	}
+	return a
+}
+
+// shapiroWilkPValue returns Royston's approximation to P(W' <= W) for a sample of
+// size n. log(1 - W) is approximately normal for n >= 12, and one more log
+// transformation makes it so for smaller samples. For n = 3 the distribution is known exactly.
+func shapiroWilkPValue(w float64, n int) float64 {
+	if n == 3 {
+		p := 6 / math.Pi * (math.Asin(math.Sqrt(w)) - math.Pi/3)
+		return math.Max(p, 0)
+	}
+
+	an := float64(n)
+	y := math.Log(1 - w)
+	var mu, sigma float64
+	if n <= 11 {
+		gamma := polynomial([]float64{-2.273, 0.459}, an)
+		if y >= gamma {
+			return 1e-99
+		}
+		y = -math.Log(gamma - y)
+		mu = polynomial([]float64{0.544, -0.39978, 0.025054, -6.714e-4}, an)
+		sigma = math.Exp(polynomial([]float64{1.3822, -0.77857, 0.062767, -0.0020322}, an))
+	} else {
+		ln := math.Log(an)
+		mu = polynomial([]float64{-1.5861, -0.31082, -0.083751, 0.0038915}, ln)
+		sigma = math.Exp(polynomial([]float64{-0.4803, -0.082676, 0.0030302}, ln))
+	}
+	return normalCDF((mu - y) / sigma)
+}
+
+// AndersonDarling tests the sample for normality, with the mean and variance
+// estimated from it, using the Anderson-Darling statistic
+//
+// A^2 = -n - \frac{1}{n} \sum_{i=1}^n (2i - 1) (\log \Phi(z_{(i)}) + \log(1 - \Phi(z_{(n+1-i)})))
+//
+// where z are the standardized sorted values. It weights the tails more heavily
+// than most tests based on the empirical distribution function. The p-value comes
+// from the modified statistic A^2 (1 + 0.75/n + 2.25/n^2) using the piecewise
+// approximation of D'Agostino & Stephens (1986) for the case with both parameters
+// estimated, as in R's nortest::ad.test. At least 8 values are required.
+func AndersonDarling(x []float64) (A2 float64, pValue float64, err error) {
+	n := len(x)
+	if n < 8 {
+		return 0, 0, fmt.Errorf("anderson darling test needs at least 8 observations, got %d", n)
+	}
+	s := sd(x)
+	if s == 0 {
+		return 0, 0, fmt.Errorf("all values are identical")
+	}
+	sorted := make([]float64, n)
+	copy(sorted, x)
+	sort.Float64s(sorted)
+
+	m := mean(sorted)
+	total := 0.0
+	for i := range sorted {
+		lower := math.Log(normalCDF((sorted[i] - m) / s))
+		upper := math.Log(normalCDF(-(sorted[n-1-i] - m) / s))
+		total += float64(2*i+1) * (lower + upper)
+	}
+	A2 = -float64(n) - total/float64(n)
+
+	an := float64(n)
+	aa := A2 * (1 + 0.75/an + 2.25/(an*an))
+	switch {
+	case aa < 0.2:
+		pValue = 1 - math.Exp(-13.436+101.14*aa-223.73*aa*aa)
+	case aa < 0.34:
+		pValue = 1 - math.Exp(-8.318+42.796*aa-59.938*aa*aa)
+	case aa < 0.6:
+		pValue = math.Exp(0.9177 - 4.279*aa - 1.38*aa*aa)
+	case aa < 10:
+		pValue = math.Exp(1.2937 - 5.709*aa + 0.0186*aa*aa)
+	default:
+		pValue = 3.7e-24
+	}
+	return A2, pValue, nil
+}
+
+// ResidualAndersonDarling runs the Anderson-Darling normality test on the residuals of the model.
+func ResidualAndersonDarling(m Summary) (A2 float64, pValue float64, err error) {
+	return AndersonDarling(m.Residuals())
+}
+
+// QQPlot holds the points of a normal quantile-quantile plot and its reference line.
+type QQPlot struct {
+	Theoretical []float64 // standard normal quantiles at the plotting positions (i - 0.5) / n
+	Sample      []float64 // the sorted sample
+
+	// Slope and Intercept define the line through the first and third quartiles
+	// of the sample and the normal distribution, as drawn by R's qqline.
+	Slope, Intercept float64
+}
+
+// QQData returns the points of a normal Q-Q plot of the standardized residuals of
+// the model. Residuals of observations with a leverage of one are left out.
+func QQData(m Summary) (*QQPlot, error) {
+	residuals, err := StandardizedResiduals(m)
+	if err != nil {
+		return nil, err
+	}
+	var sample []float64
+	for _, r := range residuals {
+		if !math.IsNaN(r) {
+			sample = append(sample, r)
+		}
+	}
+	return normalQQ(sample), nil
+}
+
+// normalQQ computes the Q-Q plot of x against the standard normal distribution.
+func normalQQ(x []float64) *QQPlot {
+	n := len(x)
+	sample := make([]float64, n)
+	copy(sample, x)
+	sort.Float64s(sample)
+
+	theoretical := make([]float64, n)
+	for i := range theoretical {
+		theoretical[i] = NormalQuantile((float64(i) + 0.5) / float64(n))
+	}
+
+	qq := &QQPlot{Theoretical: theoretical, Sample: sample}
+	if n > 0 {
+		x1, x2 := NormalQuantile(0.25), NormalQuantile(0.75)
+		y1, y2 := quantile(sample, 0.25), quantile(sample, 0.75)
+		qq.Slope = (y2 - y1) / (x2 - x1)
+		qq.Intercept = y1 - qq.Slope*x1
+	}
+	return qq
+}
@@ -0,0 +1,229 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestShapiroWilkCoefficients(t *testing.T) {
+	// Royston's approximations against the exact coefficients tabulated by Shapiro & Wilk (1965)
+	tables := map[int][]float64{
+		5:  {0.6646, 0.2413},
+		10: {0.5739, 0.3291, 0.2141, 0.1224, 0.0399},
+		20: {0.4734, 0.3211, 0.2565, 0.2085, 0.1686, 0.1334, 0.1013, 0.0711, 0.0422, 0.0140},
+	}
+	for n, expected := range tables {
+		a := shapiroWilkCoefficients(n)
+		assert.Equal(t, len(a), len(expected))
+		for i := range a {
+			assertClose(t, a[i], expected[i], 2e-3)
+		}
+	}
+}
+
+func TestShapiroWilkThree(t *testing.T) {
+	// W = (a_1 (x_3 - x_1))^2 / SS with a_1 = 1 / sqrt(2), and its distribution is exact
+	w, p, err := ShapiroWilk([]float64{4, 1, 2})
+	assert.Equal(t, nil, err)
+	ss := math.Pow(1-7.0/3, 2) + math.Pow(2-7.0/3, 2) + math.Pow(4-7.0/3, 2)
+	assertClose(t, w, 4.5/ss, 1e-12)
+	assertClose(t, p, 6/math.Pi*(math.Asin(math.Sqrt(w))-math.Pi/3), 1e-12)
+
+	// equally spaced values are as normal as three points can be
+	w, p, err = ShapiroWilk([]float64{1, 2, 3})
+	assert.Equal(t, nil, err)
+	assertClose(t, w, 1, 1e-12)
+	assertClose(t, p, 1, 1e-12)
+}
+
+func TestShapiroWilkInvariance(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	x := make([]float64, 50)
+	scaled := make([]float64, 50)
+	for i := range x {
+		x[i] = rng.NormFloat64()
+		scaled[len(x)-1-i] = 3*x[i] + 10
+	}
+	w, p, err := ShapiroWilk(x)
+	assert.Equal(t, nil, err)
+	ws, ps, err := ShapiroWilk(scaled)
+	assert.Equal(t, nil, err)
+	assertClose(t, w, ws, 1e-12)
+	assertClose(t, p, ps, 1e-12)
+}
+
+func TestShapiroWilkSize(t *testing.T) {
+	// normal samples should be rejected at about the nominal rate for small and
+	// large n, on both sides of the n = 11 switch in the p-value approximation
+	const reps = 2000
+	rng := rand.New(rand.NewSource(3))
+	for _, n := range []int{8, 30, 300} {
+		rejected := 0
+		for rep := 0; rep < reps; rep++ {
+			x := make([]float64, n)
+			for i := range x {
+				x[i] = rng.NormFloat64()
+			}
+			_, p, err := ShapiroWilk(x)
+			assert.Equal(t, nil, err)
+			if p < 0.05 {
+				rejected++
+			}
+		}
+		assertClose(t, float64(rejected)/reps, 0.05, 0.015)
+	}
+}
+
+func TestShapiroWilkPower(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	uniform, exponential, cauchy := make([]float64, 200), make([]float64, 200), make([]float64, 200)
+	for i := range uniform {
+		uniform[i] = rng.Float64()
+		exponential[i] = rng.ExpFloat64()
+		cauchy[i] = math.Tan(math.Pi * (rng.Float64() - 0.5))
+	}
+	for _, x := range [][]float64{uniform, exponential, cauchy} {
+		w, p, err := ShapiroWilk(x)
+		assert.Equal(t, nil, err)
+		assert.T(t, w < 1)
+		assert.T(t, p < 0.001)
+	}
+}
+
+func TestShapiroWilkResiduals(t *testing.T) {
+	w, p, err := ShapiroWilk(summary.Residuals())
+	assert.Equal(t, nil, err)
+	assert.T(t, w > 0.9 && w < 1)
+	assert.T(t, p > 0.05)
+}
+
+func TestShapiroWilkRange(t *testing.T) {
+	_, _, err := ShapiroWilk([]float64{1, 2})
+	assert.NotEqual(t, nil, err)
+	_, _, err = ShapiroWilk(make([]float64, 5001))
+	assert.NotEqual(t, nil, err)
+	_, _, err = ShapiroWilk([]float64{2, 2, 2, 2})
+	assert.NotEqual(t, nil, err)
+	_, _, err = ShapiroWilk(seqFloat(5000))
+	assert.Equal(t, nil, err)
+}
+
+func seqFloat(n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = float64(i)
+	}
+	return x
+}
+
+func TestAndersonDarling(t *testing.T) {
+	x := []float64{2.1, 3.4, 1.9, 5.6, 4.4, 3.3, 2.8, 3.9, 4.1, 2.5}
+	a2, p, err := AndersonDarling(x)
+	assert.Equal(t, nil, err)
+
+	// from the definition, with the empirical CDF at each sorted value
+	sorted := []float64{1.9, 2.1, 2.5, 2.8, 3.3, 3.4, 3.9, 4.1, 4.4, 5.6}
+	m, s := mean(x), sd(x)
+	n := float64(len(x))
+	expected := -n
+	for i := range sorted {
+		f := normalCDF((sorted[i] - m) / s)
+		g := normalCDF((sorted[len(x)-1-i] - m) / s)
+		expected -= float64(2*i+1) / n * (math.Log(f) + math.Log(1-g))
+	}
+	assertClose(t, a2, expected, 1e-10)
+
+	aa := expected * (1 + 0.75/n + 2.25/(n*n))
+	assert.T(t, aa < 0.2)
+	assertClose(t, p, 1-math.Exp(-13.436+101.14*aa-223.73*aa*aa), 1e-12)
+
+	ra, rp, err := ResidualAndersonDarling(summary)
+	assert.Equal(t, nil, err)
+	a2, p, err = AndersonDarling(summary.Residuals())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ra, a2)
+	assert.Equal(t, rp, p)
+}
+
+func TestAndersonDarlingSize(t *testing.T) {
+	const reps = 2000
+	rng := rand.New(rand.NewSource(5))
+	for _, n := range []int{10, 100} {
+		rejected := 0
+		for rep := 0; rep < reps; rep++ {
+			x := make([]float64, n)
+			for i := range x {
+				x[i] = rng.NormFloat64()
+			}
+			_, p, err := AndersonDarling(x)
+			assert.Equal(t, nil, err)
+			if p < 0.05 {
+				rejected++
+			}
+		}
+		assertClose(t, float64(rejected)/reps, 0.05, 0.015)
+	}
+}
+
+func TestAndersonDarlingTails(t *testing.T) {
+	// a t distribution with 2 degrees of freedom is symmetric, so skewness
+	// carries no signal, but its tails are heavy
+	rng := rand.New(rand.NewSource(6))
+	x := make([]float64, 300)
+	for i := range x {
+		z := rng.NormFloat64()
+		chi := 0.0
+		for k := 0; k < 2; k++ {
+			g := rng.NormFloat64()
+			chi += g * g
+		}
+		x[i] = z / math.Sqrt(chi/2)
+	}
+	_, p, err := AndersonDarling(x)
+	assert.Equal(t, nil, err)
+	assert.T(t, p < 0.001)
+
+	_, _, err = AndersonDarling(x[:7])
+	assert.NotEqual(t, nil, err)
+	_, _, err = AndersonDarling(rep(1, 10))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestQQData(t *testing.T) {
+	qq, err := QQData(summary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(qq.Theoretical), 21)
+	assert.Equal(t, len(qq.Sample), 21)
+	for i := 1; i < 21; i++ {
+		assert.T(t, qq.Sample[i-1] <= qq.Sample[i])
+		assert.T(t, qq.Theoretical[i-1] < qq.Theoretical[i])
+	}
+	// the plotting positions are symmetric about the median
+	assertClose(t, qq.Theoretical[10], 0, 1e-15)
+	assertClose(t, qq.Theoretical[0], -qq.Theoretical[20], 1e-12)
+	assertClose(t, qq.Theoretical[0], NormalQuantile(0.5/21), 1e-15)
+
+	// the reference line passes through the quartiles
+	x1, x2 := NormalQuantile(0.25), NormalQuantile(0.75)
+	assertClose(t, qq.Intercept+qq.Slope*x1, quantile(qq.Sample, 0.25), 1e-12)
+	assertClose(t, qq.Intercept+qq.Slope*x2, quantile(qq.Sample, 0.75), 1e-12)
+
+	// a sample of exact normal quantiles falls on the line y = 2x + 1
+	z := make([]float64, 100)
+	for i := range z {
+		z[i] = 2*NormalQuantile((float64(i)+0.5)/100) + 1
+	}
+	qq = normalQQ(z)
+	for i := range z {
+		assertClose(t, qq.Sample[i], 2*qq.Theoretical[i]+1, 1e-12)
+	}
+	assertClose(t, qq.Slope, 2, 0.03)
+	assertClose(t, qq.Intercept, 1, 1e-12)
+
+	qq, err = QQData(unitLeverageSummary(t))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(qq.Sample), 6)
+}
@@ -1,12 +1,13 @@
 package glasso
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sync"
 
 	"github.com/drewlanenga/govector"
-	"github.com/ematvey/gostat"
-	"github.com/gonum/matrix/mat64"
+	"gonum.org/v1/gonum/mat"
 )
 
 // Ordinary Least Squares regression using QR factorization
@@ -15,23 +16,317 @@ import (
 // X = Q*R
 // XtX = (QR)t(QR) = RtQtQR = RtR
 // Rβ = Qt y
+//
+// A model is never modified once it is fit, so its methods are safe to call
+// from several goroutines at once, as are the diagnostics of its summary.
 type OLS struct {
 	betas []float64
-	n, p  int
+	n, p  int // observations and coefficients (including the intercept) of the fit
+
+	names   []string   // predictor names, if the training data had them
+	weights []float64  // observation weights of a weighted fit, or nil
+	sigma2  float64    // residual variance RSS / (n - p)
+	vcov    *mat.Dense // variance-covariance matrix of the coefficients
+
+	// for a fit on standardized predictors, their means and standard
+	// deviations and the coefficients on that scale, which Predict uses
+	center, scale, standardized []float64
+
+	rows []int    // the rows of the training data fit, or nil for all of them
+	ids  []string // of the observations, from those WithRowIDs gave the rows, or nil
+
+	noIntercept bool // fit through the origin, so betas has no intercept
+
+	aliased []int // coefficients that couldn't be estimated, which are zero in betas
+
+	fit *deletion // the fit that Without deletes observations from, or nil
+
+	response *responseTransform // the transform of the response fit, or nil
+
+	rank int // the singular values kept by FitSVD, or zero for other fits
+
+	// the condition number of the design, with its columns scaled to unit
+	// length for a least squares fit, or of the design as given for a fit by
+	// FitSVD, which sets rawCondition
+	condition    float64
+	rawCondition bool
+	implicated   []string  // the columns of its nearest dependency
+	warnings     []Warning // found at fit time
 }
 
-func NewOlsTrainer() Trainer {
-	return &olsTrainer{}
+// NewOlsTrainer returns a Trainer for ordinary least squares. With
+// WithStandardize(true) the predictors are centered and scaled to unit
+// variance before the fit, which can make it more accurate when they are on
+// very different scales; see Train. They aren't by default. WithNAPolicy sets
+// how missing values are handled; they are an error by default. The trainer
+// adds the intercept column itself unless WithIntercept(false) is given, for a
+// fit through the origin; the data shouldn't include a column of ones. With
+// WithResponseTransform the fit is of the transformed response, as are its
+// summary and Predict, and OLS.PredictResponse predicts on the original scale.
+// A fit of an ill-conditioned design succeeds with a warning, which
+// OLS.Warnings returns; WithMaxCondition sets how ill-conditioned.
+func NewOlsTrainer(opts ...Option) Trainer {
+	return &olsTrainer{opts: newOptions(append([]Option{WithStandardize(false)}, opts...))}
 }
 
-type olsTrainer struct{}
+type olsTrainer struct {
+	opts options
+}
 
+// Train fits the model. The DataFrame is not modified. A fit on standardized
+// predictors reports its coefficients and their covariance matrix on the
+// original scale, and its summary describes the fit on the original
+// predictors, so that the two fits have the same fitted values, coefficients
+// and diagnostics but for rounding. The model keeps the means and standard
+// deviations of the predictors and standardizes new data with them. A constant
+// predictor, which duplicates the intercept, is aliased with it.
+//
+// A rank-deficient design doesn't fail the fit. As R's lm does, a predictor that
+// is a linear combination of the intercept and the predictors before it is
+// aliased: the fit is of the remaining predictors, the model's coefficient for
+// it is NaN (and it is left out of Predict), and the summary, its covariance
+// matrix and its diagnostics are those of the fit on the estimable
+// coefficients alone. Aliased lists the coefficients that weren't estimated.
 func (o *olsTrainer) Train(x *DataFrame, yvector []float64) (Model, Summary, error) {
+	if err := checkRowIDs(o.opts.rowIDs, x.Rows()); err != nil {
+		return nil, nil, err
+	}
+	x, yvector, rows, err := handleMissing(x, yvector, o.opts.na)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t := o.opts.response; t != nil {
+		if yvector, err = transformResponse(*t, yvector); err != nil {
+			return nil, nil, err
+		}
+	}
+	intercept := o.opts.intercept
+	model, summary, err := fitEstimable(x, x, intercept, func(x *DataFrame) (*OLS, OlsSummary, error) {
+		if o.opts.standardize {
+			return trainStandardized(x, yvector, intercept, o.opts.solver)
+		}
+		return fitLeastSquares(x, yvector, nil, intercept, o.opts.solver)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	model.rows, summary.rows = rows, rows
+	model.ids = selectIDs(o.opts.rowIDs, model.RetainedRows())
+	summary.ids = model.ids
+	model.fit = newDeletion(summary)
+	model.checkCondition(o.opts.maxCondition)
+	if t := o.opts.response; t != nil {
+		model.response = &responseTransform{ResponseTransform: *t, residuals: summary.residuals}
+	}
+	return model, summary, nil
+}
+
+// NewWlsTrainer returns a Trainer for weighted least squares, which minimizes
+// \sum_i w_i (y_i - x_i'\beta)^2 for the given weights, one per observation.
+//
+// The fit is the OLS fit of the rescaled problem \sqrt{w_i} y_i = \sqrt{w_i} x_i'\beta + e_i,
+// and its summary describes that problem: the design and response are scaled by
+// \sqrt{w}, the residuals are R's weighted residuals \sqrt{w_i} e_i, and the
+// diagnostics (leverage from the weighted hat matrix W^{1/2}X(X'WX)^-1X'W^{1/2},
+// Cook's distance, VarCov = \sigma^2 (X'WX)^-1, the log-likelihood, ...) are those of R's
+// lm(weights = w). The unweighted residuals are available from
+// OlsSummary.OriginalResiduals. Observations with a weight of zero are dropped
+// from the fit, as are those with missing values under WithNAPolicy(OmitRows),
+// and negative weights are an error.
+func NewWlsTrainer(weights []float64, opts ...Option) Trainer {
+	return &wlsTrainer{weights: weights, opts: newOptions(opts)}
+}
+
+type wlsTrainer struct {
+	weights []float64
+	opts    options
+}
+
+func (w *wlsTrainer) Train(x *DataFrame, yvector []float64) (Model, Summary, error) {
+	if w.weights == nil {
+		return nil, nil, fmt.Errorf("no weights given")
+	}
+	if len(w.weights) != x.Rows() {
+		return nil, nil, DimensionError
+	}
+	for i, v := range w.weights {
+		if !(v >= 0) || math.IsInf(v, 0) {
+			return nil, nil, fmt.Errorf("weight %d is %v, but weights must be finite and non-negative", i, v)
+		}
+	}
+	if err := checkRowIDs(w.opts.rowIDs, x.Rows()); err != nil {
+		return nil, nil, err
+	}
+
+	x, yvector, rows, err := handleMissing(x, yvector, w.opts.na)
+	if err != nil {
+		return nil, nil, err
+	}
+	weights := w.weights
+	if rows != nil {
+		weights = make([]float64, len(rows))
+		for k, i := range rows {
+			weights[k] = w.weights[i]
+		}
+	}
+	// the columns aliased among the observations of positive weight, which are those fit
+	var positive []int
+	for i, v := range weights {
+		if v > 0 {
+			positive = append(positive, i)
+		}
+	}
+	if positive == nil {
+		return nil, nil, fmt.Errorf("every weight is zero")
+	}
+	fitted, err := x.SelectRows(positive)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, summary, err := fitEstimable(x, fitted, w.opts.intercept, func(x *DataFrame) (*OLS, OlsSummary, error) {
+		return fitLeastSquares(x, yvector, func(x *DataFrame, y []float64) (*DataFrame, []float64, []int, error) {
+			return rescale(x, y, weights)
+		}, w.opts.intercept, w.opts.solver)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	model.weights = append([]float64(nil), weights...)
+	for _, v := range weights {
+		if v > 0 {
+			summary.weights = append(summary.weights, v)
+		}
+	}
+	model.rows = compose(rows, model.rows)
+	summary.rows = model.rows
+	model.ids = selectIDs(w.opts.rowIDs, model.RetainedRows())
+	summary.ids = model.ids
+	model.fit = newDeletion(summary)
+	model.checkCondition(w.opts.maxCondition)
+	return model, summary, nil
+}
+
+// aliasTolerance is the tolerance of aliasedColumns, that of R's lm.fit.
+const aliasTolerance = 1e-7
+
+// aliasedColumns returns the columns of x that are linear combinations of the
+// intercept, if there is one, and the columns before them that aren't aliased
+// themselves, in order. As with the pivoting of R's QR decomposition, a column
+// is aliased if its residual on those columns has a norm within aliasTolerance
+// of its own. The residuals are from Gram-Schmidt, orthogonalizing twice.
+func aliasedColumns(x *DataFrame, intercept bool) []int {
+	n := x.Rows()
+	var basis [][]float64 // orthonormal
+	if intercept {
+		basis = append(basis, rep(1/math.Sqrt(float64(n)), n))
+	}
+	var aliased []int
+	for j := 0; j < x.Cols(); j++ {
+		residual := x.GetCol(j)
+		norm := math.Sqrt(sum(prod(residual, residual)))
+		for pass := 0; pass < 2; pass++ {
+			for _, q := range basis {
+				d := sum(prod(q, residual))
+				for i := range residual {
+					residual[i] -= d * q[i]
+				}
+			}
+		}
+		r := math.Sqrt(sum(prod(residual, residual)))
+		if !(r > aliasTolerance*norm) {
+			aliased = append(aliased, j)
+			continue
+		}
+		basis = append(basis, multSlice(residual, 1/r))
+	}
+	return aliased
+}
+
+// fitEstimable fits x with fit after dropping the columns that are aliased in
+// design, the rows of x that the fit uses. The model has a coefficient of zero
+// for each aliased column, and its covariance matrix and summary are those of
+// the fit on the rest.
+func fitEstimable(x, design *DataFrame, intercept bool, fit func(x *DataFrame) (*OLS, OlsSummary, error)) (*OLS, OlsSummary, error) {
+	aliased := aliasedColumns(design, intercept)
+	if aliased == nil {
+		return fit(x)
+	}
+	c := x.Cols()
+	names := x.names()
+	if len(aliased) == c {
+		return nil, OlsSummary{}, fmt.Errorf("every predictor is aliased")
+	}
+	isAliased := make([]bool, c)
+	for _, j := range aliased {
+		isAliased[j] = true
+	}
+	var kept []int
+	for j := 0; j < c; j++ {
+		if !isAliased[j] {
+			kept = append(kept, j)
+		}
+	}
+	reduced := mat.NewDense(x.Rows(), len(kept), nil)
+	labels := make([]string, len(kept))
+	for k, j := range kept {
+		reduced.SetCol(k, x.GetCol(j))
+		labels[k] = names[j]
+	}
+	estimable := MatToDF(reduced)
+	estimable.labels = labels
+	model, summary, err := fit(estimable)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+
+	offset := 0
+	if intercept {
+		offset = 1
+	}
+	// expand fills in the values of the aliased columns, after the first offset
+	expand := func(v []float64, offset int, fill float64) []float64 {
+		if v == nil {
+			return nil
+		}
+		full := rep(fill, c+offset)
+		copy(full, v[:offset])
+		for k, j := range kept {
+			full[j+offset] = v[k+offset]
+		}
+		return full
+	}
+	model.betas = expand(model.betas, offset, 0)
+	model.standardized = expand(model.standardized, offset, 0)
+	model.center = expand(model.center, 0, 0)
+	model.scale = expand(model.scale, 0, 1)
+	model.p = len(model.betas)
+	model.names = x.Labels()
+	model.aliased = make([]int, len(aliased))
+	summary.aliased = make([]string, len(aliased))
+	for k, j := range aliased {
+		model.aliased[k] = j + offset
+		summary.aliased[k] = names[j]
+	}
+	return model, summary, nil
+}
+
+// A transformation maps the design (with its intercept column) and response of
+// a regression to those of an equivalent OLS problem, such as the rescaled
+// problem of weighted least squares. rows are the observations of x that
+// correspond to the rows of the transformed design.
+type transformation func(x *DataFrame, y []float64) (design *DataFrame, response []float64, rows []int, err error)
+
+// trainLeastSquares fits the model with an intercept by least squares, after
+// transforming the problem if transform is not nil.
+func trainLeastSquares(x *DataFrame, yvector []float64, transform transformation) (*OLS, OlsSummary, error) {
+	return fitLeastSquares(x, yvector, transform, true, QRSolver)
+}
+
+// fitLeastSquares fits the model by least squares with solver, with an
+// intercept column pushed on to the front of a copy of x if intercept is set
+// and through the origin otherwise. It doesn't modify x.
+func fitLeastSquares(x *DataFrame, yvector []float64, transform transformation, intercept bool, solver Solver) (*OLS, OlsSummary, error) {
 	rows, cols := x.Rows(), x.Cols()
-	//	cols := x.cols + 1
-	//	d := mat64.DenseCopyOf(x.data.Grow(0, 1))
-	//	d.SetCol(0, rep(1.0, rows))
-	dataframe := x
 	betas := make([]float64, cols)
 	residuals := make([]float64, rows)
 	fitted := make([]float64, rows)
@@ -41,93 +336,443 @@ func (o *olsTrainer) Train(x *DataFrame, yvector []float64) (Model, Summary, err
 
 	// sanity check
 	if len(yvector) != n {
-		return nil, nil, DimensionError
+		return nil, OlsSummary{}, DimensionError
 	}
 
 	copy(response, yvector)
-	y := mat64.NewDense(len(yvector), 1, yvector)
 
-	// remove?
-	x.PushCol(rep(1., x.Rows()))
+	names := x.names()
+	design := x.Copy()
+	design.labels = x.Labels()
+	if intercept {
+		names = append([]string{"(Intercept)"}, names...)
+		design.PushCol(rep(1., rows))
+	}
+	dataframe := design
+
+	var kept []int
+	if transform != nil {
+		var err error
+		dataframe, response, kept, err = transform(design, yvector)
+		if err != nil {
+			return nil, OlsSummary{}, err
+		}
+		n = dataframe.Rows()
+		if n < dataframe.Cols() {
+			return nil, OlsSummary{}, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, dataframe.Cols())
+		}
+	}
+	y := mat.NewDense(n, 1, append([]float64(nil), response...))
 
 	// it's easier to do things with X = QR
-	betaMat := &mat64.Dense{}
-	qr := &mat64.QR{}
-	data := x.Data()
-	qr.Factorize(data)
-	if err := betaMat.SolveQR(qr, false, y); err != nil {
-		return nil, nil, err
+	fit, err := leastSquaresBy(dataframe.X, y, solver)
+	if err != nil {
+		if !errors.Is(err, SingularDesignError) {
+			return nil, OlsSummary{}, err
+		}
+		if collinear := collinearColumns(dataframe.X, names); collinear != nil {
+			return nil, OlsSummary{}, collinear
+		}
+		return nil, OlsSummary{}, err
 	}
 	// first one is intercept
-	betas = mat64.Col(nil, 0, betaMat)
-	fittedMat := &mat64.Dense{}
-	fittedMat.Mul(x.Data(), betaMat)
-	fitted = mat64.Col(nil, 0, fittedMat)
+	betas = fit.betas
+	fitted = fit.fitted
+	residuals = fit.residuals
 
-	residualMat := &mat64.Dense{}
-	residualMat.Sub(y, fittedMat)
-	residuals = mat64.Col(nil, 0, residualMat)
-
-	// fmt.Printf("betas=%v\n", o.betas)
-	// Q := &mat64.Dense{}
-	// Q.QFromQR(qr)
-	// fmt.Printf("q=%+v\nn\n\n\n\n", Q)
-	// qqt := &mat64.Dense{}
-	// qqt.Mul(Q, Q.T())
-	// fmt.Printf("qqt=%+v\n", qqt)
-	// yhat := &mat64.Dense{}
-	// yhat.Mul(qqt, y)
-	// o.fitted = mat64.Col(nil, 0, yhat)
-	// fmt.Printf("fitted=%v\n", o.fitted)
-	// y.Sub(y, yhat)
-	// o.residuals = mat64.Col(nil, 0, y)
+	summary := OlsSummary{
+		betas:     betas,
+		residuals: residuals,
+		fitted:    fitted,
+		response:  response,
+		n:         n,
+		p:         p,
+		data:      dataframe,
+		qr:        &qrCache{x: dataframe.X, qr: fit.qr, r: fit.r},
+		origin:    !intercept,
+	}
+	if transform != nil {
+		if intercept {
+			summary.ones = dataframe.GetCol(0)
+		} else {
+			// the transformed intercept, which the total sum of squares is around
+			ones, _, _, err := transform(MatToDF(mat.NewDense(rows, 1, rep(1, rows))), yvector)
+			if err != nil {
+				return nil, OlsSummary{}, err
+			}
+			summary.ones = ones.GetCol(0)
+		}
+		summary.original = make([]float64, n)
+		for i, row := range kept {
+			summary.original[i] = yvector[row] - sum(prod(design.GetRow(row), betas))
+		}
+	}
+	if len(kept) < rows {
+		summary.rows = kept
+	}
+	// an exact fit, such as a minimal subset of RANSAC, has no estimate of the
+	// error variance to scale its covariance matrix by
+	sigma2, err := MseAdjusted(summary)
+	if err != nil {
+		sigma2 = math.NaN()
+	}
+	vcov, err := scaledVarCov(summary, sigma2)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	condition, implicated := fitCondition(fit, names)
+
+	return &OLS{
+		betas:  betas,
+		n:      n,
+		p:      len(betas),
+		names:  x.Labels(),
+		sigma2: sigma2,
+		vcov:   vcov.X,
+		rows:   summary.rows,
+
+		noIntercept: !intercept,
+
+		condition:  condition,
+		implicated: implicated,
+	}, summary, nil
+}
+
+// trainStandardized fits the model by least squares on the predictors
+// centered and scaled to unit variance, and transforms the coefficients and
+// their covariance matrix back to the original scale: with \gamma the
+// coefficients on the standardized scale, \beta = T\gamma for
+//
+// T = [1, -m_1/s_1, ..., -m_c/s_c; 0, diag(1/s_j)]
+//
+// and the covariance matrix is T V_\gamma T'. Without an intercept the
+// predictors aren't centered, and are scaled by their root mean squares, so
+// that T = diag(1/s_j).
+func trainStandardized(x *DataFrame, yvector []float64, intercept bool, solver Solver) (*OLS, OlsSummary, error) {
+	n, c := x.Rows(), x.Cols()
+	if len(yvector) != n {
+		return nil, OlsSummary{}, DimensionError
+	}
+	means, scales := make([]float64, c), make([]float64, c)
+	z := mat.NewDense(n, c, nil)
+	names := x.names()
+	for j := 0; j < c; j++ {
+		col := x.GetCol(j)
+		if intercept {
+			// a constant column is a multiple of the intercept, and can't be scaled
+			means[j], scales[j] = mean(col), math.Sqrt(centralMoment(col, 2))
+			if !(scales[j] > 0) {
+				return nil, OlsSummary{}, fmt.Errorf("column %q is constant and can't be standardized", names[j])
+			}
+		} else {
+			scales[j] = math.Sqrt(sum(prod(col, col)) / float64(n))
+			if !(scales[j] > 0) {
+				return nil, OlsSummary{}, fmt.Errorf("column %q is identically zero", names[j])
+			}
+		}
+		z.SetCol(j, multSlice(subSlice(col, means[j]), 1/scales[j]))
+	}
+	scaled := MatToDF(z)
+	scaled.labels = names
+	standardized, summary, err := fitLeastSquares(scaled, yvector, nil, intercept, solver)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+
+	gamma := standardized.betas
+	p, offset := len(gamma), 0
+	t := mat.NewDense(p, p, nil)
+	if intercept {
+		t.Set(0, 0, 1)
+		offset = 1
+	}
+	for j := 0; j < c; j++ {
+		if intercept {
+			t.Set(0, j+1, -means[j]/scales[j])
+		}
+		t.Set(j+offset, j+offset, 1/scales[j])
+	}
+	betas := make([]float64, p)
+	for j := range betas {
+		betas[j] = sum(prod(t.RawRowView(j), gamma))
+	}
+	left, vcov := &mat.Dense{}, &mat.Dense{}
+	left.Mul(t, standardized.vcov)
+	vcov.Mul(left, t.T())
+	symmetrize(vcov)
 
+	design := x.Copy()
+	design.labels = x.Labels()
+	if intercept {
+		design.PushCol(rep(1, n))
+	}
+	summary.betas = betas
+	summary.data = design
+	summary.qr = &qrCache{}
 	return &OLS{
-			betas: betas,
-		},
-		OlsSummary{
-			betas:     betas,
-			residuals: residuals,
-			fitted:    fitted,
-			response:  response,
-			n:         n,
-			p:         p,
-			data:      dataframe,
-		}, nil
-}
-
-//func (o *OLS) prediction
+		betas:        betas,
+		n:            n,
+		p:            p,
+		names:        x.Labels(),
+		sigma2:       standardized.sigma2,
+		vcov:         vcov,
+		center:       means,
+		scale:        scales,
+		standardized: gamma,
+		noIntercept:  !intercept,
+		condition:    standardized.condition,
+		implicated:   standardized.implicated,
+	}, summary, nil
+}
+
+// rescale drops the observations with a weight of zero and scales the rest of
+// the rows of x and y by the square root of their weights.
+func rescale(x *DataFrame, y, weights []float64) (*DataFrame, []float64, []int, error) {
+	var rows [][]float64
+	var response []float64
+	var kept []int
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		s := math.Sqrt(w)
+		rows = append(rows, multSlice(x.GetRow(i), s))
+		response = append(response, s*y[i])
+		kept = append(kept, i)
+	}
+	if len(rows) == 0 {
+		return nil, nil, nil, fmt.Errorf("every weight is zero")
+	}
+	return NewDataFrame(rows, x.Labels()), response, kept, nil
+}
+
+// lsFit holds the solution to a least squares problem
+type lsFit struct {
+	betas     []float64
+	fitted    []float64
+	residuals []float64
+	qr        *mat.QR
+	r         *mat.TriDense // R'R = X'X, for a fit by the normal equations without qr
+}
+
+// leastSquares solves min ||y - X beta|| using the QR factorization of x.
+func leastSquares(x *mat.Dense, y *mat.Dense) (*lsFit, error) {
+	if n, p := x.Dims(); n < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+	betaMat := &mat.Dense{}
+	qr := factorize(x)
+	if err := qr.SolveTo(betaMat, false, y); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+
+	fittedMat := &mat.Dense{}
+	fittedMat.Mul(x, betaMat)
+
+	residualMat := &mat.Dense{}
+	residualMat.Sub(y, fittedMat)
+
+	return &lsFit{
+		betas:     mat.Col(nil, 0, betaMat),
+		fitted:    mat.Col(nil, 0, fittedMat),
+		residuals: mat.Col(nil, 0, residualMat),
+		qr:        qr,
+	}, nil
+}
+
+// interceptColumn returns the index of the first column of x that is all ones, or -1.
+func interceptColumn(x mat.Matrix) int {
+	n, _ := x.Dims()
+	return matchingColumn(x, rep(1, n))
+}
+
+// matchingColumn returns the index of the first column of x that equals v, or -1.
+func matchingColumn(x mat.Matrix, v []float64) int {
+	n, c := x.Dims()
+	for j := 0; j < c; j++ {
+		i := 0
+		for ; i < n && x.At(i, j) == v[i]; i++ {
+		}
+		if i == n {
+			return j
+		}
+	}
+	return -1
+}
+
+// interceptValues returns the column an intercept has in the design matrix of
+// the model: ones, or for instance \sqrt{w} for a weighted fit.
+func interceptValues(m Summary) []float64 {
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return s.ones
+	}
+	return rep(1, m.Data().Rows())
+}
+
+// interceptOf returns the index of the intercept column of the design matrix of the model, or -1.
+func interceptOf(m Summary) int {
+	if s, ok := m.(OlsSummary); ok && s.origin {
+		return -1
+	}
+	return matchingColumn(m.Data().X, interceptValues(m))
+}
+
+// sumOfSquaresAround returns the sum of squares of the residuals of v regressed
+// on c alone, which for c = 1 is the sum of squares of v around its mean.
+func sumOfSquaresAround(v, c []float64) float64 {
+	mu := sum(prod(v, c)) / sum(prod(c, c))
+	ss := 0.0
+	for i := range v {
+		ss += (v[i] - mu*c[i]) * (v[i] - mu*c[i])
+	}
+	return ss
+}
+
+// Coefficients returns the coefficients of the model, the intercept first
+// unless it was fit through the origin, in the order of Names. Aliased
+// coefficients, which couldn't be estimated, are NaN. They are a copy, which
+// the caller may modify.
+func (o *OLS) Coefficients() []float64 {
+	betas := append([]float64(nil), o.betas...)
+	for _, j := range o.aliased {
+		betas[j] = math.NaN()
+	}
+	return betas
+}
+
+// NumObs returns the number of observations the model was fit to.
+func (o *OLS) NumObs() int { return o.n }
+
+// NumPredictors returns the number of predictors of the model, not counting
+// the intercept.
+func (o *OLS) NumPredictors() int { return o.predictors() }
+
+// FittedValues returns the fitted values of the observations the model was
+// fit to, one for each of RetainedRows, computed from the fit it keeps. Those
+// of a weighted fit are on the transformed scale, as its summary's are. A
+// model that doesn't keep its fit, as Without describes, returns nil.
+func (o *OLS) FittedValues() []float64 {
+	d := o.fit
+	if d == nil {
+		return nil
+	}
+	rows := d.kept
+	if rows == nil {
+		rows = identityRows(d.summary.n)
+	}
+	x := d.summary.data.X
+	fitted := make([]float64, len(rows))
+	for k, row := range rows {
+		fitted[k] = sum(prod(x.RawRowView(row), d.betas))
+	}
+	return fitted
+}
+
+// Residuals returns the residuals of the observations the model was fit to,
+// as FittedValues returns their fitted values.
+func (o *OLS) Residuals() []float64 {
+	fitted := o.FittedValues()
+	if fitted == nil {
+		return nil
+	}
+	d := o.fit
+	residuals := make([]float64, len(fitted))
+	for k := range fitted {
+		row := k
+		if d.kept != nil {
+			row = d.kept[k]
+		}
+		residuals[k] = d.summary.response[row] - fitted[k]
+	}
+	return residuals
+}
+
+// Aliased returns the names of the coefficients that couldn't be estimated
+// because their predictors are linear combinations of the others, or nil if
+// the design had full rank. See NewOlsTrainer.
+func (o *OLS) Aliased() []string {
+	if o.aliased == nil {
+		return nil
+	}
+	names := o.Names()
+	aliased := make([]string, len(o.aliased))
+	for k, j := range o.aliased {
+		aliased[k] = names[j]
+	}
+	return aliased
+}
+
+// ResidualStandardError returns the estimate of the error standard deviation
+// sqrt(RSS / (n - p)) of the fit, as OlsSummary.ResidualStandardError does.
+func (o *OLS) ResidualStandardError() float64 {
+	return math.Sqrt(o.sigma2)
+}
+
+// Names returns the names of the coefficients: "(Intercept)", unless the
+// model was fit through the origin, and the labels of the predictors it was
+// trained on, or x0, x1, ... if they had none.
+func (o *OLS) Names() []string {
+	var names []string
+	if !o.noIntercept {
+		names = append(names, "(Intercept)")
+	}
+	if len(o.names) == o.predictors() {
+		return append(names, o.names...)
+	}
+	for j := 0; j < o.predictors(); j++ {
+		names = append(names, fmt.Sprintf("x%d", j))
+	}
+	return names
+}
+
+// predictors returns the number of predictors of the model.
+func (o *OLS) predictors() int {
+	if o.noIntercept {
+		return len(o.betas)
+	}
+	return len(o.betas) - 1
+}
+
+// RetainedRows returns the rows of the training data that the model was fit
+// to, as OlsSummary.RetainedRows does.
+func (o *OLS) RetainedRows() []int {
+	if o.rows == nil {
+		return identityRows(o.n)
+	}
+	return o.rows
+}
+
+// RowIDs returns the ids of the observations the model was fit to, as
+// OlsSummary.RowIDs does.
+func (o *OLS) RowIDs() []string {
+	return append([]string(nil), o.ids...)
+}
+
+// func (o *OLS) prediction
 func (o *OLS) Predict(x []float64) float64 {
+	if o.scale != nil {
+		v, gamma := 0.0, o.standardized
+		if !o.noIntercept {
+			v, gamma = gamma[0], gamma[1:]
+		}
+		for j, b := range gamma {
+			v += b * (x[j] - o.center[j]) / o.scale[j]
+		}
+		return v
+	}
+	if o.noIntercept {
+		return sum(prod(x, o.betas))
+	}
 	return o.betas[0] + sum(prod(x, o.betas[1:]))
 }
 
+// String renders the model in the layout of R's summary.lm. See NewReport.
 func (o OlsSummary) String() string {
-	q, _ := govector.AsVector(o.residuals)
-	points := []float64{0.0, 0.25, 0.5, 0.75, 1.0}
-	p, _ := govector.AsVector(points)
-	qnt := q.Quantiles(p)
-	f, fp := o.F_Statistic()
-	return fmt.Sprintf(`
-		Residuals: 
-		Min  25  50t 75  Max: 
-		%v
-		
-		Coefficients: 
-		%v
-
-		RSS: %v 
-		MSE: %v
-		Adjusted R-Squared: %v
-		R-squared: %v
-		F-statistic: %v with P-value: %v`,
-		roundAll(qnt),
-		roundAll(o.betas),
-		round(o.ResidualSumofSquares(), 3),
-		round(o.MeanSquaredError(), 3),
-		round(o.AdjustedRSquared(), 3),
-		round(o.RSquared(), 3),
-		round(f, 4), round(fp, 10),
-	)
+	r, err := NewReport(o)
+	if err != nil {
+		return fmt.Sprintf("summary unavailable: %v", err)
+	}
+	return r.String()
 }
 
 type OlsSummary struct {
@@ -137,14 +782,150 @@ type OlsSummary struct {
 	response  []float64
 	n, p      int
 	data      *DataFrame
+	qr        *qrCache // factorization of data, shared by copies of the summary
+
+	// For a fit that transforms the problem, such as weighted least squares, the
+	// fields above describe the transformed problem. ones is then the intercept
+	// column of the transformed design, and original holds the residuals on the
+	// scale of the response.
+	ones, original []float64
+	weights        []float64 // of a weighted fit, one for each row of data
+
+	rows   []int    // the rows of the training data fit, or nil for all of them
+	ids    []string // of the observations, from those WithRowIDs gave the rows, or nil
+	origin bool     // the fit has no intercept, whatever the columns of data are
+
+	aliased []string // predictors left out of data because they were aliased
+
+	svd *svdFactors // the truncated SVD of data, for a fit by FitSVD
+}
+
+// qrCache memoizes the QR factorization of a design matrix so that the
+// diagnostics don't refactorize it on every call. It is safe for concurrent
+// use, and the factorization is recomputed if the design matrix is replaced.
+type qrCache struct {
+	mu sync.Mutex
+	x  *mat.Dense // the matrix that was factorized
+	qr *mat.QR
+	r  *mat.TriDense // the Cholesky factor of x'x, for a fit by the normal equations
+	h  *mat.Dense    // the hat matrix of x, once HatMatrix has formed it
+
+	// leverage is the diagonal of the hat matrix of x, once LeveragePoints has
+	// found it. lmu is held while it is found, so that it is found once.
+	lmu      sync.Mutex
+	leverage []float64
+}
+
+func (c *qrCache) get(x *mat.Dense) *mat.QR {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.qr == nil || c.x != x {
+		c.qr = factorize(x)
+		if c.x != x {
+			c.r, c.h, c.leverage = nil, nil, nil
+		}
+		c.x = x
+	}
+	return c.qr
+}
+
+// leverages returns a copy of the leverages of x, found by compute the first
+// time they are asked for.
+func (c *qrCache) leverages(x *mat.Dense, compute func() ([]float64, error)) ([]float64, error) {
+	c.lmu.Lock()
+	defer c.lmu.Unlock()
+	c.mu.Lock()
+	h := c.leverage
+	if c.x != x {
+		h = nil
+	}
+	c.mu.Unlock()
+	if h == nil {
+		var err error
+		if h, err = compute(); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		if c.x == x || c.x == nil {
+			c.x, c.leverage = x, h
+		}
+		c.mu.Unlock()
+	}
+	return append([]float64(nil), h...), nil
+}
+
+// hatMatrix returns the hat matrix of x that HatMatrix formed, or nil if it
+// hasn't or x has been replaced.
+func (c *qrCache) hatMatrix(x *mat.Dense) *mat.Dense {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.x != x {
+		return nil
+	}
+	return c.h
+}
+
+// setHatMatrix keeps the hat matrix h of x.
+func (c *qrCache) setHatMatrix(x *mat.Dense, h *mat.Dense) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.x != x {
+		c.x, c.qr, c.r, c.leverage = x, nil, nil, nil
+	}
+	c.h = h
+}
+
+// cholesky returns the Cholesky factor of x'x that the fit found, or nil if it
+// used QR or x has been replaced.
+func (c *qrCache) cholesky(x *mat.Dense) *mat.TriDense {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.x != x {
+		return nil
+	}
+	return c.r
+}
+
+// factorizeHook is called whenever a design matrix is factorized.
+var factorizeHook = func() {}
+
+func factorize(x *mat.Dense) *mat.QR {
+	factorizeHook()
+	qr := &mat.QR{}
+	qr.Factorize(x)
+	return qr
+}
+
+// qrOf returns the QR factorization of the design matrix of the summary,
+// reusing the cached factorization when the summary carries one. A design with
+// fewer rows than columns can't be factorized, and is a TooFewObservationsError.
+func qrOf(m Summary) (*mat.QR, error) {
+	x := m.Data().X
+	if n, p := x.Dims(); n < p {
+		return nil, TooFewObservationsError
+	}
+	if s, ok := m.(OlsSummary); ok && s.qr != nil {
+		return s.qr.get(x), nil
+	}
+	return factorize(x), nil
 }
 
+// The coefficients, residuals and fitted values of a summary are copies, so
+// that a caller that modifies them doesn't change its diagnostics.
 func (o OlsSummary) Data() *DataFrame        { return o.data }
-func (o OlsSummary) Coefficients() []float64 { return o.betas }
-func (o OlsSummary) Residuals() []float64    { return o.residuals }
-func (o OlsSummary) Yhat() []float64         { return o.fitted }
+func (o OlsSummary) Coefficients() []float64 { return append([]float64(nil), o.betas...) }
+func (o OlsSummary) Residuals() []float64    { return append([]float64(nil), o.residuals...) }
+func (o OlsSummary) Yhat() []float64         { return append([]float64(nil), o.fitted...) }
 
+// TotalSumofSquares returns the sum of squares of the response around its mean,
+// or around zero when the model has no intercept, as R does for summary.lm.
 func (o OlsSummary) TotalSumofSquares() float64 {
+	if !o.hasIntercept() {
+		return sum(prod(o.response, o.response))
+	}
+	if o.transformed() {
+		return sumOfSquaresAround(o.response, o.ones)
+	}
 	y := govector.Vector(o.response)
 	ybar := y.Mean()
 	squaredDiff := func(x float64) float64 {
@@ -153,6 +934,49 @@ func (o OlsSummary) TotalSumofSquares() float64 {
 	return y.Apply(squaredDiff).Sum()
 }
 
+// transformed reports whether the summary describes a transformed problem; see NewWlsTrainer.
+func (o OlsSummary) transformed() bool {
+	return o.ones != nil
+}
+
+// OriginalResiduals returns the residuals y - X\beta on the scale of the
+// response. They are the Residuals, except for fits that transform the problem
+// such as weighted least squares, whose Residuals are on the transformed scale.
+func (o OlsSummary) OriginalResiduals() []float64 {
+	if o.transformed() {
+		return append([]float64(nil), o.original...)
+	}
+	return o.Residuals()
+}
+
+// RetainedRows returns the rows of the training data that the fit used, in
+// order, after dropping those with missing values or a weight of zero. The
+// residuals, fitted values and diagnostics such as CooksDistance have a value
+// for each of them: the kth is that of the row RetainedRows()[k].
+func (o OlsSummary) RetainedRows() []int {
+	if o.rows == nil {
+		return identityRows(o.n)
+	}
+	return o.rows
+}
+
+// RowIDs returns the ids that WithRowIDs gave the rows of the training data
+// for the observations of the fit, one for each of RetainedRows, or nil if it
+// gave none.
+func (o OlsSummary) RowIDs() []string {
+	return append([]string(nil), o.ids...)
+}
+
+// Aliased returns the names of the predictors that were left out of the fit
+// because they are linear combinations of the others, or nil if the design had
+// full rank. The summary's design, coefficients and diagnostics don't include
+// them.
+func (o OlsSummary) Aliased() []string { return o.aliased }
+
+func (o OlsSummary) hasIntercept() bool {
+	return o.data == nil || interceptOf(o) >= 0
+}
+
 func (o OlsSummary) SumOfSquares() float64 {
 	return o.ResidualSumofSquares()
 }
@@ -161,59 +985,63 @@ func (o OlsSummary) ResidualSumofSquares() float64 {
 	return sum(prod(o.residuals, o.residuals))
 }
 
+// RSquared returns 1 - RSS / TSS, the proportion of the variation of the response explained by the model.
 func (o OlsSummary) RSquared() float64 {
 	return float64(1 - (o.ResidualSumofSquares() / o.TotalSumofSquares()))
 }
 
+// MeanSquaredError returns RSS / n, the maximum likelihood estimate of the
+// error variance, which is biased downwards. Cook's distance, the covariance of
+// the coefficients and the standardized residuals use the unbiased
+// RSS / (n - p) of MseAdjusted instead; see ResidualStandardError.
 func (o OlsSummary) MeanSquaredError() float64 {
-	return o.ResidualSumofSquares() / float64(o.n) // - o.dataframe.Cols()))
+	return o.ResidualSumofSquares() / float64(o.n)
+}
+
+// ResidualStandardError returns the estimate of the error standard deviation
+// sqrt(RSS / (n - p)), with p the number of coefficients including the
+// intercept, as R's sigma. It is NaN if there are no more observations than
+// coefficients.
+func (o OlsSummary) ResidualStandardError() float64 {
+	mse, err := MseAdjusted(o)
+	if err != nil {
+		return math.NaN()
+	}
+	return math.Sqrt(mse)
 }
 
 // the adjusted r-squared adjusts the r-squared value to reflect the importance of predictor variables
 // https://en.wikipedia.org/wiki/Coefficient_of_determination#Adjusted_R2
+//
+// 1 - (1 - R^2) (n - 1) / (n - p), where p counts the coefficients including the
+// intercept; without an intercept the n - 1 becomes n.
 func (o OlsSummary) AdjustedRSquared() float64 {
-	dfe := float64(o.n)
-	dft := dfe - float64(o.p)
-	return 1 - (o.ResidualSumofSquares()*(dfe-1.0))/(o.TotalSumofSquares()*dft)
-}
-
-func (o OlsSummary) sdResiduals() float64 {
-	ybar := mean(o.response)
-	ss := 0.0
-	for i := 0; i < o.n; i++ {
-		ss += math.Pow(ybar-o.fitted[i], 2.0)
+	n := float64(o.n)
+	dft := n
+	if o.hasIntercept() {
+		dft--
 	}
-	return math.Sqrt(ss / float64(o.n-2))
+	dfe := n - float64(len(o.betas))
+	return 1 - (o.ResidualSumofSquares()*dft)/(o.TotalSumofSquares()*dfe)
 }
 
 func (o OlsSummary) Response() []float64 {
 	return o.response
 }
 
+// F_Statistic returns the overall F statistic of the model and its p-value. See OverallFTest.
 func (o OlsSummary) F_Statistic() (float64, float64) {
-	r1 := o.TotalSumofSquares()
-	r2 := o.ResidualSumofSquares()
-	p1 := float64(1)
-	p2 := float64(o.p)
-	denom1 := p2 - p1 + 1
-	denom2 := float64(o.n) - p2 - 1
-
-	f := (r1 - r2) / denom1
-	f /= r2 / denom2
-
-	Fdist := stat.F_CDF(denom1, denom2)
-	return f, 1.0 - Fdist(f)
-}
-
-func (o OlsSummary) Confidence_interval(alpha float64) [][2]float64 {
-	tdist := stat.StudentsT_PDF(float64(o.n - 1))
-	t := tdist(1 - alpha)
-	std_err := VarBeta(o)
-	cis := make([][2]float64, len(o.betas))
-	for i, b := range o.betas {
-		v := math.Sqrt(std_err[i])
-		cis[i] = [2]float64{b - t*v, b + t*v}
+	result, err := OverallFTest(o)
+	if err != nil {
+		return math.NaN(), math.NaN()
 	}
+	return result.Statistic, result.PValue
+}
 
-	return cis
+// Confidence_interval returns the confidence intervals of the coefficients at
+// the level 1 - alpha.
+//
+// Deprecated: use ConfInt.
+func (o OlsSummary) Confidence_interval(alpha float64) ([][2]float64, error) {
+	return ConfInt(o, 1-alpha)
 }
@@ -0,0 +1,315 @@
+package glasso
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// olsFormatVersion is the version of the serialized form of OLS. Decoders accept
+// any version up to their own, so fields may be added but never change meaning.
+// Version 2 added the rank and condition number of a fit by FitSVD, which a
+// decoder of version 1 would drop, reporting a fit of full rank, and version 3
+// the ids of the observations of WithRowIDs.
+const olsFormatVersion = 3
+
+// olsState is the serialized form of OLS, shared by the JSON and gob encodings.
+// mat.Dense has no exported fields, so matrices are stored as rawMatrix.
+type olsState struct {
+	Version          int        `json:"version"`
+	Coefficients     []float64  `json:"coefficients"`
+	Names            []string   `json:"names,omitempty"`
+	Weights          []float64  `json:"weights,omitempty"`
+	N                int        `json:"n"`
+	P                int        `json:"p"`
+	ResidualVariance float64    `json:"residual_variance"`
+	VCov             *rawMatrix `json:"vcov"`
+
+	// a fit on standardized predictors
+	Center       []float64 `json:"center,omitempty"`
+	Scale        []float64 `json:"scale,omitempty"`
+	Standardized []float64 `json:"standardized_coefficients,omitempty"`
+
+	// the rows of the training data fit, if some were dropped, and the ids of
+	// the observations, if WithRowIDs gave them
+	Rows []int    `json:"rows,omitempty"`
+	IDs  []string `json:"ids,omitempty"`
+
+	// a fit through the origin, whose coefficients have no intercept
+	NoIntercept bool `json:"no_intercept,omitempty"`
+
+	// the coefficients that couldn't be estimated, which are zero in
+	// Coefficients and have no rows in VCov
+	Aliased []int `json:"aliased,omitempty"`
+
+	// the name of the transform of the response fit, one of knownTransforms,
+	// and the residuals on its scale
+	ResponseTransform string    `json:"response_transform,omitempty"`
+	SmearingResiduals []float64 `json:"smearing_residuals,omitempty"`
+
+	// the condition number of the scaled design of a least squares fit, the
+	// columns of its nearest dependency and the warnings of the fit
+	Condition  float64   `json:"condition,omitempty"`
+	Implicated []string  `json:"implicated,omitempty"`
+	Warnings   []Warning `json:"warnings,omitempty"`
+
+	// the number of singular values a fit by FitSVD kept and the condition
+	// number of its design over all of them, which is infinite for a singular
+	// design and written as null in JSON
+	Rank         int       `json:"rank,omitempty"`
+	SVDCondition jsonFloat `json:"svd_condition,omitempty"`
+}
+
+// rawMatrix is a dense matrix stored in row-major order.
+type rawMatrix struct {
+	Rows int       `json:"rows"`
+	Cols int       `json:"cols"`
+	Data []float64 `json:"data"`
+}
+
+func newRawMatrix(m *mat.Dense) *rawMatrix {
+	if m == nil {
+		return nil
+	}
+	r, c := m.Dims()
+	data := make([]float64, 0, r*c)
+	for i := 0; i < r; i++ {
+		data = append(data, m.RawRowView(i)...)
+	}
+	return &rawMatrix{Rows: r, Cols: c, Data: data}
+}
+
+func (m *rawMatrix) dense() (*mat.Dense, error) {
+	if m.Rows <= 0 || m.Cols <= 0 || len(m.Data) != m.Rows*m.Cols {
+		return nil, fmt.Errorf("matrix of %d x %d has %d values", m.Rows, m.Cols, len(m.Data))
+	}
+	return mat.NewDense(m.Rows, m.Cols, m.Data), nil
+}
+
+// MarshalJSON encodes the model's coefficients, predictor names, residual
+// variance and coefficient covariance matrix. A model fit on a transformed
+// response is encoded with the name of the transform, so only one fit on
+// LogTransform can be; other transforms are an error.
+func (o *OLS) MarshalJSON() ([]byte, error) {
+	if err := o.encodable(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(o.state())
+}
+
+// encodable checks that the model's transform of the response, if any, can be
+// decoded by its name.
+func (o *OLS) encodable() error {
+	if o.response == nil {
+		return nil
+	}
+	if _, ok := knownTransforms[o.response.Name]; !ok {
+		return fmt.Errorf("encoding model: the %q response transform can't be encoded", o.response.Name)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a model encoded with MarshalJSON, checking that its
+// dimensions are consistent.
+func (o *OLS) UnmarshalJSON(b []byte) error {
+	var v olsState
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("decoding model: %v", err)
+	}
+	decoded, err := v.model()
+	if err != nil {
+		return fmt.Errorf("decoding model: %v", err)
+	}
+	*o = *decoded
+	return nil
+}
+
+// Save writes the model to w in a binary form that LoadOLS reads back. It holds
+// the same fields as the JSON encoding but is much smaller for large models.
+func (o *OLS) Save(w io.Writer) error {
+	if err := o.encodable(); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(w).Encode(o.state()); err != nil {
+		return fmt.Errorf("encoding model: %v", err)
+	}
+	return nil
+}
+
+// LoadOLS reads a model written by Save, checking that its dimensions are consistent.
+func LoadOLS(r io.Reader) (*OLS, error) {
+	var v olsState
+	if err := gob.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding model: %v", err)
+	}
+	o, err := v.model()
+	if err != nil {
+		return nil, fmt.Errorf("decoding model: %v", err)
+	}
+	return o, nil
+}
+
+func (o *OLS) state() olsState {
+	v := olsState{
+		Version:          olsFormatVersion,
+		Coefficients:     o.betas,
+		Names:            o.names,
+		Weights:          o.weights,
+		N:                o.n,
+		P:                o.p,
+		ResidualVariance: o.sigma2,
+		VCov:             newRawMatrix(o.vcov),
+		Center:           o.center,
+		Scale:            o.scale,
+		Standardized:     o.standardized,
+		Rows:             o.rows,
+		IDs:              o.ids,
+		NoIntercept:      o.noIntercept,
+		Aliased:          o.aliased,
+		Implicated:       o.implicated,
+		Warnings:         o.warnings,
+		Rank:             o.rank,
+	}
+	// the condition number of a fit by FitSVD is of the design as given
+	if o.rawCondition {
+		v.SVDCondition = jsonFloat(o.condition)
+	} else {
+		v.Condition = o.condition
+	}
+	if o.response != nil {
+		v.ResponseTransform = o.response.Name
+		v.SmearingResiduals = o.response.residuals
+	}
+	return v
+}
+
+// model validates the serialized form and builds the model from it.
+func (v *olsState) model() (*OLS, error) {
+	predictors := v.P - 1
+	if v.NoIntercept {
+		predictors = v.P
+	}
+	switch {
+	case v.Version < 1 || v.Version > olsFormatVersion:
+		return nil, fmt.Errorf("unsupported format version %d", v.Version)
+	case len(v.Coefficients) == 0:
+		return nil, fmt.Errorf("no coefficients")
+	case v.P != len(v.Coefficients):
+		return nil, fmt.Errorf("p is %d but there are %d coefficients", v.P, len(v.Coefficients))
+	case v.Names != nil && len(v.Names) != predictors:
+		return nil, fmt.Errorf("%d names for %d predictors", len(v.Names), predictors)
+	case v.N < v.P-len(v.Aliased):
+		return nil, fmt.Errorf("%d observations for %d coefficients", v.N, v.P-len(v.Aliased))
+	case v.VCov == nil:
+		return nil, fmt.Errorf("missing covariance matrix")
+	}
+	if v.Weights != nil {
+		positive := 0
+		for _, w := range v.Weights {
+			if !(w >= 0) {
+				return nil, fmt.Errorf("weight %v is negative", w)
+			}
+			if w > 0 {
+				positive++
+			}
+		}
+		if positive != v.N {
+			return nil, fmt.Errorf("%d positive weights for %d observations", positive, v.N)
+		}
+	}
+	if v.Scale != nil || v.Center != nil || v.Standardized != nil {
+		if len(v.Center) != predictors || len(v.Scale) != predictors || len(v.Standardized) != v.P {
+			return nil, fmt.Errorf("standardization of %d centers, %d scales and %d coefficients for %d predictors",
+				len(v.Center), len(v.Scale), len(v.Standardized), predictors)
+		}
+		for _, s := range v.Scale {
+			if !(s > 0) {
+				return nil, fmt.Errorf("scale %v is not positive", s)
+			}
+		}
+	}
+	if v.Rows != nil {
+		if len(v.Rows) != v.N {
+			return nil, fmt.Errorf("%d rows for %d observations", len(v.Rows), v.N)
+		}
+		for k, i := range v.Rows {
+			if i < 0 || k > 0 && i <= v.Rows[k-1] {
+				return nil, fmt.Errorf("rows are not increasing at %d", i)
+			}
+		}
+	}
+	if err := checkRowIDs(v.IDs, v.N); err != nil {
+		return nil, err
+	}
+	for k, j := range v.Aliased {
+		if j < 0 || j >= v.P || k > 0 && j <= v.Aliased[k-1] {
+			return nil, fmt.Errorf("aliased coefficients are not increasing at %d", j)
+		}
+		if v.Coefficients[j] != 0 {
+			return nil, fmt.Errorf("aliased coefficient %d is %v", j, v.Coefficients[j])
+		}
+	}
+	if v.Rank != 0 {
+		switch cond := float64(v.SVDCondition); {
+		case v.Rank < 1 || v.Rank > v.P:
+			return nil, fmt.Errorf("rank %d is not between 1 and the %d coefficients", v.Rank, v.P)
+		case cond == 0:
+			// JSON's null, for the infinite condition number of a singular design
+			v.SVDCondition = jsonFloat(math.Inf(1))
+		case !(cond > 0) && !math.IsNaN(cond):
+			return nil, fmt.Errorf("condition number %v is not positive", cond)
+		}
+	}
+	estimable := v.P - len(v.Aliased)
+	if estimable == 0 {
+		return nil, fmt.Errorf("every coefficient is aliased")
+	}
+	vcov, err := v.VCov.dense()
+	if err != nil {
+		return nil, err
+	}
+	if v.VCov.Rows != estimable || v.VCov.Cols != estimable {
+		return nil, fmt.Errorf("covariance matrix is %d x %d for %d coefficients", v.VCov.Rows, v.VCov.Cols, estimable)
+	}
+	model := &OLS{
+		betas:   v.Coefficients,
+		n:       v.N,
+		p:       v.P,
+		names:   v.Names,
+		weights: v.Weights,
+		sigma2:  v.ResidualVariance,
+		vcov:    vcov,
+
+		center:       v.Center,
+		scale:        v.Scale,
+		standardized: v.Standardized,
+		rows:         v.Rows,
+		ids:          v.IDs,
+		noIntercept:  v.NoIntercept,
+		aliased:      v.Aliased,
+
+		condition:  v.Condition,
+		implicated: v.Implicated,
+		warnings:   v.Warnings,
+
+		rank: v.Rank,
+	}
+	if v.Rank != 0 {
+		model.condition, model.rawCondition = float64(v.SVDCondition), true
+	}
+	if v.ResponseTransform != "" {
+		t, ok := knownTransforms[v.ResponseTransform]
+		if !ok {
+			return nil, fmt.Errorf("unknown response transform %q", v.ResponseTransform)
+		}
+		if len(v.SmearingResiduals) != v.N {
+			return nil, fmt.Errorf("%d smearing residuals for %d observations", len(v.SmearingResiduals), v.N)
+		}
+		model.response = &responseTransform{ResponseTransform: t, residuals: v.SmearingResiduals}
+	}
+	return model, nil
+}
@@ -0,0 +1,201 @@
+package glasso
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestOLSJSONRoundTrip(t *testing.T) {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	fitted, _, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	original := fitted.(*OLS)
+
+	b, err := json.Marshal(original)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+
+	assert.Equal(t, decoded.betas, original.betas)
+	assert.Equal(t, decoded.names, labels)
+	assert.Equal(t, decoded.n, 21)
+	assert.Equal(t, decoded.p, 4)
+	assert.Equal(t, decoded.sigma2, original.sigma2)
+	assert.T(t, mat.Equal(decoded.vcov, original.vcov))
+
+	// identical predictions on new data
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		x := []float64{50 + 30*rng.Float64(), 17 + 10*rng.Float64(), 72 + 21*rng.Float64()}
+		assert.Equal(t, decoded.Predict(x), original.Predict(x))
+	}
+}
+
+func TestOLSJSONFormat(t *testing.T) {
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	var fields map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal(b, &fields))
+	assert.Equal(t, fields["version"], float64(olsFormatVersion))
+	vcov := fields["vcov"].(map[string]interface{})
+	assert.Equal(t, vcov["rows"], 4.0)
+	assert.Equal(t, vcov["cols"], 4.0)
+	assert.Equal(t, len(vcov["data"].([]interface{})), 16)
+	_, named := fields["names"]
+	assert.T(t, !named)
+}
+
+func TestOLSJSONInvalid(t *testing.T) {
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	valid := string(b)
+
+	cases := map[string]string{
+		"truncated":      valid[:len(valid)/2],
+		"future version": strings.Replace(valid, fmt.Sprintf(`"version":%d`, olsFormatVersion), fmt.Sprintf(`"version":%d`, olsFormatVersion+1), 1),
+		"rank":           strings.Replace(valid, `"n":`, `"rank":5,"svd_condition":10,"n":`, 1),
+		"condition":      strings.Replace(valid, `"n":`, `"rank":3,"svd_condition":-1,"n":`, 1),
+		"wrong p":        strings.Replace(valid, `"p":4`, `"p":3`, 1),
+		"bad dims":       strings.Replace(valid, `"rows":4`, `"rows":5`, 1),
+		"names":          strings.Replace(valid, `"n":`, `"names":["a"],"n":`, 1),
+		"no vcov":        `{"version":1,"coefficients":[1,2],"n":5,"p":2}`,
+	}
+	for name, payload := range cases {
+		err := json.Unmarshal([]byte(payload), &OLS{})
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestOLSSaveLoad(t *testing.T) {
+	labeled, _, err := NewOlsTrainer().Train(NewDataFrame(data, []string{"Air.Flow", "Water.Temp", "Acid.Conc."}), y)
+	assert.Equal(t, nil, err)
+	weighted, _, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	// a model that has itself been through the JSON encoding carries no
+	// state from training beyond what is serialized
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+
+	rng := rand.New(rand.NewSource(1))
+	newData := make([][]float64, 50)
+	for i := range newData {
+		newData[i] = []float64{50 + 30*rng.Float64(), 17 + 10*rng.Float64(), 72 + 21*rng.Float64()}
+	}
+	x := NewDataFrame(newData)
+
+	for _, original := range []*OLS{model.(*OLS), labeled.(*OLS), weighted.(*OLS), decoded} {
+		var buf bytes.Buffer
+		assert.Equal(t, nil, original.Save(&buf))
+		loaded, err := LoadOLS(&buf)
+		assert.Equal(t, nil, err)
+		trained := *original
+		trained.fit = nil // the fit that Without needs isn't saved
+		assert.Equal(t, loaded, &trained)
+
+		before, err := original.PredictAll(x)
+		assert.Equal(t, nil, err)
+		after, err := loaded.PredictAll(x)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, after, before)
+		assert.Equal(t, StandardErrors(MatToDF(loaded.vcov)), StandardErrors(MatToDF(original.vcov)))
+	}
+}
+
+func TestOLSSaveSmallerThanJSON(t *testing.T) {
+	// gob writes the field names of the format once, so it is the smaller
+	// once the covariance matrix outweighs them
+	rng := rand.New(rand.NewSource(4))
+	rows := make([][]float64, 100)
+	response := make([]float64, len(rows))
+	for i := range rows {
+		rows[i] = make([]float64, 10)
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+		}
+		response[i] = rows[i][0] + rng.NormFloat64()
+	}
+	m, _, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	var buf bytes.Buffer
+	assert.Equal(t, nil, m.(*OLS).Save(&buf))
+	b, err := json.Marshal(m)
+	assert.Equal(t, nil, err)
+	assert.T(t, buf.Len() < len(b), buf.Len(), len(b))
+}
+
+func TestLoadOLSInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Equal(t, nil, model.(*OLS).Save(&buf))
+	valid := buf.Bytes()
+
+	_, err := LoadOLS(bytes.NewReader(valid[:len(valid)/2]))
+	assert.NotEqual(t, nil, err)
+	_, err = LoadOLS(bytes.NewReader(nil))
+	assert.NotEqual(t, nil, err)
+
+	// well formed but inconsistent
+	for _, v := range []olsState{
+		{Version: olsFormatVersion + 1, Coefficients: []float64{1}, N: 2, P: 1, VCov: &rawMatrix{1, 1, []float64{1}}},
+		{Version: 2, Coefficients: []float64{1}, N: 2, P: 1, VCov: &rawMatrix{1, 1, []float64{1}}, Rank: 2, SVDCondition: 1},
+		{Version: 1, Coefficients: []float64{1, 2}, N: 5, P: 2, VCov: &rawMatrix{2, 2, []float64{1, 0, 0}}},
+		{Version: 1, Coefficients: []float64{1, 2}, N: 1, P: 2, VCov: &rawMatrix{2, 2, []float64{1, 0, 0, 1}}},
+	} {
+		buf.Reset()
+		assert.Equal(t, nil, gob.NewEncoder(&buf).Encode(v))
+		_, err := LoadOLS(&buf)
+		assert.NotEqual(t, nil, err)
+	}
+}
+
+func TestOLSSVDRoundTrip(t *testing.T) {
+	// a truncated fit, whose third predictor duplicates the first
+	rows, response := deletionData(rand.New(rand.NewSource(5)), 40, 2)
+	for i := range rows {
+		rows[i] = append(rows[i], rows[i][0])
+	}
+	truncated, _, err := FitSVD(NewDataFrame(rows), response, 1e-10)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, truncated.Rank())
+	// and one of a design whose smallest singular value is zero exactly
+	exact := *truncated
+	exact.condition = math.Inf(1)
+
+	for _, original := range []*OLS{truncated, &exact} {
+		b, err := json.Marshal(original)
+		assert.Equal(t, nil, err)
+		decoded := &OLS{}
+		assert.Equal(t, nil, json.Unmarshal(b, decoded))
+		var buf bytes.Buffer
+		assert.Equal(t, nil, original.Save(&buf))
+		loaded, err := LoadOLS(&buf)
+		assert.Equal(t, nil, err)
+		for _, m := range []*OLS{decoded, loaded} {
+			assert.Equal(t, 3, m.Rank())
+			assert.Equal(t, original.Condition(), m.Condition())
+			assert.T(t, m.rawCondition)
+		}
+	}
+
+	// a least squares model keeps the condition number of its scaled design
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assert.Equal(t, model.(*OLS).Condition(), decoded.Condition())
+	assert.T(t, !decoded.rawCondition)
+	assert.Equal(t, 4, decoded.Rank())
+}
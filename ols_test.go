@@ -1,9 +1,13 @@
 package glasso
 
 import (
+	"encoding/json"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
 )
 
 // Stackloss Data set from R
@@ -34,6 +38,30 @@ var data = [][]float64{
 // response
 var y = []float64{42.0, 37.0, 37.0, 28.0, 18.0, 18.0, 19.0, 20.0, 15.0, 14.0, 14.0, 13.0, 11.0, 12.0, 8.0, 7.0, 8.0, 8.0, 9.0, 15.0, 15.0}
 
+// Longley's macroeconomic data set from R: a classically ill-conditioned regression
+// GNP.deflator, GNP, Unemployed, Armed.Forces, Population, Year
+var longley = [][]float64{
+	{83.0, 234.289, 235.6, 159.0, 107.608, 1947},
+	{88.5, 259.426, 232.5, 145.6, 108.632, 1948},
+	{88.2, 258.054, 368.2, 161.6, 109.773, 1949},
+	{89.5, 284.599, 335.1, 165.0, 110.929, 1950},
+	{96.2, 328.975, 209.9, 309.9, 112.075, 1951},
+	{98.1, 346.999, 193.2, 359.4, 113.270, 1952},
+	{99.0, 365.385, 187.0, 354.7, 115.094, 1953},
+	{100.0, 363.112, 357.8, 335.0, 116.219, 1954},
+	{101.2, 397.469, 290.4, 304.8, 117.388, 1955},
+	{104.6, 419.180, 282.2, 285.7, 118.734, 1956},
+	{108.4, 442.769, 293.6, 279.8, 120.445, 1957},
+	{110.8, 444.546, 468.1, 263.7, 121.950, 1958},
+	{112.6, 482.704, 381.3, 255.2, 123.366, 1959},
+	{114.2, 502.601, 393.1, 251.4, 125.368, 1960},
+	{115.7, 518.173, 480.6, 257.2, 127.852, 1961},
+	{116.9, 554.894, 400.7, 282.7, 130.081, 1962},
+}
+
+// Employed
+var longleyY = []float64{60.323, 61.122, 60.171, 61.187, 63.221, 63.639, 64.989, 63.761, 66.019, 67.857, 68.169, 66.513, 68.655, 69.564, 69.331, 70.551}
+
 func TestLeastSquares(t *testing.T) {
 	// make the data frame
 	df := NewDataFrame(data)
@@ -51,3 +79,552 @@ func TestLeastSquares(t *testing.T) {
 	t.Logf("Yhat: %v...", roundAll(summary.Yhat()[0:4]))
 	t.Logf("predict=%v", model.Predict([]float64{70.0, 20.0, 91.0}))
 }
+
+// noInterceptSummary fits the stackloss data through the origin.
+func noInterceptSummary(t *testing.T) OlsSummary {
+	x := NewDataFrame(data)
+	fit, err := leastSquares(x.X, mat.NewDense(len(y), 1, y))
+	assert.Equal(t, nil, err)
+	return OlsSummary{
+		betas:     fit.betas,
+		residuals: fit.residuals,
+		fitted:    fit.fitted,
+		response:  y,
+		n:         len(y),
+		p:         3,
+		data:      x,
+	}
+}
+
+func TestRSquared(t *testing.T) {
+	// summary(lm(stack.loss ~ ., stackloss))
+	s := summary.(OlsSummary)
+	assertClose(t, s.RSquared(), 0.9136, 1e-4)
+	assertClose(t, s.AdjustedRSquared(), 0.8983, 1e-4)
+
+	// without an intercept the total sum of squares is around zero, and the
+	// adjustment uses n rather than n - 1
+	origin := noInterceptSummary(t)
+	rss := origin.ResidualSumofSquares()
+	r2 := 1 - rss/sum(prod(y, y))
+	assertClose(t, origin.TotalSumofSquares(), sum(prod(y, y)), 1e-9)
+	assertClose(t, origin.RSquared(), r2, 1e-12)
+	assertClose(t, origin.AdjustedRSquared(), 1-(1-r2)*21/18, 1e-12)
+	assert.T(t, origin.RSquared() > s.RSquared())
+
+	// a perfect fit
+	rows := [][]float64{{1, 2}, {2, 1}, {3, 5}, {4, 3}, {5, 4}}
+	response := make([]float64, len(rows))
+	for i, r := range rows {
+		response[i] = 1 + 2*r[0] - r[1]
+	}
+	_, perfect, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	assertClose(t, perfect.(OlsSummary).RSquared(), 1, 1e-12)
+	assertClose(t, perfect.(OlsSummary).AdjustedRSquared(), 1, 1e-12)
+}
+
+func TestAccessorsCopy(t *testing.T) {
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	ols := m.(*OLS)
+	assert.Equal(t, 21, ols.NumObs())
+	assert.Equal(t, 3, ols.NumPredictors())
+	assertCloseSlices(t, ols.FittedValues(), s.Yhat(), 1e-12)
+	assertCloseSlices(t, ols.Residuals(), s.Residuals(), 1e-9)
+
+	cooks, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	prediction := ols.Predict(data[0])
+	betas := s.Coefficients()
+	for _, v := range [][]float64{ols.Coefficients(), ols.FittedValues(), ols.Residuals(), s.Coefficients(), s.Residuals(), s.Yhat()} {
+		for i := range v {
+			v[i] = 1e6
+		}
+	}
+	again, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, cooks, again)
+	assert.Equal(t, prediction, ols.Predict(data[0]))
+	assert.Equal(t, betas, s.Coefficients())
+	assertCloseSlices(t, ols.FittedValues(), s.Yhat(), 1e-12)
+
+	// a model without one observation fits the others
+	without, err := ols.Without(20)
+	assert.Equal(t, nil, err)
+	_, refit, err := NewOlsTrainer().Train(NewDataFrame(data[:20]), y[:20])
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 20, without.NumObs())
+	assertCloseSlices(t, without.Residuals(), refit.Residuals(), 1e-8)
+}
+
+func TestTrainDoesNotModify(t *testing.T) {
+	x := NewDataFrame(data, stacklossLabels)
+	for _, trainer := range []Trainer{NewOlsTrainer(), NewWlsTrainer(stacklossWeights)} {
+		first, _, err := trainer.Train(x, y)
+		assert.Equal(t, nil, err)
+		// a second fit on the same frame is of the same three predictors
+		again, _, err := trainer.Train(x, y)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 3, x.Cols())
+		assert.Equal(t, first.(*OLS).Coefficients(), again.(*OLS).Coefficients())
+		assert.Equal(t, 0, len(again.(*OLS).Aliased()))
+
+		air, err := x.Select("Air.Flow")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, x.GetCol(0), air.GetCol(0))
+		want, err := again.(*OLS).PredictAll(x)
+		assert.Equal(t, nil, err)
+		got, err := PredictFrame(again, x)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+// stacklossWeights are weights that vary by more than an order of magnitude.
+var stacklossWeights = []float64{1, 2, 0.5, 3, 1, 1, 4, 0.25, 2, 1, 1, 3, 0.5, 1, 2, 1, 1, 2, 5, 1, 0.1}
+
+func TestWlsTrainer(t *testing.T) {
+	m, s, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	// the weighted normal equations, with X'WX and X'Wy formed explicitly
+	x := NewDataFrame(data)
+	x.PushCol(rep(1, len(y)))
+	n, p := x.X.Dims()
+	wx := mat.NewDense(n, p, nil)
+	for i := 0; i < n; i++ {
+		wx.SetRow(i, multSlice(x.GetRow(i), stacklossWeights[i]))
+	}
+	xtwx, xtwxInv := &mat.Dense{}, &mat.Dense{}
+	xtwx.Mul(x.X.T(), wx)
+	assert.Equal(t, nil, xtwxInv.Inverse(xtwx))
+	xtwy := &mat.Dense{}
+	xtwy.Mul(wx.T(), mat.NewDense(n, 1, y))
+	betas := &mat.Dense{}
+	betas.Mul(xtwxInv, xtwy)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, betas.At(j, 0), 1e-10)
+	}
+
+	// weighted residuals, RSS and variance-covariance matrix
+	e := make([]float64, n)
+	rss := 0.0
+	for i := range e {
+		e[i] = y[i] - sum(prod(x.GetRow(i), s.Coefficients()))
+		rss += stacklossWeights[i] * e[i] * e[i]
+		assertClose(t, s.Residuals()[i], math.Sqrt(stacklossWeights[i])*e[i], 1e-10)
+	}
+	assertClose(t, s.SumOfSquares(), rss, 1e-10)
+	sigma2 := rss / float64(n-p)
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	expected := &mat.Dense{}
+	expected.Scale(sigma2, xtwxInv)
+	assertMatricesClose(t, vcov.X, expected, 1e-10)
+	assertMatricesClose(t, m.(*OLS).vcov, expected, 1e-10)
+
+	// leverage is the diagonal of W^1/2 X (X'WX)^-1 X' W^1/2, and Cook's distance
+	// uses the weighted residuals
+	h, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	d, err := CooksDistance(s)
+	assert.Equal(t, nil, err)
+	for i := 0; i < n; i++ {
+		xi := mat.NewVecDense(p, x.GetRow(i))
+		v := mat.NewVecDense(p, nil)
+		v.MulVec(xtwxInv, xi)
+		hii := stacklossWeights[i] * mat.Dot(xi, v)
+		assertClose(t, h[i], hii, 1e-10)
+		ri := stacklossWeights[i] * e[i] * e[i] / (float64(p) * sigma2)
+		assertClose(t, d[i], ri*hii/((1-hii)*(1-hii)), 1e-10)
+	}
+
+	// R^2 is around the weighted mean
+	ybar := sum(prod(stacklossWeights, y)) / sum(stacklossWeights)
+	tss := 0.0
+	for i := range y {
+		tss += stacklossWeights[i] * (y[i] - ybar) * (y[i] - ybar)
+	}
+	assertClose(t, s.(OlsSummary).RSquared(), 1-rss/tss, 1e-12)
+	f, err := OverallFTest(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, f.Statistic, ((tss-rss)/3)/sigma2, 1e-9)
+	assert.Equal(t, interceptOf(s), 0)
+}
+
+func coefficientSEs(t *testing.T, m Summary) []float64 {
+	vcov, err := VarCov(m)
+	assert.Equal(t, nil, err)
+	return StandardErrors(vcov)
+}
+
+func TestWlsConstantWeights(t *testing.T) {
+	// scaling every weight by the same constant changes neither the
+	// coefficients nor their standard errors
+	w := rep(7, len(y))
+	_, s, err := NewWlsTrainer(w).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, summary.Coefficients()[j], 1e-10)
+	}
+	weighted, unweighted := coefficientSEs(t, s), coefficientSEs(t, summary)
+	for j := range weighted {
+		assertClose(t, weighted[j], unweighted[j], 1e-10)
+	}
+	assertClose(t, s.(OlsSummary).RSquared(), summary.(OlsSummary).RSquared(), 1e-12)
+	vifs, err := VIF(s)
+	assert.Equal(t, nil, err)
+	expected, err := VIF(summary)
+	assert.Equal(t, nil, err)
+	for j := range vifs {
+		assertClose(t, vifs[j], expected[j], 1e-9)
+	}
+	// nor the log-likelihood, as the term of the weights offsets the larger RSS
+	assertClose(t, LogLikelihood(s), LogLikelihood(summary), 1e-10)
+	assertClose(t, AIC(s), AIC(summary), 1e-10)
+}
+
+func TestWlsInfluence(t *testing.T) {
+	_, s, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	inf, err := InfluenceMeasures(s)
+	assert.Equal(t, nil, err)
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	n, p := s.Data().Rows(), s.Data().Cols()
+	sigma2 := s.SumOfSquares() / float64(n-p)
+	betas := s.Coefficients()
+	e := s.(OlsSummary).OriginalResiduals()
+	predict := func(row []float64, b []float64) float64 {
+		return sum(prod(append([]float64{1}, row...), b))
+	}
+
+	// the measures of R's influence.measures on lm(weights = w), from the
+	// weighted fit without each observation, which a weight of zero drops
+	for i := 0; i < n; i++ {
+		w := append([]float64(nil), stacklossWeights...)
+		w[i] = 0
+		_, without, err := NewWlsTrainer(w).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		si2 := without.SumOfSquares() / float64(n-1-p)
+		h, sw := inf.Leverage[i], math.Sqrt(stacklossWeights[i])
+		b := without.Coefficients()
+
+		assertClose(t, inf.Standardized[i], sw*e[i]/math.Sqrt(sigma2*(1-h)), 1e-10)
+		assertClose(t, inf.Studentized[i], sw*e[i]/math.Sqrt(si2*(1-h)), 1e-9)
+		shift := predict(data[i], betas) - predict(data[i], b)
+		assertClose(t, inf.DFFITS[i], sw*shift/math.Sqrt(si2*h), 1e-9)
+		cooks := 0.0
+		for k := range data {
+			d := predict(data[k], betas) - predict(data[k], b)
+			cooks += stacklossWeights[k] * d * d
+		}
+		assertClose(t, inf.CooksDistance[i], cooks/(float64(p)*sigma2), 1e-9)
+		deleted, err := VarCov(without)
+		assert.Equal(t, nil, err)
+		assertClose(t, inf.COVRATIO[i], mat.Det(deleted.X)/mat.Det(vcov.X), 1e-9)
+		for j := range b {
+			scale := math.Sqrt(si2 / sigma2 * vcov.X.At(j, j))
+			assertClose(t, inf.DFBETAS[i][j], (betas[j]-b[j])/scale, 1e-9)
+		}
+	}
+
+	// the log-likelihood of R's logLik.lm, with the term of the weights
+	rss := s.SumOfSquares()
+	logw := 0.0
+	for _, w := range stacklossWeights {
+		logw += math.Log(w)
+	}
+	ll := (logw - float64(n)*(math.Log(2*math.Pi)+1-math.Log(float64(n))+math.Log(rss))) / 2
+	assertClose(t, LogLikelihood(s), ll, 1e-10)
+	assertClose(t, AIC(s), -2*ll+2*float64(p+1), 1e-9)
+	assertClose(t, BIC(s), -2*ll+math.Log(float64(n))*float64(p+1), 1e-9)
+}
+
+func TestWlsZeroWeights(t *testing.T) {
+	// a weight of zero is the same as dropping the observation
+	w := rep(1, len(y))
+	var rows [][]float64
+	var response []float64
+	for i := range w {
+		if i%4 == 1 {
+			w[i] = 0
+			continue
+		}
+		rows = append(rows, data[i])
+		response = append(response, y[i])
+	}
+	m, weighted, err := NewWlsTrainer(w).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, dropped, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, weighted.Data().Rows(), len(response))
+	assert.Equal(t, m.(*OLS).n, len(response))
+	assert.Equal(t, len(m.(*OLS).weights), len(y))
+	se, expected := coefficientSEs(t, weighted), coefficientSEs(t, dropped)
+	for j, b := range weighted.Coefficients() {
+		assertClose(t, b, dropped.Coefficients()[j], 1e-10)
+		assertClose(t, se[j], expected[j], 1e-10)
+	}
+	hw, err := LeveragePoints(weighted)
+	assert.Equal(t, nil, err)
+	hd, err := LeveragePoints(dropped)
+	assert.Equal(t, nil, err)
+	dw, err := CooksDistance(weighted)
+	assert.Equal(t, nil, err)
+	dd, err := CooksDistance(dropped)
+	assert.Equal(t, nil, err)
+	for i := range hd {
+		assertClose(t, hw[i], hd[i], 1e-10)
+		assertClose(t, dw[i], dd[i], 1e-10)
+	}
+}
+
+func TestWlsInvalidWeights(t *testing.T) {
+	w := rep(1, len(y))
+	w[3] = -1
+	_, _, err := NewWlsTrainer(w).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	w[3] = math.NaN()
+	_, _, err = NewWlsTrainer(w).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewWlsTrainer(w[:10]).Train(NewDataFrame(data), y)
+	assert.Equal(t, DimensionError, err)
+	_, _, err = NewWlsTrainer(rep(0, len(y))).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewWlsTrainer(nil).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+
+	// weighted models can't be refit from their rescaled data
+	_, s, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, _, err = FTest(s, NewWlsTrainer(stacklossWeights), []int{3})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestOlsStandardize(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		x    [][]float64
+		y    []float64
+	}{
+		{"stackloss", data, y},
+		{"longley", longley, longleyY},
+	} {
+		m, s, err := NewOlsTrainer().Train(NewDataFrame(c.x), c.y)
+		assert.Equal(t, nil, err)
+		ms, ss, err := NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(c.x), c.y)
+		assert.Equal(t, nil, err)
+		plain, standardized := m.(*OLS), ms.(*OLS)
+
+		// relative to the size of each value, since longley's coefficients
+		// range over ten orders of magnitude
+		closing := func(a, b, tol float64) {
+			if math.Abs(a-b) > tol*math.Max(1, math.Abs(b)) {
+				t.Errorf("%s: %v and %v differ by more than %v", c.name, a, b, tol)
+			}
+		}
+		for i, v := range s.Yhat() {
+			closing(ss.Yhat()[i], v, 1e-9)
+			closing(ss.Residuals()[i], s.Residuals()[i], 1e-7)
+			closing(standardized.Predict(c.x[i]), plain.Predict(c.x[i]), 1e-9)
+		}
+		for j, b := range s.Coefficients() {
+			closing(ss.Coefficients()[j], b, 1e-7)
+		}
+		se, sse := StandardErrors(MatToDF(plain.vcov)), StandardErrors(MatToDF(standardized.vcov))
+		for j := range se {
+			closing(sse[j], se[j], 1e-7)
+			for k := range se {
+				closing(standardized.vcov.At(j, k), plain.vcov.At(j, k), 1e-6)
+			}
+		}
+		// the summary's covariance matrix, from the original design, agrees
+		vcov, err := VarCov(ss)
+		assert.Equal(t, nil, err)
+		for j, v := range StandardErrors(vcov) {
+			closing(v, se[j], 1e-7)
+		}
+	}
+
+	// the standardization is saved with the model
+	m, _, err := NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	b, err := json.Marshal(m)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assert.Equal(t, decoded.Predict(data[0]), m.Predict(data[0]))
+
+	// a constant predictor is aliased with the intercept rather than standardized
+	constant := make([][]float64, len(data))
+	for i, row := range data {
+		constant[i] = []float64{row[0], 3}
+	}
+	m, _, err = NewOlsTrainer(WithStandardize(true)).Train(NewDataFrame(constant), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, m.(*OLS).Aliased(), []string{"x1"})
+	assert.T(t, math.IsNaN(m.(*OLS).Coefficients()[2]))
+	b, err = json.Marshal(m)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assertClose(t, decoded.Predict(constant[0]), m.Predict(constant[0]), 1e-12)
+}
+
+func TestWithIntercept(t *testing.T) {
+	x := NewDataFrame([][]float64{{1}, {2}, {3}, {4}, {5}})
+	response := []float64{2, 4, 5, 4, 5}
+
+	// lm(y ~ x)
+	m, s, err := NewOlsTrainer().Train(x.Copy(), response)
+	assert.Equal(t, nil, err)
+	assertClose(t, s.Coefficients()[0], 2.2, 1e-12)
+	assertClose(t, s.Coefficients()[1], 0.6, 1e-12)
+	assertClose(t, s.(OlsSummary).RSquared(), 0.6, 1e-12)
+	f, err := OverallFTest(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, f.Statistic, 4.5, 1e-10)
+	assert.Equal(t, f.DF2, 3.0)
+	assert.Equal(t, m.(*OLS).Names(), []string{"(Intercept)", "x0"})
+
+	// lm(y ~ x - 1): the sums of squares are around zero
+	m, s, err = NewOlsTrainer(WithIntercept(false)).Train(x.Copy(), response)
+	assert.Equal(t, nil, err)
+	origin := s.(OlsSummary)
+	assert.Equal(t, len(origin.Coefficients()), 1)
+	assertClose(t, origin.Coefficients()[0], 1.2, 1e-12)
+	assertClose(t, origin.RSquared(), 1-6.8/86, 1e-12)
+	assertClose(t, origin.AdjustedRSquared(), 1-(6.8/86)*5/4, 1e-12)
+	f, err = OverallFTest(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, f.Statistic, 79.2/(6.8/4), 1e-9)
+	assert.Equal(t, f.DF, 1.0)
+	assert.Equal(t, f.DF2, 4.0)
+	table, err := Anova(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(table.Rows), 2)
+	assertClose(t, table.Rows[0].SumSq, 79.2, 1e-10)
+	assert.Equal(t, table.Rows[1].DF, 4.0)
+	coefficients, err := CoefficientTable(s)
+	assert.Equal(t, nil, err)
+	assertClose(t, coefficients[0].StdError, math.Sqrt(1.7/55), 1e-12)
+	assert.Equal(t, CoefficientNames(s), []string{"x0"})
+	assert.Equal(t, m.(*OLS).Names(), []string{"x0"})
+	assertClose(t, m.Predict([]float64{6}), 7.2, 1e-12)
+	predictions, err := PredictInterval(s, NewDataFrame([][]float64{{6}}), 0.95)
+	assert.Equal(t, nil, err)
+	assertClose(t, predictions[0].Fit, 7.2, 1e-12)
+	assertClose(t, predictions[0].SE, 6*math.Sqrt(1.7/55), 1e-12)
+
+	// a column of ones given with the data isn't taken for an intercept
+	withOnes := NewDataFrame([][]float64{{1, 1}, {1, 2}, {1, 3}, {1, 4}, {1, 5}})
+	_, s, err = NewOlsTrainer(WithIntercept(false)).Train(withOnes, response)
+	assert.Equal(t, nil, err)
+	assertClose(t, s.(OlsSummary).RSquared(), 1-2.4/86, 1e-12)
+
+	// the same fit standardized, weighted, saved and penalized by nothing
+	standardized, _, err := NewOlsTrainer(WithIntercept(false), WithStandardize(true)).Train(x.Copy(), response)
+	assert.Equal(t, nil, err)
+	assertClose(t, standardized.(*OLS).Coefficients()[0], 1.2, 1e-12)
+	assertClose(t, standardized.Predict([]float64{6}), 7.2, 1e-12)
+	_, weighted, err := NewWlsTrainer(rep(2, 5), WithIntercept(false)).Train(x.Copy(), response)
+	assert.Equal(t, nil, err)
+	assertClose(t, weighted.Coefficients()[0], 1.2, 1e-12)
+	assertClose(t, weighted.(OlsSummary).RSquared(), origin.RSquared(), 1e-12)
+	b, err := json.Marshal(m)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	assert.Equal(t, decoded.Predict([]float64{6}), m.Predict([]float64{6}))
+	ridge, _, err := NewRidgeTrainer(&RidgeConfig{NoIntercept: true, Standardize: true}).Train(x, response)
+	assert.Equal(t, nil, err)
+	assertClose(t, ridge.Predict([]float64{6}), 7.2, 1e-12)
+	lasso, ls, err := NewLassoTrainer(0, WithIntercept(false)).Train(x, response)
+	assert.Equal(t, nil, err)
+	assertClose(t, lasso.Predict([]float64{6}), 7.2, 1e-6)
+	assert.Equal(t, ls.(*LassoSummary).NonZero(), []int{0})
+}
+
+func TestRankDeficient(t *testing.T) {
+	// lm(stack.loss ~ Air.Flow + Water.Temp), for the estimable coefficients
+	two := make([][]float64, len(data))
+	for i, row := range data {
+		two[i] = row[:2]
+	}
+	_, reduced, err := NewOlsTrainer().Train(NewDataFrame(two), y)
+	assert.Equal(t, nil, err)
+
+	for _, tc := range []struct {
+		name   string
+		column func(row []float64) float64
+	}{
+		{"duplicated", func(row []float64) float64 { return row[0] }},
+		{"combination", func(row []float64) float64 { return 2*row[0] - row[1] }},
+	} {
+		rows := make([][]float64, len(data))
+		for i, row := range data {
+			rows[i] = []float64{row[0], row[1], tc.column(row)}
+		}
+		labels := []string{"air", "water", "alias"}
+		m, s, err := NewOlsTrainer().Train(NewDataFrame(rows, labels), y)
+		assert.Equal(t, nil, err, tc.name)
+		model := m.(*OLS)
+		assert.Equal(t, model.Aliased(), []string{"alias"}, tc.name)
+		assert.Equal(t, s.(OlsSummary).Aliased(), []string{"alias"}, tc.name)
+
+		// coef(fit) has NA for the aliased column, and the rest are the reduced fit's
+		betas := model.Coefficients()
+		assert.Equal(t, len(betas), 4, tc.name)
+		assert.T(t, math.IsNaN(betas[3]), tc.name)
+		for j, b := range reduced.Coefficients() {
+			assertClose(t, betas[j], b, 1e-9)
+			assertClose(t, s.Coefficients()[j], b, 1e-9)
+		}
+		assert.Equal(t, CoefficientNames(s), []string{"(Intercept)", "air", "water"}, tc.name)
+
+		// the covariance matrix and diagnostics are those of the reduced basis
+		r, c := model.vcov.Dims()
+		assert.Equal(t, [2]int{r, c}, [2]int{3, 3}, tc.name)
+		want, err := VarBeta(reduced)
+		assert.Equal(t, nil, err)
+		got, err := VarBeta(s)
+		assert.Equal(t, nil, err)
+		for j, v := range want {
+			assertClose(t, got[j], v, 1e-9)
+		}
+		assertClose(t, AIC(s), AIC(reduced), 1e-9)
+		for i, row := range rows {
+			assertClose(t, m.Predict(row), reduced.(OlsSummary).fitted[i], 1e-9)
+		}
+		report, err := NewReport(s)
+		assert.Equal(t, nil, err)
+		assert.T(t, strings.Contains(report.String(), "Coefficients: (1 not defined because of singularities)"), tc.name)
+
+		// the aliasing is saved with the model
+		b, err := json.Marshal(m)
+		assert.Equal(t, nil, err)
+		decoded := &OLS{}
+		assert.Equal(t, nil, json.Unmarshal(b, decoded))
+		assert.Equal(t, decoded.Aliased(), []string{"alias"}, tc.name)
+		assertClose(t, decoded.Predict(rows[0]), m.Predict(rows[0]), 1e-12)
+
+		// and a weighted fit aliases the same column
+		w, ws, err := NewWlsTrainer(rep(2, len(y))).Train(NewDataFrame(rows, labels), y)
+		assert.Equal(t, nil, err, tc.name)
+		assert.Equal(t, w.(*OLS).Aliased(), []string{"alias"}, tc.name)
+		assertClose(t, ws.Coefficients()[1], betas[1], 1e-9)
+	}
+
+	// a column of zeros is aliased too, with or without an intercept
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{0, row[0]}
+	}
+	m, _, err := NewOlsTrainer(WithIntercept(false)).Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, m.(*OLS).Aliased(), []string{"x0"})
+	_, _, err = NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {1}, {1}}), []float64{1, 2, 3})
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,197 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// OnlineOLS is a least squares fit that is updated one observation at a time,
+// for data that arrive in a stream, without refitting. It keeps the R of the QR
+// factorization of the design, with Q'y and the residual sum of squares
+// beside it, and folds each new row into R with Givens rotations: the
+// equivalent of a rank-one update of (X'X)^-1, but without squaring the
+// condition number of the design, and well defined while the rows seen so far
+// are rank deficient.
+//
+// With WithForgetting(lambda), every past observation is down-weighted by
+// lambda at each update, so that the fit tracks data whose relationship drifts:
+// it minimizes \sum_i \lambda^{n-i} (y_i - x_i'\beta)^2.
+type OnlineOLS struct {
+	r         *mat.Dense // upper triangular R, with R'R = X'\Lambda X
+	z         []float64  // Q'y
+	norms     []float64  // the weighted sums of squares of the columns of the design
+	rss       float64
+	n         int
+	weight    float64 // \sum_i \lambda^{n-i}, the effective number of observations
+	lambda    float64
+	intercept bool
+}
+
+// NewOnlineOLS returns an empty fit with an intercept, unless
+// WithIntercept(false) is given, and the given number of predictors.
+// WithForgetting sets its forgetting factor, which is 1 by default.
+func NewOnlineOLS(predictors int, opts ...Option) (*OnlineOLS, error) {
+	o := newOptions(opts)
+	if predictors < 0 || predictors == 0 && !o.intercept {
+		return nil, fmt.Errorf("%d predictors leave nothing to fit", predictors)
+	}
+	if !(o.forgetting > 0 && o.forgetting <= 1) {
+		return nil, fmt.Errorf("forgetting factor %v is not in (0, 1]", o.forgetting)
+	}
+	p := predictors
+	if o.intercept {
+		p++
+	}
+	return &OnlineOLS{
+		r:         mat.NewDense(p, p, nil),
+		z:         make([]float64, p),
+		norms:     make([]float64, p),
+		lambda:    o.forgetting,
+		intercept: o.intercept,
+	}, nil
+}
+
+// Update adds the observation of response y at predictors x to the fit.
+func (o *OnlineOLS) Update(x []float64, y float64) error {
+	_, err := o.update(x, y)
+	return err
+}
+
+// update is Update, returning what is left of the response once the row is
+// rotated into R. Without forgetting, and with R of full rank before the
+// update, that is the recursive residual of the observation,
+//
+// (y - x'\beta) / \sqrt{1 + x'(X'X)^{-1}x}
+//
+// for the coefficients and design of the observations before it.
+func (o *OnlineOLS) update(x []float64, y float64) (float64, error) {
+	p := len(o.z)
+	row := make([]float64, 0, p)
+	if o.intercept {
+		row = append(row, 1)
+	}
+	row = append(row, x...)
+	if len(row) != p {
+		return 0, DimensionError
+	}
+	for j, v := range row {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("predictor %d is %v", j, v)
+		}
+	}
+	if math.IsNaN(y) || math.IsInf(y, 0) {
+		return 0, fmt.Errorf("response is %v", y)
+	}
+
+	if o.lambda < 1 {
+		scale := math.Sqrt(o.lambda)
+		o.r.Scale(scale, o.r)
+		for j := range o.z {
+			o.z[j] *= scale
+			o.norms[j] *= o.lambda
+		}
+		o.rss *= o.lambda
+	}
+	for j, v := range row {
+		o.norms[j] += v * v
+	}
+
+	// rotate the row into R, zeroing its entries from the left; what is left
+	// of the response is the residual of the row on the fit before it
+	for j := 0; j < p; j++ {
+		if row[j] == 0 {
+			continue
+		}
+		rjj := o.r.At(j, j)
+		h := math.Hypot(rjj, row[j])
+		c, s := rjj/h, row[j]/h
+		o.r.Set(j, j, h)
+		for k := j + 1; k < p; k++ {
+			rjk := o.r.At(j, k)
+			o.r.Set(j, k, c*rjk+s*row[k])
+			row[k] = -s*rjk + c*row[k]
+		}
+		o.z[j], y = c*o.z[j]+s*y, -s*o.z[j]+c*y
+	}
+	o.rss += y * y
+	o.n++
+	o.weight = o.lambda*o.weight + 1
+	return y, nil
+}
+
+// N returns the number of observations the fit has been updated with.
+func (o *OnlineOLS) N() int { return o.n }
+
+// RSS returns the residual sum of squares of the current fit, weighted by the
+// forgetting factor.
+func (o *OnlineOLS) RSS() float64 { return o.rss }
+
+// rank checks that the observations so far determine the coefficients, by
+// the diagonal of R as for VarCov.
+func (o *OnlineOLS) rank() error {
+	p := len(o.z)
+	if o.n < p {
+		return fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, o.n, p)
+	}
+	for j := 0; j < p; j++ {
+		if !(math.Abs(o.r.At(j, j)) > singularTolerance*math.Sqrt(o.norms[j])) {
+			return SingularDesignError
+		}
+	}
+	return nil
+}
+
+// Coefficients returns the coefficients of the current fit, the intercept
+// first if there is one. Until the observations determine them, a
+// TooFewObservationsError or SingularDesignError is returned.
+func (o *OnlineOLS) Coefficients() ([]float64, error) {
+	if err := o.rank(); err != nil {
+		return nil, err
+	}
+	p := len(o.z)
+	betas := make([]float64, p)
+	for j := p - 1; j >= 0; j-- {
+		v := o.z[j]
+		for k := j + 1; k < p; k++ {
+			v -= o.r.At(j, k) * betas[k]
+		}
+		betas[j] = v / o.r.At(j, j)
+	}
+	return betas, nil
+}
+
+// Snapshot returns the current fit as an OLS model, with the residual
+// variance RSS / (n - p) and the covariance matrix \sigma^2 (X'X)^-1, for
+// prediction and encoding. With forgetting, n is the effective number of
+// observations \sum_i \lambda^{n-i}. A fit of no more observations than
+// coefficients has a residual variance of NaN.
+func (o *OnlineOLS) Snapshot() (*OLS, error) {
+	betas, err := o.Coefficients()
+	if err != nil {
+		return nil, err
+	}
+	p := len(betas)
+	sigma2 := math.NaN()
+	if o.weight > float64(p) {
+		sigma2 = o.rss / (o.weight - float64(p))
+	}
+	rtri := mat.NewTriDense(p, mat.Upper, nil)
+	rtri.Copy(o.r)
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(rtri); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+	vcov := &mat.Dense{}
+	vcov.Mul(rinv, rinv.T())
+	vcov.Scale(sigma2, vcov)
+	return &OLS{
+		betas:       betas,
+		n:           o.n,
+		p:           p,
+		sigma2:      sigma2,
+		vcov:        vcov,
+		noIntercept: !o.intercept,
+	}, nil
+}
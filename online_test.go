@@ -0,0 +1,137 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// streamRows returns n observations of y = 1 + 2 x_1 - 3 x_2 + 0.5 x_3 + e.
+func streamRows(rng *rand.Rand, n int) ([][]float64, []float64) {
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64(), 10 + rng.NormFloat64(), rng.Float64()}
+		response[i] = 1 + 2*rows[i][0] - 3*rows[i][1] + 0.5*rows[i][2] + rng.NormFloat64()
+	}
+	return rows, response
+}
+
+// assertMatchesBatch checks an online fit against the batch fit of its rows.
+func assertMatchesBatch(t *testing.T, online *OnlineOLS, rows [][]float64, response []float64, opts ...Option) {
+	m, s, err := NewOlsTrainer(opts...).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	batch := m.(*OLS)
+
+	betas, err := online.Coefficients()
+	assert.Equal(t, nil, err)
+	for j, b := range batch.Coefficients() {
+		assertClose(t, betas[j], b, 1e-9*math.Max(1, math.Abs(b)))
+	}
+	assertClose(t, online.RSS(), s.SumOfSquares(), 1e-9*s.SumOfSquares())
+	assert.Equal(t, online.N(), len(rows))
+
+	snapshot, err := online.Snapshot()
+	assert.Equal(t, nil, err)
+	assertClose(t, snapshot.sigma2, batch.sigma2, 1e-9*batch.sigma2)
+	r, c := batch.vcov.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			assertClose(t, snapshot.vcov.At(i, j), batch.vcov.At(i, j), 1e-8*math.Sqrt(batch.vcov.At(i, i)*batch.vcov.At(j, j)))
+		}
+	}
+	assertClose(t, snapshot.Predict(rows[0]), batch.Predict(rows[0]), 1e-9*math.Max(1, math.Abs(response[0])))
+}
+
+func TestOnlineOLS(t *testing.T) {
+	rows, response := streamRows(rand.New(rand.NewSource(1)), 10000)
+	online, err := NewOnlineOLS(3)
+	assert.Equal(t, nil, err)
+	for i, row := range rows {
+		assert.Equal(t, nil, online.Update(row, response[i]))
+	}
+	assertMatchesBatch(t, online, rows, response)
+
+	// through the origin
+	online, err = NewOnlineOLS(3, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	for i, row := range rows[:500] {
+		assert.Equal(t, nil, online.Update(row, response[i]))
+	}
+	assertMatchesBatch(t, online, rows[:500], response[:500], WithIntercept(false))
+}
+
+func TestOnlineOLSRankDeficient(t *testing.T) {
+	rows, response := streamRows(rand.New(rand.NewSource(2)), 200)
+	// the first rows repeat one point, and the last predictor is zero until row 50
+	for i := 0; i < 50; i++ {
+		if i < 10 {
+			rows[i] = []float64{1, 2, 0}
+		}
+		rows[i][2] = 0
+	}
+	online, err := NewOnlineOLS(3)
+	assert.Equal(t, nil, err)
+	for i, row := range rows {
+		assert.Equal(t, nil, online.Update(row, response[i]))
+		_, err := online.Coefficients()
+		switch {
+		case i < 3:
+			assert.T(t, errors.Is(err, TooFewObservationsError), i, err)
+		case i < 50:
+			assert.T(t, errors.Is(err, SingularDesignError), i, err)
+			_, err = online.Snapshot()
+			assert.T(t, errors.Is(err, SingularDesignError), i, err)
+		default:
+			assert.Equal(t, nil, err, i)
+		}
+	}
+	assertMatchesBatch(t, online, rows, response)
+}
+
+func TestOnlineOLSForgetting(t *testing.T) {
+	// the coefficients drift, and forgetting weights the recent rows
+	rng := rand.New(rand.NewSource(3))
+	n, lambda := 400, 0.98
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	weights := make([]float64, n)
+	online, err := NewOnlineOLS(1, WithForgetting(lambda))
+	assert.Equal(t, nil, err)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64()}
+		response[i] = float64(i)/100*rows[i][0] + rng.NormFloat64()
+		weights[i] = math.Pow(lambda, float64(n-1-i))
+		assert.Equal(t, nil, online.Update(rows[i], response[i]))
+	}
+
+	// which is weighted least squares with weights \lambda^{n-i}
+	_, s, err := NewWlsTrainer(weights).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	betas, err := online.Coefficients()
+	assert.Equal(t, nil, err)
+	for j, b := range s.Coefficients() {
+		assertClose(t, betas[j], b, 1e-9)
+	}
+	assertClose(t, online.RSS(), s.SumOfSquares(), 1e-9*s.SumOfSquares())
+	assert.T(t, math.Abs(betas[1]-4) < 1, betas)
+}
+
+func TestOnlineOLSErrors(t *testing.T) {
+	for _, opts := range [][]Option{{WithForgetting(0)}, {WithForgetting(1.5)}, {WithForgetting(math.NaN())}} {
+		_, err := NewOnlineOLS(2, opts...)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := NewOnlineOLS(0, WithIntercept(false))
+	assert.NotEqual(t, nil, err)
+
+	online, err := NewOnlineOLS(2)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, DimensionError, online.Update([]float64{1}, 2))
+	assert.NotEqual(t, nil, online.Update([]float64{1, math.NaN()}, 2))
+	assert.NotEqual(t, nil, online.Update([]float64{1, 2}, math.Inf(1)))
+	assert.Equal(t, online.N(), 0)
+}
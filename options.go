@@ -0,0 +1,310 @@
+package glasso
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+)
+
+// DefaultMaxIterations bounds the number of passes an iterative fit makes
+// before giving up on convergence.
+const DefaultMaxIterations = 10000
+
+// DefaultSubsets bounds the number of random subsets of the observations that
+// a resampling fit draws.
+const DefaultSubsets = 10000
+
+// An Option configures an iterative or randomized fitting procedure, such as
+// the lasso. Every procedure takes the same options and ignores those that
+// don't apply to it. Those that most share are, with their defaults:
+//
+//	WithTolerance       DefaultTolerance
+//	WithMaxIterations   DefaultMaxIterations
+//	WithSeed            1, so that randomized fits are reproducible
+//	WithRandSource      none, drawing from the source of WithSeed
+//	WithParallelism     Parallelism(), the package's setting
+//	WithContext         context.Background()
+//
+// An iterative fit given a tolerance or number of iterations that isn't
+// positive returns an error, and one that runs out of iterations reports
+// that it didn't converge.
+type Option func(*options)
+
+type options struct {
+	tolerance    float64
+	maxIter      int
+	standardize  bool
+	subsets      int
+	seed         int64
+	shuffle      bool
+	strata       int
+	resampling   Resampling
+	adjust       bool
+	na           NAPolicy
+	intercept    bool
+	ctx          context.Context
+	forgetting   float64
+	samples      int
+	solver       Solver
+	correlation  Correlation
+	folds        int
+	covariance   CovarianceEstimator
+	updating     Updating
+	alternative  Alternative
+	source       rand.Source
+	parallelism  int
+	band         Band
+	thresholds   FlagThresholds
+	response     *ResponseTransform
+	strict       bool
+	maxCondition float64
+	variance     func(x0 []float64) float64
+	rowIDs       []string
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		tolerance:    DefaultTolerance,
+		maxIter:      DefaultMaxIterations,
+		standardize:  true,
+		subsets:      DefaultSubsets,
+		seed:         1,
+		adjust:       true,
+		intercept:    true,
+		ctx:          context.Background(),
+		forgetting:   1,
+		maxCondition: DefaultMaxCondition,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// checkIterations checks the tolerance and number of iterations of an
+// iterative fit.
+func (o options) checkIterations() error {
+	if o.maxIter < 1 || !(o.tolerance > 0) {
+		return fmt.Errorf("need a positive tolerance and number of iterations")
+	}
+	return nil
+}
+
+// rng returns the random number generator of a randomized fit.
+func (o options) rng() *rand.Rand {
+	if o.source != nil {
+		return rand.New(o.source)
+	}
+	return rand.New(rand.NewSource(o.seed))
+}
+
+// workers returns the number of workers a fit shares its work out among.
+func (o options) workers() int {
+	if o.parallelism > 0 {
+		return o.parallelism
+	}
+	return Parallelism()
+}
+
+// WithTolerance sets the convergence tolerance of the fit (DefaultTolerance by default).
+func WithTolerance(tol float64) Option {
+	return func(o *options) { o.tolerance = tol }
+}
+
+// WithMaxIterations sets the maximum number of iterations of the fit
+// (DefaultMaxIterations by default).
+func WithMaxIterations(n int) Option {
+	return func(o *options) { o.maxIter = n }
+}
+
+// WithStandardize sets whether the predictors are scaled to unit variance before
+// the penalty is applied (the default, as in glmnet), or before an ordinary
+// least squares fit (not the default). The coefficients are always returned on
+// the original scale.
+func WithStandardize(standardize bool) Option {
+	return func(o *options) { o.standardize = standardize }
+}
+
+// WithSubsets sets the number of random subsets of the observations a
+// resampling fit draws (DefaultSubsets by default).
+func WithSubsets(n int) Option {
+	return func(o *options) { o.subsets = n }
+}
+
+// WithSeed seeds the random number generator of a randomized fit. The seed is
+// fixed by default, so fits are reproducible. It replaces the source of an
+// earlier WithRandSource.
+func WithSeed(seed int64) Option {
+	return func(o *options) { o.seed, o.source = seed, nil }
+}
+
+// WithRandSource sets the source a randomized fit draws its random numbers
+// from, in place of one seeded by WithSeed, to share a stream among fits or
+// to draw from a generator other than math/rand's. The fit takes the source
+// over until it returns, so a source that isn't safe for concurrent use must
+// not be shared with fits running at the same time. A nil source restores the
+// seeded one.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) { o.source = src }
+}
+
+// WithParallelism sets the number of workers that a fit which shares its work
+// out, such as FitSparse or Bootstrap, uses for this call alone. n <= 0, the
+// default, leaves it to SetParallelism. The resampling procedures draw the
+// same random numbers whatever it is.
+func WithParallelism(n int) Option {
+	return func(o *options) { o.parallelism = n }
+}
+
+// WithShuffle sets whether the observations are shuffled before they're split
+// into folds (they aren't by default, so the folds are contiguous).
+func WithShuffle(shuffle bool) Option {
+	return func(o *options) { o.shuffle = shuffle }
+}
+
+// WithStrata splits the response into the given number of buckets of equal
+// size by rank, and spreads each bucket evenly over the folds, so that every
+// fold sees the range of a skewed response. Zero, the default, doesn't stratify.
+func WithStrata(buckets int) Option {
+	return func(o *options) { o.strata = buckets }
+}
+
+// WithResampling sets how a bootstrap draws its resamples (ResampleCases by default).
+func WithResampling(r Resampling) Option {
+	return func(o *options) { o.resampling = r }
+}
+
+// WithLeverageAdjustment sets whether the wild bootstrap weights the residuals
+// adjusted for their leverage, e_i / (1 - h_ii), rather than the residuals
+// themselves. They're adjusted by default.
+func WithLeverageAdjustment(adjust bool) Option {
+	return func(o *options) { o.adjust = adjust }
+}
+
+// WithNAPolicy sets how a least squares fit handles missing values in its
+// training data (ErrorOnNA by default).
+func WithNAPolicy(policy NAPolicy) Option {
+	return func(o *options) { o.na = policy }
+}
+
+// WithIntercept sets whether a least squares, lasso or elastic net fit adds an
+// intercept column to the design (the default) or goes through the origin, as
+// RidgeConfig.NoIntercept does for ridge regression. The intercept is never
+// penalized or standardized, and the R², F test and analysis of variance of a
+// fit through the origin are about zero rather than the mean of the response.
+func WithIntercept(intercept bool) Option {
+	return func(o *options) { o.intercept = intercept }
+}
+
+// WithContext sets a context that cancels a long-running procedure, such as
+// Bootstrap, CrossValidate or PermutationTest, which checks it between
+// resamples or folds and returns ctx.Err() once it is done.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) { o.ctx = ctx }
+}
+
+// WithForgetting sets the forgetting factor of an OnlineOLS, the weight in
+// (0, 1] that every past observation is multiplied by at each update. It is 1
+// by default, for no forgetting.
+func WithForgetting(lambda float64) Option {
+	return func(o *options) { o.forgetting = lambda }
+}
+
+// WithLeverageSamples sets the number of random projections that
+// SparseFit.Leverage estimates the leverages from, rather than computing them
+// exactly (0, the default).
+func WithLeverageSamples(k int) Option {
+	return func(o *options) { o.samples = k }
+}
+
+// WithSolver sets how a least squares fit solves for its coefficients
+// (QRSolver by default). See CholeskySolver for when the normal equations are
+// worth their loss of precision.
+func WithSolver(s Solver) Option {
+	return func(o *options) { o.solver = s }
+}
+
+// WithCorrelation sets the kind of correlation that CorrelationMatrix and
+// PartialCorrelations compute (PearsonCorrelation by default).
+func WithCorrelation(c Correlation) Option {
+	return func(o *options) { o.correlation = c }
+}
+
+// WithCrossValidation sets the number of folds that FitPCR cross-validates the
+// number of components over (0, the default, for none).
+func WithCrossValidation(folds int) Option {
+	return func(o *options) { o.folds = folds }
+}
+
+// WithCovariance sets how Mahalanobis estimates the covariance matrix of the
+// design (SampleCovariance by default).
+func WithCovariance(c CovarianceEstimator) Option {
+	return func(o *options) { o.covariance = c }
+}
+
+// WithUpdating sets how the coordinate descent of the lasso and elastic net
+// keeps track of its fit (AutomaticUpdating by default). The choice changes
+// how fast a fit is, not what it converges to.
+func WithUpdating(u Updating) Option {
+	return func(o *options) { o.updating = u }
+}
+
+// WithAlternative sets the alternative hypothesis of GoldfeldQuandt
+// (GreaterAlternative by default).
+func WithAlternative(a Alternative) Option {
+	return func(o *options) { o.alternative = a }
+}
+
+// WithBand sets the kind of simultaneous band of ConfidenceBand
+// (WorkingHotellingBand by default).
+func WithBand(b Band) Option {
+	return func(o *options) { o.band = b }
+}
+
+// WithResponseTransform fits an OLS model on the transformed response, such
+// as LogTransform, which the model keeps for OLS.PredictResponse.
+func WithResponseTransform(t ResponseTransform) Option {
+	return func(o *options) { o.response = &t }
+}
+
+// WithFlagThresholds sets the cutoffs of FlagInfluential; those left zero
+// keep their conventional values.
+func WithFlagThresholds(t FlagThresholds) Option {
+	return func(o *options) { o.thresholds = t }
+}
+
+// WithStrictColumns sets whether PredictFrame rejects new data with columns
+// that the model doesn't predict from, rather than ignoring them, which it
+// does by default.
+func WithStrictColumns(strict bool) Option {
+	return func(o *options) { o.strict = strict }
+}
+
+// WithMaxCondition sets the condition number of the scaled design above which
+// a fit of NewOlsTrainer or NewWlsTrainer warns that it is IllConditioned
+// (DefaultMaxCondition by default); see OLS.Warnings. An infinite limit turns
+// the warning off.
+func WithMaxCondition(limit float64) Option {
+	return func(o *options) { o.maxCondition = limit }
+}
+
+// WithErrorVariance sets the variance function \sigma^2(x_0) of the errors at
+// the predictors x_0, without the intercept, that the prediction intervals of
+// PredictInterval use in place of the pooled residual variance, for errors
+// whose variance changes with the predictors. FGLSFit.ErrorVariance is the
+// variance function a fit by FitFGLS estimates.
+func WithErrorVariance(variance func(x0 []float64) float64) Option {
+	return func(o *options) { o.variance = variance }
+}
+
+// WithRowIDs identifies the rows of the training data of NewOlsTrainer,
+// NewWlsTrainer or FitByGroup by ids, one for each row, all different. The
+// fit carries them through the rows it drops and the observations OLS.Without
+// and OLS.Refit delete, and RowIDs, ByRowID, InfluenceMeasures and
+// WriteDiagnostics report the observations of the fit by them, so that an
+// observation flagged in a fit of some of the rows is still known by the row
+// it came from. Save and MarshalJSON keep the ids of the model's
+// observations.
+func WithRowIDs(ids []string) Option {
+	return func(o *options) { o.rowIDs = ids }
+}
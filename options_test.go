@@ -0,0 +1,94 @@
+package glasso
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestIterationOptions(t *testing.T) {
+	x := NewDataFrame(longley)
+	sx := NewDataFrame(data)
+
+	// a tolerance or number of iterations that isn't positive is an error for
+	// every iterative fit
+	for _, opts := range [][]Option{
+		{WithTolerance(0)},
+		{WithTolerance(-1e-8)},
+		{WithMaxIterations(0)},
+		{WithTolerance(1e-8), WithMaxIterations(-5)},
+	} {
+		_, _, err := NewLassoTrainer(0.1, opts...).Train(x, longleyY)
+		assert.NotEqual(t, nil, err)
+		_, err = LassoPath(x, longleyY, nil, opts...)
+		assert.NotEqual(t, nil, err)
+		_, err = NNLS(x, longleyY, opts...)
+		assert.NotEqual(t, nil, err)
+		_, _, err = NewHuberTrainer(1.345, opts...).Train(sx, y)
+		assert.NotEqual(t, nil, err)
+		_, _, err = NewQuantileTrainer(0.5, opts...).Train(sx, y)
+		assert.NotEqual(t, nil, err)
+	}
+
+	// a single pass doesn't converge on an ill-conditioned design
+	_, s, err := NewLassoTrainer(1e-4, WithMaxIterations(1), WithTolerance(1e-12)).Train(x, longleyY)
+	assert.Equal(t, nil, err)
+	assert.T(t, !s.(*LassoSummary).Converged())
+	path, err := LassoPath(x, longleyY, nil, WithMaxIterations(1), WithTolerance(1e-12))
+	assert.Equal(t, nil, err)
+	assert.T(t, !path.Converged)
+	_, s, err = NewLassoTrainer(1e-4, WithTolerance(1e-12)).Train(x, longleyY)
+	assert.Equal(t, nil, err)
+	assert.T(t, s.(*LassoSummary).Converged())
+}
+
+func TestRandSource(t *testing.T) {
+	// a source draws what the same seed does, and the later of WithSeed and
+	// WithRandSource wins
+	p, null, err := PermutationTest(summary, 1, 99, WithSeed(7))
+	assert.Equal(t, nil, err)
+	for _, opts := range [][]Option{
+		{WithRandSource(rand.NewSource(7))},
+		{WithSeed(3), WithRandSource(rand.NewSource(7))},
+		{WithRandSource(rand.NewSource(3)), WithSeed(7)},
+		{WithSeed(7), WithRandSource(nil)},
+	} {
+		again, againNull, err := PermutationTest(summary, 1, 99, opts...)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, p, again)
+		assert.Equal(t, null, againNull)
+	}
+
+	// fits drawing from a shared source continue its stream
+	src := rand.NewSource(7)
+	first, err := Bootstrap(summary, 20, WithRandSource(src))
+	assert.Equal(t, nil, err)
+	second, err := Bootstrap(summary, 20, WithRandSource(src))
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, first.Coefficients.X.RawRowView(0), second.Coefficients.X.RawRowView(0))
+	seeded, err := Bootstrap(summary, 20, WithSeed(7))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, first.Coefficients.X.RawRowView(0), seeded.Coefficients.X.RawRowView(0))
+}
+
+func TestParallelismOption(t *testing.T) {
+	defer SetParallelism(0)
+	SetParallelism(3)
+	assert.Equal(t, 3, newOptions(nil).workers())
+	assert.Equal(t, 5, newOptions([]Option{WithParallelism(5)}).workers())
+	assert.Equal(t, 3, newOptions([]Option{WithParallelism(-1)}).workers())
+
+	x, _, response, _ := oneHotDesign(rand.New(rand.NewSource(1)), 500, 10, false)
+	fit, err := FitSparse(x, response)
+	assert.Equal(t, nil, err)
+	want, err := fit.Leverage()
+	assert.Equal(t, nil, err)
+	for _, workers := range []int{1, 2, 16} {
+		fit, err := FitSparse(x, response, WithParallelism(workers))
+		assert.Equal(t, nil, err)
+		h, err := fit.Leverage()
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, h, want, 1e-12)
+	}
+}
@@ -0,0 +1,68 @@
+package glasso
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelism is the number of workers of the concurrent diagnostics, or 0
+// for runtime.GOMAXPROCS(0).
+var parallelism int64
+
+// SetParallelism sets the number of workers that the concurrent diagnostics,
+// such as CooksDistance, share their observations out among. n <= 0 restores
+// the default, runtime.GOMAXPROCS(0) at the time of each call. It is safe to
+// call while diagnostics are running; they keep the workers they started with.
+func SetParallelism(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&parallelism, int64(n))
+}
+
+// Parallelism returns the number of workers the concurrent diagnostics use.
+func Parallelism() int {
+	if n := atomic.LoadInt64(&parallelism); n > 0 {
+		return int(n)
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelChunks calls work for the chunks [first, last] of at most chunk
+// indices that cover [start, end], from the given number of workers that take the
+// next chunk until there are none left. The workers stop early if ctx is done,
+// returning ctx.Err(), or if work returns false; parallelChunks returns once
+// they have all exited. Chunks are disjoint, so work may write to their
+// indices without locking.
+func parallelChunks(ctx context.Context, workers, start, end, chunk int, work func(first, last int) bool) error {
+	if chunks := (end - start + chunk) / chunk; workers > chunks {
+		workers = chunks
+	}
+	next := int64(start)
+	var stopped int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && atomic.LoadInt32(&stopped) == 0 {
+				first := int(atomic.AddInt64(&next, int64(chunk))) - chunk
+				if first > end {
+					return
+				}
+				last := first + chunk - 1
+				if last > end {
+					last = end
+				}
+				if !work(first, last) {
+					atomic.StoreInt32(&stopped, 1)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
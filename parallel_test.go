@@ -0,0 +1,128 @@
+package glasso
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParallelChunks(t *testing.T) {
+	defer SetParallelism(0)
+	for _, workers := range []int{1, 3, 64} {
+		SetParallelism(workers)
+		assert.Equal(t, Parallelism(), workers)
+		for _, chunk := range []int{1, 7, 1000} {
+			// every index in the range is worked on exactly once
+			counts := make([]int32, 100)
+			var oversized int32
+			err := parallelChunks(context.Background(), Parallelism(), 5, 94, chunk, func(first, last int) bool {
+				if last-first >= chunk {
+					atomic.StoreInt32(&oversized, 1)
+				}
+				for i := first; i <= last; i++ {
+					atomic.AddInt32(&counts[i], 1)
+				}
+				return true
+			})
+			assert.Equal(t, nil, err)
+			assert.Equal(t, oversized, int32(0))
+			for i, c := range counts {
+				want := int32(0)
+				if i >= 5 && i <= 94 {
+					want = 1
+				}
+				assert.Equal(t, c, want, i)
+			}
+		}
+	}
+
+	// a chunk that fails stops the workers taking more
+	SetParallelism(2)
+	var calls int32
+	parallelChunks(context.Background(), Parallelism(), 0, 999, 1, func(first, last int) bool {
+		return atomic.AddInt32(&calls, 1) > 10
+	})
+	assert.T(t, atomic.LoadInt32(&calls) < 1000)
+
+	SetParallelism(-1)
+	assert.T(t, Parallelism() > 0)
+}
+
+func TestCooksDistanceParallelism(t *testing.T) {
+	defer SetParallelism(0)
+	s := simulatedSummary(5000, 3, 1)
+	expected := sequentialCooks(s)
+
+	// the distances don't depend on the workers, even as they're changed by
+	// other goroutines while the distances are found
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			SetParallelism(g % 4)
+			cooks, err := CooksDistance(s)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for i := range cooks {
+				if math.Abs(cooks[i]-expected[i]) > 1e-10*(1+expected[i]) {
+					t.Errorf("distance %d is %v, want %v", i, cooks[i], expected[i])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// perRowCooks is CooksDistance with a goroutine for each observation, for
+// comparison with the worker pool.
+func perRowCooks(m Summary) []float64 {
+	h, _ := LeveragePoints(m)
+	mse, _ := MseAdjusted(m)
+	p := float64(m.Data().Cols())
+	residuals := m.Residuals()
+	distances := make([]float64, len(h))
+	var wg sync.WaitGroup
+	for i := range h {
+		wg.Add(1)
+		go func(j int) {
+			distances[j] = residuals[j] * residuals[j] / (p * mse) * h[j] / ((1 - h[j]) * (1 - h[j]))
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	return distances
+}
+
+var (
+	millionOnce    sync.Once
+	millionSummary Summary
+)
+
+func million() Summary {
+	millionOnce.Do(func() { millionSummary = simulatedSummary(1000000, 4, 1) })
+	return millionSummary
+}
+
+func BenchmarkCooksDistanceMillion(b *testing.B) {
+	s := million()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CooksDistance(s)
+	}
+}
+
+func BenchmarkCooksDistanceMillionPerRow(b *testing.B) {
+	s := million()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		perRowCooks(s)
+	}
+}
@@ -0,0 +1,239 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PCRModel is a principal component regression. It regresses the response on
+// the first k principal components of the centered design, z_c = X_c v_c for
+// the right singular vectors v_c of X_c = U D V', and since the components are
+// orthogonal the fit is a sum of univariate regressions,
+//
+// \hat{y} = \bar{y} + \sum_{c \le k} \gamma_c z_c, \gamma_c = z_c'y / d_c^2
+//
+// whose coefficients on the predictors are \beta = V_k \gamma.
+type PCRModel struct {
+	betas []float64 // the intercept and the coefficients of the predictors
+
+	center, scale []float64  // of the predictors, with scale nil unless they were scaled
+	loadings      *mat.Dense // p x k, the rotation from the predictors to the components
+	gamma         []float64  // the coefficients of the components
+	ybar          float64
+	explained     []float64 // the proportion of the variance of the design of every component
+	cv            []float64 // the cross-validated RMSE of 0, ..., k components, or nil
+}
+
+// FitPCR fits y on the first k principal components of the columns of x, as
+// R's pls::pcr does: the predictors are centered and, with
+// WithStandardize(true), scaled by their standard deviations, which they
+// aren't by default. Each loading vector is signed so that its entry of
+// largest magnitude is positive. The model always has an intercept, the mean
+// of the response. k must be between 1 and the smaller of n - 1 and the
+// number of predictors.
+//
+// With WithCrossValidation(folds), k is instead the largest number of
+// components considered: the folds are assigned as CrossValidate assigns them
+// (WithShuffle, WithSeed and WithStrata apply), CVRMSE holds the pooled
+// root mean squared error of prediction for each number of components from 0
+// to k, and the model is fit on the number with the least, or on one if none
+// does better than the mean of the response.
+func FitPCR(x *DataFrame, y []float64, k int, opts ...Option) (*PCRModel, error) {
+	o := newOptions(append([]Option{WithStandardize(false)}, opts...))
+	x, y, _, err := handleMissing(x, y, o.na)
+	if err != nil {
+		return nil, err
+	}
+	n, p := x.Rows(), x.Cols()
+	if k < 1 || k > p || k > n-1 {
+		return nil, fmt.Errorf("%d components is not between 1 and %d", k, int(math.Min(float64(p), float64(n-1))))
+	}
+	if o.folds == 0 {
+		return fitPCR(x, y, k, o.standardize)
+	}
+	if o.folds < 2 || o.folds > n {
+		return nil, fmt.Errorf("%d folds is not between 2 and the %d observations", o.folds, n)
+	}
+
+	predicted := make([][]float64, k+1)
+	for m := range predicted {
+		predicted[m] = make([]float64, n)
+	}
+	for f, rows := range assignFolds(y, o.folds, o) {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		held := make(map[int]bool, len(rows))
+		for _, i := range rows {
+			held[i] = true
+		}
+		var training []int
+		for i := 0; i < n; i++ {
+			if !held[i] {
+				training = append(training, i)
+			}
+		}
+		// a fold can leave too few observations for k components
+		components := k
+		if components > len(training)-1 {
+			components = len(training) - 1
+		}
+		model, err := fitPCR(subsetRows(x, training), subsetSlice(y, training), components, o.standardize)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %v", f, err)
+		}
+		for _, i := range rows {
+			for m := range predicted {
+				predicted[m][i] = model.predict(x.GetRow(i), m)
+			}
+		}
+	}
+	cv := make([]float64, k+1)
+	best := 0
+	for m := range cv {
+		cv[m] = math.Sqrt(MSE(y, predicted[m]))
+		if cv[m] < cv[best] {
+			best = m
+		}
+	}
+	if best == 0 {
+		// no components at all is a model of the mean alone
+		best = 1
+	}
+	model, err := fitPCR(x, y, best, o.standardize)
+	if err != nil {
+		return nil, err
+	}
+	model.cv = cv
+	return model, nil
+}
+
+// fitPCR fits y on the first k principal components of x.
+func fitPCR(x *DataFrame, y []float64, k int, scale bool) (*PCRModel, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	names := x.names()
+	model := &PCRModel{center: make([]float64, p), ybar: mean(y)}
+	if scale {
+		model.scale = make([]float64, p)
+	}
+	z := mat.NewDense(n, p, nil)
+	for j := 0; j < p; j++ {
+		col := x.GetCol(j)
+		model.center[j] = mean(col)
+		col = subSlice(col, model.center[j])
+		if scale {
+			model.scale[j] = sd(col)
+			if !(model.scale[j] > 0) {
+				return nil, fmt.Errorf("column %q is constant and can't be scaled", names[j])
+			}
+			col = multSlice(col, 1/model.scale[j])
+		}
+		z.SetCol(j, col)
+	}
+
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(z, mat.SVDThin); !ok {
+		return nil, fmt.Errorf("singular value decomposition of the %d x %d design failed", n, p)
+	}
+	d := svd.Values(nil)
+	total := sum(prod(d, d))
+	if !(total > 0) {
+		return nil, fmt.Errorf("every column is constant")
+	}
+	model.explained = make([]float64, len(d))
+	for c, dc := range d {
+		model.explained[c] = dc * dc / total
+	}
+	if !(d[k-1] > 0) {
+		return nil, fmt.Errorf("%w: the design has fewer than %d components", SingularDesignError, k)
+	}
+
+	v := &mat.Dense{}
+	svd.VTo(v)
+	model.loadings = mat.DenseCopyOf(v.Slice(0, p, 0, k))
+	for c := 0; c < k; c++ {
+		loading := mat.Col(nil, c, model.loadings)
+		largest := 0
+		for j, l := range loading {
+			if math.Abs(l) > math.Abs(loading[largest]) {
+				largest = j
+			}
+		}
+		if loading[largest] < 0 {
+			model.loadings.SetCol(c, multSlice(loading, -1))
+		}
+	}
+
+	scores := &mat.Dense{}
+	scores.Mul(z, model.loadings)
+	centered := subSlice(y, model.ybar)
+	model.gamma = make([]float64, k)
+	for c := range model.gamma {
+		model.gamma[c] = sum(prod(mat.Col(nil, c, scores), centered)) / (d[c] * d[c])
+	}
+
+	// \beta = V_k \gamma on the scale of the predictors
+	model.betas = make([]float64, p+1)
+	model.betas[0] = model.ybar
+	for j := 0; j < p; j++ {
+		b := sum(prod(model.loadings.RawRowView(j), model.gamma))
+		if scale {
+			b /= model.scale[j]
+		}
+		model.betas[j+1] = b
+		model.betas[0] -= model.center[j] * b
+	}
+	return model, nil
+}
+
+// predict returns the prediction for x from the given number of components.
+func (m *PCRModel) predict(x []float64, components int) float64 {
+	v := m.ybar
+	_, k := m.loadings.Dims()
+	z := make([]float64, len(x))
+	for j, xj := range x {
+		z[j] = xj - m.center[j]
+		if m.scale != nil {
+			z[j] /= m.scale[j]
+		}
+	}
+	for c := 0; c < components && c < k; c++ {
+		v += m.gamma[c] * sum(prod(z, mat.Col(nil, c, m.loadings)))
+	}
+	return v
+}
+
+// Predict returns the prediction for the predictors x, which are centered,
+// scaled and rotated on to the components as the training data was.
+func (m *PCRModel) Predict(x []float64) float64 {
+	_, k := m.loadings.Dims()
+	return m.predict(x, k)
+}
+
+// Coefficients returns the intercept and the coefficients of the predictors
+// on their original scale, \beta = V_k \gamma, as pls's coef(fit, intercept =
+// TRUE) does for an unscaled fit.
+func (m *PCRModel) Coefficients() []float64 { return m.betas }
+
+// Components returns the number of components the model was fit on.
+func (m *PCRModel) Components() int { return len(m.gamma) }
+
+// Loadings returns the p x k matrix whose columns are the loading vectors of
+// the components, the rotation from the centered and scaled predictors.
+func (m *PCRModel) Loadings() *DataFrame { return MatToDF(mat.DenseCopyOf(m.loadings)) }
+
+// ExplainedVariance returns the proportion of the variance of the centered
+// and scaled design that each component explains, d_c^2 / \sum d^2, for every
+// component rather than only the first k. pls's explvar is this as a
+// percentage.
+func (m *PCRModel) ExplainedVariance() []float64 { return m.explained }
+
+// CVRMSE returns the cross-validated root mean squared error of prediction of
+// the models with 0, 1, ..., k components under WithCrossValidation, as pls's
+// RMSEP does for its "CV" estimate, or nil without it.
+func (m *PCRModel) CVRMSE() []float64 { return m.cv }
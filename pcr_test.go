@@ -0,0 +1,128 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestFitPCRLongley(t *testing.T) {
+	// the fits of pls::pcr(Employed ~ ., data = longley) and the same with
+	// scale = TRUE by their definitions, from a Jacobi eigendecomposition of
+	// the cross products in 60-digit decimal arithmetic, with the loadings
+	// signed as FitPCR signs them; they are not pcr's output, which hasn't
+	// been run against them
+	for _, c := range []struct {
+		name         string
+		opts         []Option
+		coefficients [][]float64 // for 2 and 3 components
+		explained    []float64
+		loadings     [][]float64 // of the first three components
+	}{
+		{"centered", nil, [][]float64{
+			{47.445284769948941, 0.0025523199789150883, 0.023461487866115516, 0.0075693638265419035, 0.014456222828343255, 0.001554303752644535, 0.0010991442550044312},
+			{49.653307071128296, 0.003807320569776874, 0.040175126987944856, -0.0080408985863393862, -0.0048695767602830392, 0.0025817022273526991, 0.0016549648176424302},
+		}, []float64{0.64962475606379499, 0.29935555085632531, 0.050943319178211648, 6.9315454838959705e-05, 5.8585887761643871e-06, 1.199858052992629e-06}, [][]float64{
+			{0.08247788491158331, 0.75623527614785002, 0.62622374677729664, 0.1575086649925008, 0.054391626461360851, 0.037175206865179077},
+			{0.034370173603488541, 0.31936977205094214, -0.58026265553263767, 0.74819437786695864, 0.013486079888510723, 0.011840145198530262},
+			{-0.041846093252753395, -0.55729097370756819, 0.52050054907210985, 0.64438950679246576, -0.034257045529239319, -0.018532994538254442},
+		}},
+		{"scaled", []Option{WithStandardize(true)}, [][]float64{
+			{-258.62568087883619, 0.069080692643927744, 0.007476802131580175, 0.0028846256931021339, 0.0090260343654046758, 0.10144670964958946, 0.15289510899886652},
+			{-358.71281331824071, 0.09478789429919679, 0.012674214334030317, -0.011614913452862157, -0.0059872957658498949, 0.15386214545505242, 0.20295752663800534},
+		}, []float64{0.76722951596139832, 0.19589008320952433, 0.033904228733572454, 0.0024880431128794863, 0.00042534429384580524, 6.2784688779617652e-05}, [][]float64{
+			{0.46183489816677176, 0.4615043466790022, 0.32131669830654197, 0.2015097418732075, 0.4622793530415602, 0.46494028423306949},
+			{0.057842767667756152, 0.05321228623622664, -0.59551376271271017, 0.79819254795644767, -0.045544469801680698, 0.00061878839318065763},
+			{-0.14911989205307996, -0.27768232680779886, 0.72830568578026811, 0.56160752371920331, -0.19598459108162566, -0.12811573124862718},
+		}},
+	} {
+		for k, want := range c.coefficients {
+			m, err := FitPCR(NewDataFrame(longley), longleyY, k+2, c.opts...)
+			assert.Equal(t, nil, err, c.name)
+			assertCloseSlices(t, m.Coefficients(), want, 1e-9)
+			assertCloseSlices(t, m.ExplainedVariance(), c.explained, 1e-9)
+			assert.Equal(t, k+2, m.Components())
+			loadings := m.Loadings()
+			for j := 0; j < k+2; j++ {
+				assertCloseSlices(t, loadings.GetCol(j), c.loadings[j], 1e-9)
+			}
+
+			// the rotation gives the same predictions as the coefficients
+			for _, row := range longley {
+				b := m.Coefficients()
+				assertClose(t, m.Predict(row), b[0]+sum(prod(row, b[1:])), 1e-9)
+			}
+		}
+	}
+}
+
+func TestFitPCRAllComponents(t *testing.T) {
+	// PCR on every component is least squares
+	for _, opts := range [][]Option{nil, {WithStandardize(true)}} {
+		m, err := FitPCR(NewDataFrame(data), y, 3, opts...)
+		assert.Equal(t, nil, err)
+		ols, _, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, m.Coefficients(), ols.(*OLS).Coefficients(), 1e-9)
+		assertClose(t, sum(m.ExplainedVariance()), 1, 1e-12)
+	}
+}
+
+func TestFitPCRCrossValidation(t *testing.T) {
+	m, err := FitPCR(NewDataFrame(longley), longleyY, 6, WithCrossValidation(4), WithShuffle(true))
+	assert.Equal(t, nil, err)
+	cv := m.CVRMSE()
+	assert.Equal(t, 7, len(cv))
+	best := 1
+	for c := 1; c < len(cv); c++ {
+		if cv[c] < cv[best] {
+			best = c
+		}
+	}
+	assert.Equal(t, best, m.Components())
+
+	// no components predicts each fold by the mean of the others
+	folds := assignFolds(longleyY, 4, newOptions([]Option{WithShuffle(true)}))
+	predicted := make([]float64, len(longleyY))
+	for _, rows := range folds {
+		held := make(map[int]bool)
+		for _, i := range rows {
+			held[i] = true
+		}
+		total, count := 0.0, 0
+		for i, v := range longleyY {
+			if !held[i] {
+				total += v
+				count++
+			}
+		}
+		for _, i := range rows {
+			predicted[i] = total / float64(count)
+		}
+	}
+	assertClose(t, cv[0]*cv[0], MSE(longleyY, predicted), 1e-10)
+
+	// the fit is that of the chosen number of components
+	chosen, err := FitPCR(NewDataFrame(longley), longleyY, m.Components())
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, m.Coefficients(), chosen.Coefficients(), 1e-12)
+	assert.Equal(t, []float64(nil), chosen.CVRMSE())
+}
+
+func TestFitPCRErrors(t *testing.T) {
+	x := NewDataFrame(longley)
+	for _, k := range []int{0, 7} {
+		_, err := FitPCR(x, longleyY, k)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := FitPCR(x, longleyY[1:], 2)
+	assert.Equal(t, DimensionError, err)
+	_, err = FitPCR(x, longleyY, 2, WithCrossValidation(1))
+	assert.NotEqual(t, nil, err)
+
+	constant := NewDataFrame([][]float64{{1, 2}, {1, 3}, {1, 5}, {1, 4}})
+	_, err = FitPCR(constant, []float64{1, 2, 3, 4}, 1, WithStandardize(true))
+	assert.NotEqual(t, nil, err)
+	_, err = FitPCR(constant, []float64{1, 2, 3, 4}, 2)
+	assert.NotEqual(t, nil, err)
+}
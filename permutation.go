@@ -0,0 +1,88 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PermutationTest tests whether the coefficient at index j of a least squares
+// fit (the intercept being 0) is zero, by the permutation scheme of Freedman &
+// Lane (1983). The model without the predictor is fit, and each permutation
+// adds its residuals, permuted, to its fitted values and refits the full model.
+// The p-value is the two-sided
+//
+// p = \frac{r + 1}{B + 1}
+//
+// where r of the B permutations have a t statistic for the coefficient at
+// least as large in absolute value as the t statistic of the fit. The t
+// statistics of the permutations, the null distribution, are returned too.
+// There must be at least 99 permutations. The random numbers are seeded by
+// WithSeed, and the permutations are shared out among the workers of
+// WithParallelism as Bootstrap's resamples are.
+func PermutationTest(m Summary, j, permutations int, opts ...Option) (float64, []float64, error) {
+	o := newOptions(opts)
+	x, y := m.Data().X, m.Response()
+	n, p := x.Dims()
+	if j < 0 || j >= p {
+		return 0, nil, fmt.Errorf("coefficient %d is not one of the %d coefficients", j, p)
+	}
+	if permutations < 99 {
+		return 0, nil, fmt.Errorf("%d permutations are too few, need at least 99", permutations)
+	}
+	if n <= p {
+		return 0, nil, fmt.Errorf("%w: %d observations for a t statistic with %d coefficients", TooFewObservationsError, n, p)
+	}
+	xtx, err := xtxInverse(m)
+	if err != nil {
+		return 0, nil, err
+	}
+	scale := math.Sqrt(xtx.At(j, j))
+	tStatistic := func(betas, residuals []float64) float64 {
+		s := math.Sqrt(sum(prod(residuals, residuals)) / float64(n-p))
+		return betas[j] / (s * scale)
+	}
+	observed := tStatistic(m.Coefficients(), m.Residuals())
+
+	// the model without the predictor, which leaves nothing to fit if it
+	// is the only coefficient
+	fitted, residuals := make([]float64, n), append([]float64(nil), y...)
+	if p > 1 {
+		reduced := mat.NewDense(n, p-1, nil)
+		for i := 0; i < n; i++ {
+			row := x.RawRowView(i)
+			reduced.SetRow(i, append(append([]float64(nil), row[:j]...), row[j+1:]...))
+		}
+		fit, err := leastSquares(reduced, mat.NewDense(n, 1, append([]float64(nil), y...)))
+		if err != nil {
+			return 0, nil, err
+		}
+		fitted, residuals = fit.fitted, fit.residuals
+	}
+
+	null := make([]float64, permutations)
+	err = newResampler(o).run(permutations, func(b int, rng *rand.Rand) error {
+		response := mat.NewDense(n, 1, nil)
+		for i, k := range rng.Perm(n) {
+			response.Set(i, 0, fitted[i]+residuals[k])
+		}
+		fit, err := leastSquares(x, response)
+		if err != nil {
+			return err
+		}
+		null[b] = tStatistic(fit.betas, fit.residuals)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	extreme := 0
+	for _, t := range null {
+		if math.Abs(t) >= math.Abs(observed) {
+			extreme++
+		}
+	}
+	return float64(extreme+1) / float64(permutations+1), null, nil
+}
@@ -0,0 +1,125 @@
+package glasso
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// nullPredictor returns n observations of y = 1 + 2 x_1 + e with a second
+// predictor x_2 that has no effect.
+func nullPredictor(rng *rand.Rand, n int) ([][]float64, []float64) {
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.Float64(), rng.NormFloat64()}
+		response[i] = 1 + 2*rows[i][0] + rng.NormFloat64()
+	}
+	return rows, response
+}
+
+func TestPermutationTest(t *testing.T) {
+	// air flow, with a t statistic of 5.3, beats every permutation
+	_, stackloss, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, null, err := PermutationTest(stackloss, 1, 199)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(null) != 199 {
+		t.Fatalf("got %d permutations, want 199", len(null))
+	}
+	if p != 1.0/200 {
+		t.Errorf("p-value of air flow is %v, want 1/200", p)
+	}
+
+	rows, response := nullPredictor(rand.New(rand.NewSource(1)), 15)
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, null, err = PermutationTest(s, 2, 1999, WithSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := PermutationTest(s, 2, 1999, WithSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != p {
+		t.Errorf("p-values with the same seed differ: %v and %v", p, again)
+	}
+	extreme := 0
+	table, err := CoefficientTable(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := table[2].T
+	for _, v := range null {
+		if math.Abs(v) >= math.Abs(observed) {
+			extreme++
+		}
+	}
+	if want := float64(extreme+1) / 2000; p != want {
+		t.Errorf("p-value %v does not count its null distribution, want %v", p, want)
+	}
+	// with normal errors the permutation p-value is close to that of the t test
+	if normal := table[2].PValue; math.Abs(p-normal) > 0.05 {
+		t.Errorf("permutation p-value %v is far from the t test p-value %v", p, normal)
+	}
+}
+
+func TestPermutationTestUniform(t *testing.T) {
+	// the p-values of the null predictor over many data sets are about uniform
+	const sets = 100
+	below := map[float64]int{}
+	for seed := int64(0); seed < sets; seed++ {
+		rows, response := nullPredictor(rand.New(rand.NewSource(seed+100)), 12)
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _, err := PermutationTest(s, 2, 199, WithSeed(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, level := range []float64{0.1, 0.25, 0.5, 0.75} {
+			if p <= level {
+				below[level]++
+			}
+		}
+	}
+	// a binomial proportion of 100 has a standard error of at most 0.05
+	for _, level := range []float64{0.1, 0.25, 0.5, 0.75} {
+		if fraction := float64(below[level]) / sets; math.Abs(fraction-level) > 0.15 {
+			t.Errorf("%v of the p-values are at most %v", fraction, level)
+		}
+	}
+}
+
+func TestPermutationTestInvalid(t *testing.T) {
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := PermutationTest(s, 1, 98); err == nil {
+		t.Errorf("expected an error for 98 permutations")
+	}
+	for _, j := range []int{-1, 4} {
+		if _, _, err := PermutationTest(s, j, 99); err == nil {
+			t.Errorf("expected an error for coefficient %d", j)
+		}
+	}
+}
+
+func TestPermutationTestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := PermutationTest(summary, 1, 999, WithContext(ctx)); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
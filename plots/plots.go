@@ -0,0 +1,340 @@
+// Package plots draws the standard diagnostic plots of a least squares fit,
+// those of R's plot.lm, with gonum/plot. It is a package of its own so that
+// glasso doesn't depend on gonum/plot; the points come from glasso's
+// diagnostics. Each plot labels its three most extreme observations by their
+// row numbers, counted from one as R counts them, and is ready to be saved
+// with its Save method as PNG, SVG or PDF.
+//
+// R adds a lowess smooth through the points of the residual plots, which
+// these plots add given WithSmooth.
+package plots
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/timkaye11/glasso"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// labeled is the number of the most extreme observations that a plot labels,
+// R's id.n.
+const labeled = 3
+
+// An Option configures a plot.
+type Option func(*config)
+
+type config struct {
+	smooth bool
+}
+
+// WithSmooth adds the smooth of R's panel.smooth through the points of the
+// residuals vs fitted and scale-location plots, as plot.lm adds by default:
+// glasso.Lowess with a span of 2/3 and 3 robustness iterations.
+func WithSmooth() Option {
+	return func(c *config) { c.smooth = true }
+}
+
+func newConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// series is the data of a diagnostic plot, a point for each observation. An
+// observation whose y is NaN, such as the standardized residual of one with a
+// leverage of one, isn't plotted.
+type series struct {
+	x, y    []float64
+	extreme []int // the observations labeled, the most extreme first
+}
+
+// mostExtreme returns the indices of the (at most) labeled largest values of
+// score that aren't NaN, the largest first.
+func mostExtreme(score []float64) []int {
+	var order []int
+	for i, s := range score {
+		if !math.IsNaN(s) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return score[order[a]] > score[order[b]] })
+	if len(order) > labeled {
+		order = order[:labeled]
+	}
+	return order
+}
+
+func abs(x []float64) []float64 {
+	a := make([]float64, len(x))
+	for i, v := range x {
+		a[i] = math.Abs(v)
+	}
+	return a
+}
+
+func residualsVsFitted(m glasso.Summary) series {
+	residuals := m.Residuals()
+	return series{x: m.Yhat(), y: residuals, extreme: mostExtreme(abs(residuals))}
+}
+
+// qq returns the standardized residuals against the normal quantiles of
+// their ranks, at the plotting positions (r - a) / (n + 1 - 2a) of R's
+// ppoints, a = 3/8 for n <= 10 and 1/2 otherwise.
+func qq(m glasso.Summary) (series, error) {
+	rs, err := glasso.StandardizedResiduals(m)
+	if err != nil {
+		return series{}, err
+	}
+	var order []int
+	for i, r := range rs {
+		if !math.IsNaN(r) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rs[order[a]] < rs[order[b]] })
+	n := float64(len(order))
+	a := 0.5
+	if n <= 10 {
+		a = 3.0 / 8
+	}
+	x := make([]float64, len(rs))
+	for i := range x {
+		x[i] = math.NaN()
+	}
+	for rank, i := range order {
+		x[i] = glasso.NormalQuantile((float64(rank+1) - a) / (n + 1 - 2*a))
+	}
+	return series{x: x, y: rs, extreme: mostExtreme(abs(rs))}, nil
+}
+
+// scaleLocation returns \sqrt{|r_i|} for the standardized residuals against
+// the fitted values.
+func scaleLocation(m glasso.Summary) (series, error) {
+	x, y, err := glasso.ScaleLocation(m)
+	if err != nil {
+		return series{}, err
+	}
+	return series{x: x, y: y, extreme: mostExtreme(y)}, nil
+}
+
+// cooks returns the Cook's distance of each observation against its row
+// number, counted from one.
+func cooks(m glasso.Summary) (series, error) {
+	inf, err := glasso.InfluenceMeasures(m)
+	if err != nil {
+		return series{}, err
+	}
+	x := make([]float64, len(inf.CooksDistance))
+	for i := range x {
+		x[i] = float64(i + 1)
+	}
+	return series{x: x, y: inf.CooksDistance, extreme: mostExtreme(inf.CooksDistance)}, nil
+}
+
+// points returns the points of s that are plotted and the labels of its most
+// extreme observations.
+func (s series) points() (plotter.XYs, plotter.XYLabels) {
+	var xys plotter.XYs
+	for i := range s.x {
+		if !math.IsNaN(s.x[i]) && !math.IsNaN(s.y[i]) {
+			xys = append(xys, plotter.XY{X: s.x[i], Y: s.y[i]})
+		}
+	}
+	labels := plotter.XYLabels{}
+	for _, i := range s.extreme {
+		labels.XYs = append(labels.XYs, plotter.XY{X: s.x[i], Y: s.y[i]})
+		labels.Labels = append(labels.Labels, strconv.Itoa(i+1))
+	}
+	return xys, labels
+}
+
+// newPlot returns a plot of the points of s with the labels of its most
+// extreme observations, and with a scatter of them unless scatter is false.
+func newPlot(title, x, y string, s series, scatter bool) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = x
+	p.Y.Label.Text = y
+	xys, labels := s.points()
+	if len(xys) == 0 {
+		return nil, fmt.Errorf("no observation can be plotted")
+	}
+	if scatter {
+		points, err := plotter.NewScatter(xys)
+		if err != nil {
+			return nil, err
+		}
+		p.Add(points)
+	}
+	l, err := plotter.NewLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	// set the labels off to the right of their points
+	for i := range l.TextStyle {
+		l.TextStyle[i].XAlign = 0
+		l.TextStyle[i].YAlign = -0.5
+	}
+	l.Offset.X = vg.Points(4)
+	p.Add(l)
+	return p, nil
+}
+
+// smooth returns the lowess smooth of the points of s that are plotted, in
+// the order of x, as R's panel.smooth draws it.
+func (s series) smooth() (plotter.XYs, error) {
+	var x, y []float64
+	for i := range s.x {
+		if !math.IsNaN(s.x[i]) && !math.IsNaN(s.y[i]) {
+			x = append(x, s.x[i])
+			y = append(y, s.y[i])
+		}
+	}
+	fitted, err := glasso.Lowess(x, y, 2.0/3, 3)
+	if err != nil {
+		return nil, err
+	}
+	xys := make(plotter.XYs, len(x))
+	for i := range x {
+		xys[i] = plotter.XY{X: x[i], Y: fitted[i]}
+	}
+	sort.SliceStable(xys, func(a, b int) bool { return xys[a].X < xys[b].X })
+	return xys, nil
+}
+
+// addSmooth adds the smooth of s to p.
+func addSmooth(p *plot.Plot, s series) error {
+	xys, err := s.smooth()
+	if err != nil {
+		return err
+	}
+	line, err := plotter.NewLine(xys)
+	if err != nil {
+		return err
+	}
+	line.Color = color.RGBA{R: 255, A: 255}
+	p.Add(line)
+	return nil
+}
+
+// reference returns a dashed line of the given intercept and slope.
+func reference(intercept, slope float64) *plotter.Function {
+	f := plotter.NewFunction(func(x float64) float64 { return intercept + slope*x })
+	f.Dashes = []vg.Length{vg.Points(3), vg.Points(3)}
+	return f
+}
+
+// PlotResidualsVsFitted plots the residuals of the model against its fitted
+// values, with a dashed line at zero, as which = 1 of R's plot.lm does. The
+// observations with the largest residuals in absolute value are labeled.
+func PlotResidualsVsFitted(m glasso.Summary, opts ...Option) (*plot.Plot, error) {
+	s := residualsVsFitted(m)
+	p, err := newPlot("Residuals vs Fitted", "Fitted values", "Residuals", s, true)
+	if err != nil {
+		return nil, err
+	}
+	p.Add(reference(0, 0))
+	if newConfig(opts).smooth {
+		if err := addSmooth(p, s); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// PlotQQ plots the standardized residuals of the model against the normal
+// quantiles of their ranks, as which = 2 of R's plot.lm does, with the dashed
+// line of R's qqline through their first and third quartiles. The
+// observations with the largest standardized residuals in absolute value are
+// labeled; those with a leverage of one have none and aren't plotted.
+func PlotQQ(m glasso.Summary) (*plot.Plot, error) {
+	s, err := qq(m)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPlot("Normal Q-Q", "Theoretical Quantiles", "Standardized residuals", s, true)
+	if err != nil {
+		return nil, err
+	}
+	var rs []float64
+	for _, r := range s.y {
+		if !math.IsNaN(r) {
+			rs = append(rs, r)
+		}
+	}
+	sort.Float64s(rs)
+	q1, q3 := quartile(rs, 0.25), quartile(rs, 0.75)
+	z1, z3 := glasso.NormalQuantile(0.25), glasso.NormalQuantile(0.75)
+	slope := (q3 - q1) / (z3 - z1)
+	p.Add(reference(q1-slope*z1, slope))
+	return p, nil
+}
+
+// quartile returns the pth quantile of sorted, type 7 in R's quantile.
+func quartile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	i := int(h)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (h-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// PlotScaleLocation plots the square roots of the absolute standardized
+// residuals of the model against its fitted values, as which = 3 of R's
+// plot.lm does, to show whether the spread of the residuals changes with the
+// mean. The observations with the largest standardized residuals in absolute
+// value are labeled.
+func PlotScaleLocation(m glasso.Summary, opts ...Option) (*plot.Plot, error) {
+	s, err := scaleLocation(m)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPlot("Scale-Location", "Fitted values", "√|Standardized residuals|", s, true)
+	if err != nil {
+		return nil, err
+	}
+	if newConfig(opts).smooth {
+		if err := addSmooth(p, s); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// PlotCooks plots the Cook's distance of each observation of the model
+// against its row number as a vertical bar, as which = 4 of R's plot.lm
+// does. The observations with the largest distances are labeled; those with a
+// leverage of one have none and aren't plotted.
+func PlotCooks(m glasso.Summary) (*plot.Plot, error) {
+	s, err := cooks(m)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPlot("Cook's distance", "Obs. number", "Cook's distance", s, false)
+	if err != nil {
+		return nil, err
+	}
+	d := make(plotter.Values, len(s.y))
+	for i, v := range s.y {
+		if !math.IsNaN(v) {
+			d[i] = v
+		}
+	}
+	bars, err := plotter.NewBarChart(d, vg.Points(1))
+	if err != nil {
+		return nil, err
+	}
+	bars.XMin = 1
+	bars.LineStyle.Width = 0
+	p.Add(bars)
+	return p, nil
+}
@@ -0,0 +1,170 @@
+package plots
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/timkaye11/glasso"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+)
+
+// R's stackloss data: Air.Flow, Water.Temp and Acid.Conc.
+var stackloss = [][]float64{
+	{80, 27, 89}, {80, 27, 88}, {75, 25, 90}, {62, 24, 87}, {62, 22, 87},
+	{62, 23, 87}, {62, 24, 93}, {62, 24, 93}, {58, 23, 87}, {58, 18, 80},
+	{58, 18, 89}, {58, 17, 88}, {58, 18, 82}, {58, 19, 93}, {50, 18, 89},
+	{50, 18, 86}, {50, 19, 72}, {50, 19, 79}, {50, 20, 80}, {56, 20, 82},
+	{70, 20, 91},
+}
+
+// stack.loss
+var stackLoss = []float64{42, 37, 37, 28, 18, 18, 19, 20, 15, 14, 14, 13, 11, 12, 8, 7, 8, 8, 9, 15, 15}
+
+func fit(t *testing.T) glasso.Summary {
+	_, s, err := glasso.NewOlsTrainer().Train(glasso.NewDataFrame(stackloss), stackLoss)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func assertClose(t *testing.T, got, want []float64, tol float64) {
+	t.Helper()
+	assert.Equal(t, len(want), len(got))
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tol {
+			t.Errorf("%d: %v and %v differ by more than %v", i, got[i], want[i], tol)
+		}
+	}
+}
+
+// render draws p as PNG and SVG.
+func render(t *testing.T, p *plot.Plot) {
+	for _, format := range []string{"png", "svg"} {
+		w, err := p.WriterTo(4*vg.Inch, 4*vg.Inch, format)
+		assert.Equal(t, nil, err)
+		var buf bytes.Buffer
+		_, err = w.WriteTo(&buf)
+		assert.Equal(t, nil, err)
+		assert.T(t, buf.Len() > 0)
+	}
+}
+
+func TestResidualsVsFitted(t *testing.T) {
+	m := fit(t)
+	s := residualsVsFitted(m)
+	assertClose(t, s.x, m.Yhat(), 0)
+	assertClose(t, s.y, m.Residuals(), 0)
+	// plot(fit, which = 1) in R labels observations 21, 4 and 3
+	assert.Equal(t, []int{20, 3, 2}, s.extreme)
+
+	p, err := PlotResidualsVsFitted(m)
+	assert.Equal(t, nil, err)
+	render(t, p)
+
+	// the smooth of panel.smooth, in the order of the fitted values
+	fitted, err := glasso.Lowess(m.Yhat(), m.Residuals(), 2.0/3, 3)
+	assert.Equal(t, nil, err)
+	xys, err := s.smooth()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(fitted), len(xys))
+	for k, xy := range xys {
+		if k > 0 {
+			assert.T(t, xy.X >= xys[k-1].X)
+		}
+		found := false
+		for i, x := range m.Yhat() {
+			found = found || x == xy.X && fitted[i] == xy.Y
+		}
+		assert.T(t, found)
+	}
+	smoothed, err := PlotResidualsVsFitted(m, WithSmooth())
+	assert.Equal(t, nil, err)
+	render(t, smoothed)
+}
+
+func TestQQ(t *testing.T) {
+	m := fit(t)
+	s, err := qq(m)
+	assert.Equal(t, nil, err)
+	rs, err := glasso.StandardizedResiduals(m)
+	assert.Equal(t, nil, err)
+	assertClose(t, s.y, rs, 0)
+	// qqnorm(rstandard(fit))$x: the most negative residual, of observation
+	// 21, is at qnorm(0.5 / 21) and the largest, of observation 4, at
+	// qnorm(20.5 / 21)
+	assertClose(t, []float64{s.x[20], s.x[3]}, []float64{-1.9807523966472786, 1.9807523966472786}, 1e-9)
+	assert.Equal(t, []int{20, 3, 2}, s.extreme)
+
+	p, err := PlotQQ(m)
+	assert.Equal(t, nil, err)
+	render(t, p)
+}
+
+func TestScaleLocation(t *testing.T) {
+	m := fit(t)
+	s, err := scaleLocation(m)
+	assert.Equal(t, nil, err)
+	rs, err := glasso.StandardizedResiduals(m)
+	assert.Equal(t, nil, err)
+	for i, r := range rs {
+		assertClose(t, []float64{s.y[i] * s.y[i]}, []float64{math.Abs(r)}, 1e-14)
+	}
+	assert.Equal(t, []int{20, 3, 2}, s.extreme)
+
+	p, err := PlotScaleLocation(m)
+	assert.Equal(t, nil, err)
+	render(t, p)
+	p, err = PlotScaleLocation(m, WithSmooth())
+	assert.Equal(t, nil, err)
+	render(t, p)
+}
+
+func TestCooks(t *testing.T) {
+	m := fit(t)
+	s, err := cooks(m)
+	assert.Equal(t, nil, err)
+	d, err := glasso.CooksDistance(m)
+	assert.Equal(t, nil, err)
+	assertClose(t, s.y, d, 1e-12)
+	assert.Equal(t, 1.0, s.x[0])
+	assert.Equal(t, 21.0, s.x[20])
+	// plot(fit, which = 4) in R labels observations 21, 1 and 4
+	assert.Equal(t, []int{20, 0, 3}, s.extreme)
+
+	p, err := PlotCooks(m)
+	assert.Equal(t, nil, err)
+	render(t, p)
+}
+
+func TestUnitLeverage(t *testing.T) {
+	// the last observation is the only one with a nonzero indicator, so it has
+	// a leverage of one and no standardized residual or Cook's distance
+	rows := [][]float64{{0, 1.2}, {0, 2.3}, {0, 2.9}, {0, 4.1}, {0, 5.2}, {0, 5.8}, {1, 7.1}}
+	_, m, err := glasso.NewOlsTrainer().Train(glasso.NewDataFrame(rows), []float64{1.1, 2.0, 3.2, 3.9, 5.1, 6.2, 9.0})
+	assert.Equal(t, nil, err)
+	s, err := qq(m)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.IsNaN(s.x[6]))
+	for _, i := range s.extreme {
+		assert.NotEqual(t, 6, i)
+	}
+	xys, _ := s.points()
+	assert.Equal(t, 6, len(xys))
+	// the smooth leaves out the points that aren't plotted
+	s, err = scaleLocation(m)
+	assert.Equal(t, nil, err)
+	smooth, err := s.smooth()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 6, len(smooth))
+	_, err = PlotScaleLocation(m, WithSmooth())
+	assert.Equal(t, nil, err)
+
+	s, err = cooks(m)
+	assert.Equal(t, nil, err)
+	assert.T(t, math.IsNaN(s.y[6]))
+	p, err := PlotCooks(m)
+	assert.Equal(t, nil, err)
+	render(t, p)
+}
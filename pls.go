@@ -0,0 +1,181 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PLSModel is a partial least squares regression of a single response (PLS1).
+// Where principal component regression takes the directions of greatest
+// variance of the design, PLS takes those of greatest covariance with the
+// response, so that a few components can fit well even when there are many
+// more predictors than observations.
+type PLSModel struct {
+	betas []float64 // the intercept and the coefficients of the predictors
+
+	center, scale []float64 // of the predictors, with scale nil unless they were scaled
+	scores        *mat.Dense
+	loadings      *mat.Dense
+	weights       *mat.Dense
+	yloadings     []float64
+	explained     []float64 // the proportion of the variance of the design of each component
+}
+
+// FitPLS fits y on ncomp partial least squares components of the columns of x
+// by NIPALS, as R's pls::plsr(method = "oscorespls") does; its other methods
+// give the same coefficients. The predictors are centered and, with
+// WithStandardize(true), scaled by their standard deviations, which they
+// aren't by default. From E_0 = X_c and the centered response f, each
+// component a takes the weights w_a = E_{a-1}'f / ||E_{a-1}'f||, the scores
+// t_a = E_{a-1} w_a and the loadings p_a = E_{a-1}'t_a / t_a't_a, and deflates
+// E_a = E_{a-1} - t_a p_a'. The coefficients on the predictors are
+//
+// \beta = W (P'W)^-1 q, q_a = f't_a / t_a't_a
+//
+// The model always has an intercept, the mean of the response. ncomp must be
+// between 1 and the smaller of n - 1 and the number of predictors.
+func FitPLS(x *DataFrame, y []float64, ncomp int, opts ...Option) (*PLSModel, error) {
+	o := newOptions(append([]Option{WithStandardize(false)}, opts...))
+	x, y, _, err := handleMissing(x, y, o.na)
+	if err != nil {
+		return nil, err
+	}
+	n, p := x.Rows(), x.Cols()
+	if ncomp < 1 || ncomp > p || ncomp > n-1 {
+		return nil, fmt.Errorf("%d components is not between 1 and %d", ncomp, int(math.Min(float64(p), float64(n-1))))
+	}
+
+	names := x.names()
+	model := &PLSModel{center: make([]float64, p)}
+	if o.standardize {
+		model.scale = make([]float64, p)
+	}
+	e := mat.NewDense(n, p, nil)
+	for j := 0; j < p; j++ {
+		col := x.GetCol(j)
+		model.center[j] = mean(col)
+		col = subSlice(col, model.center[j])
+		if o.standardize {
+			model.scale[j] = sd(col)
+			if !(model.scale[j] > 0) {
+				return nil, fmt.Errorf("column %q is constant and can't be scaled", names[j])
+			}
+			col = multSlice(col, 1/model.scale[j])
+		}
+		e.SetCol(j, col)
+	}
+	total := 0.0
+	for i := 0; i < n; i++ {
+		row := e.RawRowView(i)
+		total += sum(prod(row, row))
+	}
+	if !(total > 0) {
+		return nil, fmt.Errorf("every column is constant")
+	}
+	ybar := mean(y)
+	f := subSlice(y, ybar)
+
+	model.scores = mat.NewDense(n, ncomp, nil)
+	model.loadings = mat.NewDense(p, ncomp, nil)
+	model.weights = mat.NewDense(p, ncomp, nil)
+	model.yloadings = make([]float64, ncomp)
+	model.explained = make([]float64, ncomp)
+	fv := mat.NewVecDense(n, f)
+	w, t, load := mat.NewVecDense(p, nil), mat.NewVecDense(n, nil), mat.NewVecDense(p, nil)
+	first := 0.0
+	for a := 0; a < ncomp; a++ {
+		w.MulVec(e.T(), fv)
+		norm := mat.Norm(w, 2)
+		if a == 0 {
+			first = norm
+		}
+		// the covariance left is rounding once the response is fit exactly
+		if !(norm > singularTolerance*first) {
+			return nil, fmt.Errorf("%w: the response is fit exactly by %d components", SingularDesignError, a)
+		}
+		w.ScaleVec(1/norm, w)
+		t.MulVec(e, w)
+		tt := mat.Dot(t, t)
+		load.MulVec(e.T(), t)
+		load.ScaleVec(1/tt, load)
+		q := mat.Dot(fv, t) / tt
+
+		// deflate E and f by the component
+		for i := 0; i < n; i++ {
+			row, ti := e.RawRowView(i), t.At(i, 0)
+			for j := range row {
+				row[j] -= ti * load.At(j, 0)
+			}
+			fv.SetVec(i, fv.At(i, 0)-q*ti)
+		}
+		model.weights.SetCol(a, mat.Col(nil, 0, w))
+		model.scores.SetCol(a, mat.Col(nil, 0, t))
+		model.loadings.SetCol(a, mat.Col(nil, 0, load))
+		model.yloadings[a] = q
+		model.explained[a] = tt * mat.Dot(load, load) / total
+	}
+
+	// P'W is upper triangular, since p_a'w_b = t_a'E_{a-1}w_b / t_a't_a and
+	// the deflation leaves E_{a-1}w_b = 0 for b < a
+	pw := &mat.Dense{}
+	pw.Mul(model.loadings.T(), model.weights)
+	c := make([]float64, ncomp)
+	for a := ncomp - 1; a >= 0; a-- {
+		v := model.yloadings[a]
+		for b := a + 1; b < ncomp; b++ {
+			v -= pw.At(a, b) * c[b]
+		}
+		c[a] = v / pw.At(a, a)
+	}
+
+	model.betas = make([]float64, p+1)
+	model.betas[0] = ybar
+	for j := 0; j < p; j++ {
+		b := sum(prod(model.weights.RawRowView(j), c))
+		if o.standardize {
+			b /= model.scale[j]
+		}
+		model.betas[j+1] = b
+		model.betas[0] -= model.center[j] * b
+	}
+	return model, nil
+}
+
+// Predict returns the prediction for the predictors x from the coefficients on
+// their original scale, which carry the centering and scaling of the training
+// data.
+func (m *PLSModel) Predict(x []float64) float64 {
+	v := m.betas[0]
+	for j, xj := range x {
+		v += m.betas[j+1] * xj
+	}
+	return v
+}
+
+// Coefficients returns the intercept and the coefficients of the predictors
+// on their original scale.
+func (m *PLSModel) Coefficients() []float64 { return m.betas }
+
+// Components returns the number of components of the model.
+func (m *PLSModel) Components() int { return len(m.yloadings) }
+
+// Scores returns the n x ncomp matrix T of the X-scores of the training data,
+// whose columns are orthogonal.
+func (m *PLSModel) Scores() *DataFrame { return MatToDF(mat.DenseCopyOf(m.scores)) }
+
+// Loadings returns the p x ncomp matrix P of the X-loadings.
+func (m *PLSModel) Loadings() *DataFrame { return MatToDF(mat.DenseCopyOf(m.loadings)) }
+
+// Weights returns the p x ncomp matrix W of the loading weights, whose columns
+// are orthonormal.
+func (m *PLSModel) Weights() *DataFrame { return MatToDF(mat.DenseCopyOf(m.weights)) }
+
+// YLoadings returns the loading q_a of the response on each component.
+func (m *PLSModel) YLoadings() []float64 { return m.yloadings }
+
+// ExplainedVariance returns the proportion of the variance of the centered
+// and scaled design that each component explains, ||t_a||^2 ||p_a||^2 / ||X_c||^2.
+// pls's explvar is this as a percentage.
+func (m *PLSModel) ExplainedVariance() []float64 { return m.explained }
@@ -0,0 +1,110 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// a response for the six observations of wide
+var wideY = []float64{1.2, -0.7, 3.1, 0.4, -2.2, 1.9}
+
+func TestFitPLS(t *testing.T) {
+	// the fits of pls::plsr(y ~ x, ncomp = 3, method = "oscorespls") and the
+	// same with scale = TRUE by their definitions, from NIPALS with
+	// orthogonal scores in 60-digit decimal arithmetic; they are not plsr's
+	// output, which hasn't been run against them, and the gasoline data of
+	// the pls package isn't shipped here, so wide stands in for it
+	x := MatToDF(mat.DenseCopyOf(wide))
+	for _, c := range []struct {
+		name         string
+		opts         []Option
+		coefficients []float64
+		scores       [][]float64
+		explained    []float64
+	}{
+		{"centered", nil,
+			[]float64{1.2012230467466032, 0.077051586882972714, -0.25503102900964836, 0.038379081664635772, -0.15741649295667171, -0.33567165901406881, 0.28437215243521924, 0.12920922804644344, -0.13342463215853764},
+			[][]float64{
+				{-4.6245833544189212, -2.5743772389705257, 7.0442688878967354, 7.7028131472045374, -6.3872584731849962, -1.1608629685268295},
+				{2.7311149842981042, -8.0552838946032974, 5.8445565362173886, -6.4232974559121097, -1.349348920449386, 7.2522587504492995},
+				{6.7558998649752562, -0.23667703360719325, -0.29166941627926191, 0.54451660641321575, -3.9478009049744478, -2.8242691165275691},
+			},
+			[]float64{0.4629679352085222, 0.21859678955638928, 0.15258392436943027},
+		},
+		{"scaled", []Option{WithStandardize(true)},
+			[]float64{1.0390230995999228, 0.42140690172483158, -0.61925007746648952, 0.040653808692277887, -0.036464244292910314, -0.26889890811886458, 0.11303255919066046, 0.13452967287104228, -0.073872853356416343},
+			[][]float64{
+				{-0.92351011110731607, -1.1080147199018708, 1.319379907393718, 0.5785442454130163, -1.6285433463626386, 1.7621440245650914},
+				{1.5923623593557836, -1.4250947530816589, 1.1600694929366457, -1.5735410210822309, 0.3428565197334667, -0.096652597862006251},
+				{0.89151388556275191, 0.22114595766614234, 0.44860591386108672, 0.8326014221796062, -1.242554962429129, -1.1513122168404581},
+			},
+			[]float64{0.29849882315531728, 0.3014610215287209, 0.13963023347458961},
+		},
+	} {
+		m, err := FitPLS(x, wideY, 3, c.opts...)
+		assert.Equal(t, nil, err, c.name)
+		assertCloseSlices(t, m.Coefficients(), c.coefficients, 1e-10)
+		scores := m.Scores()
+		for a, want := range c.scores {
+			assertCloseSlices(t, scores.GetCol(a), want, 1e-10)
+		}
+		assertCloseSlices(t, m.ExplainedVariance(), c.explained, 1e-10)
+
+		// the scores are orthogonal and the weights orthonormal
+		weights := m.Weights()
+		for a := 0; a < 3; a++ {
+			for b := 0; b < 3; b++ {
+				if a != b {
+					assertClose(t, sum(prod(scores.GetCol(a), scores.GetCol(b))), 0, 1e-10)
+				}
+				want := 0.0
+				if a == b {
+					want = 1
+				}
+				assertClose(t, sum(prod(weights.GetCol(a), weights.GetCol(b))), want, 1e-12)
+			}
+		}
+
+		// the fitted values are the mean and the scores times their y-loadings
+		ybar := mean(wideY)
+		for i := 0; i < x.Rows(); i++ {
+			fit := ybar
+			for a, q := range m.YLoadings() {
+				fit += q * scores.X.At(i, a)
+			}
+			assertClose(t, m.Predict(x.GetRow(i)), fit, 1e-10)
+		}
+	}
+}
+
+func TestFitPLSAllComponents(t *testing.T) {
+	// PLS on as many components as predictors is least squares
+	for _, opts := range [][]Option{nil, {WithStandardize(true)}} {
+		m, err := FitPLS(NewDataFrame(longley), longleyY, 6, opts...)
+		assert.Equal(t, nil, err)
+		ols, _, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, m.Coefficients(), ols.(*OLS).Coefficients(), 1e-6)
+		assert.Equal(t, 6, m.Components())
+		assert.Equal(t, 6, m.Loadings().Cols())
+	}
+}
+
+func TestFitPLSErrors(t *testing.T) {
+	x := MatToDF(mat.DenseCopyOf(wide))
+	for _, k := range []int{0, 6} {
+		_, err := FitPLS(x, wideY, k)
+		assert.NotEqual(t, nil, err)
+	}
+	_, err := FitPLS(x, wideY[1:], 2)
+	assert.Equal(t, DimensionError, err)
+
+	constant := NewDataFrame([][]float64{{1, 2}, {1, 3}, {1, 5}, {1, 4}})
+	_, err = FitPLS(constant, []float64{1, 2, 3, 4}, 1, WithStandardize(true))
+	assert.NotEqual(t, nil, err)
+	// one component fits a response on a single direction exactly
+	_, err = FitPLS(constant, []float64{2, 3, 5, 4}, 2)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,289 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PolynomialBasis is a basis of polynomials in one predictor, of degrees 1 up
+// to Degree: the raw powers x, x^2, ..., or, if Orthogonal, the polynomials
+// of R's poly, which are orthonormal over the data the basis was built on and
+// orthogonal to the constant. Raw powers of a predictor far from zero are
+// nearly collinear, and orthogonal polynomials are not.
+type PolynomialBasis struct {
+	Degree     int
+	Orthogonal bool
+
+	// the three-term recurrence of the orthogonal polynomials, as R's poly
+	// stores in its coefs attribute
+	alpha, norm2 []float64
+}
+
+// NewPolynomialBasis builds the basis of the given degree for the predictor
+// x. The orthogonal polynomials come from the QR factorization of the
+// Vandermonde matrix of the centered x, as in R's poly, and are stored as the
+// recurrence that generates them so that Expand evaluates the same basis at
+// new values. The predictor needs more distinct values than the degree.
+func NewPolynomialBasis(x []float64, degree int, orthogonal bool) (*PolynomialBasis, error) {
+	if degree < 1 {
+		return nil, fmt.Errorf("degree %d is less than one", degree)
+	}
+	for i, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("value %d of the predictor is %v", i, v)
+		}
+	}
+	b := &PolynomialBasis{Degree: degree, Orthogonal: orthogonal}
+	n := len(x)
+	vandermonde := mat.NewDense(n, degree+1, nil)
+	centered := subtractMean(x)
+	for i, v := range centered {
+		power := 1.0
+		for k := 0; k <= degree; k++ {
+			vandermonde.Set(i, k, power)
+			power *= v
+		}
+	}
+	if n <= degree || rankDeficient(vandermonde) {
+		return nil, fmt.Errorf("degree %d needs more distinct values of the predictor than that", degree)
+	}
+	if !orthogonal {
+		return b, nil
+	}
+
+	// the polynomials are the columns of Q scaled by the diagonal of R
+	qr := factorize(vandermonde)
+	q, r := &mat.Dense{}, &mat.Dense{}
+	qr.QTo(q)
+	qr.RTo(r)
+	xbar := mean(x)
+	b.alpha = make([]float64, degree)
+	b.norm2 = make([]float64, degree+2)
+	b.norm2[0] = 1
+	for k := 0; k <= degree; k++ {
+		rkk := r.At(k, k)
+		b.norm2[k+1] = rkk * rkk
+		if k == degree {
+			break
+		}
+		weighted := 0.0
+		for i, v := range centered {
+			z := q.At(i, k) * rkk
+			weighted += v * z * z
+		}
+		b.alpha[k] = weighted/b.norm2[k+1] + xbar
+	}
+	return b, nil
+}
+
+// Expand returns the columns of the basis evaluated at x, of degree 1 up to
+// Degree.
+func (b *PolynomialBasis) Expand(x []float64) [][]float64 {
+	columns := make([][]float64, b.Degree)
+	for k := range columns {
+		columns[k] = make([]float64, len(x))
+	}
+	for i, v := range x {
+		if !b.Orthogonal {
+			power := v
+			for k := range columns {
+				columns[k][i] = power
+				power *= v
+			}
+			continue
+		}
+		// z_{k+1} = (x - \alpha_k) z_k - (norm2_{k+1} / norm2_k) z_{k-1}, with z_0 = 1
+		previous, current := 1.0, v-b.alpha[0]
+		columns[0][i] = current / math.Sqrt(b.norm2[2])
+		for k := 1; k < b.Degree; k++ {
+			previous, current = current, (v-b.alpha[k])*current-b.norm2[k+1]/b.norm2[k]*previous
+			columns[k][i] = current / math.Sqrt(b.norm2[k+2])
+		}
+	}
+	return columns
+}
+
+// Names returns the names of the columns of the basis for a predictor with
+// the given name: "x1", "x1^2", ... for raw powers and "poly(x1,1)",
+// "poly(x1,2)", ... for orthogonal polynomials.
+func (b *PolynomialBasis) Names(name string) []string {
+	names := make([]string, b.Degree)
+	for k := range names {
+		switch {
+		case b.Orthogonal:
+			names[k] = fmt.Sprintf("poly(%s,%d)", name, k+1)
+		case k == 0:
+			names[k] = name
+		default:
+			names[k] = fmt.Sprintf("%s^%d", name, k+1)
+		}
+	}
+	return names
+}
+
+// PolynomialFeatures expands columns of a design into polynomials of them,
+// with a basis for each column built from the data it was created with and
+// kept for transforming new data.
+type PolynomialFeatures struct {
+	columns []int
+	bases   []*PolynomialBasis
+	cols    int      // columns of the data it transforms
+	labels  []string // their labels, if the data had them
+}
+
+// NewPolynomialFeatures builds a PolynomialBasis of the given degree for each
+// of the columns of x (every column if none are given). Transform replaces
+// each of them with its basis columns, of degree 1 up to the degree.
+func NewPolynomialFeatures(x *DataFrame, degree int, orthogonal bool, columns ...int) (*PolynomialFeatures, error) {
+	if len(columns) == 0 {
+		for j := 0; j < x.Cols(); j++ {
+			columns = append(columns, j)
+		}
+	}
+	p := &PolynomialFeatures{cols: x.Cols()}
+	if labels := x.Labels(); len(labels) == x.Cols() {
+		p.labels = labels
+	}
+	seen := make(map[int]bool, len(columns))
+	for _, j := range columns {
+		if j < 0 || j >= x.Cols() {
+			return nil, fmt.Errorf("column %d is not one of the %d columns", j, x.Cols())
+		}
+		if seen[j] {
+			return nil, fmt.Errorf("column %d is expanded twice", j)
+		}
+		seen[j] = true
+		b, err := NewPolynomialBasis(x.GetCol(j), degree, orthogonal)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", j, err)
+		}
+		p.columns = append(p.columns, j)
+		p.bases = append(p.bases, b)
+	}
+	return p, nil
+}
+
+// Transform returns the columns of x, in order, with each expanded column
+// replaced by its basis evaluated at x. The columns are labeled, by the names
+// of PolynomialBasis, if x has labels.
+func (p *PolynomialFeatures) Transform(x *DataFrame) (*DataFrame, error) {
+	if x.Cols() != p.cols {
+		return nil, DimensionError
+	}
+	expanded := make(map[int][][]float64, len(p.columns))
+	for k, j := range p.columns {
+		expanded[j] = p.bases[k].Expand(x.GetCol(j))
+	}
+	labels := x.Labels()
+	var names []string
+	rows := make([][]float64, x.Rows())
+	for j := 0; j < p.cols; j++ {
+		columns, ok := expanded[j]
+		if !ok {
+			columns = [][]float64{x.GetCol(j)}
+		}
+		for _, column := range columns {
+			for i, v := range column {
+				rows[i] = append(rows[i], v)
+			}
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		if ok {
+			names = append(names, p.bases[p.index(j)].Names(labels[j])...)
+		} else {
+			names = append(names, labels[j])
+		}
+	}
+	if len(labels) == 0 {
+		return NewDataFrame(rows), nil
+	}
+	return NewDataFrame(rows, names), nil
+}
+
+// TransformRow expands one row of predictors, as Transform does.
+func (p *PolynomialFeatures) TransformRow(row []float64) ([]float64, error) {
+	if len(row) != p.cols {
+		return nil, DimensionError
+	}
+	var expanded []float64
+	for j, v := range row {
+		k := p.index(j)
+		if k < 0 {
+			expanded = append(expanded, v)
+			continue
+		}
+		for _, column := range p.bases[k].Expand([]float64{v}) {
+			expanded = append(expanded, column[0])
+		}
+	}
+	return expanded, nil
+}
+
+// Model returns a model that expands each row of predictors, as TransformRow
+// does, before m predicts from it, for a model trained on the transformed
+// data. Rows of the wrong length predict NaN. If the data it was built from
+// was labeled, PredictFrame matches the columns of new data to its labels.
+func (p *PolynomialFeatures) Model(m Model) Model {
+	return &polynomialModel{features: p, model: m}
+}
+
+func (p *PolynomialFeatures) index(j int) int {
+	for k, c := range p.columns {
+		if c == j {
+			return k
+		}
+	}
+	return -1
+}
+
+type polynomialModel struct {
+	features *PolynomialFeatures
+	model    Model
+}
+
+func (m *polynomialModel) Predict(x []float64) float64 {
+	row, err := m.features.TransformRow(x)
+	if err != nil {
+		return math.NaN()
+	}
+	return m.model.Predict(row)
+}
+
+func (m *polynomialModel) frameColumns() ([]string, error) {
+	return wrappedColumns(m.features, m.model)
+}
+
+func (m *polynomialModel) predictFrame(x *DataFrame) ([]float64, error) {
+	return predictWrapped(m.features, m.model, x)
+}
+
+func (p *PolynomialFeatures) inputs() ([]string, error) {
+	if p.labels == nil {
+		return nil, fmt.Errorf("%w: the polynomials were built from data without names for its columns", LabelError)
+	}
+	return p.labels, nil
+}
+
+func (p *PolynomialFeatures) outputs() []string {
+	var names []string
+	for j, label := range p.labels {
+		if k := p.index(j); k >= 0 {
+			names = append(names, p.bases[k].Names(label)...)
+		} else {
+			names = append(names, label)
+		}
+	}
+	return names
+}
+
+func (p *PolynomialFeatures) transformFrame(x *DataFrame) (*DataFrame, error) {
+	in, err := x.Select(p.labels...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Transform(in)
+}
@@ -0,0 +1,154 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPolynomialBasis(t *testing.T) {
+	// poly(1:5, 2)
+	b, err := NewPolynomialBasis([]float64{1, 2, 3, 4, 5}, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float64{
+		{-0.6324555, -0.3162278, 0, 0.3162278, 0.6324555},
+		{0.5345225, -0.2672612, -0.5345225, -0.2672612, 0.5345225},
+	}
+	for k, column := range b.Expand([]float64{1, 2, 3, 4, 5}) {
+		for i, v := range column {
+			if math.Abs(v-want[k][i]) > 1e-7 {
+				t.Errorf("degree %d, value %d: got %v, want %v", k+1, i, v, want[k][i])
+			}
+		}
+	}
+	if names := b.Names("x1"); !reflect.DeepEqual(names, []string{"poly(x1,1)", "poly(x1,2)"}) {
+		t.Errorf("got names %v", names)
+	}
+
+	raw, err := NewPolynomialBasis([]float64{1, 2, 3}, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns := raw.Expand([]float64{2, -3}); !reflect.DeepEqual(columns, [][]float64{{2, -3}, {4, 9}}) {
+		t.Errorf("got raw powers %v", columns)
+	}
+	if names := raw.Names("x1"); !reflect.DeepEqual(names, []string{"x1", "x1^2"}) {
+		t.Errorf("got names %v", names)
+	}
+
+	if _, err := NewPolynomialBasis([]float64{1, 2, 2, 1}, 2, true); err == nil {
+		t.Errorf("expected an error for a quadratic in two distinct values")
+	}
+	if _, err := NewPolynomialBasis([]float64{1, 2, 3}, 0, true); err == nil {
+		t.Errorf("expected an error for degree zero")
+	}
+}
+
+func TestPolynomialFeatures(t *testing.T) {
+	// a cubic in a predictor far from zero, whose raw powers are nearly collinear
+	rng := rand.New(rand.NewSource(6))
+	const n = 40
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		x := 10 + 2*rng.Float64()
+		rows[i] = []float64{x, rng.NormFloat64()}
+		u := x - 11
+		response[i] = 1 + u - 2*u*u + 3*u*u*u + rows[i][1] + 0.1*rng.NormFloat64()
+	}
+	df := NewDataFrame(rows, []string{"x1", "x2"})
+
+	var fitted [][]float64
+	var models []Model
+	for _, orthogonal := range []bool{false, true} {
+		features, err := NewPolynomialFeatures(df, 3, orthogonal, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expanded, err := features.Transform(df)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expanded.Cols() != 4 {
+			t.Fatalf("expanded design has %d columns, want 4", expanded.Cols())
+		}
+		names := []string{"x1", "x1^2", "x1^3", "x2"}
+		if orthogonal {
+			names = []string{"poly(x1,1)", "poly(x1,2)", "poly(x1,3)", "x2"}
+		}
+		if !reflect.DeepEqual(expanded.Labels(), names) {
+			t.Errorf("got labels %v, want %v", expanded.Labels(), names)
+		}
+		if orthogonal {
+			// the polynomials are orthonormal and orthogonal to the intercept
+			basis := mat.DenseCopyOf(expanded.X.Slice(0, n, 0, 3))
+			xtx := &mat.Dense{}
+			xtx.Mul(basis.T(), basis)
+			for j := 0; j < 3; j++ {
+				if m := mean(mat.Col(nil, j, basis)); math.Abs(m) > 1e-12 {
+					t.Errorf("polynomial %d has mean %v", j+1, m)
+				}
+				for k := 0; k < 3; k++ {
+					want := 0.0
+					if j == k {
+						want = 1
+					}
+					if math.Abs(xtx.At(j, k)-want) > 1e-12 {
+						t.Errorf("X'X(%d, %d) of the orthogonal basis is %v, want %v", j, k, xtx.At(j, k), want)
+					}
+				}
+			}
+		}
+		model, s, err := NewOlsTrainer().Train(expanded, response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fitted = append(fitted, s.Yhat())
+		models = append(models, features.Model(model))
+	}
+	for i := range fitted[0] {
+		if math.Abs(fitted[0][i]-fitted[1][i]) > 1e-8 {
+			t.Errorf("observation %d: raw fit %v, orthogonal fit %v", i, fitted[0][i], fitted[1][i])
+		}
+	}
+
+	// new data is expanded in the basis of the training data
+	for _, row := range [][]float64{{9.5, 0}, {11, 1}, {12.5, -2}} {
+		raw, orthogonal := models[0].Predict(row), models[1].Predict(row)
+		if math.Abs(raw-orthogonal) > 1e-7 {
+			t.Errorf("row %v: raw prediction %v, orthogonal %v", row, raw, orthogonal)
+		}
+	}
+	if v := models[1].Predict([]float64{1}); !math.IsNaN(v) {
+		t.Errorf("prediction from a short row is %v, want NaN", v)
+	}
+}
+
+func TestPolynomialFeaturesInvalid(t *testing.T) {
+	df := NewDataFrame([][]float64{{1, 2}, {2, 3}, {3, 5}, {4, 7}})
+	if _, err := NewPolynomialFeatures(df, 2, true, 2); err == nil {
+		t.Errorf("expected an error for a column out of range")
+	}
+	if _, err := NewPolynomialFeatures(df, 2, true, 0, 0); err == nil {
+		t.Errorf("expected an error for a column expanded twice")
+	}
+	features, err := NewPolynomialFeatures(df, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := features.Transform(df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded.Cols() != 4 || expanded.Labels() != nil {
+		t.Errorf("got %d columns labeled %v, want 4 unlabeled", expanded.Cols(), expanded.Labels())
+	}
+	if _, err := features.Transform(NewDataFrame([][]float64{{1}})); err != DimensionError {
+		t.Errorf("got error %v for the wrong number of columns, want DimensionError", err)
+	}
+}
@@ -0,0 +1,265 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Prediction holds the prediction of a fitted model for one new observation.
+type Prediction struct {
+	Fit float64
+	SE  float64 // standard error of the fitted mean, s \sqrt{x_0'(X'X)^{-1} x_0}
+
+	// Confidence bounds the mean response at x_0, and Prediction bounds a
+	// single new observation at x_0, which also carries the error variance.
+	Confidence [2]float64
+	Prediction [2]float64
+}
+
+// PredictAll returns the predictions of the model for each row of x, which
+// must have one column per predictor the model was trained on, in the same
+// order. PredictFrame matches the columns by name instead.
+func (o *OLS) PredictAll(x *DataFrame) ([]float64, error) {
+	if x.Cols() != o.predictors() {
+		return nil, fmt.Errorf("new data has %d columns but the model has %d predictors", x.Cols(), o.predictors())
+	}
+	predictions := make([]float64, x.Rows())
+	for i := range predictions {
+		predictions[i] = o.Predict(x.GetRow(i))
+	}
+	return predictions, nil
+}
+
+// SEFit returns the standard error of the model's fitted mean at the
+// predictors x0, as R's predict.lm(se.fit = TRUE):
+//
+// s \sqrt{x_0'(X'X)^{-1} x_0} = s ||z||,  R'z = x_0
+//
+// with X'X = R'R for the triangular factor R the model keeps of its fit, which
+// is solved for z by forward substitution rather than inverted. A model that
+// doesn't keep its fit, as Without describes, uses its covariance matrix. The
+// aliased coefficients, which Predict leaves out, don't contribute. x0 must
+// have one value per predictor, without the intercept, or it is a
+// DimensionError.
+func (o *OLS) SEFit(x0 []float64) (float64, error) {
+	if len(x0) != o.predictors() {
+		return 0, fmt.Errorf("%w: %d values for %d predictors", DimensionError, len(x0), o.predictors())
+	}
+	row := x0
+	if !o.noIntercept {
+		row = append([]float64{1}, x0...)
+	}
+	estimable := o.estimable()
+	x := make([]float64, len(estimable))
+	for k, j := range estimable {
+		x[k] = row[j]
+	}
+	if o.fit == nil {
+		v := mat.NewVecDense(len(x), x)
+		return math.Sqrt(mat.Inner(v, o.vcov, v)), nil
+	}
+	r, err := o.fit.factor()
+	if err != nil {
+		return 0, err
+	}
+	z := transposeSolve(r, x)
+	return math.Sqrt(o.sigma2 * sum(prod(z, z))), nil
+}
+
+// SEFitAll returns the standard error of the fitted mean at each row of x, as
+// SEFit does, for the bands of a confidence ribbon. x must have one column per
+// predictor.
+func (o *OLS) SEFitAll(x *DataFrame) ([]float64, error) {
+	if x.Cols() != o.predictors() {
+		return nil, fmt.Errorf("%w: new data has %d columns but the model has %d predictors", DimensionError, x.Cols(), o.predictors())
+	}
+	se := make([]float64, x.Rows())
+	for i := range se {
+		var err error
+		if se[i], err = o.SEFit(x.GetRow(i)); err != nil {
+			return nil, err
+		}
+	}
+	return se, nil
+}
+
+// Band is the kind of simultaneous confidence band of ConfidenceBand.
+type Band int
+
+const (
+	// WorkingHotellingBand covers the mean response at every point at once,
+	// the default.
+	WorkingHotellingBand Band = iota
+	// ScheffeBand covers the mean response at every combination of the
+	// points given.
+	ScheffeBand
+)
+
+func (b Band) String() string {
+	switch b {
+	case WorkingHotellingBand:
+		return "Working-Hotelling"
+	case ScheffeBand:
+		return "Scheffe"
+	}
+	return fmt.Sprintf("Band(%d)", int(b))
+}
+
+// ConfidenceBand returns the bounds of a simultaneous confidence band for the
+// mean response of the model at the rows of newX, one column per predictor,
+// at the given level:
+//
+// \hat{y}_0 \pm \sqrt{q F_{level; q, n - p}} se_{fit}(x_0)
+//
+// with se_fit from SEFit and p the number of estimable coefficients. By
+// default it is the band of Working and Hotelling (1929), q = p, which
+// covers the regression surface everywhere with probability level, where
+// the pointwise intervals of PredictInterval only cover each point with
+// that probability. WithBand(ScheffeBand) takes q to be the rank of the
+// points given, with the intercept, instead: the band of Scheffé's method for
+// the linear combinations of their means, which is narrower when they span
+// fewer than p dimensions, as the points of a line through a surface do, and
+// the same otherwise.
+func ConfidenceBand(o *OLS, newX *mat.Dense, level float64, opts ...Option) (lower, upper []float64, err error) {
+	g, c := newX.Dims()
+	if c != o.predictors() {
+		return nil, nil, fmt.Errorf("%w: new data has %d columns but the model has %d predictors", DimensionError, c, o.predictors())
+	}
+	if !(level > 0 && level < 1) {
+		return nil, nil, fmt.Errorf("confidence level %v is not between 0 and 1", level)
+	}
+	estimable := o.estimable()
+	p := len(estimable)
+	if o.n <= p {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, o.n, p)
+	}
+
+	q := p
+	if newOptions(opts).band == ScheffeBand {
+		points := mat.NewDense(g, p, nil)
+		for i := 0; i < g; i++ {
+			row := newX.RawRowView(i)
+			if !o.noIntercept {
+				row = append([]float64{1}, row...)
+			}
+			for k, j := range estimable {
+				points.Set(i, k, row[j])
+			}
+		}
+		q = numericalRank(points)
+	}
+	w := 0.0
+	if q > 0 {
+		w = math.Sqrt(float64(q) * fQuantile(level, float64(q), float64(o.n-p)))
+	}
+
+	lower, upper = make([]float64, g), make([]float64, g)
+	for i := range lower {
+		x0 := newX.RawRowView(i)
+		se, err := o.SEFit(x0)
+		if err != nil {
+			return nil, nil, err
+		}
+		fit := o.Predict(x0)
+		lower[i], upper[i] = fit-w*se, fit+w*se
+	}
+	return lower, upper, nil
+}
+
+// numericalRank returns the number of singular values of x above the
+// rounding of its largest.
+func numericalRank(x *mat.Dense) int {
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(x, mat.SVDNone); !ok {
+		return 0
+	}
+	s := svd.Values(nil)
+	if len(s) == 0 {
+		return 0
+	}
+	r, c := x.Dims()
+	tol := s[0] * math.Max(float64(r), float64(c)) * 2.220446049250313e-16
+	rank := 0
+	for _, v := range s {
+		if v > tol {
+			rank++
+		}
+	}
+	return rank
+}
+
+// PredictInterval returns the prediction of the model for each row of x along with
+// its standard error and the confidence and prediction intervals at the given
+// level, as R's predict.lm does:
+//
+// \hat{y}_0 \pm t_{(1 + level) / 2, n - p} s \sqrt{x_0'(X'X)^{-1} x_0}
+// \hat{y}_0 \pm t_{(1 + level) / 2, n - p} s \sqrt{1 + x_0'(X'X)^{-1} x_0}
+//
+// x must have one column per predictor, without the intercept.
+//
+// The prediction interval assumes errors of constant variance, so that for
+// errors whose variance grows with the predictors it is too wide where the
+// variance is small and too narrow where it is large. With
+// WithErrorVariance(f) it is instead
+//
+// \hat{y}_0 \pm t_{(1 + level) / 2, n - p} \sqrt{f(x_0) + se(\hat{y}_0)^2}
+//
+// with the variance f(x_0) of a new observation at x_0, such as the one
+// FGLSFit.ErrorVariance estimates, of the summary of the fit by FitFGLS.
+func PredictInterval(m Summary, x *DataFrame, level float64, opts ...Option) ([]Prediction, error) {
+	o := newOptions(opts)
+	betas := m.Coefficients()
+	intercept := interceptOf(m)
+	predictors := len(betas)
+	if intercept >= 0 {
+		predictors--
+	}
+	if x.Cols() != predictors {
+		return nil, fmt.Errorf("new data has %d columns but the model has %d predictors", x.Cols(), predictors)
+	}
+	if !(level > 0 && level < 1) {
+		return nil, fmt.Errorf("confidence level %v is not between 0 and 1", level)
+	}
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+
+	n, p := m.Data().Rows(), m.Data().Cols()
+	s2 := m.SumOfSquares() / float64(n-p)
+	t := studentTQuantile((1+level)/2, float64(n-p))
+
+	// x_0'(X'X)^-1 x_0 = ||R^-T x_0||^2
+	x0 := make([]float64, p)
+	z := mat.NewVecDense(p, nil)
+	predictions := make([]Prediction, x.Rows())
+	for i := range predictions {
+		row := x.GetRow(i)
+		variance := s2
+		if o.variance != nil {
+			if variance = o.variance(row); !(variance >= 0) {
+				return nil, fmt.Errorf("the error variance at row %d is %v", i, variance)
+			}
+		}
+		if intercept >= 0 {
+			// the intercept goes where it is in the design
+			row = append(row[:intercept:intercept], append([]float64{1}, row[intercept:]...)...)
+		}
+		copy(x0, row)
+		z.MulVec(rinv.T(), mat.NewVecDense(p, x0))
+		q := mat.Dot(z, z)
+
+		fit := sum(prod(x0, betas))
+		se := math.Sqrt(s2 * q)
+		pe := math.Sqrt(variance + s2*q)
+		predictions[i] = Prediction{
+			Fit:        fit,
+			SE:         se,
+			Confidence: [2]float64{fit - t*se, fit + t*se},
+			Prediction: [2]float64{fit - t*pe, fit + t*pe},
+		}
+	}
+	return predictions, nil
+}
@@ -0,0 +1,202 @@
+package glasso
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A frameModel predicts from the named columns of a DataFrame rather than
+// from rows of values in the order it was trained on.
+type frameModel interface {
+	Model
+
+	// frameColumns returns the names of the columns, numeric or categorical, that
+	// it predicts from, or an error if it doesn't know them.
+	frameColumns() ([]string, error)
+
+	// predictFrame predicts from x, which has the columns of frameColumns, the
+	// numeric ones in their order, and possibly others.
+	predictFrame(x *DataFrame) ([]float64, error)
+}
+
+// PredictFrame returns the predictions of the model for each row of x, with
+// the columns of x matched to those the model was trained on by name rather
+// than by position, so that they may come in any order. The columns the
+// model needs and x lacks are an error that names them. The columns of x the
+// model doesn't use are ignored, or an error naming them with
+// WithStrictColumns(true).
+//
+// The models that know the names of their columns are those of
+// NewOlsTrainer and NewWlsTrainer (and of the other trainers returning an
+// *OLS) fit on labeled data, which add the intercept and standardize the
+// predictors themselves, FormulaModel, and those of the Model methods of
+// PolynomialFeatures, InteractionFeatures and CategoricalEncoder, which
+// expand or encode the columns of x as they were for the training data before
+// the model they wrap predicts from them. Those wrappers may be nested in any
+// order, so a model trained on the encoding of a categorical column with the
+// polynomials of another predicts from a frame with both in their original
+// form.
+func PredictFrame(m Model, x *DataFrame, opts ...Option) ([]float64, error) {
+	o := newOptions(opts)
+	fm, ok := m.(frameModel)
+	if !ok {
+		return nil, fmt.Errorf("the model doesn't know the names of its columns, so it can only predict from rows in the order it was trained on")
+	}
+	return predictAligned(fm, x, o.strict)
+}
+
+// predictAligned predicts from the columns of x that m needs, checking that
+// there aren't any others if strict is set.
+func predictAligned(m frameModel, x *DataFrame, strict bool) ([]float64, error) {
+	want, err := m.frameColumns()
+	if err != nil {
+		return nil, err
+	}
+	if len(x.Labels()) != x.Cols() {
+		return nil, fmt.Errorf("%w: the new data has no names to match the columns of the model with", LabelError)
+	}
+	have := make(map[string]bool, x.Cols()+len(x.CategoricalNames()))
+	for _, name := range append(x.Labels(), x.CategoricalNames()...) {
+		have[name] = true
+	}
+	needed := make(map[string]bool, len(want))
+	var missing []string
+	for _, name := range want {
+		needed[name] = true
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the new data lacks the columns %q of the model", missing)
+	}
+	if strict {
+		var extra []string
+		for name := range have {
+			if !needed[name] {
+				extra = append(extra, name)
+			}
+		}
+		if len(extra) > 0 {
+			sort.Strings(extra)
+			return nil, fmt.Errorf("the new data has the columns %q, which the model doesn't use", extra)
+		}
+	}
+	aligned, err := x.Select(want...)
+	if err != nil {
+		return nil, err
+	}
+	return m.predictFrame(aligned)
+}
+
+// A frameTransform is the transformation of columns of the data, such as
+// PolynomialFeatures, that a wrapping model applies before the model it wraps
+// predicts.
+type frameTransform interface {
+	// inputs returns the names of the columns it transforms.
+	inputs() ([]string, error)
+
+	// outputs returns the names of the columns it produces.
+	outputs() []string
+
+	// transformFrame returns the columns of outputs computed from the columns
+	// of inputs of x.
+	transformFrame(x *DataFrame) (*DataFrame, error)
+}
+
+// wrappedColumns returns the columns that the model m wrapped by the
+// transform t predicts from: the inputs of t, and those of m that t doesn't
+// produce.
+func wrappedColumns(t frameTransform, m Model) ([]string, error) {
+	in, err := t.inputs()
+	if err != nil {
+		return nil, err
+	}
+	fm, ok := m.(frameModel)
+	if !ok {
+		return in, nil
+	}
+	inner, err := fm.frameColumns()
+	if err != nil {
+		return nil, err
+	}
+	produced := make(map[string]bool)
+	for _, name := range append(t.outputs(), in...) {
+		produced[name] = true
+	}
+	columns := append([]string(nil), in...)
+	for _, name := range inner {
+		if !produced[name] {
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
+}
+
+// predictWrapped predicts with the model m wrapped by the transform t from x,
+// which has the columns of wrappedColumns. A model m that doesn't know its
+// columns predicts from the outputs of t, in order.
+func predictWrapped(t frameTransform, m Model, x *DataFrame) ([]float64, error) {
+	transformed, err := t.transformFrame(x)
+	if err != nil {
+		return nil, err
+	}
+	fm, ok := m.(frameModel)
+	if !ok {
+		predictions := make([]float64, transformed.Rows())
+		for i := range predictions {
+			predictions[i] = m.Predict(transformed.GetRow(i))
+		}
+		return predictions, nil
+	}
+	in, err := t.inputs()
+	if err != nil {
+		return nil, err
+	}
+	return predictAligned(fm, withColumns(transformed, x, in), false)
+}
+
+// withColumns returns t with the columns of x appended but for those named
+// by consumed and those t has already, numeric and categorical.
+func withColumns(t, x *DataFrame, consumed []string) *DataFrame {
+	skip := make(map[string]bool)
+	for _, name := range append(t.names(), consumed...) {
+		skip[name] = true
+	}
+	rows := make([][]float64, t.Rows())
+	for i := range rows {
+		rows[i] = t.GetRow(i)
+	}
+	labels := t.names()
+	for j, name := range x.names() {
+		if skip[name] {
+			continue
+		}
+		for i, v := range x.GetCol(j) {
+			rows[i] = append(rows[i], v)
+		}
+		labels = append(labels, name)
+	}
+	d := NewDataFrame(rows, labels)
+	d.categorical = make(map[string][]string)
+	for _, name := range x.CategoricalNames() {
+		if !skip[name] {
+			values, _ := x.Categorical(name)
+			d.categorical[name] = values
+			d.categories = append(d.categories, name)
+		}
+	}
+	return d
+}
+
+// frameColumns returns the names of the predictors the model was trained on.
+func (o *OLS) frameColumns() ([]string, error) {
+	if len(o.names) != o.predictors() {
+		return nil, fmt.Errorf("%w: the model was trained on data without names for its columns", LabelError)
+	}
+	return o.names, nil
+}
+
+func (o *OLS) predictFrame(x *DataFrame) ([]float64, error) {
+	return o.PredictAll(x)
+}
@@ -0,0 +1,168 @@
+package glasso
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+var stacklossLabels = []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+
+// shuffledStackloss returns the stackloss predictors with their columns in
+// another order and an extra column.
+func shuffledStackloss() *DataFrame {
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{row[2], float64(i), row[0], row[1]}
+	}
+	return NewDataFrame(rows, []string{"Acid.Conc.", "Run", "Air.Flow", "Water.Temp"})
+}
+
+func TestPredictFrame(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithStandardize(true)}, {WithIntercept(false)}} {
+		model, _, err := NewOlsTrainer(opts...).Train(NewDataFrame(data, stacklossLabels), y)
+		assert.Equal(t, nil, err)
+		want, err := model.(*OLS).PredictAll(NewDataFrame(data))
+		assert.Equal(t, nil, err)
+		got, err := PredictFrame(model, shuffledStackloss())
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want, got)
+	}
+
+	model, _, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	want, err := model.(*OLS).PredictAll(NewDataFrame(data))
+	assert.Equal(t, nil, err)
+	got, err := PredictFrame(model, shuffledStackloss())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, want, got)
+
+	_, err = PredictFrame(model, shuffledStackloss(), WithStrictColumns(true))
+	assert.Equal(t, `the new data has the columns ["Run"], which the model doesn't use`, err.Error())
+	exact, err := shuffledStackloss().Drop("Run")
+	assert.Equal(t, nil, err)
+	got, err = PredictFrame(model, exact, WithStrictColumns(true))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, want, got)
+
+	missing, err := shuffledStackloss().Drop("Air.Flow", "Acid.Conc.")
+	assert.Equal(t, nil, err)
+	_, err = PredictFrame(model, missing)
+	assert.Equal(t, `the new data lacks the columns ["Air.Flow" "Acid.Conc."] of the model`, err.Error())
+	_, err = PredictFrame(model, NewDataFrame(data))
+	assert.NotEqual(t, nil, err)
+
+	unlabeled, _, err := NewOlsTrainer().Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	_, err = PredictFrame(unlabeled, shuffledStackloss())
+	assert.NotEqual(t, nil, err)
+	ridge, _, err := NewRidgeTrainer(&RidgeConfig{Lambda: 1}).Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	_, err = PredictFrame(ridge, shuffledStackloss())
+	assert.NotEqual(t, nil, err)
+}
+
+// groupedCSV returns a frame of the numeric columns u and v, the
+// categorical column color and an unused column, with a response that
+// depends on all three.
+func groupedCSV(t *testing.T, n int) (*DataFrame, []float64) {
+	rng := rand.New(rand.NewSource(1))
+	colors := []string{"red", "green", "blue"}
+	effect := map[string]float64{"red": 0, "green": 2, "blue": -1}
+	var b strings.Builder
+	b.WriteString("u,color,note,v\n")
+	z := make([]float64, n)
+	for i := range z {
+		u, v, c := 4*rng.Float64(), rng.NormFloat64(), colors[i%3]
+		fmt.Fprintf(&b, "%v,%s,n%d,%v\n", u, c, i, v)
+		z[i] = 1 + u - 0.5*u*u + 2*v + effect[c] + 0.1*rng.NormFloat64()
+	}
+	df, err := ReadCSV(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return df, z
+}
+
+func TestPredictFrameTransforms(t *testing.T) {
+	df, z := groupedCSV(t, 40)
+	colors, _ := df.Categorical("color")
+	encoder, err := NewCategoricalEncoder("color", colors, nil)
+	assert.Equal(t, nil, err)
+	numeric, err := df.Select("u", "v")
+	assert.Equal(t, nil, err)
+
+	// the polynomials of u, then the encoding of color, are undone outside in
+	poly, err := NewPolynomialFeatures(numeric, 2, true, 0)
+	assert.Equal(t, nil, err)
+	expanded, err := poly.Transform(numeric)
+	assert.Equal(t, nil, err)
+	design, _, err := encoder.Transform(expanded, colors)
+	assert.Equal(t, nil, err)
+	positional := design.Copy()
+	model, _, err := NewOlsTrainer().Train(design, z)
+	assert.Equal(t, nil, err)
+	want, err := model.(*OLS).PredictAll(positional)
+	assert.Equal(t, nil, err)
+	got, err := PredictFrame(poly.Model(encoder.Model(model)), df)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, got, want, 1e-12)
+	_, err = PredictFrame(poly.Model(encoder.Model(model)), df, WithStrictColumns(true))
+	assert.Equal(t, `the new data has the columns ["note"], which the model doesn't use`, err.Error())
+
+	// the encoding of color, then the interactions of every pair
+	encoded, _, err := encoder.Transform(numeric, colors)
+	assert.Equal(t, nil, err)
+	interactions, err := NewInteractionFeatures(encoded, AllPairs(encoded.Cols()), false)
+	assert.Equal(t, nil, err)
+	design, err = interactions.Transform(encoded)
+	assert.Equal(t, nil, err)
+	positional = design.Copy()
+	model, _, err = NewOlsTrainer().Train(design, z)
+	assert.Equal(t, nil, err)
+	want, err = model.(*OLS).PredictAll(positional)
+	assert.Equal(t, nil, err)
+	shuffled, err := df.Select("color", "note", "v", "u")
+	assert.Equal(t, nil, err)
+	got, err = PredictFrame(encoder.Model(interactions.Model(model)), shuffled)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, got, want, 1e-12)
+
+	// an unseen level
+	rows, err := df.SelectRows([]int{0, 1})
+	assert.Equal(t, nil, err)
+	rows.categorical["color"] = []string{"red", "purple"}
+	_, err = PredictFrame(encoder.Model(interactions.Model(model)), rows)
+	assert.Equal(t, `row 1: "purple" is not a level of color`, err.Error())
+
+	// an encoding needs a model that knows its columns
+	_, err = PredictFrame(encoder.Model(unnamedModel{}), df)
+	assert.NotEqual(t, nil, err)
+}
+
+type unnamedModel struct{}
+
+func (unnamedModel) Predict([]float64) float64 { return 0 }
+
+func TestPredictFrameFormula(t *testing.T) {
+	df, z := groupedCSV(t, 30)
+	numeric, err := df.Select("u", "v")
+	assert.Equal(t, nil, err)
+	withY, err := numeric.Append(z, "y")
+	assert.Equal(t, nil, err)
+	m, s, err := Fit("y ~ poly(u, 2) + v + u:v", withY)
+	assert.Equal(t, nil, err)
+	got, err := PredictFrame(m, df)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, got, s.Yhat(), 1e-10)
+	again, err := PredictFrame(m, numeric)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, got, again)
+	onlyU, err := df.Select("u")
+	assert.Equal(t, nil, err)
+	_, err = PredictFrame(m, onlyU)
+	assert.Equal(t, `the new data lacks the columns ["v"] of the model`, err.Error())
+}
@@ -0,0 +1,276 @@
+package glasso
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPredictAll(t *testing.T) {
+	predictions, err := model.(*OLS).PredictAll(NewDataFrame(data))
+	assert.Equal(t, nil, err)
+	for i, fit := range summary.Yhat() {
+		assertClose(t, predictions[i], fit, 1e-10)
+	}
+
+	_, err = model.(*OLS).PredictAll(NewDataFrame([][]float64{{80, 27}}))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestPredictInterval(t *testing.T) {
+	predictions, err := PredictInterval(summary, NewDataFrame(data), 0.95)
+	assert.Equal(t, nil, err)
+
+	// at the training rows x_0'(X'X)^-1 x_0 is the leverage
+	h, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	s2 := summary.SumOfSquares() / 17
+	tq := 2.109815577833317
+	for i, pred := range predictions {
+		assertClose(t, pred.Fit, summary.Yhat()[i], 1e-10)
+		assertClose(t, pred.SE, math.Sqrt(s2*h[i]), 1e-10)
+		assertClose(t, pred.Confidence[1]-pred.Fit, tq*pred.SE, 1e-8)
+		assertClose(t, pred.Fit-pred.Prediction[0], tq*math.Sqrt(s2*(1+h[i])), 1e-8)
+	}
+
+	// a new point, with the standard error by hand from the covariance matrix
+	vc, err := VarCov(summary)
+	assert.Equal(t, nil, err)
+	x0 := []float64{1, 60, 20, 85}
+	variance := 0.0
+	for j := range x0 {
+		for k := range x0 {
+			variance += x0[j] * vc.X.At(j, k) * x0[k]
+		}
+	}
+	predictions, err = PredictInterval(summary, NewDataFrame([][]float64{x0[1:]}), 0.9)
+	assert.Equal(t, nil, err)
+	assertClose(t, predictions[0].Fit, model.Predict(x0[1:]), 1e-10)
+	assertClose(t, predictions[0].SE, math.Sqrt(variance), 1e-10)
+	assert.T(t, predictions[0].Prediction[0] < predictions[0].Confidence[0])
+
+	_, err = PredictInterval(summary, NewDataFrame([][]float64{{1, 2}}), 0.95)
+	assert.NotEqual(t, nil, err)
+	_, err = PredictInterval(summary, NewDataFrame(data), 1.5)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestPredictIntervalCoverage(t *testing.T) {
+	// intervals should cover the mean response, and a new draw, at the nominal rate
+	const reps = 2000
+	rng := rand.New(rand.NewSource(21))
+	x0 := NewDataFrame([][]float64{{1.5}})
+	coveredMean, coveredNew := 0, 0
+	for rep := 0; rep < reps; rep++ {
+		rows := make([][]float64, 15)
+		response := make([]float64, 15)
+		for i := range rows {
+			rows[i] = []float64{float64(i) / 5}
+			response[i] = 2 + 3*rows[i][0] + rng.NormFloat64()
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		pred, err := PredictInterval(s, x0, 0.9)
+		assert.Equal(t, nil, err)
+
+		mean := 2 + 3*1.5
+		if pred[0].Confidence[0] < mean && mean < pred[0].Confidence[1] {
+			coveredMean++
+		}
+		draw := mean + rng.NormFloat64()
+		if pred[0].Prediction[0] < draw && draw < pred[0].Prediction[1] {
+			coveredNew++
+		}
+	}
+	assertClose(t, float64(coveredMean)/reps, 0.9, 0.02)
+	assertClose(t, float64(coveredNew)/reps, 0.9, 0.02)
+}
+
+func TestPredictIntervalErrorVariance(t *testing.T) {
+	// errors whose variance is proportional to x, which the pooled variance
+	// of the classical interval understates at large x, while the intervals
+	// of the true variance function and of that of FGLS cover new draws there
+	// at about the nominal rate
+	const reps = 500
+	rng := rand.New(rand.NewSource(22))
+	x0 := NewDataFrame([][]float64{{9}, {9.5}, {10}})
+	var classical, known, estimated int
+	for rep := 0; rep < reps; rep++ {
+		rows := make([][]float64, 100)
+		response := make([]float64, len(rows))
+		for i := range rows {
+			rows[i] = []float64{1 + 9*rng.Float64()}
+			response[i] = 1 + 2*rows[i][0] + math.Sqrt(rows[i][0])*rng.NormFloat64()
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		fit, err := FitFGLS(NewDataFrame(rows), response, []int{0}, 5)
+		assert.Equal(t, nil, err)
+		pooled, err := PredictInterval(s, x0, 0.95)
+		assert.Equal(t, nil, err)
+		truth, err := PredictInterval(s, x0, 0.95, WithErrorVariance(func(x []float64) float64 { return x[0] }))
+		assert.Equal(t, nil, err)
+		fgls, err := PredictInterval(fit.Summary, x0, 0.95, WithErrorVariance(fit.ErrorVariance))
+		assert.Equal(t, nil, err)
+		for i := range pooled {
+			x := x0.GetRow(i)[0]
+			draw := 1 + 2*x + math.Sqrt(x)*rng.NormFloat64()
+			covers := func(p Prediction) int {
+				if p.Prediction[0] < draw && draw < p.Prediction[1] {
+					return 1
+				}
+				return 0
+			}
+			classical += covers(pooled[i])
+			known += covers(truth[i])
+			estimated += covers(fgls[i])
+			assert.Equal(t, pooled[i].Confidence, truth[i].Confidence)
+		}
+	}
+	n := float64(reps * x0.Rows())
+	assert.T(t, float64(classical)/n < 0.92)
+	assertClose(t, float64(known)/n, 0.95, 0.015)
+	assertClose(t, float64(estimated)/n, 0.95, 0.02)
+
+	// the variance function of FGLS is that of its weights
+	rows := [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}}
+	response := []float64{3.1, 4.8, 7.4, 8.7, 11.9, 12.2, 15.6, 16.1}
+	fit, err := FitFGLS(NewDataFrame(rows), response, []int{0}, 1)
+	assert.Equal(t, nil, err)
+	for i, w := range fit.Weights {
+		assertClose(t, fit.ErrorVariance(rows[i])*w, fit.Model.sigma2, 1e-12)
+	}
+	_, err = PredictInterval(fit.Summary, NewDataFrame(rows), 0.95, WithErrorVariance(func([]float64) float64 { return -1 }))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSEFit(t *testing.T) {
+	// predict.lm(lm(y ~ x), data.frame(x = 0:5), se.fit = TRUE) for the fit
+	// of (1, 1), (2, 3), (3, 2), (4, 5): s^2 = 1.35, and the standard error is
+	// s\sqrt{1/n + (x_0 - \bar{x})^2 / S_{xx}} with \bar{x} = 2.5, S_{xx} = 5
+	m, _, err := NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {2}, {3}, {4}}), []float64{1, 3, 2, 5})
+	assert.Equal(t, nil, err)
+	grid := [][]float64{{0}, {1}, {2}, {3}, {4}, {5}}
+	se, err := m.(*OLS).SEFitAll(NewDataFrame(grid))
+	assert.Equal(t, nil, err)
+	for i, x0 := range grid {
+		want := math.Sqrt(1.35 * (0.25 + (x0[0]-2.5)*(x0[0]-2.5)/5))
+		assertClose(t, se[i], want, 1e-12)
+	}
+
+	// on a grid of stackloss points it is the standard error of PredictInterval,
+	// and a decoded model, which keeps no fit, agrees through its covariance
+	var points [][]float64
+	for air := 50.0; air <= 80; air += 10 {
+		for water := 17.0; water <= 27; water += 5 {
+			points = append(points, []float64{air, water, 85})
+		}
+	}
+	predictions, err := PredictInterval(summary, NewDataFrame(points), 0.95)
+	assert.Equal(t, nil, err)
+	ols := model.(*OLS)
+	b, err := json.Marshal(ols)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	se, err = ols.SEFitAll(NewDataFrame(points))
+	assert.Equal(t, nil, err)
+	for i, x0 := range points {
+		assertClose(t, se[i], predictions[i].SE, 1e-10)
+		fromVCov, err := decoded.SEFit(x0)
+		assert.Equal(t, nil, err)
+		assertClose(t, fromVCov, se[i], 1e-10)
+	}
+
+	// weighted, standardized and aliased fits, against PredictInterval of
+	// their summaries, which leave the aliased column out
+	aliased := make([][]float64, len(data))
+	for i, row := range data {
+		aliased[i] = append(append([]float64(nil), row...), 2*row[0]-row[1])
+	}
+	for _, c := range []struct {
+		trainer Trainer
+		x       [][]float64
+	}{
+		{NewWlsTrainer(stacklossWeights), data},
+		{NewOlsTrainer(WithStandardize(true)), data},
+		{NewOlsTrainer(), aliased},
+	} {
+		m, s, err := c.trainer.Train(NewDataFrame(c.x), y)
+		assert.Equal(t, nil, err)
+		want, err := PredictInterval(s, NewDataFrame(data[:5]), 0.95)
+		assert.Equal(t, nil, err)
+		got, err := m.(*OLS).SEFitAll(NewDataFrame(c.x[:5]))
+		assert.Equal(t, nil, err)
+		for i := range got {
+			assertClose(t, got[i], want[i].SE, 1e-8)
+		}
+	}
+
+	_, err = ols.SEFit([]float64{60, 20})
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, err = ols.SEFitAll(NewDataFrame([][]float64{{60, 20}}))
+	assert.T(t, errors.Is(err, DimensionError), err)
+}
+
+func TestConfidenceBand(t *testing.T) {
+	// for the fit of (1, 1), (2, 3), (3, 2), (4, 5), p = 2 and n - p = 2, and
+	// F_{0.95; 2, 2} = 19, so the band is \pm\sqrt{38} se_fit
+	m, s, err := NewOlsTrainer().Train(NewDataFrame([][]float64{{1}, {2}, {3}, {4}}), []float64{1, 3, 2, 5})
+	assert.Equal(t, nil, err)
+	ols := m.(*OLS)
+	grid := mat.NewDense(6, 1, []float64{0, 1, 2, 3, 4, 5})
+	lower, upper, err := ConfidenceBand(ols, grid, 0.95)
+	assert.Equal(t, nil, err)
+	pointwise, err := PredictInterval(s, MatToDF(grid), 0.95)
+	assert.Equal(t, nil, err)
+	for i := range lower {
+		x0 := grid.At(i, 0)
+		fit := 1.1 * x0
+		w := math.Sqrt(38) * math.Sqrt(1.35*(0.25+(x0-2.5)*(x0-2.5)/5))
+		assertClose(t, lower[i], fit-w, 1e-9)
+		assertClose(t, upper[i], fit+w, 1e-9)
+		assert.T(t, lower[i] < pointwise[i].Confidence[0] && upper[i] > pointwise[i].Confidence[1])
+	}
+
+	// stackloss along a line in air flow alone: the Working-Hotelling band
+	// takes q = 4, and Scheffe's the q = 2 dimensions the line spans, with
+	// F_{0.95; 2, 17} = (17/2)(0.05^{-2/17} - 1)
+	ols = model.(*OLS)
+	line := mat.NewDense(4, 3, []float64{50, 20, 85, 60, 20, 85, 70, 20, 85, 80, 20, 85})
+	whLower, whUpper, err := ConfidenceBand(ols, line, 0.95)
+	assert.Equal(t, nil, err)
+	lower, upper, err = ConfidenceBand(ols, line, 0.95, WithBand(ScheffeBand))
+	assert.Equal(t, nil, err)
+	pointwise, err = PredictInterval(summary, MatToDF(line), 0.95)
+	assert.Equal(t, nil, err)
+	f2 := 17.0 / 2 * (math.Pow(0.05, -2.0/17) - 1)
+	for i := range lower {
+		x0 := line.RawRowView(i)
+		se, err := ols.SEFit(x0)
+		assert.Equal(t, nil, err)
+		fit := ols.Predict(x0)
+		assertClose(t, upper[i]-fit, math.Sqrt(2*f2)*se, 1e-9)
+		assertClose(t, whUpper[i]-fit, math.Sqrt(4*fQuantile(0.95, 4, 17))*se, 1e-9)
+		assert.T(t, whLower[i] < lower[i] && lower[i] < pointwise[i].Confidence[0])
+		assert.T(t, whUpper[i] > upper[i] && upper[i] > pointwise[i].Confidence[1])
+	}
+
+	// on points that span every direction the two bands are the same
+	lower, upper, err = ConfidenceBand(ols, mat.DenseCopyOf(NewDataFrame(data).X), 0.9, WithBand(ScheffeBand))
+	assert.Equal(t, nil, err)
+	whLower, whUpper, err = ConfidenceBand(ols, mat.DenseCopyOf(NewDataFrame(data).X), 0.9)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, lower, whLower, 1e-12)
+	assertCloseSlices(t, upper, whUpper, 1e-12)
+
+	_, _, err = ConfidenceBand(ols, mat.NewDense(1, 2, []float64{60, 20}), 0.95)
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, _, err = ConfidenceBand(ols, line, 1)
+	assert.NotEqual(t, nil, err)
+}
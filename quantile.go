@@ -0,0 +1,353 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// stepFraction is the fraction of the distance to the boundary that an
+// interior point step may go.
+const stepFraction = 0.99995
+
+// QuantileRegression is a linear model of a conditional quantile of the response.
+type QuantileRegression struct {
+	betas []float64
+}
+
+func (q *QuantileRegression) Predict(x []float64) float64 {
+	return q.betas[0] + sum(prod(x, q.betas[1:]))
+}
+
+type quantileTrainer struct {
+	tau  float64
+	opts options
+}
+
+// NewQuantileTrainer returns a Trainer for regression of the tau quantile of
+// the response, for tau in (0, 1). tau = 0.5 is median (least absolute
+// deviations) regression.
+func NewQuantileTrainer(tau float64, opts ...Option) Trainer {
+	return &quantileTrainer{
+		tau:  tau,
+		opts: newOptions(opts),
+	}
+}
+
+// Train minimizes the check loss
+//
+// \sum_i \rho_\tau(y_i - x_i'\beta), \rho_\tau(u) = u (\tau - I(u < 0))
+//
+// by the Frisch-Newton interior point method of Portnoy & Koenker (1997), as
+// quantreg's rq.fit.fnb does. It solves the dual linear program
+//
+// \max_a y'a subject to X'a = (1 - \tau) X'1, 0 <= a <= 1
+//
+// by Mehrotra predictor-corrector steps, until the duality gap is below the
+// tolerance relative to the check loss. The solution is then moved to the
+// vertex through the p observations with the smallest residuals, if that
+// doesn't increase the loss, so it agrees with the simplex solution of rq's
+// default method. When the minimizer isn't unique the vertex may be a
+// different one. A fit that doesn't converge within the maximum number of
+// iterations is returned with Converged false.
+//
+// The DataFrame is not modified, and the summary is a *QuantileSummary.
+func (q *quantileTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	if !(q.tau > 0 && q.tau < 1) {
+		return nil, nil, fmt.Errorf("quantile %v is not in (0, 1)", q.tau)
+	}
+	if err := q.opts.checkIterations(); err != nil {
+		return nil, nil, err
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	for _, v := range y {
+		if _, err := checkFinite(v); err != nil {
+			return nil, nil, err
+		}
+	}
+	design := x.Copy()
+	design.labels = x.Labels()
+	design.PushCol(rep(1, n))
+	p := design.Cols()
+	if n < p {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+
+	fit, err := leastSquares(design.X, mat.NewDense(n, 1, y))
+	if err != nil {
+		return nil, nil, err
+	}
+	lp := newQuantileLP(design.X, y, q.tau, fit)
+	for !lp.converged(q.opts.tolerance) && lp.iterations < q.opts.maxIter {
+		lp.iterations++
+		if err := lp.step(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	betas := lp.betas
+	if vertex := basicSolution(design.X, y, lp.residuals()); vertex != nil {
+		loss, best := checkLoss(design.X, y, vertex, q.tau), checkLoss(design.X, y, betas, q.tau)
+		if loss <= best+1e-9*(1+best) {
+			betas = vertex
+		}
+	}
+
+	fitted := make([]float64, n)
+	residuals := make([]float64, n)
+	for i := range y {
+		fitted[i] = sum(prod(design.X.RawRowView(i), betas))
+		residuals[i] = y[i] - fitted[i]
+	}
+	return &QuantileRegression{
+		betas: betas,
+	}, &QuantileSummary{
+		data:       design,
+		betas:      betas,
+		fitted:     fitted,
+		residuals:  residuals,
+		response:   append([]float64(nil), y...),
+		tau:        q.tau,
+		iterations: lp.iterations,
+		converged:  lp.converged(q.opts.tolerance),
+	}, nil
+}
+
+// quantileLP is the state of the interior point method: the dual variables
+// a and s = 1 - a of the observations, their multipliers z and w, and -betas,
+// the multipliers of the equality constraints. Every iterate is feasible, with
+// X'a = (1 - \tau) X'1 and X\beta + w - z = y, so the residuals are w - z and
+// the duality gap is a'z + s'w.
+type quantileLP struct {
+	x          *mat.Dense
+	y          []float64
+	tau        float64
+	betas      []float64
+	a, s, z, w []float64
+	iterations int
+}
+
+// newQuantileLP starts the interior point method from the least squares fit,
+// with a = 1 - \tau.
+func newQuantileLP(x *mat.Dense, y []float64, tau float64, start *lsFit) *quantileLP {
+	n := len(y)
+	lp := &quantileLP{
+		x:     x,
+		y:     y,
+		tau:   tau,
+		betas: start.betas,
+		a:     rep(1-tau, n),
+		s:     rep(tau, n),
+		z:     make([]float64, n),
+		w:     make([]float64, n),
+	}
+	// the residuals are split into z and w with a margin, so both are positive
+	margin := 0.0
+	for _, e := range start.residuals {
+		margin += math.Abs(e) / float64(n)
+	}
+	if margin == 0 {
+		margin = 1
+	}
+	for i, e := range start.residuals {
+		lp.z[i], lp.w[i] = math.Max(-e, 0)+margin, math.Max(e, 0)+margin
+	}
+	return lp
+}
+
+func (lp *quantileLP) residuals() []float64 {
+	return diff(lp.w, lp.z)
+}
+
+func (lp *quantileLP) converged(tol float64) bool {
+	gap := sum(prod(lp.a, lp.z)) + sum(prod(lp.s, lp.w))
+	loss := 0.0
+	for _, e := range lp.residuals() {
+		loss += rho(e, lp.tau)
+	}
+	return gap <= tol*(1+loss)
+}
+
+// step takes a Mehrotra predictor-corrector step.
+func (lp *quantileLP) step() error {
+	n := len(lp.y)
+	az, sw := prod(lp.a, lp.z), prod(lp.s, lp.w)
+
+	// the affine scaling direction aims at complementarity
+	da, dbetas, dz, dw, err := lp.direction(multSlice(az, -1), multSlice(sw, -1))
+	if err != nil {
+		return err
+	}
+	primal, dual := lp.stepLengths(da, dz, dw)
+	if primal < 1 || dual < 1 {
+		// the corrector aims at the centered point where a z = s w = \sigma \mu
+		mu := (sum(az) + sum(sw)) / float64(2*n)
+		affine := 0.0
+		for i := range da {
+			affine += (lp.a[i]+primal*da[i])*(lp.z[i]+dual*dz[i]) + (lp.s[i]-primal*da[i])*(lp.w[i]+dual*dw[i])
+		}
+		sigma := math.Pow(affine/float64(2*n)/mu, 3)
+		ra, rs := make([]float64, n), make([]float64, n)
+		for i := range ra {
+			ra[i] = sigma*mu - az[i] - da[i]*dz[i]
+			rs[i] = sigma*mu - sw[i] + da[i]*dw[i]
+		}
+		if da, dbetas, dz, dw, err = lp.direction(ra, rs); err != nil {
+			return err
+		}
+		primal, dual = lp.stepLengths(da, dz, dw)
+	}
+
+	for i := range lp.a {
+		lp.a[i] += primal * da[i]
+		lp.s[i] -= primal * da[i]
+		lp.z[i] += dual * dz[i]
+		lp.w[i] += dual * dw[i]
+	}
+	for j := range lp.betas {
+		lp.betas[j] += dual * dbetas[j]
+	}
+	return nil
+}
+
+// direction solves the Newton equations for the step that keeps the iterate
+// feasible and moves the products a z and s w by ra and rs. With
+// q = 1 / (z / a + w / s) and v = ra / a - rs / s, the step in the
+// coefficients is the weighted least squares fit of v with weights q.
+func (lp *quantileLP) direction(ra, rs []float64) (da, dbetas, dz, dw []float64, err error) {
+	n, p := lp.x.Dims()
+	q, v := make([]float64, n), make([]float64, n)
+	wx, wv := mat.DenseCopyOf(lp.x), mat.NewDense(n, 1, nil)
+	for i := range q {
+		q[i] = 1 / (lp.z[i]/lp.a[i] + lp.w[i]/lp.s[i])
+		v[i] = ra[i]/lp.a[i] - rs[i]/lp.s[i]
+		row := wx.RawRowView(i)
+		for j := 0; j < p; j++ {
+			row[j] *= math.Sqrt(q[i])
+		}
+		wv.Set(i, 0, math.Sqrt(q[i])*v[i])
+	}
+	fit, err := leastSquares(wx, wv)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	da, dz, dw = make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range da {
+		da[i] = q[i] * (v[i] - sum(prod(lp.x.RawRowView(i), fit.betas)))
+		dz[i] = (ra[i] - lp.z[i]*da[i]) / lp.a[i]
+		dw[i] = (rs[i] + lp.w[i]*da[i]) / lp.s[i]
+	}
+	return da, fit.betas, dz, dw, nil
+}
+
+// stepLengths returns the longest primal and dual steps, up to 1, that keep
+// a, s, z and w positive.
+func (lp *quantileLP) stepLengths(da, dz, dw []float64) (primal, dual float64) {
+	primal, dual = 1, 1
+	for i := range da {
+		primal = math.Min(primal, boundaryStep(lp.a[i], da[i]))
+		primal = math.Min(primal, boundaryStep(lp.s[i], -da[i]))
+		dual = math.Min(dual, boundaryStep(lp.z[i], dz[i]))
+		dual = math.Min(dual, boundaryStep(lp.w[i], dw[i]))
+	}
+	return primal, dual
+}
+
+// boundaryStep returns the fraction stepFraction of the step from v along dv
+// to zero, or 1 if dv doesn't decrease v.
+func boundaryStep(v, dv float64) float64 {
+	if dv >= 0 {
+		return 1
+	}
+	return -stepFraction * v / dv
+}
+
+// basicSolution returns the coefficients of the fit through the p observations
+// with the smallest absolute residuals, or nil if the design of those
+// observations is singular.
+func basicSolution(x *mat.Dense, y, residuals []float64) []float64 {
+	_, p := x.Dims()
+	order := make([]int, len(y))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(residuals[order[a]]) < math.Abs(residuals[order[b]])
+	})
+	a, b := mat.NewDense(p, p, nil), mat.NewDense(p, 1, nil)
+	for k, i := range order[:p] {
+		a.SetRow(k, x.RawRowView(i))
+		b.Set(k, 0, y[i])
+	}
+	if rankDeficient(a) {
+		return nil
+	}
+	fit, err := leastSquares(a, b)
+	if err != nil {
+		return nil
+	}
+	return fit.betas
+}
+
+// checkLoss returns \sum_i \rho_\tau(y_i - x_i'\beta).
+func checkLoss(x *mat.Dense, y, betas []float64, tau float64) float64 {
+	loss := 0.0
+	for i := range y {
+		loss += rho(y[i]-sum(prod(x.RawRowView(i), betas)), tau)
+	}
+	return loss
+}
+
+// rho is the check function u (\tau - I(u < 0)).
+func rho(u, tau float64) float64 {
+	if u < 0 {
+		return u * (tau - 1)
+	}
+	return u * tau
+}
+
+// QuantileSummary summarizes a quantile regression.
+type QuantileSummary struct {
+	data       *DataFrame
+	betas      []float64
+	fitted     []float64
+	residuals  []float64
+	response   []float64
+	tau        float64
+	iterations int
+	converged  bool
+}
+
+func (q *QuantileSummary) Data() *DataFrame        { return q.data }
+func (q *QuantileSummary) Coefficients() []float64 { return q.betas }
+func (q *QuantileSummary) Residuals() []float64    { return q.residuals }
+func (q *QuantileSummary) Yhat() []float64         { return q.fitted }
+func (q *QuantileSummary) Response() []float64     { return q.response }
+
+func (q *QuantileSummary) SumOfSquares() float64 {
+	return sum(prod(q.residuals, q.residuals))
+}
+
+// Tau returns the quantile of the response that was modeled.
+func (q *QuantileSummary) Tau() float64 { return q.tau }
+
+// Loss returns the check loss of the fit, \sum \rho_\tau(e_i).
+func (q *QuantileSummary) Loss() float64 {
+	loss := 0.0
+	for _, e := range q.residuals {
+		loss += rho(e, q.tau)
+	}
+	return loss
+}
+
+// Iterations returns the number of interior point iterations of the fit.
+func (q *QuantileSummary) Iterations() int { return q.iterations }
+
+// Converged reports whether the duality gap closed within the maximum number of iterations.
+func (q *QuantileSummary) Converged() bool { return q.converged }
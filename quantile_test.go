@@ -0,0 +1,175 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bestVertex returns the smallest check loss of a fit through p of the
+// observations, which a quantile regression attains.
+func bestVertex(x *mat.Dense, y []float64, tau float64) float64 {
+	n, p := x.Dims()
+	best := math.Inf(1)
+	var choose func(rows []int, next int)
+	choose = func(rows []int, next int) {
+		if len(rows) == p {
+			a, b := mat.NewDense(p, p, nil), mat.NewDense(p, 1, nil)
+			for k, i := range rows {
+				a.SetRow(k, x.RawRowView(i))
+				b.Set(k, 0, y[i])
+			}
+			if rankDeficient(a) {
+				return
+			}
+			if fit, err := leastSquares(a, b); err == nil {
+				best = math.Min(best, checkLoss(x, y, fit.betas, tau))
+			}
+			return
+		}
+		for i := next; i < n; i++ {
+			choose(append(rows, i), i+1)
+		}
+	}
+	choose(nil, 0)
+	return best
+}
+
+func TestQuantileRegression(t *testing.T) {
+	df := NewDataFrame(data)
+	model, s, err := NewQuantileTrainer(0.5).Train(df, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if df.Cols() != 3 {
+		t.Errorf("Train changed the number of columns of the DataFrame to %d", df.Cols())
+	}
+	summary := s.(*QuantileSummary)
+	if !summary.Converged() || summary.Iterations() > 50 {
+		t.Errorf("got converged %v after %d iterations", summary.Converged(), summary.Iterations())
+	}
+
+	// coef(rq(stack.loss ~ ., stackloss))
+	want := []float64{-39.68985507, 0.83188406, 0.57391304, -0.06086957}
+	b := summary.Coefficients()
+	for j := range want {
+		if math.Abs(b[j]-want[j]) > 1e-7 {
+			t.Errorf("coefficient %d is %v, want %v", j, b[j], want[j])
+		}
+	}
+	// the fit passes through p observations
+	zeros := 0
+	for _, e := range summary.Residuals() {
+		if math.Abs(e) < 1e-9 {
+			zeros++
+		}
+	}
+	if zeros < len(b) {
+		t.Errorf("%d residuals are zero, want at least %d", zeros, len(b))
+	}
+	if got, want := model.Predict(data[0]), summary.Yhat()[0]; math.Abs(got-want) > 1e-10 {
+		t.Errorf("prediction is %v, want the fitted value %v", got, want)
+	}
+
+	for _, tau := range []float64{0.1, 0.25, 0.75, 0.9} {
+		_, s, err := NewQuantileTrainer(tau).Train(NewDataFrame(data), y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := s.(*QuantileSummary)
+		if got, want := summary.Loss(), bestVertex(summary.Data().X, y, tau); math.Abs(got-want) > 1e-9*want {
+			t.Errorf("tau %v: check loss is %v, want the smallest %v", tau, got, want)
+		}
+		// about tau of the observations are below the fit
+		below := 0
+		for _, e := range summary.Residuals() {
+			if e < -1e-9 {
+				below++
+			}
+		}
+		if n := float64(len(y)); float64(below) > tau*n || float64(below) < tau*n-4 {
+			t.Errorf("tau %v: %d observations are below the fit", tau, below)
+		}
+	}
+}
+
+func TestQuantileIntercept(t *testing.T) {
+	// with only an intercept the fit is the sample quantile, the
+	// ceiling(n tau)th smallest response when n tau isn't whole
+	sorted := append([]float64(nil), y...)
+	sort.Float64s(sorted)
+	empty := NewDataFrame(make([][]float64, len(y)))
+	for _, tau := range []float64{0.05, 0.3, 0.5, 0.8} {
+		_, s, err := NewQuantileTrainer(tau).Train(empty, y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := sorted[int(math.Ceil(tau*float64(len(y))))-1]
+		if b := s.Coefficients(); len(b) != 1 || b[0] != want {
+			t.Errorf("tau %v: got coefficients %v, want [%v]", tau, b, want)
+		}
+	}
+}
+
+func TestQuantileSimple(t *testing.T) {
+	// heavy tailed errors, against every line through two points
+	rng := rand.New(rand.NewSource(2))
+	x := make([][]float64, 40)
+	response := make([]float64, 40)
+	for i := range x {
+		x[i] = []float64{rng.Float64() * 5}
+		response[i] = 3 - x[i][0] + rng.NormFloat64()/rng.NormFloat64()
+	}
+	for _, tau := range []float64{0.2, 0.5, 0.95} {
+		_, s, err := NewQuantileTrainer(tau).Train(NewDataFrame(x), response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		summary := s.(*QuantileSummary)
+		if got, want := summary.Loss(), bestVertex(summary.Data().X, response, tau); math.Abs(got-want) > 1e-9*want {
+			t.Errorf("tau %v: check loss is %v, want the smallest %v", tau, got, want)
+		}
+	}
+
+	// a response on a line is fit exactly
+	line := make([]float64, len(x))
+	for i := range x {
+		line[i] = 1 + 2*x[i][0]
+	}
+	_, s, err := NewQuantileTrainer(0.5).Train(NewDataFrame(x), line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := s.Coefficients(); math.Abs(b[0]-1) > 1e-9 || math.Abs(b[1]-2) > 1e-9 {
+		t.Errorf("got coefficients %v, want [1 2]", b)
+	}
+}
+
+func TestQuantileInvalid(t *testing.T) {
+	for _, tau := range []float64{0, 1, -0.5, 2, math.NaN()} {
+		if _, _, err := NewQuantileTrainer(tau).Train(NewDataFrame(data), y); err == nil {
+			t.Errorf("expected an error for tau %v", tau)
+		}
+	}
+	if _, _, err := NewQuantileTrainer(0.5).Train(NewDataFrame(data), y[1:]); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+	bad := append([]float64(nil), y...)
+	bad[3] = math.Inf(1)
+	if _, _, err := NewQuantileTrainer(0.5).Train(NewDataFrame(data), bad); err == nil {
+		t.Error("expected an error for an infinite response")
+	}
+	if _, _, err := NewQuantileTrainer(0.5, WithTolerance(0)).Train(NewDataFrame(data), y); err == nil {
+		t.Error("expected an error for a zero tolerance")
+	}
+	_, s, err := NewQuantileTrainer(0.5, WithMaxIterations(1)).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.(*QuantileSummary).Converged() {
+		t.Error("fit converged in one iteration")
+	}
+}
@@ -0,0 +1,223 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultRansacTrials is the number of random subsets RANSAC tries when the
+// config doesn't set one.
+const DefaultRansacTrials = 100
+
+// RansacConfig configures a RANSAC fit.
+type RansacConfig struct {
+	Trials    int     // random minimal subsets to try, DefaultRansacTrials if zero
+	Threshold float64 // largest absolute residual of an inlier
+
+	// MADScaled makes Threshold a multiple of the MAD of the response,
+	// median|y - median(y)| / 0.6745, rather than an absolute residual.
+	MADScaled bool
+
+	// MinInliers is the smallest fraction of the observations a consensus set
+	// may have. If no trial has that many inliers the fit fails.
+	MinInliers float64
+
+	// Rand, if it isn't nil, seeds the random subsets in place of the
+	// options' WithSeed or WithRandSource.
+	Rand *rand.Rand
+}
+
+type ransacTrainer struct {
+	config *RansacConfig
+	opts   options
+}
+
+// NewRansacTrainer returns a Trainer for RANSAC (random sample consensus)
+// regression, which fits least squares while ignoring gross outliers. The
+// trials are shared out among the workers of WithParallelism, as Bootstrap's
+// resamples are, and WithSeed seeds them.
+func NewRansacTrainer(config *RansacConfig, opts ...Option) Trainer {
+	return &ransacTrainer{
+		config: config,
+		opts:   newOptions(opts),
+	}
+}
+
+// Train repeatedly fits least squares exactly through a random minimal subset
+// of p observations, for p coefficients including the intercept, and counts
+// the observations within the threshold of each fit as its inliers. The trial
+// with the most inliers wins, with ties going to the smaller residual sum of
+// squares of the inliers. Subsets whose design is rank deficient are skipped
+// and counted. The model is the OLS fit on the inliers of the winning trial.
+//
+// The DataFrame is not modified, and the summary is a *RansacSummary, whose
+// least squares summary describes the fit on the inliers.
+func (r *ransacTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	if r.config == nil {
+		return nil, nil, fmt.Errorf("config not set")
+	}
+	trials := r.config.Trials
+	if trials == 0 {
+		trials = DefaultRansacTrials
+	}
+	if trials < 0 {
+		return nil, nil, fmt.Errorf("number of trials %d is negative", trials)
+	}
+	if !(r.config.Threshold > 0) || math.IsInf(r.config.Threshold, 0) {
+		return nil, nil, fmt.Errorf("threshold %v is not a positive number", r.config.Threshold)
+	}
+	if !(r.config.MinInliers >= 0 && r.config.MinInliers <= 1) {
+		return nil, nil, fmt.Errorf("minimum inlier fraction %v is not between 0 and 1", r.config.MinInliers)
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	design := x.Copy()
+	design.PushCol(rep(1, n))
+	p := design.Cols()
+	if n < p {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+
+	threshold := r.config.Threshold
+	if r.config.MADScaled {
+		threshold *= mad(subSlice(y, median(y)))
+		if threshold == 0 {
+			return nil, nil, fmt.Errorf("response has a MAD of zero, so can't scale the threshold")
+		}
+	}
+	o := r.opts
+	if r.config.Rand != nil {
+		o.source = r.config.Rand
+	}
+
+	// the fit of each trial, nil if its subset was degenerate, and the number
+	// and residual sum of squares of its inliers
+	type trialFit struct {
+		betas []float64
+		count int
+		rss   float64
+	}
+	fits := make([]trialFit, trials)
+	err := newResampler(o).run(trials, func(trial int, rng *rand.Rand) error {
+		// a partial Fisher-Yates shuffle draws p distinct rows
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		for k := 0; k < p; k++ {
+			j := k + rng.Intn(n-k)
+			order[k], order[j] = order[j], order[k]
+		}
+		a, b := mat.NewDense(p, p, nil), mat.NewDense(p, 1, nil)
+		for k, i := range order[:p] {
+			a.SetRow(k, design.X.RawRowView(i))
+			b.Set(k, 0, y[i])
+		}
+		if rankDeficient(a) {
+			return nil
+		}
+		fit, err := leastSquares(a, b)
+		if err != nil {
+			return nil
+		}
+		f := trialFit{betas: fit.betas}
+		for i := range y {
+			if e := y[i] - sum(prod(design.X.RawRowView(i), fit.betas)); math.Abs(e) <= threshold {
+				f.count++
+				f.rss += e * e
+			}
+		}
+		fits[trial] = f
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		best       []float64
+		bestCount  int
+		bestRSS    = math.Inf(1)
+		degenerate int
+	)
+	for _, f := range fits {
+		if f.betas == nil {
+			degenerate++
+			continue
+		}
+		if f.count > bestCount || f.count == bestCount && f.rss < bestRSS {
+			best, bestCount, bestRSS = f.betas, f.count, f.rss
+		}
+	}
+	if best == nil {
+		return nil, nil, fmt.Errorf("all %d random subsets were degenerate", trials)
+	}
+	if float64(bestCount) < r.config.MinInliers*float64(n) {
+		return nil, nil, fmt.Errorf("the largest consensus set has %d of %d observations, fewer than the minimum fraction %v", bestCount, n, r.config.MinInliers)
+	}
+	inliers := make([]bool, n)
+	for i := range y {
+		inliers[i] = math.Abs(y[i]-sum(prod(design.X.RawRowView(i), best))) <= threshold
+	}
+
+	var rows [][]float64
+	var response []float64
+	for i, in := range inliers {
+		if in {
+			rows = append(rows, x.GetRow(i))
+			response = append(response, y[i])
+		}
+	}
+	model, summary, err := trainLeastSquares(NewDataFrame(rows, x.Labels()), response, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return model, &RansacSummary{
+		OlsSummary: summary,
+		inliers:    inliers,
+		trials:     trials,
+		degenerate: degenerate,
+	}, nil
+}
+
+// rankDeficient reports whether the square matrix a is numerically singular,
+// from the diagonal of R in its QR factorization.
+func rankDeficient(a *mat.Dense) bool {
+	r := &mat.Dense{}
+	factorize(a).RTo(r)
+	_, p := r.Dims()
+	largest := 0.0
+	for j := 0; j < p; j++ {
+		largest = math.Max(largest, math.Abs(r.At(j, j)))
+	}
+	for j := 0; j < p; j++ {
+		if math.Abs(r.At(j, j)) <= 1e-10*largest {
+			return true
+		}
+	}
+	return false
+}
+
+// RansacSummary summarizes a RANSAC fit. Its OlsSummary is the least squares
+// fit on the inliers, so the diagnostics describe that fit.
+type RansacSummary struct {
+	OlsSummary
+	inliers    []bool
+	trials     int
+	degenerate int
+}
+
+// Inliers returns whether each of the n observations is in the consensus set the model was fit on.
+func (r *RansacSummary) Inliers() []bool { return r.inliers }
+
+// Trials returns the number of random subsets that were tried.
+func (r *RansacSummary) Trials() int { return r.trials }
+
+// Degenerate returns the number of random subsets skipped for being rank deficient.
+func (r *RansacSummary) Degenerate() int { return r.degenerate }
@@ -0,0 +1,176 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// outlying returns n points near y = 1 + 2x, a fraction of which are moved far
+// above the line. It also returns which points are on the line.
+func outlying(rng *rand.Rand, n int, fraction float64) ([][]float64, []float64, []bool) {
+	x := make([][]float64, n)
+	y := make([]float64, n)
+	clean := make([]bool, n)
+	for i := range x {
+		x[i] = []float64{rng.Float64() * 10}
+		y[i] = 1 + 2*x[i][0] + 0.1*rng.NormFloat64()
+		clean[i] = true
+	}
+	for _, i := range rng.Perm(n)[:int(fraction*float64(n))] {
+		y[i] += 20 + 30*rng.Float64()
+		clean[i] = false
+	}
+	return x, y, clean
+}
+
+func TestRansac(t *testing.T) {
+	x, y, clean := outlying(rand.New(rand.NewSource(3)), 100, 0.3)
+	df := NewDataFrame(x)
+	_, leastSquares, err := NewOlsTrainer().Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := leastSquares.Coefficients(); math.Abs(b[0]-1) < 2 {
+		t.Fatalf("least squares intercept %v is not pulled away by the outliers", b[0])
+	}
+
+	model, s, err := NewRansacTrainer(&RansacConfig{Threshold: 0.5, MinInliers: 0.5}).Train(df, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if df.Cols() != 1 {
+		t.Errorf("Train changed the number of columns of the DataFrame to %d", df.Cols())
+	}
+	summary := s.(*RansacSummary)
+	inliers := summary.Inliers()
+	if len(inliers) != len(y) {
+		t.Fatalf("got %d inliers flags for %d observations", len(inliers), len(y))
+	}
+	count := 0
+	for i, in := range inliers {
+		if in != clean[i] {
+			t.Errorf("observation %d: inlier is %v, want %v", i, in, clean[i])
+		}
+		if in {
+			count++
+		}
+	}
+	if summary.Trials() != DefaultRansacTrials || summary.Degenerate() != 0 {
+		t.Errorf("got %d trials and %d degenerate subsets, want %d and 0", summary.Trials(), summary.Degenerate(), DefaultRansacTrials)
+	}
+
+	// the model is the least squares fit on the inliers
+	var rows [][]float64
+	var response []float64
+	for i, in := range inliers {
+		if in {
+			rows = append(rows, x[i])
+			response = append(response, y[i])
+		}
+	}
+	_, refit, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := summary.Coefficients()
+	for j, want := range refit.Coefficients() {
+		if math.Abs(b[j]-want) > 1e-10 {
+			t.Errorf("coefficient %d is %v, want the refit's %v", j, b[j], want)
+		}
+	}
+	if len(summary.Residuals()) != count {
+		t.Errorf("got %d residuals for %d inliers", len(summary.Residuals()), count)
+	}
+	if math.Abs(b[0]-1) > 0.1 || math.Abs(b[1]-2) > 0.02 {
+		t.Errorf("got coefficients %v, want about [1 2]", b)
+	}
+	if got, want := model.Predict([]float64{5}), b[0]+5*b[1]; math.Abs(got-want) > 1e-10 {
+		t.Errorf("prediction is %v, want %v", got, want)
+	}
+}
+
+func TestRansacConfig(t *testing.T) {
+	x, y, clean := outlying(rand.New(rand.NewSource(5)), 60, 0.3)
+
+	// the MAD of the response is about 7, so 0.1 of it is about 0.7
+	_, s, err := NewRansacTrainer(&RansacConfig{Threshold: 0.1, MADScaled: true}).Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, in := range s.(*RansacSummary).Inliers() {
+		if in != clean[i] {
+			t.Errorf("observation %d: inlier is %v, want %v", i, in, clean[i])
+		}
+	}
+
+	// the same source of subsets gives the same fit
+	fit := func(seed int64) []float64 {
+		config := &RansacConfig{Trials: 5, Threshold: 0.5, Rand: rand.New(rand.NewSource(seed))}
+		_, s, err := NewRansacTrainer(config).Train(NewDataFrame(x), y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Coefficients()
+	}
+	if a, b := fit(11), fit(11); a[0] != b[0] || a[1] != b[1] {
+		t.Errorf("fits from the same seed differ: %v and %v", a, b)
+	}
+
+	// no consensus set has more than 70% of the observations
+	if _, _, err := NewRansacTrainer(&RansacConfig{Threshold: 0.5, MinInliers: 0.9}).Train(NewDataFrame(x), y); err == nil {
+		t.Error("expected an error for too few inliers")
+	}
+}
+
+func TestRansacDegenerate(t *testing.T) {
+	// with x taking two values, a pair of equal x is singular
+	x := make([][]float64, 20)
+	y := make([]float64, 20)
+	for i := range x {
+		x[i] = []float64{float64(i % 2)}
+		y[i] = 3 - float64(i%2)
+	}
+	y[0] = 10
+	_, s, err := NewRansacTrainer(&RansacConfig{Threshold: 1e-6}).Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := s.(*RansacSummary)
+	if summary.Degenerate() == 0 || summary.Degenerate() == summary.Trials() {
+		t.Errorf("got %d degenerate subsets of %d", summary.Degenerate(), summary.Trials())
+	}
+	if b := summary.Coefficients(); math.Abs(b[0]-3) > 1e-10 || math.Abs(b[1]+1) > 1e-10 {
+		t.Errorf("got coefficients %v, want [3 -1]", b)
+	}
+	if summary.Inliers()[0] {
+		t.Error("the outlier is an inlier")
+	}
+
+	constant := NewDataFrame([][]float64{{1}, {1}, {1}})
+	if _, _, err := NewRansacTrainer(&RansacConfig{Threshold: 1}).Train(constant, []float64{1, 2, 3}); err == nil {
+		t.Error("expected an error when every subset is degenerate")
+	}
+}
+
+func TestRansacInvalid(t *testing.T) {
+	x, y, _ := outlying(rand.New(rand.NewSource(1)), 10, 0)
+	for _, config := range []*RansacConfig{
+		nil,
+		{},
+		{Threshold: -1},
+		{Threshold: math.NaN()},
+		{Threshold: 1, Trials: -1},
+		{Threshold: 1, MinInliers: 1.5},
+	} {
+		if _, _, err := NewRansacTrainer(config).Train(NewDataFrame(x), y); err == nil {
+			t.Errorf("expected an error for config %+v", config)
+		}
+	}
+	if _, _, err := NewRansacTrainer(&RansacConfig{Threshold: 1}).Train(NewDataFrame(x), y[1:]); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+	if _, _, err := NewRansacTrainer(&RansacConfig{Threshold: 1, MADScaled: true}).Train(NewDataFrame(x), rep(1, 10)); err == nil {
+		t.Error("expected an error for a response with a MAD of zero")
+	}
+}
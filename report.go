@@ -0,0 +1,151 @@
+package glasso
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// ReportCoefficient is one row of the coefficient table of a Report.
+type ReportCoefficient struct {
+	Name string
+	Coefficient
+}
+
+// Report collects the quantities shown by R's summary.lm for a fitted model.
+// Its String method renders them in the same layout.
+type Report struct {
+	ResidualQuantiles [5]float64 // min, first quartile, median, third quartile, max
+	Coefficients      []ReportCoefficient
+	Aliased           []string // predictors left out of a rank-deficient fit
+
+	ResidualStdError float64
+	ResidualDF       int
+	RSquared         float64
+	AdjRSquared      float64
+	F                TestResult
+}
+
+// NewReport summarizes the model.
+func NewReport(m Summary) (*Report, error) {
+	x := m.Data()
+	n, p := x.Rows(), x.Cols()
+	if n <= p {
+		return nil, fmt.Errorf("%w: summary needs more than %d observations", TooFewObservationsError, p)
+	}
+
+	table, err := CoefficientTable(m)
+	if err != nil {
+		return nil, err
+	}
+	intercept := interceptOf(m)
+	names := CoefficientNames(m)
+	coefficients := make([]ReportCoefficient, len(table))
+	for j, c := range table {
+		coefficients[j] = ReportCoefficient{Name: names[j], Coefficient: c}
+	}
+
+	residuals := make([]float64, n)
+	copy(residuals, m.Residuals())
+	sort.Float64s(residuals)
+	var quantiles [5]float64
+	for i, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		quantiles[i] = quantile(residuals, q)
+	}
+
+	rss := m.SumOfSquares()
+	df := n - p
+	r2 := 1 - rss/modelTotalSumOfSquares(m)
+	dft := float64(n)
+	if intercept >= 0 {
+		dft--
+	}
+
+	// a model with only an intercept has nothing for the F test to test
+	f, err := OverallFTest(m)
+	if err != nil {
+		f = TestResult{Statistic: math.NaN(), PValue: math.NaN()}
+	}
+
+	var aliased []string
+	if s, ok := m.(OlsSummary); ok {
+		aliased = s.Aliased()
+	}
+
+	return &Report{
+		ResidualQuantiles: quantiles,
+		Coefficients:      coefficients,
+		Aliased:           aliased,
+		ResidualStdError:  math.Sqrt(rss / float64(df)),
+		ResidualDF:        df,
+		RSquared:          r2,
+		AdjRSquared:       1 - (1-r2)*dft/float64(df),
+		F:                 f,
+	}, nil
+}
+
+// significance returns the significance stars R prints next to a p-value.
+func significance(p float64) string {
+	switch {
+	case p < 0.001:
+		return "***"
+	case p < 0.01:
+		return "**"
+	case p < 0.05:
+		return "*"
+	case p < 0.1:
+		return "."
+	}
+	return ""
+}
+
+// formatPValue prints a p-value to four significant digits, or as an upper
+// bound once it is below machine precision.
+func formatPValue(p float64) string {
+	if p < 2.2e-16 {
+		return "<2e-16"
+	}
+	return fmt.Sprintf("%.4g", p)
+}
+
+func (r *Report) String() string {
+	var buf bytes.Buffer
+
+	buf.WriteString("Residuals:\n")
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Min\t1Q\tMedian\t3Q\tMax\t")
+	q := r.ResidualQuantiles
+	fmt.Fprintf(w, "%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t\n", q[0], q[1], q[2], q[3], q[4])
+	w.Flush()
+
+	if len(r.Aliased) > 0 {
+		fmt.Fprintf(&buf, "\nCoefficients: (%d not defined because of singularities)\n", len(r.Aliased))
+	} else {
+		buf.WriteString("\nCoefficients:\n")
+	}
+	w = tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	// pad the names so they stay left aligned
+	width := 0
+	for _, c := range r.Coefficients {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
+	fmt.Fprintf(w, "%*s\tEstimate\tStd. Error\tt value\tPr(>|t|)\t\t\n", width, "")
+	for _, c := range r.Coefficients {
+		fmt.Fprintf(w, "%-*s\t%.5g\t%.5g\t%.3f\t%s\t%s\t\n",
+			width, c.Name, c.Estimate, c.StdError, c.T, formatPValue(c.PValue), significance(c.PValue))
+	}
+	w.Flush()
+	buf.WriteString("---\nSignif. codes:  0 '***' 0.001 '**' 0.01 '*' 0.05 '.' 0.1 ' ' 1\n\n")
+
+	fmt.Fprintf(&buf, "Residual standard error: %.4g on %d degrees of freedom\n", r.ResidualStdError, r.ResidualDF)
+	fmt.Fprintf(&buf, "Multiple R-squared:  %.4g,\tAdjusted R-squared:  %.4g\n", r.RSquared, r.AdjRSquared)
+	if !math.IsNaN(r.F.Statistic) {
+		fmt.Fprintf(&buf, "F-statistic: %.4g on %v and %v DF,  p-value: %s\n",
+			r.F.Statistic, r.F.DF, r.F.DF2, formatPValue(r.F.PValue))
+	}
+	return buf.String()
+}
@@ -0,0 +1,81 @@
+package glasso
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// golden compares got with the contents of testdata/name, rewriting it with -update.
+func golden(t *testing.T, name, got string) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func labeledStackloss(t *testing.T) Summary {
+	labels := []string{"Air.Flow", "Water.Temp", "Acid.Conc."}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(data, labels), y)
+	assert.Equal(t, nil, err)
+	return s
+}
+
+func TestReport(t *testing.T) {
+	r, err := NewReport(labeledStackloss(t))
+	assert.Equal(t, nil, err)
+
+	// summary(lm(stack.loss ~ ., stackloss))
+	expected := [5]float64{-7.2377, -1.7117, -0.4551, 2.3614, 5.6978}
+	for i, q := range r.ResidualQuantiles {
+		assertClose(t, q, expected[i], 1e-4)
+	}
+	names := []string{"(Intercept)", "Air.Flow", "Water.Temp", "Acid.Conc."}
+	for j, c := range r.Coefficients {
+		assert.Equal(t, c.Name, names[j])
+	}
+	assertClose(t, r.Coefficients[1].Estimate, 0.7156, 1e-4)
+	assertClose(t, r.ResidualStdError, 3.243, 1e-3)
+	assert.Equal(t, r.ResidualDF, 17)
+	assertClose(t, r.RSquared, 0.9136, 1e-4)
+	assertClose(t, r.AdjRSquared, 0.8983, 1e-4)
+	assertClose(t, r.F.Statistic, 59.9, 0.05)
+
+	golden(t, "stackloss_summary.golden", r.String())
+	assert.Equal(t, summary.(OlsSummary).String(), func() string {
+		r, _ := NewReport(summary)
+		return r.String()
+	}())
+}
+
+func TestReportUnlabeled(t *testing.T) {
+	r, err := NewReport(summary)
+	assert.Equal(t, nil, err)
+	names := []string{"(Intercept)", "x0", "x1", "x2"}
+	for j, c := range r.Coefficients {
+		assert.Equal(t, c.Name, names[j])
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	assert.Equal(t, significance(0.0002), "***")
+	assert.Equal(t, significance(0.002), "**")
+	assert.Equal(t, significance(0.02), "*")
+	assert.Equal(t, significance(0.07), ".")
+	assert.Equal(t, significance(0.3), "")
+	assert.Equal(t, formatPValue(1e-20), "<2e-16")
+}
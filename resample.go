@@ -0,0 +1,87 @@
+package glasso
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+)
+
+// resampleChunk is the number of resamples a worker of a resampler takes at
+// a time between checks of its context.
+const resampleChunk = 16
+
+// splitMix is the SplitMix64 generator of Steele, Lea & Flood (2014), a
+// rand.Source64 whose state is a single word, so that a stream of its own is
+// cheap to seed for every resample.
+type splitMix struct {
+	state uint64
+}
+
+func (s *splitMix) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+func (s *splitMix) Int63() int64 { return int64(s.Uint64() >> 1) }
+
+func (s *splitMix) Seed(seed int64) { s.state = uint64(seed) }
+
+// A resampler shares the resamples of a randomized procedure, such as
+// Bootstrap or PermutationTest, out among o.workers() workers. Each resample
+// draws from a stream of its own, seeded from a master seed and its index,
+// so the draws of a resample, and so what the procedure returns, are the same
+// whatever the number of workers and the order they run the resamples in.
+// The master seed is the first draw of o.rng(), so WithSeed and an equally
+// seeded WithRandSource agree, and procedures sharing a source continue its
+// stream.
+type resampler struct {
+	ctx     context.Context
+	workers int
+	master  uint64
+}
+
+func newResampler(o options) *resampler {
+	return &resampler{ctx: o.ctx, workers: o.workers(), master: uint64(o.rng().Int63())}
+}
+
+// stream returns the random numbers of resample b.
+func (r *resampler) stream(b int) *rand.Rand {
+	seeder := splitMix{state: r.master + uint64(b)*0xd1b54a32d192ed03}
+	return rand.New(&splitMix{state: seeder.Uint64()})
+}
+
+// run calls work for each resample b of [0, count) with its stream. work is
+// called concurrently for different resamples, so it may write to the
+// elements they index without locking. The workers stop early if the context
+// is done, returning ctx.Err(). Otherwise every resample is run, and the
+// error is that of the lowest resample that returned one, so that it too
+// doesn't depend on how the resamples were shared out.
+func (r *resampler) run(count int, work func(b int, rng *rand.Rand) error) error {
+	if count <= 0 {
+		return r.ctx.Err()
+	}
+	var (
+		mu     sync.Mutex
+		failed = count
+		first  error
+	)
+	err := parallelChunks(r.ctx, r.workers, 0, count-1, resampleChunk, func(start, end int) bool {
+		for b := start; b <= end; b++ {
+			if err := work(b, r.stream(b)); err != nil {
+				mu.Lock()
+				if b < failed {
+					failed, first = b, err
+				}
+				mu.Unlock()
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return first
+}
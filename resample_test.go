@@ -0,0 +1,84 @@
+package glasso
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestSplitMix(t *testing.T) {
+	// the reference outputs of SplitMix64 seeded with 1234567
+	s := &splitMix{}
+	s.Seed(1234567)
+	for _, want := range []uint64{6457827717110365317, 3203168211198807973, 9817491932198370423, 4593380528125082431, 16408922859458223821} {
+		assert.Equal(t, want, s.Uint64())
+	}
+}
+
+func TestResamplingParallelism(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	// the pinned percentile intervals of the stackloss coefficients for a
+	// seed of 42, which don't depend on the number of workers or processors
+	want := [][2]float64{
+		{-57.15608846731581, -19.40945748045072},
+		{0.37658542599458794, 1.0446604221503497},
+		{0.40472724282532324, 2.2791049627770144},
+		{-0.44101760515610394, 0.06808177798753193},
+	}
+	_, null, err := PermutationTest(summary, 3, 999, WithSeed(42), WithParallelism(1))
+	assert.Equal(t, nil, err)
+	ransac := func(opts ...Option) []bool {
+		_, s, err := NewRansacTrainer(&RansacConfig{Threshold: 2, Trials: 200}, opts...).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		return s.(*RansacSummary).Inliers()
+	}
+	inliers := ransac(WithSeed(42), WithParallelism(1))
+	for _, procs := range []int{1, 4} {
+		runtime.GOMAXPROCS(procs)
+		for _, workers := range []int{1, 8} {
+			b, err := Bootstrap(summary, 1000, WithSeed(42), WithParallelism(workers))
+			assert.Equal(t, nil, err)
+			intervals, err := b.PercentileInterval(0.95)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, want, intervals)
+
+			p, again, err := PermutationTest(summary, 3, 999, WithSeed(42), WithParallelism(workers))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 0.347, p)
+			assert.Equal(t, null, again)
+
+			assert.Equal(t, inliers, ransac(WithSeed(42), WithParallelism(workers)))
+		}
+	}
+
+	// the resamples draw independent streams
+	r := newResampler(newOptions([]Option{WithSeed(42)}))
+	assert.NotEqual(t, r.stream(0).Int63(), r.stream(1).Int63())
+	assert.Equal(t, r.stream(5).Int63(), r.stream(5).Int63())
+	assert.NotEqual(t, r.stream(0).Int63(), newResampler(newOptions([]Option{WithSeed(43)})).stream(0).Int63())
+}
+
+func TestResamplerError(t *testing.T) {
+	// the error is that of the lowest failing resample, however they're shared out
+	for _, workers := range []int{1, 8} {
+		r := newResampler(newOptions([]Option{WithParallelism(workers)}))
+		ran := make([]bool, 100)
+		err := r.run(100, func(b int, _ *rand.Rand) error {
+			ran[b] = true
+			if b%30 == 29 {
+				return TooFewObservationsError
+			}
+			if b == 40 {
+				return DimensionError
+			}
+			return nil
+		})
+		assert.Equal(t, TooFewObservationsError, err)
+		for b, ok := range ran {
+			assert.T(t, ok, b)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// A ResponseTransform is a transformation of the response that a model is
+// fit on, WithResponseTransform, and its inverse, which takes predictions
+// back to the scale of the response.
+type ResponseTransform struct {
+	Name    string
+	Forward func(float64) float64
+	Inverse func(float64) float64
+}
+
+// LogTransform fits log(y), which needs a positive response.
+var LogTransform = ResponseTransform{Name: "log", Forward: math.Log, Inverse: math.Exp}
+
+// knownTransforms are the transforms that a model can be encoded with, by name.
+var knownTransforms = map[string]ResponseTransform{LogTransform.Name: LogTransform}
+
+// BackTransform is a way of taking a prediction on the scale of a
+// transformed response back to the scale of the response.
+type BackTransform int
+
+const (
+	// SmearingBackTransform is Duan's (1983) smearing estimate of the mean
+	// response, \frac{1}{n} \sum_i g^{-1}(\hat y + e_i) over the residuals of
+	// the fit, the default. For the log it is \exp(\hat y) times the mean of
+	// \exp(e_i), which corrects the bias of the naive back-transform without
+	// assuming the errors are normal.
+	SmearingBackTransform BackTransform = iota
+	// NaiveBackTransform is g^{-1}(\hat y), which for the log is the median,
+	// not the mean, of a lognormal response.
+	NaiveBackTransform
+)
+
+func (b BackTransform) String() string {
+	switch b {
+	case SmearingBackTransform:
+		return "smearing"
+	case NaiveBackTransform:
+		return "naive"
+	}
+	return fmt.Sprintf("BackTransform(%d)", int(b))
+}
+
+// responseTransform is the transform of a model's response, with the
+// residuals of the fit on its scale that smearing averages over.
+type responseTransform struct {
+	ResponseTransform
+	residuals []float64
+}
+
+// transformResponse applies t to y, checking that it maps every value to a
+// finite one.
+func transformResponse(t ResponseTransform, y []float64) ([]float64, error) {
+	if t.Forward == nil || t.Inverse == nil {
+		return nil, fmt.Errorf("the %q response transform needs both a transform and its inverse", t.Name)
+	}
+	z := make([]float64, len(y))
+	for i, v := range y {
+		z[i] = t.Forward(v)
+		if math.IsNaN(z[i]) || math.IsInf(z[i], 0) {
+			return nil, fmt.Errorf("the %q transform of response %d, %v, is %v", t.Name, i, v, z[i])
+		}
+	}
+	return z, nil
+}
+
+// ResponseTransform returns the transform of the response that the model was
+// fit on, and whether it has one.
+func (o *OLS) ResponseTransform() (ResponseTransform, bool) {
+	if o.response == nil {
+		return ResponseTransform{}, false
+	}
+	return o.response.ResponseTransform, true
+}
+
+// PredictResponse predicts the response at x on its original scale, for a
+// model fit WithResponseTransform, where Predict predicts it on the scale of
+// the transform. The back-transform is by method.
+func (o *OLS) PredictResponse(x []float64, method BackTransform) (float64, error) {
+	if o.response == nil {
+		return 0, fmt.Errorf("the model wasn't fit on a transformed response")
+	}
+	if len(x) != o.predictors() {
+		return 0, DimensionError
+	}
+	yhat := o.Predict(x)
+	switch method {
+	case NaiveBackTransform:
+		return o.response.Inverse(yhat), nil
+	case SmearingBackTransform:
+		mean := 0.0
+		for _, e := range o.response.residuals {
+			mean += o.response.Inverse(yhat + e)
+		}
+		return mean / float64(len(o.response.residuals)), nil
+	}
+	return 0, fmt.Errorf("unknown back-transform %v", method)
+}
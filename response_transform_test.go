@@ -0,0 +1,135 @@
+package glasso
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// lognormalData draws y = exp(1 + 0.5x + e) for x uniform on [0, 4] and
+// normal errors e with a standard deviation of sigma.
+func lognormalData(n int, sigma float64, seed int64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		x := 4 * rng.Float64()
+		rows[i] = []float64{x}
+		response[i] = math.Exp(1 + 0.5*x + sigma*rng.NormFloat64())
+	}
+	return rows, response
+}
+
+func TestSmearing(t *testing.T) {
+	const sigma = 0.8
+	rows, response := lognormalData(5000, sigma, 3)
+	m, s, err := NewOlsTrainer(WithResponseTransform(LogTransform)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	model := m.(*OLS)
+	transform, ok := model.ResponseTransform()
+	assert.T(t, ok)
+	assert.Equal(t, "log", transform.Name)
+
+	// the fit and Predict are on the log scale
+	logs := make([]float64, len(response))
+	for i, v := range response {
+		logs[i] = math.Log(v)
+	}
+	_, direct, err := NewOlsTrainer().Train(NewDataFrame(rows), logs)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, s.Coefficients(), direct.Coefficients(), 1e-12)
+	assertClose(t, model.Predict([]float64{2}), direct.Coefficients()[0]+2*direct.Coefficients()[1], 1e-12)
+
+	// the mean of a lognormal response is exp(mu + sigma^2 / 2), which the
+	// smearing estimate recovers and the naive exp(mu), its median, doesn't
+	factor := 0.0
+	for _, e := range s.Residuals() {
+		factor += math.Exp(e)
+	}
+	factor /= float64(len(response))
+	for _, x := range []float64{0.5, 2, 3.5} {
+		mean := math.Exp(1 + 0.5*x + sigma*sigma/2)
+		smeared, err := model.PredictResponse([]float64{x}, SmearingBackTransform)
+		assert.Equal(t, nil, err)
+		naive, err := model.PredictResponse([]float64{x}, NaiveBackTransform)
+		assert.Equal(t, nil, err)
+		assertClose(t, naive, math.Exp(model.Predict([]float64{x})), 1e-12)
+		assertClose(t, smeared/naive, factor, 1e-10)
+		assert.T(t, math.Abs(smeared/mean-1) < 0.05, smeared, mean)
+		assert.T(t, naive/mean < 0.8, naive, mean)
+	}
+
+	// the mean over many samples of the prediction error
+	var smearedBias, naiveBias float64
+	const samples = 200
+	for k := 0; k < samples; k++ {
+		rows, response := lognormalData(50, sigma, int64(100+k))
+		m, _, err := NewOlsTrainer(WithResponseTransform(LogTransform)).Train(NewDataFrame(rows), response)
+		assert.Equal(t, nil, err)
+		smeared, err := m.(*OLS).PredictResponse([]float64{2}, SmearingBackTransform)
+		assert.Equal(t, nil, err)
+		naive, err := m.(*OLS).PredictResponse([]float64{2}, NaiveBackTransform)
+		assert.Equal(t, nil, err)
+		mean := math.Exp(2 + sigma*sigma/2)
+		smearedBias += (smeared/mean - 1) / samples
+		naiveBias += (naive/mean - 1) / samples
+	}
+	assert.T(t, math.Abs(smearedBias) < 0.05, smearedBias)
+	assert.T(t, naiveBias < -0.2, naiveBias)
+
+	// a model without an observation smears over its own residuals
+	without, err := model.Without(0)
+	assert.Equal(t, nil, err)
+	_, ok = without.ResponseTransform()
+	assert.T(t, ok)
+	smeared, err := without.PredictResponse([]float64{2}, SmearingBackTransform)
+	assert.Equal(t, nil, err)
+	factor = 0.0
+	for _, e := range without.Residuals() {
+		factor += math.Exp(e)
+	}
+	assertClose(t, smeared, math.Exp(without.Predict([]float64{2}))*factor/float64(without.NumObs()), 1e-9)
+
+	// and the transform is saved with the model
+	b, err := json.Marshal(model)
+	assert.Equal(t, nil, err)
+	decoded := &OLS{}
+	assert.Equal(t, nil, json.Unmarshal(b, decoded))
+	want, err := model.PredictResponse([]float64{2}, SmearingBackTransform)
+	assert.Equal(t, nil, err)
+	got, err := decoded.PredictResponse([]float64{2}, SmearingBackTransform)
+	assert.Equal(t, nil, err)
+	assertClose(t, got, want, 1e-12)
+}
+
+func TestResponseTransformErrors(t *testing.T) {
+	// the log of a response that isn't positive is an error at fit time
+	for _, v := range []float64{0, -1} {
+		response := append([]float64(nil), y...)
+		response[4] = v
+		_, _, err := NewOlsTrainer(WithResponseTransform(LogTransform)).Train(NewDataFrame(data), response)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, err := NewOlsTrainer(WithResponseTransform(ResponseTransform{Name: "half", Forward: math.Sqrt})).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+
+	// a transform of the caller's own, which can't be encoded
+	sqrt := ResponseTransform{Name: "sqrt", Forward: math.Sqrt, Inverse: func(z float64) float64 { return z * z }}
+	m, _, err := NewOlsTrainer(WithResponseTransform(sqrt)).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	naive, err := m.(*OLS).PredictResponse(data[0], NaiveBackTransform)
+	assert.Equal(t, nil, err)
+	assertClose(t, naive, math.Pow(m.Predict(data[0]), 2), 1e-12)
+	_, err = json.Marshal(m)
+	assert.NotEqual(t, nil, err)
+
+	_, err = m.(*OLS).PredictResponse(data[0][:2], NaiveBackTransform)
+	assert.Equal(t, DimensionError, err)
+	_, err = m.(*OLS).PredictResponse(data[0], BackTransform(7))
+	assert.NotEqual(t, nil, err)
+	_, err = model.(*OLS).PredictResponse(data[0], SmearingBackTransform)
+	assert.NotEqual(t, nil, err)
+}
@@ -1,8 +1,10 @@
 package glasso
 
 import (
-	"github.com/gonum/matrix"
-	"github.com/gonum/matrix/mat64"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
 )
 
 // Beta_ridge = min( \sum(y_i - \beta_0  - \sum x_ij * \beta_j)^2 + \lambda \sum \beta_j^2)
@@ -14,31 +16,53 @@ import (
 // X = UDVt
 //
 // We can solve for Beta_ridge using:
-// X Beta_ridge = U D(D2 + \lambda I)−1D UT y
+// Beta_ridge = V D(D2 + \lambda I)−1 UT y
 //
-// type ridgeSummary struct {
-// 	x          *DataFrame
-// 	lambda     float64
-// 	n, c       int
-// 	fitted     []float64
-// 	residuals  []float64
-// 	response   []float64
-// 	beta_ridge []float64
-// }
+// which stays stable for large lambda and collinear X, since X'X + \lambda I is never formed.
 type Ridge struct {
-	betas []float64
+	betas       []float64
+	noIntercept bool
 }
 
 func (r *Ridge) Predict(x []float64) float64 {
+	if r.noIntercept {
+		return sum(prod(x, r.betas))
+	}
 	return r.betas[0] + sum(prod(x, r.betas[1:]))
 }
 
+// RidgeConfig specifies the penalty of a ridge regression.
+type RidgeConfig struct {
+	Lambda float64 // amount of shrinkage
+
+	// Standardize scales each predictor to unit standard deviation (with
+	// divisor n) before the penalty is applied, as MASS::lm.ridge and glmnet
+	// do. The coefficients are always returned on the original scale.
+	Standardize bool
+
+	// PenalizeIntercept shrinks the intercept along with the other
+	// coefficients. By default it is left unpenalized, by centering the
+	// predictors and the response.
+	PenalizeIntercept bool
+
+	// NoIntercept fits through the origin, without centering, and scales
+	// the predictors by their root mean squares if Standardize is set.
+	NoIntercept bool
+}
+
 // x = n x c
 // U = n x c
 // D = c x c
 // V = c x c
 type ridgeTrainer struct {
-	lambda float64
+	config *RidgeConfig
+}
+
+// NewRidgeTrainer returns a Trainer for ridge regression with the given penalty.
+func NewRidgeTrainer(config *RidgeConfig) Trainer {
+	return &ridgeTrainer{
+		config: config,
+	}
 }
 
 // Ridge regression for model shrinkage
@@ -46,63 +70,277 @@ type ridgeTrainer struct {
 // Larger lambda equals more shrinkage of the variables.
 // lambda -> 0 equals the least squares solution
 // lambda -> oo means all coeffients equal 0
+//
+// The DataFrame is not modified, and the summary is a *RidgeSummary, whose
+// design includes the intercept column.
 func (r *ridgeTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
-	n, c := x.Data().Dims()
-	var (
-		fitted     = make([]float64, n)
-		residuals  = make([]float64, n)
-		beta_ridge = make([]float64, c)
-	)
-
-	// standarize matrix and have y_bar = 0
-	x.Normalize()
-	y = subtractMean(y)
-	response := y
-	svd := &mat64.SVD{}
-	svd.Factorize(mat64.DenseCopyOf(x.Data()), matrix.SVDFull)
-
-	U := (&mat64.Dense{})
-	U.UFromSVD(svd)
-	V := (&mat64.Dense{})
-	V.VFromSVD(svd)
+	if r.config == nil {
+		return nil, nil, fmt.Errorf("config not set")
+	}
+	lambda := r.config.Lambda
+	if !(lambda >= 0) || math.IsInf(lambda, 0) {
+		return nil, nil, fmt.Errorf("penalty %v is not a non-negative number", lambda)
+	}
+	problem, err := newRidgeProblem(x, y, r.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	betas, fitted, residuals, edf := problem.solve(lambda)
+	return &Ridge{
+		betas:       betas,
+		noIntercept: r.config.NoIntercept,
+	}, &RidgeSummary{
+		data:      problem.design,
+		lambda:    lambda,
+		edf:       edf,
+		fitted:    fitted,
+		residuals: residuals,
+		response:  append([]float64(nil), y...),
+		betas:     betas,
+	}, nil
+}
+
+// ridgeProblem is the centered and scaled design of a ridge regression and its
+// singular value decomposition, from which the fit for any penalty is found
+// without refactorizing.
+type ridgeProblem struct {
+	config        *RidgeConfig
+	design        *DataFrame // the predictors, with an intercept column first if there is one
+	y             []float64
+	means, scales []float64
+	ybar          float64
+	offset        int // 1 if the first column of the SVD is a penalized intercept
+	v             *mat.Dense
+	d             []float64
+	uty           *mat.VecDense // U'(y - \bar{y})
+}
+
+func newRidgeProblem(x *DataFrame, y []float64, config *RidgeConfig) (*ridgeProblem, error) {
+	n, c := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if config.NoIntercept && config.PenalizeIntercept {
+		return nil, fmt.Errorf("a fit without an intercept has no intercept to penalize")
+	}
+
+	// center (unless the intercept is penalized, or there is none) and scale
+	// the predictors
+	intercept := !config.NoIntercept
+	center := intercept && !config.PenalizeIntercept
+	offset := 0
+	if config.PenalizeIntercept {
+		offset = 1
+	}
+	means, scales := make([]float64, c), rep(1, c)
+	z := mat.NewDense(n, c+offset, nil)
+	if config.PenalizeIntercept {
+		z.SetCol(0, rep(1, n))
+	}
+	names := x.names()
+	for j := 0; j < c; j++ {
+		col := x.GetCol(j)
+		mu := mean(col)
+		switch {
+		case config.Standardize && intercept:
+			scales[j] = math.Sqrt(centralMoment(col, 2))
+			if scales[j] == 0 {
+				return nil, fmt.Errorf("column %q is constant and can't be standardized", names[j])
+			}
+		case config.Standardize:
+			scales[j] = math.Sqrt(sum(prod(col, col)) / float64(n))
+			if scales[j] == 0 {
+				return nil, fmt.Errorf("column %q is identically zero and can't be standardized", names[j])
+			}
+		}
+		if center {
+			means[j] = mu
+		}
+		z.SetCol(j+offset, multSlice(subSlice(col, means[j]), 1/scales[j]))
+	}
+	ybar := 0.0
+	if center {
+		ybar = mean(y)
+	}
+
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(z, mat.SVDThin); !ok {
+		return nil, fmt.Errorf("singular value decomposition of the %d x %d design failed", n, c+offset)
+	}
+	u, v := &mat.Dense{}, &mat.Dense{}
+	svd.UTo(u)
+	svd.VTo(v)
 	d := svd.Values(nil)
+	uty := mat.NewVecDense(len(d), nil)
+	uty.MulVec(u.T(), mat.NewVecDense(n, subSlice(y, ybar)))
+
+	design := x.Copy()
+	design.labels = x.Labels()
+	if intercept {
+		design.PushCol(rep(1, n))
+	}
+	return &ridgeProblem{
+		config: config,
+		design: design,
+		y:      y,
+		means:  means,
+		scales: scales,
+		ybar:   ybar,
+		offset: offset,
+		v:      v,
+		d:      d,
+		uty:    uty,
+	}, nil
+}
 
-	// convert the c x c diagonal matrix D into a mat64.Dense matrix
-	D := mat64.NewDense(c, c, rep(0.0, c*c))
-	for i := 0; i < c; i++ {
-		val := d[i] / (d[i] + r.lambda)
-		D.Set(i, i, val)
+// solve returns the coefficients on the original scale, the fitted values,
+// the residuals and the effective degrees of freedom of the fit with penalty
+// lambda.
+func (p *ridgeProblem) solve(lambda float64) (betas, fitted, residuals []float64, edf float64) {
+	// gamma = V diag(d / (d^2 + lambda)) U'y, and tr(H) = \sum d^2 / (d^2 + lambda)
+	shrunk := mat.NewVecDense(len(p.d), nil)
+	for k, dk := range p.d {
+		if dk == 0 {
+			continue
+		}
+		shrunk.SetVec(k, dk/(dk*dk+lambda)*p.uty.At(k, 0))
+		edf += dk * dk / (dk*dk + lambda)
 	}
+	c := len(p.means)
+	gamma := mat.NewVecDense(c+p.offset, nil)
+	gamma.MulVec(p.v, shrunk)
 
-	// solve for beta_ridge
-	betaMat := &mat64.Dense{}
-	betaMat.Mul(V, D)
-	betaMat.Mul(betaMat, U.T())
-	Y := mat64.NewDense(len(y), 1, y)
-	betaMat.Mul(betaMat, Y)
+	// back to the original scale
+	if !p.config.NoIntercept {
+		betas = make([]float64, c+1)
+		if p.config.PenalizeIntercept {
+			betas[0] = gamma.At(0, 0)
+		} else {
+			betas[0] = p.ybar
+			edf++
+		}
+		for j := 0; j < c; j++ {
+			betas[j+1] = gamma.At(j+p.offset, 0) / p.scales[j]
+			betas[0] -= p.means[j] * betas[j+1]
+		}
+	} else {
+		betas = make([]float64, c)
+		for j := range betas {
+			betas[j] = gamma.At(j, 0) / p.scales[j]
+		}
+	}
+	n := len(p.y)
+	fitted = make([]float64, n)
+	residuals = make([]float64, n)
+	for i := range fitted {
+		fitted[i] = sum(prod(p.design.GetRow(i), betas))
+		residuals[i] = p.y[i] - fitted[i]
+	}
+	return betas, fitted, residuals, edf
+}
 
-	// save beta values
-	beta_ridge = mat64.Col(nil, 0, betaMat)
+// RidgeSummary summarizes a ridge regression. The diagnostics that assume a
+// least squares fit, such as LeveragePoints, don't apply to it.
+type RidgeSummary struct {
+	data      *DataFrame
+	lambda    float64
+	edf       float64
+	fitted    []float64
+	residuals []float64
+	response  []float64
+	betas     []float64
+}
 
-	// find the fitted values : X * \beta_ridge
-	fittedMat := &mat64.Dense{}
-	fittedMat.Mul(x.Data(), betaMat)
-	fitted = mat64.Col(nil, 0, fittedMat)
+func (r *RidgeSummary) Data() *DataFrame        { return r.data }
+func (r *RidgeSummary) Coefficients() []float64 { return r.betas }
+func (r *RidgeSummary) Residuals() []float64    { return r.residuals }
+func (r *RidgeSummary) Yhat() []float64         { return r.fitted }
+func (r *RidgeSummary) Response() []float64     { return r.response }
+func (r *RidgeSummary) Lambda() float64         { return r.lambda }
 
-	// get residuals
-	fittedMat.Sub(fittedMat, Y)
-	residuals = mat64.Col(nil, 0, fittedMat)
+func (r *RidgeSummary) SumOfSquares() float64 {
+	return sum(prod(r.residuals, r.residuals))
+}
 
-	return &Ridge{
-			betas: beta_ridge,
-		}, OlsSummary{
-			data: x,
-			//lambda:     r.lambda,
-			n:         n,
-			p:         c,
-			fitted:    fitted,
-			residuals: residuals,
-			response:  response,
-			betas:     beta_ridge,
-		}, nil
+// EffectiveDF returns the effective degrees of freedom of the fit, the trace of
+// the linear smoother H_\lambda with \hat{y} = H_\lambda y:
+//
+// df(\lambda) = \sum_k \frac{d_k^2}{d_k^2 + \lambda}
+//
+// over the singular values d_k of the (centered and scaled) design, plus one for
+// an unpenalized intercept. It makes the summary a Smoother, whose AIC and BIC
+// count the coefficients by it.
+func (r *RidgeSummary) EffectiveDF() float64 {
+	return r.edf
+}
+
+// GCV returns the generalized cross-validation score of the fit,
+//
+// GCV = \frac{n RSS}{(n - df(\lambda))^2}
+//
+// an approximation to the leave-one-out prediction error that is minimized to choose lambda.
+func (r *RidgeSummary) GCV() float64 {
+	n := float64(len(r.residuals))
+	return n * r.SumOfSquares() / math.Pow(n-r.edf, 2)
+}
+
+// RidgeTraceResult is the ridge trace of RidgeTrace: the fits of a ridge
+// regression over a grid of penalties.
+type RidgeTraceResult struct {
+	Lambdas []float64
+
+	// Coefficients holds a column of the coefficients, in the order of
+	// RidgeSummary's, for each penalty.
+	Coefficients *mat.Dense
+
+	EffectiveDF []float64 // as RidgeSummary.EffectiveDF, for each penalty
+	GCV         []float64 // as RidgeSummary.GCV, for each penalty
+}
+
+// RidgeTrace fits the ridge regression that config describes with each of the
+// penalties in lambdas, whatever config.Lambda is, as MASS::lm.ridge does for
+// a vector of lambda. The design is centered, scaled and decomposed once, so
+// that each penalty costs only a product with the singular vectors. A nil
+// config fits with the defaults of RidgeConfig.
+func RidgeTrace(x *DataFrame, y []float64, lambdas []float64, config *RidgeConfig) (*RidgeTraceResult, error) {
+	if config == nil {
+		config = &RidgeConfig{}
+	}
+	if len(lambdas) == 0 {
+		return nil, fmt.Errorf("no penalties to fit")
+	}
+	for _, lambda := range lambdas {
+		if !(lambda >= 0) || math.IsInf(lambda, 0) {
+			return nil, fmt.Errorf("penalty %v is not a non-negative number", lambda)
+		}
+	}
+	problem, err := newRidgeProblem(x, y, config)
+	if err != nil {
+		return nil, err
+	}
+	n := float64(len(y))
+	trace := &RidgeTraceResult{
+		Lambdas:      append([]float64(nil), lambdas...),
+		Coefficients: mat.NewDense(problem.design.Cols(), len(lambdas), nil),
+		EffectiveDF:  make([]float64, len(lambdas)),
+		GCV:          make([]float64, len(lambdas)),
+	}
+	for k, lambda := range lambdas {
+		betas, _, residuals, edf := problem.solve(lambda)
+		trace.Coefficients.SetCol(k, betas)
+		trace.EffectiveDF[k] = edf
+		trace.GCV[k] = n * sum(prod(residuals, residuals)) / math.Pow(n-edf, 2)
+	}
+	return trace, nil
+}
+
+// BestLambda returns the penalty of the trace with the least GCV, and its
+// index in Lambdas; the first of them if there is a tie.
+func (r *RidgeTraceResult) BestLambda() (lambda float64, index int) {
+	for k, g := range r.GCV {
+		if g < r.GCV[index] {
+			index = k
+		}
+	}
+	return r.Lambdas[index], index
 }
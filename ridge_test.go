@@ -0,0 +1,247 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// augmentedRidge solves the ridge problem for the design x, with the columns in
+// penalized shrunk, as the least squares problem [x; sqrt(lambda) I] beta = [y; 0].
+func augmentedRidge(t *testing.T, x *mat.Dense, y []float64, lambda float64, penalized []int) []float64 {
+	n, c := x.Dims()
+	aug := mat.NewDense(n+len(penalized), c, nil)
+	aug.Copy(x)
+	for k, j := range penalized {
+		aug.Set(n+k, j, math.Sqrt(lambda))
+	}
+	response := make([]float64, n+len(penalized))
+	copy(response, y)
+	fit, err := leastSquares(aug, mat.NewDense(len(response), 1, response))
+	assert.Equal(t, nil, err)
+	return fit.betas
+}
+
+func TestRidgePenalizedIntercept(t *testing.T) {
+	m, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: 25, PenalizeIntercept: true}).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	x := NewDataFrame(data)
+	x.PushCol(rep(1, len(y)))
+	expected := augmentedRidge(t, x.X, y, 25, []int{0, 1, 2, 3})
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, expected[j], 1e-10)
+	}
+	assertClose(t, m.Predict(data[4]), s.Yhat()[4], 1e-10)
+	assertClose(t, s.Residuals()[4], y[4]-s.Yhat()[4], 1e-10)
+}
+
+func TestRidgeUnpenalizedIntercept(t *testing.T) {
+	_, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: 40}).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+
+	// the slopes of the centered problem, and an intercept through the means
+	x := NewDataFrame(data)
+	for j := 0; j < x.Cols(); j++ {
+		x.X.SetCol(j, subtractMean(x.GetCol(j)))
+	}
+	slopes := augmentedRidge(t, x.X, subtractMean(y), 40, []int{0, 1, 2})
+	betas := s.Coefficients()
+	intercept := mean(y)
+	for j, b := range slopes {
+		assertClose(t, betas[j+1], b, 1e-10)
+		intercept -= b * mean(NewDataFrame(data).GetCol(j))
+	}
+	assertClose(t, betas[0], intercept, 1e-9)
+
+	// the fitted values are the shrunk projection, so their mean is that of y
+	assertClose(t, mean(s.Yhat()), mean(y), 1e-10)
+}
+
+func TestRidgeStandardize(t *testing.T) {
+	_, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: 3, Standardize: true}).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+
+	// fitting the standardized predictors directly, then rescaling
+	scaled := NewDataFrame(longley)
+	scales := make([]float64, scaled.Cols())
+	for j := range scales {
+		col := scaled.GetCol(j)
+		scales[j] = math.Sqrt(centralMoment(col, 2))
+		scaled.X.SetCol(j, multSlice(col, 1/scales[j]))
+	}
+	_, direct, err := NewRidgeTrainer(&RidgeConfig{Lambda: 3}).Train(scaled, longleyY)
+	assert.Equal(t, nil, err)
+	for j, b := range direct.Coefficients()[1:] {
+		assertClose(t, s.Coefficients()[j+1], b/scales[j], 1e-8*math.Abs(b/scales[j]))
+	}
+	for i, f := range direct.Yhat() {
+		assertClose(t, s.Yhat()[i], f, 1e-8)
+	}
+}
+
+func TestRidgeLimits(t *testing.T) {
+	// no penalty is least squares
+	_, s, err := NewRidgeTrainer(&RidgeConfig{}).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for j, b := range s.Coefficients() {
+		assertClose(t, b, summary.Coefficients()[j], 1e-9)
+	}
+	assertClose(t, s.(*RidgeSummary).EffectiveDF(), 4, 1e-12)
+
+	// an enormous one shrinks the slopes to zero, leaving the mean
+	_, s, err = NewRidgeTrainer(&RidgeConfig{Lambda: 1e15, Standardize: true}).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	for _, b := range s.Coefficients()[1:] {
+		assertClose(t, b, 0, 1e-10)
+	}
+	assertClose(t, s.Coefficients()[0], mean(y), 1e-9)
+	assertClose(t, s.(*RidgeSummary).EffectiveDF(), 1, 1e-9)
+
+	// tr(H) runs from the number of coefficients to that of the unpenalized
+	// ones, none when the intercept is penalized or there is none
+	for _, config := range []RidgeConfig{{}, {PenalizeIntercept: true}, {NoIntercept: true}} {
+		p, unpenalized := 4.0, 1.0
+		if config.PenalizeIntercept || config.NoIntercept {
+			unpenalized = 0
+		}
+		if config.NoIntercept {
+			p = 3
+		}
+		config.Lambda = 1e-12
+		_, s, err = NewRidgeTrainer(&config).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertClose(t, s.(*RidgeSummary).EffectiveDF(), p, 1e-9)
+		config.Lambda = 1e15
+		_, s, err = NewRidgeTrainer(&config).Train(NewDataFrame(data), y)
+		assert.Equal(t, nil, err)
+		assertClose(t, s.(*RidgeSummary).EffectiveDF(), unpenalized, 1e-9)
+	}
+}
+
+func TestRidgeEffectiveDF(t *testing.T) {
+	lambda := 12.0
+	_, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: lambda}).Train(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	r := s.(*RidgeSummary)
+
+	// tr(H) with H = 11'/n + Z(Z'Z + lambda I)^-1 Z' for the centered design Z
+	z := NewDataFrame(data)
+	for j := 0; j < z.Cols(); j++ {
+		z.X.SetCol(j, subtractMean(z.GetCol(j)))
+	}
+	ztz, inv := &mat.Dense{}, &mat.Dense{}
+	ztz.Mul(z.X.T(), z.X)
+	for j := 0; j < z.Cols(); j++ {
+		ztz.Set(j, j, ztz.At(j, j)+lambda)
+	}
+	assert.Equal(t, nil, inv.Inverse(ztz))
+	zinv, h := &mat.Dense{}, &mat.Dense{}
+	zinv.Mul(z.X, inv)
+	h.Mul(zinv, z.X.T())
+	edf := 1 + mat.Trace(h)
+	assertClose(t, r.EffectiveDF(), edf, 1e-10)
+	assert.T(t, r.EffectiveDF() < 4)
+
+	n := float64(len(y))
+	assertClose(t, r.GCV(), n*r.SumOfSquares()/((n-edf)*(n-edf)), 1e-10)
+	assert.Equal(t, r.Lambda(), lambda)
+}
+
+func TestRidgeCollinear(t *testing.T) {
+	// duplicated and wide designs, where OLS has no unique solution, are fine
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{row[0], row[0], row[1]}
+	}
+	_, s, err := NewRidgeTrainer(&RidgeConfig{Lambda: 5}).Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	betas := s.Coefficients()
+	assertClose(t, betas[1], betas[2], 1e-10)
+
+	wide := make([][]float64, 3)
+	for i := range wide {
+		wide[i] = data[i]
+	}
+	_, s, err = NewRidgeTrainer(&RidgeConfig{Lambda: 1}).Train(NewDataFrame(wide), y[:3])
+	assert.Equal(t, nil, err)
+	assert.T(t, s.(*RidgeSummary).EffectiveDF() < 3)
+}
+
+func TestRidgeInvalid(t *testing.T) {
+	_, _, err := NewRidgeTrainer(nil).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewRidgeTrainer(&RidgeConfig{Lambda: -1}).Train(NewDataFrame(data), y)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewRidgeTrainer(&RidgeConfig{Lambda: 1}).Train(NewDataFrame(data), y[1:])
+	assert.Equal(t, DimensionError, err)
+
+	rows := make([][]float64, len(data))
+	for i, row := range data {
+		rows[i] = []float64{row[0], 3}
+	}
+	_, _, err = NewRidgeTrainer(&RidgeConfig{Lambda: 1, Standardize: true}).Train(NewDataFrame(rows), y)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestRidgeTrace(t *testing.T) {
+	lambdas := []float64{0, 0.1, 1, 5, 20, 100, 1000, 1e6}
+	for _, config := range []*RidgeConfig{nil, {Standardize: true}, {PenalizeIntercept: true}} {
+		trace, err := RidgeTrace(NewDataFrame(data), y, lambdas, config)
+		assert.Equal(t, nil, err)
+		rows, cols := trace.Coefficients.Dims()
+		assert.Equal(t, 4, rows)
+		assert.Equal(t, len(lambdas), cols)
+
+		// each penalty is the fit NewRidgeTrainer makes with it
+		c := RidgeConfig{}
+		if config != nil {
+			c = *config
+		}
+		for k, lambda := range lambdas {
+			c.Lambda = lambda
+			_, s, err := NewRidgeTrainer(&c).Train(NewDataFrame(data), y)
+			assert.Equal(t, nil, err)
+			r := s.(*RidgeSummary)
+			assertCloseSlices(t, mat.Col(nil, k, trace.Coefficients), r.Coefficients(), 1e-12)
+			assertClose(t, trace.EffectiveDF[k], r.EffectiveDF(), 1e-12)
+			assertClose(t, trace.GCV[k], r.GCV(), 1e-12)
+		}
+	}
+
+	// no penalty is least squares, and the norm of the penalized coefficients
+	// shrinks as the penalty grows
+	trace, err := RidgeTrace(NewDataFrame(data), y, lambdas, nil)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, mat.Col(nil, 0, trace.Coefficients), summary.Coefficients(), 1e-9)
+	assertClose(t, trace.EffectiveDF[0], 4, 1e-12)
+	norm := math.Inf(1)
+	for k := range lambdas {
+		slopes := mat.Col(nil, k, trace.Coefficients)[1:]
+		next := math.Sqrt(sum(prod(slopes, slopes)))
+		assert.T(t, next < norm, k)
+		norm = next
+		if k > 0 {
+			assert.T(t, trace.EffectiveDF[k] < trace.EffectiveDF[k-1])
+		}
+	}
+
+	lambda, best := trace.BestLambda()
+	assert.Equal(t, lambdas[best], lambda)
+	for _, g := range trace.GCV {
+		assert.T(t, trace.GCV[best] <= g)
+	}
+}
+
+func TestRidgeTraceInvalid(t *testing.T) {
+	_, err := RidgeTrace(NewDataFrame(data), y, nil, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = RidgeTrace(NewDataFrame(data), y, []float64{1, -1}, nil)
+	assert.NotEqual(t, nil, err)
+	_, err = RidgeTrace(NewDataFrame(data), y[1:], []float64{1}, nil)
+	assert.Equal(t, DimensionError, err)
+	_, err = RidgeTrace(NewDataFrame(data), y, []float64{1}, &RidgeConfig{NoIntercept: true, PenalizeIntercept: true})
+	assert.NotEqual(t, nil, err)
+}
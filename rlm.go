@@ -0,0 +1,286 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// DefaultHuberK is the tuning constant of Huber's psi with 95% efficiency at the normal.
+	DefaultHuberK = 1.345
+
+	// DefaultBisquareC is the tuning constant of Tukey's bisquare with 95% efficiency at the normal.
+	DefaultBisquareC = 4.685
+)
+
+// A Psi is the influence function \psi of an M-estimator, which downweights
+// observations with large scaled residuals u = e / s.
+type Psi interface {
+	Weight(u float64) float64     // the IRLS weight \psi(u) / u
+	Derivative(u float64) float64 // \psi'(u)
+}
+
+// Huber returns Huber's psi, \psi(u) = max(-k, min(k, u)), which is linear for
+// small residuals, so it is least squares in the middle and least absolute
+// deviations in the tails.
+func Huber(k float64) Psi { return huber{k} }
+
+type huber struct{ k float64 }
+
+func (h huber) Weight(u float64) float64 {
+	if math.Abs(u) <= h.k {
+		return 1
+	}
+	return h.k / math.Abs(u)
+}
+
+func (h huber) Derivative(u float64) float64 {
+	if math.Abs(u) <= h.k {
+		return 1
+	}
+	return 0
+}
+
+// Bisquare returns Tukey's bisquare psi, \psi(u) = u (1 - (u / c)^2)^2 for
+// |u| < c and zero beyond, which rejects gross outliers entirely.
+func Bisquare(c float64) Psi { return bisquare{c} }
+
+type bisquare struct{ c float64 }
+
+func (b bisquare) Weight(u float64) float64 {
+	if math.Abs(u) >= b.c {
+		return 0
+	}
+	t := 1 - (u/b.c)*(u/b.c)
+	return t * t
+}
+
+func (b bisquare) Derivative(u float64) float64 {
+	t := (u / b.c) * (u / b.c)
+	if t >= 1 {
+		return 0
+	}
+	return (1 - t) * (1 - 5*t)
+}
+
+// RLM is a robust linear model fit by M-estimation.
+type RLM struct {
+	betas []float64
+}
+
+func (r *RLM) Predict(x []float64) float64 {
+	return r.betas[0] + sum(prod(x, r.betas[1:]))
+}
+
+type rlmTrainer struct {
+	psi  Psi
+	opts options
+}
+
+// NewRobustTrainer returns a Trainer for robust regression by M-estimation
+// with the given psi function.
+func NewRobustTrainer(psi Psi, opts ...Option) Trainer {
+	return &rlmTrainer{
+		psi:  psi,
+		opts: newOptions(opts),
+	}
+}
+
+// NewHuberTrainer returns a Trainer for robust regression with Huber's psi
+// and tuning constant k, usually DefaultHuberK.
+func NewHuberTrainer(k float64, opts ...Option) Trainer {
+	return NewRobustTrainer(Huber(k), opts...)
+}
+
+// Train fits the model by iteratively reweighted least squares from the least
+// squares fit, as MASS::rlm does. Each iteration estimates the scale by the
+// MAD of the residuals, median|e| / 0.6745, weights the observations by
+// \psi(e / s) / (e / s), and refits by weighted least squares. The fit has
+// converged when the relative change in the residuals,
+//
+// \sqrt{\sum (e_old - e)^2 / \sum e_old^2}
+//
+// is below the tolerance. A fit that doesn't converge within the maximum
+// number of iterations is returned with Converged false.
+//
+// The DataFrame is not modified, and the summary is an *RLMSummary, whose
+// design includes the intercept column.
+func (r *rlmTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	if r.psi == nil {
+		return nil, nil, fmt.Errorf("psi function not set")
+	}
+	if err := r.opts.checkIterations(); err != nil {
+		return nil, nil, err
+	}
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	design := x.Copy()
+	design.labels = x.Labels()
+	design.PushCol(rep(1, n))
+	p := design.Cols()
+	if n <= p {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+
+	fit, err := leastSquares(design.X, mat.NewDense(n, 1, y))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		weights    = rep(1, n)
+		scale      float64
+		iterations int
+		converged  bool
+	)
+	for iterations < r.opts.maxIter {
+		iterations++
+		scale = mad(fit.residuals)
+		if scale == 0 {
+			return nil, nil, fmt.Errorf("median absolute residual is zero, so the scale can't be estimated")
+		}
+		for i, e := range fit.residuals {
+			weights[i] = r.psi.Weight(e / scale)
+		}
+
+		wx, wy := mat.DenseCopyOf(design.X), mat.NewDense(n, 1, nil)
+		for i, w := range weights {
+			row := wx.RawRowView(i)
+			for j := range row {
+				row[j] *= math.Sqrt(w)
+			}
+			wy.Set(i, 0, math.Sqrt(w)*y[i])
+		}
+		next, err := leastSquares(wx, wy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		residuals := make([]float64, n)
+		change, size := 0.0, 0.0
+		for i := range residuals {
+			residuals[i] = y[i] - sum(prod(design.X.RawRowView(i), next.betas))
+			change += (fit.residuals[i] - residuals[i]) * (fit.residuals[i] - residuals[i])
+			size += fit.residuals[i] * fit.residuals[i]
+		}
+		fit = &lsFit{betas: next.betas, residuals: residuals}
+		if math.Sqrt(change/math.Max(size, 1e-20)) < r.opts.tolerance {
+			converged = true
+			break
+		}
+	}
+
+	vcov, err := rlmVCov(design.X, fit.residuals, scale, r.psi)
+	if err != nil {
+		return nil, nil, err
+	}
+	fitted := make([]float64, n)
+	for i, e := range fit.residuals {
+		fitted[i] = y[i] - e
+	}
+
+	return &RLM{
+		betas: fit.betas,
+	}, &RLMSummary{
+		data:       design,
+		betas:      fit.betas,
+		fitted:     fitted,
+		residuals:  fit.residuals,
+		response:   append([]float64(nil), y...),
+		weights:    weights,
+		scale:      scale,
+		vcov:       vcov,
+		iterations: iterations,
+		converged:  converged,
+	}, nil
+}
+
+// mad returns the median absolute residual scaled to estimate the standard
+// deviation at the normal, median|e| / 0.6745. The residuals are not centered,
+// since they already have a location of zero.
+func mad(residuals []float64) float64 {
+	abs := make([]float64, len(residuals))
+	for i, e := range residuals {
+		abs[i] = math.Abs(e)
+	}
+	return median(abs) / 0.6745
+}
+
+// rlmVCov returns the covariance of the coefficients as summary.rlm does,
+//
+// V = \kappa^2 \frac{s^2 \sum \psi(u_i)^2 / (n - p)}{(\frac{1}{n} \sum \psi'(u_i))^2} (X'X)^-1
+//
+// with Huber's correction \kappa = 1 + p Var(\psi') / (n \bar{\psi'}^2).
+func rlmVCov(x *mat.Dense, residuals []float64, scale float64, psi Psi) (*DataFrame, error) {
+	n, p := x.Dims()
+	s2, derivatives := 0.0, make([]float64, n)
+	for i, e := range residuals {
+		u := e / scale
+		s2 += math.Pow(scale*u*psi.Weight(u), 2) / float64(n-p)
+		derivatives[i] = psi.Derivative(u)
+	}
+	mn := mean(derivatives)
+	if mn == 0 {
+		return nil, fmt.Errorf("psi function is flat at every residual")
+	}
+	kappa := 1 + float64(p)*variance(derivatives)/(float64(n)*mn*mn)
+
+	xtx := &mat.Dense{}
+	xtx.Mul(x.T(), x)
+	vcov := &mat.Dense{}
+	if err := vcov.Inverse(xtx); err != nil {
+		return nil, err
+	}
+	vcov.Scale(s2*kappa*kappa/(mn*mn), vcov)
+	symmetrize(vcov)
+	return MatToDF(vcov), nil
+}
+
+// RLMSummary summarizes a robust linear model. The diagnostics that assume a
+// least squares fit, such as VarCov and LeveragePoints, don't apply to it.
+type RLMSummary struct {
+	data       *DataFrame
+	betas      []float64
+	fitted     []float64
+	residuals  []float64
+	response   []float64
+	weights    []float64
+	scale      float64
+	vcov       *DataFrame
+	iterations int
+	converged  bool
+}
+
+func (r *RLMSummary) Data() *DataFrame        { return r.data }
+func (r *RLMSummary) Coefficients() []float64 { return r.betas }
+func (r *RLMSummary) Residuals() []float64    { return r.residuals }
+func (r *RLMSummary) Yhat() []float64         { return r.fitted }
+func (r *RLMSummary) Response() []float64     { return r.response }
+
+func (r *RLMSummary) SumOfSquares() float64 {
+	return sum(prod(r.residuals, r.residuals))
+}
+
+// Weights returns the weight of each observation in the final weighted least
+// squares fit, from one for observations that aren't downweighted toward zero
+// for outliers.
+func (r *RLMSummary) Weights() []float64 { return r.weights }
+
+// Scale returns the robust estimate of the residual scale in the final iteration.
+func (r *RLMSummary) Scale() float64 { return r.scale }
+
+// VarCov returns the variance-covariance matrix of the coefficients.
+func (r *RLMSummary) VarCov() *DataFrame { return r.vcov }
+
+// StandardErrors returns the standard errors of the coefficients.
+func (r *RLMSummary) StandardErrors() []float64 { return StandardErrors(r.vcov) }
+
+// Iterations returns the number of IRLS iterations of the fit.
+func (r *RLMSummary) Iterations() int { return r.iterations }
+
+// Converged reports whether the residuals converged within the maximum number of iterations.
+func (r *RLMSummary) Converged() bool { return r.converged }
@@ -0,0 +1,153 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+)
+
+// rlmDefaults are the convergence settings of MASS::rlm
+var rlmDefaults = []Option{WithTolerance(1e-4), WithMaxIterations(20)}
+
+func TestHuberTrainer(t *testing.T) {
+	// summary(rlm(stack.loss ~ ., stackloss))
+	model, summary, err := NewHuberTrainer(DefaultHuberK, rlmDefaults...).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summary.(*RLMSummary)
+	betas := []float64{-41.0265, 0.8294, 0.9261, -0.1278}
+	se := []float64{9.8073, 0.1112, 0.3034, 0.1289}
+	for j, b := range s.Coefficients() {
+		if math.Abs(b-betas[j]) > 5e-5 {
+			t.Errorf("coefficient %d: got %v, want %v", j, b, betas[j])
+		}
+		if got := s.StandardErrors()[j]; math.Abs(got-se[j]) > 5e-5 {
+			t.Errorf("standard error %d: got %v, want %v", j, got, se[j])
+		}
+	}
+	if math.Abs(s.Scale()-2.441) > 5e-4 {
+		t.Errorf("scale: got %v, want 2.441", s.Scale())
+	}
+	if !s.Converged() {
+		t.Errorf("not converged after %d iterations", s.Iterations())
+	}
+
+	// observations 3, 4 and 21 are downweighted, and the rest are not
+	downweighted := map[int]float64{2: 0.786, 3: 0.505, 20: 0.368}
+	for i, w := range s.Weights() {
+		want, ok := downweighted[i]
+		if !ok {
+			want = 1
+		}
+		if math.Abs(w-want) > 5e-4 {
+			t.Errorf("weight %d: got %v, want %v", i, w, want)
+		}
+	}
+	for i, fitted := range s.Yhat() {
+		if got := model.Predict(data[i]); math.Abs(got-fitted) > 1e-10 {
+			t.Errorf("prediction %d: got %v, want %v", i, got, fitted)
+		}
+	}
+}
+
+func TestBisquareTrainer(t *testing.T) {
+	// rlm(stack.loss ~ ., stackloss, psi = psi.bisquare)
+	_, summary, err := NewRobustTrainer(Bisquare(DefaultBisquareC), rlmDefaults...).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summary.(*RLMSummary)
+	betas := []float64{-42.2853, 0.9275, 0.6507, -0.1123}
+	for j, b := range s.Coefficients() {
+		if math.Abs(b-betas[j]) > 5e-5 {
+			t.Errorf("coefficient %d: got %v, want %v", j, b, betas[j])
+		}
+	}
+	// observation 21 is all but rejected
+	if w := s.Weights()[20]; w > 0.01 {
+		t.Errorf("weight of observation 21: got %v", w)
+	}
+}
+
+func TestRobustFixedPoint(t *testing.T) {
+	// at convergence the estimating equations \sum x_i \psi(e_i / s) = 0 hold
+	for name, psi := range map[string]Psi{"huber": Huber(DefaultHuberK), "bisquare": Bisquare(DefaultBisquareC)} {
+		_, summary, err := NewRobustTrainer(psi, WithTolerance(1e-12)).Train(NewDataFrame(longley), longleyY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := summary.(*RLMSummary)
+		x := s.Data()
+		for j := 0; j < x.Cols(); j++ {
+			score, size := 0.0, 0.0
+			for i, e := range s.Residuals() {
+				u := e / s.Scale()
+				score += x.X.At(i, j) * u * psi.Weight(u)
+				size += math.Abs(x.X.At(i, j))
+			}
+			if math.Abs(score) > 1e-8*size {
+				t.Errorf("%s: estimating equation %d is %v", name, j, score)
+			}
+		}
+		if got := mad(s.Residuals()); math.Abs(got-s.Scale()) > 1e-8*s.Scale() {
+			t.Errorf("%s: scale %v is not the MAD of the residuals, %v", name, s.Scale(), got)
+		}
+	}
+}
+
+func TestRobustOutlier(t *testing.T) {
+	// y = 1 + 2x with small errors, and a gross outlier at the end
+	noise := []float64{0.1, -0.2, 0.05, 0.15, -0.1, 0.2, -0.05, -0.15, 0.1, -0.1, 0.05, 0}
+	var x [][]float64
+	var response []float64
+	for i, e := range noise {
+		x = append(x, []float64{float64(i)})
+		response = append(response, 1+2*float64(i)+e)
+	}
+	response[len(response)-1] += 30
+
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(x), response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, psi := range map[string]Psi{"huber": Huber(DefaultHuberK), "bisquare": Bisquare(DefaultBisquareC)} {
+		_, summary, err := NewRobustTrainer(psi).Train(NewDataFrame(x), response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := summary.(*RLMSummary)
+		if got, ls := math.Abs(s.Coefficients()[1]-2), math.Abs(ols.Coefficients()[1]-2); got > 0.05 || got > ls/10 {
+			t.Errorf("%s: slope %v, least squares %v", name, s.Coefficients()[1], ols.Coefficients()[1])
+		}
+		if w := s.Weights()[len(noise)-1]; w > 0.05 {
+			t.Errorf("%s: outlier weight %v", name, w)
+		}
+	}
+}
+
+func TestRobustConvergence(t *testing.T) {
+	_, summary, err := NewHuberTrainer(DefaultHuberK, WithMaxIterations(1)).Train(NewDataFrame(data), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := summary.(*RLMSummary); s.Converged() || s.Iterations() != 1 {
+		t.Errorf("converged %v after %d iterations", s.Converged(), s.Iterations())
+	}
+}
+
+func TestRobustInvalid(t *testing.T) {
+	if _, _, err := NewRobustTrainer(nil).Train(NewDataFrame(data), y); err == nil {
+		t.Error("no psi: expected an error")
+	}
+	if _, _, err := NewHuberTrainer(DefaultHuberK).Train(NewDataFrame(data), y[1:]); err != DimensionError {
+		t.Errorf("short response: got %v", err)
+	}
+	if _, _, err := NewHuberTrainer(DefaultHuberK).Train(NewDataFrame(data[:4]), y[:4]); err == nil {
+		t.Error("too few observations: expected an error")
+	}
+	// an exact fit has no residual scale
+	x := NewDataFrame([][]float64{{1}, {2}, {3}, {4}})
+	if _, _, err := NewHuberTrainer(DefaultHuberK).Train(x, []float64{3, 5, 7, 9}); err == nil {
+		t.Error("exact fit: expected an error")
+	}
+}
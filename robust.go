@@ -0,0 +1,352 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// HCKind selects the weighting of squared residuals in a heteroskedasticity-consistent
+// covariance estimate.
+type HCKind int
+
+const (
+	HC0 HCKind = iota // White's estimator, e_i^2
+	HC1               // e_i^2 n / (n - p)
+	HC2               // e_i^2 / (1 - h_ii)
+	HC3               // e_i^2 / (1 - h_ii)^2, approximately the jackknife
+)
+
+func (k HCKind) String() string {
+	switch k {
+	case HC0:
+		return "HC0"
+	case HC1:
+		return "HC1"
+	case HC2:
+		return "HC2"
+	case HC3:
+		return "HC3"
+	}
+	return fmt.Sprintf("HCKind(%d)", int(k))
+}
+
+// RobustVCov calculates a heteroskedasticity-consistent (sandwich) variance-covariance
+// matrix of the regression coefficients
+//
+// V = (X'X)^-1 X' \Omega X (X'X)^-1, \Omega = diag(\omega_i)
+//
+// with the weights \omega_i given by kind. Observations with a leverage of one
+// are fit exactly, so their residuals are zero and they contribute nothing,
+// rather than the 0/0 that HC2 and HC3 would otherwise produce.
+func RobustVCov(m Summary, kind HCKind) (*DataFrame, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	residuals := m.Residuals()
+	h, err := LeveragePoints(m)
+	if err != nil {
+		return nil, err
+	}
+
+	omega := make([]float64, n)
+	for i, e := range residuals {
+		if isUnitLeverage(h[i]) {
+			continue
+		}
+		switch kind {
+		case HC0:
+			omega[i] = e * e
+		case HC1:
+			omega[i] = e * e * float64(n) / float64(n-p)
+		case HC2:
+			omega[i] = e * e / (1 - h[i])
+		case HC3:
+			omega[i] = e * e / ((1 - h[i]) * (1 - h[i]))
+		default:
+			return nil, fmt.Errorf("unknown covariance estimator %v", kind)
+		}
+	}
+	return sandwich(m, func(u breadScores) *mat.Dense {
+		meat := mat.NewDense(p, p, nil)
+		for i := 0; i < n; i++ {
+			addOuter(meat, u.c.RawRowView(i), u.c.RawRowView(i), omega[i])
+		}
+		return meat
+	})
+}
+
+// ScoreMatrix returns the n x p matrix of the contributions of the
+// observations to the gradient of the least squares criterion, the ith row
+// x_i e_i, with the columns named and ordered as the coefficients, as
+// sandwich::estfun does for lm fits. For a weighted fit, whose design and
+// residuals are scaled by the square roots of the weights, the rows are
+// w_i x_i e_i. The columns of a fit with an intercept sum to zero, the normal
+// equations X'e = 0, up to rounding.
+//
+// The sandwich estimators are (X'X)^-1 M (X'X)^-1 for sums M of outer products
+// of the scores: over single observations for RobustVCov, over lagged pairs
+// for NeweyWestVCov and over the sums within clusters for ClusteredVCov.
+func ScoreMatrix(m Summary) *DataFrame {
+	x, residuals := m.Data(), m.Residuals()
+	u := mat.DenseCopyOf(x.X)
+	for i, e := range residuals {
+		row := u.RawRowView(i)
+		for j := range row {
+			row[j] *= e
+		}
+	}
+	s := MatToDF(u)
+	s.labels = CoefficientNames(m)
+	return s
+}
+
+// breadScores are the scores of a fit multiplied by the bread (X'X)^-1,
+// ((X'X)^-1 x_i)' e_i, kept as the rows of C = X (X'X)^-1 = Q R^-T and the
+// residuals e_i.
+type breadScores struct {
+	c         *mat.Dense
+	residuals []float64
+}
+
+// addOuter adds w u_i u_i' to meat.
+func (u breadScores) addOuter(meat *mat.Dense, i int, w float64) {
+	addOuter(meat, u.c.RawRowView(i), u.c.RawRowView(i), w*u.residuals[i]*u.residuals[i])
+}
+
+// addCross adds w (u_i u_j' + u_j u_i') to meat.
+func (u breadScores) addCross(meat *mat.Dense, i, j int, w float64) {
+	scale := w * u.residuals[i] * u.residuals[j]
+	addOuter(meat, u.c.RawRowView(i), u.c.RawRowView(j), scale)
+	addOuter(meat, u.c.RawRowView(j), u.c.RawRowView(i), scale)
+}
+
+// addTo adds u_i to sum.
+func (u breadScores) addTo(sum []float64, i int) {
+	for j, v := range u.c.RawRowView(i) {
+		sum[j] += v * u.residuals[i]
+	}
+}
+
+// sandwich assembles (X'X)^-1 M (X'X)^-1 as the sum of outer products meat of
+// the scores multiplied by the bread, which it returns as a p x p matrix.
+func sandwich(m Summary, meat func(u breadScores) *mat.Dense) (*DataFrame, error) {
+	rinv, err := rInverse(m)
+	if err != nil {
+		return nil, err
+	}
+	q := &mat.Dense{}
+	q.Mul(m.Data().X, rinv)
+	c := &mat.Dense{}
+	c.Mul(q, rinv.T())
+
+	v := meat(breadScores{c: c, residuals: m.Residuals()})
+	symmetrize(v)
+	return MatToDF(v), nil
+}
+
+// addOuter adds scale * x y' to m.
+func addOuter(m *mat.Dense, x, y []float64, scale float64) {
+	if scale == 0 {
+		return
+	}
+	for j, xj := range x {
+		row := m.RawRowView(j)
+		for k, yk := range y {
+			row[k] += scale * xj * yk
+		}
+	}
+}
+
+// symmetrize replaces the square matrix m with (m + m') / 2, discarding rounding asymmetry.
+func symmetrize(m *mat.Dense) {
+	p, _ := m.Dims()
+	for j := 0; j < p; j++ {
+		for k := j + 1; k < p; k++ {
+			v := (m.At(j, k) + m.At(k, j)) / 2
+			m.Set(j, k, v)
+			m.Set(k, j, v)
+		}
+	}
+}
+
+// StandardErrors returns the square roots of the diagonal of a coefficient
+// variance-covariance matrix, such as the one from VarCov or RobustVCov.
+func StandardErrors(vcov *DataFrame) []float64 {
+	se := make([]float64, vcov.Rows())
+	for j := range se {
+		se[j] = math.Sqrt(vcov.X.At(j, j))
+	}
+	return se
+}
+
+// NeweyWestVCov calculates the heteroskedasticity and autocorrelation consistent
+// variance-covariance matrix of Newey & West (1987) for time-ordered observations
+//
+// S = \sum_t u_t u_t' + \sum_{l=1}^L w_l \sum_{t=l+1}^n (u_t u_{t-l}' + u_{t-l} u_t')
+// V = (X'X)^-1 S (X'X)^-1
+//
+// where u_t = x_t e_t and the Bartlett weights w_l = 1 - l / (L + 1) keep S
+// positive semi-definite. A negative lags uses the bandwidth floor(4 (n/100)^{2/9}).
+// This matches sandwich::NeweyWest(fit, lag = L, prewhite = FALSE, adjust = FALSE).
+func NeweyWestVCov(m Summary, lags int) (*DataFrame, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	if lags < 0 {
+		lags = neweyWestLags(n)
+	}
+	if lags >= n {
+		return nil, fmt.Errorf("%d lags is too many for %d observations", lags, n)
+	}
+
+	return sandwich(m, func(u breadScores) *mat.Dense {
+		meat := mat.NewDense(p, p, nil)
+		for t := 0; t < n; t++ {
+			u.addOuter(meat, t, 1)
+		}
+		for l := 1; l <= lags; l++ {
+			w := 1 - float64(l)/float64(lags+1)
+			for t := l; t < n; t++ {
+				u.addCross(meat, t, t-l, w)
+			}
+		}
+		return meat
+	})
+}
+
+// neweyWestLags is the automatic bandwidth floor(4 (n/100)^{2/9}).
+func neweyWestLags(n int) int {
+	return int(math.Floor(4 * math.Pow(float64(n)/100, 2.0/9)))
+}
+
+// fewClusters is the number of clusters below which cluster-robust standard
+// errors are known to be biased downwards.
+const fewClusters = 20
+
+// ClusterVCov is a cluster-robust variance-covariance matrix of the regression coefficients.
+type ClusterVCov struct {
+	VCov     *DataFrame
+	Clusters int // number of distinct clusters (of the coarser dimension for two-way clustering)
+
+	// Warnings describe conditions under which the estimate may be unreliable.
+	Warnings []string
+}
+
+// ClusteredVCov calculates the one-way cluster-robust variance-covariance matrix
+// of the regression coefficients, allowing arbitrary correlation between the
+// errors of observations that share a cluster label
+//
+// V = c (X'X)^-1 \sum_g s_g s_g' (X'X)^-1, s_g = \sum_{i \in g} x_i e_i
+// c = \frac{G}{G - 1} \frac{n - 1}{n - p}
+//
+// with the small-sample correction c used by Stata. At least two clusters are
+// required, and a warning is included with fewer than 20.
+func ClusteredVCov(m Summary, cluster []int) (*ClusterVCov, error) {
+	n := m.Data().Rows()
+	if len(cluster) != n {
+		return nil, DimensionError
+	}
+	groups := clusterIndex(cluster)
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("cluster-robust covariance needs at least 2 clusters, got %d", len(groups))
+	}
+	vcov, err := clusterSandwich(m, groups)
+	if err != nil {
+		return nil, err
+	}
+	return newClusterVCov(vcov, len(groups)), nil
+}
+
+// TwoWayClusteredVCov calculates the two-way cluster-robust variance-covariance
+// matrix of Cameron, Gelbach & Miller (2011), allowing correlation between
+// observations that share either label, by inclusion-exclusion:
+//
+// V = V_1 + V_2 - V_{1 \cap 2}
+//
+// where V_{1 \cap 2} clusters on the pairs of labels. Each term uses its own
+// small-sample correction. Unlike the one-way estimate the result is not
+// guaranteed to be positive semi-definite.
+func TwoWayClusteredVCov(m Summary, first, second []int) (*ClusterVCov, error) {
+	n := m.Data().Rows()
+	if len(first) != n || len(second) != n {
+		return nil, DimensionError
+	}
+
+	// label each observation by its pair of clusters
+	pairs := make(map[[2]int]int)
+	both := make([]int, n)
+	for i := range both {
+		key := [2]int{first[i], second[i]}
+		if _, ok := pairs[key]; !ok {
+			pairs[key] = len(pairs)
+		}
+		both[i] = pairs[key]
+	}
+
+	var terms [3]*DataFrame
+	g := n
+	for k, labels := range [][]int{first, second, both} {
+		groups := clusterIndex(labels)
+		if len(groups) < 2 {
+			return nil, fmt.Errorf("cluster-robust covariance needs at least 2 clusters, got %d", len(groups))
+		}
+		if len(groups) < g {
+			g = len(groups)
+		}
+		vcov, err := clusterSandwich(m, groups)
+		if err != nil {
+			return nil, err
+		}
+		terms[k] = vcov
+	}
+
+	v := &mat.Dense{}
+	v.Add(terms[0].X, terms[1].X)
+	v.Sub(v, terms[2].X)
+	return newClusterVCov(MatToDF(v), g), nil
+}
+
+func newClusterVCov(vcov *DataFrame, clusters int) *ClusterVCov {
+	c := &ClusterVCov{VCov: vcov, Clusters: clusters}
+	if clusters < fewClusters {
+		c.Warnings = append(c.Warnings,
+			fmt.Sprintf("%d clusters is too few for cluster-robust standard errors to be reliable", clusters))
+	}
+	return c
+}
+
+// clusterIndex groups the observation indices by cluster label, in order of first appearance.
+func clusterIndex(cluster []int) [][]int {
+	index := make(map[int]int)
+	var groups [][]int
+	for i, label := range cluster {
+		g, ok := index[label]
+		if !ok {
+			g = len(groups)
+			index[label] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// clusterSandwich forms the corrected one-way cluster-robust sandwich for the given clusters.
+func clusterSandwich(m Summary, groups [][]int) (*DataFrame, error) {
+	n, p := m.Data().Rows(), m.Data().Cols()
+	g := float64(len(groups))
+	correction := g / (g - 1) * float64(n-1) / float64(n-p)
+
+	return sandwich(m, func(u breadScores) *mat.Dense {
+		meat := mat.NewDense(p, p, nil)
+		score := make([]float64, p)
+		for _, group := range groups {
+			for j := range score {
+				score[j] = 0
+			}
+			for _, i := range group {
+				u.addTo(score, i)
+			}
+			addOuter(meat, score, score, correction)
+		}
+		return meat
+	})
+}
@@ -0,0 +1,345 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// explicitHC builds (X'X)^-1 X' diag(omega) X (X'X)^-1 with dense matrices.
+func explicitHC(t *testing.T, s Summary, omega []float64) *mat.Dense {
+	x := s.Data().X
+	n, _ := x.Dims()
+	xtx, err := xtxInverse(s)
+	assert.Equal(t, nil, err)
+	diag := mat.NewDense(n, n, nil)
+	for i, w := range omega {
+		diag.Set(i, i, w)
+	}
+	xo, meat := &mat.Dense{}, &mat.Dense{}
+	xo.Mul(x.T(), diag)
+	meat.Mul(xo, x)
+	bread, v := &mat.Dense{}, &mat.Dense{}
+	bread.Mul(xtx, meat)
+	v.Mul(bread, xtx)
+	return v
+}
+
+func TestRobustVCov(t *testing.T) {
+	e := summary.Residuals()
+	h, err := LeveragePoints(summary)
+	assert.Equal(t, nil, err)
+	n, p := 21.0, 4.0
+	weights := map[HCKind]func(i int) float64{
+		HC0: func(i int) float64 { return e[i] * e[i] },
+		HC1: func(i int) float64 { return e[i] * e[i] * n / (n - p) },
+		HC2: func(i int) float64 { return e[i] * e[i] / (1 - h[i]) },
+		HC3: func(i int) float64 { return e[i] * e[i] / math.Pow(1-h[i], 2) },
+	}
+	for kind, weight := range weights {
+		omega := make([]float64, len(e))
+		for i := range omega {
+			omega[i] = weight(i)
+		}
+		expected := explicitHC(t, summary, omega)
+
+		vc, err := RobustVCov(summary, kind)
+		assert.Equal(t, nil, err)
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				assertClose(t, vc.X.At(j, k), expected.At(j, k), 1e-10*math.Abs(expected.At(j, j)))
+				assert.Equal(t, vc.X.At(j, k), vc.X.At(k, j))
+			}
+		}
+	}
+
+	_, err = RobustVCov(summary, HCKind(7))
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, HC2.String(), "HC2")
+}
+
+func TestScoreMatrix(t *testing.T) {
+	_, wls, err := NewWlsTrainer(stacklossWeights).Train(NewDataFrame(data, stacklossLabels), y)
+	assert.Equal(t, nil, err)
+	for _, m := range []Summary{summary, wls} {
+		u := ScoreMatrix(m)
+		n, p := u.Rows(), u.Cols()
+		assert.Equal(t, m.Data().Rows(), n)
+		assert.Equal(t, CoefficientNames(m), u.Labels())
+		x := m.Data()
+		for i := 0; i < n; i++ {
+			for j := 0; j < p; j++ {
+				assert.Equal(t, x.X.At(i, j)*m.Residuals()[i], u.X.At(i, j))
+			}
+		}
+
+		// the normal equations, with an intercept among the coefficients
+		for j := 0; j < p; j++ {
+			col := u.GetCol(j)
+			assertClose(t, sum(col), 0, 1e-9*math.Sqrt(sum(prod(col, col))))
+		}
+
+		// HC0 is the sandwich of the scores, (X'X)^-1 U'U (X'X)^-1
+		xtx, err := xtxInverse(m)
+		assert.Equal(t, nil, err)
+		utu, bread, v := &mat.Dense{}, &mat.Dense{}, &mat.Dense{}
+		utu.Mul(u.X.T(), u.X)
+		bread.Mul(xtx, utu)
+		v.Mul(bread, xtx)
+		hc0, err := RobustVCov(m, HC0)
+		assert.Equal(t, nil, err)
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				assertClose(t, hc0.X.At(j, k), v.At(j, k), 1e-10*math.Abs(v.At(j, j)))
+			}
+		}
+
+		// and the clustered covariance that of their sums within clusters
+		cluster := make([]int, n)
+		sums := mat.NewDense(7, p, nil)
+		for i := range cluster {
+			cluster[i] = i % 7
+			for j := 0; j < p; j++ {
+				sums.Set(cluster[i], j, sums.At(cluster[i], j)+u.X.At(i, j))
+			}
+		}
+		utu.Mul(sums.T(), sums)
+		bread.Mul(xtx, utu)
+		v.Mul(bread, xtx)
+		v.Scale(7.0/6*float64(n-1)/float64(n-p), v)
+		clustered, err := ClusteredVCov(m, cluster)
+		assert.Equal(t, nil, err)
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				assertClose(t, clustered.VCov.X.At(j, k), v.At(j, k), 1e-10*math.Abs(v.At(j, j)))
+			}
+		}
+	}
+}
+
+func TestRobustVCovJackknife(t *testing.T) {
+	// HC3 is the sum of the outer products of the leave-one-out coefficient changes
+	betas := summary.Coefficients()
+	expected := mat.NewDense(4, 4, nil)
+	for i := range data {
+		_, s := looFit(t, i)
+		d := diff(betas, s.Coefficients())
+		addOuter(expected, d, d, 1)
+	}
+
+	vc, err := RobustVCov(summary, HC3)
+	assert.Equal(t, nil, err)
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			assertClose(t, vc.X.At(j, k), expected.At(j, k), 1e-8*expected.At(j, j))
+		}
+	}
+}
+
+func TestRobustVCovUnitLeverage(t *testing.T) {
+	s := unitLeverageSummary(t)
+	for _, kind := range []HCKind{HC0, HC1, HC2, HC3} {
+		vc, err := RobustVCov(s, kind)
+		assert.Equal(t, nil, err)
+		for _, se := range StandardErrors(vc) {
+			assert.T(t, !math.IsNaN(se) && !math.IsInf(se, 0))
+		}
+	}
+}
+
+func TestRobustStandardErrors(t *testing.T) {
+	vc, err := VarCov(summary)
+	assert.Equal(t, nil, err)
+	// summary(lm(stack.loss ~ ., stackloss))
+	expected := []float64{11.8960, 0.1349, 0.3680, 0.1563}
+	for j, se := range StandardErrors(vc) {
+		assertClose(t, se, expected[j], 1e-4)
+	}
+
+	// with heteroskedastic errors the robust estimate is consistent, the classical is not
+	rng := rand.New(rand.NewSource(9))
+	const reps = 500
+	const n = 200
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64()}
+	}
+	var slopes, robust []float64
+	for rep := 0; rep < reps; rep++ {
+		response := make([]float64, n)
+		for i := range response {
+			x := rows[i][0]
+			response[i] = 1 + x + x*x*rng.NormFloat64()
+		}
+		copied := make([][]float64, n)
+		for i := range rows {
+			copied[i] = []float64{rows[i][0]}
+		}
+		_, s, err := NewOlsTrainer().Train(NewDataFrame(copied), response)
+		assert.Equal(t, nil, err)
+		vc, err := RobustVCov(s, HC3)
+		assert.Equal(t, nil, err)
+		slopes = append(slopes, s.Coefficients()[1])
+		robust = append(robust, vc.X.At(1, 1))
+	}
+	assertClose(t, mean(robust)/variance(slopes), 1, 0.2)
+}
+
+func TestNeweyWestVCov(t *testing.T) {
+	// with no lags it is White's estimator
+	hc0, err := RobustVCov(summary, HC0)
+	assert.Equal(t, nil, err)
+	nw, err := NeweyWestVCov(summary, 0)
+	assert.Equal(t, nil, err)
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			assertClose(t, nw.X.At(j, k), hc0.X.At(j, k), 1e-12*hc0.X.At(j, j))
+		}
+	}
+
+	// the meat built from the scores u_t = x_t e_t directly
+	x := summary.Data().X
+	e := summary.Residuals()
+	lags := 3
+	meat := mat.NewDense(4, 4, nil)
+	for s := range e {
+		for r := range e {
+			l := s - r
+			if l < 0 {
+				l = -l
+			}
+			if l > lags {
+				continue
+			}
+			w := 1 - float64(l)/float64(lags+1)
+			addOuter(meat, mat.Row(nil, s, x), mat.Row(nil, r, x), w*e[s]*e[r])
+		}
+	}
+	xtx, err := xtxInverse(summary)
+	assert.Equal(t, nil, err)
+	bread, expected := &mat.Dense{}, &mat.Dense{}
+	bread.Mul(xtx, meat)
+	expected.Mul(bread, xtx)
+
+	nw, err = NeweyWestVCov(summary, lags)
+	assert.Equal(t, nil, err)
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			assertClose(t, nw.X.At(j, k), expected.At(j, k), 1e-10*expected.At(j, j))
+			assert.Equal(t, nw.X.At(j, k), nw.X.At(k, j))
+		}
+	}
+
+	// positive semi-definite
+	sym := mat.NewSymDense(4, nil)
+	for j := 0; j < 4; j++ {
+		for k := j; k < 4; k++ {
+			sym.SetSym(j, k, nw.X.At(j, k))
+		}
+	}
+	eigen := &mat.EigenSym{}
+	assert.T(t, eigen.Factorize(sym, false))
+	for _, v := range eigen.Values(nil) {
+		assert.T(t, v > -1e-12*nw.X.At(0, 0))
+	}
+
+	// the automatic bandwidth is floor(4 (21/100)^(2/9)) = 2
+	assert.Equal(t, neweyWestLags(21), 2)
+	auto, err := NeweyWestVCov(summary, -1)
+	assert.Equal(t, nil, err)
+	two, err := NeweyWestVCov(summary, 2)
+	assert.Equal(t, nil, err)
+	assert.T(t, mat.Equal(auto.X, two.X))
+
+	_, err = NeweyWestVCov(summary, 21)
+	assert.NotEqual(t, nil, err)
+}
+
+func assertMatricesClose(t *testing.T, a, b *mat.Dense, tol float64) {
+	r, c := a.Dims()
+	for j := 0; j < r; j++ {
+		for k := 0; k < c; k++ {
+			assertClose(t, a.At(j, k), b.At(j, k), tol*math.Abs(b.At(j, j)))
+		}
+	}
+}
+
+func TestClusteredVCov(t *testing.T) {
+	// singleton clusters reduce to HC1, since G / (G - 1) (n - 1) / (n - p) = n / (n - p)
+	singletons := make([]int, 21)
+	for i := range singletons {
+		singletons[i] = i
+	}
+	hc1, err := RobustVCov(summary, HC1)
+	assert.Equal(t, nil, err)
+	cl, err := ClusteredVCov(summary, singletons)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, cl.Clusters, 21)
+	assertMatricesClose(t, cl.VCov.X, hc1.X, 1e-12)
+
+	// three clusters, built from the summed scores directly
+	cluster := make([]int, 21)
+	for i := range cluster {
+		cluster[i] = i % 3
+	}
+	x := summary.Data().X
+	e := summary.Residuals()
+	meat := mat.NewDense(4, 4, nil)
+	for g := 0; g < 3; g++ {
+		score := make([]float64, 4)
+		for i := g; i < 21; i += 3 {
+			for j := range score {
+				score[j] += x.At(i, j) * e[i]
+			}
+		}
+		addOuter(meat, score, score, 1.5*20/17)
+	}
+	xtx, err := xtxInverse(summary)
+	assert.Equal(t, nil, err)
+	bread, expected := &mat.Dense{}, &mat.Dense{}
+	bread.Mul(xtx, meat)
+	expected.Mul(bread, xtx)
+
+	cl, err = ClusteredVCov(summary, cluster)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, cl.Clusters, 3)
+	assert.Equal(t, len(cl.Warnings), 1)
+	assertMatricesClose(t, cl.VCov.X, expected, 1e-10)
+
+	_, err = ClusteredVCov(summary, cluster[:20])
+	assert.Equal(t, DimensionError, err)
+	_, err = ClusteredVCov(summary, make([]int, 21))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestTwoWayClusteredVCov(t *testing.T) {
+	first := make([]int, 21)
+	singletons := make([]int, 21)
+	for i := range first {
+		first[i] = i % 4
+		singletons[i] = i
+	}
+
+	// crossing with singletons adds HC1 and takes it away again
+	one, err := ClusteredVCov(summary, first)
+	assert.Equal(t, nil, err)
+	two, err := TwoWayClusteredVCov(summary, first, singletons)
+	assert.Equal(t, nil, err)
+	assertMatricesClose(t, two.VCov.X, one.VCov.X, 1e-10)
+	assert.Equal(t, two.Clusters, 4)
+
+	// and is symmetric in its arguments
+	second := make([]int, 21)
+	for i := range second {
+		second[i] = i / 7
+	}
+	ab, err := TwoWayClusteredVCov(summary, first, second)
+	assert.Equal(t, nil, err)
+	ba, err := TwoWayClusteredVCov(summary, second, first)
+	assert.Equal(t, nil, err)
+	assertMatricesClose(t, ab.VCov.X, ba.VCov.X, 1e-12)
+	assert.Equal(t, ab.Clusters, 3)
+}
@@ -0,0 +1,68 @@
+package glasso
+
+import "fmt"
+
+// checkRowIDs checks the ids of WithRowIDs for n rows of training data: one
+// for each, all different. No ids at all is fine.
+func checkRowIDs(ids []string, n int) error {
+	if ids == nil {
+		return nil
+	}
+	if len(ids) != n {
+		return fmt.Errorf("%w: %d row ids for %d rows", DimensionError, len(ids), n)
+	}
+	seen := make(map[string]int, n)
+	for i, id := range ids {
+		if j, ok := seen[id]; ok {
+			return fmt.Errorf("rows %d and %d have the same id %q", j, i, id)
+		}
+		seen[id] = i
+	}
+	return nil
+}
+
+// selectIDs returns the ids of the given rows, or nil if there are no ids.
+func selectIDs(ids []string, rows []int) []string {
+	if ids == nil {
+		return nil
+	}
+	selected := make([]string, len(rows))
+	for k, i := range rows {
+		selected[k] = ids[i]
+	}
+	return selected
+}
+
+// withoutID returns ids without its i-th entry, or nil if there are no ids.
+func withoutID(ids []string, i int) []string {
+	if ids == nil {
+		return nil
+	}
+	return append(append(make([]string, 0, len(ids)-1), ids[:i]...), ids[i+1:]...)
+}
+
+// rowIDs returns the RowIDs of the observations of m, or nil if it has none.
+func rowIDs(m Summary) []string {
+	if r, ok := m.(interface{ RowIDs() []string }); ok {
+		return r.RowIDs()
+	}
+	return nil
+}
+
+// ByRowID keys values, one for each observation of the model such as its
+// CooksDistance, LeveragePoints or ExternallyStudentizedResiduals, by the
+// RowIDs of the observations. The model must have ids, from WithRowIDs.
+func ByRowID(m Summary, values []float64) (map[string]float64, error) {
+	ids := rowIDs(m)
+	if ids == nil {
+		return nil, fmt.Errorf("the model has no row ids; see WithRowIDs")
+	}
+	if len(values) != len(ids) {
+		return nil, fmt.Errorf("%w: %d values for %d observations", DimensionError, len(values), len(ids))
+	}
+	keyed := make(map[string]float64, len(ids))
+	for k, id := range ids {
+		keyed[id] = values[k]
+	}
+	return keyed, nil
+}
@@ -0,0 +1,208 @@
+package glasso
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// rowIDData returns 40 observations of two predictors, with missing values in
+// rows 3 and 8, an outlier at row 17 and a high leverage point at row 25, and
+// the ids r00, r01, ... of the rows.
+func rowIDData() ([][]float64, []float64, []string) {
+	rows, response := deletionData(rand.New(rand.NewSource(6)), 40, 2)
+	rows[3][0] = math.NaN()
+	response[8] = math.NaN()
+	response[17] += 12
+	rows[25] = []float64{9, 1}
+	response[25] = 1 + 9 + 2
+	ids := make([]string, len(rows))
+	for i := range ids {
+		ids[i] = fmt.Sprintf("r%02d", i)
+	}
+	return rows, response, ids
+}
+
+func TestRowIDs(t *testing.T) {
+	rows, response, ids := rowIDData()
+	m, s, err := NewOlsTrainer(WithNAPolicy(OmitRows), WithRowIDs(ids)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	model, summary := m.(*OLS), s.(OlsSummary)
+
+	// the observations are known by the rows they came from
+	kept := summary.RowIDs()
+	assert.Equal(t, 38, len(kept))
+	assert.Equal(t, "r02", kept[2])
+	assert.Equal(t, "r04", kept[3])
+	assert.Equal(t, kept, model.RowIDs())
+	for k, i := range summary.RetainedRows() {
+		assert.Equal(t, ids[i], kept[k])
+	}
+
+	// the outlier is flagged, and then deleted by its index
+	flagged, reasons, err := FlagInfluential(summary)
+	assert.Equal(t, nil, err)
+	outlier := -1
+	for _, k := range flagged {
+		if kept[k] == "r17" {
+			outlier = k
+		}
+	}
+	assert.Equal(t, 15, outlier)
+	assert.T(t, len(reasons[outlier]) > 0)
+	refit, err := model.Refit([]int{outlier})
+	assert.Equal(t, nil, err)
+	refitSummary, err := refit.Summary()
+	assert.Equal(t, nil, err)
+	left := refitSummary.RowIDs()
+	assert.Equal(t, 37, len(left))
+	assert.Equal(t, append(append([]string(nil), kept[:15]...), kept[16:]...), left)
+	assert.Equal(t, left, refit.RowIDs())
+
+	// the exact diagnostics of the refit are those of a fit to the rows left
+	r, y := except(rows, response, 3, 8, 17)
+	_, want, err := NewOlsTrainer().Train(NewDataFrame(r), y)
+	assert.Equal(t, nil, err)
+	wantCooks, err := CooksDistance(want)
+	assert.Equal(t, nil, err)
+	cooks, err := CooksDistance(refitSummary)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, cooks, wantCooks, 1e-8)
+	byID, err := ByRowID(refitSummary, cooks)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 37, len(byID))
+	_, ok := byID["r17"]
+	assert.T(t, !ok)
+
+	// and the high leverage point is flagged in the refit by its own id
+	flagged, _, err = FlagInfluential(refitSummary)
+	assert.Equal(t, nil, err)
+	var flaggedIDs []string
+	for _, k := range flagged {
+		flaggedIDs = append(flaggedIDs, left[k])
+	}
+	assert.T(t, strings.Contains(strings.Join(flaggedIDs, " "), "r25"), flaggedIDs)
+	h, err := LeveragePoints(refitSummary)
+	assert.Equal(t, nil, err)
+	leverage, err := ByRowID(refitSummary, h)
+	assert.Equal(t, nil, err)
+	for id, v := range leverage {
+		assert.T(t, id == "r25" || v < leverage["r25"], id)
+	}
+
+	inf, err := InfluenceMeasures(refitSummary)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, left, inf.IDs)
+	var buf bytes.Buffer
+	assert.Equal(t, nil, inf.WriteCSV(&buf))
+	assert.T(t, strings.HasPrefix(buf.String(), "observation,id,leverage,"), buf.String()[:40])
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(refitSummary, &buf, CSVFormat))
+	lines := strings.Split(buf.String(), "\n")
+	assert.T(t, strings.HasPrefix(lines[0], "row,id,y,fitted,"), lines[0])
+	assert.T(t, strings.HasPrefix(lines[16], "18,r18,"), lines[16])
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(refitSummary, &buf, JSONLinesFormat))
+	assert.T(t, strings.HasPrefix(buf.String(), `{"row":0,"id":"r00",`), buf.String()[:40])
+
+	// a model without ids writes none
+	buf.Reset()
+	assert.Equal(t, nil, WriteDiagnostics(want, &buf, CSVFormat))
+	assert.T(t, strings.HasPrefix(buf.String(), "row,y,"))
+	_, err = ByRowID(want, wantCooks)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, []string(nil), want.(OlsSummary).RowIDs())
+}
+
+func TestRowIDsWeighted(t *testing.T) {
+	rows, response, ids := rowIDData()
+	weights := rep(1, len(rows))
+	weights[5] = 0
+	m, s, err := NewWlsTrainer(weights, WithNAPolicy(OmitRows), WithRowIDs(ids)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	kept := s.(OlsSummary).RowIDs()
+	assert.Equal(t, 37, len(kept))
+	assert.Equal(t, []string{"r04", "r06"}, kept[3:5])
+
+	without, err := m.(*OLS).Without(3)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "r06", without.RowIDs()[3])
+}
+
+func TestRowIDsByGroup(t *testing.T) {
+	rows, response, ids := rowIDData()
+	group := make([]string, len(rows))
+	for i := range group {
+		group[i] = []string{"a", "b"}[i%2]
+	}
+	models, _, err := FitByGroup(NewDataFrame(rows), response, group, WithNAPolicy(OmitRows), WithRowIDs(ids))
+	assert.Equal(t, nil, err)
+	// group b is the odd rows, of which row 3 is missing
+	b := models["b"].RowIDs()
+	assert.Equal(t, 19, len(b))
+	assert.Equal(t, []string{"r01", "r05", "r07"}, b[:3])
+	summary, err := models["a"].Summary()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "r10", summary.RowIDs()[4])
+
+	_, _, err = FitByGroup(NewDataFrame(rows), response, group, WithRowIDs(ids[1:]))
+	assert.T(t, errors.Is(err, DimensionError), err)
+}
+
+func TestRowIDsErrors(t *testing.T) {
+	rows, response, ids := rowIDData()
+	_, _, err := NewOlsTrainer(WithNAPolicy(OmitRows), WithRowIDs(ids[:10])).Train(NewDataFrame(rows), response)
+	assert.T(t, errors.Is(err, DimensionError), err)
+	ids[7] = ids[2]
+	_, _, err = NewOlsTrainer(WithNAPolicy(OmitRows), WithRowIDs(ids)).Train(NewDataFrame(rows), response)
+	assert.NotEqual(t, nil, err)
+	_, _, err = NewWlsTrainer(rep(1, len(rows)), WithNAPolicy(OmitRows), WithRowIDs(ids)).Train(NewDataFrame(rows), response)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestRowIDsRoundTrip(t *testing.T) {
+	rows, response, ids := rowIDData()
+	m, s, err := NewOlsTrainer(WithNAPolicy(OmitRows), WithRowIDs(ids)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	refit, err := m.(*OLS).Refit([]int{15})
+	assert.Equal(t, nil, err)
+	summary, err := refit.Summary()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, refit.RowIDs(), summary.RowIDs())
+
+	for _, original := range []*OLS{m.(*OLS), refit} {
+		b, err := json.Marshal(original)
+		assert.Equal(t, nil, err)
+		decoded := &OLS{}
+		assert.Equal(t, nil, json.Unmarshal(b, decoded))
+		var buf bytes.Buffer
+		assert.Equal(t, nil, original.Save(&buf))
+		loaded, err := LoadOLS(&buf)
+		assert.Equal(t, nil, err)
+		for _, model := range []*OLS{decoded, loaded} {
+			assert.Equal(t, original.RowIDs(), model.RowIDs())
+			assert.Equal(t, original.RetainedRows(), model.RetainedRows())
+			// the fit to summarize isn't serialized
+			_, err = model.Summary()
+			assert.NotEqual(t, nil, err)
+		}
+	}
+	assert.Equal(t, s.(OlsSummary).RowIDs(), m.(*OLS).RowIDs())
+
+	// the ids must be one for each observation, all different
+	b, err := json.Marshal(refit)
+	assert.Equal(t, nil, err)
+	for _, invalid := range []string{
+		strings.Replace(string(b), `"ids":["r00",`, `"ids":[`, 1),
+		strings.Replace(string(b), `"ids":["r00","r01"`, `"ids":["r00","r00"`, 1),
+	} {
+		assert.NotEqual(t, nil, json.Unmarshal([]byte(invalid), &OLS{}))
+	}
+}
@@ -0,0 +1,117 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestRobustScales(t *testing.T) {
+	// R's mad, IQR(x) / (2 * qnorm(0.75)) and robustbase::Qn from their
+	// definitions, computed exactly in Python since R isn't available here
+	odd := []float64{1, 2, 3, 4, 100}
+	even := []float64{2.1, 3.7, 0.4, 9.9, 5.5, 1.2, 7.3, 4.8, 6.6, 3.3, 8.1, 0.9}
+	for _, c := range []struct {
+		x            []float64
+		mad, iqr, qn float64
+	}{
+		{odd, 1.4826, 1.482602218505602, 1.87295416},
+		{even, 4.00302, 3.6323754353387248, 3.5393878481012657},
+		{y, 5.9304, 5.930408874022408, 8.321775},
+	} {
+		assertClose(t, MAD(c.x), c.mad, 1e-12)
+		assertClose(t, IQRSD(c.x), c.iqr, 1e-12)
+		assertClose(t, Qn(c.x), c.qn, 1e-12)
+	}
+	assertClose(t, MADAround(odd, 0), 1.4826*3, 1e-12)
+
+	// an outlier moves none of them, but it moves the standard deviation
+	moved := append([]float64(nil), odd...)
+	moved[4] = 1e6
+	assert.Equal(t, MAD(odd), MAD(moved))
+	assert.Equal(t, IQRSD(odd), IQRSD(moved))
+	assert.Equal(t, Qn(odd), Qn(moved))
+
+	for _, scale := range []func([]float64) float64{MAD, IQRSD, Qn} {
+		assert.T(t, math.IsNaN(scale(nil)))
+		assert.T(t, math.IsNaN(scale([]float64{1, math.NaN(), 3})))
+		assert.Equal(t, 0.0, scale([]float64{7}))
+		assert.Equal(t, 0.0, scale([]float64{7, 7, 7, 7}))
+	}
+	assert.T(t, math.IsNaN(MADAround(odd, math.NaN())))
+}
+
+func TestQnSelection(t *testing.T) {
+	// the selected distance is the order statistic of the sorted distances
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{2, 3, 10, 11, 50, 51} {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = math.Round(10 * rng.NormFloat64())
+		}
+		sorted := append([]float64(nil), x...)
+		sort.Float64s(sorted)
+		var distances []float64
+		for i := range sorted {
+			for j := i + 1; j < n; j++ {
+				distances = append(distances, sorted[j]-sorted[i])
+			}
+		}
+		sort.Float64s(distances)
+		for _, k := range []int{1, len(distances) / 4, len(distances)} {
+			if k > 0 {
+				assert.Equal(t, distances[k-1], kthDistance(sorted, k))
+			}
+		}
+	}
+
+	// and Qn estimates the standard deviation at the normal
+	x := make([]float64, 10000)
+	for i := range x {
+		x[i] = 3 * rng.NormFloat64()
+	}
+	assertClose(t, Qn(x), 3, 0.1)
+	assertClose(t, MAD(x), 3, 0.1)
+	assertClose(t, IQRSD(x), 3, 0.1)
+}
+
+func TestRobustStudentizedResiduals(t *testing.T) {
+	// a line with four outliers planted among 30 observations, which inflate
+	// the residual standard error so that none of them stands out
+	rng := rand.New(rand.NewSource(1))
+	x := make([][]float64, 30)
+	z := make([]float64, 30)
+	for i := range x {
+		x[i] = []float64{float64(i)}
+		z[i] = 2 + 0.5*float64(i) + 0.3*rng.NormFloat64()
+	}
+	planted := []int{6, 11, 16, 21}
+	for _, i := range planted {
+		z[i] += 6
+	}
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(x), z)
+	assert.Equal(t, nil, err)
+
+	classical, err := StandardizedResiduals(m)
+	assert.Equal(t, nil, err)
+	robust, err := RobustStudentizedResiduals(m)
+	assert.Equal(t, nil, err)
+	for _, i := range planted {
+		if math.Abs(classical[i]) > 3 {
+			t.Errorf("classical residual %d is %v, want it masked", i, classical[i])
+		}
+		if robust[i] < 3 {
+			t.Errorf("robust residual %d is %v, want it flagged", i, robust[i])
+		}
+	}
+
+	h, err := LeveragePoints(m)
+	assert.Equal(t, nil, err)
+	s := MAD(m.Residuals())
+	for i, e := range m.Residuals() {
+		assertClose(t, robust[i], e/(s*math.Sqrt(1-h[i])), 1e-12)
+	}
+}
@@ -0,0 +1,97 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ShrinkCovariance returns the Ledoit-Wolf estimate of the covariance matrix
+// of the columns of the n x p matrix x, and its shrinkage intensity. The
+// sample covariance S = X_c'X_c / n of the centered columns, which is singular
+// for n <= p, is shrunk toward \mu I, the identity scaled by the mean variance
+// \mu = tr(S) / p,
+//
+// \Sigma = (1 - \delta) S + \delta \mu I
+//
+// with the intensity that Ledoit and Wolf (2004) estimate to minimize the
+// expected squared Frobenius error,
+//
+// \delta = \min(b^2, d^2) / d^2, d^2 = ||S - \mu I||^2 / p,
+// b^2 = \frac{1}{n^2 p} \sum_i ||x_i x_i' - S||^2
+//
+// which lies in [0, 1], as scikit-learn's LedoitWolf computes them. With a
+// positive intensity and a column that isn't constant the estimate is
+// positive definite whatever n and p are. The intensity is zero only if S is
+// already \mu I, or, as for n = 2, each product x_ij x_ik is the same in every
+// observation; a singular S that isn't shrunk is then a SingularDesignError.
+// Data whose columns are all constant is a ConstantColumnError.
+func ShrinkCovariance(x *mat.Dense) (sigma *mat.SymDense, shrinkage float64, err error) {
+	n, p := x.Dims()
+	if n < 2 {
+		return nil, 0, fmt.Errorf("%w: %d observations", TooFewObservationsError, n)
+	}
+	centered := mat.DenseCopyOf(x)
+	for j := 0; j < p; j++ {
+		centered.SetCol(j, subtractMean(mat.Col(nil, j, x)))
+	}
+	s := &mat.Dense{}
+	s.Mul(centered.T(), centered)
+	s.Scale(1/float64(n), s)
+
+	trace := 0.0
+	for j := 0; j < p; j++ {
+		trace += s.At(j, j)
+	}
+	mu := trace / float64(p)
+	if math.IsNaN(mu) || math.IsInf(mu, 0) {
+		return nil, 0, fmt.Errorf("the data has a value that isn't finite")
+	}
+	if !(mu > 0) {
+		return nil, 0, fmt.Errorf("%w: every column is", ConstantColumnError)
+	}
+
+	if p > 1 {
+		// \sum_i ||x_i x_i'||^2 = \sum_i ||x_i||^4 and \sum_i x_i x_i' = nS, so
+		// n p b^2 = \sum_i ||x_i||^4 / n - ||S||^2
+		squares, norm2 := 0.0, 0.0
+		for i := 0; i < n; i++ {
+			row := centered.RawRowView(i)
+			r2 := sum(prod(row, row))
+			squares += r2 * r2
+		}
+		for j := 0; j < p; j++ {
+			row := s.RawRowView(j)
+			norm2 += sum(prod(row, row))
+		}
+		b2 := (squares/float64(n) - norm2) / float64(n*p)
+		// ||S - \mu I||^2 = ||S||^2 - 2\mu tr(S) + p \mu^2
+		d2 := (norm2 - 2*mu*trace + float64(p)*mu*mu) / float64(p)
+		b2 = math.Min(b2, d2)
+		if b2 > 0 && d2 > 0 {
+			shrinkage = math.Max(0, math.Min(1, b2/d2))
+		}
+	}
+
+	sigma = mat.NewSymDense(p, s.RawMatrix().Data)
+	sigma.ScaleSym(1-shrinkage, sigma)
+	for j := 0; j < p; j++ {
+		sigma.SetSym(j, j, sigma.At(j, j)+shrinkage*mu)
+	}
+	chol := &mat.Cholesky{}
+	singular := !chol.Factorize(sigma)
+	if !singular {
+		r := &mat.TriDense{}
+		chol.UTo(r)
+		for j := 0; j < p; j++ {
+			if d := r.At(j, j); !(d*d > pivotTolerance*sigma.At(j, j)) {
+				singular = true
+			}
+		}
+	}
+	if singular {
+		return nil, 0, fmt.Errorf("%w: the sample covariance matrix is singular and the shrinkage is zero", SingularDesignError)
+	}
+	return sigma, shrinkage, nil
+}
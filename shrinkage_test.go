@@ -0,0 +1,83 @@
+package glasso
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// six observations of eight variables, whose sample covariance is singular
+var wide = mat.NewDense(6, 8, []float64{
+	0.09, 2.5, -2.79, 3.97, -1.3, -1.57, 13.3, 1.26,
+	-0.04, 1.46, 3.38, -0.12, 2.94, -5.84, -2.57, -3.51,
+	-1.33, -3.02, -4.88, -0.95, -0.86, -1.92, 0.48, -10.68,
+	-0.08, 0.48, 2.25, -3.38, -2.0, -12.09, -3.53, -17.57,
+	-1.42, 2.2, -6.6, 3.19, 1.64, -1.87, 3.22, 4.22,
+	1.05, -0.46, -1.78, -2.42, -4.93, -0.27, -5.5, 8.55,
+})
+
+func TestShrinkCovariance(t *testing.T) {
+	// the covariance_ and shrinkage_ of scikit-learn's LedoitWolf().fit(x),
+	// by the formulas of its ledoit_wolf_shrinkage evaluated in exact
+	// rational arithmetic; scikit-learn itself hasn't been run against them
+	for _, c := range []struct {
+		name      string
+		x         *mat.Dense
+		sigma     [][]float64
+		shrinkage float64
+	}{
+		{"wide", wide, [][]float64{
+			{9.7580225296370351, 0.13189412008644516, 0.94875893688758783, -0.46344240311178897, -0.70423989726703418, -0.073563104982418706, -0.71426364406250609, 1.2504347249223398},
+			{0.13189412008644516, 11.279530803058563, 0.5064882284225084, 1.7272522380309321, 0.99500137707617309, -0.36754850819709384, 3.0152073628320086, 3.3256507053893931},
+			{0.94875893688758783, 0.5064882284225084, 16.340529207792745, -2.7939961515781624, 0.30767911270096682, -5.5786831295015622, -5.2944408021035239, -6.9778203732914932},
+			{-0.46344240311178897, 1.7272522380309321, -2.7939961515781624, 13.373271068128721, 1.7699005725500043, 3.0023844919503184, 8.0019083666175206, 6.2430707820548905},
+			{-0.70423989726703418, 0.99500137707617309, 0.30767911270096682, 1.7699005725500043, 12.877803347761654, -0.79580387340554104, 1.6618331428560045, -1.4737820698961839},
+			{-0.073563104982418706, -0.36754850819709384, -5.5786831295015622, 3.0023844919503184, -0.79580387340554104, 18.230716259028835, 4.7836803062410205, 15.622972006343304},
+			{-0.71426364406250609, 3.0152073628320086, -5.2944408021035239, 8.0019083666175206, 1.6618331428560045, 4.7836803062410205, 30.470586486325878, 5.9345799992259414},
+			{1.2504347249223398, 3.3256507053893931, -6.9778203732914932, 6.2430707820548905, -1.4737820698961839, 15.622972006343304, 5.9345799992259414, 52.613173631599899},
+		}, 0.4538294465915127},
+		{"mtcars", mtcars, [][]float64{
+			{184.38560738330401, -3.1387000652895214, -145.68643289295173, 0.55742197083705192},
+			{-3.1387000652895214, 179.38579821522825, 90.381084738159174, -0.33608623308970653},
+			{-145.68643289295173, 90.381084738159174, 5895.3429262398959, -22.989040639057507},
+			{0.55742197083705192, -0.33608623308970653, -22.989040639057507, 177.6485261497379},
+		}, 0.11029803583708639},
+	} {
+		sigma, shrinkage, err := ShrinkCovariance(c.x)
+		assert.Equal(t, nil, err)
+		assertClose(t, shrinkage, c.shrinkage, 1e-12)
+		assertCloseMatrix(t, mat.DenseCopyOf(sigma), c.sigma, 1e-12)
+
+		// positive definite, so that the graphical lasso can start from it
+		chol := &mat.Cholesky{}
+		assert.T(t, chol.Factorize(sigma))
+		_, err = GraphicalLasso(MatToDF(mat.DenseCopyOf(sigma)), 0.1)
+		assert.Equal(t, nil, err, c.name)
+	}
+}
+
+func TestShrinkCovarianceDegenerate(t *testing.T) {
+	// a single variable isn't shrunk
+	sigma, shrinkage, err := ShrinkCovariance(mat.NewDense(4, 1, []float64{1, 2, 3, 6}))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0.0, shrinkage)
+	assertClose(t, sigma.At(0, 0), 3.5, 1e-15)
+
+	// a constant column is given the shrunk mean variance
+	sigma, shrinkage, err = ShrinkCovariance(mat.NewDense(4, 2, []float64{1, 5, 2, 5, 3, 5, 6, 5}))
+	assert.Equal(t, nil, err)
+	assert.T(t, shrinkage > 0)
+	assertClose(t, sigma.At(1, 1), shrinkage*3.5/2, 1e-15)
+
+	// two observations have products with no variance, so nothing is shrunk
+	// and the sample covariance of rank one remains
+	_, _, err = ShrinkCovariance(mat.NewDense(2, 3, []float64{1, 2, 3, 2, 0, 1}))
+	assert.T(t, errors.Is(err, SingularDesignError))
+
+	_, _, err = ShrinkCovariance(mat.NewDense(3, 2, []float64{1, 2, 1, 2, 1, 2}))
+	assert.T(t, errors.Is(err, ConstantColumnError))
+	_, _, err = ShrinkCovariance(mat.NewDense(1, 2, []float64{1, 2}))
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+}
@@ -0,0 +1,444 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SparseMatrix is a matrix stored in compressed sparse row (CSR) form: the
+// entries of row i are values[indptr[i]:indptr[i+1]], in the columns
+// indices[indptr[i]:indptr[i+1]], which increase along the row. It holds only
+// its nonzero entries, so a design of indicators for a predictor with
+// thousands of levels takes a few entries a row rather than thousands.
+type SparseMatrix struct {
+	rows, cols int
+	indptr     []int
+	indices    []int
+	values     []float64
+}
+
+// NewSparseMatrix returns the rows x cols matrix with the given CSR arrays,
+// which it keeps rather than copies. indptr has rows + 1 offsets, from zero
+// and nondecreasing, and within each row the column indices must be in range
+// and increasing.
+func NewSparseMatrix(rows, cols int, indptr, indices []int, values []float64) (*SparseMatrix, error) {
+	switch {
+	case rows <= 0 || cols <= 0:
+		return nil, fmt.Errorf("sparse matrix of %d x %d", rows, cols)
+	case len(indptr) != rows+1:
+		return nil, fmt.Errorf("%d row offsets for %d rows", len(indptr), rows)
+	case indptr[0] != 0 || indptr[rows] != len(indices) || len(indices) != len(values):
+		return nil, fmt.Errorf("row offsets end at %d for %d indices and %d values", indptr[rows], len(indices), len(values))
+	}
+	for i := 0; i < rows; i++ {
+		if indptr[i+1] < indptr[i] {
+			return nil, fmt.Errorf("row offsets decrease at row %d", i)
+		}
+		for k := indptr[i]; k < indptr[i+1]; k++ {
+			j := indices[k]
+			if j < 0 || j >= cols || k > indptr[i] && j <= indices[k-1] {
+				return nil, fmt.Errorf("column %d of row %d is out of range or out of order", j, i)
+			}
+			if math.IsNaN(values[k]) || math.IsInf(values[k], 0) {
+				return nil, fmt.Errorf("entry (%d, %d) is %v", i, j, values[k])
+			}
+		}
+	}
+	return &SparseMatrix{rows: rows, cols: cols, indptr: indptr, indices: indices, values: values}, nil
+}
+
+// NewSparseMatrixFromTriplets returns the rows x cols matrix whose entry
+// (i[k], j[k]) is v[k], summing the values of repeated entries.
+func NewSparseMatrixFromTriplets(rows, cols int, i, j []int, v []float64) (*SparseMatrix, error) {
+	if len(i) != len(j) || len(i) != len(v) {
+		return nil, DimensionError
+	}
+	if rows <= 0 || cols <= 0 {
+		return nil, fmt.Errorf("sparse matrix of %d x %d", rows, cols)
+	}
+	order := make([]int, len(i))
+	for k := range order {
+		if i[k] < 0 || i[k] >= rows || j[k] < 0 || j[k] >= cols {
+			return nil, fmt.Errorf("entry (%d, %d) is out of range for %d x %d", i[k], j[k], rows, cols)
+		}
+		order[k] = k
+	}
+	sort.Slice(order, func(a, b int) bool {
+		if i[order[a]] != i[order[b]] {
+			return i[order[a]] < i[order[b]]
+		}
+		return j[order[a]] < j[order[b]]
+	})
+	indptr := make([]int, rows+1)
+	var indices []int
+	var values []float64
+	for n, k := range order {
+		if n > 0 && i[k] == i[order[n-1]] && j[k] == j[order[n-1]] {
+			values[len(values)-1] += v[k]
+			continue
+		}
+		indptr[i[k]+1]++
+		indices = append(indices, j[k])
+		values = append(values, v[k])
+	}
+	for r := 0; r < rows; r++ {
+		indptr[r+1] += indptr[r]
+	}
+	return NewSparseMatrix(rows, cols, indptr, indices, values)
+}
+
+// Dims returns the numbers of rows and columns of the matrix.
+func (m *SparseMatrix) Dims() (int, int) { return m.rows, m.cols }
+
+// NNZ returns the number of entries the matrix stores.
+func (m *SparseMatrix) NNZ() int { return len(m.values) }
+
+// At returns the entry in row i and column j.
+func (m *SparseMatrix) At(i, j int) float64 {
+	if i < 0 || i >= m.rows || j < 0 || j >= m.cols {
+		panic(fmt.Sprintf("sparse matrix index (%d, %d) out of range for %d x %d", i, j, m.rows, m.cols))
+	}
+	cols := m.indices[m.indptr[i]:m.indptr[i+1]]
+	k := sort.SearchInts(cols, j)
+	if k < len(cols) && cols[k] == j {
+		return m.values[m.indptr[i]+k]
+	}
+	return 0
+}
+
+// row returns the columns and values of the entries of row i.
+func (m *SparseMatrix) row(i int) ([]int, []float64) {
+	return m.indices[m.indptr[i]:m.indptr[i+1]], m.values[m.indptr[i]:m.indptr[i+1]]
+}
+
+// withOnes returns the matrix with a column of ones pushed on to the front.
+func (m *SparseMatrix) withOnes() *SparseMatrix {
+	indptr := make([]int, m.rows+1)
+	indices := make([]int, 0, len(m.indices)+m.rows)
+	values := make([]float64, 0, len(m.values)+m.rows)
+	for i := 0; i < m.rows; i++ {
+		cols, vals := m.row(i)
+		indices = append(indices, 0)
+		values = append(values, 1)
+		for _, j := range cols {
+			indices = append(indices, j+1)
+		}
+		values = append(values, vals...)
+		indptr[i+1] = len(indices)
+	}
+	return &SparseMatrix{rows: m.rows, cols: m.cols + 1, indptr: indptr, indices: indices, values: values}
+}
+
+// mulVec returns m v.
+func (m *SparseMatrix) mulVec(v []float64) []float64 {
+	out := make([]float64, m.rows)
+	for i := range out {
+		cols, vals := m.row(i)
+		for k, j := range cols {
+			out[i] += vals[k] * v[j]
+		}
+	}
+	return out
+}
+
+// mulTransVec returns m' v.
+func (m *SparseMatrix) mulTransVec(v []float64) []float64 {
+	out := make([]float64, m.cols)
+	for i, w := range v {
+		cols, vals := m.row(i)
+		for k, j := range cols {
+			out[j] += vals[k] * w
+		}
+	}
+	return out
+}
+
+// sparseColumn is a column of a sparse symmetric or triangular matrix, its
+// entries in increasing order of row.
+type sparseColumn struct {
+	rows   []int
+	values []float64
+}
+
+// weightedGram returns the columns of the symmetric m' diag(w) m, each with
+// the entries above the diagonal and on it, or of m'm if w is nil. The work is
+// the sum over the rows of the square of their numbers of entries.
+func (m *SparseMatrix) weightedGram(w []float64) []sparseColumn {
+	// the columns of m, as the rows of each and the values in them
+	counts := make([]int, m.cols+1)
+	for _, j := range m.indices {
+		counts[j+1]++
+	}
+	for j := 0; j < m.cols; j++ {
+		counts[j+1] += counts[j]
+	}
+	next := append([]int(nil), counts[:m.cols]...)
+	rowOf := make([]int, len(m.indices))
+	valueOf := make([]float64, len(m.indices))
+	for i := 0; i < m.rows; i++ {
+		cols, vals := m.row(i)
+		for k, j := range cols {
+			rowOf[next[j]], valueOf[next[j]] = i, vals[k]
+			next[j]++
+		}
+	}
+
+	gram := make([]sparseColumn, m.cols)
+	acc := make([]float64, m.cols)
+	seen := make([]bool, m.cols)
+	var touched []int
+	for j := 0; j < m.cols; j++ {
+		touched = touched[:0]
+		for e := counts[j]; e < counts[j+1]; e++ {
+			i, xij := rowOf[e], valueOf[e]
+			if w != nil {
+				xij *= w[i]
+			}
+			cols, vals := m.row(i)
+			for k, c := range cols {
+				if c > j {
+					break
+				}
+				if !seen[c] {
+					seen[c] = true
+					touched = append(touched, c)
+				}
+				acc[c] += xij * vals[k]
+			}
+		}
+		sort.Ints(touched)
+		column := sparseColumn{rows: make([]int, len(touched)), values: make([]float64, len(touched))}
+		for k, c := range touched {
+			column.rows[k], column.values[k] = c, acc[c]
+			acc[c], seen[c] = 0, false
+		}
+		gram[j] = column
+	}
+	return gram
+}
+
+// pivotTolerance bounds d_k / a_kk, the pivot of a Cholesky factorization of
+// X'X relative to the diagonal entry of its column, below which the column is
+// taken to be a linear combination of those before it.
+const pivotTolerance = 1e-10
+
+// sparseCholesky is the factorization P A P' = L L' of a symmetric positive
+// definite matrix, with the permutation P chosen to keep L sparse.
+type sparseCholesky struct {
+	n       int
+	perm    []int          // row k of P A P' is row perm[k] of A
+	inverse []int          // row j of A is row inverse[j] of P A P'
+	l       []sparseColumn // the columns of L, the diagonal first
+}
+
+// newSparseCholesky factorizes the symmetric matrix with the given columns,
+// which hold the entries above the diagonal and on it, by the up-looking
+// algorithm of Davis's CSparse: row k of L is found from a triangular solve
+// whose pattern is the reach of column k in the elimination tree. The columns
+// are ordered by their numbers of entries, fewest first, so that a dense
+// column such as the intercept's, which every other column meets, comes last
+// and fills nothing in. A pivot within pivotTolerance of zero is a
+// SingularDesignError naming its column.
+func newSparseCholesky(a []sparseColumn, names func(j int) string) (*sparseCholesky, error) {
+	n := len(a)
+	// the full pattern of each column, counting the entries below the diagonal
+	degree := make([]int, n)
+	for j, col := range a {
+		for _, i := range col.rows {
+			degree[j]++
+			if i != j {
+				degree[i]++
+			}
+		}
+	}
+	perm := make([]int, n)
+	for j := range perm {
+		perm[j] = j
+	}
+	sort.SliceStable(perm, func(x, y int) bool { return degree[perm[x]] < degree[perm[y]] })
+	inverse := make([]int, n)
+	for k, j := range perm {
+		inverse[j] = k
+	}
+
+	// b holds the columns of P A P', with the entries above the diagonal and on it
+	b := make([]sparseColumn, n)
+	for j, col := range a {
+		for e, i := range col.rows {
+			r, c := inverse[i], inverse[j]
+			if r > c {
+				r, c = c, r
+			}
+			b[c].rows = append(b[c].rows, r)
+			b[c].values = append(b[c].values, col.values[e])
+		}
+	}
+
+	// the elimination tree
+	parent, ancestor := make([]int, n), make([]int, n)
+	for k := 0; k < n; k++ {
+		parent[k], ancestor[k] = -1, -1
+		for _, i := range b[k].rows {
+			for i != -1 && i < k {
+				next := ancestor[i]
+				ancestor[i] = k
+				if next == -1 {
+					parent[i] = k
+				}
+				i = next
+			}
+		}
+	}
+
+	l := make([]sparseColumn, n)
+	x := make([]float64, n)
+	mark := make([]bool, n)
+	stack := make([]int, n)
+	path := make([]int, n)
+	for k := 0; k < n; k++ {
+		// the pattern of row k of L, in topological order in stack[top:]
+		top := n
+		mark[k] = true
+		for e, i := range b[k].rows {
+			x[i] += b[k].values[e]
+			length := 0
+			for ; !mark[i]; i = parent[i] {
+				path[length] = i
+				mark[i] = true
+				length++
+			}
+			for length > 0 {
+				length--
+				top--
+				stack[top] = path[length]
+			}
+		}
+		d := x[k]
+		x[k] = 0
+		for _, i := range stack[top:] {
+			mark[i] = false
+			col := &l[i]
+			lki := x[i] / col.values[0]
+			x[i] = 0
+			for e := 1; e < len(col.rows); e++ {
+				x[col.rows[e]] -= col.values[e] * lki
+			}
+			d -= lki * lki
+			col.rows = append(col.rows, k)
+			col.values = append(col.values, lki)
+		}
+		mark[k] = false
+		var akk float64
+		if last := len(b[k].rows) - 1; last >= 0 && b[k].rows[last] == k {
+			akk = b[k].values[last]
+		}
+		if !(d > pivotTolerance*akk) {
+			return nil, fmt.Errorf("%w: column %s is a linear combination of the others", SingularDesignError, names(perm[k]))
+		}
+		l[k] = sparseColumn{rows: []int{k}, values: []float64{math.Sqrt(d)}}
+	}
+	return &sparseCholesky{n: n, perm: perm, inverse: inverse, l: l}, nil
+}
+
+// solveLower overwrites x, in the permuted order, with L^-1 x.
+func (c *sparseCholesky) solveLower(x []float64) {
+	for j, col := range c.l {
+		x[j] /= col.values[0]
+		for e := 1; e < len(col.rows); e++ {
+			x[col.rows[e]] -= col.values[e] * x[j]
+		}
+	}
+}
+
+// solveUpper overwrites x, in the permuted order, with L'^-1 x.
+func (c *sparseCholesky) solveUpper(x []float64) {
+	for j := c.n - 1; j >= 0; j-- {
+		col := c.l[j]
+		for e := 1; e < len(col.rows); e++ {
+			x[j] -= col.values[e] * x[col.rows[e]]
+		}
+		x[j] /= col.values[0]
+	}
+}
+
+// solve returns A^-1 b.
+func (c *sparseCholesky) solve(b []float64) []float64 {
+	x := make([]float64, c.n)
+	for j, v := range b {
+		x[c.inverse[j]] = v
+	}
+	c.solveLower(x)
+	c.solveUpper(x)
+	out := make([]float64, c.n)
+	for k, v := range x {
+		out[c.perm[k]] = v
+	}
+	return out
+}
+
+// sparseSolver solves L z = b for a sparse b, touching only the entries of z
+// that can be nonzero: those reachable from the entries of b in the graph of
+// L. Each goroutine needs its own.
+type sparseSolver struct {
+	c     *sparseCholesky
+	z     []float64
+	mark  []bool
+	stack []int
+	next  []int // the next entry of the column of each node on stack to search
+	reach []int
+}
+
+func (c *sparseCholesky) newSolver() *sparseSolver {
+	return &sparseSolver{c: c, z: make([]float64, c.n), mark: make([]bool, c.n)}
+}
+
+// squaredNorm returns ||L^-1 P b||^2 for the b with the given entries, in the
+// order of A.
+func (s *sparseSolver) squaredNorm(cols []int, vals []float64) float64 {
+	l := s.c.l
+	// the reach, in topological order, by depth-first search
+	s.reach = s.reach[:0]
+	for _, j := range cols {
+		start := s.c.inverse[j]
+		if s.mark[start] {
+			continue
+		}
+		s.stack = append(s.stack[:0], start)
+		s.mark[start] = true
+		s.next = append(s.next[:0], 1)
+		for len(s.stack) > 0 {
+			top := len(s.stack) - 1
+			col := l[s.stack[top]]
+			e := s.next[top]
+			for e < len(col.rows) && s.mark[col.rows[e]] {
+				e++
+			}
+			if e < len(col.rows) {
+				s.next[top] = e + 1
+				child := col.rows[e]
+				s.mark[child] = true
+				s.stack = append(s.stack, child)
+				s.next = append(s.next, 1)
+				continue
+			}
+			s.reach = append(s.reach, s.stack[top])
+			s.stack, s.next = s.stack[:top], s.next[:top]
+		}
+	}
+	for k, j := range cols {
+		s.z[s.c.inverse[j]] = vals[k]
+	}
+	norm := 0.0
+	for r := len(s.reach) - 1; r >= 0; r-- {
+		j := s.reach[r]
+		col := l[j]
+		zj := s.z[j] / col.values[0]
+		for e := 1; e < len(col.rows); e++ {
+			s.z[col.rows[e]] -= col.values[e] * zj
+		}
+		norm += zj * zj
+		s.z[j], s.mark[j] = 0, false
+	}
+	return norm
+}
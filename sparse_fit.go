@@ -0,0 +1,261 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// sparseChunk is the number of observations or coefficients a worker of the
+// sparse fit takes at a time.
+const sparseChunk = 256
+
+// SparseFit is the least squares fit of a sparse design. It solves the normal
+// equations X'X \beta = X'y by a sparse Cholesky factorization of X'X, which
+// for a design of indicators is about as sparse as X'X itself, and holds
+// nothing larger than the design, its factor and vectors of length n or p.
+type SparseFit struct {
+	betas       []float64
+	n, p        int
+	noIntercept bool
+
+	design    *SparseMatrix // the design with its intercept column
+	chol      *sparseCholesky
+	fitted    []float64
+	residuals []float64
+	rss       float64
+	opts      options
+}
+
+// FitSparse fits y on the columns of x by least squares, adding an intercept
+// unless WithIntercept(false) is given, as NewOlsTrainer does; x shouldn't
+// include a column of ones. The design must have full column rank: a column
+// that is a linear combination of the others, such as the indicator of every
+// level of a predictor alongside the intercept, is a SingularDesignError.
+// WithContext cancels the diagnostics, and WithLeverageSamples makes Leverage
+// an approximation.
+func FitSparse(x *SparseMatrix, y []float64, opts ...Option) (*SparseFit, error) {
+	o := newOptions(opts)
+	n, _ := x.Dims()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	for i, v := range y {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("response %d is %v", i, v)
+		}
+	}
+	design := x
+	if o.intercept {
+		design = x.withOnes()
+	}
+	p := design.cols
+	if n < p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+	f := &SparseFit{n: n, p: p, noIntercept: !o.intercept, design: design, opts: o}
+	chol, err := newSparseCholesky(design.weightedGram(nil), func(j int) string { return f.name(j) })
+	if err != nil {
+		return nil, err
+	}
+	f.chol = chol
+	f.betas = chol.solve(design.mulTransVec(y))
+	f.fitted = design.mulVec(f.betas)
+	f.residuals = make([]float64, n)
+	for i, v := range y {
+		f.residuals[i] = v - f.fitted[i]
+		f.rss += f.residuals[i] * f.residuals[i]
+	}
+	return f, nil
+}
+
+// name returns the name of coefficient j.
+func (f *SparseFit) name(j int) string {
+	if !f.noIntercept {
+		if j == 0 {
+			return "(Intercept)"
+		}
+		j--
+	}
+	return fmt.Sprintf("x%d", j)
+}
+
+// Coefficients returns the coefficients, the intercept first unless the fit
+// is through the origin.
+func (f *SparseFit) Coefficients() []float64 { return f.betas }
+
+// Yhat returns the fitted values.
+func (f *SparseFit) Yhat() []float64 { return f.fitted }
+
+// Residuals returns the residuals.
+func (f *SparseFit) Residuals() []float64 { return f.residuals }
+
+// SumOfSquares returns the residual sum of squares.
+func (f *SparseFit) SumOfSquares() float64 { return f.rss }
+
+// N returns the number of observations.
+func (f *SparseFit) N() int { return f.n }
+
+// Predict returns the prediction for the predictors x, a dense row of the
+// columns of the design.
+func (f *SparseFit) Predict(x []float64) float64 {
+	if f.noIntercept {
+		return sum(prod(x, f.betas))
+	}
+	return f.betas[0] + sum(prod(x, f.betas[1:]))
+}
+
+// StandardErrors returns the standard errors of the coefficients,
+// \sqrt{s^2 [(X'X)^-1]_{jj}} with s^2 = RSS / (n - p). Each column of
+// (X'X)^-1 is solved for in turn, rather than the matrix formed.
+func (f *SparseFit) StandardErrors() ([]float64, error) {
+	if f.n <= f.p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients leave no residual degrees of freedom", TooFewObservationsError, f.n, f.p)
+	}
+	s2 := f.rss / float64(f.n-f.p)
+	se := make([]float64, f.p)
+	err := parallelChunks(f.opts.ctx, f.opts.workers(), 0, f.p-1, sparseChunk, func(first, last int) bool {
+		e := make([]float64, f.p)
+		for j := first; j <= last; j++ {
+			e[j] = 1
+			se[j] = math.Sqrt(s2 * f.chol.solve(e)[j])
+			e[j] = 0
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return se, nil
+}
+
+// RobustStandardErrors returns the heteroskedasticity-consistent standard
+// errors of the coefficients of the given kind, the square roots of the
+// diagonal of RobustVCov's sandwich
+//
+// V = (X'X)^-1 X' \Omega X (X'X)^-1
+//
+// Each diagonal entry is c_j' (X' \Omega X) c_j for the column c_j of
+// (X'X)^-1, with X' \Omega X as sparse as X'X. HC2 and HC3 need the leverages,
+// which are approximate under WithLeverageSamples.
+func (f *SparseFit) RobustStandardErrors(kind HCKind) ([]float64, error) {
+	omega := make([]float64, f.n)
+	var h []float64
+	if kind == HC2 || kind == HC3 {
+		var err error
+		if h, err = f.Leverage(); err != nil {
+			return nil, err
+		}
+	}
+	for i, e := range f.residuals {
+		switch kind {
+		case HC0:
+			omega[i] = e * e
+		case HC1:
+			if f.n <= f.p {
+				return nil, fmt.Errorf("%w: %d observations for %d coefficients leave no residual degrees of freedom", TooFewObservationsError, f.n, f.p)
+			}
+			omega[i] = e * e * float64(f.n) / float64(f.n-f.p)
+		case HC2:
+			if !isUnitLeverage(h[i]) {
+				omega[i] = e * e / (1 - h[i])
+			}
+		case HC3:
+			if !isUnitLeverage(h[i]) {
+				omega[i] = e * e / ((1 - h[i]) * (1 - h[i]))
+			}
+		default:
+			return nil, fmt.Errorf("unknown covariance estimator %v", kind)
+		}
+	}
+	meat := f.design.weightedGram(omega)
+
+	se := make([]float64, f.p)
+	err := parallelChunks(f.opts.ctx, f.opts.workers(), 0, f.p-1, sparseChunk, func(first, last int) bool {
+		e := make([]float64, f.p)
+		for j := first; j <= last; j++ {
+			e[j] = 1
+			c := f.chol.solve(e)
+			e[j] = 0
+			v := 0.0
+			for k, col := range meat {
+				for m, i := range col.rows {
+					if i == k {
+						v += col.values[m] * c[k] * c[k]
+					} else {
+						v += 2 * col.values[m] * c[i] * c[k]
+					}
+				}
+			}
+			se[j] = math.Sqrt(v)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return se, nil
+}
+
+// Leverage returns the diagonal of the hat matrix, h_ii = ||L^-1 x_i||^2 for
+// the Cholesky factor L of X'X, from a sparse triangular solve for each
+// observation that touches only the part of L its entries reach.
+//
+// With WithLeverageSamples(k) the leverages are instead estimated as
+// ||\Omega L^-1 x_i||^2 for a random k x p Gaussian \Omega scaled by 1/\sqrt{k},
+// as Drineas et al. do: each is unbiased with a relative standard deviation of
+// about \sqrt{2/k}, and the work is k triangular solves and k products with
+// the design, whatever the pattern of L. The projection is seeded by WithSeed.
+func (f *SparseFit) Leverage() ([]float64, error) {
+	if k := f.opts.samples; k > 0 {
+		return f.sampledLeverage(k)
+	}
+	h := make([]float64, f.n)
+	err := parallelChunks(f.opts.ctx, f.opts.workers(), 0, f.n-1, sparseChunk, func(first, last int) bool {
+		solver := f.chol.newSolver()
+		for i := first; i <= last; i++ {
+			h[i] = solver.squaredNorm(f.design.row(i))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// sampledLeverage estimates the leverages from k random projections.
+func (f *SparseFit) sampledLeverage(k int) ([]float64, error) {
+	rng := f.opts.rng()
+	scale := 1 / math.Sqrt(float64(k))
+	// the rows of \Omega L^-1 P, in the order of the columns of the design
+	projections := make([][]float64, k)
+	for r := range projections {
+		w := make([]float64, f.p)
+		for j := range w {
+			w[j] = rng.NormFloat64() * scale
+		}
+		f.chol.solveUpper(w)
+		projections[r] = make([]float64, f.p)
+		for m, j := range f.chol.perm {
+			projections[r][j] = w[m]
+		}
+	}
+	h := make([]float64, f.n)
+	err := parallelChunks(f.opts.ctx, f.opts.workers(), 0, f.n-1, sparseChunk, func(first, last int) bool {
+		for i := first; i <= last; i++ {
+			cols, vals := f.design.row(i)
+			for _, w := range projections {
+				v := 0.0
+				for e, j := range cols {
+					v += w[j] * vals[e]
+				}
+				h[i] += v * v
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
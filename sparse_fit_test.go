@@ -0,0 +1,192 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// oneHotDesign returns n observations of a predictor with the given number of
+// levels, coded as indicators (without the first level's unless all is set),
+// and of two numeric predictors, as a sparse matrix and as dense rows, with a
+// heteroskedastic response.
+func oneHotDesign(rng *rand.Rand, n, levels int, all bool) (*SparseMatrix, [][]float64, []float64, []int) {
+	first := 1
+	if all {
+		first = 0
+	}
+	cols := levels - first + 2
+	indptr := []int{0}
+	var indices []int
+	var values []float64
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	level := make([]int, n)
+	for i := range rows {
+		rows[i] = make([]float64, cols)
+		level[i] = rng.Intn(levels)
+		if level[i] >= first {
+			rows[i][level[i]-first] = 1
+			indices, values = append(indices, level[i]-first), append(values, 1)
+		}
+		u, v := rng.NormFloat64(), rng.Float64()
+		rows[i][cols-2], rows[i][cols-1] = u, v
+		indices, values = append(indices, cols-2, cols-1), append(values, u, v)
+		indptr = append(indptr, len(indices))
+		response[i] = math.Sin(float64(level[i])) + 2*u - v + (1+v)*rng.NormFloat64()
+	}
+	x, err := NewSparseMatrix(n, cols, indptr, indices, values)
+	if err != nil {
+		panic(err)
+	}
+	return x, rows, response, level
+}
+
+func assertCloseSlices(t *testing.T, got, want []float64, tol float64) {
+	assert.Equal(t, len(got), len(want))
+	for i := range want {
+		assertClose(t, got[i], want[i], tol*math.Max(1, math.Abs(want[i])))
+	}
+}
+
+func TestFitSparse(t *testing.T) {
+	x, rows, response, _ := oneHotDesign(rand.New(rand.NewSource(1)), 2000, 40, false)
+	fit, err := FitSparse(x, response)
+	assert.Equal(t, nil, err)
+	m, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+
+	assertCloseSlices(t, fit.Coefficients(), m.(*OLS).Coefficients(), 1e-9)
+	assertCloseSlices(t, fit.Yhat(), s.Yhat(), 1e-9)
+	assertCloseSlices(t, fit.Residuals(), s.Residuals(), 1e-9)
+	assertClose(t, fit.SumOfSquares(), s.SumOfSquares(), 1e-9*s.SumOfSquares())
+	assert.Equal(t, fit.N(), 2000)
+	assertClose(t, fit.Predict(rows[3]), m.Predict(rows[3]), 1e-9)
+
+	se, err := fit.StandardErrors()
+	assert.Equal(t, nil, err)
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, se, StandardErrors(vcov), 1e-9)
+
+	for _, kind := range []HCKind{HC0, HC1, HC2, HC3} {
+		robust, err := fit.RobustStandardErrors(kind)
+		assert.Equal(t, nil, err)
+		vcov, err := RobustVCov(s, kind)
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, robust, StandardErrors(vcov), 1e-9)
+	}
+
+	h, err := fit.Leverage()
+	assert.Equal(t, nil, err)
+	want, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, h, want, 1e-9)
+
+	// Through the origin with an indicator for every level, the coefficient of
+	// a level is the intercept plus its coefficient above, and the fit is the same.
+	all, _, _, _ := oneHotDesign(rand.New(rand.NewSource(1)), 2000, 40, true)
+	origin, err := FitSparse(all, response, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	betas := fit.Coefficients()
+	shifted := append([]float64(nil), betas...)
+	for l := 1; l < 40; l++ {
+		shifted[l] += betas[0]
+	}
+	assertCloseSlices(t, origin.Coefficients(), shifted, 1e-9)
+	assertCloseSlices(t, origin.Residuals(), fit.Residuals(), 1e-9)
+	originSE, err := origin.StandardErrors()
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, originSE[40:], se[40:], 1e-9)
+	originH, err := origin.Leverage()
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, originH, h, 1e-9)
+}
+
+func TestFitSparseSampledLeverage(t *testing.T) {
+	x, _, response, _ := oneHotDesign(rand.New(rand.NewSource(2)), 3000, 30, false)
+	exact, err := FitSparse(x, response)
+	assert.Equal(t, nil, err)
+	h, err := exact.Leverage()
+	assert.Equal(t, nil, err)
+
+	sampled, err := FitSparse(x, response, WithLeverageSamples(800), WithSeed(3))
+	assert.Equal(t, nil, err)
+	approx, err := sampled.Leverage()
+	assert.Equal(t, nil, err)
+	// each is within a few multiples of sqrt(2/800) = 5% of the exact leverage
+	relative := 0.0
+	for i := range h {
+		d := math.Abs(approx[i]-h[i]) / h[i]
+		assert.T(t, d < 0.25, i, approx[i], h[i])
+		relative += d / float64(len(h))
+	}
+	assert.T(t, relative < 0.06, relative)
+}
+
+func TestFitSparseLarge(t *testing.T) {
+	// 100k observations of a predictor with 5k levels, which as a dense design
+	// would take 4GB
+	const n, levels = 100000, 5000
+	rng := rand.New(rand.NewSource(4))
+	indptr := []int{0}
+	var indices []int
+	var values []float64
+	response := make([]float64, n)
+	sums, counts := make([]float64, levels), make([]float64, levels)
+	for i := 0; i < n; i++ {
+		// every level but the reference, 0, has a column
+		level := i % levels
+		if i >= levels {
+			level = rng.Intn(levels)
+		}
+		if level > 0 {
+			indices, values = append(indices, level-1), append(values, 1)
+		}
+		indptr = append(indptr, len(indices))
+		response[i] = float64(level%7) + rng.NormFloat64()
+		sums[level] += response[i]
+		counts[level]++
+	}
+	x, err := NewSparseMatrix(n, levels-1, indptr, indices, values)
+	assert.Equal(t, nil, err)
+	fit, err := FitSparse(x, response)
+	assert.Equal(t, nil, err)
+
+	// the intercept is the mean of the reference level, and the coefficients
+	// the differences of the means of the others from it
+	betas := fit.Coefficients()
+	reference := sums[0] / counts[0]
+	assertClose(t, betas[0], reference, 1e-9)
+	for level := 1; level < levels; level++ {
+		assertClose(t, betas[level], sums[level]/counts[level]-reference, 1e-9)
+	}
+	se, err := fit.StandardErrors()
+	assert.Equal(t, nil, err)
+	s := math.Sqrt(fit.SumOfSquares() / float64(n-levels))
+	assertClose(t, se[0], s/math.Sqrt(counts[0]), 1e-9)
+	assertClose(t, se[1], s*math.Sqrt(1/counts[0]+1/counts[1]), 1e-9)
+	h, err := fit.Leverage()
+	assert.Equal(t, nil, err)
+	assertClose(t, h[7], 1/counts[7], 1e-12)
+}
+
+func TestFitSparseErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	x, _, response, _ := oneHotDesign(rng, 100, 5, true)
+	// every level has an indicator, as well as there being an intercept
+	_, err := FitSparse(x, response)
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+	_, err = FitSparse(x, response[1:], WithIntercept(false))
+	assert.Equal(t, DimensionError, err)
+	response[0] = math.NaN()
+	_, err = FitSparse(x, response, WithIntercept(false))
+	assert.NotEqual(t, nil, err)
+
+	small, _, response, _ := oneHotDesign(rng, 3, 3, false)
+	_, err = FitSparse(small, response)
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+}
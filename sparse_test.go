@@ -0,0 +1,109 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSparseMatrix(t *testing.T) {
+	// [1 0 2; 0 0 3]
+	m, err := NewSparseMatrix(2, 3, []int{0, 2, 3}, []int{0, 2, 2}, []float64{1, 2, 3})
+	assert.Equal(t, nil, err)
+	r, c := m.Dims()
+	assert.Equal(t, []int{r, c, m.NNZ()}, []int{2, 3, 3})
+	assert.Equal(t, []float64{m.At(0, 0), m.At(0, 1), m.At(0, 2), m.At(1, 0), m.At(1, 2)}, []float64{1, 0, 2, 0, 3})
+	assert.Equal(t, m.mulVec([]float64{1, 1, 1}), []float64{3, 3})
+	assert.Equal(t, m.mulTransVec([]float64{1, 2}), []float64{1, 0, 8})
+
+	// repeated triplets are summed, in any order
+	triplets, err := NewSparseMatrixFromTriplets(2, 3, []int{1, 0, 0, 0}, []int{2, 2, 0, 2}, []float64{3, 1.5, 1, 0.5})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, triplets, m)
+
+	for _, bad := range []struct {
+		rows, cols      int
+		indptr, indices []int
+		values          []float64
+	}{
+		{0, 3, []int{0}, nil, nil},
+		{2, 3, []int{0, 2}, []int{0, 2}, []float64{1, 2}},
+		{2, 3, []int{0, 2, 1}, []int{0, 2}, []float64{1, 2}},
+		{1, 3, []int{0, 2}, []int{2, 0}, []float64{1, 2}},
+		{1, 3, []int{0, 1}, []int{3}, []float64{1}},
+		{1, 3, []int{0, 1}, []int{0}, []float64{math.NaN()}},
+	} {
+		_, err := NewSparseMatrix(bad.rows, bad.cols, bad.indptr, bad.indices, bad.values)
+		assert.NotEqual(t, nil, err, bad)
+	}
+	_, err = NewSparseMatrixFromTriplets(2, 3, []int{2}, []int{0}, []float64{1})
+	assert.NotEqual(t, nil, err)
+}
+
+// randomSparse returns an n x p sparse matrix with about density of its
+// entries nonzero and a nonzero diagonal, and the dense matrix equal to it.
+func randomSparse(rng *rand.Rand, n, p int, density float64) (*SparseMatrix, *mat.Dense) {
+	dense := mat.NewDense(n, p, nil)
+	var is, js []int
+	var vs []float64
+	for i := 0; i < n; i++ {
+		for j := 0; j < p; j++ {
+			if i == j || rng.Float64() < density {
+				v := rng.NormFloat64()
+				dense.Set(i, j, v)
+				is, js, vs = append(is, i), append(js, j), append(vs, v)
+			}
+		}
+	}
+	m, err := NewSparseMatrixFromTriplets(n, p, is, js, vs)
+	if err != nil {
+		panic(err)
+	}
+	return m, dense
+}
+
+func TestSparseCholesky(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x, dense := randomSparse(rng, 200, 30, 0.05)
+	gram := x.weightedGram(nil)
+	xtx := &mat.Dense{}
+	xtx.Mul(dense.T(), dense)
+	for j, col := range gram {
+		for e, i := range col.rows {
+			assert.T(t, i <= j)
+			assertClose(t, col.values[e], xtx.At(i, j), 1e-10)
+		}
+	}
+
+	chol, err := newSparseCholesky(gram, func(j int) string { return "x" })
+	assert.Equal(t, nil, err)
+	b := make([]float64, 30)
+	for j := range b {
+		b[j] = rng.NormFloat64()
+	}
+	want := &mat.Dense{}
+	assert.Equal(t, nil, want.Solve(xtx, mat.NewDense(30, 1, append([]float64(nil), b...))))
+	for j, v := range chol.solve(b) {
+		assertClose(t, v, want.At(j, 0), 1e-9*math.Max(1, math.Abs(v)))
+	}
+
+	// the sparse solve of L z = x_i gives the leverage
+	inverse := &mat.Dense{}
+	assert.Equal(t, nil, inverse.Inverse(xtx))
+	solver := chol.newSolver()
+	for i := 0; i < 200; i++ {
+		row := dense.RawRowView(i)
+		v := &mat.Dense{}
+		v.Mul(mat.NewDense(1, 30, row), inverse)
+		assertClose(t, solver.squaredNorm(x.row(i)), sum(prod(v.RawRowView(0), row)), 1e-10)
+	}
+
+	// a column that duplicates another can't be factorized
+	dup, _ := NewSparseMatrixFromTriplets(3, 2, []int{0, 1, 0, 1}, []int{0, 0, 1, 1}, []float64{1, 2, 1, 2})
+	_, err = newSparseCholesky(dup.weightedGram(nil), func(j int) string { return "x" })
+	assert.T(t, errors.Is(err, SingularDesignError), err)
+}
@@ -0,0 +1,146 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// ScaleLocation returns the data of the scale-location plot of the model,
+// which = 3 of R's plot.lm: its fitted values, and the square roots of the
+// absolute values of its standardized residuals, \sqrt{|r_i|}. A spread of the
+// residuals that changes with the mean shows as a trend in them.
+// Observations with a leverage of one have no standardized residual and are
+// NaN.
+func ScaleLocation(m Summary) (fitted, sqrtAbsStdResid []float64, err error) {
+	rs, err := StandardizedResiduals(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqrtAbsStdResid = make([]float64, len(rs))
+	for i, r := range rs {
+		sqrtAbsStdResid[i] = math.Sqrt(math.Abs(r))
+	}
+	return m.Yhat(), sqrtAbsStdResid, nil
+}
+
+// ScaleLocationTrend returns the trend line of the scale-location plot of the
+// model, the Lowess smooth of \sqrt{|r_i|} against the fitted values with the
+// span of 2/3 and 3 robustness iterations of R's panel.smooth, at each fitted
+// value. Observations with a leverage of one are left out of the smooth and
+// are NaN.
+func ScaleLocationTrend(m Summary) ([]float64, error) {
+	fitted, s, err := ScaleLocation(m)
+	if err != nil {
+		return nil, err
+	}
+	var x, y []float64
+	for i := range fitted {
+		if !math.IsNaN(s[i]) {
+			x = append(x, fitted[i])
+			y = append(y, s[i])
+		}
+	}
+	smooth, err := Lowess(x, y, 2.0/3, 3)
+	if err != nil {
+		return nil, err
+	}
+	trend := make([]float64, len(fitted))
+	k := 0
+	for i := range trend {
+		if math.IsNaN(s[i]) {
+			trend[i] = math.NaN()
+			continue
+		}
+		trend[i] = smooth[k]
+		k++
+	}
+	return trend, nil
+}
+
+// SpreadLevel is the spread-level regression of a model, the least squares
+// line through the logs of the absolute externally studentized residuals
+// against the logs of the fitted values, as car's spreadLevelPlot fits it.
+// If the spread of the residuals grows as a power b of the mean, the slope
+// estimates b, and the power transformation y^{1 - b} of the response
+// stabilizes its variance: 0 for the log, 1/2 for the square root, 1 for none.
+type SpreadLevel struct {
+	Intercept, Slope float64
+
+	// Test is the t test of a zero slope, a spread that doesn't change with
+	// the mean, on n - 2 degrees of freedom.
+	Test TestResult
+
+	// Power is the suggested power transformation of the response, 1 - Slope.
+	Power float64
+
+	// Excluded counts the observations left out of the regression: those
+	// whose fitted values aren't positive, which have no log, and those with
+	// a leverage of one or a residual of zero.
+	Excluded int
+
+	// Warnings describe the observations that were left out.
+	Warnings []string
+}
+
+// SpreadLevelTest regresses the logs of the absolute externally studentized
+// residuals of the model on the logs of its fitted values to suggest a power
+// transformation of the response that stabilizes its variance. Observations
+// with fitted values that aren't positive are left out with a warning, as
+// car's spreadLevelPlot drops them; a model with many of them may need its
+// response shifted first.
+func SpreadLevelTest(m Summary) (*SpreadLevel, error) {
+	rs, err := ExternallyStudentizedResiduals(m)
+	if err != nil {
+		return nil, err
+	}
+	fitted := m.Yhat()
+	var x, y []float64
+	nonPositive, undefined := 0, 0
+	for i, r := range rs {
+		switch {
+		case !(fitted[i] > 0):
+			nonPositive++
+		case math.IsNaN(r) || r == 0:
+			undefined++
+		default:
+			x = append(x, math.Log(fitted[i]))
+			y = append(y, math.Log(math.Abs(r)))
+		}
+	}
+	n := len(x)
+	if n < 3 {
+		return nil, fmt.Errorf("%w: %d observations with positive fitted values and nonzero residuals for the spread-level regression", TooFewObservationsError, n)
+	}
+
+	xbar, ybar := mean(x), mean(y)
+	var sxx, sxy float64
+	for i := range x {
+		sxx += (x[i] - xbar) * (x[i] - xbar)
+		sxy += (x[i] - xbar) * (y[i] - ybar)
+	}
+	if sxx == 0 {
+		return nil, fmt.Errorf("fitted values are constant, so the spread-level slope is undefined")
+	}
+	s := &SpreadLevel{Slope: sxy / sxx}
+	s.Intercept = ybar - s.Slope*xbar
+	s.Power = 1 - s.Slope
+	rss := 0.0
+	for i := range x {
+		e := y[i] - s.Intercept - s.Slope*x[i]
+		rss += e * e
+	}
+	df := float64(n - 2)
+	t := s.Slope / math.Sqrt(rss/df/sxx)
+	s.Test = TestResult{Statistic: t, DF: df, PValue: 2 * studentTCDF(-math.Abs(t), df)}
+
+	s.Excluded = nonPositive + undefined
+	if nonPositive > 0 {
+		s.Warnings = append(s.Warnings,
+			fmt.Sprintf("%d observations with fitted values that aren't positive were left out", nonPositive))
+	}
+	if undefined > 0 {
+		s.Warnings = append(s.Warnings,
+			fmt.Sprintf("%d observations with a leverage of one or a zero residual were left out", undefined))
+	}
+	return s, nil
+}
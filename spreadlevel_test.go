@@ -0,0 +1,135 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// spreadData returns a straight line observed with errors whose standard
+// deviation is sd(mu) at the mean mu.
+func spreadData(n int, seed int64, sd func(mu float64) float64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	x := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range x {
+		v := 1 + 99*float64(i)/float64(n-1)
+		mu := 5 + 2*v
+		x[i] = []float64{v}
+		y[i] = mu + sd(mu)*rng.NormFloat64()
+	}
+	return x, y
+}
+
+func TestScaleLocation(t *testing.T) {
+	fitted, s, err := ScaleLocation(summary)
+	assert.Equal(t, nil, err)
+	rs, err := StandardizedResiduals(summary)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, fitted, summary.Yhat(), 0)
+	for i := range s {
+		assertClose(t, s[i]*s[i], math.Abs(rs[i]), 1e-12)
+	}
+
+	trend, err := ScaleLocationTrend(summary)
+	assert.Equal(t, nil, err)
+	want, err := Lowess(fitted, s, 2.0/3, 3)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, trend, want, 0)
+}
+
+func TestSpreadLevelTest(t *testing.T) {
+	// a standard deviation proportional to the mean, a variance proportional
+	// to its square, is stabilized by the log, a power of 0; the slope has a
+	// standard error of about 0.03 for 2000 observations
+	x, y := spreadData(2000, 1, func(mu float64) float64 { return 0.2 * mu })
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(x), y)
+	assert.Equal(t, nil, err)
+	s, err := SpreadLevelTest(m)
+	assert.Equal(t, nil, err)
+	if math.Abs(s.Power) > 0.15 {
+		t.Errorf("suggested power %v, want about 0", s.Power)
+	}
+	if s.Test.PValue > 1e-6 {
+		t.Errorf("p-value %v of the slope, want the spread to grow with the mean", s.Test.PValue)
+	}
+	assert.Equal(t, 0, s.Excluded)
+	assert.Equal(t, 0, len(s.Warnings))
+
+	// a standard deviation of the square root of the mean, a Poisson-like
+	// variance, is stabilized by the square root
+	x, y = spreadData(2000, 1, func(mu float64) float64 { return math.Sqrt(mu) })
+	_, m, err = NewOlsTrainer().Train(NewDataFrame(x), y)
+	assert.Equal(t, nil, err)
+	s, err = SpreadLevelTest(m)
+	assert.Equal(t, nil, err)
+	if math.Abs(s.Power-0.5) > 0.15 {
+		t.Errorf("suggested power %v, want about 1/2", s.Power)
+	}
+
+	// a constant spread needs no transformation
+	x, y = spreadData(2000, 1, func(float64) float64 { return 10 })
+	_, m, err = NewOlsTrainer().Train(NewDataFrame(x), y)
+	assert.Equal(t, nil, err)
+	s, err = SpreadLevelTest(m)
+	assert.Equal(t, nil, err)
+	if math.Abs(s.Power-1) > 0.15 {
+		t.Errorf("suggested power %v, want about 1", s.Power)
+	}
+
+	// the line and its test are those of the least squares fit of log|t_i| on
+	// log \hat y_i
+	s, err = SpreadLevelTest(summary)
+	assert.Equal(t, nil, err)
+	rs, err := ExternallyStudentizedResiduals(summary)
+	assert.Equal(t, nil, err)
+	logFitted := make([][]float64, len(rs))
+	logResid := make([]float64, len(rs))
+	for i, r := range rs {
+		logFitted[i] = []float64{math.Log(summary.Yhat()[i])}
+		logResid[i] = math.Log(math.Abs(r))
+	}
+	_, aux, err := NewOlsTrainer().Train(NewDataFrame(logFitted), logResid)
+	assert.Equal(t, nil, err)
+	table, err := CoefficientTable(aux)
+	assert.Equal(t, nil, err)
+	assertClose(t, s.Intercept, table[0].Estimate, 1e-10)
+	assertClose(t, s.Slope, table[1].Estimate, 1e-10)
+	assertClose(t, s.Test.Statistic, table[1].T, 1e-10)
+	assertClose(t, s.Test.PValue, table[1].PValue, 1e-10)
+	assertClose(t, s.Power, 1-s.Slope, 0)
+	assert.Equal(t, float64(len(rs)-2), s.Test.DF)
+}
+
+func TestSpreadLevelExcluded(t *testing.T) {
+	// the fitted values of the first observations are negative
+	x, y := spreadData(60, 3, func(mu float64) float64 { return 0.2 * mu })
+	for i := range x {
+		y[i] -= 30
+	}
+	_, m, err := NewOlsTrainer().Train(NewDataFrame(x), y)
+	assert.Equal(t, nil, err)
+	nonPositive := 0
+	for _, v := range m.Yhat() {
+		if v <= 0 {
+			nonPositive++
+		}
+	}
+	assert.T(t, nonPositive > 0)
+	s, err := SpreadLevelTest(m)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nonPositive, s.Excluded)
+	assert.Equal(t, 1, len(s.Warnings))
+	assert.Equal(t, float64(60-nonPositive-2), s.Test.DF)
+
+	// too few positive fitted values to fit a line
+	for i := range y {
+		y[i] -= 1000
+	}
+	_, m, err = NewOlsTrainer().Train(NewDataFrame(x), y)
+	assert.Equal(t, nil, err)
+	_, err = SpreadLevelTest(m)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,145 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// cusumCritical is the \lambda of the 5% boundaries \pm\lambda(1 + 2t) of the
+// CUSUM of recursive residuals, from Brown, Durbin & Evans (1975), as
+// strucchange's boundary for a Rec-CUSUM process.
+const cusumCritical = 0.948
+
+// cusumSquaresCritical is the asymptotic 5% critical value c_0\sqrt{m} of the
+// CUSUM of squares, \sqrt{\ln(40) / 2}: the boundaries are t \pm c_0 for
+// m = (n - k) / 2, each crossed with probability 2.5% under stability.
+var cusumSquaresCritical = math.Sqrt(math.Log(40) / 2)
+
+// RecursiveResiduals returns the recursive residuals of the regression of y on
+// the columns of x, with an intercept unless WithIntercept(false) is given, in
+// the order of the rows: for each observation r after the first k that
+// determine the fit,
+//
+// w_r = (y_r - x_r'\beta_{r-1}) / \sqrt{1 + x_r'(X_{r-1}'X_{r-1})^{-1}x_r}
+//
+// with \beta_{r-1} and X_{r-1} the fit to and design of the observations
+// before it, as strucchange's recresid. They are found by updating an
+// OnlineOLS one row at a time, which leaves w_r behind as it folds in row r,
+// rather than by refitting. start is the row of the first recursive residual,
+// the first whose predecessors have a design of full rank, so that w[i] is
+// that of row start + i. Under a stable model with normal errors they are
+// independent with mean zero and the error variance.
+func RecursiveResiduals(x *DataFrame, y []float64, opts ...Option) (w []float64, start int, err error) {
+	o := newOptions(opts)
+	n := x.Rows()
+	if len(y) != n {
+		return nil, 0, DimensionError
+	}
+	fit, err := NewOnlineOLS(x.Cols(), WithIntercept(o.intercept))
+	if err != nil {
+		return nil, 0, err
+	}
+	start = -1
+	for i := 0; i < n; i++ {
+		if start < 0 && fit.rank() == nil {
+			start = i
+		}
+		e, err := fit.update(x.GetRow(i), y[i])
+		if err != nil {
+			return nil, 0, fmt.Errorf("row %d: %v", i, err)
+		}
+		if start >= 0 {
+			w = append(w, e)
+		}
+	}
+	if start < 0 {
+		return nil, 0, fmt.Errorf("%w: the observations never determine the coefficients", TooFewObservationsError)
+	}
+	return w, start, nil
+}
+
+// StabilityResult holds the CUSUM and CUSUM of squares tests of a regression
+// for structural stability, as series over the observations with a recursive
+// residual: the ith value of each is that of row Start + i.
+type StabilityResult struct {
+	RecursiveResiduals []float64
+	Start              int
+
+	// CUSUM is the cumulative sum of the recursive residuals, scaled by their
+	// standard deviation \hat\sigma and \sqrt{n - k}:
+	//
+	// W_r = \sum_{i=k+1}^r w_i / (\hat\sigma\sqrt{n - k})
+	//
+	// strucchange's Rec-CUSUM efp process without its leading zero.
+	// CUSUMBound is its 5% boundary \lambda(1 + 2t) at t = (r - k) / (n - k),
+	// which it crosses if it goes above it or below its negative.
+	CUSUM, CUSUMBound []float64
+
+	// CUSUMSquares is the cumulative sum of the squared recursive residuals
+	// as a proportion of their total,
+	//
+	// S_r = \sum_{i=k+1}^r w_i^2 / \sum_{i=k+1}^n w_i^2
+	//
+	// which under stability follows the line t. It crosses its 5% boundaries
+	// if it strays from t by more than SquaresBound, the asymptotic
+	// approximation to the critical value of Durbin's (1969) table; for
+	// short series the table is the more accurate.
+	CUSUMSquares []float64
+	SquaresBound float64
+
+	Crossed              bool // whether the CUSUM crosses its boundaries
+	FirstCrossing        int  // the first row at which it does, or -1
+	SquaresCrossed       bool // whether the CUSUM of squares crosses its boundaries
+	FirstSquaresCrossing int  // the first row at which it does, or -1
+}
+
+// CUSUM tests the regression of y on the columns of x, with its rows in time
+// order, for parameter drift by the CUSUM and CUSUM of squares of its
+// recursive residuals (Brown, Durbin & Evans, 1975), at 5% significance. A
+// change in the coefficients takes the CUSUM steadily away from zero, and a
+// change in the error variance the CUSUM of squares away from its line. See
+// RecursiveResiduals for the options; it needs at least two recursive
+// residuals.
+func CUSUM(x *DataFrame, y []float64, opts ...Option) (*StabilityResult, error) {
+	w, start, err := RecursiveResiduals(x, y, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m := len(w)
+	if m < 2 {
+		return nil, fmt.Errorf("%w: %d recursive residuals", TooFewObservationsError, m)
+	}
+	sigma := sd(w)
+	ss := sum(prod(w, w))
+	if !(sigma > 0) {
+		return nil, fmt.Errorf("the recursive residuals are constant, so the model fits exactly")
+	}
+
+	res := &StabilityResult{
+		RecursiveResiduals:   w,
+		Start:                start,
+		CUSUM:                make([]float64, m),
+		CUSUMBound:           make([]float64, m),
+		CUSUMSquares:         make([]float64, m),
+		SquaresBound:         cusumSquaresCritical / math.Sqrt(float64(m)/2),
+		FirstCrossing:        -1,
+		FirstSquaresCrossing: -1,
+	}
+	scale := sigma * math.Sqrt(float64(m))
+	cusum, squares := 0.0, 0.0
+	for i, e := range w {
+		cusum += e
+		squares += e * e
+		t := float64(i+1) / float64(m)
+		res.CUSUM[i] = cusum / scale
+		res.CUSUMBound[i] = cusumCritical * (1 + 2*t)
+		res.CUSUMSquares[i] = squares / ss
+		if !res.Crossed && math.Abs(res.CUSUM[i]) > res.CUSUMBound[i] {
+			res.Crossed, res.FirstCrossing = true, start+i
+		}
+		if !res.SquaresCrossed && math.Abs(res.CUSUMSquares[i]-t) > res.SquaresBound {
+			res.SquaresCrossed, res.FirstSquaresCrossing = true, start+i
+		}
+	}
+	return res, nil
+}
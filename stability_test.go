@@ -0,0 +1,93 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestRecursiveResiduals(t *testing.T) {
+	// (y_t - x_t'b_{t-1}) / sqrt(1 + x_t'(X_{t-1}'X_{t-1})^{-1} x_t), the
+	// definition of strucchange's recresid(lm(stack.loss ~ ., stackloss)),
+	// hand-derived in exact rational arithmetic up to the square roots;
+	// strucchange hasn't been run against these
+	want := []float64{1.016168991701771, -4.0470386482029745, -7.47253930174773, -0.5822096001991355, -2.6874483885583964, 1.2268896474979003, 1.769479907215473, 0.34214805359435874, -2.5835981098858074, -1.1632907743964362, 2.808842756898447, 1.124538734236636, 0.11204577442027261, 0.5624573639373371, 0.7103157831783408, 1.4255361850613517, -8.556707495142037}
+	w, start, err := RecursiveResiduals(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, start)
+	assertCloseSlices(t, w, want, 1e-10)
+
+	// the squares of the recursive residuals add up to the residual sum of
+	// squares of the full fit
+	assertClose(t, sum(prod(w, w)), summary.SumOfSquares(), 1e-9)
+}
+
+func TestCUSUM(t *testing.T) {
+	// the cumulative sums of the recursive residuals over sd(w) sqrt(n - p),
+	// as strucchange's efp(stack.loss ~ ., stackloss, type = "Rec-CUSUM")
+	// defines its process, without the leading zero; hand-derived from the
+	// recursive residuals above, not strucchange's output
+	want := []float64{0.07703158506650976, -0.22975774273458252, -0.796220163440245, -0.8403550747694826, -1.0440794659343795, -0.9510740160335903, -0.8169370337902352, -0.7910001993551911, -0.9868521287139557, -1.0750364100458782, -0.8621096119115249, -0.7768629631017986, -0.7683692345250746, -0.7257316588558304, -0.6718855455392322, -0.5638215199670278, -1.212470265091029}
+	res, err := CUSUM(NewDataFrame(data), y)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, res.Start)
+	assertCloseSlices(t, res.CUSUM, want, 1e-10)
+	m := float64(len(want))
+	assertClose(t, res.CUSUMBound[0], 0.948*(1+2/m), 1e-12)
+	assertClose(t, res.CUSUMBound[len(want)-1], 3*0.948, 1e-12)
+	assertClose(t, res.CUSUMSquares[len(want)-1], 1, 1e-12)
+	assertClose(t, res.SquaresBound, 1.3581015157406195/math.Sqrt(m/2), 1e-12)
+	assert.T(t, !res.Crossed)
+	assert.Equal(t, -1, res.FirstCrossing)
+}
+
+func TestCUSUMBreak(t *testing.T) {
+	// the slope changes halfway through, and the CUSUM crosses its boundary
+	// after it; the CUSUM of squares sees the error variance change, as the
+	// quiet first half leaves it below its line
+	rng := rand.New(rand.NewSource(1))
+	n := 100
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		x := rng.Float64() * 10
+		rows[i] = []float64{x}
+		response[i] = 1 + 2*x + rng.NormFloat64()
+		if i >= n/2 {
+			response[i] = 1 + 3*x + 4*rng.NormFloat64()
+		}
+	}
+	res, err := CUSUM(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, res.Start)
+	assert.T(t, res.Crossed)
+	assert.T(t, res.FirstCrossing > n/2)
+	assert.T(t, res.SquaresCrossed)
+	assert.T(t, res.CUSUMSquares[n/2-res.Start] < 0.5)
+
+	// without the change neither crosses
+	res, err = CUSUM(NewDataFrame(rows[:n/2]), response[:n/2])
+	assert.Equal(t, nil, err)
+	assert.T(t, !res.Crossed)
+	assert.T(t, !res.SquaresCrossed)
+	assert.Equal(t, -1, res.FirstSquaresCrossing)
+}
+
+func TestCUSUMErrors(t *testing.T) {
+	_, err := CUSUM(NewDataFrame(data), y[1:])
+	assert.Equal(t, DimensionError, err)
+	_, err = CUSUM(NewDataFrame(data[:5]), y[:5])
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+	_, _, err = RecursiveResiduals(NewDataFrame(data[:3]), y[:3])
+	assert.T(t, errors.Is(err, TooFewObservationsError))
+
+	// the first rows are all alike, so the recursive residuals
+	// start once they aren't
+	rows := [][]float64{{1}, {1}, {1}, {2}, {3}, {5}, {4}}
+	_, start, err := RecursiveResiduals(NewDataFrame(rows), []float64{1, 2, 3, 3, 5, 4, 6})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 4, start)
+}
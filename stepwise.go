@@ -0,0 +1,142 @@
+package glasso
+
+import "fmt"
+
+// A Direction is the kind of step a stepwise search may take.
+type Direction int
+
+const (
+	StepForward  Direction = iota // add predictors to the intercept-only model
+	StepBackward                  // remove predictors from the full model
+	StepBoth                      // add or remove predictors, starting from the full model
+)
+
+// A Criterion scores a fit for model selection, lower being better. AIC, AICc
+// and BIC are criteria.
+type Criterion func(Summary) float64
+
+// A Step adds a predictor to a model or removes one from it.
+type Step struct {
+	Column int    // the column of the predictor
+	Name   string // the label of the column, if the data has labels
+	Added  bool   // whether the predictor was added rather than removed
+
+	Before, After float64 // the criterion of the model before and after the step
+}
+
+// StepwiseFit is the model a stepwise search ends at.
+type StepwiseFit struct {
+	Model   *OLS
+	Summary OlsSummary
+	Columns []int  // the columns of the data in the model, in order
+	Steps   []Step // the path of the search
+}
+
+// Stepwise selects predictors for a least squares fit by a greedy search, as
+// R's step does. Each step makes the addition or removal of one predictor that
+// lowers the criterion most, and the search stops when no step lowers it. The
+// intercept is always kept. Candidate models that are rank deficient are
+// skipped.
+//
+// The criteria differ from those R's step prints (its extractAIC drops the
+// constants of the log-likelihood) by a constant, so the path is the same.
+func Stepwise(x *DataFrame, y []float64, direction Direction, criterion Criterion) (*StepwiseFit, error) {
+	if criterion == nil {
+		return nil, fmt.Errorf("criterion not set")
+	}
+	if direction != StepForward && direction != StepBackward && direction != StepBoth {
+		return nil, fmt.Errorf("unknown direction %d", direction)
+	}
+	if len(y) != x.Rows() {
+		return nil, DimensionError
+	}
+
+	in := make([]bool, x.Cols())
+	if direction != StepForward {
+		for j := range in {
+			in[j] = true
+		}
+	}
+	model, summary, err := fitColumns(x, y, in)
+	if err != nil {
+		return nil, err
+	}
+	current := criterion(summary)
+
+	var steps []Step
+	for {
+		best := -1
+		var (
+			bestModel   *OLS
+			bestSummary OlsSummary
+			bestScore   float64
+		)
+		// removals are tried before additions, each in column order, as in R
+		for _, removing := range []bool{true, false} {
+			if removing && direction == StepForward || !removing && direction == StepBackward {
+				continue
+			}
+			for j := range in {
+				if in[j] != removing {
+					continue
+				}
+				in[j] = !in[j]
+				m, s, err := fitColumns(x, y, in)
+				in[j] = !in[j]
+				if err != nil {
+					continue
+				}
+				if score := criterion(s); best < 0 || score < bestScore {
+					best, bestModel, bestSummary, bestScore = j, m, s, score
+				}
+			}
+		}
+		if best < 0 || bestScore >= current-1e-7 {
+			break
+		}
+
+		step := Step{Column: best, Added: !in[best], Before: current, After: bestScore}
+		if labels := x.Labels(); best < len(labels) {
+			step.Name = labels[best]
+		}
+		steps = append(steps, step)
+		in[best] = !in[best]
+		model, summary, current = bestModel, bestSummary, bestScore
+	}
+
+	var columns []int
+	for j, ok := range in {
+		if ok {
+			columns = append(columns, j)
+		}
+	}
+	return &StepwiseFit{
+		Model:   model,
+		Summary: summary,
+		Columns: columns,
+		Steps:   steps,
+	}, nil
+}
+
+// fitColumns fits least squares on the columns of x that are in the model.
+func fitColumns(x *DataFrame, y []float64, in []bool) (*OLS, OlsSummary, error) {
+	labels := x.Labels()
+	var names []string
+	rows := make([][]float64, x.Rows())
+	for j, ok := range in {
+		if !ok {
+			continue
+		}
+		for i := range rows {
+			rows[i] = append(rows[i], x.X.At(i, j))
+		}
+		if j < len(labels) {
+			names = append(names, labels[j])
+		}
+	}
+	sub := NewDataFrame(rows)
+	if len(labels) > 0 {
+		sub = NewDataFrame(rows, names)
+	}
+	return trainLeastSquares(sub, y, nil)
+}
@@ -0,0 +1,153 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+)
+
+// the rest of mtcars
+var (
+	mtcarsMpg = []float64{21.0, 21.0, 22.8, 21.4, 18.7, 18.1, 14.3, 24.4, 22.8, 19.2, 17.8, 16.4, 17.3,
+		15.2, 10.4, 10.4, 14.7, 32.4, 30.4, 33.9, 21.5, 15.5, 15.2, 13.3, 19.2, 27.3, 26.0, 30.4, 15.8,
+		19.7, 15.0, 21.4}
+	mtcarsCyl  = []float64{6, 6, 4, 6, 8, 6, 8, 4, 4, 6, 6, 8, 8, 8, 8, 8, 8, 4, 4, 4, 4, 8, 8, 8, 8, 4, 4, 4, 8, 6, 8, 4}
+	mtcarsDisp = []float64{160.0, 160.0, 108.0, 258.0, 360.0, 225.0, 360.0, 146.7, 140.8, 167.6, 167.6,
+		275.8, 275.8, 275.8, 472.0, 460.0, 440.0, 78.7, 75.7, 71.1, 120.1, 318.0, 304.0, 350.0, 400.0,
+		79.0, 120.3, 95.1, 351.0, 145.0, 301.0, 121.0}
+	mtcarsDrat = []float64{3.90, 3.90, 3.85, 3.08, 3.15, 2.76, 3.21, 3.69, 3.92, 3.92, 3.92, 3.07, 3.07,
+		3.07, 2.93, 3.00, 3.23, 4.08, 4.93, 4.22, 3.70, 2.76, 3.15, 3.73, 3.08, 4.08, 4.43, 3.77, 4.22,
+		3.62, 3.54, 4.11}
+	mtcarsQsec = []float64{16.46, 17.02, 18.61, 19.44, 17.02, 20.22, 15.84, 20.00, 22.90, 18.30, 18.90,
+		17.40, 17.60, 18.00, 17.98, 17.82, 17.42, 19.47, 18.52, 19.90, 20.01, 16.87, 17.30, 15.41, 17.05,
+		18.90, 16.70, 16.90, 14.50, 15.50, 14.60, 18.60}
+	mtcarsVs   = []float64{0, 0, 1, 1, 0, 1, 0, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0, 1}
+	mtcarsGear = []float64{4, 4, 4, 3, 3, 3, 3, 4, 4, 4, 4, 3, 3, 3, 3, 3, 3, 4, 4, 4, 3, 3, 3, 3, 3, 4, 5, 5, 5, 5, 5, 4}
+	mtcarsCarb = []float64{4, 4, 1, 1, 2, 1, 4, 2, 2, 4, 4, 3, 3, 3, 4, 4, 4, 1, 2, 1, 1, 2, 2, 4, 2, 1, 2, 2, 4, 6, 8, 2}
+)
+
+// mtcarsPredictors is mtcars without mpg, with its column names.
+func mtcarsPredictors() *DataFrame {
+	x := mtcarsFrame(mtcarsCyl, mtcarsDisp, mtcarsHp, mtcarsDrat, mtcarsWt, mtcarsQsec, mtcarsVs,
+		mtcarsAm, mtcarsGear, mtcarsCarb)
+	x.labels = []string{"cyl", "disp", "hp", "drat", "wt", "qsec", "vs", "am", "gear", "carb"}
+	return x
+}
+
+// extractAIC returns the AIC that R's step prints, n log(RSS / n) + 2 edf.
+func extractAIC(m Summary) float64 {
+	n := float64(m.Data().Rows())
+	return n*math.Log(m.SumOfSquares()/n) + 2*float64(m.Data().Cols())
+}
+
+func TestStepwise(t *testing.T) {
+	// step(lm(mpg ~ ., mtcars))
+	fit, err := Stepwise(mtcarsPredictors(), mtcarsMpg, StepBackward, extractAIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := []string{"cyl", "vs", "carb", "gear", "drat", "disp", "hp"}
+	aic := []float64{70.9, 68.92, 66.97, 65.12, 63.46, 62.16, 61.52, 61.31}
+	if len(fit.Steps) != len(removed) {
+		t.Fatalf("got %d steps, want %d: %+v", len(fit.Steps), len(removed), fit.Steps)
+	}
+	for k, step := range fit.Steps {
+		if step.Name != removed[k] || step.Added {
+			t.Errorf("step %d: got %+v, want removing %s", k, step, removed[k])
+		}
+		if math.Abs(step.Before-aic[k]) > 0.006 || math.Abs(step.After-aic[k+1]) > 0.006 {
+			t.Errorf("step %d: AIC went from %v to %v, want %v to %v", k, step.Before, step.After, aic[k], aic[k+1])
+		}
+	}
+	if want := []int{4, 5, 7}; len(fit.Columns) != 3 || fit.Columns[0] != want[0] || fit.Columns[1] != want[1] || fit.Columns[2] != want[2] {
+		t.Errorf("got columns %v, want %v", fit.Columns, want)
+	}
+	// lm(mpg ~ wt + qsec + am, mtcars)
+	for j, want := range []float64{9.6178, -3.9165, 1.2259, 2.9358} {
+		if b := fit.Summary.Coefficients()[j]; math.Abs(b-want) > 5e-5 {
+			t.Errorf("coefficient %d is %v, want %v", j, b, want)
+		}
+	}
+	if got, want := fit.Model.Predict([]float64{3, 18, 1}), sum(prod([]float64{1, 3, 18, 1}, fit.Summary.Coefficients())); math.Abs(got-want) > 1e-10 {
+		t.Errorf("prediction is %v, want %v", got, want)
+	}
+
+	// step(lm(mpg ~ 1, mtcars), scope = ~ cyl + disp + ..., direction = "forward")
+	fit, err = Stepwise(mtcarsPredictors(), mtcarsMpg, StepForward, extractAIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := []string{"wt", "cyl", "hp"}
+	aic = []float64{115.94, 73.22, 63.2, 62.66}
+	if len(fit.Steps) != len(added) {
+		t.Fatalf("got %d steps, want %d: %+v", len(fit.Steps), len(added), fit.Steps)
+	}
+	for k, step := range fit.Steps {
+		if step.Name != added[k] || !step.Added {
+			t.Errorf("step %d: got %+v, want adding %s", k, step, added[k])
+		}
+		if math.Abs(step.Before-aic[k]) > 0.006 || math.Abs(step.After-aic[k+1]) > 0.006 {
+			t.Errorf("step %d: AIC went from %v to %v, want %v to %v", k, step.Before, step.After, aic[k], aic[k+1])
+		}
+	}
+}
+
+func TestStepwiseCriteria(t *testing.T) {
+	// AIC differs from extractAIC by a constant, so the path is the same
+	x := mtcarsPredictors()
+	byAIC, err := Stepwise(x, mtcarsMpg, StepBoth, AIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byExtract, err := Stepwise(x, mtcarsMpg, StepBoth, extractAIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAIC.Steps) != len(byExtract.Steps) {
+		t.Fatalf("got %d steps by AIC and %d by extractAIC", len(byAIC.Steps), len(byExtract.Steps))
+	}
+	for k := range byAIC.Steps {
+		if byAIC.Steps[k].Column != byExtract.Steps[k].Column {
+			t.Errorf("step %d: got column %d by AIC and %d by extractAIC", k, byAIC.Steps[k].Column, byExtract.Steps[k].Column)
+		}
+	}
+	if x.Cols() != 10 {
+		t.Errorf("Stepwise changed the number of columns of the DataFrame to %d", x.Cols())
+	}
+
+	// BIC penalizes more, so it keeps no more predictors
+	byBIC, err := Stepwise(x, mtcarsMpg, StepBoth, BIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byBIC.Columns) > len(byAIC.Columns) {
+		t.Errorf("BIC kept %v, more than AIC's %v", byBIC.Columns, byAIC.Columns)
+	}
+	for k, step := range byBIC.Steps {
+		if !(step.After < step.Before) {
+			t.Errorf("step %d didn't lower the criterion: %+v", k, step)
+		}
+	}
+
+	// without labels the steps have no names
+	unnamed := mtcarsFrame(mtcarsWt, mtcarsHp)
+	fit, err := Stepwise(unnamed, mtcarsMpg, StepForward, AIC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fit.Steps) == 0 || fit.Steps[0].Name != "" || fit.Steps[0].Column != 0 {
+		t.Errorf("got steps %+v, want the first adding unnamed column 0", fit.Steps)
+	}
+}
+
+func TestStepwiseInvalid(t *testing.T) {
+	x := mtcarsPredictors()
+	if _, err := Stepwise(x, mtcarsMpg, StepBoth, nil); err == nil {
+		t.Error("expected an error for no criterion")
+	}
+	if _, err := Stepwise(x, mtcarsMpg, Direction(7), AIC); err == nil {
+		t.Error("expected an error for an unknown direction")
+	}
+	if _, err := Stepwise(x, mtcarsMpg[1:], StepBoth, AIC); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+}
@@ -0,0 +1,230 @@
+package glasso
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DefaultBlockRows is the number of rows of the blocks ObservationBlocks
+// gathers by default.
+const DefaultBlockRows = 4096
+
+// An ObservationIterator supplies the observations of a regression one row at
+// a time, such as the records of a file too large to read at once. Next
+// returns the predictors and the response of the next row, with ok false once
+// there are none left. The predictors needn't be copied: they are only read
+// until the following call.
+type ObservationIterator interface {
+	Next() (x []float64, y float64, ok bool)
+}
+
+// ObservationBlocks returns a RowIterator over the rows of rows, size at a
+// time, or DefaultBlockRows if size isn't positive, for FitStream,
+// SummarizeStream and StreamLeverage. The blocks have the column labels
+// labels, which may be nil. A row of another length than the first is a
+// DimensionError.
+func ObservationBlocks(rows ObservationIterator, size int, labels []string) RowIterator {
+	if size <= 0 {
+		size = DefaultBlockRows
+	}
+	return &observationBlocks{rows: rows, size: size, labels: labels, cols: -1}
+}
+
+type observationBlocks struct {
+	rows   ObservationIterator
+	size   int
+	labels []string
+	cols   int // the length of the first row, or -1 before it
+	read   int // the rows read so far
+	done   bool
+}
+
+func (b *observationBlocks) Next() (*DataFrame, []float64, error) {
+	if b.done {
+		return nil, nil, io.EOF
+	}
+	var x, y []float64
+	for len(y) < b.size {
+		row, v, ok := b.rows.Next()
+		if !ok {
+			b.done = true
+			break
+		}
+		if b.cols < 0 {
+			b.cols = len(row)
+		} else if len(row) != b.cols {
+			return nil, nil, fmt.Errorf("%w: row %d has %d values after rows of %d", DimensionError, b.read, len(row), b.cols)
+		}
+		x = append(x, row...)
+		y = append(y, v)
+		b.read++
+	}
+	if len(y) == 0 {
+		return nil, nil, io.EOF
+	}
+	df := &DataFrame{X: &mat.Dense{}, c: b.cols, n: len(y), labels: b.labels}
+	if b.cols > 0 {
+		df.X = mat.NewDense(len(y), b.cols, x)
+	}
+	return df, y, nil
+}
+
+// A StreamSummary is the fit of a model to observations supplied a block at a
+// time, from a second pass over them. See SummarizeStream.
+type StreamSummary struct {
+	N int // the number of observations
+
+	// RSS is the residual sum of squares and TSS the total sum of squares of
+	// the response around its mean, or around zero for a model without an
+	// intercept, as OlsSummary.TotalSumofSquares has it.
+	RSS, TSS float64
+
+	// RSquared and AdjustedRSquared are those of OlsSummary.
+	RSquared, AdjustedRSquared float64
+
+	// Model is the model with the residual variance RSS / (n - p) and the
+	// covariance matrix of the coefficients of this exact RSS.
+	Model *OLS
+}
+
+// SummarizeStream makes a second pass over the observations of a model fit by
+// FitStream, which rows supplies again from the start, for the residual sum of
+// squares as the sum of the squared residuals rather than y'y - \beta'X'y, which
+// loses most of its digits when R² is near one, and for the total sum of squares
+// and R² that FitStream doesn't compute. The memory used is again that of a
+// block and p^2, since X'X is accumulated a second time for the covariance
+// matrix of the coefficients. The rows must be those of the fit: a block of
+// another number of columns is a DimensionError, as is a different number of
+// rows in all. WithContext stops the pass between blocks.
+func SummarizeStream(m *OLS, rows RowIterator, opts ...Option) (*StreamSummary, error) {
+	o := newOptions(opts)
+	intercept := !m.noIntercept
+	normal := newNormalEquations(len(m.betas))
+	var rss, tss, mean float64
+	for {
+		if err := o.ctx.Err(); err != nil {
+			return nil, err
+		}
+		x, y, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStreamBlock(m, x, y); err != nil {
+			return nil, err
+		}
+		for i, v := range y {
+			e := v - m.Predict(x.X.RawRowView(i))
+			rss += e * e
+			if !intercept {
+				tss += v * v
+				continue
+			}
+			// Welford's update, for the sum of squares around the mean in one
+			// pass without the cancellation of \sum y^2 - n \bar y^2
+			k := float64(normal.n + i + 1)
+			d := v - mean
+			mean += d / k
+			tss += d * (v - mean)
+		}
+		normal.add(designBlock(x, intercept), y)
+	}
+	n, p := normal.n, len(m.betas)
+	if n != m.n {
+		return nil, fmt.Errorf("%w: %d rows for a model fit to %d", DimensionError, n, m.n)
+	}
+	_, r, err := normal.solve()
+	if err != nil {
+		return nil, err
+	}
+	rinv := &mat.TriDense{}
+	if err := rinv.InverseTri(r); err != nil {
+		return nil, fmt.Errorf("%w: %v", SingularDesignError, err)
+	}
+
+	model := *m
+	model.sigma2 = math.NaN()
+	if n > p {
+		model.sigma2 = rss / float64(n-p)
+	}
+	model.vcov = &mat.Dense{}
+	model.vcov.Mul(rinv, rinv.T())
+	model.vcov.Scale(model.sigma2, model.vcov)
+
+	dft := float64(n)
+	if intercept {
+		dft--
+	}
+	return &StreamSummary{
+		N:                n,
+		RSS:              rss,
+		TSS:              tss,
+		RSquared:         1 - rss/tss,
+		AdjustedRSquared: 1 - (rss*dft)/(tss*float64(n-p)),
+		Model:            &model,
+	}, nil
+}
+
+// StreamLeverage makes a pass over the observations of a model fit by
+// FitStream, which rows supplies again from the start, and calls each with the
+// leverages h_ii and the residuals of each block in turn, for the diagnostics of
+// StandardizedResiduals or CooksDistance without holding the design or the
+// leverages of every row at once. The leverage of a row is
+// x_i'(X'X)^{-1}x_i, from the covariance matrix of the coefficients, so that
+// the model must have a positive residual variance: that of the model of
+// SummarizeStream is the exact one. An error from each stops the pass and is
+// returned, as is a block of another number of columns than the model's
+// predictors, a DimensionError. WithContext stops the pass between blocks.
+func StreamLeverage(m *OLS, rows RowIterator, each func(h, residuals []float64) error, opts ...Option) error {
+	if !(m.sigma2 > 0) {
+		return fmt.Errorf("the leverages need a positive residual variance, but the model has %v", m.sigma2)
+	}
+	o := newOptions(opts)
+	for {
+		if err := o.ctx.Err(); err != nil {
+			return err
+		}
+		x, y, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkStreamBlock(m, x, y); err != nil {
+			return err
+		}
+		h := make([]float64, len(y))
+		residuals := make([]float64, len(y))
+		for i, v := range y {
+			row := x.X.RawRowView(i)
+			se, err := m.SEFit(row)
+			if err != nil {
+				return err
+			}
+			h[i] = se * se / m.sigma2
+			residuals[i] = v - m.Predict(row)
+		}
+		if err := each(h, residuals); err != nil {
+			return err
+		}
+	}
+}
+
+// checkStreamBlock checks that a block of rows has a response for each row and
+// a column for each predictor of m.
+func checkStreamBlock(m *OLS, x *DataFrame, y []float64) error {
+	if len(y) != x.Rows() {
+		return DimensionError
+	}
+	if x.Cols() != m.predictors() {
+		return fmt.Errorf("%w: a block of %d columns for a model of %d predictors", DimensionError, x.Cols(), m.predictors())
+	}
+	return nil
+}
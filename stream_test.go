@@ -0,0 +1,150 @@
+package glasso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// sliceObservations is an ObservationIterator over rows, which reuses the
+// slice it returns.
+type sliceObservations struct {
+	rows     [][]float64
+	response []float64
+	next     int
+	buf      []float64
+}
+
+func (s *sliceObservations) Next() ([]float64, float64, bool) {
+	if s.next >= len(s.rows) {
+		return nil, 0, false
+	}
+	s.buf = append(s.buf[:0], s.rows[s.next]...)
+	s.next++
+	return s.buf, s.response[s.next-1], true
+}
+
+func TestObservationBlocks(t *testing.T) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(5)), 2000, 3)
+	labels := []string{"a", "b", "c"}
+	for _, intercept := range []bool{true, false} {
+		m, s, err := NewOlsTrainer(WithIntercept(intercept)).Train(NewDataFrame(rows, labels), response)
+		assert.Equal(t, nil, err)
+		batch, summary := m.(*OLS), s.(OlsSummary)
+		observations := func() RowIterator {
+			return ObservationBlocks(&sliceObservations{rows: rows, response: response}, 300, labels)
+		}
+
+		model, err := FitStream(observations(), WithIntercept(intercept))
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, model.Coefficients(), batch.Coefficients(), 1e-10)
+		assert.Equal(t, model.Names(), batch.Names())
+		assert.Equal(t, 2000, model.NumObs())
+
+		stream, err := SummarizeStream(model, observations())
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2000, stream.N)
+		assertClose(t, stream.RSS, summary.ResidualSumofSquares(), 1e-9)
+		assertClose(t, stream.TSS, summary.TotalSumofSquares(), 1e-9)
+		assertClose(t, stream.RSquared, summary.RSquared(), 1e-12)
+		assertClose(t, stream.AdjustedRSquared, summary.AdjustedRSquared(), 1e-12)
+		assertClose(t, stream.Model.ResidualStandardError(), summary.ResidualStandardError(), 1e-12)
+		for j := range batch.betas {
+			assertCloseSlices(t, stream.Model.vcov.RawRowView(j), batch.vcov.RawRowView(j), 1e-12)
+		}
+		assert.Equal(t, model.Coefficients(), stream.Model.Coefficients())
+
+		// the leverages and residuals of a third pass are those of the
+		// summary, a block at a time
+		want, err := LeveragePoints(summary)
+		assert.Equal(t, nil, err)
+		var h, residuals []float64
+		err = StreamLeverage(stream.Model, observations(), func(hb, eb []float64) error {
+			assert.T(t, len(hb) <= 300)
+			h, residuals = append(h, hb...), append(residuals, eb...)
+			return nil
+		})
+		assert.Equal(t, nil, err)
+		assertCloseSlices(t, h, want, 1e-12)
+		assertCloseSlices(t, residuals, summary.Residuals(), 1e-9)
+	}
+}
+
+func TestStreamSummaryPrecision(t *testing.T) {
+	// a fit with an R² near one, whose y'y - \beta'X'y has lost most of the
+	// digits of the RSS
+	rng := rand.New(rand.NewSource(6))
+	rows := make([][]float64, 1000)
+	response := make([]float64, len(rows))
+	for i := range rows {
+		rows[i] = []float64{rng.NormFloat64()}
+		response[i] = 1e6 + 1e3*rows[i][0] + 1e-4*rng.NormFloat64()
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	rss := s.(OlsSummary).ResidualSumofSquares()
+	model, err := FitStream(ObservationBlocks(&sliceObservations{rows: rows, response: response}, 0, nil))
+	assert.Equal(t, nil, err)
+	stream, err := SummarizeStream(model, ObservationBlocks(&sliceObservations{rows: rows, response: response}, 0, nil))
+	assert.Equal(t, nil, err)
+	assertClose(t, stream.RSS, rss, 1e-6*rss)
+	assert.T(t, math.Abs(model.sigma2*998-rss) > 1e-3*rss)
+}
+
+// raggedObservations returns rows of one value and then one of two.
+type raggedObservations struct{ next int }
+
+func (r *raggedObservations) Next() ([]float64, float64, bool) {
+	r.next++
+	if r.next > 5 {
+		return []float64{1, 2}, 0, true
+	}
+	return []float64{float64(r.next)}, 0, true
+}
+
+func TestStreamErrors(t *testing.T) {
+	rows, response := wellConditioned(rand.New(rand.NewSource(7)), 50, 2)
+	_, err := FitStream(ObservationBlocks(&raggedObservations{}, 3, nil))
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, err = FitStream(ObservationBlocks(&sliceObservations{}, 0, nil))
+	assert.NotEqual(t, nil, err)
+
+	model, err := FitStream(&blockRows{rows: rows, response: response, block: 10})
+	assert.Equal(t, nil, err)
+	// fewer rows than the fit, or other columns
+	_, err = SummarizeStream(model, &blockRows{rows: rows[:40], response: response[:40], block: 10})
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, err = SummarizeStream(model, &widening{})
+	assert.T(t, errors.Is(err, DimensionError), err)
+	err = StreamLeverage(model, &widening{}, func(h, e []float64) error { return nil })
+	assert.T(t, errors.Is(err, DimensionError), err)
+	_, err = SummarizeStream(model, &failingRows{blockRows{rows: rows, response: response, block: 10}})
+	assert.Equal(t, "read failed", err.Error())
+
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err = StreamLeverage(model, &blockRows{rows: rows, response: response, block: 10}, func(h, e []float64) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SummarizeStream(model, &blockRows{rows: rows, response: response, block: 10}, WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+	err = StreamLeverage(model, &blockRows{rows: rows, response: response, block: 10}, nil, WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+
+	// as many rows as coefficients leave no residual variance
+	exact, err := FitStream(&blockRows{rows: rows[:3], response: response[:3], block: 10})
+	assert.Equal(t, nil, err)
+	err = StreamLeverage(exact, &blockRows{rows: rows[:3], response: response[:3], block: 10}, nil)
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,238 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SubsetResult is the best subset of the predictors of some size.
+type SubsetResult struct {
+	Columns []int    // the columns of the data in the subset, in order
+	Names   []string // their labels, if the data has labels
+
+	RSS              float64
+	RSquared         float64
+	AdjustedRSquared float64
+	Cp               float64 // Mallows' Cp, with the residual variance of the full model
+	BIC              float64 // as BIC of the least squares fit on the subset
+}
+
+// BestSubsetsFit is the result of a best subset search.
+type BestSubsetsFit struct {
+	Best []SubsetResult // the subset of each size 1, 2, ... with the smallest RSS
+
+	Subsets  int  // subsets visited by the search
+	Complete bool // whether the search finished, so that Best are the best subsets
+}
+
+// BestSubsets finds, for each size up to maxSize, the subset of the predictors
+// whose least squares fit (with an intercept, which every subset has) has the
+// smallest residual sum of squares, as leaps::regsubsets does.
+//
+// The search is a branch and bound over the subsets in the manner of Furnival
+// & Wilson (1974). Fits are updated by sweeping the cross-product matrix of
+// the centered data, one predictor at a time, and the subsets that extend a
+// set of predictors with some of the remaining ones are skipped when the fit
+// on all of them is no better than the best subsets of the sizes they could
+// have. The search visits at most the maximum number of iterations
+// (WithMaxIterations) subsets. If it stops early, Complete is false and Best
+// are the best subsets found.
+//
+// Cp and the adjusted R^2 are those of regsubsets, and BIC differs from its
+// (relative) BIC by a constant for the data, so they rank subsets alike.
+// Predictors that are linear combinations of those already in a subset are
+// not added to it.
+func BestSubsets(x *DataFrame, y []float64, maxSize int, opts ...Option) (*BestSubsetsFit, error) {
+	o := newOptions(opts)
+	if o.maxIter < 1 {
+		return nil, fmt.Errorf("need a positive number of iterations")
+	}
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if maxSize < 1 || maxSize > p {
+		return nil, fmt.Errorf("subset size %d is not between 1 and the %d predictors", maxSize, p)
+	}
+	if n <= p+1 {
+		return nil, fmt.Errorf("%w: %d observations for Cp with %d predictors", TooFewObservationsError, n, p)
+	}
+
+	s := newSweepSearch(x, y, maxSize, o.maxIter)
+	tss := s.cross[p][p]
+	if tss == 0 {
+		return nil, fmt.Errorf("response is constant")
+	}
+	full := s.cross
+	for j := 0; j < p; j++ {
+		if swept := s.sweep(full, j); swept != nil {
+			full = swept
+		}
+	}
+	sigma2 := full[p][p] / float64(n-p-1)
+
+	// predictors that matter most in the full fit go first, so that the
+	// remaining ones, whose fit bounds the search, matter least
+	drop := make([]float64, p)
+	for j := range drop {
+		if full[j][j] < 0 {
+			drop[j] = full[j][p] * full[j][p] / -full[j][j]
+		}
+	}
+	s.order = make([]int, p)
+	for j := range s.order {
+		s.order[j] = j
+	}
+	sort.SliceStable(s.order, func(a, b int) bool { return drop[s.order[a]] > drop[s.order[b]] })
+
+	s.search(s.cross, nil, 0)
+
+	fit := &BestSubsetsFit{Subsets: s.visited, Complete: !s.stopped}
+	labels := x.Labels()
+	for k := 1; k <= maxSize; k++ {
+		columns := s.best[k]
+		if columns == nil {
+			break
+		}
+		sort.Ints(columns)
+		rss, size := s.rss[k], float64(k)
+		result := SubsetResult{
+			Columns:          columns,
+			RSS:              rss,
+			RSquared:         1 - rss/tss,
+			AdjustedRSquared: 1 - rss/float64(n-k-1)/(tss/float64(n-1)),
+			Cp:               mallowsCp(rss, sigma2, n, float64(k+1)),
+			BIC:              float64(n)*(math.Log(2*math.Pi)+math.Log(rss/float64(n))+1) + math.Log(float64(n))*(size+2),
+		}
+		if len(labels) > 0 {
+			for _, j := range columns {
+				result.Names = append(result.Names, labels[j])
+			}
+		}
+		fit.Best = append(fit.Best, result)
+	}
+	return fit, nil
+}
+
+// sweepSearch is the state of a best subset search. cross is the cross-product
+// matrix of the centered predictors and response, with the response last.
+type sweepSearch struct {
+	cross    [][]float64
+	diagonal []float64 // the diagonal of cross before any sweeps
+	order    []int     // the order in which predictors are considered
+	maxSize  int
+
+	best [][]int   // the best subset of each size found so far
+	rss  []float64 // and its RSS
+
+	visited, budget int
+	stopped         bool
+}
+
+func newSweepSearch(x *DataFrame, y []float64, maxSize, budget int) *sweepSearch {
+	n, p := x.Rows(), x.Cols()
+	columns := make([][]float64, p+1)
+	for j := 0; j < p; j++ {
+		columns[j] = subtractMean(x.GetCol(j))
+	}
+	columns[p] = subtractMean(y)
+	cross := make([][]float64, p+1)
+	for j := range cross {
+		cross[j] = make([]float64, p+1)
+		for k := 0; k <= j; k++ {
+			for i := 0; i < n; i++ {
+				cross[j][k] += columns[j][i] * columns[k][i]
+			}
+			cross[k][j] = cross[j][k]
+		}
+	}
+	s := &sweepSearch{
+		cross:    cross,
+		diagonal: make([]float64, p),
+		maxSize:  maxSize,
+		best:     make([][]int, maxSize+1),
+		rss:      make([]float64, maxSize+1),
+		budget:   budget,
+	}
+	for j := range s.diagonal {
+		s.diagonal[j] = cross[j][j]
+	}
+	for k := range s.rss {
+		s.rss[k] = math.Inf(1)
+	}
+	return s
+}
+
+// sweep returns a copy of a with predictor j swept in, or nil if j is a linear
+// combination of the predictors already swept in.
+func (s *sweepSearch) sweep(a [][]float64, j int) [][]float64 {
+	d := a[j][j]
+	if !(d > 1e-10*s.diagonal[j]) {
+		return nil
+	}
+	b := make([][]float64, len(a))
+	for r := range a {
+		b[r] = make([]float64, len(a))
+		for c := range a {
+			switch {
+			case r == j && c == j:
+				b[r][c] = -1 / d
+			case r == j:
+				b[r][c] = a[j][c] / d
+			case c == j:
+				b[r][c] = a[r][j] / d
+			default:
+				b[r][c] = a[r][c] - a[r][j]*a[j][c]/d
+			}
+		}
+	}
+	return b
+}
+
+// search visits the subset in, whose predictors are swept into a, and then
+// the subsets that extend it by predictors from position next of the order.
+func (s *sweepSearch) search(a [][]float64, in []int, next int) {
+	if s.visited == s.budget {
+		s.stopped = true
+		return
+	}
+	s.visited++
+	y := len(a) - 1
+	if k := len(in); k > 0 && a[y][y] < s.rss[k] {
+		s.rss[k] = a[y][y]
+		s.best[k] = append([]int(nil), in...)
+	}
+	if len(in) == s.maxSize || next == len(s.order) {
+		return
+	}
+
+	// no extension fits better than all of the remaining predictors
+	bound := a
+	for _, j := range s.order[next:] {
+		if swept := s.sweep(bound, j); swept != nil {
+			bound = swept
+		}
+	}
+	largest := len(in) + len(s.order) - next
+	if largest > s.maxSize {
+		largest = s.maxSize
+	}
+	useful := false
+	for k := len(in) + 1; k <= largest; k++ {
+		useful = useful || bound[y][y] < s.rss[k]
+	}
+	if !useful {
+		return
+	}
+
+	for position := next; position < len(s.order); position++ {
+		j := s.order[position]
+		if swept := s.sweep(a, j); swept != nil {
+			s.search(swept, append(in, j), position+1)
+		}
+		if s.stopped {
+			return
+		}
+	}
+}
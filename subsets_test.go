@@ -0,0 +1,239 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// R's swiss data: Agriculture, Examination, Education, Catholic, Infant.Mortality
+var swiss = [][]float64{
+	{17.0, 15, 12, 9.96, 22.2}, {45.1, 6, 9, 84.84, 22.2}, {39.7, 5, 5, 93.40, 20.2},
+	{36.5, 12, 7, 33.77, 20.3}, {43.5, 17, 15, 5.16, 20.6}, {35.3, 9, 7, 90.57, 26.6},
+	{70.2, 16, 7, 92.85, 23.6}, {67.8, 14, 8, 97.16, 24.9}, {53.3, 12, 7, 97.67, 21.0},
+	{45.2, 16, 13, 91.38, 24.4}, {64.5, 14, 6, 98.61, 24.5}, {62.0, 21, 12, 8.52, 16.5},
+	{67.5, 14, 7, 2.27, 19.1}, {60.7, 19, 12, 4.43, 22.7}, {69.3, 22, 5, 2.82, 18.7},
+	{72.6, 18, 2, 24.20, 21.2}, {34.0, 17, 8, 3.30, 20.0}, {19.4, 26, 28, 12.11, 20.2},
+	{15.2, 31, 20, 2.15, 10.8}, {73.0, 19, 9, 2.84, 20.0}, {59.8, 22, 10, 5.23, 18.0},
+	{55.1, 14, 3, 4.52, 22.4}, {50.9, 22, 12, 15.14, 16.7}, {54.1, 20, 6, 4.20, 15.3},
+	{71.2, 12, 1, 2.40, 21.0}, {58.1, 14, 8, 5.23, 23.8}, {63.5, 6, 3, 2.56, 18.0},
+	{60.8, 16, 10, 7.72, 16.3}, {26.8, 25, 19, 18.46, 20.9}, {49.5, 15, 8, 6.10, 22.5},
+	{85.9, 3, 2, 99.71, 15.1}, {84.9, 7, 6, 99.68, 19.8}, {89.7, 5, 2, 100.00, 18.3},
+	{78.2, 12, 6, 98.96, 19.4}, {64.9, 7, 3, 98.22, 20.2}, {75.9, 9, 9, 99.06, 17.8},
+	{84.6, 3, 3, 99.46, 16.3}, {63.1, 13, 13, 96.83, 18.1}, {38.4, 26, 12, 5.62, 20.3},
+	{7.7, 29, 11, 13.79, 20.5}, {16.7, 22, 13, 11.22, 18.9}, {17.6, 35, 32, 16.92, 23.0},
+	{37.6, 15, 7, 4.97, 20.0}, {18.7, 25, 7, 8.65, 19.5}, {1.2, 37, 53, 42.34, 18.0},
+	{46.6, 16, 29, 50.43, 18.2}, {27.7, 22, 29, 58.33, 19.3},
+}
+
+// swiss Fertility
+var swissFertility = []float64{80.2, 83.1, 92.5, 85.8, 76.9, 76.1, 83.8, 92.4, 82.4, 82.9, 87.1,
+	64.1, 66.9, 68.9, 61.7, 68.3, 71.7, 55.7, 54.3, 65.1, 65.5, 65.0, 56.6, 57.4, 72.5, 74.2, 72.0,
+	60.5, 58.3, 65.4, 75.5, 69.3, 77.3, 70.5, 79.4, 65.0, 92.2, 79.3, 70.4, 65.7, 72.7, 64.4, 77.6,
+	67.6, 35.0, 44.7, 42.8}
+
+// enumerateSubsets returns the smallest RSS of the least squares fits on the
+// subsets of each size of the columns of x.
+func enumerateSubsets(t *testing.T, x *DataFrame, y []float64) []float64 {
+	p := x.Cols()
+	best := rep(math.Inf(1), p+1)
+	for set := 1; set < 1<<uint(p); set++ {
+		in := make([]bool, p)
+		k := 0
+		for j := range in {
+			in[j] = set&(1<<uint(j)) != 0
+			if in[j] {
+				k++
+			}
+		}
+		_, s, err := fitColumns(x, y, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		best[k] = math.Min(best[k], s.SumOfSquares())
+	}
+	return best
+}
+
+func TestBestSubsets(t *testing.T) {
+	x := NewDataFrame(swiss, []string{"Agriculture", "Examination", "Education", "Catholic", "Infant.Mortality"})
+	fit, err := BestSubsets(x, swissFertility, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fit.Complete || len(fit.Best) != 5 {
+		t.Fatalf("got complete %v with %d sizes", fit.Complete, len(fit.Best))
+	}
+
+	// summary(regsubsets(Fertility ~ ., swiss))
+	names := [][]string{
+		{"Education"},
+		{"Education", "Catholic"},
+		{"Education", "Catholic", "Infant.Mortality"},
+		{"Agriculture", "Education", "Catholic", "Infant.Mortality"},
+		{"Agriculture", "Examination", "Education", "Catholic", "Infant.Mortality"},
+	}
+	rss := enumerateSubsets(t, x, swissFertility)
+	for k, best := range fit.Best {
+		if len(best.Names) != len(names[k]) {
+			t.Fatalf("size %d: got %v, want %v", k+1, best.Names, names[k])
+		}
+		for j := range names[k] {
+			if best.Names[j] != names[k][j] {
+				t.Errorf("size %d: got %v, want %v", k+1, best.Names, names[k])
+			}
+		}
+		if math.Abs(best.RSS-rss[k+1]) > 1e-8*rss[k+1] {
+			t.Errorf("size %d: RSS is %v, want %v", k+1, best.RSS, rss[k+1])
+		}
+
+		// the statistics of the least squares fit on the subset
+		in := make([]bool, x.Cols())
+		for _, j := range best.Columns {
+			in[j] = true
+		}
+		_, s, err := fitColumns(x, swissFertility, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(best.RSquared-s.RSquared()) > 1e-10 || math.Abs(best.AdjustedRSquared-s.AdjustedRSquared()) > 1e-10 {
+			t.Errorf("size %d: R^2 %v and adjusted %v, want %v and %v", k+1, best.RSquared, best.AdjustedRSquared, s.RSquared(), s.AdjustedRSquared())
+		}
+		if math.Abs(best.BIC-BIC(s)) > 1e-8 {
+			t.Errorf("size %d: BIC is %v, want %v", k+1, best.BIC, BIC(s))
+		}
+	}
+	// summary(lm(Fertility ~ ., swiss)): Multiple R-squared: 0.7067, Adjusted R-squared: 0.671
+	full := fit.Best[4]
+	if math.Abs(full.RSquared-0.7067) > 5e-5 || math.Abs(full.AdjustedRSquared-0.671) > 5e-4 {
+		t.Errorf("full model R^2 is %v and adjusted %v, want 0.7067 and 0.671", full.RSquared, full.AdjustedRSquared)
+	}
+	// Cp of the full model is p + 1, and without Examination R^2 is 0.6993
+	if math.Abs(full.Cp-6) > 1e-10 {
+		t.Errorf("full model Cp is %v, want 6", full.Cp)
+	}
+	if best := fit.Best[3]; math.Abs(best.RSquared-0.6993) > 5e-5 {
+		t.Errorf("R^2 without Examination is %v, want 0.6993", best.RSquared)
+	}
+}
+
+func TestMallowsCp(t *testing.T) {
+	x := NewDataFrame(swiss, []string{"Agriculture", "Examination", "Education", "Catholic", "Infant.Mortality"})
+	fit, err := BestSubsets(x, swissFertility, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, full, err := fitColumns(x, swissFertility, []bool{true, true, true, true, true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// summary(regsubsets(Fertility ~ ., swiss))$cp, here computed in exact
+	// arithmetic
+	want := []float64{35.204895261549, 18.486157795804, 8.178161595066, 5.032800234481, 6}
+	for k, best := range fit.Best {
+		in := make([]bool, x.Cols())
+		for _, j := range best.Columns {
+			in[j] = true
+		}
+		_, s, err := fitColumns(x, swissFertility, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp, err := MallowsCp(s, full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(cp-best.Cp) > 1e-10 || math.Abs(cp-want[k]) > 1e-9 {
+			t.Errorf("size %d: Cp is %v and %v from BestSubsets, want %v", k+1, cp, best.Cp, want[k])
+		}
+	}
+
+	// a model that isn't nested in the other, and one of another response
+	_, education, err := fitColumns(x, swissFertility, []bool{false, false, true, false, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MallowsCp(full, education); err == nil {
+		t.Error("expected an error for a model that isn't nested")
+	}
+	shifted := append([]float64(nil), swissFertility...)
+	shifted[0]++
+	_, other, err := fitColumns(x, shifted, []bool{false, false, true, false, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MallowsCp(other, full); !errors.Is(err, DimensionError) {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+}
+
+func TestBestSubsetsPruning(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	n, p := 60, 12
+	rows := make([][]float64, n)
+	response := make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		for j := range rows[i] {
+			rows[i][j] = rng.NormFloat64()
+		}
+		rows[i][p-1] = rows[i][0] + 0.5*rows[i][1] + 0.3*rng.NormFloat64()
+		response[i] = 2*rows[i][0] - rows[i][3] + 0.5*rows[i][7] + rng.NormFloat64()
+	}
+	x := NewDataFrame(rows)
+	fit, err := BestSubsets(x, response, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fit.Complete || fit.Subsets >= 1<<uint(p) {
+		t.Errorf("visited %d subsets (complete %v), want fewer than %d", fit.Subsets, fit.Complete, 1<<uint(p))
+	}
+	rss := enumerateSubsets(t, x, response)
+	for k, best := range fit.Best {
+		if len(best.Columns) != k+1 || best.Names != nil {
+			t.Errorf("size %d: got columns %v and names %v", k+1, best.Columns, best.Names)
+		}
+		if math.Abs(best.RSS-rss[k+1]) > 1e-8*rss[k+1] {
+			t.Errorf("size %d: RSS is %v, want %v", k+1, best.RSS, rss[k+1])
+		}
+	}
+	if want := []int{0, 3, 7}; fit.Best[2].Columns[0] != want[0] || fit.Best[2].Columns[1] != want[1] || fit.Best[2].Columns[2] != want[2] {
+		t.Errorf("best subset of 3 is %v, want %v", fit.Best[2].Columns, want)
+	}
+
+	// a smaller maximum size, and a budget that stops the search
+	small, err := BestSubsets(x, response, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(small.Best) != 3 || math.Abs(small.Best[2].RSS-fit.Best[2].RSS) > 1e-8 {
+		t.Errorf("got %d sizes, with RSS %v for 3", len(small.Best), small.Best[2].RSS)
+	}
+	stopped, err := BestSubsets(x, response, p, WithMaxIterations(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stopped.Complete || stopped.Subsets != 20 {
+		t.Errorf("got complete %v after %d subsets, want false after 20", stopped.Complete, stopped.Subsets)
+	}
+}
+
+func TestBestSubsetsInvalid(t *testing.T) {
+	x := NewDataFrame(swiss)
+	for _, size := range []int{0, 6} {
+		if _, err := BestSubsets(x, swissFertility, size); err == nil {
+			t.Errorf("expected an error for size %d", size)
+		}
+	}
+	if _, err := BestSubsets(x, swissFertility[1:], 3); err != DimensionError {
+		t.Errorf("got error %v, want DimensionError", err)
+	}
+	if _, err := BestSubsets(x, rep(1, len(swiss)), 3); err == nil {
+		t.Error("expected an error for a constant response")
+	}
+	if _, err := BestSubsets(NewDataFrame(swiss[:6]), swissFertility[:6], 3); err == nil {
+		t.Error("expected an error for too few observations")
+	}
+}
@@ -0,0 +1,174 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// svdFactors is the truncated singular value decomposition X ≈ U_k S_k V_k' of
+// a design, keeping the k singular values that FitSVD didn't discard.
+type svdFactors struct {
+	x *mat.Dense // the design that was factorized
+	u *mat.Dense // n x k, the left singular vectors kept
+	s []float64  // the k singular values kept, largest first
+	v *mat.Dense // p x k, the right singular vectors kept
+}
+
+// pseudoInverse returns the pseudoinverse V_k S_k^-2 V_k' of X'X.
+func (f *svdFactors) pseudoInverse() *mat.Dense {
+	w := mat.DenseCopyOf(f.v)
+	p, _ := w.Dims()
+	for i := 0; i < p; i++ {
+		row := w.RawRowView(i)
+		for k, s := range f.s {
+			row[k] /= s
+		}
+	}
+	inv := &mat.Dense{}
+	inv.Mul(w, w.T())
+	return inv
+}
+
+// FitSVD fits y on the columns of x by least squares from the singular value
+// decomposition X = U S V' of the design, with an intercept column unless
+// WithIntercept(false) is given. Singular values below rcond times the largest
+// are treated as zero, and the coefficients are the minimum-norm solution
+//
+// \beta = V_k S_k^-1 U_k' y
+//
+// over the k singular values kept. Where QR loses the digits of R^-1 to the
+// rounding of a nearly collinear design, the truncated solution discards the
+// directions the data can't determine instead; an rcond of about 1e-15 times
+// the larger dimension of x keeps all but those at rounding level, as LAPACK's
+// dgelss suggests. rcond must be in [0, 1). The singular values are those of
+// the design as given, intercept column included, so WithStandardize has no
+// effect. WithNAPolicy sets how missing values are handled.
+//
+// The model's Rank is k and its Condition is s_max/s_min over every singular
+// value, which Save and MarshalJSON keep. The residual variance is
+// RSS / (n - k), and the covariance matrix of the coefficients is
+// \sigma^2 V_k S_k^-2 V_k', which the summary's VarCov also returns; its
+// LeveragePoints and HatMatrix are from U_k, so the leverages sum to k. Diagnostics that need the triangular factor of a design
+// of full rank, such as CooksDistance, DFBETA, RobustVCov and
+// PredictInterval, are a SingularDesignError for a fit that discarded a
+// singular value.
+func FitSVD(x *DataFrame, y []float64, rcond float64, opts ...Option) (*OLS, OlsSummary, error) {
+	if !(rcond >= 0 && rcond < 1) {
+		return nil, OlsSummary{}, fmt.Errorf("rcond %v is not in [0, 1)", rcond)
+	}
+	o := newOptions(opts)
+	x, y, rows, err := handleMissing(x, y, o.na)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	n := x.Rows()
+	design := x.Copy()
+	design.labels = x.Labels()
+	if o.intercept {
+		design.PushCol(rep(1, n))
+	}
+	p := design.Cols()
+
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(design.X, mat.SVDThin); !ok {
+		return nil, OlsSummary{}, fmt.Errorf("singular value decomposition of the %d x %d design failed", n, p)
+	}
+	s := svd.Values(nil)
+	k := 0
+	for k < len(s) && s[k] > 0 && s[k] > rcond*s[0] {
+		k++
+	}
+	if k == 0 {
+		return nil, OlsSummary{}, fmt.Errorf("%w: the design is zero", SingularDesignError)
+	}
+	u, v := &mat.Dense{}, &mat.Dense{}
+	svd.UTo(u)
+	svd.VTo(v)
+	factors := &svdFactors{
+		x: design.X,
+		u: mat.DenseCopyOf(u.Slice(0, n, 0, k)),
+		s: s[:k],
+		v: mat.DenseCopyOf(v.Slice(0, p, 0, k)),
+	}
+
+	// c = S_k^-1 U_k' y, the coefficients on the singular vectors
+	c := mat.NewVecDense(k, nil)
+	c.MulVec(factors.u.T(), mat.NewVecDense(n, append([]float64(nil), y...)))
+	fitted := &mat.VecDense{}
+	fitted.MulVec(factors.u, c)
+	for j, sj := range factors.s {
+		c.SetVec(j, c.At(j, 0)/sj)
+	}
+	betas := &mat.VecDense{}
+	betas.MulVec(factors.v, c)
+
+	summary := OlsSummary{
+		betas:     mat.Col(nil, 0, betas),
+		residuals: make([]float64, n),
+		fitted:    mat.Col(nil, 0, fitted),
+		response:  append([]float64(nil), y...),
+		n:         n,
+		p:         p,
+		data:      design,
+		qr:        &qrCache{},
+		svd:       factors,
+		rows:      rows,
+		origin:    !o.intercept,
+	}
+	for i, yi := range y {
+		summary.residuals[i] = yi - summary.fitted[i]
+	}
+
+	sigma2, err := MseAdjusted(summary)
+	if err != nil {
+		sigma2 = math.NaN()
+	}
+	vcov, err := scaledVarCov(summary, sigma2)
+	if err != nil {
+		return nil, OlsSummary{}, err
+	}
+	return &OLS{
+		betas:       summary.betas,
+		n:           n,
+		p:           p,
+		names:       x.Labels(),
+		sigma2:      sigma2,
+		vcov:        vcov.X,
+		rows:        rows,
+		noIntercept: !o.intercept,
+		rank:        k,
+
+		condition:    s[0] / s[len(s)-1],
+		rawCondition: true,
+	}, summary, nil
+}
+
+// svdOf returns the truncated singular value decomposition of the summary's
+// design, or nil if it wasn't fit by FitSVD or the design has been replaced.
+func svdOf(m Summary) *svdFactors {
+	if s, ok := m.(OlsSummary); ok && s.svd != nil && s.svd.x == m.Data().X {
+		return s.svd
+	}
+	return nil
+}
+
+// rankOf returns the rank of the summary's fit: the number of singular values
+// kept for a fit by FitSVD, and otherwise the number of columns of its design.
+func rankOf(m Summary) int {
+	if f := svdOf(m); f != nil {
+		return len(f.s)
+	}
+	return m.Data().Cols()
+}
+
+// Rank returns the rank of the fit: the number of singular values kept for a
+// model fit by FitSVD, and otherwise the number of coefficients that weren't
+// aliased.
+func (o *OLS) Rank() int {
+	if o.rank > 0 {
+		return o.rank
+	}
+	return o.p - len(o.aliased)
+}
@@ -0,0 +1,153 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestFitSVDLongley(t *testing.T) {
+	m, s, err := FitSVD(NewDataFrame(longley), longleyY, 1e-13)
+	assert.Equal(t, nil, err)
+
+	// NIST's certified values for Longley, on the scale of R's longley
+	assertCloseSlices(t, m.Coefficients(), []float64{
+		-3482.25863459582, 0.0150618722713733, -0.0358191792925910, -0.0202022980381683,
+		-0.0103322686717359, -0.0511041056535807, 1.82915146461355,
+	}, 1e-8)
+	assertCloseSlices(t, StandardErrors(MatToDF(m.vcov)), []float64{
+		890.420383607373, 0.0849149257747669, 0.0334910077722432, 0.00488399681651699,
+		0.00214274163161675, 0.226073200069370, 0.455478499142212,
+	}, 1e-7)
+	assertCloseSlices(t, []float64{math.Sqrt(m.sigma2)}, []float64{0.304854073561965}, 1e-9)
+	assert.Equal(t, 7, m.Rank())
+
+	collinearity, err := CollinearityDiagnostics(s, false)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, []float64{m.Condition()}, []float64{collinearity.Indices[6]}, 1e-6)
+	if m.Condition() < 1e7 {
+		t.Errorf("condition number %v, want Longley's of about 1e9", m.Condition())
+	}
+
+	// nothing was discarded, so the diagnostics are those of the QR fit
+	_, qs, err := NewOlsTrainer(WithStandardize(false)).Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	h, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	qh, err := LeveragePoints(qs)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, h, qh, 1e-8)
+	assertClose(t, sum(h), 7, 1e-10)
+	assertCloseSlices(t, s.Residuals(), qs.Residuals(), 1e-6)
+	_, err = CooksDistance(s)
+	assert.Equal(t, nil, err)
+
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, CoefficientNames(qs), vcov.Labels())
+	for j := 0; j < 7; j++ {
+		assertCloseSlices(t, vcov.X.RawRowView(j), m.vcov.RawRowView(j), 1e-12)
+	}
+}
+
+func TestFitSVDTruncated(t *testing.T) {
+	// the third predictor duplicates the first, so the design has rank 3
+	rng := rand.New(rand.NewSource(5))
+	rows, response := deletionData(rng, 40, 2)
+	for i := range rows {
+		rows[i] = append(rows[i], rows[i][0])
+	}
+	m, s, err := FitSVD(NewDataFrame(rows), response, 1e-10)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, m.Rank())
+	if m.Condition() < 1e12 {
+		t.Errorf("condition number %v of a singular design", m.Condition())
+	}
+
+	// the minimum-norm solution splits the aliased fit's coefficient evenly
+	qm, qs, err := NewOlsTrainer(WithStandardize(false)).Train(NewDataFrame(rows), response)
+	assert.Equal(t, nil, err)
+	qb := qm.(*OLS).betas
+	assertCloseSlices(t, m.Coefficients(), []float64{qb[0], qb[1] / 2, qb[2], qb[1] / 2}, 1e-10)
+	assertCloseSlices(t, s.Yhat(), qs.Yhat(), 1e-10)
+	assertCloseSlices(t, []float64{m.sigma2}, []float64{qm.(*OLS).sigma2}, 1e-10)
+
+	h, err := LeveragePoints(s)
+	assert.Equal(t, nil, err)
+	qh, err := LeveragePoints(qs)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, h, qh, 1e-10)
+	assertClose(t, sum(h), 3, 1e-10)
+
+	// the covariance matrix is that of the aliased fit with the coefficient
+	// split in the same way, and the summary's agrees with the model's
+	vcov, err := VarCov(s)
+	assert.Equal(t, nil, err)
+	qv := qm.(*OLS).vcov
+	split := [][]float64{{1, 0, 0}, {0, 0.5, 0}, {0, 0, 1}, {0, 0.5, 0}}
+	for j := 0; j < 4; j++ {
+		for k := 0; k < 4; k++ {
+			want := 0.0
+			for a := 0; a < 3; a++ {
+				for b := 0; b < 3; b++ {
+					want += split[j][a] * qv.At(a, b) * split[k][b]
+				}
+			}
+			assertClose(t, m.vcov.At(j, k), want, 1e-10)
+			assertClose(t, vcov.X.At(j, k), want, 1e-10)
+		}
+	}
+	table, err := CoefficientTable(s)
+	assert.Equal(t, nil, err)
+	qtable, err := CoefficientTable(qs)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, []float64{table[2].PValue}, []float64{qtable[2].PValue}, 1e-8)
+
+	// without a full-rank R there is no Cook's distance
+	_, err = CooksDistance(s)
+	assert.T(t, errors.Is(err, SingularDesignError))
+
+	// a looser rcond discards more
+	m, _, err = FitSVD(NewDataFrame(longley), longleyY, 1e-5)
+	assert.Equal(t, nil, err)
+	if m.Rank() >= 7 {
+		t.Errorf("rank %d of Longley with rcond 1e-5", m.Rank())
+	}
+}
+
+func TestFitSVDUnderdetermined(t *testing.T) {
+	x := NewDataFrame([][]float64{{1, 2, 3, 4}, {2, 1, 0, 1}, {0, 1, 1, 5}})
+	response := []float64{1, 2, 3}
+	m, s, err := FitSVD(x, response, 1e-12, WithIntercept(false))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, m.Rank())
+	assert.T(t, math.IsNaN(m.sigma2))
+	for i, e := range s.Residuals() {
+		assertClose(t, e, 0, 1e-12)
+		assertClose(t, m.Predict(x.GetRow(i)), response[i], 1e-12)
+	}
+}
+
+func TestRank(t *testing.T) {
+	qm, _, err := NewOlsTrainer().Train(NewDataFrame(longley), longleyY)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 7, qm.(*OLS).Rank())
+	assert.T(t, !qm.(*OLS).rawCondition)
+}
+
+func TestFitSVDErrors(t *testing.T) {
+	x := NewDataFrame(longley)
+	for _, rcond := range []float64{-1, 1, math.NaN()} {
+		_, _, err := FitSVD(x, longleyY, rcond)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, err := FitSVD(x, longleyY[1:], 1e-12)
+	assert.Equal(t, DimensionError, err)
+
+	zero := NewDataFrame([][]float64{{0}, {0}, {0}})
+	_, _, err = FitSVD(zero, []float64{1, 2, 3}, 1e-12, WithIntercept(false))
+	assert.T(t, errors.Is(err, SingularDesignError))
+}
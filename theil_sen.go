@@ -0,0 +1,424 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// maxPairwiseSlopes is the number of pairwise slopes above which TheilSen
+// selects their median by counting rather than by sorting them.
+const maxPairwiseSlopes = 1 << 20
+
+// TheilSen returns the Theil-Sen estimate of the line through the points (x, y):
+// the slope is the median of the slopes (y_j - y_i) / (x_j - x_i) over every
+// pair of points, and the intercept is the median of y - slope x. Pairs with
+// equal x have no slope and are left out, so ties in x are harmless, but at
+// least two distinct values of x are needed.
+//
+// The estimate resists outliers in both x and y, with a breakdown point of
+// 1 - 1/\sqrt{2}, about 29%. For large n the median is selected without forming
+// the n(n - 1)/2 slopes, by at most 64 bisection steps of O(n \log n) each.
+func TheilSen(x, y []float64) (slope, intercept float64, err error) {
+	if len(x) != len(y) {
+		return 0, 0, DimensionError
+	}
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsInf(x[i], 0) || math.IsNaN(y[i]) || math.IsInf(y[i], 0) {
+			return 0, 0, fmt.Errorf("observation %d is not finite", i)
+		}
+	}
+
+	points := newSlopePoints(x, y)
+	if points.pairs == 0 {
+		return 0, 0, fmt.Errorf("theil sen estimator needs at least two distinct values of x")
+	}
+	if points.pairs <= maxPairwiseSlopes {
+		slope = points.sortedMedian()
+	} else {
+		slope = points.countedMedian()
+	}
+	return slope, median(subSlope(x, y, slope)), nil
+}
+
+// subSlope returns y - slope x.
+func subSlope(x, y []float64, slope float64) []float64 {
+	r := make([]float64, len(y))
+	for i := range y {
+		r[i] = y[i] - slope*x[i]
+	}
+	return r
+}
+
+// slopePoints are points sorted by x and then y, with the pairs among them
+// that have a slope.
+type slopePoints struct {
+	x, y  []float64
+	pairs int // pairs with distinct x
+}
+
+func newSlopePoints(x, y []float64) *slopePoints {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		return x[i] < x[j] || x[i] == x[j] && y[i] < y[j]
+	})
+	s := &slopePoints{x: make([]float64, len(x)), y: make([]float64, len(y))}
+	for k, i := range order {
+		s.x[k], s.y[k] = x[i], y[i]
+	}
+	n := len(x)
+	s.pairs = n * (n - 1) / 2
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && s.x[end] == s.x[start] {
+			end++
+		}
+		s.pairs -= (end - start) * (end - start - 1) / 2
+		start = end
+	}
+	return s
+}
+
+// sortedMedian forms and sorts every slope.
+func (s *slopePoints) sortedMedian() float64 {
+	slopes := make([]float64, 0, s.pairs)
+	for i := range s.x {
+		for j := i + 1; j < len(s.x); j++ {
+			if s.x[j] != s.x[i] {
+				slopes = append(slopes, (s.y[j]-s.y[i])/(s.x[j]-s.x[i]))
+			}
+		}
+	}
+	sort.Float64s(slopes)
+	return quantile(slopes, 0.5)
+}
+
+// countedMedian selects the median slope by bisection on the slope t, counting
+// the slopes at most t in O(n \log n) with countAtMost. The bisection runs over
+// the ordered bit patterns of float64s, so it takes at most 64 steps. Since
+// the comparisons are rounded, the result may differ from sortedMedian in the
+// last few bits.
+func (s *slopePoints) countedMedian() float64 {
+	if s.pairs%2 == 1 {
+		return s.kth(s.pairs / 2)
+	}
+	return (s.kth(s.pairs/2-1) + s.kth(s.pairs/2)) / 2
+}
+
+// kth returns the kth smallest slope (from zero), the smallest t with more
+// than k slopes at most t.
+func (s *slopePoints) kth(k int) float64 {
+	// every slope is bounded by the range of y over the smallest gap in x
+	gap, lo, hi := math.Inf(1), math.Inf(1), math.Inf(-1)
+	for i, v := range s.y {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+		if i > 0 && s.x[i] > s.x[i-1] {
+			gap = math.Min(gap, s.x[i]-s.x[i-1])
+		}
+	}
+	bound := 2*(hi-lo)/gap + 1
+
+	below, above := orderedBits(-bound), orderedBits(bound)
+	// the distance can exceed the range of an int64, but not of a uint64
+	for uint64(above)-uint64(below) > 1 {
+		mid := below + int64((uint64(above)-uint64(below))/2)
+		if s.countAtMost(fromOrderedBits(mid)) > k {
+			above = mid
+		} else {
+			below = mid
+		}
+	}
+	return fromOrderedBits(above)
+}
+
+// countAtMost counts the pairs i < j with distinct x and a slope at most t.
+// For x_i < x_j the slope is at most t when y_j - t x_j <= y_i - t x_i, so these
+// are the inversions of r = y - t x in the order of x, less the pairs with equal x.
+func (s *slopePoints) countAtMost(t float64) int {
+	r := subSlope(s.x, s.y, t)
+	count := 0
+	for start := 0; start < len(r); {
+		// with equal x, r is in the order of y, so only runs of equal r are inversions
+		end := start + 1
+		for end < len(r) && s.x[end] == s.x[start] && r[end] == r[end-1] {
+			end++
+		}
+		count -= (end - start) * (end - start - 1) / 2
+		start = end
+	}
+	return count + inversions(r, make([]float64, len(r)))
+}
+
+// inversions counts the pairs i < j with r_i >= r_j by merge sort, sorting r
+// and using buf as scratch space.
+func inversions(r, buf []float64) int {
+	if len(r) < 2 {
+		return 0
+	}
+	m := len(r) / 2
+	count := inversions(r[:m], buf[:m]) + inversions(r[m:], buf[m:])
+	a, b := 0, m
+	for k := range buf {
+		if b == len(r) || a < m && r[a] < r[b] {
+			buf[k] = r[a]
+			a++
+		} else {
+			// every r[a:m] is at least r[a] >= r[b]
+			count += m - a
+			buf[k] = r[b]
+			b++
+		}
+	}
+	copy(r, buf)
+	return count
+}
+
+// orderedBits maps a float64 to an int64 with the same order.
+func orderedBits(f float64) int64 {
+	b := int64(math.Float64bits(f))
+	if b < 0 {
+		b = math.MinInt64 - b
+	}
+	return b
+}
+
+func fromOrderedBits(b int64) float64 {
+	if b < 0 {
+		b = math.MinInt64 - b
+	}
+	return math.Float64frombits(uint64(b))
+}
+
+// TheilSenRegression is a multiple regression fit by the Theil-Sen estimator.
+type TheilSenRegression struct {
+	betas []float64
+}
+
+func (t *TheilSenRegression) Predict(x []float64) float64 {
+	return t.betas[0] + sum(prod(x, t.betas[1:]))
+}
+
+type theilSenTrainer struct {
+	opts options
+}
+
+// NewTheilSenTrainer returns a Trainer for the multivariate generalization of
+// the Theil-Sen estimator. Every elemental subset of p observations, for p
+// coefficients including the intercept, determines an exact fit, and the
+// estimate is the spatial median of those fits. When there are more subsets
+// than WithSubsets allows, that many are drawn at random (with the seed set by
+// WithSeed). The options also set the tolerance and maximum number of
+// iterations of the spatial median.
+func NewTheilSenTrainer(opts ...Option) Trainer {
+	return &theilSenTrainer{
+		opts: newOptions(opts),
+	}
+}
+
+// Train fits the model. The DataFrame is not modified, and the summary is a
+// *TheilSenSummary, whose design includes the intercept column. Since the
+// spatial median isn't invariant to the scale of the coefficients, predictors
+// on very different scales should be standardized first.
+func (t *theilSenTrainer) Train(x *DataFrame, y []float64) (Model, Summary, error) {
+	n := x.Rows()
+	if len(y) != n {
+		return nil, nil, DimensionError
+	}
+	if t.opts.subsets < 1 || t.opts.maxIter < 1 || !(t.opts.tolerance > 0) {
+		return nil, nil, fmt.Errorf("need a positive number of subsets, tolerance and number of iterations")
+	}
+	design := x.Copy()
+	design.labels = x.Labels()
+	design.PushCol(rep(1, n))
+	p := design.Cols()
+	if n < p {
+		return nil, nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p)
+	}
+
+	var fits [][]float64
+	solve := func(rows []int) {
+		a, b := mat.NewDense(p, p, nil), mat.NewDense(p, 1, nil)
+		for k, i := range rows {
+			a.SetRow(k, design.X.RawRowView(i))
+			b.Set(k, 0, y[i])
+		}
+		beta := &mat.Dense{}
+		if err := beta.Solve(a, b); err != nil {
+			return
+		}
+		coef := mat.Col(nil, 0, beta)
+		for _, c := range coef {
+			if math.IsNaN(c) || math.IsInf(c, 0) {
+				return
+			}
+		}
+		fits = append(fits, coef)
+	}
+	if subsetsAtMost(n, p, t.opts.subsets) {
+		// every combination, in lexicographic order
+		rows := make([]int, p)
+		for k := range rows {
+			rows[k] = k
+		}
+		for {
+			solve(rows)
+			k := p - 1
+			for k >= 0 && rows[k] == n-p+k {
+				k--
+			}
+			if k < 0 {
+				break
+			}
+			rows[k]++
+			for l := k + 1; l < p; l++ {
+				rows[l] = rows[l-1] + 1
+			}
+		}
+	} else {
+		rng := t.opts.rng()
+		order := make([]int, n)
+		for i := range order {
+			order[i] = i
+		}
+		for d := 0; d < t.opts.subsets; d++ {
+			// a partial Fisher-Yates shuffle draws p distinct rows
+			for k := 0; k < p; k++ {
+				j := k + rng.Intn(n-k)
+				order[k], order[j] = order[j], order[k]
+			}
+			solve(order[:p])
+		}
+	}
+	if len(fits) == 0 {
+		return nil, nil, fmt.Errorf("%w: every elemental subset is singular", SingularDesignError)
+	}
+
+	betas, iterations, converged := spatialMedian(fits, t.opts)
+	fitted := make([]float64, n)
+	residuals := make([]float64, n)
+	for i := range y {
+		fitted[i] = sum(prod(design.X.RawRowView(i), betas))
+		residuals[i] = y[i] - fitted[i]
+	}
+
+	return &TheilSenRegression{
+		betas: betas,
+	}, &TheilSenSummary{
+		data:       design,
+		betas:      betas,
+		fitted:     fitted,
+		residuals:  residuals,
+		response:   append([]float64(nil), y...),
+		subsets:    len(fits),
+		iterations: iterations,
+		converged:  converged,
+	}, nil
+}
+
+// subsetsAtMost reports whether there are at most limit subsets of size k of n items.
+func subsetsAtMost(n, k, limit int) bool {
+	c := 1.0
+	for i := 0; i < k; i++ {
+		c = c * float64(n-i) / float64(i+1)
+		if c > float64(limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// spatialMedian returns the point minimizing the sum of Euclidean distances to
+// the points, by the Weiszfeld iteration with the modification of Vardi & Zhang
+// (2000) for iterates that land on one of the points. It stops when a step is
+// smaller than the tolerance relative to the size of the estimate.
+func spatialMedian(points [][]float64, opts options) ([]float64, int, bool) {
+	p := len(points[0])
+	m := make([]float64, p)
+	for _, v := range points {
+		for j := range m {
+			m[j] += v[j] / float64(len(points))
+		}
+	}
+
+	for iteration := 1; iteration <= opts.maxIter; iteration++ {
+		next, pull := make([]float64, p), make([]float64, p)
+		total, coincident := 0.0, 0
+		for _, v := range points {
+			d := 0.0
+			for j := range m {
+				d += (v[j] - m[j]) * (v[j] - m[j])
+			}
+			d = math.Sqrt(d)
+			if d == 0 {
+				coincident++
+				continue
+			}
+			total += 1 / d
+			for j := range next {
+				next[j] += v[j] / d
+				pull[j] += (v[j] - m[j]) / d
+			}
+		}
+		if total == 0 {
+			return m, iteration, true
+		}
+		for j := range next {
+			next[j] /= total
+		}
+		if coincident > 0 {
+			// stay at the coincident point unless the others pull harder than it holds
+			gamma := math.Min(1, float64(coincident)/math.Sqrt(sum(prod(pull, pull))))
+			for j := range next {
+				next[j] = (1-gamma)*next[j] + gamma*m[j]
+			}
+		}
+
+		step, size := 0.0, 0.0
+		for j := range m {
+			step += (next[j] - m[j]) * (next[j] - m[j])
+			size += next[j] * next[j]
+		}
+		m = next
+		if math.Sqrt(step) <= opts.tolerance*math.Max(math.Sqrt(size), 1) {
+			return m, iteration, true
+		}
+	}
+	return m, opts.maxIter, false
+}
+
+// TheilSenSummary summarizes a Theil-Sen regression.
+type TheilSenSummary struct {
+	data       *DataFrame
+	betas      []float64
+	fitted     []float64
+	residuals  []float64
+	response   []float64
+	subsets    int
+	iterations int
+	converged  bool
+}
+
+func (t *TheilSenSummary) Data() *DataFrame        { return t.data }
+func (t *TheilSenSummary) Coefficients() []float64 { return t.betas }
+func (t *TheilSenSummary) Residuals() []float64    { return t.residuals }
+func (t *TheilSenSummary) Yhat() []float64         { return t.fitted }
+func (t *TheilSenSummary) Response() []float64     { return t.response }
+
+func (t *TheilSenSummary) SumOfSquares() float64 {
+	return sum(prod(t.residuals, t.residuals))
+}
+
+// Subsets returns the number of nonsingular elemental subsets the estimate is the median of.
+func (t *TheilSenSummary) Subsets() int { return t.subsets }
+
+// Iterations returns the number of iterations of the spatial median.
+func (t *TheilSenSummary) Iterations() int { return t.iterations }
+
+// Converged reports whether the spatial median converged within the maximum number of iterations.
+func (t *TheilSenSummary) Converged() bool { return t.converged }
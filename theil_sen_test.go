@@ -0,0 +1,183 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTheilSen(t *testing.T) {
+	// the slopes are -1, -1, 1/3, 1/2, 3/4, 1, 1, 4/3, 2 and 3
+	slope, intercept, err := TheilSen([]float64{1, 2, 3, 4, 5}, []float64{1, 3, 2, 5, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slope != 0.875 || intercept != 0.125 {
+		t.Errorf("got slope %v and intercept %v, want 0.875 and 0.125", slope, intercept)
+	}
+
+	// pairs with equal x are left out: the slopes are 1, 2, 2 and 3, and
+	// y - 2x is -2, -1, -2, -1
+	slope, intercept, err = TheilSen([]float64{1, 1, 2, 2}, []float64{0, 1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slope != 2 || intercept != -1.5 {
+		t.Errorf("ties: got slope %v and intercept %v, want 2 and -1.5", slope, intercept)
+	}
+}
+
+func TestTheilSenCounting(t *testing.T) {
+	rng := rand.New(rand.NewSource(43))
+	for _, n := range []int{2, 3, 50, 301} {
+		x, y := make([]float64, n), make([]float64, n)
+		for i := range x {
+			// rounding x and y makes ties in both
+			x[i] = math.Round(rng.Float64() * 20)
+			y[i] = math.Round(3*x[i] + 10*rng.NormFloat64())
+		}
+		x[0], x[1] = 0, 1
+		points := newSlopePoints(x, y)
+		want := points.sortedMedian()
+		if got := points.countedMedian(); math.Abs(got-want) > 1e-12*math.Max(math.Abs(want), 1) {
+			t.Errorf("n = %d (%d pairs): counted median %v, sorted median %v", n, points.pairs, got, want)
+		}
+		for _, s := range []float64{-1, 0.5, 2.75, 3} {
+			brute := 0
+			for i := range x {
+				for j := i + 1; j < n; j++ {
+					if x[i] != x[j] && (y[j]-y[i])/(x[j]-x[i]) <= s {
+						brute++
+					}
+				}
+			}
+			if got := points.countAtMost(s); got != brute {
+				t.Errorf("n = %d: %d slopes at most %v, want %d", n, got, s, brute)
+			}
+		}
+	}
+}
+
+// contaminated returns points on y = 2 + 0.5 x + 3 x2 with small errors, a
+// quarter of which are replaced by gross outliers at the largest x.
+func contaminated(rng *rand.Rand, n int) ([][]float64, []float64) {
+	x := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range x {
+		x[i] = []float64{float64(i), rng.Float64() * 10}
+		y[i] = 2 + 0.5*x[i][0] + 3*x[i][1] + 0.1*rng.NormFloat64()
+	}
+	for i := n - n/4; i < n; i++ {
+		y[i] = 200 + 5*rng.Float64()
+	}
+	return x, y
+}
+
+func TestTheilSenBreakdown(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	x, y := contaminated(rng, 40)
+	line := make([]float64, len(x))
+	for i := range x {
+		line[i] = x[i][0]
+		y[i] -= 3 * x[i][1]
+	}
+	slope, intercept, err := TheilSen(line, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(slope-0.5) > 0.05 || math.Abs(intercept-2) > 1 {
+		t.Errorf("got slope %v and intercept %v, want about 0.5 and 2", slope, intercept)
+	}
+	_, ols, err := NewOlsTrainer().Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(ols.Coefficients()[1]-0.5) < 1 {
+		t.Errorf("least squares slope %v is not broken down", ols.Coefficients()[1])
+	}
+}
+
+func TestTheilSenTrainer(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	x, y := contaminated(rng, 40)
+	model, summary, err := NewTheilSenTrainer().Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := summary.(*TheilSenSummary)
+	for j, want := range []float64{2, 0.5, 3} {
+		if got := s.Coefficients()[j]; math.Abs(got-want) > 0.5 {
+			t.Errorf("coefficient %d: got %v, want about %v", j, got, want)
+		}
+	}
+	// C(40, 3) = 9880 subsets are few enough to use them all
+	if s.Subsets() != 9880 || !s.Converged() {
+		t.Errorf("%d subsets, converged %v", s.Subsets(), s.Converged())
+	}
+	for i, fitted := range s.Yhat() {
+		if got := model.Predict(x[i]); math.Abs(got-fitted) > 1e-10 {
+			t.Errorf("prediction %d: got %v, want %v", i, got, fitted)
+		}
+	}
+
+	// random subsets are reproducible
+	fit := func(seed int64) []float64 {
+		_, s, err := NewTheilSenTrainer(WithSubsets(500), WithSeed(seed)).Train(NewDataFrame(x), y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.(*TheilSenSummary).Subsets() != 500 {
+			t.Errorf("%d subsets, want 500", s.(*TheilSenSummary).Subsets())
+		}
+		return s.Coefficients()
+	}
+	first, again, other := fit(1), fit(1), fit(2)
+	for j := range first {
+		if first[j] != again[j] {
+			t.Errorf("coefficient %d differs between fits with the same seed", j)
+		}
+		if math.Abs(first[j]-other[j]) > 0.5 {
+			t.Errorf("coefficient %d: %v and %v with different seeds", j, first[j], other[j])
+		}
+	}
+}
+
+func TestTheilSenExact(t *testing.T) {
+	// every elemental fit of points on a plane is the plane
+	x := [][]float64{{0, 1}, {1, 0}, {2, 3}, {3, 1}, {4, 4}, {1, 1}}
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = -1 + 2*x[i][0] - 0.5*x[i][1]
+	}
+	_, summary, err := NewTheilSenTrainer().Train(NewDataFrame(x), y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, want := range []float64{-1, 2, -0.5} {
+		if got := summary.Coefficients()[j]; math.Abs(got-want) > 1e-10 {
+			t.Errorf("coefficient %d: got %v, want %v", j, got, want)
+		}
+	}
+}
+
+func TestTheilSenInvalid(t *testing.T) {
+	if _, _, err := TheilSen([]float64{1, 2}, []float64{1}); err != DimensionError {
+		t.Errorf("short y: got %v", err)
+	}
+	if _, _, err := TheilSen([]float64{3, 3, 3}, []float64{1, 2, 3}); err == nil {
+		t.Error("constant x: expected an error")
+	}
+	if _, _, err := TheilSen([]float64{1, math.NaN()}, []float64{1, 2}); err == nil {
+		t.Error("NaN: expected an error")
+	}
+	if _, _, err := NewTheilSenTrainer().Train(NewDataFrame([][]float64{{1, 2}, {2, 3}}), []float64{1, 2}); err == nil {
+		t.Error("too few observations: expected an error")
+	}
+	if _, _, err := NewTheilSenTrainer(WithSubsets(0)).Train(NewDataFrame(data), y); err == nil {
+		t.Error("no subsets: expected an error")
+	}
+	// x2 = 2 x1 in every row, so every subset is singular
+	if _, _, err := NewTheilSenTrainer().Train(NewDataFrame([][]float64{{1, 2}, {2, 4}, {3, 6}, {4, 8}}), []float64{1, 2, 3, 5}); err == nil {
+		t.Error("collinear design: expected an error")
+	}
+}
@@ -0,0 +1,126 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// FitTLS fits the line y = intercept + slope x by Deming regression, for a
+// predictor measured with error as well as the response. delta is the ratio
+// \sigma^2_y / \sigma^2_x of the variances of their errors, and with the
+// sample moments s_xx, s_yy and s_xy the slope is
+//
+// \frac{s_{yy} - \delta s_{xx} + \sqrt{(s_{yy} - \delta s_{xx})^2 + 4\delta s_{xy}^2}}{2 s_{xy}}
+//
+// A delta of one is orthogonal regression, the total least squares of
+// FitTLSMulti, and as delta grows to infinity, which it may be, the slope is
+// that of least squares on x. Where least squares on x attenuates the slope
+// toward zero, the Deming slope does not.
+func FitTLS(x, y []float64, delta float64) (slope, intercept float64, err error) {
+	n := len(x)
+	if len(y) != n {
+		return 0, 0, DimensionError
+	}
+	if n < 2 {
+		return 0, 0, fmt.Errorf("%w: %d observations for a line", TooFewObservationsError, n)
+	}
+	if !(delta > 0) {
+		return 0, 0, fmt.Errorf("variance ratio %v is not positive", delta)
+	}
+	xbar, ybar := mean(x), mean(y)
+	var sxx, syy, sxy float64
+	for i := range x {
+		dx, dy := x[i]-xbar, y[i]-ybar
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	if sxy == 0 {
+		return 0, 0, fmt.Errorf("x and y are uncorrelated, so the Deming slope is undefined")
+	}
+	if math.IsInf(delta, 1) {
+		slope = sxy / sxx
+	} else {
+		d := syy - delta*sxx
+		slope = (d + math.Sqrt(d*d+4*delta*sxy*sxy)) / (2 * sxy)
+	}
+	return slope, ybar - slope*xbar, nil
+}
+
+// TLSFit is a total least squares fit, the hyperplane through the centroid of
+// the points (x_i, y_i) that minimizes the sum of their squared orthogonal
+// distances to it.
+type TLSFit struct {
+	// Coefficients are those of y = \beta_0 + x'\beta on the hyperplane, the
+	// intercept first.
+	Coefficients []float64
+	// Center is the centroid of the points, the means of the columns of x
+	// and then of y, which the hyperplane passes through.
+	Center []float64
+	// Subspace is a (p + 1) x p matrix whose orthonormal columns span the
+	// directions of the hyperplane, and Normal is the unit vector
+	// orthogonal to it.
+	Subspace *mat.Dense
+	Normal   []float64
+	// Residuals are the signed orthogonal distances of the points to the
+	// hyperplane, along Normal.
+	Residuals []float64
+}
+
+// FitTLSMulti fits y on the columns of x by total least squares, for
+// predictors measured with errors of the same variance as the response. The
+// hyperplane is found from the right singular vectors of the centered
+// augmented matrix [X y]: that of its smallest singular value is the normal
+// v, and \beta = -v_x / v_y. A normal with no component along y is a
+// hyperplane parallel to the response, which has no coefficients, and is an
+// error.
+func FitTLSMulti(x *DataFrame, y []float64) (*TLSFit, error) {
+	n, p := x.Rows(), x.Cols()
+	if len(y) != n {
+		return nil, DimensionError
+	}
+	if n <= p {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients", TooFewObservationsError, n, p+1)
+	}
+	fit := &TLSFit{Center: make([]float64, p+1)}
+	for j := 0; j < p; j++ {
+		fit.Center[j] = mean(x.GetCol(j))
+	}
+	fit.Center[p] = mean(y)
+	z := mat.NewDense(n, p+1, nil)
+	for i := 0; i < n; i++ {
+		row := z.RawRowView(i)
+		for j := 0; j < p; j++ {
+			row[j] = x.X.At(i, j) - fit.Center[j]
+		}
+		row[p] = y[i] - fit.Center[p]
+	}
+
+	svd := &mat.SVD{}
+	if ok := svd.Factorize(z, mat.SVDThin); !ok {
+		return nil, fmt.Errorf("the singular value decomposition failed")
+	}
+	v := &mat.Dense{}
+	svd.VTo(v)
+	fit.Normal = mat.Col(nil, p, v)
+	fit.Subspace = mat.DenseCopyOf(v.Slice(0, p+1, 0, p))
+	vy := fit.Normal[p]
+	if math.Abs(vy) <= 1e-12 {
+		return nil, fmt.Errorf("%w: the hyperplane is parallel to the response", SingularDesignError)
+	}
+
+	fit.Coefficients = make([]float64, p+1)
+	fit.Coefficients[0] = fit.Center[p]
+	for j := 0; j < p; j++ {
+		b := -fit.Normal[j] / vy
+		fit.Coefficients[j+1] = b
+		fit.Coefficients[0] -= b * fit.Center[j]
+	}
+	fit.Residuals = make([]float64, n)
+	for i := range fit.Residuals {
+		fit.Residuals[i] = sum(prod(z.RawRowView(i), fit.Normal))
+	}
+	return fit, nil
+}
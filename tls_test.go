@@ -0,0 +1,148 @@
+package glasso
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+var (
+	demingX = []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	demingY = []float64{1.8, 4.1, 5.9, 8.3, 9.7, 12.4, 13.8, 16.2}
+)
+
+func TestFitTLS(t *testing.T) {
+	// the closed form, here computed to 40 digits
+	for _, c := range []struct{ delta, slope, intercept float64 }{
+		{1, 2.030408355778973992, -0.111837601005382966},
+		{4, 2.028847864806610739, -0.104815391629748326},
+		{0.25, 2.031133345481837590, -0.115100054668269156},
+	} {
+		slope, intercept, err := FitTLS(demingX, demingY, c.delta)
+		assert.Equal(t, nil, err)
+		assertClose(t, slope, c.slope, 1e-13)
+		assertClose(t, intercept, c.intercept, 1e-13)
+	}
+
+	// a delta of one is the total least squares of the SVD
+	slope, intercept, err := FitTLS(demingX, demingY, 1)
+	assert.Equal(t, nil, err)
+	rows := make([][]float64, len(demingX))
+	for i, v := range demingX {
+		rows[i] = []float64{v}
+	}
+	fit, err := FitTLSMulti(NewDataFrame(rows), demingY)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, fit.Coefficients, []float64{intercept, slope}, 1e-12)
+
+	// as delta grows it tends to least squares on x, and as it shrinks to
+	// least squares of x on y
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), demingY)
+	assert.Equal(t, nil, err)
+	ols := s.Coefficients()
+	previous := math.Inf(1)
+	for _, delta := range []float64{1e2, 1e4, 1e6, 1e8} {
+		slope, _, err := FitTLS(demingX, demingY, delta)
+		assert.Equal(t, nil, err)
+		assert.T(t, math.Abs(slope-ols[1]) < previous)
+		previous = math.Abs(slope - ols[1])
+	}
+	assert.T(t, previous < 1e-8, previous)
+	slope, intercept, err = FitTLS(demingX, demingY, math.Inf(1))
+	assert.Equal(t, nil, err)
+	assertClose(t, slope, ols[1], 1e-12)
+	assertClose(t, intercept, ols[0], 1e-12)
+	slope, _, err = FitTLS(demingX, demingY, 1e-10)
+	assert.Equal(t, nil, err)
+	assertClose(t, slope, 2.031433607520564042, 1e-8)
+
+	for _, delta := range []float64{0, -1, math.NaN()} {
+		_, _, err := FitTLS(demingX, demingY, delta)
+		assert.NotEqual(t, nil, err)
+	}
+	_, _, err = FitTLS(demingX, demingY[1:], 1)
+	assert.Equal(t, DimensionError, err)
+	_, _, err = FitTLS([]float64{1, 2, 3}, []float64{1, 0, 1}, 1)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestFitTLSMulti(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	n, p := 200, 3
+	beta := []float64{1, 2, -1, 0.5}
+	rows := make([][]float64, n)
+	exact, noisy := make([]float64, n), make([]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, p)
+		exact[i] = beta[0]
+		for j := range rows[i] {
+			rows[i][j] = 3 * rng.NormFloat64()
+			exact[i] += beta[j+1] * rows[i][j]
+		}
+		noisy[i] = exact[i] + 0.3*rng.NormFloat64()
+	}
+
+	// points on a hyperplane are fit exactly
+	fit, err := FitTLSMulti(NewDataFrame(rows), exact)
+	assert.Equal(t, nil, err)
+	assertCloseSlices(t, fit.Coefficients, beta, 1e-10)
+	for _, r := range fit.Residuals {
+		assertClose(t, r, 0, 1e-10)
+	}
+
+	// the normal is the eigenvector of the smallest eigenvalue of Z'Z for the
+	// centered points Z, the residuals are the distances along it, and the
+	// subspace completes it to an orthonormal basis
+	fit, err = FitTLSMulti(NewDataFrame(rows), noisy)
+	assert.Equal(t, nil, err)
+	z := mat.NewDense(n, p+1, nil)
+	for i, row := range rows {
+		for j, v := range append(append([]float64(nil), row...), noisy[i]) {
+			z.Set(i, j, v-fit.Center[j])
+		}
+	}
+	ztz := &mat.Dense{}
+	ztz.Mul(z.T(), z)
+	eigen := &mat.Eigen{}
+	assert.T(t, eigen.Factorize(ztz, mat.EigenNone))
+	values := eigen.Values(nil)
+	smallest := math.Inf(1)
+	for _, v := range values {
+		smallest = math.Min(smallest, real(v))
+	}
+	zv := mat.NewVecDense(p+1, nil)
+	zv.MulVec(ztz, mat.NewVecDense(p+1, fit.Normal))
+	assertCloseSlices(t, zv.RawVector().Data, multSlice(fit.Normal, smallest), 1e-8)
+	ss := 0.0
+	for i, r := range fit.Residuals {
+		assertClose(t, r, sum(prod(z.RawRowView(i), fit.Normal)), 1e-12)
+		ss += r * r
+	}
+	assertClose(t, ss, smallest, 1e-8*smallest)
+	basis := mat.NewDense(p+1, p+1, nil)
+	basis.Copy(fit.Subspace)
+	basis.SetCol(p, fit.Normal)
+	gram := &mat.Dense{}
+	gram.Mul(basis.T(), basis)
+	for i := 0; i <= p; i++ {
+		for j := 0; j <= p; j++ {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			assertClose(t, gram.At(i, j), want, 1e-12)
+		}
+	}
+	for j := range beta {
+		assertClose(t, fit.Coefficients[j], beta[j], 0.1)
+	}
+
+	_, err = FitTLSMulti(NewDataFrame(rows), noisy[1:])
+	assert.Equal(t, DimensionError, err)
+	_, err = FitTLSMulti(NewDataFrame(rows[:3]), noisy[:3])
+	assert.T(t, errors.Is(err, TooFewObservationsError), err)
+}
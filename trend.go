@@ -0,0 +1,83 @@
+package glasso
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TrendTest tests the linear, quadratic, cubic, ... components of the trend
+// of the response in column j of the model's design, such as a dose, up to
+// maxDegree, adjusting for the other columns. The column is replaced by the
+// orthogonal polynomials of a PolynomialBasis built from its values, which
+// needn't be equally spaced, and the model is refit with them entered after
+// the other columns, lowest degree first. The test of degree k is the F test
+// of its sequential sum of squares, as Anova finds it,
+//
+// F_k = \frac{SS_k}{RSS / (n - p)}
+//
+// on 1 and n - p degrees of freedom, for the p coefficients of the refit:
+// the reduction in RSS from adding the polynomial of degree k to the others
+// and those of lower degree, against the residual variance of the refit. The
+// components partition the sum of squares of the trend, so their tests are
+// independent, and F_k is the square of the t statistic of the polynomial
+// when the other columns are orthogonal to it. There is one result for each
+// degree, in order.
+//
+// j indexes the coefficients, as it does for AddedVariable, and can't be the
+// intercept. The column needs more distinct values than maxDegree. A model fit
+// to a transformed problem, such as weighted least squares, only has its
+// transformed design and is an error.
+func TrendTest(m Summary, j int, maxDegree int) ([]TestResult, error) {
+	if s, ok := m.(OlsSummary); ok && s.transformed() {
+		return nil, fmt.Errorf("a model fit to a transformed problem doesn't have the columns of its design; test the trend on the original data")
+	}
+	x := m.Data()
+	n, p := x.Rows(), x.Cols()
+	intercept := interceptOf(m)
+	switch {
+	case j < 0 || j >= p:
+		return nil, fmt.Errorf("column %d is out of range for %d coefficients", j, p)
+	case j == intercept:
+		return nil, fmt.Errorf("column %d is the intercept, which has no trend", j)
+	}
+	basis, err := NewPolynomialBasis(x.GetCol(j), maxDegree, true)
+	if err != nil {
+		return nil, err
+	}
+	q := p - 1 + maxDegree
+	if n <= q {
+		return nil, fmt.Errorf("%w: %d observations for %d coefficients of the refit", TooFewObservationsError, n, q)
+	}
+
+	z := mat.NewDense(n, q, nil)
+	for k, c := 0, 0; k < p; k++ {
+		if k != j {
+			z.SetCol(c, x.GetCol(k))
+			c++
+		}
+	}
+	for k, column := range basis.Expand(x.GetCol(j)) {
+		z.SetCol(p-1+k, column)
+	}
+	_, refit, err := fitLeastSquares(MatToDF(z), m.Response(), nil, false, QRSolver)
+	if err != nil {
+		return nil, err
+	}
+	table, err := Anova(refit)
+	if err != nil {
+		return nil, err
+	}
+	// the rows of the polynomials are the last before the residuals
+	rows := table.Rows[len(table.Rows)-1-maxDegree : len(table.Rows)-1]
+	results := make([]TestResult, maxDegree)
+	for k, row := range rows {
+		results[k] = TestResult{
+			Statistic: row.F,
+			DF:        1,
+			DF2:       float64(n - q),
+			PValue:    row.PValue,
+		}
+	}
+	return results, nil
+}
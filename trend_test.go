@@ -0,0 +1,88 @@
+package glasso
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// doseResponse returns three replicates of a response at the unequally spaced
+// doses 0, 0.5, 1, 2, 4 and 8, with a covariate w.
+func doseResponse() (*DataFrame, []float64) {
+	doses := []float64{0, 0.5, 1, 2, 4, 8}
+	w := []float64{3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9, 3, 2, 3}
+	y := []float64{1.2, 1.5, 1.9, 2.2, 3.1, 3.8, 1.3, 1.7, 1.8, 2.5, 3.0, 4.1, 1.1, 1.6, 2.0, 2.4, 3.3, 3.6}
+	rows := make([][]float64, len(y))
+	for i := range rows {
+		rows[i] = []float64{w[i], doses[i%len(doses)]}
+	}
+	return NewDataFrame(rows, []string{"w", "dose"}), y
+}
+
+func TestTrendTest(t *testing.T) {
+	x, y := doseResponse()
+	_, s, err := NewOlsTrainer().Train(x, y)
+	assert.Equal(t, nil, err)
+	results, err := TrendTest(s, 2, 3)
+	assert.Equal(t, nil, err)
+
+	// hand-derived: the sequential sums of squares of dose, dose^2 and dose^3
+	// entered after w, which are those of the orthogonal polynomials, in
+	// exact rational arithmetic, over the residual variance of the full fit,
+	// with the p-values from a 50-digit incomplete beta function
+	want := []struct{ f, p float64 }{
+		{646.3679198614997, 1.8057977743809054e-12},
+		{45.179395226637546, 1.4228544413888045e-05},
+		{0.7075207869966378, 0.41547263899167736},
+	}
+	assert.Equal(t, 3, len(results))
+	for k, r := range results {
+		assertClose(t, r.Statistic, want[k].f, 1e-9*want[k].f)
+		assertClose(t, r.PValue, want[k].p, 1e-6*want[k].p)
+		assert.Equal(t, 1.0, r.DF)
+		assert.Equal(t, 13.0, r.DF2)
+	}
+
+	// the test of the highest degree is that of its coefficient in the refit
+	w, dose := s.Data().GetCol(1), s.Data().GetCol(2)
+	basis, err := NewPolynomialBasis(dose, 3, true)
+	assert.Equal(t, nil, err)
+	rows := make([][]float64, len(y))
+	expanded := basis.Expand(dose)
+	for i := range rows {
+		rows[i] = []float64{w[i], expanded[0][i], expanded[1][i], expanded[2][i]}
+	}
+	_, refit, err := NewOlsTrainer().Train(NewDataFrame(rows), y)
+	assert.Equal(t, nil, err)
+	table, err := CoefficientTable(refit)
+	assert.Equal(t, nil, err)
+	assertClose(t, results[2].Statistic, table[4].T*table[4].T, 1e-9)
+	assertClose(t, results[2].PValue, table[4].PValue, 1e-9)
+
+	// a lower degree tests the same components, against a larger residual
+	linear, err := TrendTest(s, 2, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 15.0, linear[0].DF2)
+	assert.T(t, linear[0].Statistic < results[0].Statistic)
+}
+
+func TestTrendTestErrors(t *testing.T) {
+	x, y := doseResponse()
+	_, s, err := NewOlsTrainer().Train(x, y)
+	assert.Equal(t, nil, err)
+	_, err = TrendTest(s, 0, 2)
+	assert.NotEqual(t, nil, err)
+	_, err = TrendTest(s, 3, 2)
+	assert.NotEqual(t, nil, err)
+	// six doses have no polynomial of degree six
+	_, err = TrendTest(s, 2, 6)
+	assert.NotEqual(t, nil, err)
+	_, err = TrendTest(s, 2, 0)
+	assert.NotEqual(t, nil, err)
+
+	x, y = doseResponse()
+	_, weighted, err := NewWlsTrainer(rep(2, len(y))).Train(x, y)
+	assert.Equal(t, nil, err)
+	_, err = TrendTest(weighted, 2, 2)
+	assert.NotEqual(t, nil, err)
+}
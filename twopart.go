@@ -0,0 +1,105 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+)
+
+// TwoPartFit is a two-part, or hurdle, model of a response that is either zero
+// or a positive amount: a logistic regression for whether it is positive, and
+// a least squares fit of the amount over the positive observations.
+type TwoPartFit struct {
+	// Binary is the logistic regression of the indicator y > 0 on every
+	// observation, and BinarySummary its summary.
+	Binary        *GLM
+	BinarySummary *GLMSummary
+
+	// Model is the least squares fit of the positive responses, on the scale
+	// of the transform WithResponseTransform gave, and Summary its summary,
+	// whose observations are the rows Positive.
+	Model   *OLS
+	Summary OlsSummary
+
+	// Positive are the rows of the data with a positive response, in order,
+	// the observations of Model.
+	Positive []int
+}
+
+// FitTwoPart fits a two-part model of the response y, each of whose values is
+// zero or positive, on the columns of x. The first part is the logistic
+// regression of the indicator y > 0 by NewLogisticTrainer, and the second the
+// least squares fit of y over the rows with y > 0 by NewOlsTrainer, which the
+// options are given to: WithResponseTransform(LogTransform) fits log(y) for an
+// amount that is skewed, as they often are. The two parts fit separately, so
+// each has its own coefficients and diagnostics. A negative or missing
+// response is an error, as is a response without both zeros and positive
+// values. x isn't modified.
+func FitTwoPart(x *DataFrame, y []float64, opts ...Option) (*TwoPartFit, error) {
+	if len(y) != x.Rows() {
+		return nil, DimensionError
+	}
+	positive := make([]float64, len(y))
+	fit := &TwoPartFit{}
+	for i, v := range y {
+		if !(v >= 0) {
+			return nil, fmt.Errorf("response %d is %v, but a two-part model needs a response of zero or more", i, v)
+		}
+		if v > 0 {
+			positive[i] = 1
+			fit.Positive = append(fit.Positive, i)
+		}
+	}
+	if len(fit.Positive) == 0 || len(fit.Positive) == len(y) {
+		return nil, fmt.Errorf("%d of the %d responses are positive, but a two-part model needs both zeros and positive values", len(fit.Positive), len(y))
+	}
+
+	binary, summary, err := NewLogisticTrainer(opts...).Train(x, positive)
+	if err != nil {
+		return nil, fmt.Errorf("the binary part: %w", err)
+	}
+	fit.Binary, fit.BinarySummary = binary.(*GLM), summary.(*GLMSummary)
+
+	amounts, err := x.SelectRows(fit.Positive)
+	if err != nil {
+		return nil, err
+	}
+	z := make([]float64, len(fit.Positive))
+	for k, i := range fit.Positive {
+		z[k] = y[i]
+	}
+	model, summary, err := NewOlsTrainer(opts...).Train(amounts, z)
+	if err != nil {
+		return nil, fmt.Errorf("the positive part: %w", err)
+	}
+	fit.Model, fit.Summary = model.(*OLS), summary.(OlsSummary)
+	return fit, nil
+}
+
+// Probability returns the probability \hat p(x) that the response is positive
+// at the predictors x, without the intercept, from the binary part.
+func (f *TwoPartFit) Probability(x []float64) float64 {
+	return f.Binary.Predict(x)
+}
+
+// Amount returns the mean of the response at the predictors x given that it
+// is positive, E[y | y > 0, x], from the positive part. For a fit of a
+// transformed response it is the SmearingBackTransform of its prediction,
+// which for the log corrects the bias of \exp(\hat y) as an estimate of the
+// mean.
+func (f *TwoPartFit) Amount(x []float64) float64 {
+	if _, ok := f.Model.ResponseTransform(); !ok {
+		return f.Model.Predict(x)
+	}
+	amount, err := f.Model.PredictResponse(x, SmearingBackTransform)
+	if err != nil {
+		return math.NaN()
+	}
+	return amount
+}
+
+// Predict returns the mean of the response at the predictors x, without the
+// intercept, E[y | x] = \hat p(x) E[y | y > 0, x], the product of Probability
+// and Amount.
+func (f *TwoPartFit) Predict(x []float64) float64 {
+	return f.Probability(x) * f.Amount(x)
+}
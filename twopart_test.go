@@ -0,0 +1,85 @@
+package glasso
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// twoPartData simulates a response that is positive with the probability
+// logistic(0.5 + x_1 - 0.5 x_2) and then lognormal, with log(y) = 1 + 0.3 x_1 +
+// 0.2 x_2 + 0.5 e, and returns the mean of each response too.
+func twoPartData(rng *rand.Rand, n int) (*DataFrame, []float64, []float64) {
+	rows := make([][]float64, n)
+	z := make([]float64, n)
+	means := make([]float64, n)
+	for i := range rows {
+		u, v := rng.NormFloat64(), rng.NormFloat64()
+		rows[i] = []float64{u, v}
+		p := logistic(0.5 + u - 0.5*v)
+		mu := 1 + 0.3*u + 0.2*v
+		means[i] = p * math.Exp(mu+0.125)
+		if rng.Float64() < p {
+			z[i] = math.Exp(mu + 0.5*rng.NormFloat64())
+		}
+	}
+	return NewDataFrame(rows, []string{"u", "v"}), z, means
+}
+
+func TestFitTwoPart(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x, z, means := twoPartData(rng, 5000)
+	fit, err := FitTwoPart(x, z, WithResponseTransform(LogTransform))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, x.Cols())
+
+	// both parts recover their coefficients
+	assertCloseSlices(t, fit.BinarySummary.Coefficients(), []float64{0.5, 1, -0.5}, 0.1)
+	assertCloseSlices(t, fit.Model.Coefficients(), []float64{1, 0.3, 0.2}, 0.03)
+	assertClose(t, fit.Model.ResidualStandardError(), 0.5, 0.02)
+
+	// the positive part is fit to the positive rows
+	assert.Equal(t, len(fit.Positive), fit.Summary.Data().Rows())
+	for k, i := range fit.Positive {
+		assert.T(t, z[i] > 0)
+		assertClose(t, fit.Summary.Response()[k], math.Log(z[i]), 1e-12)
+	}
+	assert.Equal(t, len(z), len(fit.BinarySummary.Response()))
+
+	// the mean predictions are nearly unbiased, for each observation and on
+	// average, where the naive back-transform is biased down
+	total, totalMean, totalNaive := 0.0, 0.0, 0.0
+	for i := range z {
+		row := x.GetRow(i)
+		predicted := fit.Predict(row)
+		assertClose(t, predicted, means[i], 0.15*means[i])
+		assertClose(t, predicted, fit.Probability(row)*fit.Amount(row), 1e-12)
+		total += z[i]
+		totalMean += predicted
+		totalNaive += fit.Probability(row) * math.Exp(fit.Model.Predict(row))
+	}
+	assertClose(t, totalMean/total, 1, 0.03)
+	assert.T(t, totalNaive/total < 0.92)
+
+	// without the transform, the amount is the fit of y itself
+	untransformed, err := FitTwoPart(x, z)
+	assert.Equal(t, nil, err)
+	assertClose(t, untransformed.Amount(x.GetRow(0)), untransformed.Model.Predict(x.GetRow(0)), 1e-12)
+	assert.Equal(t, fit.Positive, untransformed.Positive)
+}
+
+func TestFitTwoPartErrors(t *testing.T) {
+	x, z, _ := twoPartData(rand.New(rand.NewSource(2)), 50)
+	_, err := FitTwoPart(x, z[:10])
+	assert.Equal(t, DimensionError, err)
+	negative := append([]float64(nil), z...)
+	negative[3] = -1
+	_, err = FitTwoPart(x, negative)
+	assert.NotEqual(t, nil, err)
+	_, err = FitTwoPart(x, make([]float64, len(z)))
+	assert.NotEqual(t, nil, err)
+	_, err = FitTwoPart(x, rep(1, len(z)))
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,166 @@
+package glasso
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// YeoJohnson returns the Yeo-Johnson transformation of the responses y,
+//
+// \psi(y, \lambda) = ((y + 1)^\lambda - 1) / \lambda          y >= 0, \lambda != 0
+// \psi(y, \lambda) = \log(y + 1)                               y >= 0, \lambda = 0
+// \psi(y, \lambda) = -((1 - y)^{2 - \lambda} - 1) / (2 - \lambda)  y < 0, \lambda != 2
+// \psi(y, \lambda) = -\log(1 - y)                              y < 0, \lambda = 2
+//
+// the Box-Cox transformation of y + 1 for y >= 0 and of 1 - y with the power
+// 2 - \lambda for y < 0, so that, unlike BoxCox, it takes responses of any
+// sign (Yeo & Johnson, 2000).
+func YeoJohnson(y []float64, lambda float64) []float64 {
+	z := make([]float64, len(y))
+	for i, v := range y {
+		z[i] = yeoJohnson(v, lambda)
+	}
+	return z
+}
+
+// InverseYeoJohnson returns the responses whose Yeo-Johnson transformations
+// are z, to back-transform predictions. The transformation keeps the sign of
+// y, and values outside its range, such as z >= -1 / \lambda for a negative
+// \lambda, are NaN.
+func InverseYeoJohnson(z []float64, lambda float64) []float64 {
+	y := make([]float64, len(z))
+	for i, v := range z {
+		switch {
+		case v >= 0 && lambda == 0:
+			y[i] = math.Expm1(v)
+		case v >= 0 && lambda*v+1 > 0:
+			y[i] = math.Expm1(math.Log1p(lambda*v) / lambda)
+		case v < 0 && lambda == 2:
+			y[i] = -math.Expm1(-v)
+		case v < 0 && 1-(2-lambda)*v > 0:
+			y[i] = -math.Expm1(math.Log1p(-(2-lambda)*v) / (2 - lambda))
+		default:
+			y[i] = math.NaN()
+		}
+	}
+	return y
+}
+
+// YeoJohnsonLambda profiles the log-likelihood of the least squares fit of the
+// Yeo-Johnson transformed response on the design of m over a grid of lambda
+// (DefaultBoxCoxGrid if grid is nil), as BoxCoxLambda does for Box-Cox. The
+// profile log-likelihood, with the Jacobian of the transformation, is
+//
+// l(\lambda) = -\frac{n}{2} \log(RSS(\psi(y, \lambda)) / n) + (\lambda - 1) \sum sign(y_i) \log(|y_i| + 1)
+//
+// The lambda returned is the maximum of the grid refined by a golden-section
+// search between its neighbors in the grid, so it is the maximum likelihood
+// estimate when the profile peaks inside the grid. On a fit of the intercept
+// alone it is the lambda of scikit-learn's
+// PowerTransformer(method="yeo-johnson"). The profile is that of the grid.
+func YeoJohnsonLambda(m Summary, grid []float64) (float64, []float64, error) {
+	if grid == nil {
+		grid = DefaultBoxCoxGrid()
+	}
+	if len(grid) == 0 {
+		return 0, nil, fmt.Errorf("empty grid of lambda")
+	}
+	y := m.Response()
+	jacobian := 0.0
+	for i, v := range y {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, nil, fmt.Errorf("response %d is %v, but the Yeo-Johnson transformation needs finite responses", i, v)
+		}
+		if v >= 0 {
+			jacobian += math.Log1p(v)
+		} else {
+			jacobian -= math.Log1p(-v)
+		}
+	}
+	x := m.Data().X
+	n := float64(len(y))
+	var fitErr error
+	loglik := func(lambda float64) float64 {
+		z := mat.NewDense(len(y), 1, YeoJohnson(y, lambda))
+		fit, err := leastSquares(x, z)
+		if err != nil {
+			fitErr = err
+			return math.NaN()
+		}
+		return -n/2*math.Log(sum(prod(fit.residuals, fit.residuals))/n) + (lambda-1)*jacobian
+	}
+
+	best, bestLoglik := 0.0, math.Inf(-1)
+	profile := make([]float64, len(grid))
+	for k, lambda := range grid {
+		if math.IsNaN(lambda) || math.IsInf(lambda, 0) {
+			return 0, nil, fmt.Errorf("lambda %v is not finite", lambda)
+		}
+		profile[k] = loglik(lambda)
+		if fitErr != nil {
+			return 0, nil, fitErr
+		}
+		if profile[k] > bestLoglik {
+			best, bestLoglik = lambda, profile[k]
+		}
+	}
+
+	// the neighbors of the best lambda in the grid bracket the maximum
+	sorted := append([]float64(nil), grid...)
+	sort.Float64s(sorted)
+	k := sort.SearchFloat64s(sorted, best)
+	lo, hi := best, best
+	if k > 0 {
+		lo = sorted[k-1]
+	}
+	for j := k; j < len(sorted); j++ {
+		if sorted[j] > best {
+			hi = sorted[j]
+			break
+		}
+	}
+	if lambda := goldenSectionMax(loglik, lo, hi, 1e-10); loglik(lambda) > bestLoglik {
+		best = lambda
+	}
+	if fitErr != nil {
+		return 0, nil, fitErr
+	}
+	return best, profile, nil
+}
+
+// goldenSectionMax returns the maximum of f on [lo, hi], taking f to be
+// unimodal there, to within tol.
+func goldenSectionMax(f func(float64) float64, lo, hi, tol float64) float64 {
+	ratio := (math.Sqrt(5) - 1) / 2
+	a, b := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+	fa, fb := f(a), f(b)
+	for hi-lo > tol {
+		if fa > fb {
+			hi, b, fb = b, a, fa
+			a = hi - ratio*(hi-lo)
+			fa = f(a)
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + ratio*(hi-lo)
+			fb = f(b)
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// yeoJohnson transforms y, accurately for lambda near 0 and 2.
+func yeoJohnson(y, lambda float64) float64 {
+	switch {
+	case y >= 0 && lambda == 0:
+		return math.Log1p(y)
+	case y >= 0:
+		return math.Expm1(lambda*math.Log1p(y)) / lambda
+	case lambda == 2:
+		return -math.Log1p(-y)
+	default:
+		return -math.Expm1((2-lambda)*math.Log1p(-y)) / (2 - lambda)
+	}
+}
@@ -0,0 +1,135 @@
+package glasso
+
+import (
+	"math"
+	"testing"
+)
+
+// mixedSigns is a skewed sample with negative, zero and positive values.
+var mixedSigns = []float64{-3.2, -1.5, -0.7, 0, 0.4, 1.1, 2.3, 3.8, 5.9, 9.4, 14.2, 21.7}
+
+func TestYeoJohnson(t *testing.T) {
+	// computed in 50-digit decimal arithmetic from the definition
+	for _, c := range []struct {
+		lambda float64
+		want   []float64
+	}{
+		{0, []float64{-8.32, 0, 3.122364924487357}},
+		{2, []float64{-1.4350845252893227, 0, 257.145}},
+		{0.5, []float64{-5.071625762270709, 0, 7.528903399657277}},
+		{-1, []float64{-24.362666666666666, 0, 0.9559471365638766}},
+	} {
+		for i, v := range YeoJohnson([]float64{-3.2, 0, 21.7}, c.lambda) {
+			if math.Abs(v-c.want[i]) > 1e-12*math.Max(1, math.Abs(c.want[i])) {
+				t.Errorf("lambda %v: transformed value %d is %v, want %v", c.lambda, i, v, c.want[i])
+			}
+		}
+	}
+
+	for _, lambda := range []float64{-1, 0, 0.5, 1, 2, 2.5} {
+		z := YeoJohnson(mixedSigns, lambda)
+		for i, v := range InverseYeoJohnson(z, lambda) {
+			if math.Abs(v-mixedSigns[i]) > 1e-12*math.Max(1, math.Abs(mixedSigns[i])) {
+				t.Errorf("lambda %v: back-transformed %v to %v, want %v", lambda, z[i], v, mixedSigns[i])
+			}
+		}
+		if lambda == 1 {
+			for i, v := range z {
+				if math.Abs(v-mixedSigns[i]) > 1e-12*math.Max(1, math.Abs(v)) {
+					t.Errorf("lambda 1 transformed %v to %v, want the identity", mixedSigns[i], v)
+				}
+			}
+		}
+	}
+
+	// the transformation is continuous at the log forms, lambda 0 and 2
+	for _, lambda := range []float64{0, 2} {
+		exact := YeoJohnson(mixedSigns, lambda)
+		near := YeoJohnson(mixedSigns, lambda+1e-9)
+		for i := range exact {
+			if math.Abs(near[i]-exact[i]) > 1e-6 {
+				t.Errorf("lambda %v + 1e-9: transformed %v to %v, want about %v", lambda, mixedSigns[i], near[i], exact[i])
+			}
+		}
+	}
+	if v := InverseYeoJohnson([]float64{1.5}, -1)[0]; !math.IsNaN(v) {
+		t.Errorf("back-transformed 1.5 at lambda -1 to %v, want NaN", v)
+	}
+	if v := InverseYeoJohnson([]float64{-2}, 3)[0]; !math.IsNaN(v) {
+		t.Errorf("back-transformed -2 at lambda 3 to %v, want NaN", v)
+	}
+}
+
+func TestYeoJohnsonLambda(t *testing.T) {
+	// the maximum likelihood lambda of the intercept alone, which scikit-learn's
+	// PowerTransformer(method="yeo-johnson", standardize=False) estimates; the
+	// reference maximizes the same likelihood in 50-digit decimal arithmetic
+	rows := make([][]float64, len(mixedSigns))
+	for i := range rows {
+		rows[i] = []float64{}
+	}
+	_, s, err := NewOlsTrainer().Train(NewDataFrame(rows), mixedSigns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lambda, profile, err := YeoJohnsonLambda(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile) != 41 {
+		t.Fatalf("got a profile of %d, want 41", len(profile))
+	}
+	// a maximum is found to about the square root of the precision
+	if math.Abs(lambda-0.5085790673891565) > 1e-7 {
+		t.Errorf("lambda %v, want 0.5085790673891565", lambda)
+	}
+	want := []float64{-5.030180325914451, -1.9591356194528893, -0.8089342380553296, 0, 0.3669761408097864, 0.9013171650196243, 1.6424047871257854, 2.3999668234396587, 3.2849863422278744, 4.5034261250709635, 5.880715284330858, 7.656236622432777}
+	for i, v := range YeoJohnson(mixedSigns, 0.5085790673891565) {
+		if math.Abs(v-want[i]) > 1e-12*math.Max(1, math.Abs(want[i])) {
+			t.Errorf("transformed value %d is %v, want %v", i, v, want[i])
+		}
+	}
+	if math.Abs(profile[25]-(-19.916091630526408)) > 0.01 || profile[25] < profile[24] || profile[25] < profile[26] {
+		t.Errorf("profile %v is not highest near 0.5", profile[24:27])
+	}
+
+	// on positive responses it is Box-Cox of y + 1, with the likelihood
+	// shifted by n/2 log n
+	trees := make([][]float64, len(treesGirth))
+	shifted := make([]float64, len(treesVolume))
+	for i := range trees {
+		trees[i] = []float64{treesGirth[i], treesHeight[i]}
+		shifted[i] = treesVolume[i] + 1
+	}
+	_, plusOne, err := NewOlsTrainer().Train(NewDataFrame(trees), shifted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxCoxBest, boxCoxProfile, err := BoxCoxLambda(plusOne, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lambda, profile, err = YeoJohnsonLambda(treesSummary(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := float64(len(treesVolume))
+	for k := range profile {
+		if want := boxCoxProfile[k] + n/2*math.Log(n); math.Abs(profile[k]-want) > 1e-9 {
+			t.Errorf("profile %d is %v, want %v", k, profile[k], want)
+		}
+	}
+	if math.Abs(lambda-boxCoxBest) > 0.1 {
+		t.Errorf("lambda %v is not within the grid step of %v", lambda, boxCoxBest)
+	}
+
+	if _, _, err := YeoJohnsonLambda(s, []float64{}); err == nil {
+		t.Errorf("expected an error for an empty grid")
+	}
+	if _, _, err := YeoJohnsonLambda(s, []float64{0, math.NaN()}); err == nil {
+		t.Errorf("expected an error for a lambda that isn't finite")
+	}
+	if l, _, err := YeoJohnsonLambda(s, []float64{1}); err != nil || l != 1 {
+		t.Errorf("got lambda %v and error %v for a grid of one, want 1", l, err)
+	}
+}